@@ -0,0 +1,122 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// accessible is set by the global --accessible flag or FOCUSMODE_ACCESSIBLE=1
+// Output is then written for screen readers: no lines redrawn in place, no decorative
+// glyphs, and state changes announced as full sentences
+var accessible bool
+
+// accessibleEnv enables accessible output for scripts and shells without passing the flag
+const accessibleEnv = "FOCUSMODE_ACCESSIBLE"
+
+// accessibleAnnounceMinutes is how often, in minutes, the remaining time is announced during a session
+const accessibleAnnounceMinutes = 5
+
+// parseSwitchArg removes a leading global switch such as --accessible from args
+func parseSwitchArg(args []string, name string) (bool, []string) {
+	if len(args) > 0 && (args[0] == "--"+name || args[0] == "-"+name) {
+		return true, args[1:]
+	}
+	return false, args
+}
+
+// envSwitch reports whether an environment variable turns a switch on
+func envSwitch(name string) bool {
+	value := strings.TrimSpace(os.Getenv(name))
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// accessibleFromEnv reports whether FOCUSMODE_ACCESSIBLE asks for accessible output
+func accessibleFromEnv() bool {
+	return envSwitch(accessibleEnv)
+}
+
+// glyph returns a decorative symbol, or nothing in accessible mode
+// The symbol carries its own trailing spacing, e.g. glyph("✓ ")
+func glyph(symbol string) string {
+	if accessible {
+		return ""
+	}
+	return symbol
+}
+
+// glyphOr returns a symbol, or words saying the same in accessible mode
+func glyphOr(symbol, words string) string {
+	if accessible {
+		return words
+	}
+	return symbol
+}
+
+// accessibleAnnouncer is a session hook that announces state changes and the remaining
+// time as sentences, replacing the countdown line that is redrawn every second
+type accessibleAnnouncer struct {
+	w           io.Writer
+	lastMinutes int // remaining minutes at the last tick, so each mark is announced once
+}
+
+// remainingMinutes returns the remaining time rounded up to whole minutes
+func remainingMinutes(fs *FocusSession) int {
+	return int((fs.remaining() + time.Minute - 1) / time.Minute)
+}
+
+// remainingSentence describes the remaining time, e.g. "20 minutes remaining."
+func remainingSentence(fs *FocusSession) string {
+	minutes := remainingMinutes(fs)
+	if minutes == 1 {
+		return "1 minute remaining."
+	}
+	return fmt.Sprintf("%s remaining.", spokenDuration(time.Duration(minutes)*time.Minute))
+}
+
+// OnStart announces the session
+func (a *accessibleAnnouncer) OnStart(fs *FocusSession) {
+	a.lastMinutes = remainingMinutes(fs)
+	if fs.Break {
+		fmt.Fprintf(a.w, "Break started. %s\n", remainingSentence(fs))
+		return
+	}
+	fmt.Fprintf(a.w, "Focus session started in %s. %s\n", fs.Mode, remainingSentence(fs))
+}
+
+// OnPause announces the pause
+func (a *accessibleAnnouncer) OnPause(fs *FocusSession) {
+	fmt.Fprintf(a.w, "Session paused. %s\n", remainingSentence(fs))
+}
+
+// OnResume announces the resumed countdown
+func (a *accessibleAnnouncer) OnResume(fs *FocusSession) {
+	a.lastMinutes = remainingMinutes(fs)
+	fmt.Fprintf(a.w, "Session resumed. %s\n", remainingSentence(fs))
+}
+
+// OnExtend announces the new remaining time
+func (a *accessibleAnnouncer) OnExtend(fs *FocusSession) {
+	a.lastMinutes = remainingMinutes(fs)
+	fmt.Fprintf(a.w, "Session extended. %s\n", remainingSentence(fs))
+}
+
+// OnTick announces the remaining time every few minutes and when one minute is left
+func (a *accessibleAnnouncer) OnTick(fs *FocusSession) {
+	minutes := remainingMinutes(fs)
+	if minutes == a.lastMinutes {
+		return
+	}
+	a.lastMinutes = minutes
+	if minutes == 1 || (minutes > 0 && minutes%accessibleAnnounceMinutes == 0) {
+		fmt.Fprintln(a.w, remainingSentence(fs))
+	}
+}
+
+// OnEnd does nothing; the session reports how it ended
+func (a *accessibleAnnouncer) OnEnd(fs *FocusSession) {}
+
+// DryRun reports nothing; announcements only write to the terminal
+func (a *accessibleAnnouncer) DryRun(fs *FocusSession) []string { return nil }
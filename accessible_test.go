@@ -0,0 +1,99 @@
+package focusmode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setAccessible turns accessible output on for the rest of the test
+func setAccessible(t *testing.T) {
+	accessible = true
+	t.Cleanup(func() { accessible = false })
+}
+
+// TestParseSwitchArg tests that only a leading --accessible flag is taken
+func TestParseSwitchArg(t *testing.T) {
+	on, rest := parseSwitchArg([]string{"--accessible", "session", "start"}, "accessible")
+	if !on || strings.Join(rest, " ") != "session start" {
+		t.Errorf("Unexpected result: %v, %v", on, rest)
+	}
+	on, rest = parseSwitchArg([]string{"session", "--accessible"}, "accessible")
+	if on || len(rest) != 2 {
+		t.Errorf("Expected a later --accessible to be left alone, got %v, %v", on, rest)
+	}
+
+	for value, want := range map[string]bool{"1": true, "yes": true, "": false, "0": false, "false": false} {
+		t.Setenv(accessibleEnv, value)
+		if got := accessibleFromEnv(); got != want {
+			t.Errorf("%s=%q: got %v, want %v", accessibleEnv, value, got, want)
+		}
+	}
+}
+
+// TestGlyph tests that symbols are dropped or spelled out in accessible mode
+func TestGlyph(t *testing.T) {
+	if glyph("✓ ") != "✓ " || glyphOr("✗", "no") != "✗" {
+		t.Error("Expected symbols by default")
+	}
+	setAccessible(t)
+	if glyph("✓ ") != "" || glyphOr("✗", "no") != "no" {
+		t.Error("Expected no symbols in accessible mode")
+	}
+}
+
+// TestAccessibleAnnouncer tests that state changes and every fifth minute are announced once
+func TestAccessibleAnnouncer(t *testing.T) {
+	var buf bytes.Buffer
+	announcer := &accessibleAnnouncer{w: &buf}
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		Mode:      "focusmode",
+		StartTime: time.Now(),
+		State:     StateRunning,
+		Hooks:     []SessionHook{announcer},
+	}
+
+	announcer.OnStart(fs)
+	if got := buf.String(); got != "Focus session started in focusmode. 25 minutes remaining.\n" {
+		t.Errorf("Unexpected start announcement: %q", got)
+	}
+
+	buf.Reset()
+	fs.StartTime = fs.StartTime.Add(-90 * time.Second) // 23.5 minutes left
+	announcer.OnTick(fs)
+	announcer.OnTick(fs)
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing between marks, got %q", buf.String())
+	}
+	fs.StartTime = fs.StartTime.Add(-4 * time.Minute) // 19.5 minutes left
+	announcer.OnTick(fs)
+	announcer.OnTick(fs)
+	if got := buf.String(); got != "20 minutes remaining.\n" {
+		t.Errorf("Expected the 20 minute mark once, got %q", got)
+	}
+
+	buf.Reset()
+	fs.pause()
+	fs.extend(5 * time.Minute)
+	if got := buf.String(); got != "Session paused. 20 minutes remaining.\nSession extended. 25 minutes remaining.\n" {
+		t.Errorf("Unexpected state change announcements: %q", got)
+	}
+}
+
+// TestWaitGracePeriodAccessible tests that the countdown is announced once instead of redrawn
+func TestWaitGracePeriodAccessible(t *testing.T) {
+	setAccessible(t)
+	tick := make(chan time.Time, 3)
+	for i := 0; i < 3; i++ {
+		tick <- time.Now()
+	}
+	var out bytes.Buffer
+	if !waitGracePeriod("Applying focusmode", 3, nil, tick, &out) {
+		t.Fatal("Expected countdown to proceed")
+	}
+	if got := out.String(); got != "Applying focusmode in 3 seconds. Type c and press Enter to cancel.\n" {
+		t.Errorf("Unexpected countdown output: %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package focusmode
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// SessionAllowlist represents the only apps and sites a preset's sessions expect; anything else is
+// warned about and counted in the distraction log
+type SessionAllowlist struct {
+	Apps         []string `yaml:"apps" doc:"Programs that may be in the foreground, by process name; FocusMode itself always may" example:"[code, WindowsTerminal, firefox]"`
+	Sites        []string `yaml:"sites" doc:"Sites the companion browser extension doesn't warn about, with their subdomains; a path limits an entry to that part of the site" example:"[github.com, pkg.go.dev]"`
+	GraceSeconds int      `yaml:"grace_seconds" doc:"How long another program may stay in the foreground before a warning, so switching through windows isn't flagged" default:"15"`
+}
+
+const (
+	defaultAllowlistGraceSeconds = 15
+	allowlistPollInterval        = 5 * time.Second
+)
+
+// grace returns how long a program outside the allowlist may stay in the foreground
+func (a *SessionAllowlist) grace() time.Duration {
+	if a.GraceSeconds <= 0 {
+		return defaultAllowlistGraceSeconds * time.Second
+	}
+	return time.Duration(a.GraceSeconds) * time.Second
+}
+
+// validate checks the allowlist's sites
+func (a *SessionAllowlist) validate() error {
+	for _, site := range a.Sites {
+		if _, err := parseSiteRule(site); err != nil {
+			return fmt.Errorf("allowlist: %w", err)
+		}
+	}
+	return nil
+}
+
+// appAllowed reports whether a foreground app is on the allowlist
+// An app that couldn't be named, such as the desktop itself, isn't warned about
+func appAllowed(app string, apps []string) bool {
+	key := appKey(app)
+	if key == "" || key == "focusmode" {
+		return true
+	}
+	for _, allowed := range apps {
+		if appKey(allowed) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// outsideAllowlist reports whether a web page is on none of the allowed sites
+// Without allowed sites, or for pages that aren't on the web, nothing is outside
+func outsideAllowlist(allowed []siteRule, rawURL string) bool {
+	if len(allowed) == 0 {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	_, ok := siteBlocked(allowed, rawURL)
+	return !ok
+}
+
+// allowlistWatcher warns when an app outside the session's allowlist stays in the foreground
+// It checks every few seconds in the background, and not while the session is paused
+type allowlistWatcher struct {
+	allowlist  *SessionAllowlist
+	foreground func() (string, error)
+	logPath    string
+
+	mu        sync.Mutex
+	paused    bool
+	app       string    // App outside the allowlist now in the foreground, if any
+	since     time.Time // When it came to the foreground
+	warned    bool      // Whether it was warned about since
+	warnings  map[string]int
+	failed    bool // Reading the foreground app failed, which is only reported once
+	stop      chan struct{}
+	done      chan struct{}
+	notifiers []Notifier
+}
+
+// observe records the foreground app at now, and returns the app to warn about, if one is due
+func (w *allowlistWatcher) observe(app string, now time.Time) (string, int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.paused || appAllowed(app, w.allowlist.Apps) {
+		w.app = ""
+		return "", 0, false
+	}
+	if app != w.app {
+		w.app, w.since, w.warned = app, now, false
+	}
+	if w.warned || now.Sub(w.since) < w.allowlist.grace() {
+		return "", 0, false
+	}
+	w.warned = true
+	w.warnings[app]++
+	return app, w.warnings[app], true
+}
+
+// check reads the foreground app once, logging and announcing an app outside the allowlist
+func (w *allowlistWatcher) check(fs *FocusSession) {
+	app, err := w.foreground()
+	if err != nil {
+		if !w.failed {
+			w.failed = true
+			fmt.Fprintf(os.Stderr, "\nWarning: can't check apps against the allowlist: %v\n", err)
+		}
+		return
+	}
+	app, n, due := w.observe(app, time.Now())
+	if !due {
+		return
+	}
+	event := DistractionEvent{Time: time.Now(), Kind: distractionOutsideAllowlist, Mode: fs.Mode, App: app, Attempt: n}
+	if err := appendDistraction(w.logPath, event); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not record distraction: %v\n", err)
+	}
+	notifyAll(w.notifiers, "Outside the allowlist", fmt.Sprintf("%s isn't one of this session's apps", app))
+}
+
+// OnStart starts checking the foreground app
+func (w *allowlistWatcher) OnStart(fs *FocusSession) {
+	w.warnings = make(map[string]int)
+	w.notifiers = buildNotifiers(fs.Hooks)
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(allowlistPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.check(fs)
+			}
+		}
+	}()
+}
+
+// OnPause stops warning until the session resumes
+func (w *allowlistWatcher) OnPause(fs *FocusSession) {
+	w.mu.Lock()
+	w.paused = true
+	w.mu.Unlock()
+}
+
+// OnResume warns again, giving the app in the foreground a new grace period
+func (w *allowlistWatcher) OnResume(fs *FocusSession) {
+	w.mu.Lock()
+	w.paused, w.app = false, ""
+	w.mu.Unlock()
+}
+
+// OnEnd stops checking and reports the apps warned about
+func (w *allowlistWatcher) OnEnd(fs *FocusSession) {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+	w.stop = nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.warnings) > 0 {
+		total := 0
+		for _, n := range w.warnings {
+			total += n
+		}
+		fmt.Printf("%sLeft the allowlist %d time(s) across %d app(s)\n", glyph("🎯 "), total, len(w.warnings))
+	}
+}
+
+// DryRun reports the apps that would be allowed
+func (w *allowlistWatcher) DryRun(fs *FocusSession) []string {
+	return []string{fmt.Sprintf("warn when an app other than %v stays in the foreground for %s", w.allowlist.Apps, w.allowlist.grace())}
+}
@@ -0,0 +1,128 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAppAllowed tests matching foreground apps against the allowlist
+func TestAppAllowed(t *testing.T) {
+	apps := []string{"code", "WindowsTerminal.exe", "Firefox"}
+	tests := map[string]bool{
+		"Code.exe": true, "windowsterminal": true, "firefox": true, "focusmode.exe": true, "": true,
+		"Discord": false, "Code - Insiders": false,
+	}
+	for app, want := range tests {
+		if got := appAllowed(app, apps); got != want {
+			t.Errorf("appAllowed(%q) = %v, want %v", app, got, want)
+		}
+	}
+
+	allowed := []siteRule{{Host: "github.com"}, {Host: "pkg.go.dev"}}
+	for page, want := range map[string]bool{
+		"https://github.com/golang/go":   false,
+		"https://gist.github.com/":       false,
+		"https://www.reddit.com/":        true,
+		"chrome://extensions":            false,
+		"https://pkg.go.dev/net/http#Do": false,
+	} {
+		if got := outsideAllowlist(allowed, page); got != want {
+			t.Errorf("outsideAllowlist(%q) = %v, want %v", page, got, want)
+		}
+	}
+	if outsideAllowlist(nil, "https://www.reddit.com/") {
+		t.Error("Expected nothing outside an allowlist without sites")
+	}
+}
+
+// TestAllowlistWatcher tests that an app outside the allowlist is warned about once after the
+// grace period, and not while the session is paused
+func TestAllowlistWatcher(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "distractions.jsonl")
+	foreground := "code"
+	w := &allowlistWatcher{
+		allowlist:  &SessionAllowlist{Apps: []string{"code"}, GraceSeconds: 10},
+		foreground: func() (string, error) { return foreground, nil },
+		logPath:    logPath,
+		warnings:   make(map[string]int),
+	}
+	start := time.Now()
+	steps := []struct {
+		app     string
+		seconds int
+		want    bool
+	}{
+		{"code", 0, false},
+		{"Discord", 5, false},  // Grace period starts
+		{"Discord", 14, false}, // 9 seconds in
+		{"Discord", 15, true},  // 10 seconds in
+		{"Discord", 30, false}, // Already warned
+		{"code", 35, false},
+		{"Discord", 40, false}, // A new grace period
+		{"Discord", 50, true},
+	}
+	for _, step := range steps {
+		app, n, due := w.observe(step.app, start.Add(time.Duration(step.seconds)*time.Second))
+		if due != step.want || (due && app != step.app) {
+			t.Errorf("observe(%s, %ds) = %q, %v, want %v", step.app, step.seconds, app, due, step.want)
+		}
+		if due && step.seconds == 50 && n != 2 {
+			t.Errorf("Expected the second warning for Discord, got %d", n)
+		}
+	}
+
+	w.OnPause(nil)
+	if _, _, due := w.observe("Slack", start.Add(time.Hour)); due {
+		t.Error("Expected no warning while paused")
+	}
+	w.OnResume(nil)
+
+	// A check past the grace period logs the app to the distraction log
+	foreground = "Slack"
+	fs := &FocusSession{Mode: "deepwork"}
+	w.check(fs)
+	w.since = w.since.Add(-time.Minute) // As if Slack had been in the foreground for a minute
+	w.check(fs)
+	events, err := readDistractions(logPath)
+	if err != nil || len(events) != 1 || events[0].Kind != distractionOutsideAllowlist || events[0].App != "Slack" || events[0].Mode != "deepwork" {
+		t.Errorf("Expected Slack logged outside the allowlist, got %+v (err %v)", events, err)
+	}
+}
+
+// TestAllowlistPreset tests that a preset's allowlist sites are checked and reach the browser host
+func TestAllowlistPreset(t *testing.T) {
+	config := &Config{DefaultMode: "work", Modes: map[string]ModeConfig{"work": {}}, Presets: map[string]SessionPreset{
+		"extreme": {Duration: 90, Allowlist: &SessionAllowlist{Sites: []string{"github.com", "not a site"}}},
+	}}
+	if _, err := config.getPreset("extreme"); err == nil || !strings.Contains(err.Error(), "allowlist") {
+		t.Errorf("Expected an allowlist error, got %v", err)
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("AppData", filepath.Join(home, ".config"))
+	configPath := filepath.Join(home, "profile.yml")
+	os.WriteFile(configPath, []byte("modes:\n  work:\n    destination: Hidden\n"), 0644)
+	path, err := activeSessionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeActiveSession(path, activeSession{PID: os.Getpid(), Mode: "work", StartTime: time.Now(), DurationSeconds: 3600, AllowSites: []string{"github.com"}})
+
+	host := &browserHost{configPath: configPath, now: time.Now}
+	state := host.reply(browserRequest{Type: "state"})
+	if state.browserState == nil || len(state.AllowSites) != 1 || state.AllowSites[0].Host != "github.com" {
+		t.Errorf("Expected github.com in allow_sites, got %+v", state)
+	}
+	for page, want := range map[string]bool{"https://github.com/": false, "https://news.ycombinator.com/": true} {
+		reply := host.reply(browserRequest{Type: "check", URL: page})
+		if reply.browserCheck == nil || reply.OutsideAllowlist != want || reply.Blocked {
+			t.Errorf("check(%s) = %+v, want outside_allowlist %v", page, reply.browserCheck, want)
+		}
+	}
+}
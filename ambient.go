@@ -0,0 +1,379 @@
+package focusmode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AmbientConfig represents the ambient sound settings used during sessions
+type AmbientConfig struct {
+	Enabled    bool   `yaml:"enabled" doc:"Play ambient sound during sessions" default:"false"`
+	Sound      string `yaml:"sound" doc:"Built-in noise (white, pink, brown) or path to an audio file" example:"brown"`
+	Volume     int    `yaml:"volume" doc:"Playback volume 0-100" default:"60"`
+	DuckVolume int    `yaml:"duck_volume" doc:"Volume used while ducked 0-100" default:"20"`
+}
+
+const (
+	defaultAmbientVolume     = 60
+	defaultAmbientDuckVolume = 20
+	noiseSampleRate          = 22050
+	noiseLoopSeconds         = 10
+)
+
+// builtinNoises lists the noise colors that can be generated without an audio file
+var builtinNoises = []string{"white", "pink", "brown"}
+
+// ambientPlayer loops an audio file with an external player for the duration of a session
+type ambientPlayer struct {
+	config AmbientConfig
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	done    chan struct{}
+	ducked  bool
+	running bool
+	restart bool // set when the current player was killed to apply a new volume
+}
+
+// newAmbientPlayer creates an ambient player, filling in default volumes
+func newAmbientPlayer(config AmbientConfig) *ambientPlayer {
+	if config.Sound == "" {
+		config.Sound = "white"
+	}
+	if config.Volume <= 0 {
+		config.Volume = defaultAmbientVolume
+	}
+	if config.DuckVolume <= 0 {
+		config.DuckVolume = defaultAmbientDuckVolume
+	}
+	config.Volume = clampVolume(config.Volume)
+	config.DuckVolume = clampVolume(config.DuckVolume)
+	return &ambientPlayer{config: config}
+}
+
+// clampVolume limits a volume to the 0-100 range
+func clampVolume(volume int) int {
+	if volume < 0 {
+		return 0
+	}
+	if volume > 100 {
+		return 100
+	}
+	return volume
+}
+
+// isBuiltinNoise reports whether sound names a generated noise color
+func isBuiltinNoise(sound string) bool {
+	for _, noise := range builtinNoises {
+		if strings.EqualFold(sound, noise) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAmbientSound returns the audio file to play, generating built-in noise if needed
+func resolveAmbientSound(sound string) (string, error) {
+	if !isBuiltinNoise(sound) {
+		if _, err := os.Stat(sound); err != nil {
+			return "", fmt.Errorf("ambient sound file not found: %s", sound)
+		}
+		return sound, nil
+	}
+
+	kind := strings.ToLower(sound)
+	return generatedSound(kind+"-noise.wav", func(w io.Writer) error {
+		return writeNoiseWAV(w, kind, noiseLoopSeconds)
+	})
+}
+
+// generatedSound returns the path of a sound file focusmode generates, writing it the first time
+// It is kept in the data directory rather than under a fixed name in the shared temp directory,
+// where another user could create that file first. The file is written under a temporary name and
+// renamed into place, so an interrupted write is never played
+func generatedSound(name string, write func(io.Writer) error) (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := guardWrite("create " + path); err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %w", name, err)
+	}
+	defer os.Remove(file.Name())
+	writer := bufio.NewWriter(file)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", name, err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", name, err)
+	}
+	ownByUser(path)
+	return path, nil
+}
+
+// writeWAVHeader writes the header of a mono 16-bit PCM WAV file of samples at noiseSampleRate
+func writeWAVHeader(w io.Writer, samples int) error {
+	dataSize := uint32(samples * 2)
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + dataSize, [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16), uint16(1), uint16(1),
+		uint32(noiseSampleRate), uint32(noiseSampleRate * 2), uint16(2), uint16(16),
+		[4]byte{'d', 'a', 't', 'a'}, dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return fmt.Errorf("error writing WAV header: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeNoiseWAV writes a mono 16-bit PCM WAV file of generated noise
+// Supported kinds are "white", "pink" (Paul Kellet's filter) and "brown" (leaky integrator)
+func writeNoiseWAV(w io.Writer, kind string, seconds int) error {
+	samples := noiseSampleRate * seconds
+	if err := writeWAVHeader(w, samples); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var b0, b1, b2, b3, b4, b5, b6, brown float64
+
+	buf := make([]byte, 2)
+	for i := 0; i < samples; i++ {
+		white := rng.Float64()*2 - 1
+		var sample float64
+
+		switch kind {
+		case "white":
+			sample = white * 0.5
+		case "pink":
+			b0 = 0.99886*b0 + white*0.0555179
+			b1 = 0.99332*b1 + white*0.0750759
+			b2 = 0.96900*b2 + white*0.1538520
+			b3 = 0.86650*b3 + white*0.3104856
+			b4 = 0.55000*b4 + white*0.5329522
+			b5 = -0.7616*b5 - white*0.0168980
+			sample = (b0 + b1 + b2 + b3 + b4 + b5 + b6 + white*0.5362) * 0.11
+			b6 = white * 0.115926
+		case "brown":
+			brown = (brown + 0.02*white) / 1.02
+			sample = brown * 3.5
+		default:
+			return fmt.Errorf("unknown noise type: %s", kind)
+		}
+
+		sample = math.Max(-1, math.Min(1, sample))
+		binary.LittleEndian.PutUint16(buf, uint16(int16(sample*math.MaxInt16)))
+		if _, err := w.Write(buf); err != nil {
+			return fmt.Errorf("error writing WAV data: %w", err)
+		}
+	}
+	return nil
+}
+
+// playerCommandArgs returns the external command used to play a file once at the given volume
+// lookPath is used to detect optional players such as ffplay
+func playerCommandArgs(goos string, file string, volume int, lookPath func(string) (string, error)) (string, []string, error) {
+	if _, err := lookPath("ffplay"); err == nil {
+		return "ffplay", []string{"-nodisp", "-autoexit", "-loglevel", "quiet", "-volume", fmt.Sprint(volume), file}, nil
+	}
+
+	switch goos {
+	case "darwin":
+		return "afplay", []string{"-v", fmt.Sprintf("%.2f", float64(volume)/100), file}, nil
+	case "linux":
+		if _, err := lookPath("paplay"); err == nil {
+			return "paplay", []string{fmt.Sprintf("--volume=%d", volume*65536/100), file}, nil
+		}
+		if _, err := lookPath("aplay"); err == nil {
+			return "aplay", []string{"-q", file}, nil
+		}
+		return "", nil, fmt.Errorf("no audio player found (install ffplay, paplay or aplay)")
+	case "windows":
+		script := fmt.Sprintf("(New-Object Media.SoundPlayer '%s').PlaySync()", strings.ReplaceAll(file, "'", "''"))
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// currentVolume returns the volume to play at, taking ducking into account
+func (p *ambientPlayer) currentVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ducked {
+		return p.config.DuckVolume
+	}
+	return p.config.Volume
+}
+
+// start begins looping the configured sound in the background
+func (p *ambientPlayer) start() error {
+	file, err := resolveAmbientSound(p.config.Sound)
+	if err != nil {
+		return err
+	}
+
+	// Fail early if no player is available rather than inside the loop
+	if _, _, err := playerCommandArgs(runtime.GOOS, file, p.config.Volume, exec.LookPath); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return nil
+	}
+	p.running = true
+	p.done = make(chan struct{})
+	go p.loop(file, p.done)
+	return nil
+}
+
+// loop replays the file until done is closed
+// The player is restarted whenever it exits, which is also how volume changes take effect
+func (p *ambientPlayer) loop(file string, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		name, args, err := playerCommandArgs(runtime.GOOS, file, p.currentVolume(), exec.LookPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nAmbient sound stopped: %v\n", err)
+			return
+		}
+
+		cmd := exec.Command(name, args...)
+		p.mu.Lock()
+		p.cmd = cmd
+		p.mu.Unlock()
+
+		started := time.Now()
+		err = cmd.Run()
+
+		p.mu.Lock()
+		p.cmd = nil
+		restarted := p.restart
+		p.restart = false
+		p.mu.Unlock()
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		// Avoid spinning when the player fails immediately
+		if err != nil && !restarted && time.Since(started) < time.Second {
+			fmt.Fprintf(os.Stderr, "\nAmbient sound stopped: %s failed: %v\n", name, err)
+			return
+		}
+	}
+}
+
+// stop stops playback if it is running
+func (p *ambientPlayer) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return
+	}
+	p.running = false
+	close(p.done)
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+// setDucked lowers or restores the playback volume
+func (p *ambientPlayer) setDucked(ducked bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ducked == ducked {
+		return
+	}
+	p.ducked = ducked
+	// Restart the current player so the new volume takes effect
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.restart = true
+		p.cmd.Process.Kill()
+	}
+}
+
+// isDucked reports whether playback is currently ducked
+func (p *ambientPlayer) isDucked() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ducked
+}
+
+// toggleDuck switches ducking on or off and returns the new state
+func (p *ambientPlayer) toggleDuck() bool {
+	p.mu.Lock()
+	ducked := !p.ducked
+	p.mu.Unlock()
+	p.setDucked(ducked)
+	return ducked
+}
+
+// OnStart starts ambient sound when the session begins
+func (p *ambientPlayer) OnStart(fs *FocusSession) {
+	if err := p.start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ambient sound unavailable: %v\n", err)
+		return
+	}
+	fmt.Printf("%sAmbient sound: %s (volume %d%%)\n", glyph("🎵 "), p.config.Sound, p.config.Volume)
+}
+
+// OnPause stops ambient sound while the session is paused
+func (p *ambientPlayer) OnPause(fs *FocusSession) {
+	p.stop()
+}
+
+// OnResume restarts ambient sound when the session resumes
+func (p *ambientPlayer) OnResume(fs *FocusSession) {
+	if err := p.start(); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: ambient sound unavailable: %v\n", err)
+	}
+}
+
+// OnEnd stops ambient sound when the session ends
+func (p *ambientPlayer) OnEnd(fs *FocusSession) {
+	p.stop()
+}
+
+// DryRun reports the sound that would play
+func (p *ambientPlayer) DryRun(fs *FocusSession) []string {
+	return []string{fmt.Sprintf("play ambient sound %s at volume %d%% (ducked to %d%%)", p.config.Sound, p.config.Volume, p.config.DuckVolume)}
+}
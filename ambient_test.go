@@ -0,0 +1,151 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteNoiseWAV tests that generated noise files have a valid WAV header and length
+func TestWriteNoiseWAV(t *testing.T) {
+	for _, kind := range builtinNoises {
+		t.Run(kind, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeNoiseWAV(&buf, kind, 1); err != nil {
+				t.Fatalf("writeNoiseWAV() returned error: %v", err)
+			}
+
+			data := buf.Bytes()
+			if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+				t.Fatalf("Invalid WAV header: %q", data[0:12])
+			}
+
+			dataSize := binary.LittleEndian.Uint32(data[40:44])
+			if want := uint32(noiseSampleRate * 2); dataSize != want {
+				t.Errorf("Expected data size %d, got %d", want, dataSize)
+			}
+			if len(data) != 44+int(dataSize) {
+				t.Errorf("Expected file length %d, got %d", 44+dataSize, len(data))
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeNoiseWAV(&buf, "purple", 1); err == nil {
+		t.Error("Expected error for unknown noise type")
+	}
+}
+
+// TestResolveAmbientSound tests that built-in noise is generated once, in the data directory
+func TestResolveAmbientSound(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	path, err := resolveAmbientSound("Pink")
+	if err != nil {
+		t.Fatalf("resolveAmbientSound() returned error: %v", err)
+	}
+	dir, _ := dataDir()
+	if path != filepath.Join(dir, "pink-noise.wav") {
+		t.Errorf("Expected the noise in %s, got %s", dir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != int64(44+noiseSampleRate*noiseLoopSeconds*2) {
+		t.Fatalf("Expected a complete noise file, got %v, %v", info, err)
+	}
+	if again, err := resolveAmbientSound("pink"); err != nil || again != path {
+		t.Errorf("Expected the generated file reused, got %s, %v", again, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
+
+// TestNewAmbientPlayerDefaults tests default and clamped volumes
+func TestNewAmbientPlayerDefaults(t *testing.T) {
+	player := newAmbientPlayer(AmbientConfig{Enabled: true})
+	if player.config.Sound != "white" {
+		t.Errorf("Expected default sound 'white', got %q", player.config.Sound)
+	}
+	if player.config.Volume != defaultAmbientVolume {
+		t.Errorf("Expected default volume %d, got %d", defaultAmbientVolume, player.config.Volume)
+	}
+	if player.config.DuckVolume != defaultAmbientDuckVolume {
+		t.Errorf("Expected default duck volume %d, got %d", defaultAmbientDuckVolume, player.config.DuckVolume)
+	}
+
+	player = newAmbientPlayer(AmbientConfig{Volume: 150, DuckVolume: 10})
+	if player.config.Volume != 100 {
+		t.Errorf("Expected volume clamped to 100, got %d", player.config.Volume)
+	}
+}
+
+// TestAmbientPlayerDucking tests that ducking switches the playback volume
+func TestAmbientPlayerDucking(t *testing.T) {
+	player := newAmbientPlayer(AmbientConfig{Volume: 70, DuckVolume: 15})
+
+	if got := player.currentVolume(); got != 70 {
+		t.Errorf("Expected volume 70, got %d", got)
+	}
+	if !player.toggleDuck() {
+		t.Error("Expected toggleDuck() to report ducked")
+	}
+	if got := player.currentVolume(); got != 15 {
+		t.Errorf("Expected ducked volume 15, got %d", got)
+	}
+	if player.toggleDuck() {
+		t.Error("Expected toggleDuck() to report unducked")
+	}
+	if got := player.currentVolume(); got != 70 {
+		t.Errorf("Expected volume 70 after unducking, got %d", got)
+	}
+}
+
+// TestPlayerCommandArgs tests player selection per platform
+func TestPlayerCommandArgs(t *testing.T) {
+	available := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	tests := []struct {
+		name     string
+		goos     string
+		players  []string
+		wantName string
+		wantErr  bool
+	}{
+		{"ffplay preferred", "linux", []string{"ffplay", "paplay"}, "ffplay", false},
+		{"paplay on linux", "linux", []string{"paplay"}, "paplay", false},
+		{"aplay fallback", "linux", []string{"aplay"}, "aplay", false},
+		{"no player on linux", "linux", nil, "", true},
+		{"afplay on macOS", "darwin", nil, "afplay", false},
+		{"powershell on windows", "windows", nil, "powershell", false},
+		{"unsupported OS", "plan9", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args, err := playerCommandArgs(tt.goos, "noise.wav", 50, available(tt.players...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("playerCommandArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name != tt.wantName {
+				t.Errorf("Expected player %q, got %q", tt.wantName, name)
+			}
+			if !tt.wantErr && len(args) == 0 {
+				t.Error("Expected player arguments")
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package focusmode
+
+import "fmt"
+
+// Session is a timed focus session; see NewSession
+type Session = FocusSession
+
+// LoadConfig reads the profile at path with machine policy applied, as the focusmode command does
+func LoadConfig(path string) (*Config, error) {
+	return loadConfig(path)
+}
+
+// LoadCategories reads the categories at path, or the built-in ones if there is no file
+func LoadCategories(path string) (*CategoriesConfig, error) {
+	return loadCategoriesConfig(path)
+}
+
+// StartTracing sends OpenTelemetry spans of the applies and restores made through Mover, and
+// of anything else FocusMode traces, to the OTLP/HTTP collector in config
+// Call the returned function before exiting to send the spans still queued
+func StartTracing(config TracingConfig) (stop func()) {
+	return startTracing(config)
+}
+
+// Mover applies and restores modes on the OS desktop for programs embedding FocusMode
+// It records what it moves in the same state as the focusmode command, so the two can be
+// used side by side: a mode applied from a tray app can be restored with focusmode -restore,
+// undone with focusmode undo and shown by focusmode status
+// Progress is printed to standard output, as the command prints it
+type Mover struct {
+	Config *Config
+	DryRun bool // Print what would be moved without moving anything
+}
+
+// NewMover returns a Mover for config
+func NewMover(config *Config) *Mover {
+	return &Mover{Config: config}
+}
+
+// Apply moves a mode's shortcuts off the desktop, first restoring modes it conflicts with and
+// applying the modes it requires; an empty mode is the default mode
+// A mode protected by the guardian asks for its PIN on standard input
+func (m *Mover) Apply(mode string) error {
+	mode, err := m.mode(mode)
+	if err != nil {
+		return err
+	}
+	_, err = applyModeWithDependencies(m.Config, mode, m.DryRun, false)
+	return err
+}
+
+// Restore moves everything in a mode's folder back to the desktop; an empty mode is the default mode
+func (m *Mover) Restore(mode string) error {
+	mode, err := m.mode(mode)
+	if err != nil {
+		return err
+	}
+	return restoreShortcutsForMode(m.Config, mode, m.DryRun)
+}
+
+// RestoreAll moves everything in every mode's folder back to the desktop
+func (m *Mover) RestoreAll() error {
+	return restoreAllShortcuts(m.Config, m.DryRun)
+}
+
+// AppliedModes returns the modes applied now, from the bottom of the stack to the top
+func (m *Mover) AppliedModes() ([]string, error) {
+	path, err := appliedModesPath()
+	if err != nil {
+		return nil, err
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = mode.Mode
+	}
+	return names, nil
+}
+
+// mode returns the mode to act on, checking that it is configured
+func (m *Mover) mode(mode string) (string, error) {
+	if m.Config == nil {
+		return "", fmt.Errorf("mover has no config")
+	}
+	if mode == "" {
+		mode = m.Config.DefaultMode
+	}
+	if _, err := m.Config.getModeConfig(mode); err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+// NewSession returns a session that hides a mode's shortcuts for minutes and, with autoRestore,
+// puts them back at the end; an empty mode is the default mode
+// The session's hooks, such as ambient sound and history, are set up from config as for
+// focusmode session start. Close a channel set as its Stop field to end it early
+func NewSession(config *Config, mode string, minutes int, autoRestore bool) (*Session, error) {
+	if mode == "" {
+		mode = config.DefaultMode
+	}
+	if isSessionRunning() {
+		return nil, fmt.Errorf("a focus session is already running")
+	}
+	session, err := startFocusSession(config, mode, minutes, autoRestore)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachSessionHooks(session, config, sessionOptions{}); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Run hides the session's shortcuts and counts down until the session completes or is
+// stopped, handling commands such as "p" (pause), "w" (show or hide the countdown widget)
+// and "q" (stop) read from commands
+// commands may be nil when the session is only controlled through Stop
+func (fs *FocusSession) Run(commands <-chan string) error {
+	if err := guardWrite("start a focus session"); err != nil {
+		return err
+	}
+	return fs.run(commands)
+}
@@ -0,0 +1,51 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMover tests applying and restoring modes through the exported API, with errors
+// returned instead of exiting
+func TestMover(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	os.MkdirAll(desktop, 0755)
+	for _, name := range []string{"Steam.lnk", "Notes.txt"} {
+		os.WriteFile(filepath.Join(desktop, name), nil, 0644)
+	}
+
+	mover := NewMover(&Config{
+		Modes:       map[string]ModeConfig{"gamemode": {Destination: "Games", Shortcuts: []string{"Steam.lnk"}}},
+		DefaultMode: "gamemode",
+	})
+	if err := mover.Apply("nosuch"); err == nil || !strings.Contains(err.Error(), "nosuch") {
+		t.Errorf("Expected an error for an unknown mode, got %v", err)
+	}
+
+	if err := mover.Apply(""); err != nil {
+		t.Fatalf("Apply() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Games", "Steam.lnk")); err != nil {
+		t.Errorf("Expected Steam.lnk in Games: %v", err)
+	}
+	if modes, err := mover.AppliedModes(); err != nil || !reflect.DeepEqual(modes, []string{"gamemode"}) {
+		t.Errorf("Expected gamemode applied, got %v (err %v)", modes, err)
+	}
+
+	if err := mover.Restore("gamemode"); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Steam.lnk")); err != nil {
+		t.Errorf("Expected Steam.lnk back on the desktop: %v", err)
+	}
+	if modes, err := mover.AppliedModes(); err != nil || len(modes) != 0 {
+		t.Errorf("Expected no modes applied, got %v (err %v)", modes, err)
+	}
+}
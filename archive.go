@@ -0,0 +1,400 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveConfig represents the settings of the weekly sweep of stale desktop files
+type ArchiveConfig struct {
+	Enabled bool   `yaml:"enabled" doc:"Archive stale desktop files every Sunday from focusmode daemon" default:"false"`
+	Days    int    `yaml:"days" doc:"Days a file must go unmodified to be archived" default:"14"`
+	Folder  string `yaml:"folder" doc:"Folder in the home directory holding a dated folder for each sweep" default:"Archive"`
+
+	Retention map[string]int `yaml:"retention" doc:"Days archived files of each category from categories.yml are kept before they are deleted; categories not listed are kept forever" example:"{installers: 7}"`
+}
+
+const (
+	defaultArchiveDays   = 14
+	defaultArchiveFolder = "Archive"
+	archivePollInterval  = time.Minute
+	archiveRetryInterval = time.Hour
+	archiveDateLayout    = "2006-01-02"
+)
+
+// days returns how long a file must go unmodified to be archived
+func (c ArchiveConfig) days() int {
+	if c.Days <= 0 {
+		return defaultArchiveDays
+	}
+	return c.Days
+}
+
+// root returns the folder holding the dated folders of every sweep, e.g. ~/Archive
+func (c ArchiveConfig) root() (string, error) {
+	root, err := hiddenFoldersRoot()
+	if err != nil {
+		return "", err
+	}
+	folder := c.Folder
+	if folder == "" {
+		folder = defaultArchiveFolder
+	}
+	return filepath.Join(root, folder), nil
+}
+
+// folder returns the folder of an archive sweep made at now, e.g. ~/Archive/2026-10-18
+func (c ArchiveConfig) folder(now time.Time) (string, error) {
+	root, err := c.root()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, now.Format(archiveDateLayout)), nil
+}
+
+// staleDesktopFiles returns the files on the desktop last modified before cutoff, leaving out
+// metadata files, folders and the shortcuts a mode names, which applying the mode looks for
+func staleDesktopFiles(desktopPath string, cutoff time.Time, keep []string) ([]string, error) {
+	names, err := folderEntries(desktopPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading desktop directory: %w", err)
+	}
+	var stale []string
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(desktopPath, name))
+		if err != nil || info.IsDir() || !info.ModTime().Before(cutoff) || containsFold(keep, name) {
+			continue
+		}
+		stale = append(stale, name)
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// modeShortcuts returns the shortcuts named by every mode
+func (c *Config) modeShortcuts() []string {
+	var names []string
+	for _, mode := range c.Modes {
+		names = append(names, mode.Shortcuts...)
+	}
+	return names
+}
+
+// archiveDesktop moves the stale desktop files into the dated archive folder for now and
+// records the sweep as one operation, so a single undo puts every file back
+// The moves made are returned; a file whose name is already in the folder is left alone
+func archiveDesktop(config *Config, now time.Time, dryRun bool) ([]fileMove, error) {
+	op := &archiveOperation{config: config, now: now, dryRun: dryRun}
+	report := &operationReport{Action: "move", Verb: "moved"}
+	if err := runOperation(config, op, report, dryRun); err != nil {
+		return nil, err
+	}
+	return report.Done, nil
+}
+
+// archiveOperation sweeps stale desktop files into the archive, all of them or none
+type archiveOperation struct {
+	config *Config
+	now    time.Time
+	dryRun bool
+	folder string
+}
+
+// Plan lists the desktop files unmodified for archive.days
+func (op *archiveOperation) Plan() ([]fileMove, error) {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return nil, err
+	}
+	op.folder, err = op.config.Archive.folder(op.now)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := op.now.AddDate(0, 0, -op.config.Archive.days())
+	// Pinned files would only be skipped one by one, leaving nothing to record
+	keep := append(append([]string(nil), op.config.modeShortcuts()...), op.config.pinned()...)
+	names, err := staleDesktopFiles(desktopPath, cutoff, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	label := filepath.Base(op.folder)
+	var moves []fileMove
+	for _, name := range names {
+		if _, ok := findFileName(op.folder, name); ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s is already in %s; left on the desktop\n", name, op.folder)
+			continue
+		}
+		moves = append(moves, fileMove{Name: name, Mode: label, From: desktopPath, To: op.folder})
+	}
+	if len(moves) == 0 {
+		return nil, errNothingToDo
+	}
+	return moves, nil
+}
+
+// Execute moves one file into the archive folder
+func (op *archiveOperation) Execute(move *fileMove) (stepOutcome, string, error) {
+	if err := moveOne(op.config, move); err != nil {
+		return stepDone, "", err
+	}
+	return stepDone, fmt.Sprintf("%sMoved: %s -> %s", glyph("✓ "), move.Name, move.To), nil
+}
+
+// Rollback puts the files archived so far back on the desktop, as a sweep is all or nothing
+func (op *archiveOperation) Rollback(done []fileMove) bool {
+	rollbackMoves(done)
+	return true
+}
+
+// Finish records the sweep for undo and as the last sweep made
+func (op *archiveOperation) Finish(report *operationReport) {
+	if op.dryRun {
+		return
+	}
+	if err := updateArchiveState(func(state *archiveState) { state.LastSweep = op.now }); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	// Every file may have been skipped, such as a pinned one, leaving nothing to undo
+	if len(report.Done) == 0 {
+		return
+	}
+	ownByUser(filepath.Dir(op.folder))
+	ownByUser(op.folder)
+	recordOperation(operationArchive, filepath.Base(op.folder), report.Done, "")
+	report.DoneLine = archiveSummary(report.Done, op.config.Archive.days())
+}
+
+// archiveSummary describes a sweep for the notification sent after it, or "" if it moved nothing
+func archiveSummary(moves []fileMove, days int) string {
+	if len(moves) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Archived %d desktop file(s) unmodified for %d+ days to %s; run 'focusmode archive undo' to put them back",
+		len(moves), days, moves[0].To)
+}
+
+// archiveState records when the desktop was last swept and the archive last purged
+type archiveState struct {
+	LastSweep time.Time `json:"last_sweep"`
+	LastPurge time.Time `json:"last_purge,omitempty"`
+}
+
+// archiveStatePath returns the path of the archive sweep state
+func archiveStatePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive.json"), nil
+}
+
+// readArchiveState returns when the desktop was last swept and the archive last purged,
+// with zero times for never
+func readArchiveState() (archiveState, error) {
+	var state archiveState
+	path, err := archiveStatePath()
+	if err != nil {
+		return state, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading archive state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error parsing archive state: %w", err)
+	}
+	return state, nil
+}
+
+// updateArchiveState changes the recorded archive state with update
+func updateArchiveState(update func(*archiveState)) error {
+	state, err := readArchiveState()
+	if err != nil {
+		return err
+	}
+	update(&state)
+	path, err := archiveStatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding archive state: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing archive state: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// archiveDue reports whether a weekly sweep is due at now, given the last one
+// A sweep is due from Sunday midnight until one is made; a Sunday missed while the machine
+// was off is made up later in the week, but the first sweep waits for a Sunday
+func archiveDue(last, now time.Time) bool {
+	sunday := at(now.AddDate(0, 0, -int(now.Weekday())), 0)
+	if last.IsZero() {
+		return now.Weekday() == time.Sunday
+	}
+	return last.Before(sunday)
+}
+
+// watchArchive sweeps stale desktop files once a week and purges archived files past their
+// retention once a day until ctx is cancelled; run by the daemon
+// mu is held for each sweep so it can't interleave with other moves
+func watchArchive(ctx context.Context, config *Config, mu sync.Locker, beat func()) {
+	notifiers := []Notifier{consoleNotifier{}}
+	fmt.Printf("Archiving desktop files unmodified for %d+ days every Sunday\n", config.Archive.days())
+	var failed time.Time
+	for {
+		beat()
+		state, err := readArchiveState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else if now := time.Now(); archiveDue(state.LastSweep, now) && now.Sub(failed) >= archiveRetryInterval {
+			_, span := startSpan(ctx, "archive sweep")
+			var moves []fileMove
+			withLock(mu, func() { moves, err = archiveDesktop(config, now, false) })
+			span.set("focusmode.archived", len(moves))
+			span.finish(err)
+			switch {
+			case err != nil:
+				// Tried again later rather than on every check
+				failed = now
+				fmt.Fprintf(os.Stderr, "Error archiving desktop files: %v\n", err)
+			case len(moves) > 0:
+				notifyAll(notifiers, "Desktop archived", archiveSummary(moves, config.Archive.days()))
+			default:
+				// Nothing stale; don't look again until next week
+				if err := updateArchiveState(func(state *archiveState) { state.LastSweep = now }); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+		} else if len(config.Archive.Retention) > 0 && purgeDue(state.LastPurge, now) {
+			_, span := startSpan(ctx, "archive purge")
+			var purged []archivedFile
+			withLock(mu, func() { purged = purgeArchiveNow(config, now) })
+			span.set("focusmode.purged", len(purged))
+			span.finish(nil)
+			if len(purged) > 0 {
+				notifyAll(notifiers, "Archive purged", purgeSummary(purged))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(archivePollInterval):
+		}
+	}
+}
+
+// lastArchive returns the index in the operation log of the most recent archive sweep, or -1
+func lastArchive(log *operationLog) int {
+	for i := len(log.Done) - 1; i >= 0; i-- {
+		if log.Done[i].Kind == operationArchive {
+			return i
+		}
+	}
+	return -1
+}
+
+// undoArchive puts back every file of the most recent sweep, even when other operations
+// came after it; undone as the last operation, it can be redone like any other
+func undoArchive(config *Config, dryRun bool) error {
+	path, err := operationLogPath()
+	if err != nil {
+		return err
+	}
+	log, err := readOperationLog(path)
+	if err != nil {
+		return err
+	}
+	i := lastArchive(log)
+	if i < 0 {
+		fmt.Println("No archive sweep to undo.")
+		return nil
+	}
+	op := log.Done[i]
+	if err := replayOperation(config, "undo", op, op.reversed(), dryRun); err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Println("(Dry run - no files were actually moved)")
+		return nil
+	}
+	if i == len(log.Done)-1 {
+		log.Undone = append(log.Undone, op)
+	}
+	log.Done = append(log.Done[:i], log.Done[i+1:]...)
+	return writeOperationLog(path, log)
+}
+
+// runArchiveCommand handles "archive", which sweeps stale desktop files now, and its
+// undo, purge and report subcommands
+func runArchiveCommand(args []string) {
+	sub := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("archive", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be moved or deleted without doing it")
+	days := flags.Int("days", 0, "Archive files unmodified for this many days (default archive.days); for report, how many days ahead to look")
+	flags.Parse(args)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	switch sub {
+	case "":
+	case "undo":
+		if err := undoArchive(config, *dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "purge", "report":
+		categories, err := loadCategoriesConfig(*categoriesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading categories: %v\n", err)
+			os.Exit(1)
+		}
+		if sub == "purge" {
+			runArchivePurge(config, categories, *dryRun)
+		} else {
+			runArchiveReport(config, categories, *days)
+		}
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown archive command %q (use undo, purge or report)\n", sub)
+		os.Exit(1)
+	}
+
+	if *days > 0 {
+		config.Archive.Days = *days
+	}
+	moves, err := archiveDesktop(config, time.Now(), *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(moves) == 0 {
+		fmt.Printf("No desktop files unmodified for %d+ days.\n", config.Archive.days())
+	}
+}
@@ -0,0 +1,134 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestArchiveDue tests that a sweep is due once a week from Sunday, and made up after a missed one
+func TestArchiveDue(t *testing.T) {
+	tests := []struct {
+		last time.Time
+		now  time.Time
+		want bool
+	}{
+		{time.Time{}, scheduleTime(18, 9, 0), true},               // Never swept, Sunday
+		{time.Time{}, scheduleTime(14, 9, 0), false},              // Never swept, Wednesday: waits for Sunday
+		{scheduleTime(18, 9, 0), scheduleTime(18, 15, 0), false},  // Swept this morning
+		{scheduleTime(18, 9, 0), scheduleTime(21, 9, 0), false},   // Swept this week
+		{scheduleTime(11, 9, 0), scheduleTime(18, 0, 1), true},    // Last Sunday's sweep; new week
+		{scheduleTime(4, 9, 0), scheduleTime(14, 9, 0), true},     // Missed a Sunday: made up on Wednesday
+		{scheduleTime(17, 23, 59), scheduleTime(18, 0, 0), true},  // Saturday night's doesn't count
+		{scheduleTime(12, 9, 0), scheduleTime(17, 23, 0), false},  // Monday's sweep covers the week
+		{scheduleTime(11, 0, 0), scheduleTime(17, 23, 59), false}, // Sunday midnight counts
+	}
+	for _, tt := range tests {
+		if got := archiveDue(tt.last, tt.now); got != tt.want {
+			t.Errorf("archiveDue(%s, %s) = %v, want %v", tt.last.Format("Mon 02 15:04"), tt.now.Format("Mon 02 15:04"), got, tt.want)
+		}
+	}
+}
+
+// TestArchiveDesktop tests that stale files are swept into a dated folder and one undo puts them all back
+func TestArchiveDesktop(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	desktop := filepath.Join(dir, "Desktop")
+	os.MkdirAll(filepath.Join(desktop, "Old folder"), 0755)
+	now := scheduleTime(18, 9, 0)
+	old := now.AddDate(0, 0, -20)
+	for _, name := range []string{"notes.txt", "report.pdf", "Steam.lnk", "fresh.txt", ".DS_Store"} {
+		path := filepath.Join(desktop, name)
+		os.WriteFile(path, nil, 0644)
+		if name != "fresh.txt" {
+			os.Chtimes(path, old, old)
+		}
+	}
+	os.Chtimes(filepath.Join(desktop, "Old folder"), old, old)
+
+	config := &Config{Modes: map[string]ModeConfig{"gamemode": {Shortcuts: []string{"steam.lnk"}}}}
+	stale, err := staleDesktopFiles(desktop, now.AddDate(0, 0, -14), config.modeShortcuts())
+	if err != nil || !reflect.DeepEqual(stale, []string{"notes.txt", "report.pdf"}) {
+		t.Fatalf("Expected notes.txt and report.pdf to be stale, got %v (err %v)", stale, err)
+	}
+
+	moves, err := archiveDesktop(config, now, false)
+	if err != nil || len(moves) != 2 {
+		t.Fatalf("Expected two files archived, got %v (err %v)", moves, err)
+	}
+	folder := filepath.Join(dir, "Archive", "2026-10-18")
+	for _, name := range []string{"notes.txt", "report.pdf"} {
+		if _, err := os.Stat(filepath.Join(folder, name)); err != nil {
+			t.Errorf("Expected %s in %s: %v", name, folder, err)
+		}
+	}
+	if state, err := readArchiveState(); err != nil || !state.LastSweep.Equal(now) {
+		t.Errorf("Expected the sweep to be recorded at %s, got %s (err %v)", now, state.LastSweep, err)
+	}
+
+	// Another operation after the sweep doesn't stop it being undone
+	recordOperation(operationApply, "gamemode", []fileMove{{Name: "Steam.lnk", Mode: "gamemode", From: desktop, To: filepath.Join(dir, "Games")}}, "")
+	if err := undoArchive(config, false); err != nil {
+		t.Fatalf("undoArchive() returned error: %v", err)
+	}
+	for _, name := range []string{"notes.txt", "report.pdf"} {
+		if _, err := os.Stat(filepath.Join(desktop, name)); err != nil {
+			t.Errorf("Expected %s back on the desktop: %v", name, err)
+		}
+	}
+	path, _ := operationLogPath()
+	log, err := readOperationLog(path)
+	if err != nil || len(log.Done) != 1 || log.Done[0].Kind != operationApply || lastArchive(log) != -1 {
+		t.Errorf("Expected only the apply left in the log, got %+v (err %v)", log, err)
+	}
+	path, _ = appliedModesPath()
+	if modes, _ := readAppliedModes(path); len(modes) != 0 {
+		t.Errorf("Expected the undo to leave the applied modes alone, got %+v", modes)
+	}
+}
+
+// TestArchivePinnedOnly tests a sweep where every stale file is pinned: nothing moves or is recorded
+func TestArchivePinnedOnly(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	os.MkdirAll(desktop, 0755)
+	config := &Config{Pinned: []string{"Notes.txt"}}
+
+	now := scheduleTime(18, 9, 0)
+	old := now.AddDate(0, 0, -20)
+	path := filepath.Join(desktop, "Notes.txt")
+	os.WriteFile(path, nil, 0644)
+	os.Chtimes(path, old, old)
+
+	moves, err := archiveDesktop(config, now, false)
+	if err != nil || len(moves) != 0 {
+		t.Fatalf("archiveDesktop() = %v, %v; want nothing archived", moves, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the pinned file left on the desktop: %v", err)
+	}
+
+	// A sweep whose every move was skipped finishes without a record or summary
+	op := &archiveOperation{config: config, now: now, folder: filepath.Join(dir, "Archive", "2026-10-18")}
+	report := &operationReport{Action: "move", Verb: "moved"}
+	op.Finish(report)
+	if report.DoneLine != "" {
+		t.Errorf("Expected no summary, got %q", report.DoneLine)
+	}
+	logPath, _ := operationLogPath()
+	if log, err := readOperationLog(logPath); err != nil || len(log.Done) != 0 {
+		t.Errorf("Expected no sweep recorded, got %+v (err %v)", log, err)
+	}
+	if archiveSummary(nil, 14) != "" {
+		t.Error("Expected no summary of an empty sweep")
+	}
+}
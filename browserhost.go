@@ -0,0 +1,600 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// browserHostName is the native messaging host the companion extension connects to,
+// e.g. with chrome.runtime.connectNative("com.focusmode.host")
+const browserHostName = "com.focusmode.host"
+
+// browserProtocolVersion is sent in every state message; it changes when fields change meaning
+const browserProtocolVersion = 1
+
+// browserMessageLimit is the largest message accepted from the extension; browsers cap
+// messages sent to them at 1 MB as well
+const browserMessageLimit = 1 << 20
+
+// browserPollInterval is how often a subscribed extension is sent changes to the state
+const browserPollInterval = 2 * time.Second
+
+// siteHostPattern is a host name in a block_sites entry, in lower case
+var siteHostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// siteRule is a parsed block_sites entry: a host with its subdomains, optionally limited to a path
+type siteRule struct {
+	Rule string // As written in the config
+	Host string
+	Path string // Without a trailing slash; empty for the whole site
+	Mode string // Mode the entry belongs to
+}
+
+// parseSiteRule parses a block_sites entry such as "reddit.com", "*.reddit.com",
+// "https://www.youtube.com/shorts" or "youtube.com/shorts"
+func parseSiteRule(rule string) (siteRule, error) {
+	s := strings.TrimSpace(rule)
+	for _, scheme := range []string{"https://", "http://"} {
+		if len(s) >= len(scheme) && strings.EqualFold(s[:len(scheme)], scheme) {
+			s = s[len(scheme):]
+		}
+	}
+	host, path, _ := strings.Cut(s, "/")
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(host), "*."), ".")
+	if !siteHostPattern.MatchString(host) {
+		return siteRule{}, fmt.Errorf("block_sites entry %q isn't a site such as reddit.com or youtube.com/shorts", rule)
+	}
+	if path = strings.TrimSuffix(path, "/"); path != "" {
+		path = "/" + path
+	}
+	return siteRule{Rule: rule, Host: host, Path: path}, nil
+}
+
+// matches reports whether the rule covers a page with the given lower-case host and path
+func (r siteRule) matches(host, path string) bool {
+	if host != r.Host && !strings.HasSuffix(host, "."+r.Host) {
+		return false
+	}
+	return r.Path == "" || path == r.Path || strings.HasPrefix(path, r.Path+"/")
+}
+
+// blockedSites returns the sites blocked by the applied modes and the running session's mode,
+// skipping entries that don't parse; config validate reports those
+// A session on a break blocks nothing of its own, as no mode is applied for it
+func blockedSites(config *Config, status *statusReport) []siteRule {
+	var modes []string
+	for _, m := range status.Modes {
+		modes = append(modes, m.Mode)
+	}
+	if s := status.Session; s != nil && !s.Break && s.Mode != "" {
+		modes = append(modes, s.Mode)
+	}
+
+	var rules []siteRule
+	seen := make(map[string]bool)
+	for _, mode := range modes {
+		for _, entry := range config.Modes[mode].BlockSites {
+			rule, err := parseSiteRule(entry)
+			if err != nil || seen[rule.Host+rule.Path] {
+				continue
+			}
+			seen[rule.Host+rule.Path] = true
+			rule.Mode = mode
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// siteBlocked returns the rule blocking a page, if any; only web pages are ever blocked
+func siteBlocked(rules []siteRule, rawURL string) (siteRule, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return siteRule{}, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, rule := range rules {
+		if rule.matches(host, u.EscapedPath()) {
+			return rule, true
+		}
+	}
+	return siteRule{}, false
+}
+
+// browserRequest is a message from the extension
+// type is "state" for the current state, "check" with url for whether a page is blocked,
+// "subscribe" for the state now and again each time it changes, or "ping"
+type browserRequest struct {
+	ID   json.RawMessage `json:"id,omitempty"` // Echoed in the reply, to match replies to requests
+	Type string          `json:"type"`
+	URL  string          `json:"url,omitempty"`
+}
+
+// browserSession is the running session as the extension sees it
+type browserSession struct {
+	Mode             string    `json:"mode"`
+	Goal             string    `json:"goal,omitempty"`
+	Break            bool      `json:"break,omitempty"`
+	Strict           bool      `json:"strict,omitempty"`
+	Paused           bool      `json:"paused,omitempty"`
+	RemainingSeconds int64     `json:"remaining_seconds"`
+	EndsAt           time.Time `json:"ends_at"` // If it isn't paused again
+}
+
+// browserBlock is a block_sites entry in force
+type browserBlock struct {
+	Host string `json:"host"` // Also covers its subdomains
+	Path string `json:"path,omitempty"`
+	Mode string `json:"mode"`
+}
+
+// browserState is what the extension enforces: the sites to block and the session to show
+type browserState struct {
+	Version    int             `json:"version"`
+	Modes      []string        `json:"modes"` // Applied modes, bottom layer first
+	Session    *browserSession `json:"session"`
+	BlockSites []browserBlock  `json:"block_sites"`
+	AllowSites []browserBlock  `json:"allow_sites,omitempty"` // During allowlist sessions, the only sites expected
+}
+
+// browserCheck answers a check request
+type browserCheck struct {
+	URL     string `json:"url"`
+	Blocked bool   `json:"blocked"`
+	Rule    string `json:"rule,omitempty"` // The block_sites entry as written
+	Mode    string `json:"mode,omitempty"`
+	// The page is on none of the allowlist session's sites, which the extension warns about
+	OutsideAllowlist bool `json:"outside_allowlist,omitempty"`
+}
+
+// browserReply is a message to the extension; type is "state", "check", "pong" or "error"
+// State replies, and the messages a subscription pushes, carry the state's fields
+type browserReply struct {
+	ID   json.RawMessage `json:"id,omitempty"`
+	Type string          `json:"type"`
+	*browserState
+	*browserCheck
+	Error string `json:"error,omitempty"`
+}
+
+// readBrowserMessage reads one native messaging message: a 32-bit length in native byte
+// order followed by that much JSON. It returns io.EOF when the browser closes the pipe
+func readBrowserMessage(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if size > browserMessageLimit {
+		return nil, fmt.Errorf("message of %d bytes is over the %d byte limit", size, browserMessageLimit)
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, fmt.Errorf("error reading message: %w", err)
+	}
+	return message, nil
+}
+
+// writeBrowserMessage writes v as one native messaging message
+func writeBrowserMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > browserMessageLimit {
+		return fmt.Errorf("message of %d bytes is over the %d byte limit", len(data), browserMessageLimit)
+	}
+	var message bytes.Buffer
+	binary.Write(&message, binary.NativeEndian, uint32(len(data)))
+	message.Write(data)
+	_, err = w.Write(message.Bytes())
+	return err
+}
+
+// browserHost answers the companion extension over the pipes the browser starts it with
+// It only reads the config and state files, so block_sites edits apply without a restart
+type browserHost struct {
+	configPath string
+	now        func() time.Time
+	mu         sync.Mutex // serializes writes, which the subscription also makes
+	out        io.Writer
+}
+
+// state reads what the extension should enforce now
+func (h *browserHost) state() (*browserState, []siteRule, error) {
+	config, err := loadConfig(h.configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	status, err := currentStatus()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := &browserState{Version: browserProtocolVersion, Modes: []string{}, BlockSites: []browserBlock{}}
+	for _, m := range status.Modes {
+		state.Modes = append(state.Modes, m.Mode)
+	}
+	if s := status.Session; s != nil {
+		remaining := s.remaining(h.now())
+		state.Session = &browserSession{
+			Mode:             s.Mode,
+			Goal:             s.Goal,
+			Break:            s.Break,
+			Strict:           s.Strict,
+			Paused:           s.PausedAt != nil,
+			RemainingSeconds: int64(remaining.Round(time.Second) / time.Second),
+			EndsAt:           h.now().Add(remaining).Truncate(time.Second),
+		}
+	}
+	if s := status.Session; s != nil && !s.Break {
+		for _, entry := range s.AllowSites {
+			if rule, err := parseSiteRule(entry); err == nil {
+				state.AllowSites = append(state.AllowSites, browserBlock{Host: rule.Host, Path: rule.Path, Mode: s.Mode})
+			}
+		}
+	}
+	rules := blockedSites(config, status)
+	for _, rule := range rules {
+		state.BlockSites = append(state.BlockSites, browserBlock{Host: rule.Host, Path: rule.Path, Mode: rule.Mode})
+	}
+	return state, rules, nil
+}
+
+// reply answers one request
+func (h *browserHost) reply(req browserRequest) browserReply {
+	reply := browserReply{ID: req.ID, Type: req.Type}
+	switch req.Type {
+	case "ping":
+		reply.Type = "pong"
+		return reply
+	case "state", "subscribe", "check":
+	default:
+		return browserReply{ID: req.ID, Type: "error", Error: fmt.Sprintf("unknown message type %q", req.Type)}
+	}
+
+	state, rules, err := h.state()
+	if err != nil {
+		return browserReply{ID: req.ID, Type: "error", Error: err.Error()}
+	}
+	if req.Type != "check" {
+		reply.Type = "state"
+		reply.browserState = state
+		return reply
+	}
+	reply.browserCheck = &browserCheck{URL: req.URL}
+	if rule, blocked := siteBlocked(rules, req.URL); blocked {
+		reply.browserCheck.Blocked, reply.browserCheck.Rule, reply.browserCheck.Mode = true, rule.Rule, rule.Mode
+	}
+	var allowed []siteRule
+	for _, site := range state.AllowSites {
+		allowed = append(allowed, siteRule{Host: site.Host, Path: site.Path})
+	}
+	reply.browserCheck.OutsideAllowlist = outsideAllowlist(allowed, req.URL)
+	return reply
+}
+
+// send writes a message to the extension
+func (h *browserHost) send(v any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return writeBrowserMessage(h.out, v)
+}
+
+// stateFingerprint returns what tells one state from another for a subscription
+// The remaining time changes every tick and the extension counts it down itself,
+// so a running session is compared by when it ends and a paused one by what is left
+func stateFingerprint(state *browserState) []byte {
+	compare := *state
+	if compare.Session != nil {
+		session := *compare.Session
+		if session.Paused {
+			session.EndsAt = time.Time{}
+		} else {
+			session.RemainingSeconds = 0
+		}
+		compare.Session = &session
+	}
+	data, _ := json.Marshal(compare)
+	return data
+}
+
+// subscribe sends the state each time it differs from the last one sent, until ctx ends
+func (h *browserHost) subscribe(ctx context.Context, last []byte, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reply := h.reply(browserRequest{Type: "state"})
+		if reply.browserState != nil {
+			data := stateFingerprint(reply.browserState)
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+		}
+		if err := h.send(reply); err != nil {
+			return
+		}
+	}
+}
+
+// serve answers messages from in until the browser closes it
+func (h *browserHost) serve(in io.Reader, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscribed := false
+	for {
+		message, err := readBrowserMessage(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req browserRequest
+		if err := json.Unmarshal(message, &req); err != nil {
+			if err := h.send(browserReply{Type: "error", Error: fmt.Sprintf("invalid message: %v", err)}); err != nil {
+				return err
+			}
+			continue
+		}
+		reply := h.reply(req)
+		if err := h.send(reply); err != nil {
+			return err
+		}
+		if req.Type == "subscribe" && !subscribed && reply.browserState != nil {
+			subscribed = true
+			go h.subscribe(ctx, stateFingerprint(reply.browserState), interval)
+		}
+	}
+}
+
+// browserTarget is where a browser looks for native messaging hosts
+type browserTarget struct {
+	firefox  bool   // Firefox names extensions by ID rather than by origin
+	linux    string // Manifest folder under the home folder
+	darwin   string
+	registry string // Windows registry key under HKCU holding a key per host
+}
+
+// browserTargets are the browsers the host can be installed for
+var browserTargets = map[string]browserTarget{
+	"chrome": {
+		linux:    ".config/google-chrome/NativeMessagingHosts",
+		darwin:   "Library/Application Support/Google/Chrome/NativeMessagingHosts",
+		registry: `Software\Google\Chrome\NativeMessagingHosts`,
+	},
+	"chromium": {
+		linux:    ".config/chromium/NativeMessagingHosts",
+		darwin:   "Library/Application Support/Chromium/NativeMessagingHosts",
+		registry: `Software\Chromium\NativeMessagingHosts`,
+	},
+	"edge": {
+		linux:    ".config/microsoft-edge/NativeMessagingHosts",
+		darwin:   "Library/Application Support/Microsoft Edge/NativeMessagingHosts",
+		registry: `Software\Microsoft\Edge\NativeMessagingHosts`,
+	},
+	"brave": {
+		linux:    ".config/BraveSoftware/Brave-Browser/NativeMessagingHosts",
+		darwin:   "Library/Application Support/BraveSoftware/Brave-Browser/NativeMessagingHosts",
+		registry: `Software\BraveSoftware\Brave-Browser\NativeMessagingHosts`,
+	},
+	"firefox": {
+		firefox:  true,
+		linux:    ".mozilla/native-messaging-hosts",
+		darwin:   "Library/Application Support/Mozilla/NativeMessagingHosts",
+		registry: `Software\Mozilla\NativeMessagingHosts`,
+	},
+}
+
+// chromeExtensionIDPattern is the form of Chrome, Edge and Brave extension IDs
+var chromeExtensionIDPattern = regexp.MustCompile(`^[a-p]{32}$`)
+
+// browserManifest returns the host manifest that lets the given extension start launcher
+func browserManifest(target browserTarget, extensionID, launcher string) ([]byte, error) {
+	manifest := map[string]any{
+		"name":        browserHostName,
+		"description": "FocusMode session state and site blocking",
+		"path":        launcher,
+		"type":        "stdio",
+	}
+	if target.firefox {
+		if extensionID == "" || strings.ContainsAny(extensionID, " \t\r\n") {
+			return nil, fmt.Errorf("invalid Firefox extension ID %q", extensionID)
+		}
+		manifest["allowed_extensions"] = []string{extensionID}
+	} else {
+		if !chromeExtensionIDPattern.MatchString(extensionID) {
+			return nil, fmt.Errorf("invalid extension ID %q (expected 32 letters a to p, as shown on the extensions page)", extensionID)
+		}
+		manifest["allowed_origins"] = []string{"chrome-extension://" + extensionID + "/"}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// browserLauncher returns the script browsers start as the host; manifests can't pass
+// arguments, so the script passes the config file
+func browserLauncher(goos, executable, configPath string) (name string, script []byte) {
+	if goos == "windows" {
+		return "focusmode-host.bat", []byte(fmt.Sprintf("@echo off\r\n\"%s\" browser-host -config \"%s\" %%*\r\n", executable, configPath))
+	}
+	return "focusmode-host", []byte(fmt.Sprintf("#!/bin/sh\nexec %s browser-host -config %s \"$@\"\n", shellQuote(executable), shellQuote(configPath)))
+}
+
+// browserManifestPath returns where the host manifest for a browser goes; on Windows the
+// manifest sits in the data directory and the registry points to it
+func browserManifestPath(ctx *userContext, name string, target browserTarget) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		dir, err := dataDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "browser", browserHostName+"-"+name+".json"), nil
+	case "darwin":
+		return filepath.Join(ctx.Home, filepath.FromSlash(target.darwin), browserHostName+".json"), nil
+	default:
+		return filepath.Join(ctx.Home, filepath.FromSlash(target.linux), browserHostName+".json"), nil
+	}
+}
+
+// installBrowserHost writes the launcher and manifest for a browser and, on Windows, registers it
+func installBrowserHost(name, extensionID, configPath string) (string, error) {
+	target, ok := browserTargets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown browser %q (expected %s)", name, strings.Join(browserNames(), ", "))
+	}
+	ctx, err := currentUserContext()
+	if err != nil {
+		return "", err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("error locating executable: %w", err)
+	}
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+
+	launcherName, script := browserLauncher(runtime.GOOS, executable, configPath)
+	launcher := filepath.Join(dir, "browser", launcherName)
+	manifest, err := browserManifest(target, extensionID, launcher)
+	if err != nil {
+		return "", err
+	}
+	manifestPath, err := browserManifestPath(ctx, name, target)
+	if err != nil {
+		return "", err
+	}
+	for _, folder := range []string{filepath.Dir(launcher), filepath.Dir(manifestPath)} {
+		if err := mkdirAll(folder, 0755); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFile(launcher, script, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFile(manifestPath, manifest, 0644); err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		key := shellFoldersRoot(ctx) + `\` + target.registry + `\` + browserHostName
+		if out, err := exec.Command("reg", "add", key, "/ve", "/t", "REG_SZ", "/d", manifestPath, "/f").CombinedOutput(); err != nil {
+			return "", fmt.Errorf("error registering %s: %v: %s", key, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return manifestPath, nil
+}
+
+// uninstallBrowserHost removes a browser's manifest and registration; the launcher stays
+// for the other browsers
+func uninstallBrowserHost(name string) (string, error) {
+	target, ok := browserTargets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown browser %q (expected %s)", name, strings.Join(browserNames(), ", "))
+	}
+	ctx, err := currentUserContext()
+	if err != nil {
+		return "", err
+	}
+	manifestPath, err := browserManifestPath(ctx, name, target)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		key := shellFoldersRoot(ctx) + `\` + target.registry + `\` + browserHostName
+		exec.Command("reg", "delete", key, "/f").Run()
+	}
+	if err := removeFile(manifestPath); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return manifestPath, nil
+}
+
+// browserNames returns the browsers the host can be installed for, sorted
+func browserNames() []string {
+	names := make([]string, 0, len(browserTargets))
+	for name := range browserTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runBrowserHostCommand handles "browser-host": install and uninstall set the host up for a
+// browser, and without a subcommand it is the host the browser starts. Browsers pass the
+// extension's origin and other arguments after the flags, which the host ignores
+func runBrowserHostCommand(args []string) {
+	if len(args) > 0 && (args[0] == "install" || args[0] == "uninstall") {
+		flags := flag.NewFlagSet("browser-host "+args[0], flag.ExitOnError)
+		configPath := flags.String("config", "profile.yml", "Path to configuration file the host reads")
+		browser := flags.String("browser", "chrome", "Browser: "+strings.Join(browserNames(), ", "))
+		extensionID := flags.String("extension-id", "", "ID of the companion extension allowed to connect")
+		flags.Parse(args[1:])
+
+		var path string
+		var err error
+		if args[0] == "install" {
+			if *extensionID == "" {
+				fmt.Fprintln(os.Stderr, "Usage: focusmode browser-host install -extension-id ID [-browser chrome] [-config profile.yml]")
+				os.Exit(2)
+			}
+			path, err = installBrowserHost(*browser, *extensionID, *configPath)
+		} else {
+			path, err = uninstallBrowserHost(*browser)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if args[0] == "install" {
+			fmt.Printf("%sInstalled %s for %s: %s\n", glyph("✅ "), browserHostName, *browser, path)
+			fmt.Println("Restart the browser if the extension doesn't connect")
+		} else {
+			fmt.Printf("Removed %s for %s\n", browserHostName, *browser)
+		}
+		return
+	}
+
+	flags := flag.NewFlagSet("browser-host", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args)
+
+	// Stdout carries the messages, so everything else goes to stderr, which browsers log
+	host := &browserHost{configPath: *configPath, now: time.Now, out: os.Stdout}
+	if err := host.serve(os.Stdin, browserPollInterval); err != nil {
+		fmt.Fprintf(os.Stderr, "focusmode browser-host: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,200 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSiteBlocked tests parsing block_sites entries and matching pages against them
+func TestSiteBlocked(t *testing.T) {
+	var rules []siteRule
+	for _, entry := range []string{"Reddit.com", "*.news.ycombinator.com", "https://www.youtube.com/shorts/"} {
+		rule, err := parseSiteRule(entry)
+		if err != nil {
+			t.Fatalf("parseSiteRule(%q) returned error: %v", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+	for _, entry := range []string{"", "reddit com", "ftp://reddit.com", "-reddit.com"} {
+		if _, err := parseSiteRule(entry); err == nil {
+			t.Errorf("Expected parseSiteRule(%q) to fail", entry)
+		}
+	}
+
+	tests := map[string]string{
+		"https://reddit.com/":                    "Reddit.com",
+		"https://old.REDDIT.com/r/golang":        "Reddit.com",
+		"http://notreddit.com/":                  "",
+		"https://news.ycombinator.com/item?id=1": "*.news.ycombinator.com",
+		"https://ycombinator.com/":               "",
+		"https://www.youtube.com/shorts":         "https://www.youtube.com/shorts/",
+		"https://www.youtube.com/shorts/abc":     "https://www.youtube.com/shorts/",
+		"https://www.youtube.com/shortsfeed":     "",
+		"https://youtube.com/shorts/abc":         "",
+		"chrome://extensions":                    "",
+		"file:///home/me/reddit.com":             "",
+	}
+	for page, want := range tests {
+		rule, blocked := siteBlocked(rules, page)
+		if blocked != (want != "") || rule.Rule != want {
+			t.Errorf("siteBlocked(%q) = %q, %v, want %q", page, rule.Rule, blocked, want)
+		}
+	}
+}
+
+// TestBlockedSites tests that applied modes and the session's mode block their sites, but breaks don't
+func TestBlockedSites(t *testing.T) {
+	config := &Config{Modes: map[string]ModeConfig{
+		"focusmode": {BlockSites: []string{"reddit.com", "youtube.com"}},
+		"deepwork":  {BlockSites: []string{"youtube.com", "not a site"}},
+	}}
+	status := &statusReport{
+		Modes:   []appliedMode{{Mode: "focusmode"}},
+		Session: &activeSession{Mode: "deepwork"},
+	}
+	hosts := func(rules []siteRule) []string {
+		var out []string
+		for _, rule := range rules {
+			out = append(out, rule.Mode+":"+rule.Host)
+		}
+		return out
+	}
+	if got, want := hosts(blockedSites(config, status)), []string{"focusmode:reddit.com", "focusmode:youtube.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	status.Modes = nil
+	if got, want := hosts(blockedSites(config, status)), []string{"deepwork:youtube.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	status.Session.Break = true
+	if got := blockedSites(config, status); len(got) != 0 {
+		t.Errorf("Expected nothing blocked during a break, got %v", got)
+	}
+}
+
+// TestBrowserMessageFraming tests the length-prefixed messages browsers exchange with the host
+func TestBrowserMessageFraming(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeBrowserMessage(&buf, map[string]string{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 4+len(`{"type":"ping"}`) {
+		t.Errorf("Expected a 4-byte length before the JSON, got %d bytes", buf.Len())
+	}
+	message, err := readBrowserMessage(&buf)
+	if err != nil || string(message) != `{"type":"ping"}` {
+		t.Errorf("Expected the message back, got %q (err %v)", message, err)
+	}
+	if _, err := readBrowserMessage(&buf); err != io.EOF {
+		t.Errorf("Expected io.EOF once the pipe is empty, got %v", err)
+	}
+	if _, err := readBrowserMessage(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0x7f})); err == nil {
+		t.Error("Expected an oversized message to be refused")
+	}
+}
+
+// TestBrowserHostServe tests answering state, check and unknown requests for a running session
+func TestBrowserHostServe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("AppData", filepath.Join(home, ".config"))
+	configPath := filepath.Join(home, "profile.yml")
+	os.WriteFile(configPath, []byte("modes:\n  focusmode:\n    destination: Hidden\n    block_sites: [reddit.com]\n"), 0644)
+
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	path, err := activeSessionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeActiveSession(path, activeSession{PID: os.Getpid(), Mode: "focusmode", StartTime: now.Add(-10 * time.Minute), DurationSeconds: 1500, Goal: "Write docs"})
+
+	var in bytes.Buffer
+	for _, req := range []string{`{"id":1,"type":"state"}`, `{"id":"a","type":"check","url":"https://www.reddit.com/r/golang"}`, `{"type":"nosuch"}`, `not json`} {
+		binary.Write(&in, binary.NativeEndian, uint32(len(req)))
+		in.WriteString(req)
+	}
+	var out bytes.Buffer
+	host := &browserHost{configPath: configPath, now: func() time.Time { return now }, out: &out}
+	if err := host.serve(&in, time.Hour); err != nil {
+		t.Fatalf("serve() returned error: %v", err)
+	}
+
+	var replies []map[string]any
+	for {
+		message, err := readBrowserMessage(&out)
+		if err != nil {
+			break
+		}
+		var reply map[string]any
+		json.Unmarshal(message, &reply)
+		replies = append(replies, reply)
+	}
+	if len(replies) != 4 {
+		t.Fatalf("Expected 4 replies, got %d: %v", len(replies), replies)
+	}
+
+	state := replies[0]
+	session, _ := state["session"].(map[string]any)
+	if state["type"] != "state" || state["id"] != 1.0 || session["goal"] != "Write docs" || session["remaining_seconds"] != 900.0 {
+		t.Errorf("Unexpected state reply: %v", state)
+	}
+	if blocks, _ := state["block_sites"].([]any); len(blocks) != 1 || !strings.Contains(mustJSON(t, blocks[0]), `"host":"reddit.com"`) {
+		t.Errorf("Expected reddit.com blocked, got %v", state["block_sites"])
+	}
+	if check := replies[1]; check["type"] != "check" || check["id"] != "a" || check["blocked"] != true || check["rule"] != "reddit.com" || check["mode"] != "focusmode" {
+		t.Errorf("Unexpected check reply: %v", check)
+	}
+	for _, reply := range replies[2:] {
+		if reply["type"] != "error" {
+			t.Errorf("Expected an error reply, got %v", reply)
+		}
+	}
+}
+
+// mustJSON encodes v for comparison in tests
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestBrowserManifest tests the host manifests written for Chrome-family browsers and Firefox
+func TestBrowserManifest(t *testing.T) {
+	id := strings.Repeat("abcdefghijklmnop", 2)
+	manifest, err := browserManifest(browserTargets["chrome"], id, "/data/browser/focusmode-host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), `"chrome-extension://`+id+`/"`) || !strings.Contains(string(manifest), `"name": "com.focusmode.host"`) {
+		t.Errorf("Unexpected Chrome manifest:\n%s", manifest)
+	}
+	if _, err := browserManifest(browserTargets["edge"], "focusmode@example.com", "/x"); err == nil {
+		t.Error("Expected a Firefox-style ID to be refused for Edge")
+	}
+	manifest, err = browserManifest(browserTargets["firefox"], "focusmode@example.com", "/x")
+	if err != nil || !strings.Contains(string(manifest), `"allowed_extensions"`) {
+		t.Errorf("Unexpected Firefox manifest %s (err %v)", manifest, err)
+	}
+
+	name, script := browserLauncher("windows", `C:\Tools\focusmode.exe`, `C:\Users\me\profile.yml`)
+	if name != "focusmode-host.bat" || !strings.Contains(string(script), `"C:\Tools\focusmode.exe" browser-host -config "C:\Users\me\profile.yml" %*`) {
+		t.Errorf("Unexpected Windows launcher %s:\n%s", name, script)
+	}
+	name, script = browserLauncher("linux", "/opt/focus mode/focusmode", "/home/me/profile.yml")
+	if name != "focusmode-host" || !strings.Contains(string(script), `exec '/opt/focus mode/focusmode' browser-host -config '/home/me/profile.yml' "$@"`) {
+		t.Errorf("Unexpected launcher %s:\n%s", name, script)
+	}
+}
@@ -0,0 +1,430 @@
+package focusmode
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits on what a bug report takes from logs, so the bundle stays small enough to attach
+const (
+	bugReportLogLines      = 500 // Last lines of the daemon log
+	bugReportHistoryLines  = 50  // Last lines of the session history and distraction log
+	bugReportPreviewLines  = 40  // Lines shown when a file is viewed during review
+	bugReportRedactedValue = "REDACTED"
+)
+
+// bugReportSecretKeys are the config keys whose values never leave the machine
+// headers is a map, often of authorization headers, and is replaced whole
+var bugReportSecretKeys = map[string]bool{
+	"token":         true,
+	"slack_token":   true,
+	"client_secret": true,
+	"api_key":       true,
+	"password":      true,
+	"pin_hash":      true,
+	"headers":       true,
+}
+
+// bugReportSecretFlags are the command-line flags whose values are masked in the last error log
+var bugReportSecretFlags = map[string]bool{
+	"token":         true,
+	"key":           true,
+	"password":      true,
+	"pin":           true,
+	"secret":        true,
+	"client-secret": true,
+}
+
+// bugReportManifests are the state files a bug report includes; anything else in the state
+// folder, such as calendar_token.json and tokens.json, may hold credentials and is left out
+var bugReportManifests = []string{
+	"modes.json",
+	"session.json",
+	"session-queue.json",
+	"operations.json",
+	"schedule.json",
+	"lock.json",
+	"color.json",
+	"archive.json",
+	"launches.json",
+	"public-desktop.json", "restore-at.json", "ide-offer.json",
+	"firewall-rules.json",
+	"dns-blocked.json",
+}
+
+// bugReportFile is one file of a bug report bundle
+type bugReportFile struct {
+	Name string // Path inside the zip, with forward slashes
+	Data []byte
+}
+
+// lastErrorPath returns the path where the trace of the last crash is kept
+func lastErrorPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "last-error.log"), nil
+}
+
+// recordCrash writes the trace of a panic to the last error log, then lets the panic go on
+// It is deferred by Main, so a crash can be attached to a bug report afterwards
+func recordCrash() {
+	value := recover()
+	if value == nil {
+		return
+	}
+	if path, err := lastErrorPath(); err == nil {
+		report := fmt.Sprintf("%s\nfocusmode %s\npanic: %v\n\n%s",
+			time.Now().Format(time.RFC3339), strings.Join(redactArgs(os.Args[1:]), " "), value, debug.Stack())
+		if writeFile(path, []byte(report), 0644) == nil {
+			ownByUser(path)
+		}
+	}
+	panic(value)
+}
+
+// redactArgs returns args with the values of secret flags, such as --token, replaced with REDACTED
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !bugReportSecretFlags[name] {
+			continue
+		}
+		if hasValue {
+			redacted[i] = arg[:strings.Index(arg, "=")+1] + bugReportRedactedValue
+		} else if i+1 < len(redacted) {
+			i++
+			redacted[i] = bugReportRedactedValue
+		}
+	}
+	return redacted
+}
+
+// redactConfigYAML replaces the values of secret keys anywhere in a YAML file with REDACTED
+// It returns the redacted file and the secrets it removed, so they can be scrubbed from logs too
+func redactConfigYAML(data []byte) ([]byte, []string, error) {
+	document, err := parseYAMLDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if document.Kind == 0 {
+		return data, nil, nil
+	}
+	var secrets []string
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if !bugReportSecretKeys[key.Value] {
+					continue
+				}
+				secrets = append(secrets, scalarValues(value)...)
+				*value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: bugReportRedactedValue}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(document)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, nil, err
+	}
+	encoder.Close()
+	return out.Bytes(), secrets, nil
+}
+
+// scalarValues returns the non-empty scalars in a node and below it, skipping map keys
+func scalarValues(node *yaml.Node) []string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value == "" {
+			return nil
+		}
+		return []string{node.Value}
+	case yaml.MappingNode:
+		var values []string
+		for i := 1; i < len(node.Content); i += 2 {
+			values = append(values, scalarValues(node.Content[i])...)
+		}
+		return values
+	}
+	var values []string
+	for _, child := range node.Content {
+		values = append(values, scalarValues(child)...)
+	}
+	return values
+}
+
+// bugReportRedactor scrubs personal details from the text of every file in a report
+type bugReportRedactor struct {
+	Home    string   // Replaced with ~
+	User    string   // Replaced with <user>
+	Secrets []string // Replaced with REDACTED
+}
+
+// redact returns text with the secrets, home directory and user name replaced
+// Short user names are left alone, since they would match inside unrelated words
+func (r bugReportRedactor) redact(text string) string {
+	for _, secret := range r.Secrets {
+		if len(secret) >= 4 {
+			text = strings.ReplaceAll(text, secret, bugReportRedactedValue)
+		}
+	}
+	if r.Home != "" {
+		text = strings.ReplaceAll(text, r.Home, "~")
+	}
+	if len(r.User) >= 3 {
+		text = strings.ReplaceAll(text, r.User, "<user>")
+	}
+	return text
+}
+
+// lastLines returns at most the last n lines of data
+func lastLines(data []byte, n int) []byte {
+	lines := strings.SplitAfter(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return []byte(strings.Join(lines, "") + "\n")
+}
+
+// bugReportSystem describes the machine and the FocusMode build for a report
+func bugReportSystem(now time.Time) []byte {
+	var b strings.Builder
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	fmt.Fprintf(&b, "FocusMode: %s\n", version)
+	fmt.Fprintf(&b, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&b, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Created: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Read-only: %v\n", readOnly)
+	fmt.Fprintf(&b, "Accessible: %v\n", accessible)
+	if targetLocation != "" {
+		fmt.Fprintf(&b, "Location: %s\n", targetLocation)
+	}
+	if targetUser != "" {
+		fmt.Fprintf(&b, "Acting for another user: yes\n")
+	}
+	if desktop, err := getDesktopPath(); err == nil {
+		fmt.Fprintf(&b, "Desktop: %s\n", desktop)
+	} else {
+		fmt.Fprintf(&b, "Desktop: %v\n", err)
+	}
+	return []byte(b.String())
+}
+
+// bugReportConfigCheck reports whether the profile loads and what config validate would say
+func bugReportConfigCheck(configPath string) []byte {
+	config, err := readConfig(configPath)
+	if err != nil {
+		return []byte(fmt.Sprintf("Error loading config: %v\n", err))
+	}
+	warnings := lintConfig(config)
+	if len(warnings) == 0 {
+		return []byte("valid\n")
+	}
+	return []byte("Warning: " + strings.Join(warnings, "\nWarning: ") + "\n")
+}
+
+// collectBugReport gathers the redacted config, recent logs, manifests, system details and the
+// last crash into the files of a report, sorted by name
+// Files that don't exist are left out; ones that can't be read are noted in errors.txt
+func collectBugReport(configPath, categoriesPath string, now time.Time) []bugReportFile {
+	var files []bugReportFile
+	var problems []string
+	var secrets []string
+
+	add := func(name string, data []byte) {
+		files = append(files, bugReportFile{Name: name, Data: data})
+	}
+	read := func(path string) ([]byte, bool) {
+		if path == "" {
+			return nil, false
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				problems = append(problems, err.Error())
+			}
+			return nil, false
+		}
+		return data, true
+	}
+
+	// Config files are only added redacted; one that can't be parsed is left out rather than risk its secrets
+	for _, config := range []struct{ name, path string }{
+		{"config/profile.yml", configPath},
+		{"config/categories.yml", categoriesPath},
+		{"config/policy.yml", machinePolicyPath},
+	} {
+		data, ok := read(config.path)
+		if !ok {
+			continue
+		}
+		redacted, found, err := redactConfigYAML(data)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s left out: %v", config.path, err))
+			continue
+		}
+		secrets = append(secrets, found...)
+		add(config.name, redacted)
+	}
+	add("config/check.txt", bugReportConfigCheck(configPath))
+	add("system.txt", bugReportSystem(now))
+
+	logs := []struct {
+		name  string
+		path  func() (string, error)
+		lines int
+	}{
+		{"logs/daemon.log", daemonLogPath, bugReportLogLines},
+		{"logs/history.jsonl", historyPath, bugReportHistoryLines},
+		{"logs/distractions.jsonl", distractionLogPath, bugReportHistoryLines},
+		{"errors/last-error.log", lastErrorPath, bugReportLogLines},
+	}
+	for _, log := range logs {
+		path, err := log.path()
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if data, ok := read(path); ok {
+			add(log.name, lastLines(data, log.lines))
+		}
+	}
+
+	// Manifests of applied modes, the running session, undo history and the like
+	if dir, err := stateDir(); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		for _, name := range bugReportManifests {
+			if data, ok := read(filepath.Join(dir, name)); ok {
+				add("manifests/"+name, data)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		add("errors.txt", []byte(strings.Join(problems, "\n")+"\n"))
+	}
+
+	redactor := bugReportRedactor{Secrets: secrets}
+	if ctx, err := currentUserContext(); err == nil {
+		redactor.Home, redactor.User = ctx.Home, ctx.Name
+	}
+	for i := range files {
+		files[i].Data = []byte(redactor.redact(string(files[i].Data)))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// reviewBugReport asks about each file before it goes in the report, showing it on request
+// It returns the files kept; an unanswered question keeps the file
+func reviewBugReport(files []bugReportFile, answers <-chan string, out io.Writer) []bugReportFile {
+	fmt.Fprintln(out, "Review what goes in the report. Secrets, your home folder and user name are already replaced.")
+	var kept []bugReportFile
+	for _, file := range files {
+		for {
+			fmt.Fprintf(out, "Include %s (%d bytes)? [Y/n/v(iew)] ", file.Name, len(file.Data))
+			answer, ok := <-answers
+			if !ok {
+				answer = ""
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "", "y", "yes":
+				kept = append(kept, file)
+			case "n", "no":
+			case "v", "view":
+				fmt.Fprintf(out, "\n%s\n", lastLines(file.Data, bugReportPreviewLines))
+				continue
+			default:
+				fmt.Fprintln(out, "Please answer y, n or v")
+				continue
+			}
+			break
+		}
+	}
+	return kept
+}
+
+// writeBugReport writes the files to a zip at path
+func writeBugReport(path string, files []bugReportFile, now time.Time) error {
+	file, err := createFile(path)
+	if err != nil {
+		return fmt.Errorf("error creating bug report: %w", err)
+	}
+	archive := zip.NewWriter(file)
+	for _, f := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: now})
+		if err == nil {
+			_, err = w.Write(f.Data)
+		}
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("error writing bug report: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing bug report: %w", err)
+	}
+	return file.Close()
+}
+
+// runBugReportCommand handles "bug-report", which bundles what is needed to look into a problem
+func runBugReportCommand(args []string) {
+	flags := flag.NewFlagSet("bug-report", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	now := time.Now()
+	output := flags.String("o", "focusmode-bug-report-"+now.Format("20060102-150405")+".zip", "Zip file to write")
+	yes := flags.Bool("yes", false, "Include every file without reviewing them")
+	flags.Parse(args)
+
+	files := collectBugReport(*configPath, *categoriesPath, now)
+	if !*yes {
+		if !stdinIsTerminal() {
+			fmt.Fprintln(os.Stderr, "Error: the files can only be reviewed from a terminal; pass -yes to include them all")
+			os.Exit(1)
+		}
+		files = reviewBugReport(files, stdinLines(), os.Stdout)
+		if len(files) == 0 {
+			fmt.Println("Nothing left to include - no report written")
+			return
+		}
+	}
+
+	if err := writeBugReport(*output, files, now); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%sWrote %s with %d file(s); attach it to an issue at https://github.com/chenyuan99/FocusMode/issues\n", glyph("✓ "), *output, len(files))
+}
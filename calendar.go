@@ -0,0 +1,613 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CalendarConfig represents the calendar write-back settings
+type CalendarConfig struct {
+	Enabled      bool   `yaml:"enabled" doc:"Add an event for each completed session" default:"false"`
+	Provider     string `yaml:"provider" doc:"Calendar service" enum:"google,outlook" example:"google"`
+	ClientID     string `yaml:"client_id" doc:"OAuth client ID of your app registration"`
+	ClientSecret string `yaml:"client_secret" doc:"Required by Google for device-flow clients"`
+	CalendarID   string `yaml:"calendar_id" doc:"Calendar to write to (empty uses the primary calendar)"`
+	Tenant       string `yaml:"tenant" doc:"Microsoft tenant" default:"common"`
+}
+
+// oauthToken is the cached OAuth token for the calendar provider
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// calendarProvider holds the endpoints and event format of a calendar API
+type calendarProvider struct {
+	name      string
+	deviceURL string
+	tokenURL  string
+	eventsURL string
+	scope     string
+	event     func(summary, description string, start, end time.Time) interface{}
+
+	meetingsURL   func(from, to time.Time) string              // events overlapping a time window
+	parseMeetings func(data []byte) ([]calendarMeeting, error) // decodes the response of meetingsURL
+	prefer        string                                       // Prefer header sent when listing events
+	retry         RetryPolicy                                  // network policy requests are retried under
+}
+
+// calendarMeeting is an event read from the calendar
+type calendarMeeting struct {
+	ID      string
+	Title   string
+	Start   time.Time
+	End     time.Time
+	Details []string // location, description and conference links, searched for a video link
+}
+
+// calendarHTTPClient is used for all calendar API requests
+var calendarHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// getCalendarProvider returns the API description for the configured provider, whose requests
+// are retried under the network policy retry
+func getCalendarProvider(config CalendarConfig, retry RetryPolicy) (*calendarProvider, error) {
+	switch strings.ToLower(config.Provider) {
+	case "google":
+		calendarID := config.CalendarID
+		if calendarID == "" {
+			calendarID = "primary"
+		}
+		return &calendarProvider{
+			name:      "Google Calendar",
+			deviceURL: "https://oauth2.googleapis.com/device/code",
+			tokenURL:  "https://oauth2.googleapis.com/token",
+			eventsURL: fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", url.PathEscape(calendarID)),
+			scope:     "https://www.googleapis.com/auth/calendar.events",
+			event:     googleCalendarEvent,
+			meetingsURL: func(from, to time.Time) string {
+				query := url.Values{
+					"timeMin":      {from.Format(time.RFC3339)},
+					"timeMax":      {to.Format(time.RFC3339)},
+					"singleEvents": {"true"},
+					"orderBy":      {"startTime"},
+				}
+				return fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events?%s", url.PathEscape(calendarID), query.Encode())
+			},
+			parseMeetings: parseGoogleMeetings,
+			retry:         retry,
+		}, nil
+	case "outlook", "microsoft":
+		tenant := config.Tenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		calendarURL := "https://graph.microsoft.com/v1.0/me"
+		if config.CalendarID != "" {
+			calendarURL = fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s", url.PathEscape(config.CalendarID))
+		}
+		return &calendarProvider{
+			name:      "Outlook Calendar",
+			deviceURL: fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/devicecode", tenant),
+			tokenURL:  fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", tenant),
+			eventsURL: calendarURL + "/events",
+			scope:     "Calendars.ReadWrite offline_access",
+			event:     outlookCalendarEvent,
+			meetingsURL: func(from, to time.Time) string {
+				query := url.Values{
+					"startDateTime": {from.UTC().Format(time.RFC3339)},
+					"endDateTime":   {to.UTC().Format(time.RFC3339)},
+				}
+				return calendarURL + "/calendarView?" + query.Encode()
+			},
+			parseMeetings: parseOutlookMeetings,
+			prefer:        `outlook.timezone="UTC"`,
+			retry:         retry,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown calendar provider '%s' (use google or outlook)", config.Provider)
+	}
+}
+
+// googleCalendarEvent builds a Google Calendar event body
+func googleCalendarEvent(summary, description string, start, end time.Time) interface{} {
+	return map[string]interface{}{
+		"summary":     summary,
+		"description": description,
+		"start":       map[string]string{"dateTime": start.Format(time.RFC3339)},
+		"end":         map[string]string{"dateTime": end.Format(time.RFC3339)},
+	}
+}
+
+// outlookCalendarEvent builds a Microsoft Graph event body
+func outlookCalendarEvent(summary, description string, start, end time.Time) interface{} {
+	const graphTime = "2006-01-02T15:04:05"
+	return map[string]interface{}{
+		"subject":    summary,
+		"body":       map[string]string{"contentType": "text", "content": description},
+		"start":      map[string]string{"dateTime": start.UTC().Format(graphTime), "timeZone": "UTC"},
+		"end":        map[string]string{"dateTime": end.UTC().Format(graphTime), "timeZone": "UTC"},
+		"showAs":     "busy",
+		"categories": []string{"FocusMode"},
+	}
+}
+
+// parseGoogleMeetings decodes a Google Calendar event list
+func parseGoogleMeetings(data []byte) ([]calendarMeeting, error) {
+	var response struct {
+		Items []struct {
+			ID             string `json:"id"`
+			Summary        string `json:"summary"`
+			Location       string `json:"location"`
+			Description    string `json:"description"`
+			HangoutLink    string `json:"hangoutLink"`
+			ConferenceData struct {
+				EntryPoints []struct {
+					URI string `json:"uri"`
+				} `json:"entryPoints"`
+			} `json:"conferenceData"`
+			Start struct {
+				DateTime time.Time `json:"dateTime"`
+			} `json:"start"`
+			End struct {
+				DateTime time.Time `json:"dateTime"`
+			} `json:"end"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("error decoding events: %w", err)
+	}
+
+	var meetings []calendarMeeting
+	for _, item := range response.Items {
+		if item.Start.DateTime.IsZero() {
+			continue // all-day event
+		}
+		meeting := calendarMeeting{
+			ID:      item.ID,
+			Title:   item.Summary,
+			Start:   item.Start.DateTime,
+			End:     item.End.DateTime,
+			Details: []string{item.Location, item.Description, item.HangoutLink},
+		}
+		for _, entry := range item.ConferenceData.EntryPoints {
+			meeting.Details = append(meeting.Details, entry.URI)
+		}
+		meetings = append(meetings, meeting)
+	}
+	return meetings, nil
+}
+
+// parseOutlookMeetings decodes a Microsoft Graph calendar view with times in UTC
+func parseOutlookMeetings(data []byte) ([]calendarMeeting, error) {
+	const graphTime = "2006-01-02T15:04:05"
+	var response struct {
+		Value []struct {
+			ID            string `json:"id"`
+			Subject       string `json:"subject"`
+			IsAllDay      bool   `json:"isAllDay"`
+			BodyPreview   string `json:"bodyPreview"`
+			OnlineMeeting *struct {
+				JoinURL string `json:"joinUrl"`
+			} `json:"onlineMeeting"`
+			Location struct {
+				DisplayName string `json:"displayName"`
+			} `json:"location"`
+			Start struct {
+				DateTime string `json:"dateTime"`
+			} `json:"start"`
+			End struct {
+				DateTime string `json:"dateTime"`
+			} `json:"end"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("error decoding events: %w", err)
+	}
+
+	var meetings []calendarMeeting
+	for _, item := range response.Value {
+		if item.IsAllDay {
+			continue
+		}
+		// Graph returns fractional seconds, which the layout doesn't need
+		start, err := time.Parse(graphTime, strings.Split(item.Start.DateTime, ".")[0])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing start of %q: %w", item.Subject, err)
+		}
+		end, err := time.Parse(graphTime, strings.Split(item.End.DateTime, ".")[0])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing end of %q: %w", item.Subject, err)
+		}
+		meeting := calendarMeeting{
+			ID:      item.ID,
+			Title:   item.Subject,
+			Start:   start,
+			End:     end,
+			Details: []string{item.Location.DisplayName, item.BodyPreview},
+		}
+		if item.OnlineMeeting != nil {
+			meeting.Details = append(meeting.Details, item.OnlineMeeting.JoinURL)
+		}
+		meetings = append(meetings, meeting)
+	}
+	return meetings, nil
+}
+
+// calendarTokenPath returns the path of the cached calendar token
+func calendarTokenPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "calendar_token.json"), nil
+}
+
+// loadOAuthToken reads a cached token from path
+func loadOAuthToken(path string) (*oauthToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("not logged in to calendar (run 'focusmode calendar login'): %w", err)
+	}
+	var token oauthToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("error parsing calendar token: %w", err)
+	}
+	return &token, nil
+}
+
+// saveOAuthToken writes a token to path, readable only by the current user
+func saveOAuthToken(path string, token *oauthToken) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding calendar token: %w", err)
+	}
+	if err := writeFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing calendar token: %w", err)
+	}
+	return nil
+}
+
+// tokenResponse is the OAuth token endpoint response (success or error)
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// postForm posts form values and decodes the JSON response into out, retrying under policy
+func postForm(policy RetryPolicy, endpoint string, values url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := doWithRetry(policy, calendarHTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("error contacting %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unexpected response (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// deviceLogin runs the OAuth device authorization flow and returns the resulting token
+// Instructions for the user are written to out; sleep is used between polls
+func deviceLogin(provider *calendarProvider, config CalendarConfig, out io.Writer, sleep func(time.Duration)) (*oauthToken, error) {
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("calendar.client_id is required")
+	}
+
+	var device struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURL string `json:"verification_url"` // Google
+		VerificationURI string `json:"verification_uri"` // Microsoft and RFC 8628
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+		Error           string `json:"error"`
+		Description     string `json:"error_description"`
+	}
+	err := postForm(provider.retry, provider.deviceURL, url.Values{"client_id": {config.ClientID}, "scope": {provider.scope}}, &device)
+	if err != nil {
+		return nil, err
+	}
+	if device.Error != "" {
+		return nil, fmt.Errorf("device authorization failed: %s %s", device.Error, device.Description)
+	}
+
+	verification := device.VerificationURI
+	if verification == "" {
+		verification = device.VerificationURL
+	}
+	fmt.Fprintf(out, "To connect %s, visit %s and enter the code: %s\n", provider.name, verification, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+
+	values := url.Values{
+		"client_id":   {config.ClientID},
+		"device_code": {device.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	if config.ClientSecret != "" {
+		values.Set("client_secret", config.ClientSecret)
+	}
+
+	for device.ExpiresIn == 0 || time.Now().Before(deadline) {
+		sleep(interval)
+
+		var token tokenResponse
+		if err := postForm(provider.retry, provider.tokenURL, values, &token); err != nil {
+			return nil, err
+		}
+		switch token.Error {
+		case "":
+			return &oauthToken{
+				AccessToken:  token.AccessToken,
+				RefreshToken: token.RefreshToken,
+				Expiry:       time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+			}, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("authorization failed: %s %s", token.Error, token.Description)
+		}
+	}
+	return nil, fmt.Errorf("device code expired before authorization completed")
+}
+
+// refreshOAuthToken exchanges a refresh token for a new access token
+func refreshOAuthToken(provider *calendarProvider, config CalendarConfig, token *oauthToken) (*oauthToken, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("calendar token expired and cannot be refreshed (run 'focusmode calendar login')")
+	}
+
+	values := url.Values{
+		"client_id":     {config.ClientID},
+		"refresh_token": {token.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+	if config.ClientSecret != "" {
+		values.Set("client_secret", config.ClientSecret)
+	}
+	if strings.Contains(provider.tokenURL, "microsoftonline") {
+		values.Set("scope", provider.scope)
+	}
+
+	var response tokenResponse
+	if err := postForm(provider.retry, provider.tokenURL, values, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("token refresh failed: %s %s", response.Error, response.Description)
+	}
+
+	refreshed := &oauthToken{
+		AccessToken:  response.AccessToken,
+		RefreshToken: response.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	}
+	// Google only returns a new refresh token occasionally
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+// validAccessToken returns the cached access token, refreshing and saving it if it is about to expire
+func validAccessToken(provider *calendarProvider, config CalendarConfig, tokenPath string) (string, error) {
+	token, err := loadOAuthToken(tokenPath)
+	if err != nil {
+		return "", err
+	}
+	if time.Now().After(token.Expiry.Add(-time.Minute)) {
+		token, err = refreshOAuthToken(provider, config, token)
+		if err != nil {
+			return "", err
+		}
+		if err := saveOAuthToken(tokenPath, token); err != nil {
+			return "", err
+		}
+	}
+	return token.AccessToken, nil
+}
+
+// listMeetings returns the events that overlap the window from..to
+func listMeetings(provider *calendarProvider, accessToken string, from, to time.Time) ([]calendarMeeting, error) {
+	req, err := http.NewRequest(http.MethodGet, provider.meetingsURL(from, to), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if provider.prefer != "" {
+		req.Header.Set("Prefer", provider.prefer)
+	}
+
+	resp, err := doWithRetry(provider.retry, calendarHTTPClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting %s: %w", provider.name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("error reading events: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s rejected event query (HTTP %d): %s", provider.name, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return provider.parseMeetings(data)
+}
+
+// createCalendarEvent posts an event for the given time window
+func createCalendarEvent(provider *calendarProvider, accessToken, summary, description string, start, end time.Time) error {
+	body, err := json.Marshal(provider.event(summary, description, start, end))
+	if err != nil {
+		return fmt.Errorf("error encoding event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, provider.eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doWithRetry(provider.retry, calendarHTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("error contacting %s: %w", provider.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s rejected event (HTTP %d): %s", provider.name, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// calendarWriter is a session hook that writes completed sessions to a calendar
+type calendarWriter struct {
+	config    CalendarConfig
+	provider  *calendarProvider
+	tokenPath string
+}
+
+// OnStart does nothing
+func (w *calendarWriter) OnStart(fs *FocusSession) {}
+
+// OnPause does nothing
+func (w *calendarWriter) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (w *calendarWriter) OnResume(fs *FocusSession) {}
+
+// OnEnd creates a calendar event covering a completed focus session
+func (w *calendarWriter) OnEnd(fs *FocusSession) {
+	if fs.Break || fs.State != StateCompleted {
+		return
+	}
+	if err := w.writeSession(fs, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not add session to calendar: %v\n", err)
+		return
+	}
+	fmt.Printf("\n%sSession added to %s\n", glyph("📅 "), w.provider.name)
+}
+
+// writeSession creates the event for a session that ended at end, refreshing the token if needed
+func (w *calendarWriter) writeSession(fs *FocusSession, end time.Time) error {
+	accessToken, err := validAccessToken(w.provider, w.config, w.tokenPath)
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("🎯 Deep work (%s)", fs.Mode)
+	description := fmt.Sprintf("FocusMode session: %s focused", formatDuration(fs.elapsed().Round(time.Minute)))
+	if fs.PausedTotal > 0 {
+		description += fmt.Sprintf(", %s paused", formatDuration(fs.PausedTotal.Round(time.Second)))
+	}
+	return createCalendarEvent(w.provider, accessToken, summary, description, fs.StartTime, end)
+}
+
+// newCalendarWriter creates a calendar hook from config, retrying its requests under retry
+func newCalendarWriter(config CalendarConfig, retry RetryPolicy) (*calendarWriter, error) {
+	provider, err := getCalendarProvider(config, retry)
+	if err != nil {
+		return nil, err
+	}
+	tokenPath, err := calendarTokenPath()
+	if err != nil {
+		return nil, err
+	}
+	return &calendarWriter{config: config, provider: provider, tokenPath: tokenPath}, nil
+}
+
+// runCalendarCommand handles the "calendar" subcommand
+func runCalendarCommand(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  focusmode calendar login [-config profile.yml]")
+		fmt.Fprintln(os.Stderr, "  focusmode calendar logout")
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("calendar "+args[0], flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args[1:])
+
+	tokenPath, err := calendarTokenPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "login":
+		config, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		provider, err := getCalendarProvider(config.Calendar, config.Retry.Network)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		token, err := deviceLogin(provider, config.Calendar, os.Stdout, time.Sleep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := saveOAuthToken(tokenPath, token); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sConnected to %s\n", glyph("✅ "), provider.name)
+	case "logout":
+		if err := removeFile(tokenPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error removing calendar token: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Calendar token removed.")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown calendar command: %s\n\n", args[0])
+		usage()
+		os.Exit(1)
+	}
+}
+
+// DryRun reports the calendar event that would be created
+func (w *calendarWriter) DryRun(fs *FocusSession) []string {
+	if fs.Break {
+		return nil
+	}
+	return []string{fmt.Sprintf("add a %s event to %s if the session completes", formatDuration(fs.Duration), w.provider.name)}
+}
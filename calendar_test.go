@@ -0,0 +1,178 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGetCalendarProvider tests provider selection and defaults
+func TestGetCalendarProvider(t *testing.T) {
+	google, err := getCalendarProvider(CalendarConfig{Provider: "google"}, RetryPolicy{})
+	if err != nil {
+		t.Fatalf("getCalendarProvider(google) returned error: %v", err)
+	}
+	if !strings.HasSuffix(google.eventsURL, "/calendars/primary/events") {
+		t.Errorf("Expected primary calendar, got %s", google.eventsURL)
+	}
+
+	outlook, err := getCalendarProvider(CalendarConfig{Provider: "Outlook", CalendarID: "abc"}, RetryPolicy{})
+	if err != nil {
+		t.Fatalf("getCalendarProvider(outlook) returned error: %v", err)
+	}
+	if !strings.Contains(outlook.tokenURL, "/common/") || !strings.HasSuffix(outlook.eventsURL, "/calendars/abc/events") {
+		t.Errorf("Unexpected Outlook endpoints: %s %s", outlook.tokenURL, outlook.eventsURL)
+	}
+
+	if _, err := getCalendarProvider(CalendarConfig{Provider: "ical"}, RetryPolicy{}); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
+
+// TestDeviceLogin tests the device flow including pending and slow_down responses
+func TestDeviceLogin(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/device":
+			if r.Form.Get("client_id") != "client" {
+				t.Errorf("Expected client_id, got %q", r.Form.Get("client_id"))
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code": "dev", "user_code": "ABCD-EFGH",
+				"verification_url": "https://example.com/device", "expires_in": 600, "interval": 1,
+			})
+		case "/token":
+			polls++
+			if r.Form.Get("device_code") != "dev" || r.Form.Get("client_secret") != "secret" {
+				t.Errorf("Unexpected token request: %v", r.Form)
+			}
+			switch polls {
+			case 1:
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+			case 2:
+				json.NewEncoder(w).Encode(map[string]string{"error": "slow_down"})
+			default:
+				json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "refresh_token": "refresh", "expires_in": 3600})
+			}
+		}
+	}))
+	defer server.Close()
+
+	provider := &calendarProvider{name: "Test", deviceURL: server.URL + "/device", tokenURL: server.URL + "/token"}
+	var out bytes.Buffer
+	var waits []time.Duration
+	token, err := deviceLogin(provider, CalendarConfig{ClientID: "client", ClientSecret: "secret"}, &out, func(d time.Duration) {
+		waits = append(waits, d)
+	})
+	if err != nil {
+		t.Fatalf("deviceLogin() returned error: %v", err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	if !strings.Contains(out.String(), "ABCD-EFGH") || !strings.Contains(out.String(), "https://example.com/device") {
+		t.Errorf("Expected user instructions, got %q", out.String())
+	}
+	if len(waits) != 3 || waits[2] != 6*time.Second {
+		t.Errorf("Expected interval to grow after slow_down, got %v", waits)
+	}
+}
+
+// TestDeviceLoginDenied tests that authorization errors are reported
+func TestDeviceLoginDenied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/device" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"device_code": "dev", "user_code": "X", "verification_uri": "u"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": "access_denied"})
+	}))
+	defer server.Close()
+
+	provider := &calendarProvider{deviceURL: server.URL + "/device", tokenURL: server.URL + "/token"}
+	_, err := deviceLogin(provider, CalendarConfig{ClientID: "client"}, &bytes.Buffer{}, func(time.Duration) {})
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("Expected access_denied error, got %v", err)
+	}
+
+	if _, err := deviceLogin(provider, CalendarConfig{}, &bytes.Buffer{}, func(time.Duration) {}); err == nil {
+		t.Error("Expected error without client_id")
+	}
+}
+
+// TestCalendarWriterWriteSession tests token refresh and event creation for a completed session
+func TestCalendarWriterWriteSession(t *testing.T) {
+	var event map[string]interface{}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "old-refresh" {
+				t.Errorf("Unexpected refresh request: %v", r.Form)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "new-access", "expires_in": 3600})
+		case "/events":
+			auth = r.Header.Get("Authorization")
+			json.NewDecoder(r.Body).Decode(&event)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	saveOAuthToken(tokenPath, &oauthToken{AccessToken: "old", RefreshToken: "old-refresh", Expiry: time.Now().Add(-time.Hour)})
+
+	writer := &calendarWriter{
+		config: CalendarConfig{ClientID: "client"},
+		provider: &calendarProvider{
+			name: "Test", tokenURL: server.URL + "/token", eventsURL: server.URL + "/events", event: googleCalendarEvent,
+		},
+		tokenPath: tokenPath,
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	fs := &FocusSession{Duration: 25 * time.Minute, Mode: "focusmode", StartTime: start, State: StateCompleted}
+	if err := writer.writeSession(fs, start.Add(25*time.Minute)); err != nil {
+		t.Fatalf("writeSession() returned error: %v", err)
+	}
+
+	if auth != "Bearer new-access" {
+		t.Errorf("Expected refreshed access token, got %q", auth)
+	}
+	if event["summary"] != "🎯 Deep work (focusmode)" {
+		t.Errorf("Unexpected event summary: %v", event["summary"])
+	}
+	if startTime := event["start"].(map[string]interface{})["dateTime"]; startTime != "2024-05-01T09:00:00Z" {
+		t.Errorf("Unexpected event start: %v", startTime)
+	}
+
+	saved, err := loadOAuthToken(tokenPath)
+	if err != nil {
+		t.Fatalf("loadOAuthToken() returned error: %v", err)
+	}
+	if saved.AccessToken != "new-access" || saved.RefreshToken != "old-refresh" {
+		t.Errorf("Expected refreshed token to be saved with original refresh token, got %+v", saved)
+	}
+}
+
+// TestOutlookCalendarEvent tests the Microsoft Graph event format
+func TestOutlookCalendarEvent(t *testing.T) {
+	start := time.Date(2024, 5, 1, 11, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	event := outlookCalendarEvent("Deep work", "desc", start, start.Add(time.Hour)).(map[string]interface{})
+
+	if event["subject"] != "Deep work" {
+		t.Errorf("Unexpected subject: %v", event["subject"])
+	}
+	startField := event["start"].(map[string]string)
+	if startField["dateTime"] != "2024-05-01T09:00:00" || startField["timeZone"] != "UTC" {
+		t.Errorf("Expected UTC start time, got %v", startField)
+	}
+}
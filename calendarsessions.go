@@ -0,0 +1,266 @@
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CalendarSessionsConfig represents starting sessions from calendar events
+type CalendarSessionsConfig struct {
+	Enabled bool                  `yaml:"enabled" doc:"Start a session when a calendar event whose title matches a rule begins, and restore when it ends; run by the daemon or 'focusmode watch calendar'" default:"false"`
+	ICSURL  string                `yaml:"ics_url" doc:"Calendar to read in iCalendar format, such as the secret address in iCal format of a Google calendar; empty reads the calendar signed in with 'focusmode calendar login'" example:"https://calendar.google.com/calendar/ical/me%40gmail.com/private-abc123/basic.ics"`
+	Rules   []CalendarSessionRule `yaml:"rules" doc:"Event titles that start sessions; the first rule matching an event picks its mode"`
+}
+
+// CalendarSessionRule starts a mode for events whose title matches
+type CalendarSessionRule struct {
+	Match string `yaml:"match" doc:"Text the event's title contains, ignoring case, or a regular expression between slashes" example:"Deep Work"`
+	Mode  string `yaml:"mode" doc:"Mode of the session (default mode if empty)"`
+}
+
+const (
+	calendarSessionPollInterval = time.Minute
+	icsRefreshInterval          = 5 * time.Minute // How often an ICS calendar is downloaded again
+	calendarSessionLookahead    = 24 * time.Hour  // How far ahead upcoming sessions are listed
+)
+
+// matcher returns whether a title matches the rule
+func (r CalendarSessionRule) matcher() (func(string) bool, error) {
+	match := strings.TrimSpace(r.Match)
+	if match == "" {
+		return nil, fmt.Errorf("calendar_sessions rule for mode '%s' has no match", r.Mode)
+	}
+	if len(match) > 2 && strings.HasPrefix(match, "/") && strings.HasSuffix(match, "/") {
+		pattern, err := regexp.Compile("(?i)" + match[1:len(match)-1])
+		if err != nil {
+			return nil, fmt.Errorf("calendar_sessions match %s: %w", match, err)
+		}
+		return pattern.MatchString, nil
+	}
+	lower := strings.ToLower(match)
+	return func(title string) bool { return strings.Contains(strings.ToLower(title), lower) }, nil
+}
+
+// calendarSessionMatcher picks the mode of an event by its title; rules that can't be read are skipped
+type calendarSessionMatcher func(title string) (string, bool)
+
+// newCalendarSessionMatcher returns the matcher of the configured rules
+func (c *Config) newCalendarSessionMatcher() calendarSessionMatcher {
+	type rule struct {
+		match func(string) bool
+		mode  string
+	}
+	var rules []rule
+	for _, r := range c.CalendarSessions.Rules {
+		match, err := r.matcher()
+		if err != nil {
+			continue
+		}
+		mode := r.Mode
+		if mode == "" {
+			mode = c.DefaultMode
+		}
+		rules = append(rules, rule{match, mode})
+	}
+	return func(title string) (string, bool) {
+		for _, r := range rules {
+			if r.match(title) {
+				return r.mode, true
+			}
+		}
+		return "", false
+	}
+}
+
+// calendarSessionTracker remembers which events already started a session
+type calendarSessionTracker struct {
+	handled map[string]time.Time // event ID to end time
+}
+
+// due returns the matching event in progress at now that hasn't been handled yet, with its
+// mode, and marks it handled
+func (t *calendarSessionTracker) due(events []calendarMeeting, now time.Time, match calendarSessionMatcher) (*calendarMeeting, string) {
+	if t.handled == nil {
+		t.handled = make(map[string]time.Time)
+	}
+	for id, end := range t.handled {
+		if end.Before(now) {
+			delete(t.handled, id)
+		}
+	}
+	for i, event := range events {
+		if _, done := t.handled[event.ID]; done || now.Before(event.Start) || !now.Before(event.End) {
+			continue
+		}
+		mode, ok := match(event.Title)
+		if !ok {
+			continue
+		}
+		t.handled[event.ID] = event.End
+		return &events[i], mode
+	}
+	return nil, ""
+}
+
+// calendarEventSource lists the timed events overlapping from..to
+type calendarEventSource func(from, to time.Time) ([]calendarMeeting, error)
+
+// icsCalendar downloads an ICS calendar at most every icsRefreshInterval
+type icsCalendar struct {
+	url   string
+	fetch func(string) ([]byte, error) // fetchICS outside tests
+
+	mu      sync.Mutex
+	events  []icsEvent
+	fetched time.Time
+	warned  map[string]bool // Recurring events already reported as not expanded
+}
+
+// list returns the calendar's events overlapping from..to, downloading it again when it is old;
+// when a download fails, the last calendar read is used
+func (c *icsCalendar) list(from, to time.Time) ([]calendarMeeting, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.fetched.IsZero() || time.Since(c.fetched) >= icsRefreshInterval {
+		var data []byte
+		if data, err = c.fetch(c.url); err == nil {
+			var events []icsEvent
+			if events, err = parseICS(data); err == nil {
+				c.events = events
+			}
+		}
+		c.fetched = time.Now()
+	}
+	events, unsupported := expandICS(c.events, from, to)
+	if c.warned == nil {
+		c.warned = make(map[string]bool)
+	}
+	for _, title := range unsupported {
+		if !c.warned[title] {
+			c.warned[title] = true
+			fmt.Fprintf(os.Stderr, "Warning: only the first occurrence of %s is read\n", title)
+		}
+	}
+	return events, err
+}
+
+// newCalendarEventSource returns where the events are read: the ICS calendar if one is given,
+// otherwise the calendar signed in with "focusmode calendar login"
+func newCalendarEventSource(config *Config) (calendarEventSource, string, error) {
+	if address := strings.TrimSpace(config.CalendarSessions.ICSURL); address != "" {
+		calendar := &icsCalendar{url: address, fetch: func(address string) ([]byte, error) {
+			return fetchICS(config.Retry.Network, address)
+		}}
+		return calendar.list, "the ICS calendar", nil
+	}
+	provider, err := getCalendarProvider(config.Calendar, config.Retry.Network)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w; set calendar_sessions.ics_url, or calendar.provider and run 'focusmode calendar login'", err)
+	}
+	tokenPath, err := calendarTokenPath()
+	if err != nil {
+		return nil, "", err
+	}
+	return func(from, to time.Time) ([]calendarMeeting, error) {
+		accessToken, err := validAccessToken(provider, config.Calendar, tokenPath)
+		if err != nil {
+			return nil, err
+		}
+		return listMeetings(provider, accessToken, from, to)
+	}, provider.name, nil
+}
+
+// watchCalendarSessions starts a session when a matching calendar event begins
+// The session is backdated to the start of the event so it ends, and restores, with it
+// It calls beat after every poll and returns when ctx is cancelled
+func watchCalendarSessions(ctx context.Context, config *Config, opts sessionOptions, beat func()) {
+	source, name, err := newCalendarEventSource(config)
+	if err != nil {
+		// Retrying can't fix the configuration; wait without beating so the daemon reports the loop as unhealthy
+		fmt.Fprintf(os.Stderr, "Error: cannot watch the calendar: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	pollCalendarSessions(ctx, config, opts, beat, source, name)
+}
+
+// pollCalendarSessions is the loop of watchCalendarSessions
+func pollCalendarSessions(ctx context.Context, config *Config, opts sessionOptions, beat func(), source calendarEventSource, name string) {
+	match := config.newCalendarSessionMatcher()
+	opts.fromCalendar = true
+	fmt.Printf("Watching %s for events that start sessions...\n", name)
+	now := time.Now()
+	if upcoming, err := source(now, now.Add(calendarSessionLookahead)); err == nil {
+		for _, event := range upcoming {
+			if mode, ok := match(event.Title); ok {
+				fmt.Printf("Upcoming: %s-%s %s (%s)\n", event.Start.Local().Format("Mon 15:04"), event.End.Local().Format("15:04"), event.Title, mode)
+			}
+		}
+	}
+
+	tracker := &calendarSessionTracker{}
+	answers := stdinLines()
+	for {
+		beat()
+		now := time.Now()
+		events, err := source(now, now.Add(time.Minute))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		if event, mode := tracker.due(events, now, match); event != nil && !isSessionRunning() {
+			duration := int((event.End.Sub(event.Start) + time.Minute - 1) / time.Minute)
+			notifyAll([]Notifier{consoleNotifier{}}, "FocusMode", fmt.Sprintf("%s has started", event.Title))
+			action := fmt.Sprintf("Starting %s until %s", mode, event.End.Local().Format("15:04"))
+			ticker := time.NewTicker(time.Second)
+			proceed := waitGracePeriod(action, config.Automation.graceSeconds(), answers, ticker.C, os.Stdout)
+			ticker.Stop()
+			if proceed {
+				startRetroactiveSession(config, mode, duration, event.Start, answers, opts)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(calendarSessionPollInterval):
+		}
+	}
+}
+
+// lintCalendarSessions warns about calendar sessions that can't start
+func (c *Config) lintCalendarSessions() []string {
+	if !c.CalendarSessions.Enabled {
+		return nil
+	}
+	var warnings []string
+	if len(c.CalendarSessions.Rules) == 0 {
+		warnings = append(warnings, "calendar_sessions has no rules; no event starts a session")
+	}
+	if strings.TrimSpace(c.CalendarSessions.ICSURL) == "" {
+		if _, err := getCalendarProvider(c.Calendar, c.Retry.Network); err != nil {
+			warnings = append(warnings, "calendar_sessions needs ics_url, or calendar.provider and 'focusmode calendar login'")
+		}
+	} else if address := c.CalendarSessions.ICSURL; !strings.HasPrefix(address, "https://") && !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "webcal://") {
+		warnings = append(warnings, fmt.Sprintf("calendar_sessions.ics_url %q isn't an http(s) or webcal address", address))
+	}
+	for _, rule := range c.CalendarSessions.Rules {
+		if _, err := rule.matcher(); err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		mode := rule.Mode
+		if mode == "" {
+			mode = c.DefaultMode
+		}
+		if _, exists := c.Modes[mode]; !exists {
+			warnings = append(warnings, fmt.Sprintf("calendar_sessions rule %q starts mode '%s', which is not defined in modes", rule.Match, mode))
+		}
+	}
+	return warnings
+}
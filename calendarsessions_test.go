@@ -0,0 +1,133 @@
+package focusmode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCalendarSessionMatcher tests picking modes by event title
+func TestCalendarSessionMatcher(t *testing.T) {
+	config := &Config{DefaultMode: "focusmode", CalendarSessions: CalendarSessionsConfig{Rules: []CalendarSessionRule{
+		{Match: "deep work", Mode: "work"},
+		{Match: "/^(study|revision)\\b/", Mode: "study"},
+		{Match: "/[/"},
+		{Match: "Focus"},
+	}}}
+	match := config.newCalendarSessionMatcher()
+
+	for title, want := range map[string]string{
+		"Deep Work: report":  "work",
+		"Study session":      "study",
+		"Revision for exams": "study",
+		"Team focus time":    "focusmode",
+		"Deep focus":         "focusmode",
+	} {
+		if mode, ok := match(title); !ok || mode != want {
+			t.Errorf("match(%q) = %q, %v; want %q", title, mode, ok, want)
+		}
+	}
+	for _, title := range []string{"Standup", "Case study review", ""} {
+		if mode, ok := match(title); ok {
+			t.Errorf("Expected %q not to match, got %q", title, mode)
+		}
+	}
+}
+
+// TestCalendarSessionTracker tests that each occurrence starts one session, while it is in progress
+func TestCalendarSessionTracker(t *testing.T) {
+	now := time.Now()
+	match := func(title string) (string, bool) { return "work", strings.Contains(title, "Deep Work") }
+	events := []calendarMeeting{
+		{ID: "standup@1", Title: "Standup", Start: now.Add(-5 * time.Minute), End: now.Add(10 * time.Minute)},
+		{ID: "focus@1", Title: "Deep Work", Start: now.Add(-time.Minute), End: now.Add(time.Hour)},
+		{ID: "focus@2", Title: "Deep Work", Start: now.Add(24 * time.Hour), End: now.Add(25 * time.Hour)},
+	}
+	tracker := &calendarSessionTracker{}
+
+	event, mode := tracker.due(events, now, match)
+	if event == nil || event.ID != "focus@1" || mode != "work" {
+		t.Fatalf("Expected the Deep Work in progress, got %+v %q", event, mode)
+	}
+	if event, _ := tracker.due(events, now.Add(time.Minute), match); event != nil {
+		t.Errorf("Expected the event to start one session, got %+v", event)
+	}
+	if event, _ := tracker.due(events, now.Add(24*time.Hour+time.Minute), match); event == nil || event.ID != "focus@2" {
+		t.Errorf("Expected the next occurrence, got %+v", event)
+	}
+	if len(tracker.handled) != 1 {
+		t.Errorf("Expected ended events forgotten, got %v", tracker.handled)
+	}
+}
+
+// TestICSCalendarList tests refreshing a calendar and keeping the last one when a download fails
+func TestICSCalendarList(t *testing.T) {
+	now := time.Now().UTC()
+	data := icsCalendarOf(`
+BEGIN:VEVENT
+UID:focus
+SUMMARY:Deep Work
+DTSTART:` + now.Add(-time.Minute).Format("20060102T150405Z") + `
+DTEND:` + now.Add(time.Hour).Format("20060102T150405Z") + `
+END:VEVENT`)
+	fetches := 0
+	var fail error
+	calendar := &icsCalendar{url: "https://example.com/basic.ics", fetch: func(string) ([]byte, error) {
+		fetches++
+		return data, fail
+	}}
+
+	events, err := calendar.list(now, now.Add(time.Minute))
+	if err != nil || len(events) != 1 || events[0].Title != "Deep Work" {
+		t.Fatalf("list() = %+v, %v", events, err)
+	}
+	calendar.list(now, now.Add(time.Minute))
+	if fetches != 1 {
+		t.Errorf("Expected the calendar downloaded once within the refresh interval, got %d", fetches)
+	}
+
+	calendar.fetched = now.Add(-icsRefreshInterval)
+	fail = errors.New("offline")
+	events, err = calendar.list(now, now.Add(time.Minute))
+	if err == nil || len(events) != 1 {
+		t.Errorf("Expected the error and the last calendar, got %+v, %v", events, err)
+	}
+}
+
+// TestLintCalendarSessions tests warnings about calendar sessions that can't start
+func TestLintCalendarSessions(t *testing.T) {
+	config := &Config{
+		DefaultMode: "focusmode",
+		Modes:       map[string]ModeConfig{"work": {}},
+		CalendarSessions: CalendarSessionsConfig{Enabled: true, Rules: []CalendarSessionRule{
+			{Match: "Deep Work", Mode: "work"},
+			{Match: "/(/", Mode: "work"},
+			{Match: "Study", Mode: "study"},
+			{Match: "Focus"},
+		}},
+	}
+	warnings := strings.Join(config.lintCalendarSessions(), "\n")
+	for _, want := range []string{
+		"calendar_sessions needs ics_url",
+		"calendar_sessions match /(/",
+		"starts mode 'study', which is not defined",
+		"starts mode 'focusmode', which is not defined",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected a warning %q, got:\n%s", want, warnings)
+		}
+	}
+	if strings.Contains(warnings, "\"Deep Work\"") {
+		t.Errorf("Expected no warning about a valid rule, got:\n%s", warnings)
+	}
+
+	config.CalendarSessions.ICSURL = "calendar.ics"
+	if warnings := strings.Join(config.lintCalendarSessions(), "\n"); !strings.Contains(warnings, "isn't an http(s) or webcal address") {
+		t.Errorf("Expected a warning about the address, got:\n%s", warnings)
+	}
+	config.CalendarSessions = CalendarSessionsConfig{Enabled: true, ICSURL: "webcal://example.com/basic.ics"}
+	if warnings := config.lintCalendarSessions(); len(warnings) != 1 || !strings.Contains(warnings[0], "no rules") {
+		t.Errorf("Expected only the missing rules reported, got %v", warnings)
+	}
+}
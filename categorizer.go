@@ -0,0 +1,165 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMetadata describes the file a Categorizer is asked about
+type FileMetadata struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path,omitempty"` // Empty when the file isn't on disk, e.g. in the shortcut corpus
+	Ext      string    `json:"ext"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified,omitempty"`
+	IsDir    bool      `json:"is_dir"`
+}
+
+// Categorization is a Categorizer's answer: a category key from categories.yml, or "other",
+// and how sure it is between 0 and 1
+type Categorization struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Categorizer decides the category of a file ahead of the keywords in categories.yml
+// An empty category means it has no opinion; the keywords decide then, as they do when
+// the answer is below min_confidence, names an unknown category or comes with an error
+type Categorizer interface {
+	Categorize(file FileMetadata) (Categorization, error)
+}
+
+// CategorizerConfig represents an external program that categorizes files
+type CategorizerConfig struct {
+	Command        []string `yaml:"command" doc:"Program and arguments run once per file, reading the file's metadata as JSON on stdin and writing {\"category\": ..., \"confidence\": ...} to stdout" example:"[python3, classify.py]"`
+	MinConfidence  int      `yaml:"min_confidence" doc:"Lowest confidence, in percent, at which the program's category is used instead of the keywords" default:"50"`
+	TimeoutSeconds int      `yaml:"timeout_seconds" doc:"How long the program may take for one file" default:"5"`
+}
+
+const (
+	defaultCategorizerMinConfidence = 50
+	defaultCategorizerTimeout       = 5 * time.Second
+)
+
+// minConfidence returns the lowest confidence at which an answer is used, between 0 and 1
+func (c CategorizerConfig) minConfidence() float64 {
+	if c.MinConfidence <= 0 {
+		return defaultCategorizerMinConfidence / 100.0
+	}
+	return float64(c.MinConfidence) / 100
+}
+
+// timeout returns how long the program may take for one file
+func (c CategorizerConfig) timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return defaultCategorizerTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// execCategorizer is a Categorizer that runs an external program for each file
+type execCategorizer struct {
+	command []string
+	timeout time.Duration
+}
+
+// Categorize runs the program with the file's metadata on stdin and reads its answer from stdout
+func (e execCategorizer) Categorize(file FileMetadata) (Categorization, error) {
+	input, err := json.Marshal(file)
+	if err != nil {
+		return Categorization{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return Categorization{}, fmt.Errorf("%s took longer than %s", e.command[0], e.timeout)
+	}
+	if err != nil {
+		return Categorization{}, fmt.Errorf("%s failed: %v %s", e.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	var answer Categorization
+	if err := json.Unmarshal(out, &answer); err != nil {
+		return Categorization{}, fmt.Errorf("%s wrote invalid JSON: %w", e.command[0], err)
+	}
+	return answer, nil
+}
+
+// categorizerPlugin asks a Categorizer about files, remembering its answers for the run
+// so that listing and moving the same desktop asks once per file
+type categorizerPlugin struct {
+	categorizer   Categorizer
+	minConfidence float64
+
+	mu      sync.Mutex
+	answers map[string]ShortcutCategory // By path or name; "" when the keywords decide
+	failed  bool                        // Set after the first error, which is warned about once
+}
+
+// SetCategorizer makes categorizer decide the category of files ahead of the keywords,
+// using its answers when they're at least minConfidence sure (between 0 and 1)
+// A nil categorizer leaves categorizing to the keywords again
+func (c *CategoriesConfig) SetCategorizer(categorizer Categorizer, minConfidence float64) {
+	if categorizer == nil {
+		c.plugin = nil
+		return
+	}
+	c.plugin = &categorizerPlugin{categorizer: categorizer, minConfidence: minConfidence, answers: make(map[string]ShortcutCategory)}
+}
+
+// fileMetadata returns the metadata of the file at path, or only its name when it doesn't exist
+func fileMetadata(path string) FileMetadata {
+	name := filepath.Base(path)
+	file := FileMetadata{Name: name, Ext: strings.ToLower(filepath.Ext(name))}
+	info, err := os.Stat(path)
+	if err != nil {
+		return file
+	}
+	file.Path = path
+	file.Size = info.Size()
+	file.Modified = info.ModTime()
+	file.IsDir = info.IsDir()
+	return file
+}
+
+// pluginCategory returns the category the categorizer gives the file at path, if any
+func (c *CategoriesConfig) pluginCategory(path string) (ShortcutCategory, bool) {
+	p := c.plugin
+	if p == nil {
+		return "", false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if category, ok := p.answers[path]; ok {
+		return category, category != ""
+	}
+	if p.failed {
+		return "", false
+	}
+
+	var category ShortcutCategory
+	answer, err := p.categorizer.Categorize(fileMetadata(path))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: categorizer failed, using keywords instead: %v\n", err)
+		p.failed = true
+		return "", false
+	}
+	_, known := c.Categories[answer.Category]
+	if answer.Category != "" && (known || answer.Category == string(CategoryOther)) && answer.Confidence >= p.minConfidence {
+		category = ShortcutCategory(answer.Category)
+	}
+	p.answers[path] = category
+	return category, category != ""
+}
@@ -0,0 +1,115 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeCategorizer answers from a map of file names, counting how often it's asked
+type fakeCategorizer struct {
+	answers map[string]Categorization
+	err     error
+	asked   int
+}
+
+func (f *fakeCategorizer) Categorize(file FileMetadata) (Categorization, error) {
+	f.asked++
+	return f.answers[file.Name], f.err
+}
+
+// TestCategorizerPlugin tests that a categorizer's confident answers override the keywords
+// and that the keywords decide otherwise
+func TestCategorizerPlugin(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+
+	config := getDefaultCategoriesConfig()
+	fake := &fakeCategorizer{answers: map[string]Categorization{
+		"Steam.lnk":   {Category: "work", Confidence: 0.9},
+		"Docker.lnk":  {Category: "game", Confidence: 0.2},
+		"Epic.lnk":    {Category: "payroll", Confidence: 1},
+		"Report.docx": {Category: "other", Confidence: 0.7},
+	}}
+	config.SetCategorizer(fake, 0.5)
+
+	tests := map[string]ShortcutCategory{
+		"Steam.lnk":   "work",        // Confident answer
+		"Docker.lnk":  "development", // Too unsure, keywords decide
+		"Epic.lnk":    "game",        // Unknown category, keywords decide
+		"Report.docx": CategoryOther,
+		"Git.lnk":     "development", // No opinion
+	}
+	for name, want := range tests {
+		if got := categorizeShortcut(name, config); got != want {
+			t.Errorf("categorizeShortcut(%q) = %q, want %q", name, got, want)
+		}
+	}
+	asked := fake.asked
+	categorizeShortcut("Steam.lnk", config)
+	if fake.asked != asked {
+		t.Error("Expected answers to be remembered for the run")
+	}
+
+	// After an error the categorizer isn't asked again
+	failing := &fakeCategorizer{err: errors.New("model not loaded")}
+	config.SetCategorizer(failing, 0.5)
+	for _, name := range []string{"Steam.lnk", "Docker.lnk"} {
+		if got := categorizeShortcut(name, config); got == "work" {
+			t.Errorf("Expected keywords after an error for %s, got %q", name, got)
+		}
+	}
+	if failing.asked != 1 {
+		t.Errorf("Expected the failing categorizer to be asked once, got %d", failing.asked)
+	}
+
+	config.SetCategorizer(nil, 0)
+	if got := categorizeShortcut("Steam.lnk", config); got != "game" {
+		t.Errorf("Expected keywords without a categorizer, got %q", got)
+	}
+}
+
+// TestExecCategorizer tests the protocol of an external categorizer program
+func TestExecCategorizer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "classify.sh")
+	os.WriteFile(script, []byte(`#!/bin/sh
+input=$(cat)
+case "$input" in
+*'"name":"Budget.xlsx"'*'"ext":".xlsx"'*) echo '{"category": "work", "confidence": 0.8}' ;;
+*'"name":"Broken.lnk"'*) echo 'not json' ;;
+*) echo '{}' ;;
+esac
+`), 0755)
+	categorizer := execCategorizer{command: []string{script}, timeout: defaultCategorizerTimeout}
+
+	file := filepath.Join(dir, "Budget.xlsx")
+	os.WriteFile(file, []byte("data"), 0644)
+	answer, err := categorizer.Categorize(fileMetadata(file))
+	if err != nil || answer.Category != "work" || answer.Confidence != 0.8 {
+		t.Errorf("Unexpected answer %+v (err %v)", answer, err)
+	}
+	if metadata := fileMetadata(file); metadata.Path != file || metadata.Size != 4 || metadata.Modified.IsZero() {
+		t.Errorf("Unexpected metadata %+v", metadata)
+	}
+	if _, err := categorizer.Categorize(FileMetadata{Name: "Broken.lnk"}); err == nil {
+		t.Error("Expected an error for invalid output")
+	}
+
+	// Loaded from categories.yml
+	path := filepath.Join(dir, "categories.yml")
+	os.WriteFile(path, []byte("categories:\n  work:\n    keywords: [office]\ncategorizer:\n  command: ["+script+"]\n"), 0644)
+	config, err := loadCategoriesConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := categorizeFile(file, config); got != "work" {
+		t.Errorf("Expected the program's category, got %q", got)
+	}
+}
@@ -0,0 +1,152 @@
+package focusmode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ChimeConfig represents the sound played when a focus session ends or a break starts
+type ChimeConfig struct {
+	Enabled bool   `yaml:"enabled" doc:"Play a chime when a focus session completes and when a break starts" default:"false"`
+	Sound   string `yaml:"sound" doc:"Audio file to play, with ~/ for the home directory (only .wav on Windows without ffplay); empty plays a built-in chime" example:"~/Music/bell.wav"`
+	Volume  int    `yaml:"volume" doc:"Playback volume 0-100" default:"80"`
+}
+
+const defaultChimeVolume = 80
+
+// chimeNotes are the pitches in Hz of the built-in chime, a falling two-note bell
+var chimeNotes = []float64{659.25, 523.25}
+
+// chimeNoteSeconds is how long each note of the built-in chime rings
+const chimeNoteSeconds = 0.7
+
+// writeChimeWAV writes the built-in chime as a mono 16-bit PCM WAV file
+// Each note is a sine with a quieter octave above it, fading out like a struck bell
+func writeChimeWAV(w io.Writer) error {
+	noteSamples := int(chimeNoteSeconds * noiseSampleRate)
+	if err := writeWAVHeader(w, noteSamples*len(chimeNotes)); err != nil {
+		return err
+	}
+	buf := make([]byte, 2)
+	for _, freq := range chimeNotes {
+		for i := 0; i < noteSamples; i++ {
+			t := float64(i) / noiseSampleRate
+			envelope := math.Exp(-4 * t)
+			if i < 200 {
+				envelope *= float64(i) / 200 // Soften the strike so it doesn't click
+			}
+			sample := envelope * (0.5*math.Sin(2*math.Pi*freq*t) + 0.15*math.Sin(4*math.Pi*freq*t))
+			binary.LittleEndian.PutUint16(buf, uint16(int16(sample*math.MaxInt16)))
+			if _, err := w.Write(buf); err != nil {
+				return fmt.Errorf("error writing WAV data: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveChimeSound returns the audio file to play, writing the built-in chime if none is configured
+func resolveChimeSound(sound string) (string, error) {
+	if sound != "" {
+		if strings.HasPrefix(sound, "~/") {
+			home, err := userHomeDir()
+			if err != nil {
+				return "", err
+			}
+			sound = filepath.Join(home, sound[2:])
+		}
+		if _, err := os.Stat(sound); err != nil {
+			return "", fmt.Errorf("chime sound file not found: %s", sound)
+		}
+		return sound, nil
+	}
+
+	return generatedSound("chime.wav", writeChimeWAV)
+}
+
+// chimePlayer plays a chime when a focus session completes and when a break starts
+type chimePlayer struct {
+	config ChimeConfig
+	play   func(file string, volume int) error
+
+	mu     sync.Mutex
+	failed bool // Playing failed, which is only reported once
+}
+
+// newChimePlayer creates a chime player, defaulting the volume
+func newChimePlayer(config ChimeConfig) *chimePlayer {
+	if config.Volume <= 0 {
+		config.Volume = defaultChimeVolume
+	}
+	config.Volume = clampVolume(config.Volume)
+	return &chimePlayer{config: config, play: playSoundOnce}
+}
+
+// playSoundOnce plays a file once with the platform's audio player and waits for it to finish
+func playSoundOnce(file string, volume int) error {
+	name, args, err := playerCommandArgs(runtime.GOOS, file, volume, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	if err := exec.Command(name, args...).Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+// ring plays the chime, warning once if it can't be played
+func (c *chimePlayer) ring() {
+	file, err := resolveChimeSound(c.config.Sound)
+	if err == nil {
+		err = c.play(file, c.config.Volume)
+	}
+	if err != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.failed {
+			c.failed = true
+			fmt.Fprintf(os.Stderr, "\nWarning: chime unavailable: %v\n", err)
+		}
+	}
+}
+
+// OnStart chimes in the background when a break starts
+func (c *chimePlayer) OnStart(fs *FocusSession) {
+	if fs.Break {
+		go c.ring()
+	}
+}
+
+// OnPause does nothing
+func (c *chimePlayer) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (c *chimePlayer) OnResume(fs *FocusSession) {}
+
+// OnEnd chimes when a focus session ran to the end, waiting for the sound as the process may exit next
+// Stopping a session early doesn't chime; you are at the keyboard then
+func (c *chimePlayer) OnEnd(fs *FocusSession) {
+	if !fs.Break && fs.State == StateCompleted {
+		c.ring()
+	}
+}
+
+// DryRun reports when the chime would play
+func (c *chimePlayer) DryRun(fs *FocusSession) []string {
+	sound := c.config.Sound
+	if sound == "" {
+		sound = "the built-in chime"
+	}
+	if fs.Break {
+		return []string{fmt.Sprintf("play %s at volume %d%% when the break starts", sound, c.config.Volume)}
+	}
+	return []string{fmt.Sprintf("play %s at volume %d%% when the session completes", sound, c.config.Volume)}
+}
@@ -0,0 +1,81 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWriteChimeWAV tests that the built-in chime is a valid WAV file of both notes
+func TestWriteChimeWAV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeChimeWAV(&buf); err != nil {
+		t.Fatalf("writeChimeWAV() returned error: %v", err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Fatal("Expected a RIFF/WAVE header")
+	}
+	samples := int(chimeNoteSeconds*noiseSampleRate) * len(chimeNotes)
+	if size := binary.LittleEndian.Uint32(data[40:44]); int(size) != samples*2 || len(data) != 44+samples*2 {
+		t.Errorf("Expected %d samples, got a data size of %d and %d bytes", samples, size, len(data))
+	}
+}
+
+// TestResolveChimeSound tests expanding ~/ and reporting a missing sound file
+func TestResolveChimeSound(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.WriteFile(filepath.Join(home, "bell.wav"), []byte("RIFF"), 0644)
+	if path, err := resolveChimeSound("~/bell.wav"); err != nil || path != filepath.Join(home, "bell.wav") {
+		t.Errorf("resolveChimeSound(~/bell.wav) = %q, %v", path, err)
+	}
+	if _, err := resolveChimeSound(filepath.Join(home, "gong.wav")); err == nil {
+		t.Error("Expected an error for a missing sound file")
+	}
+}
+
+// TestChimePlayer tests that the chime plays when a break starts and a session completes, but not
+// when a session is stopped early
+func TestChimePlayer(t *testing.T) {
+	played := make(chan string, 10)
+	sound := filepath.Join(t.TempDir(), "bell.wav")
+	os.WriteFile(sound, []byte("RIFF"), 0644)
+	c := newChimePlayer(ChimeConfig{Enabled: true, Sound: sound, Volume: 150})
+	c.play = func(file string, volume int) error {
+		if volume != 100 {
+			t.Errorf("Expected the volume clamped to 100, got %d", volume)
+		}
+		played <- file
+		return nil
+	}
+
+	session := &FocusSession{Mode: "focusmode", State: StateRunning}
+	c.OnStart(session)
+	session.State = StateInterrupted
+	c.OnEnd(session)
+	select {
+	case <-played:
+		t.Fatal("Expected no chime for a session stopped early")
+	default:
+	}
+	session.State = StateCompleted
+	c.OnEnd(session)
+	if len(played) != 1 {
+		t.Fatalf("Expected a chime when the session completes, got %d", len(played))
+	}
+	<-played
+
+	c.OnStart(&FocusSession{Break: true, State: StateRunning})
+	select {
+	case file := <-played:
+		if file != sound {
+			t.Errorf("Expected %s played, got %s", sound, file)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a chime when the break starts")
+	}
+}
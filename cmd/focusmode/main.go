@@ -0,0 +1,9 @@
+// Command focusmode moves desktop shortcuts out of the way for focus and game modes
+// The work is done by the focusmode package, which other programs can import too
+package main
+
+import "focusmode"
+
+func main() {
+	focusmode.Main()
+}
@@ -0,0 +1,340 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	neutralColorTemperature = 6500
+	minColorTemperature     = 1000
+	maxColorTemperature     = 10000
+	gnomeColorSchema        = "org.gnome.settings-daemon.plugins.color"
+)
+
+// gnomeNightLightKeys are the GNOME settings saved before and restored after a change
+var gnomeNightLightKeys = []string{
+	"night-light-enabled",
+	"night-light-temperature",
+	"night-light-schedule-automatic",
+	"night-light-schedule-from",
+	"night-light-schedule-to",
+}
+
+// colorState remembers how the screen color was changed so it can be restored
+type colorState struct {
+	Backend  string            `json:"backend"`
+	Kelvin   int               `json:"kelvin"`
+	Previous map[string]string `json:"previous,omitempty"` // previous GNOME settings
+}
+
+// kelvinToRGB returns the white point multipliers (0-1) for a color temperature
+// using Tanner Helland's approximation of the black-body curve
+func kelvinToRGB(kelvin int) (float64, float64, float64) {
+	temp := float64(kelvin) / 100
+	var r, g, b float64
+
+	if temp <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(temp) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(temp-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(temp-60, -0.0755148492)
+	}
+
+	switch {
+	case temp >= 66:
+		b = 255
+	case temp <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(temp-10) - 305.0447927307
+	}
+
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(255, v)) / 255 }
+	return clamp(r), clamp(g), clamp(b)
+}
+
+// nightShiftStrength maps a color temperature to a 0-100 Night Shift strength
+func nightShiftStrength(kelvin int) int {
+	const warmest = 2700
+	strength := float64(neutralColorTemperature-kelvin) / float64(neutralColorTemperature-warmest) * 100
+	return int(math.Round(math.Max(0, math.Min(100, strength))))
+}
+
+// gammaRampScript returns a PowerShell script that sets the display gamma ramp
+// to the given channel multipliers (1, 1, 1 resets to neutral)
+func gammaRampScript(r, g, b float64) string {
+	return fmt.Sprintf(`Add-Type @"
+using System;
+using System.Runtime.InteropServices;
+public class FocusModeGamma {
+  [DllImport("user32.dll")] public static extern IntPtr GetDC(IntPtr hWnd);
+  [DllImport("gdi32.dll")] public static extern bool SetDeviceGammaRamp(IntPtr hdc, ushort[] ramp);
+  public static bool Apply(double r, double g, double b) {
+    ushort[] ramp = new ushort[768];
+    for (int i = 0; i < 256; i++) {
+      ramp[i] = (ushort)Math.Min(65535, i * 256 * r);
+      ramp[i + 256] = (ushort)Math.Min(65535, i * 256 * g);
+      ramp[i + 512] = (ushort)Math.Min(65535, i * 256 * b);
+    }
+    return SetDeviceGammaRamp(GetDC(IntPtr.Zero), ramp);
+  }
+}
+"@
+if (-not [FocusModeGamma]::Apply(%.4f, %.4f, %.4f)) { exit 1 }`, r, g, b)
+}
+
+// colorStatePath returns the path where the color change is remembered
+func colorStatePath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "color.json"), nil
+}
+
+// runQuiet runs a command and includes its output in the error on failure
+func runQuiet(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// hasGnomeNightLight reports whether GNOME Night Light settings are available
+func hasGnomeNightLight() bool {
+	if _, err := exec.LookPath("gsettings"); err != nil {
+		return false
+	}
+	_, err := runQuiet("gsettings", "get", gnomeColorSchema, "night-light-enabled")
+	return err == nil
+}
+
+// setColorTemperature warms or cools the screen using the best backend for the platform
+func setColorTemperature(kelvin int) (*colorState, error) {
+	if kelvin < minColorTemperature || kelvin > maxColorTemperature {
+		return nil, fmt.Errorf("color temperature must be between %dK and %dK, got: %dK", minColorTemperature, maxColorTemperature, kelvin)
+	}
+	if err := guardWrite("change the screen color temperature"); err != nil {
+		return nil, err
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		r, g, b := kelvinToRGB(kelvin)
+		if _, err := runQuiet("powershell", "-NoProfile", "-NonInteractive", "-Command", gammaRampScript(r, g, b)); err != nil {
+			return nil, err
+		}
+		return &colorState{Backend: "gamma", Kelvin: kelvin}, nil
+	case "darwin":
+		if _, err := exec.LookPath("nightlight"); err != nil {
+			return nil, fmt.Errorf("Night Shift control requires the 'nightlight' CLI (brew install smudge/smudge/nightlight)")
+		}
+		if _, err := runQuiet("nightlight", "temp", fmt.Sprint(nightShiftStrength(kelvin))); err != nil {
+			return nil, err
+		}
+		if _, err := runQuiet("nightlight", "on"); err != nil {
+			return nil, err
+		}
+		return &colorState{Backend: "nightshift", Kelvin: kelvin}, nil
+	case "linux":
+		if hasGnomeNightLight() {
+			previous := make(map[string]string)
+			for _, key := range gnomeNightLightKeys {
+				value, err := runQuiet("gsettings", "get", gnomeColorSchema, key)
+				if err != nil {
+					return nil, err
+				}
+				previous[key] = value
+			}
+			// Force Night Light on for the whole day at the requested temperature
+			settings := [][]string{
+				{"night-light-schedule-automatic", "false"},
+				{"night-light-schedule-from", "0.0"},
+				{"night-light-schedule-to", "23.99"},
+				{"night-light-temperature", fmt.Sprintf("uint32 %d", kelvin)},
+				{"night-light-enabled", "true"},
+			}
+			for _, setting := range settings {
+				if _, err := runQuiet("gsettings", "set", gnomeColorSchema, setting[0], setting[1]); err != nil {
+					return nil, err
+				}
+			}
+			return &colorState{Backend: "gnome", Kelvin: kelvin, Previous: previous}, nil
+		}
+		if _, err := exec.LookPath("redshift"); err == nil {
+			if _, err := runQuiet("redshift", "-P", "-O", fmt.Sprint(kelvin)); err != nil {
+				return nil, err
+			}
+			return &colorState{Backend: "redshift", Kelvin: kelvin}, nil
+		}
+		return nil, fmt.Errorf("no color temperature backend found (GNOME Night Light or redshift)")
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// resetColorTemperature undoes a change made by setColorTemperature
+func resetColorTemperature(state *colorState) error {
+	if err := guardWrite("reset the screen color temperature"); err != nil {
+		return err
+	}
+	switch state.Backend {
+	case "gamma":
+		_, err := runQuiet("powershell", "-NoProfile", "-NonInteractive", "-Command", gammaRampScript(1, 1, 1))
+		return err
+	case "nightshift":
+		_, err := runQuiet("nightlight", "off")
+		return err
+	case "gnome":
+		for _, key := range gnomeNightLightKeys {
+			value, ok := state.Previous[key]
+			if !ok {
+				continue
+			}
+			if _, err := runQuiet("gsettings", "set", gnomeColorSchema, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "redshift":
+		_, err := runQuiet("redshift", "-x")
+		return err
+	default:
+		return fmt.Errorf("unknown color backend: %s", state.Backend)
+	}
+}
+
+// applyModeColorTemperature changes the screen color for a mode and remembers how to undo it
+// If a previous change is still active it is kept as the one to restore
+func applyModeColorTemperature(modeName string, modeConfig *ModeConfig, dryRun bool) {
+	if modeConfig.ColorTemperature == 0 {
+		return
+	}
+	if dryRun {
+		fmt.Printf("[DRY RUN] Would set screen color temperature to %dK\n", modeConfig.ColorTemperature)
+		return
+	}
+
+	path, err := colorStatePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	var previous *colorState
+	if data, err := os.ReadFile(path); err == nil {
+		previous = &colorState{}
+		if json.Unmarshal(data, previous) != nil {
+			previous = nil
+		}
+	}
+
+	state, err := setColorTemperature(modeConfig.ColorTemperature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not change color temperature for %s: %v\n", modeName, err)
+		return
+	}
+	// Keep the original settings when switching between two warm modes
+	if previous != nil && previous.Backend == state.Backend {
+		state.Previous = previous.Previous
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = writeFile(path, data, 0644)
+	}
+	if err == nil {
+		ownByUser(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save color state: %v\n", err)
+	}
+	fmt.Printf("%sScreen color temperature set to %dK\n", glyph("🌙 "), modeConfig.ColorTemperature)
+}
+
+// restoreColorTemperature undoes the remembered screen color change, if any
+func restoreColorTemperature(dryRun bool) {
+	path, err := colorStatePath()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var state colorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid color state: %v\n", err)
+		return
+	}
+	if dryRun {
+		fmt.Println("[DRY RUN] Would restore screen color temperature")
+		return
+	}
+
+	if err := resetColorTemperature(&state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not restore color temperature: %v\n", err)
+		return
+	}
+	removeFile(path)
+	fmt.Println(glyph("☀️  ") + "Screen color temperature restored")
+}
+
+// colorTemperatureHook applies a mode's color temperature for the duration of a session
+type colorTemperatureHook struct{}
+
+// OnStart warms the screen if the session's mode asks for it
+func (colorTemperatureHook) OnStart(fs *FocusSession) {
+	if fs.Break {
+		return
+	}
+	modeConfig, err := fs.Config.getModeConfig(fs.Mode)
+	if err != nil {
+		return
+	}
+	applyModeColorTemperature(fs.Mode, modeConfig, false)
+}
+
+// OnPause does nothing
+func (colorTemperatureHook) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (colorTemperatureHook) OnResume(fs *FocusSession) {}
+
+// OnEnd restores the screen color when the session restores its shortcuts
+func (colorTemperatureHook) OnEnd(fs *FocusSession) {
+	if fs.Break || !fs.AutoRestore {
+		return
+	}
+	modeConfig, err := fs.Config.getModeConfig(fs.Mode)
+	if err != nil || modeConfig.ColorTemperature == 0 {
+		return
+	}
+	restoreColorTemperature(false)
+}
+
+// DryRun reports the color changes the session would make
+func (colorTemperatureHook) DryRun(fs *FocusSession) []string {
+	if fs.Break {
+		return nil
+	}
+	modeConfig, err := fs.Config.getModeConfig(fs.Mode)
+	if err != nil || modeConfig.ColorTemperature == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("set screen color temperature to %dK", modeConfig.ColorTemperature)}
+	if fs.AutoRestore {
+		lines = append(lines, "restore screen color temperature when the session ends")
+	}
+	return lines
+}
@@ -0,0 +1,53 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKelvinToRGB(t *testing.T) {
+	r, g, b := kelvinToRGB(6600)
+	if r != 1 || g < 0.99 || b != 1 {
+		t.Errorf("6600K should be neutral white, got (%.2f, %.2f, %.2f)", r, g, b)
+	}
+
+	r, g, b = kelvinToRGB(3400)
+	if r != 1 {
+		t.Errorf("Expected full red at 3400K, got %.2f", r)
+	}
+	if !(b < g && g < r) {
+		t.Errorf("Expected warm colors to reduce blue most, got (%.2f, %.2f, %.2f)", r, g, b)
+	}
+
+	_, _, b = kelvinToRGB(1500)
+	if b != 0 {
+		t.Errorf("Expected no blue at 1500K, got %.2f", b)
+	}
+}
+
+func TestNightShiftStrength(t *testing.T) {
+	cases := map[int]int{6500: 0, 8000: 0, 2700: 100, 1500: 100, 4600: 50}
+	for kelvin, expected := range cases {
+		if got := nightShiftStrength(kelvin); got != expected {
+			t.Errorf("nightShiftStrength(%d) = %d, expected %d", kelvin, got, expected)
+		}
+	}
+}
+
+func TestGammaRampScript(t *testing.T) {
+	script := gammaRampScript(1, 0.8, 0.5)
+	if !strings.Contains(script, "Apply(1.0000, 0.8000, 0.5000)") {
+		t.Errorf("Expected channel multipliers in script, got: %s", script)
+	}
+	if !strings.Contains(script, "SetDeviceGammaRamp") {
+		t.Error("Expected script to call SetDeviceGammaRamp")
+	}
+}
+
+func TestSetColorTemperatureRejectsOutOfRange(t *testing.T) {
+	for _, kelvin := range []int{500, 20000} {
+		if _, err := setColorTemperature(kelvin); err == nil {
+			t.Errorf("Expected error for %dK", kelvin)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// configField describes one configuration key, read from the struct it is loaded into
+// Descriptions, defaults, examples and allowed values come from the doc, default, example and enum struct tags
+type configField struct {
+	Key     string
+	Type    reflect.Type
+	Doc     string
+	Default string
+	Example string
+	Enum    string // comma-separated allowed values
+}
+
+// configFields returns the keys of a config struct in declaration order
+// Fields that are not read from YAML are skipped
+func configFields(t reflect.Type) []configField {
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		fields = append(fields, configField{
+			Key:     key,
+			Type:    f.Type,
+			Doc:     f.Tag.Get("doc"),
+			Default: f.Tag.Get("default"),
+			Example: f.Tag.Get("example"),
+			Enum:    f.Tag.Get("enum"),
+		})
+	}
+	return fields
+}
+
+// nestedStruct returns the struct type holding a field's entries and the path suffix that reaches them,
+// e.g. ".<name>" for a map of structs or "[]" for a list of structs
+func nestedStruct(t reflect.Type) (reflect.Type, string) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nestedStruct(t.Elem())
+	case reflect.Struct:
+		return t, ""
+	case reflect.Map:
+		elem, suffix := nestedStruct(t.Elem())
+		return elem, ".<name>" + suffix
+	case reflect.Slice:
+		elem, suffix := nestedStruct(t.Elem())
+		return elem, "[]" + suffix
+	}
+	return nil, ""
+}
+
+// configTypeName returns the name of a field's type as shown in the documentation
+func configTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return configTypeName(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	case reflect.String:
+		return "string"
+	case reflect.Struct:
+		return "object"
+	case reflect.Slice:
+		return "list of " + configTypeName(t.Elem())
+	case reflect.Map:
+		return "map of name to " + configTypeName(t.Elem())
+	}
+	return t.Kind().String()
+}
+
+// markdownCell escapes text for use in a Markdown table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// writeConfigDocs writes a Markdown reference for the config file loaded into t
+// Each struct gets its own table, headed by the path that reaches it
+func writeConfigDocs(w io.Writer, file string, t reflect.Type) {
+	fmt.Fprintf(w, "# %s reference\n\n", file)
+	fmt.Fprintf(w, "Generated by `focusmode config docs` from the definitions FocusMode loads %s into.\n", file)
+	writeConfigSection(w, "Top level", "", t)
+}
+
+// writeConfigSection writes the table for one struct and then the sections of its nested structs
+func writeConfigSection(w io.Writer, title, path string, t reflect.Type) {
+	fields := configFields(t)
+	fmt.Fprintf(w, "\n## %s\n\n", title)
+	fmt.Fprintln(w, "| Key | Type | Default | Example | Description |")
+	fmt.Fprintln(w, "|-----|------|---------|---------|-------------|")
+	for _, f := range fields {
+		var defaultValue, example string
+		if f.Default != "" {
+			defaultValue = "`" + f.Default + "`"
+		}
+		if f.Example != "" {
+			example = "`" + f.Example + "`"
+		}
+		doc := f.Doc
+		if f.Enum != "" {
+			doc += " (one of `" + strings.ReplaceAll(f.Enum, ",", "`, `") + "`)"
+		}
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s | %s |\n",
+			f.Key, configTypeName(f.Type), markdownCell(defaultValue), markdownCell(example), markdownCell(doc))
+	}
+
+	for _, f := range fields {
+		if nested, suffix := nestedStruct(f.Type); nested != nil {
+			fieldPath := strings.TrimPrefix(path+"."+f.Key+suffix, ".")
+			writeConfigSection(w, "`"+fieldPath+"`", fieldPath, nested)
+		}
+	}
+}
+
+// runConfigDocs handles "config docs", which prints the config file reference
+func runConfigDocs(args []string) {
+	flags := flag.NewFlagSet("config docs", flag.ExitOnError)
+	output := flags.String("o", "", "Write the documentation to this file instead of stdout")
+	flags.Parse(args)
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := createFile(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	writeConfigDocs(w, "profile.yml", reflect.TypeOf(Config{}))
+	fmt.Fprintln(w)
+	writeConfigDocs(w, "categories.yml", reflect.TypeOf(CategoriesConfig{}))
+}
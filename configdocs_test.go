@@ -0,0 +1,119 @@
+package focusmode
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestConfigFieldsDocumented tests that every config key has a description
+func TestConfigFieldsDocumented(t *testing.T) {
+	var check func(path string, typ reflect.Type)
+	check = func(path string, typ reflect.Type) {
+		for _, f := range configFields(typ) {
+			if f.Doc == "" {
+				t.Errorf("%s%s has no doc tag", path, f.Key)
+			}
+			if nested, suffix := nestedStruct(f.Type); nested != nil {
+				check(path+f.Key+suffix+".", nested)
+			}
+		}
+	}
+	check("", reflect.TypeOf(Config{}))
+	check("", reflect.TypeOf(CategoriesConfig{}))
+}
+
+// TestConfigDefaultsMatchCode tests that documented defaults are the ones the code applies
+func TestConfigDefaultsMatchCode(t *testing.T) {
+	defaultOf := func(typ reflect.Type, key string) string {
+		for _, f := range configFields(typ) {
+			if f.Key == key {
+				return f.Default
+			}
+		}
+		t.Fatalf("%s has no key %s", typ.Name(), key)
+		return ""
+	}
+	ints := []struct {
+		typ  reflect.Type
+		key  string
+		want int
+	}{
+		{reflect.TypeOf(AmbientConfig{}), "volume", defaultAmbientVolume},
+		{reflect.TypeOf(AmbientConfig{}), "duck_volume", defaultAmbientDuckVolume},
+		{reflect.TypeOf(GitHubConfig{}), "grace_minutes", defaultGitHubGraceMinutes},
+		{reflect.TypeOf(AutomationConfig{}), "grace_seconds", defaultGraceSeconds},
+		{reflect.TypeOf(IDEWatchConfig{}), "minutes", defaultIDEWatchMinutes},
+		{reflect.TypeOf(IDEWatchConfig{}), "duration", defaultIDEWatchDuration},
+		{reflect.TypeOf(DaemonConfig{}), "shutdown_timeout", int(defaultShutdownTimeout.Seconds())},
+		{reflect.TypeOf(MeetingConfig{}), "duration", defaultMeetingDuration},
+		{reflect.TypeOf(ArchiveConfig{}), "days", defaultArchiveDays},
+		{reflect.TypeOf(CategorizerConfig{}), "min_confidence", defaultCategorizerMinConfidence},
+		{reflect.TypeOf(CategorizerConfig{}), "timeout_seconds", int(defaultCategorizerTimeout.Seconds())},
+		{reflect.TypeOf(RetryPolicy{}), "max_attempts", defaultRetryAttempts},
+		{reflect.TypeOf(RetryPolicy{}), "base_delay_ms", defaultRetryBaseDelayMS},
+		{reflect.TypeOf(RetryPolicy{}), "jitter", defaultRetryJitter},
+	}
+	for _, tt := range ints {
+		if got := defaultOf(tt.typ, tt.key); got != strconv.Itoa(tt.want) {
+			t.Errorf("%s.%s documents default %q, code uses %d", tt.typ.Name(), tt.key, got, tt.want)
+		}
+	}
+
+	if got := defaultOf(reflect.TypeOf(WakaTimeConfig{}), "api_url"); got != defaultWakaTimeAPIURL {
+		t.Errorf("wakatime.api_url documents default %q, code uses %q", got, defaultWakaTimeAPIURL)
+	}
+	if got, want := defaultOf(reflect.TypeOf(TTSConfig{}), "announce_at"), yamlList(defaultAnnounceAt); got != want {
+		t.Errorf("tts.announce_at documents default %q, code uses %q", got, want)
+	}
+	if got, want := defaultOf(reflect.TypeOf(MeetingConfig{}), "hide_categories"), yamlList(defaultMeetingCategories); got != want {
+		t.Errorf("meeting.hide_categories documents default %q, code uses %q", got, want)
+	}
+	if got := defaultOf(reflect.TypeOf(MeetingConfig{}), "slack_status"); got != defaultSlackStatus {
+		t.Errorf("meeting.slack_status documents default %q, code uses %q", got, defaultSlackStatus)
+	}
+	if got := tagValue(defaultOf(reflect.TypeOf(MeetingConfig{}), "slack_emoji")); got != defaultSlackEmoji {
+		t.Errorf("meeting.slack_emoji documents default %v, code uses %q", got, defaultSlackEmoji)
+	}
+	if got := tagValue(defaultOf(reflect.TypeOf(ScreenLockConfig{}), "after")); got != defaultScreenLockAfter {
+		t.Errorf("screen_lock.after documents default %v, code uses %q", got, defaultScreenLockAfter)
+	}
+	if got, want := defaultOf(reflect.TypeOf(IDEWatchConfig{}), "apps"), yamlList(defaultIDEApps); got != want {
+		t.Errorf("ide_watch.apps documents default %q, code uses %q", got, want)
+	}
+}
+
+// yamlList formats a list the way default tags write it
+func yamlList[T any](items []T) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// TestWriteConfigDocs tests that nested settings get their own sections
+func TestWriteConfigDocs(t *testing.T) {
+	var out bytes.Buffer
+	writeConfigDocs(&out, "profile.yml", reflect.TypeOf(Config{}))
+	docs := out.String()
+	for _, want := range []string{
+		"# profile.yml reference",
+		"## `modes.<name>`",
+		"## `routines.<name>[]`",
+		"## `presets.<name>`",
+		"| `duck_volume` | integer | `20` |  | Volume used while ducked 0-100 |",
+		"| `auto_restore` | boolean | `true` |",
+		"| `routines` | map of name to list of object |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("Expected %q in docs:\n%s", want, docs)
+		}
+	}
+	if strings.Contains(docs, "policy") {
+		t.Error("Expected the machine policy to be left out of the profile docs")
+	}
+}
@@ -0,0 +1,929 @@
+package focusmode
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// editorNamePattern limits the names of modes, categories and routines created in the editor,
+// which are typed on the command line afterwards
+var editorNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// configEditor edits the modes, categories and routines of a profile through numbered forms
+// Every answer is checked as it is given and the config is linted after each change; nothing is
+// written until the user saves
+type configEditor struct {
+	configPath     string
+	categoriesPath string
+
+	// The files as read, so sections the editor doesn't touch keep their comments
+	profile       yaml.Node
+	categoryFile  yaml.Node
+	config        Config // What profile.yml says, without defaults, built-in modes or policy
+	categories    CategoriesConfig
+	configDirty   bool
+	categoryDirty bool
+	warnings      []string // Lint warnings after the last change
+
+	desktopFiles func() ([]string, error) // Files offered by the shortcut picker
+	lines        <-chan string
+	out          io.Writer
+	closed       bool // Input ended; every form returns and nothing is saved
+}
+
+// newConfigEditor reads the config and categories files to edit
+// A missing config file starts empty and a missing categories file starts from the defaults
+func newConfigEditor(configPath, categoriesPath string, lines <-chan string, out io.Writer) (*configEditor, error) {
+	e := &configEditor{
+		configPath:     configPath,
+		categoriesPath: categoriesPath,
+		desktopFiles:   getAllDesktopShortcuts,
+		lines:          lines,
+		out:            out,
+	}
+	if err := readEditorDocument(configPath, nil, &e.profile); err != nil {
+		return nil, err
+	}
+	migrateDeprecatedKeys(&e.profile, deprecatedConfigKeys, warnDeprecatedOnce)
+	if err := e.profile.Decode(&e.config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", configPath, err)
+	}
+	if err := readEditorDocument(categoriesPath, getDefaultCategoriesConfig(), &e.categoryFile); err != nil {
+		return nil, err
+	}
+	if err := e.categoryFile.Decode(&e.categories); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", categoriesPath, err)
+	}
+	if e.config.Modes == nil {
+		e.config.Modes = make(map[string]ModeConfig)
+	}
+	if e.categories.Categories == nil {
+		e.categories.Categories = make(map[string]CategoryConfig)
+	}
+	e.warnings = e.validate()
+	return e, nil
+}
+
+// readEditorDocument parses a YAML file into document
+// A missing or empty file becomes an empty mapping, or fallback encoded when it is not nil
+func readEditorDocument(path string, fallback interface{}, document *yaml.Node) error {
+	data, err := readYAMLFile(path)
+	if errors.Is(err, os.ErrNotExist) && fallback != nil {
+		data, err = yaml.Marshal(fallback)
+	} else if errors.Is(err, os.ErrNotExist) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	parsed, err := parseYAMLDocument(data)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	*document = *parsed
+	if document.Kind == 0 || len(document.Content) == 0 {
+		*document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", path)
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key in a YAML mapping, keeping the comments around it
+// An empty value removes the key; zero fields of the value are left out
+func setMappingValue(mapping *yaml.Node, key string, value interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+	pruneZeroFields(&node)
+	empty := len(node.Content) == 0 && node.Kind != yaml.ScalarNode || node.Kind == yaml.ScalarNode && node.Value == ""
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if empty {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return nil
+		}
+		old := mapping.Content[i+1]
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		mapping.Content[i+1] = &node
+		return nil
+	}
+	if !empty {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+	}
+	return nil
+}
+
+// pruneZeroFields drops mapping entries that hold their type's zero value, such as "move_all: false",
+// so edited modes and steps read like hand-written ones
+func pruneZeroFields(node *yaml.Node) {
+	for _, child := range node.Content {
+		pruneZeroFields(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	kept := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		zero := false
+		switch value.Kind {
+		case yaml.ScalarNode:
+			zero = value.Value == "" || value.Tag == "!!bool" && value.Value == "false" || value.Tag == "!!int" && value.Value == "0"
+		case yaml.SequenceNode, yaml.MappingNode:
+			zero = len(value.Content) == 0
+		}
+		if !zero {
+			kept = append(kept, node.Content[i], value)
+		}
+	}
+	node.Content = kept
+}
+
+// encode returns the edited files, with the sections the editor manages replaced
+func (e *configEditor) encode() (profile, categories []byte, err error) {
+	root := e.profile.Content[0]
+	if err := setMappingValue(root, "modes", e.config.Modes); err != nil {
+		return nil, nil, err
+	}
+	if err := setMappingValue(root, "default_mode", e.config.DefaultMode); err != nil {
+		return nil, nil, err
+	}
+	if err := setMappingValue(root, "routines", e.config.Routines); err != nil {
+		return nil, nil, err
+	}
+	catRoot := e.categoryFile.Content[0]
+	if err := setMappingValue(catRoot, "categories", e.categories.Categories); err != nil {
+		return nil, nil, err
+	}
+	if err := setMappingValue(catRoot, "category_order", e.categories.CategoryOrder); err != nil {
+		return nil, nil, err
+	}
+	if profile, err = encodeEditorDocument(&e.profile); err != nil {
+		return nil, nil, err
+	}
+	if categories, err = encodeEditorDocument(&e.categoryFile); err != nil {
+		return nil, nil, err
+	}
+	return profile, categories, nil
+}
+
+// encodeEditorDocument writes a document with the two-space indent the example configs use
+func encodeEditorDocument(document *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validate parses the YAML that saving would write the way readConfig does and lints the result
+func (e *configEditor) validate() []string {
+	profile, _, err := e.encode()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var config Config
+	if err := yaml.Unmarshal(profile, &config); err != nil {
+		return []string{fmt.Sprintf("error parsing YAML: %v", err)}
+	}
+	if config.DefaultMode == "" {
+		config.DefaultMode = "focusmode"
+	}
+	config.addBuiltinModes()
+	if policy, err := loadPolicy(machinePolicyPath); err == nil {
+		config.applyPolicy(policy)
+	}
+	return lintConfig(&config)
+}
+
+// changed marks a file as edited and reports lint warnings the change brought in
+func (e *configEditor) changed(categories bool) {
+	if categories {
+		e.categoryDirty = true
+	} else {
+		e.configDirty = true
+	}
+	previous := make(map[string]bool)
+	for _, warning := range e.warnings {
+		previous[warning] = true
+	}
+	e.warnings = e.validate()
+	for _, warning := range e.warnings {
+		if !previous[warning] {
+			fmt.Fprintf(e.out, "  %s%s\n", glyph("⚠️  "), warning)
+		}
+	}
+}
+
+// ask prints a prompt and returns the trimmed answer, or false once input has ended
+func (e *configEditor) ask(prompt string) (string, bool) {
+	if e.closed {
+		return "", false
+	}
+	fmt.Fprint(e.out, prompt)
+	line, ok := <-e.lines
+	if !ok {
+		fmt.Fprintln(e.out)
+		e.closed = true
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// askField asks for a new value until check accepts it
+// Enter keeps the current value and "-" clears it; check is not called for either
+func (e *configEditor) askField(label, current string, check func(string) error) (string, bool) {
+	for {
+		answer, ok := e.ask(fmt.Sprintf("%s [%s]: ", label, current))
+		switch {
+		case !ok:
+			return current, false
+		case answer == "":
+			return current, false
+		case answer == "-":
+			return "", true
+		}
+		if err := check(answer); err != nil {
+			fmt.Fprintf(e.out, "  %v\n", err)
+			continue
+		}
+		return answer, true
+	}
+}
+
+// askYes asks a yes/no question that defaults to no
+func (e *configEditor) askYes(question string) bool {
+	answer, ok := e.ask(question + " [y/N] ")
+	return ok && (strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"))
+}
+
+// checkNewName checks the name of a mode, category or routine about to be created
+func checkNewName(kind, name string, exists bool) error {
+	if !editorNamePattern.MatchString(name) {
+		return fmt.Errorf("%s names are letters, digits, - and _", kind)
+	}
+	if exists {
+		return fmt.Errorf("%s '%s' already exists", kind, name)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated answer into its non-empty entries
+func splitList(answer string) []string {
+	var items []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pickNumber parses a 1-based menu entry number
+func pickNumber(answer string, count int) (int, bool) {
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > count {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// run shows the main menu until the user saves or quits, and reports whether anything was saved
+func (e *configEditor) run() bool {
+	for !e.closed {
+		fmt.Fprintf(e.out, "\nEditing %s and %s\n", e.configPath, e.categoriesPath)
+		fmt.Fprintf(e.out, "  1. Modes (%d)\n", len(e.config.Modes))
+		fmt.Fprintf(e.out, "  2. Categories (%d)\n", len(e.categories.Categories))
+		fmt.Fprintf(e.out, "  3. Schedules: routines (%d)\n", len(e.config.Routines))
+		fmt.Fprintf(e.out, "  4. Default mode: %s\n", e.defaultMode())
+		if len(e.warnings) > 0 {
+			fmt.Fprintf(e.out, "  %d warning(s); w = show\n", len(e.warnings))
+		}
+		answer, ok := e.ask("Number, s = save, q = quit: ")
+		if !ok {
+			break
+		}
+		switch strings.ToLower(answer) {
+		case "1":
+			e.editModes()
+		case "2":
+			e.editCategories()
+		case "3":
+			e.editRoutines()
+		case "4":
+			e.editDefaultMode()
+		case "w":
+			for _, warning := range e.warnings {
+				fmt.Fprintf(e.out, "  %s%s\n", glyph("⚠️  "), warning)
+			}
+		case "s":
+			if e.save() {
+				return true
+			}
+		case "q":
+			if !e.configDirty && !e.categoryDirty || e.askYes("Discard your changes?") {
+				return false
+			}
+		default:
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+		}
+	}
+	fmt.Fprintln(e.out, "Input ended; nothing was saved")
+	return false
+}
+
+// save writes the edited files, asking first when the config has lint warnings
+func (e *configEditor) save() bool {
+	if !e.configDirty && !e.categoryDirty {
+		fmt.Fprintln(e.out, "Nothing to save")
+		return true
+	}
+	profile, categories, err := e.encode()
+	if err != nil {
+		fmt.Fprintf(e.out, "Error: %v\n", err)
+		return false
+	}
+	if len(e.warnings) > 0 {
+		for _, warning := range e.warnings {
+			fmt.Fprintf(e.out, "  %s%s\n", glyph("⚠️  "), warning)
+		}
+		if !e.askYes(fmt.Sprintf("Save with %d warning(s)?", len(e.warnings))) {
+			return false
+		}
+	}
+	if e.configDirty {
+		if err := writeFile(e.configPath, profile, 0644); err != nil {
+			fmt.Fprintf(e.out, "Error writing %s: %v\n", e.configPath, err)
+			return false
+		}
+		fmt.Fprintf(e.out, "%sSaved %s\n", glyph("✅ "), e.configPath)
+	}
+	if e.categoryDirty {
+		if err := writeFile(e.categoriesPath, categories, 0644); err != nil {
+			fmt.Fprintf(e.out, "Error writing %s: %v\n", e.categoriesPath, err)
+			return false
+		}
+		fmt.Fprintf(e.out, "%sSaved %s\n", glyph("✅ "), e.categoriesPath)
+	}
+	return true
+}
+
+// valueOrNone returns s, or "none" when it is empty
+func valueOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// defaultMode describes the mode used when none is named, which readConfig falls back to focusmode for
+func (e *configEditor) defaultMode() string {
+	if e.config.DefaultMode == "" {
+		return "focusmode (not set)"
+	}
+	return e.config.DefaultMode
+}
+
+// sortedModeNames returns the modes being edited in order
+func (e *configEditor) sortedModeNames() []string {
+	names := make([]string, 0, len(e.config.Modes))
+	for name := range e.config.Modes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// editModes lists the modes and opens the one picked
+func (e *configEditor) editModes() {
+	for !e.closed {
+		names := e.sortedModeNames()
+		fmt.Fprintln(e.out, "\nModes:")
+		for i, name := range names {
+			mode := e.config.Modes[name]
+			fmt.Fprintf(e.out, "  %2d. %-14s → %s (%d shortcut(s)", i+1, name, valueOrNone(mode.Destination), len(mode.Shortcuts))
+			if len(mode.Categories) > 0 {
+				fmt.Fprintf(e.out, ", categories: %s", strings.Join(mode.Categories, ", "))
+			}
+			fmt.Fprintln(e.out, ")")
+		}
+		answer, ok := e.ask("Number = edit, a = add, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+		if strings.EqualFold(answer, "a") {
+			name, ok := e.askField("New mode name", "", func(name string) error {
+				_, exists := e.config.Modes[name]
+				return checkNewName("mode", name, exists)
+			})
+			if ok && name != "" {
+				e.config.Modes[name] = ModeConfig{Destination: name + "_Shortcuts"}
+				e.changed(false)
+				e.editMode(name)
+			}
+			continue
+		}
+		if i, ok := pickNumber(answer, len(names)); ok {
+			e.editMode(names[i])
+		} else {
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+		}
+	}
+}
+
+// editMode shows the form of one mode
+func (e *configEditor) editMode(name string) {
+	for !e.closed {
+		mode := e.config.Modes[name]
+		temperature := "unchanged"
+		if mode.ColorTemperature != 0 {
+			temperature = fmt.Sprintf("%dK", mode.ColorTemperature)
+		}
+		moveAll := "no"
+		if mode.MoveAll {
+			moveAll = "yes"
+		}
+		fmt.Fprintf(e.out, "\nMode %s:\n", name)
+		fmt.Fprintf(e.out, "  1. Destination: %s\n", valueOrNone(mode.Destination))
+		fmt.Fprintf(e.out, "  2. Shortcuts: %s\n", valueOrNone(strings.Join(mode.Shortcuts, ", ")))
+		fmt.Fprintf(e.out, "  3. Categories: %s\n", valueOrNone(strings.Join(mode.Categories, ", ")))
+		fmt.Fprintf(e.out, "  4. Move all: %s\n", moveAll)
+		fmt.Fprintf(e.out, "  5. Color temperature: %s\n", temperature)
+		answer, ok := e.ask("Number = edit, r = rename, d = delete, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+
+		edited := true
+		switch strings.ToLower(answer) {
+		case "1":
+			mode.Destination, edited = e.askField("Destination folder (- = default)", mode.Destination, func(value string) error {
+				if strings.Contains(value, "..") {
+					return fmt.Errorf("the destination can't leave the folder that holds the desktop")
+				}
+				return nil
+			})
+		case "2":
+			mode.Shortcuts, edited = e.pickShortcuts(mode.Shortcuts, mode.Destination)
+		case "3":
+			var list string
+			list, edited = e.askField("Categories, comma-separated (- = none)", strings.Join(mode.Categories, ", "), func(value string) error {
+				for _, category := range splitList(value) {
+					if _, exists := e.categories.Categories[category]; !exists && category != string(CategoryOther) {
+						return fmt.Errorf("unknown category '%s' (available: %s)", category, strings.Join(e.categoryKeys(), ", "))
+					}
+				}
+				return nil
+			})
+			mode.Categories = splitList(list)
+		case "4":
+			mode.MoveAll = !mode.MoveAll
+		case "5":
+			var value string
+			value, edited = e.askField(fmt.Sprintf("Color temperature in Kelvin, %d-%d (- = unchanged)", minColorTemperature, maxColorTemperature),
+				strings.TrimSuffix(temperature, "K"), func(value string) error {
+					kelvin, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(value), "K"))
+					if err != nil || kelvin < minColorTemperature || kelvin > maxColorTemperature {
+						return fmt.Errorf("color temperature must be between %dK and %dK, got: %s", minColorTemperature, maxColorTemperature, value)
+					}
+					return nil
+				})
+			if edited {
+				mode.ColorTemperature, _ = strconv.Atoi(strings.TrimSuffix(strings.ToUpper(value), "K"))
+			}
+		case "r":
+			newName, ok := e.askField("New name", name, func(value string) error {
+				_, exists := e.config.Modes[value]
+				return checkNewName("mode", value, exists)
+			})
+			if ok && newName != "" {
+				e.renameMode(name, newName)
+				name = newName
+				e.changed(false)
+			}
+			continue
+		case "d":
+			if e.askYes(fmt.Sprintf("Delete mode %s?", name)) {
+				delete(e.config.Modes, name)
+				e.changed(false)
+				return
+			}
+			continue
+		default:
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+			continue
+		}
+		if edited {
+			e.config.Modes[name] = mode
+			e.changed(false)
+		}
+	}
+}
+
+// renameMode renames a mode along with the references to it from other modes, routines and presets
+func (e *configEditor) renameMode(from, to string) {
+	rename := func(names []string) {
+		for i := range names {
+			if names[i] == from {
+				names[i] = to
+			}
+		}
+	}
+	e.config.Modes[to] = e.config.Modes[from]
+	delete(e.config.Modes, from)
+	for _, mode := range e.config.Modes {
+		rename(mode.Requires)
+		rename(mode.ConflictsWith)
+	}
+	if e.config.DefaultMode == from {
+		e.config.DefaultMode = to
+	}
+	for _, steps := range e.config.Routines {
+		for i := range steps {
+			if steps[i].Mode == from {
+				steps[i].Mode = to
+			}
+		}
+	}
+	for name, preset := range e.config.Presets {
+		if preset.Mode == from {
+			preset.Mode = to
+			e.config.Presets[name] = preset
+			// Presets aren't otherwise edited here, so only rewrite them when one changed
+			setMappingValue(e.profile.Content[0], "presets", e.config.Presets)
+		}
+	}
+}
+
+// pickShortcuts lets the user toggle the mode's shortcuts in a list of what is on the desktop now
+// Listed shortcuts that aren't on the desktop can be removed but not added back
+func (e *configEditor) pickShortcuts(listed []string, destination string) ([]string, bool) {
+	files, err := e.desktopFiles()
+	if err != nil {
+		fmt.Fprintf(e.out, "Warning: %v\n", err)
+	}
+	var moves []plannedMove
+	seen := make(map[string]bool)
+	for _, name := range listed {
+		seen[strings.ToLower(name)] = true
+		moves = append(moves, plannedMove{Name: name, Selected: true, Missing: !containsFold(files, name)})
+	}
+	for _, name := range files {
+		if !seen[strings.ToLower(name)] {
+			moves = append(moves, plannedMove{Name: name})
+		}
+	}
+	if len(moves) == 0 {
+		fmt.Fprintln(e.out, "The desktop is empty and the mode lists no shortcuts")
+		return listed, false
+	}
+	selected, ok := selectMoves(moves, valueOrNone(destination), e.lines, e.out)
+	if !ok {
+		return listed, false
+	}
+	// Entries missing from the desktop can't be toggled on again, so keep ones still selected
+	for _, move := range moves {
+		if move.Missing && move.Selected && !containsFold(selected, move.Name) {
+			selected = append(selected, move.Name)
+		}
+	}
+	return selected, true
+}
+
+// editDefaultMode picks the mode used when no -mode is given
+func (e *configEditor) editDefaultMode() {
+	names := e.sortedModeNames()
+	if len(names) == 0 {
+		fmt.Fprintln(e.out, "Add a mode first")
+		return
+	}
+	for i, name := range names {
+		fmt.Fprintf(e.out, "  %2d. %s\n", i+1, name)
+	}
+	answer, ok := e.askField("Default mode (number)", e.config.DefaultMode, func(value string) error {
+		if _, ok := pickNumber(value, len(names)); !ok {
+			return fmt.Errorf("pick a number from 1 to %d", len(names))
+		}
+		return nil
+	})
+	if !ok {
+		return
+	}
+	e.config.DefaultMode = ""
+	if i, ok := pickNumber(answer, len(names)); ok {
+		e.config.DefaultMode = names[i]
+	}
+	e.changed(false)
+}
+
+// categoryKeys returns the categories being edited in match order, then any missing from the order
+func (e *configEditor) categoryKeys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range e.categories.CategoryOrder {
+		if _, exists := e.categories.Categories[key]; exists && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	var rest []string
+	for key := range e.categories.Categories {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// editCategories lists the categories and opens the one picked
+func (e *configEditor) editCategories() {
+	for !e.closed {
+		keys := e.categoryKeys()
+		fmt.Fprintln(e.out, "\nCategories, in match order:")
+		for i, key := range keys {
+			category := e.categories.Categories[key]
+			fmt.Fprintf(e.out, "  %2d. %s%-14s %s (%d keyword(s))\n", i+1, glyph(category.Icon+" "), key, category.Name, len(category.Keywords))
+		}
+		answer, ok := e.ask("Number = edit, a = add, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+		if strings.EqualFold(answer, "a") {
+			key, ok := e.askField("New category key", "", func(key string) error {
+				_, exists := e.categories.Categories[key]
+				return checkNewName("category", key, exists || key == string(CategoryOther))
+			})
+			if ok && key != "" {
+				e.categories.Categories[key] = CategoryConfig{Name: key}
+				e.addToCategoryOrder(key)
+				e.changed(true)
+				e.editCategory(key)
+			}
+			continue
+		}
+		if i, ok := pickNumber(answer, len(keys)); ok {
+			e.editCategory(keys[i])
+		} else {
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+		}
+	}
+}
+
+// addToCategoryOrder adds a new category to the match order, ahead of the catch-all "other"
+func (e *configEditor) addToCategoryOrder(key string) {
+	order := e.categories.CategoryOrder
+	for i, existing := range order {
+		if existing == string(CategoryOther) {
+			e.categories.CategoryOrder = append(order[:i:i], append([]string{key}, order[i:]...)...)
+			return
+		}
+	}
+	e.categories.CategoryOrder = append(order, key)
+}
+
+// editCategory shows the form of one category
+func (e *configEditor) editCategory(key string) {
+	for !e.closed {
+		category := e.categories.Categories[key]
+		fmt.Fprintf(e.out, "\nCategory %s:\n", key)
+		fmt.Fprintf(e.out, "  1. Name: %s\n", valueOrNone(category.Name))
+		fmt.Fprintf(e.out, "  2. Icon: %s\n", valueOrNone(category.Icon))
+		fmt.Fprintf(e.out, "  3. Keywords: %s\n", valueOrNone(strings.Join(category.Keywords, ", ")))
+		answer, ok := e.ask("Number = edit, d = delete, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+
+		edited := false
+		anything := func(string) error { return nil }
+		switch strings.ToLower(answer) {
+		case "1":
+			category.Name, edited = e.askField("Name", category.Name, anything)
+		case "2":
+			category.Icon, edited = e.askField("Icon", category.Icon, anything)
+		case "3":
+			var list string
+			list, edited = e.askField("Keywords, comma-separated (- = none)", strings.Join(category.Keywords, ", "), func(value string) error {
+				for _, keyword := range splitList(value) {
+					if len([]rune(keyword)) < 2 {
+						return fmt.Errorf("keyword '%s' is too short; it would match almost every file", keyword)
+					}
+				}
+				return nil
+			})
+			category.Keywords = splitList(list)
+		case "d":
+			var users []string
+			for _, name := range e.sortedModeNames() {
+				if containsFold(e.config.Modes[name].Categories, key) {
+					users = append(users, name)
+				}
+			}
+			if len(users) > 0 {
+				fmt.Fprintf(e.out, "Category %s is used by mode(s) %s; remove it from them first\n", key, strings.Join(users, ", "))
+				continue
+			}
+			if e.askYes(fmt.Sprintf("Delete category %s?", key)) {
+				delete(e.categories.Categories, key)
+				order := e.categories.CategoryOrder[:0]
+				for _, existing := range e.categories.CategoryOrder {
+					if existing != key {
+						order = append(order, existing)
+					}
+				}
+				e.categories.CategoryOrder = order
+				e.changed(true)
+				return
+			}
+			continue
+		default:
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+			continue
+		}
+		if edited {
+			e.categories.Categories[key] = category
+			e.changed(true)
+		}
+	}
+}
+
+// editRoutines lists the routines, the editor's schedules, and opens the one picked
+func (e *configEditor) editRoutines() {
+	for !e.closed {
+		names := e.config.getAvailableRoutines()
+		fmt.Fprintln(e.out, "\nRoutines:")
+		for i, name := range names {
+			var steps []string
+			for _, step := range e.config.Routines[name] {
+				steps = append(steps, e.describeStep(step))
+			}
+			fmt.Fprintf(e.out, "  %2d. %-14s %s\n", i+1, name, strings.Join(steps, ", "))
+		}
+		answer, ok := e.ask("Number = edit, a = add, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+		if strings.EqualFold(answer, "a") {
+			name, ok := e.askField("New routine name", "", func(name string) error {
+				_, exists := e.config.Routines[name]
+				return checkNewName("routine", name, exists)
+			})
+			if ok && name != "" {
+				if e.config.Routines == nil {
+					e.config.Routines = make(map[string][]RoutineStep)
+				}
+				// Not recorded until it has a step, since a routine without steps doesn't load
+				e.config.Routines[name] = nil
+				e.editRoutine(name)
+				if len(e.config.Routines[name]) == 0 {
+					delete(e.config.Routines, name)
+				}
+			}
+			continue
+		}
+		if i, ok := pickNumber(answer, len(names)); ok {
+			e.editRoutine(names[i])
+		} else {
+			fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+		}
+	}
+}
+
+// describeStep describes a routine step, naming the default mode for steps that leave it out
+func (e *configEditor) describeStep(step RoutineStep) string {
+	if !step.Break && step.Mode == "" {
+		step.Mode = e.defaultMode()
+	}
+	return step.describe()
+}
+
+// editRoutine shows the steps of one routine
+func (e *configEditor) editRoutine(name string) {
+	for !e.closed {
+		steps := e.config.Routines[name]
+		fmt.Fprintf(e.out, "\nRoutine %s:\n", name)
+		for i, step := range steps {
+			fmt.Fprintf(e.out, "  %2d. %s\n", i+1, e.describeStep(step))
+		}
+		answer, ok := e.ask("Number = edit step, a = add step, x N = remove step, d = delete routine, Enter = back: ")
+		if !ok || answer == "" {
+			return
+		}
+		lower := strings.ToLower(answer)
+		switch {
+		case lower == "a":
+			if step, ok := e.askStep(RoutineStep{}); ok {
+				e.config.Routines[name] = append(steps, step)
+				e.changed(false)
+			}
+		case strings.HasPrefix(lower, "x"):
+			if i, ok := pickNumber(strings.TrimSpace(lower[1:]), len(steps)); ok {
+				e.config.Routines[name] = append(steps[:i:i], steps[i+1:]...)
+				e.changed(false)
+			} else {
+				fmt.Fprintf(e.out, "Unknown step: %s\n", strings.TrimSpace(answer[1:]))
+			}
+		case lower == "d":
+			if e.askYes(fmt.Sprintf("Delete routine %s?", name)) {
+				delete(e.config.Routines, name)
+				e.changed(false)
+				return
+			}
+		default:
+			i, ok := pickNumber(answer, len(steps))
+			if !ok {
+				fmt.Fprintf(e.out, "Unknown choice: %s\n", answer)
+				continue
+			}
+			if step, ok := e.askStep(steps[i]); ok {
+				steps[i] = step
+				e.changed(false)
+			}
+		}
+	}
+}
+
+// askStep asks for the mode and length of a routine step
+func (e *configEditor) askStep(step RoutineStep) (RoutineStep, bool) {
+	current := step.Mode
+	if step.Break {
+		current = "break"
+	}
+	mode, modeEdited := e.askField("Mode, or break (- = default mode)", current, func(value string) error {
+		if _, exists := e.config.Modes[value]; !exists && value != "break" {
+			return fmt.Errorf("unknown mode '%s' (available: %s)", value, strings.Join(e.sortedModeNames(), ", "))
+		}
+		return nil
+	})
+	if e.closed {
+		return step, false
+	}
+	if modeEdited {
+		step.Break = mode == "break"
+		step.Mode = mode
+		if step.Break {
+			step.Mode = ""
+		}
+	}
+	current = ""
+	if step.Duration > 0 {
+		current = strconv.Itoa(step.Duration)
+	}
+	minutes, durationEdited := e.askField("Minutes", current, func(value string) error {
+		_, err := parseMinutes(value)
+		return err
+	})
+	if durationEdited {
+		step.Duration, _ = parseMinutes(minutes)
+	}
+	if step.Duration <= 0 {
+		fmt.Fprintln(e.out, "  A step needs a length; not changed")
+		return step, false
+	}
+	return step, modeEdited || durationEdited
+}
+
+// runConfigEdit handles "config edit", the form-based editor for modes, categories and routines
+func runConfigEdit(args []string) {
+	flags := flag.NewFlagSet("config edit", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories file")
+	flags.Bool("tui", true, "Edit through interactive forms (the default)")
+	flags.Parse(args)
+
+	if readOnly {
+		fmt.Fprintln(os.Stderr, "Error: the config editor saves changes, which --read-only refuses; use 'config validate' instead")
+		os.Exit(1)
+	}
+	if !stdinIsTerminal() {
+		fmt.Fprintln(os.Stderr, "Warning: standard input is not a terminal; answers are read from it line by line")
+	}
+	editor, err := newConfigEditor(*configPath, *categoriesPath, stdinLines(), os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Enter keeps a value, - clears it. Nothing is written until you save.")
+	editor.run()
+}
@@ -0,0 +1,185 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// editorAnswers returns a closed channel holding the given answers
+func editorAnswers(answers ...string) <-chan string {
+	lines := make(chan string, len(answers))
+	for _, answer := range answers {
+		lines <- answer
+	}
+	close(lines)
+	return lines
+}
+
+// newTestConfigEditor writes a profile to a temp dir and opens the editor on it
+func newTestConfigEditor(t *testing.T, profile string, answers <-chan string, out *strings.Builder) (*configEditor, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "profile.yml")
+	categoriesPath := filepath.Join(dir, "categories.yml")
+	if err := os.WriteFile(configPath, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	editor, err := newConfigEditor(configPath, categoriesPath, answers, out)
+	if err != nil {
+		t.Fatalf("newConfigEditor() returned error: %v", err)
+	}
+	editor.desktopFiles = func() ([]string, error) { return []string{"Kindle.lnk", "Steam.lnk"}, nil }
+	return editor, configPath, categoriesPath
+}
+
+// TestConfigEditorAddMode tests adding a mode, rejecting an invalid answer and keeping comments on save
+func TestConfigEditorAddMode(t *testing.T) {
+	profile := "# my settings\nmilestones: [50%] # halfway\nmodes:\n  focusmode:\n    destination: Hidden_Shortcuts\n    shortcuts: [Steam.lnk]\n"
+	answers := editorAnswers(
+		"1", "a", "reading",
+		"5", "99999", "4000",
+		"2", "1", "",
+		"", "", "s")
+	var out strings.Builder
+	editor, configPath, _ := newTestConfigEditor(t, profile, answers, &out)
+	if !editor.run() {
+		t.Fatalf("Expected the editor to save, output:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "color temperature must be between") {
+		t.Errorf("Expected the invalid temperature to be rejected, output:\n%s", out.String())
+	}
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() returned error: %v", err)
+	}
+	want := ModeConfig{Destination: "reading_Shortcuts", Shortcuts: []string{"Kindle.lnk"}, ColorTemperature: 4000}
+	if got := config.Modes["reading"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected mode %+v, got %+v", want, got)
+	}
+	if got := config.Modes["focusmode"].Shortcuts; !reflect.DeepEqual(got, []string{"Steam.lnk"}) {
+		t.Errorf("Expected focusmode untouched, got shortcuts %v", got)
+	}
+	data, _ := os.ReadFile(configPath)
+	for _, comment := range []string{"# my settings", "# halfway"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("Expected %q to be kept, got:\n%s", comment, data)
+		}
+	}
+	if strings.Contains(string(data), "move_all") {
+		t.Errorf("Expected zero fields to be left out, got:\n%s", data)
+	}
+}
+
+// TestConfigEditorRoutine tests building a routine step by step, with invalid answers asked again
+func TestConfigEditorRoutine(t *testing.T) {
+	profile := "modes:\n  focusmode:\n    shortcuts: [Steam.lnk]\n"
+	answers := editorAnswers(
+		"3", "a", "deep",
+		"a", "nosuch", "focusmode", "0", "50",
+		"a", "break", "10",
+		"", "", "s")
+	var out strings.Builder
+	editor, configPath, _ := newTestConfigEditor(t, profile, answers, &out)
+	if !editor.run() {
+		t.Fatalf("Expected the editor to save, output:\n%s", out.String())
+	}
+	for _, message := range []string{"unknown mode 'nosuch'", "must be longer than zero"} {
+		if !strings.Contains(out.String(), message) {
+			t.Errorf("Expected %q in output:\n%s", message, out.String())
+		}
+	}
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() returned error: %v", err)
+	}
+	steps, err := config.getRoutine("deep")
+	if err != nil {
+		t.Fatalf("getRoutine() returned error: %v", err)
+	}
+	want := []RoutineStep{{Mode: "focusmode", Duration: 50}, {Break: true, Duration: 10}}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Expected steps %+v, got %+v", want, steps)
+	}
+}
+
+// TestConfigEditorRenameMode tests that renaming a mode follows references to it,
+// and that nothing is written when input ends before saving
+func TestConfigEditorRenameMode(t *testing.T) {
+	profile := "default_mode: focusmode\nmodes:\n  focusmode: {}\n  gamemode:\n    conflicts_with: [focusmode]\nroutines:\n  day:\n    - mode: focusmode\n      duration: 50\n"
+	var out strings.Builder
+	editor, configPath, _ := newTestConfigEditor(t, profile, editorAnswers("1", "1", "r", "deep", "", ""), &out)
+	if editor.run() {
+		t.Fatal("Expected nothing to be saved")
+	}
+
+	if editor.config.DefaultMode != "deep" {
+		t.Errorf("Expected default mode deep, got %s", editor.config.DefaultMode)
+	}
+	if got := editor.config.Modes["gamemode"].ConflictsWith; !reflect.DeepEqual(got, []string{"deep"}) {
+		t.Errorf("Expected conflicts_with [deep], got %v", got)
+	}
+	if got := editor.config.Routines["day"][0].Mode; got != "deep" {
+		t.Errorf("Expected routine step mode deep, got %s", got)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != profile {
+		t.Errorf("Expected the file untouched, got:\n%s", data)
+	}
+}
+
+// TestConfigEditorAddCategory tests adding a category ahead of "other" in a new categories file
+func TestConfigEditorAddCategory(t *testing.T) {
+	answers := editorAnswers(
+		"2", "a", "music",
+		"3", "spotify, a", "spotify, tidal",
+		"", "", "s")
+	var out strings.Builder
+	editor, _, categoriesPath := newTestConfigEditor(t, "", answers, &out)
+	if !editor.run() {
+		t.Fatalf("Expected the editor to save, output:\n%s", out.String())
+	}
+
+	config, err := loadCategoriesConfig(categoriesPath)
+	if err != nil {
+		t.Fatalf("loadCategoriesConfig() returned error: %v", err)
+	}
+	if got := config.Categories["music"].Keywords; !reflect.DeepEqual(got, []string{"spotify", "tidal"}) {
+		t.Errorf("Expected keywords [spotify tidal], got %v", got)
+	}
+	if _, exists := config.Categories["game"]; !exists {
+		t.Error("Expected the default categories to be kept")
+	}
+	order := config.CategoryOrder
+	if len(order) < 2 || order[len(order)-2] != "music" || order[len(order)-1] != string(CategoryOther) {
+		t.Errorf("Expected music just ahead of other, got %v", order)
+	}
+}
+
+// TestSetMappingValue tests replacing, adding and removing keys of a mapping
+func TestSetMappingValue(t *testing.T) {
+	var out strings.Builder
+	editor, _, _ := newTestConfigEditor(t, "default_mode: focusmode # the usual\nroutines: {}\n", editorAnswers(), &out)
+	root := editor.profile.Content[0]
+
+	if err := setMappingValue(root, "default_mode", "deep"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setMappingValue(root, "routines", map[string][]RoutineStep{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setMappingValue(root, "milestones", []string{"5m"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeEditorDocument(&editor.profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "default_mode: deep # the usual\nmilestones:\n  - 5m\n"
+	if string(data) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, data)
+	}
+}
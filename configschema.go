@@ -0,0 +1,130 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchemaDraft is the JSON Schema version emitted by "config schema"; it is the one editors support best
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema is the subset of JSON Schema needed to describe the config files
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false or a schema
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Examples             []any                  `json:"examples,omitempty"`
+}
+
+// configSchema returns the JSON Schema of a config file loaded into t
+func configSchema(title string, t reflect.Type) *jsonSchema {
+	schema := typeSchema(t)
+	schema.Schema = jsonSchemaDraft
+	schema.Title = title
+	return schema
+}
+
+// typeSchema returns the schema of a Go type as it is read from YAML
+// Structs don't allow unknown keys, so editors flag misspelled settings
+func typeSchema(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+		for _, f := range configFields(t) {
+			schema.Properties[f.Key] = fieldSchema(f)
+		}
+		return schema
+	}
+	return &jsonSchema{}
+}
+
+// fieldSchema returns the schema of one config key, with its documentation
+func fieldSchema(f configField) *jsonSchema {
+	schema := typeSchema(f.Type)
+	schema.Description = f.Doc
+	if f.Enum != "" {
+		schema.Enum = strings.Split(f.Enum, ",")
+	}
+	if f.Default != "" {
+		schema.Default = tagValue(f.Default)
+	}
+	if f.Example != "" {
+		schema.Examples = []any{tagValue(f.Example)}
+	}
+	return schema
+}
+
+// tagValue parses a default or example tag, which is written in YAML flow style
+func tagValue(tag string) any {
+	var value any
+	if err := yaml.Unmarshal([]byte(tag), &value); err != nil {
+		return tag
+	}
+	return value
+}
+
+// writeConfigSchema writes the JSON Schema of a config file
+func writeConfigSchema(w io.Writer, title string, t reflect.Type) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(configSchema(title, t))
+}
+
+// runConfigSchema handles "config schema", which prints the JSON Schema of profile.yml or categories.yml
+func runConfigSchema(args []string) {
+	flags := flag.NewFlagSet("config schema", flag.ExitOnError)
+	file := flags.String("file", "profile", "Config file to describe: profile or categories")
+	output := flags.String("o", "", "Write the schema to this file instead of stdout")
+	flags.Parse(args)
+
+	var t reflect.Type
+	switch *file {
+	case "profile":
+		t = reflect.TypeOf(Config{})
+	case "categories":
+		t = reflect.TypeOf(CategoriesConfig{})
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown config file %q (use profile or categories)\n", *file)
+		os.Exit(1)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		out, err := createFile(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+			os.Exit(1)
+		}
+		defer out.Close()
+		w = out
+	}
+	if err := writeConfigSchema(w, "FocusMode "+*file+".yml", t); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing schema: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,112 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestConfigSchemaTagValues tests that every default and example has the type of its key
+// A default tag that doesn't parse would show editors a wrong value
+func TestConfigSchemaTagValues(t *testing.T) {
+	var check func(path string, schema *jsonSchema)
+	check = func(path string, schema *jsonSchema) {
+		for key, prop := range schema.Properties {
+			if prop.Default != nil && !schemaAccepts(prop, prop.Default) {
+				t.Errorf("%s%s: default %#v is not a %s", path, key, prop.Default, prop.Type)
+			}
+			for _, example := range prop.Examples {
+				if !schemaAccepts(prop, example) {
+					t.Errorf("%s%s: example %#v is not a %s", path, key, example, prop.Type)
+				}
+			}
+			check(path+key+".", prop)
+		}
+		if nested, ok := schema.AdditionalProperties.(*jsonSchema); ok {
+			check(path+"<name>.", nested)
+		}
+		if schema.Items != nil {
+			check(path+"[].", schema.Items)
+		}
+	}
+	check("", configSchema("profile", reflect.TypeOf(Config{})))
+	check("", configSchema("categories", reflect.TypeOf(CategoriesConfig{})))
+}
+
+// schemaAccepts reports whether a value parsed from a tag matches a schema's type
+func schemaAccepts(schema *jsonSchema, value any) bool {
+	switch schema.Type {
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		_, ok := value.(int)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if !schemaAccepts(schema.Items, item) {
+				return false
+			}
+		}
+		return true
+	case "object":
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return false
+		}
+		nested, ok := schema.AdditionalProperties.(*jsonSchema)
+		for _, entry := range entries {
+			if !ok || !schemaAccepts(nested, entry) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// TestWriteConfigSchema tests the emitted schema for nested settings, enums and unknown keys
+func TestWriteConfigSchema(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeConfigSchema(&out, "FocusMode profile.yml", reflect.TypeOf(Config{})); err != nil {
+		t.Fatalf("writeConfigSchema() returned error: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema["$schema"] != jsonSchemaDraft || schema["additionalProperties"] != false {
+		t.Errorf("Unexpected top level: %v", schema)
+	}
+
+	props := schema["properties"].(map[string]any)
+	if _, ok := props["Policy"]; ok {
+		t.Error("Expected the machine policy to be left out")
+	}
+	mode := props["modes"].(map[string]any)["additionalProperties"].(map[string]any)
+	shortcuts := mode["properties"].(map[string]any)["shortcuts"].(map[string]any)
+	if shortcuts["type"] != "array" || shortcuts["items"].(map[string]any)["type"] != "string" {
+		t.Errorf("Unexpected schema for modes.<name>.shortcuts: %v", shortcuts)
+	}
+	step := props["routines"].(map[string]any)["additionalProperties"].(map[string]any)["items"].(map[string]any)
+	if step["properties"].(map[string]any)["break"].(map[string]any)["type"] != "boolean" {
+		t.Errorf("Unexpected schema for routine steps: %v", step)
+	}
+	provider := props["calendar"].(map[string]any)["properties"].(map[string]any)["provider"].(map[string]any)
+	if !reflect.DeepEqual(provider["enum"], []any{"google", "outlook"}) {
+		t.Errorf("Expected calendar.provider enum, got %v", provider["enum"])
+	}
+	volume := props["ambient"].(map[string]any)["properties"].(map[string]any)["volume"].(map[string]any)
+	if volume["default"] != float64(defaultAmbientVolume) {
+		t.Errorf("Expected ambient.volume default %d, got %v", defaultAmbientVolume, volume["default"])
+	}
+}
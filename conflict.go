@@ -0,0 +1,136 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policies for restoring a hidden file whose name is already taken on the desktop
+const (
+	conflictFail      = "fail"               // Leave both where they are and report an error
+	conflictSkip      = "skip"               // Keep the desktop's file and leave the hidden one in its folder
+	conflictOverwrite = "overwrite"          // Replace the desktop's file with the hidden one
+	conflictRename    = "rename-with-suffix" // Restore the hidden file next to it as "Name (2).lnk"
+	conflictKeepNewer = "keep-newer"         // Overwrite when the hidden file was modified later, otherwise skip
+)
+
+// conflictPolicies lists the valid on_conflict and -on-conflict values
+var conflictPolicies = []string{conflictFail, conflictSkip, conflictOverwrite, conflictRename, conflictKeepNewer}
+
+// onConflictFlag is the -on-conflict policy given on the command line; it overrides the modes' on_conflict
+var onConflictFlag string
+
+// validConflictPolicy checks an on_conflict or -on-conflict value; empty means the default
+func validConflictPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, valid := range conflictPolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conflict policy %q (expected %s)", policy, strings.Join(conflictPolicies, ", "))
+}
+
+// restoreConflictPolicy returns how restores of the mode's files treat names taken on the desktop
+func (m ModeConfig) restoreConflictPolicy() string {
+	if onConflictFlag != "" {
+		return onConflictFlag
+	}
+	if m.OnConflict != "" {
+		return m.OnConflict
+	}
+	return conflictFail
+}
+
+// restoreResult is what a restore did with a hidden file
+type restoreResult struct {
+	Name     string // Name on the desktop, which differs from the hidden file's after a rename
+	Skipped  bool   // The desktop's file was kept and the hidden one left in its folder
+	Replaced bool   // The desktop's file was replaced
+	Folder   string // Folder of the restore handler the file went to instead of the desktop
+}
+
+// undoable reports whether the restore can be recorded for undo, which puts files back by name;
+// a renamed file has another name and a replaced one can't be brought back
+func (r restoreResult) undoable(shortcutName string) bool {
+	return !r.Skipped && !r.Replaced && nfc(r.Name) == nfc(shortcutName)
+}
+
+// describe returns the line printed for the restore of shortcutName
+func (r restoreResult) describe(shortcutName string) string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%sSkipped: %s (already %s)", glyph("- "), shortcutName, r.place())
+	case r.Replaced:
+		return fmt.Sprintf("%sRestored: %s, replacing the copy %s", glyph("✓ "), shortcutName, r.place())
+	case nfc(r.Name) != nfc(shortcutName):
+		return fmt.Sprintf("%sRestored: %s as %s%s", glyph("✓ "), shortcutName, r.Name, r.to())
+	}
+	return fmt.Sprintf("%sRestored: %s%s", glyph("✓ "), shortcutName, r.to())
+}
+
+// place names where the file was restored, for describe
+func (r restoreResult) place() string {
+	if r.Folder != "" {
+		return "in " + r.Folder
+	}
+	return "on the desktop"
+}
+
+// to returns " -> folder" for a file a restore handler sent somewhere other than the desktop
+func (r restoreResult) to() string {
+	if r.Folder != "" {
+		return " -> " + r.Folder
+	}
+	return ""
+}
+
+// freeDesktopName returns name with the first " (n)" suffix not taken in dir, e.g. "Steam (2).lnk"
+func freeDesktopName(dir, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, taken := findFileName(dir, candidate); !taken {
+			return candidate
+		}
+	}
+}
+
+// hiddenIsNewer reports whether the hidden file was modified after the desktop's file
+func hiddenIsNewer(hiddenPath, desktopPath string) (bool, error) {
+	hidden, err := os.Stat(hiddenPath)
+	if err != nil {
+		return false, err
+	}
+	existing, err := os.Stat(desktopPath)
+	if err != nil {
+		return false, err
+	}
+	return hidden.ModTime().After(existing.ModTime()), nil
+}
+
+// replaceFile moves from over the file at to, which is set aside first and put back if the move fails
+// The move is retried under policy
+func replaceFile(policy RetryPolicy, from, to string) error {
+	info, err := os.Lstat(to)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a folder on the desktop and isn't overwritten", filepath.Base(to))
+	}
+	aside := filepath.Join(filepath.Dir(to), "."+filepath.Base(to)+".focusmode-replaced")
+	if err := renameFile(to, aside); err != nil {
+		return err
+	}
+	if err := withFileRetry(policy, func() error { return moveFile(from, to) }); err != nil {
+		renameFile(aside, to)
+		return err
+	}
+	return removeFile(aside)
+}
@@ -0,0 +1,100 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRestoreConflictPolicies tests each way of restoring onto a name already taken on the desktop
+func TestRestoreConflictPolicies(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	tests := []struct {
+		policy      string
+		hiddenNewer bool
+		wantErr     bool
+		wantResult  restoreResult
+		wantDesktop map[string]string // Desktop files and their contents afterwards
+		wantHidden  bool              // Whether the hidden file is still in its folder
+	}{
+		{policy: conflictFail, wantErr: true, wantDesktop: map[string]string{"Steam.lnk": "desktop"}, wantHidden: true},
+		{policy: conflictSkip, wantResult: restoreResult{Name: "Steam.lnk", Skipped: true},
+			wantDesktop: map[string]string{"Steam.lnk": "desktop"}, wantHidden: true},
+		{policy: conflictOverwrite, wantResult: restoreResult{Name: "Steam.lnk", Replaced: true},
+			wantDesktop: map[string]string{"Steam.lnk": "hidden"}},
+		{policy: conflictRename, wantResult: restoreResult{Name: "Steam (3).lnk"},
+			wantDesktop: map[string]string{"Steam.lnk": "desktop", "Steam (2).lnk": "other", "Steam (3).lnk": "hidden"}},
+		{policy: conflictKeepNewer, hiddenNewer: true, wantResult: restoreResult{Name: "Steam.lnk", Replaced: true},
+			wantDesktop: map[string]string{"Steam.lnk": "hidden"}},
+		{policy: conflictKeepNewer, wantResult: restoreResult{Name: "Steam.lnk", Skipped: true},
+			wantDesktop: map[string]string{"Steam.lnk": "desktop"}, wantHidden: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("USERPROFILE", dir)
+			desktop := filepath.Join(dir, "Desktop")
+			games := filepath.Join(dir, "Games")
+			os.MkdirAll(desktop, 0755)
+			os.MkdirAll(games, 0755)
+			hidden := filepath.Join(games, "Steam.lnk")
+			os.WriteFile(hidden, []byte("hidden"), 0644)
+			os.WriteFile(filepath.Join(desktop, "Steam.lnk"), []byte("desktop"), 0644)
+			if tt.policy == conflictRename {
+				os.WriteFile(filepath.Join(desktop, "Steam (2).lnk"), []byte("other"), 0644)
+			}
+			if tt.hiddenNewer {
+				os.Chtimes(filepath.Join(desktop, "Steam.lnk"), older, older)
+			} else {
+				os.Chtimes(hidden, older, older)
+			}
+
+			result, err := (&Config{}).restoreShortcutWithPolicy("Steam.lnk", games, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("restoreShortcutWithPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.wantResult {
+				t.Errorf("Expected %+v, got %+v", tt.wantResult, result)
+			}
+			entries, _ := os.ReadDir(desktop)
+			if len(entries) != len(tt.wantDesktop) {
+				t.Errorf("Expected %d desktop files, got %d", len(tt.wantDesktop), len(entries))
+			}
+			for name, want := range tt.wantDesktop {
+				if data, err := os.ReadFile(filepath.Join(desktop, name)); err != nil || string(data) != want {
+					t.Errorf("Expected %s to hold %q, got %q (err %v)", name, want, data, err)
+				}
+			}
+			if _, err := os.Stat(hidden); (err == nil) != tt.wantHidden {
+				t.Errorf("Expected the hidden file left in its folder: %v, got err %v", tt.wantHidden, err)
+			}
+		})
+	}
+}
+
+// TestRestoreConflictPolicyOverride tests that -on-conflict overrides the mode's on_conflict
+func TestRestoreConflictPolicyOverride(t *testing.T) {
+	mode := ModeConfig{OnConflict: conflictRename}
+	if got := (ModeConfig{}).restoreConflictPolicy(); got != conflictFail {
+		t.Errorf("Expected %q by default, got %q", conflictFail, got)
+	}
+	if got := mode.restoreConflictPolicy(); got != conflictRename {
+		t.Errorf("Expected the mode's %q, got %q", conflictRename, got)
+	}
+	onConflictFlag = conflictSkip
+	t.Cleanup(func() { onConflictFlag = "" })
+	if got := mode.restoreConflictPolicy(); got != conflictSkip {
+		t.Errorf("Expected the flag's %q, got %q", conflictSkip, got)
+	}
+
+	if err := validConflictPolicy("rename"); err == nil || !strings.Contains(err.Error(), "keep-newer") {
+		t.Errorf("Expected an error listing the policies, got %v", err)
+	}
+	warnings := lintConfig(&Config{DefaultMode: "work", Modes: map[string]ModeConfig{"work": {OnConflict: "newest"}}})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "on_conflict") {
+		t.Errorf("Expected an on_conflict warning, got %v", warnings)
+	}
+}
@@ -0,0 +1,165 @@
+package focusmode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// crossDevice reports whether err is a rename refused because the two paths are on different
+// drives or partitions, e.g. a mode folder on D: for a desktop on C:
+func crossDevice(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return errno == 17 // ERROR_NOT_SAME_DEVICE
+	}
+	return errno == syscall.EXDEV
+}
+
+// moveFile moves a file or folder like renameFile, falling back to copying it, checking the
+// copy and deleting the original when the destination is on another drive or partition
+func moveFile(from, to string) error {
+	err := renameFile(from, to)
+	if err == nil || !crossDevice(err) {
+		return err
+	}
+	return copyThenDelete(from, to)
+}
+
+// copyThenDelete copies from to to, checks the copy against the original and only then deletes
+// the original; a copy that fails either step is removed again, leaving the original in place
+func copyThenDelete(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return &os.LinkError{Op: "move", Old: from, New: to, Err: fs.ErrExist}
+	}
+	if err := copyTree(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	if err := verifyCopy(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	if err := os.RemoveAll(from); err != nil {
+		return fmt.Errorf("copied %s to %s but couldn't delete the original: %w", from, to, err)
+	}
+	return nil
+}
+
+// copyTree copies a file, symlink or folder with its contents, keeping permissions and
+// modification times, which the archive sweep goes by
+func copyTree(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, to)
+	case info.IsDir():
+		if err := os.Mkdir(to, info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(from)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := copyRegularFile(from, to, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(to, info.ModTime(), info.ModTime())
+}
+
+// copyRegularFile copies the contents of one file to a new file
+func copyRegularFile(from, to string, perm os.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	// Sync so a failing drive reports it here rather than after the original is gone
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// verifyCopy checks that to holds the same files with the same contents as from
+func verifyCopy(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		copied := filepath.Join(to, rel)
+		info, err := os.Lstat(copied)
+		if err != nil {
+			return fmt.Errorf("copy of %s is missing %s: %w", from, rel, err)
+		}
+		if !d.Type().IsRegular() {
+			if d.Type().Type() != info.Mode().Type() {
+				return fmt.Errorf("copy of %s has %s as a different kind of file", from, rel)
+			}
+			return nil
+		}
+		want, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		got, err := fileDigest(copied)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(want, got) {
+			return fmt.Errorf("copy of %s differs from the original", path)
+		}
+		return nil
+	})
+}
+
+// fileDigest returns the SHA-256 of a file's contents
+func fileDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
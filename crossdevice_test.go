@@ -0,0 +1,79 @@
+package focusmode
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestCrossDevice tests recognizing renames refused across drives or partitions
+func TestCrossDevice(t *testing.T) {
+	errno := syscall.EXDEV
+	if runtime.GOOS == "windows" {
+		errno = syscall.Errno(17)
+	}
+	if !crossDevice(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: errno}) {
+		t.Error("Expected a cross-device rename to be recognized")
+	}
+	if crossDevice(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.ENOENT}) || crossDevice(errors.New("exdev")) {
+		t.Error("Expected other errors not to be taken for cross-device renames")
+	}
+}
+
+// TestCopyThenDelete tests the copy fallback used for moves across drives
+func TestCopyThenDelete(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "desktop"), filepath.Join(dir, "other-drive")
+	os.MkdirAll(filepath.Join(from, "Project", "notes"), 0755)
+	os.WriteFile(filepath.Join(from, "Steam.lnk"), []byte("shortcut"), 0644)
+	os.WriteFile(filepath.Join(from, "Project", "notes", "todo.txt"), []byte("ship it"), 0600)
+	os.MkdirAll(to, 0755)
+	old := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(filepath.Join(from, "Steam.lnk"), old, old)
+
+	if err := copyThenDelete(filepath.Join(from, "Steam.lnk"), filepath.Join(to, "Steam.lnk")); err != nil {
+		t.Fatalf("Unexpected error moving a file: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(to, "Steam.lnk")); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("Expected the file copied with its modification time, got %v (err %v)", info, err)
+	}
+	if err := copyThenDelete(filepath.Join(from, "Project"), filepath.Join(to, "Project")); err != nil {
+		t.Fatalf("Unexpected error moving a folder: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(to, "Project", "notes", "todo.txt")); err != nil || string(data) != "ship it" {
+		t.Errorf("Expected the folder's contents copied, got %q (err %v)", data, err)
+	}
+	if entries, _ := os.ReadDir(from); len(entries) != 0 {
+		t.Errorf("Expected the originals deleted, found %v", entries)
+	}
+
+	// A name already at the destination is never overwritten, and the original is kept
+	os.WriteFile(filepath.Join(from, "Steam.lnk"), []byte("newer"), 0644)
+	if err := copyThenDelete(filepath.Join(from, "Steam.lnk"), filepath.Join(to, "Steam.lnk")); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected an error for an existing destination, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(to, "Steam.lnk")); string(data) != "shortcut" {
+		t.Errorf("Expected the destination left alone, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(from, "Steam.lnk")); err != nil {
+		t.Errorf("Expected the original kept: %v", err)
+	}
+}
+
+// TestVerifyCopy tests that a copy that doesn't match its original is caught
+func TestVerifyCopy(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("original"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("truncat"), 0644)
+	if err := verifyCopy(filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")); err == nil {
+		t.Error("Expected a differing copy to fail verification")
+	}
+	if err := verifyCopy(filepath.Join(dir, "a.txt"), filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected a missing copy to fail verification")
+	}
+}
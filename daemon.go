@@ -0,0 +1,701 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DaemonConfig represents the control API served by "focusmode daemon"
+type DaemonConfig struct {
+	Listen   string `yaml:"listen" doc:"TCP address for remote control (local control uses a socket)" example:"0.0.0.0:7420"`
+	Token    string `yaml:"token" doc:"Bearer token accepted in addition to tokens from focusmode token create"`
+	TLSCert  string `yaml:"tls_cert" doc:"Certificate file; TLS is used when set together with tls_key"`
+	TLSKey   string `yaml:"tls_key" doc:"Private key file"`
+	ClientCA string `yaml:"client_ca" doc:"CA file for mutual TLS; clients must present a certificate signed by it"`
+
+	PublicStatus bool `yaml:"public_status" doc:"Serve an unauthenticated, rate-limited /public-status line" default:"false"`
+
+	ShutdownTimeout int  `yaml:"shutdown_timeout" doc:"Seconds to finish in-flight work on SIGTERM" default:"30"`
+	ShutdownRestore bool `yaml:"shutdown_restore" doc:"End running sessions (with their auto-restore) instead of keeping them for recovery" default:"false"`
+}
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// commandRequest is the body of a daemon API command
+type commandRequest struct {
+	Mode   string `json:"mode,omitempty"`
+	All    bool   `json:"all,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+	User   string `json:"user,omitempty"` // account whose desktop is managed (daemon running as root/SYSTEM)
+}
+
+// commandResponse is the result of a daemon API command
+type commandResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// commandRunner runs focusmode with the given arguments and returns its output
+// ctx carries the span the command is traced under
+type commandRunner func(ctx context.Context, args ...string) (string, error)
+
+// selfRunner returns a runner that executes this binary with the given config file
+// Commands run in a child process so their exits and output don't affect the daemon
+func selfRunner(configPath string) commandRunner {
+	return func(ctx context.Context, args ...string) (string, error) {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("error locating executable: %w", err)
+		}
+		// The global --user flag must stay in front of everything else
+		var user []string
+		if len(args) > 1 && args[0] == "--user" {
+			user, args = args[:2], args[2:]
+		}
+		// Subcommands take their own -config flag after the subcommand name;
+		// "session" has subcommands of its own, which is where its flags go
+		if len(args) > 1 && args[0] == "session" {
+			args = append([]string{args[0], args[1], "-config", configPath}, args[2:]...)
+		} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			args = append([]string{args[0], "-config", configPath}, args[1:]...)
+		} else {
+			args = append([]string{"-config", configPath}, args...)
+		}
+		args = append(user, args...)
+		cmd := exec.Command(executable, args...)
+		if env := spanFromContext(ctx).environ(); env != nil {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+}
+
+// commandArgs translates an API command into command-line arguments
+func commandArgs(config *Config, command string, req commandRequest) ([]string, error) {
+	// Only configured mode names are passed on, so requests can't inject flags
+	if req.Mode != "" {
+		if _, exists := config.Modes[req.Mode]; !exists {
+			return nil, fmt.Errorf("mode '%s' %w. Available modes: %v", req.Mode, errModeNotFound, config.getAvailableModes())
+		}
+	}
+
+	if req.User != "" && !validUserName(req.User) {
+		return nil, fmt.Errorf("invalid user name: %q", req.User)
+	}
+
+	var args []string
+	switch command {
+	case "apply":
+		if req.Mode == "" {
+			return nil, fmt.Errorf("mode is required")
+		}
+		args = []string{"-mode", req.Mode}
+	case "restore":
+		if req.All {
+			args = []string{"-restore-all"}
+		} else if req.Mode != "" {
+			args = []string{"-restore", "-mode", req.Mode}
+		} else {
+			args = []string{"-restore"}
+		}
+	case "push":
+		if req.Mode == "" {
+			return nil, fmt.Errorf("mode is required")
+		}
+		args = []string{"push"}
+		if req.DryRun {
+			args = append(args, "-dry-run")
+		}
+		return withUser(req.User, append(args, req.Mode)), nil
+	case "pop":
+		args = []string{"pop"}
+	case "panic":
+		args = []string{"panic"}
+	case "status":
+		args = []string{"status", "-json"}
+	default:
+		return nil, fmt.Errorf("unknown command: %s", command)
+	}
+	if req.DryRun {
+		args = append(args, "-dry-run")
+	}
+	return withUser(req.User, args), nil
+}
+
+// withUser prefixes args with the global --user flag when a user is given
+func withUser(user string, args []string) []string {
+	if user == "" {
+		return args
+	}
+	return append([]string{"--user", user}, args...)
+}
+
+// daemonServer serves the control API
+type daemonServer struct {
+	config     *Config
+	run        commandRunner
+	tokensPath string     // token list created by "focusmode token", re-read on every request
+	mu         sync.Mutex // serializes commands so moves never interleave
+	watchdog   *watchdog  // supervisor of background loops, reported by /healthz (may be nil)
+
+	shutdown     chan struct{} // closed by POST /shutdown on the local socket (nil disables it)
+	shutdownOnce sync.Once
+}
+
+// newDaemonHandler returns the HTTP handler for the control API
+// tokensPath may be empty to accept only the configured token
+func newDaemonHandler(config *Config, run commandRunner, tokensPath string) http.Handler {
+	return newDaemonServer(config, run, tokensPath).handler()
+}
+
+// newDaemonServer creates a control API server whose handlers share one command lock
+func newDaemonServer(config *Config, run commandRunner, tokensPath string) *daemonServer {
+	return &daemonServer{config: config, run: run, tokensPath: tokensPath}
+}
+
+// api returns the unauthenticated control API routes
+func (s *daemonServer) api() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("/api/status", s.handleStatus)
+	api.HandleFunc("/api/", s.handleCommand)
+	return api
+}
+
+// handler returns the network handler, which requires a token except for /healthz
+func (s *daemonServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", s.authenticate(s.api()))
+	mux.Handle("/healthz", healthHandler(s.watchdog))
+	if s.config.Daemon.PublicStatus {
+		mux.Handle("/public-status", publicStatusHandler(newRateLimiter(publicStatusInterval, publicStatusBurst), readCurrentSession))
+	}
+	return mux
+}
+
+// localHandler returns the handler for the local socket
+// No token is needed there: only the socket's owner can connect to it
+func (s *daemonServer) localHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", s.api())
+	mux.Handle("/healthz", healthHandler(s.watchdog))
+	mux.HandleFunc("/cancel", s.handleCancel)
+	if s.shutdown != nil {
+		mux.HandleFunc("/shutdown", s.handleShutdown)
+	}
+	return mux
+}
+
+// handleShutdown asks the daemon to shut down, as "daemon stop" does; it is only served on the local socket
+func (s *daemonServer) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use POST"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, commandResponse{Output: "shutting down"})
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+}
+
+// handleCancel cancels the countdowns before automatic applies, as "daemon cancel" does;
+// it is only served on the local socket
+func (s *daemonServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use POST"})
+		return
+	}
+	cancelled := pendingGrace.cancelAll()
+	if len(cancelled) == 0 {
+		writeJSON(w, http.StatusOK, commandResponse{Output: "No automatic apply is counting down"})
+		return
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Output: "Cancelled: " + strings.Join(cancelled, ", ")})
+}
+
+// authenticate rejects requests without the configured bearer token
+func (s *daemonServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var stored []storedToken
+		if s.tokensPath != "" {
+			var err error
+			if stored, err = readTokens(s.tokensPath); err != nil {
+				writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+				return
+			}
+		}
+
+		// Without any token configured only local, unauthenticated use is possible
+		if s.config.Daemon.Token != "" || len(stored) > 0 {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !tokenMatches(given, s.config.Daemon.Token, stored) {
+				writeJSON(w, http.StatusUnauthorized, commandResponse{Error: "invalid or missing token"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStatus returns the applied modes and running session
+// With ?user=name the status of that user is read by a child process acting for them
+func (s *daemonServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use GET"})
+		return
+	}
+
+	if user := r.URL.Query().Get("user"); user != "" {
+		args, err := commandArgs(s.config, "status", commandRequest{User: user})
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, commandResponse{Error: err.Error()})
+			return
+		}
+		output, err := s.run(r.Context(), args...)
+		var status statusReport
+		if err == nil {
+			err = json.Unmarshal([]byte(output), &status)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, commandResponse{Output: output, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	status, err := currentStatus()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleCommand runs apply, restore, push, pop and panic requests
+func (s *daemonServer) handleCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use POST"})
+		return
+	}
+
+	var req commandRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, commandResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+			return
+		}
+	}
+
+	command := strings.TrimPrefix(r.URL.Path, "/api/")
+	args, err := commandArgs(s.config, command, req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: err.Error()})
+		return
+	}
+
+	ctx, span := startSpan(r.Context(), "daemon "+command)
+	span.set("focusmode.command", command)
+	span.set("focusmode.mode", req.Mode)
+	span.set("focusmode.user", req.User)
+	s.mu.Lock()
+	output, err := s.run(ctx, args...)
+	s.mu.Unlock()
+	span.finish(err)
+
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Output: output, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Output: output})
+}
+
+// runHotkey runs the command bound to a pressed hotkey, in turn with API commands
+// A session started from a hotkey runs on its own so it doesn't hold up other commands
+func (s *daemonServer) runHotkey(b hotkeyBinding) {
+	fmt.Printf("%s hotkey %s: %s\n", time.Now().Format(time.RFC3339), b.combo, b.name)
+	ctx, span := startSpan(context.Background(), "hotkey "+b.name)
+	span.set("focusmode.hotkey", b.combo)
+	if b.detach {
+		go func() {
+			output, err := s.run(ctx, b.args...)
+			span.finish(err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error running %s: %v\n%s", b.name, err, output)
+			}
+		}()
+		return
+	}
+	s.mu.Lock()
+	output, err := s.run(ctx, b.args...)
+	s.mu.Unlock()
+	span.finish(err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n%s", b.name, err, output)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// isLoopbackAddress reports whether a listen address only accepts local connections
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runDaemonCommand handles the "daemon" subcommand
+// Without a subcommand, or with "run", the daemon runs in the foreground
+func runDaemonCommand(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			runDaemon(args[1:])
+		case "start":
+			runDaemonStart(args[1:])
+		case "stop":
+			runDaemonStop(args[1:])
+		case "status":
+			runDaemonStatus(args[1:])
+		case "cancel":
+			runDaemonCancel(args[1:])
+		case "help", "-h", "-help", "--help":
+			printDaemonUsage()
+		default:
+			if !strings.HasPrefix(args[0], "-") {
+				printDaemonUsage()
+				os.Exit(1)
+			}
+			runDaemon(args)
+		}
+		return
+	}
+	runDaemon(args)
+}
+
+// runDaemon runs the daemon in the foreground until it is stopped
+func runDaemon(args []string) {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args)
+
+	// Everything the daemon does changes something, so it isn't started at all
+	if err := guardWrite("start the daemon"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Commands run in child processes send their spans to the same collector
+	stopTracing := func() {}
+	if config.Tracing.Enabled {
+		stopTracing = startTracing(config.Tracing)
+	}
+
+	daemon := config.Daemon
+	path, err := tokensPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	server := newDaemonServer(config, selfRunner(*configPath), path)
+	server.shutdown = make(chan struct{})
+	server.watchdog = newWatchdog(context.Background(), os.Stderr)
+	if config.IDEWatch.Enabled {
+		// Without a terminal, offers are only answered from their notification
+		server.watchdog.add("ide-watch", 0, func(ctx context.Context, beat func()) {
+			opts := sessionOptions{heartbeat: beat, stop: ctx.Done(), restoreOnStop: config.Daemon.ShutdownRestore}
+			watchIDE(ctx, config, opts, false, beat)
+		})
+	}
+	if config.Meeting.Enabled && config.Meeting.AutoStart {
+		server.watchdog.add("meeting-watch", 0, func(ctx context.Context, beat func()) {
+			opts := sessionOptions{heartbeat: beat, stop: ctx.Done(), restoreOnStop: config.Daemon.ShutdownRestore}
+			watchMeetings(ctx, config, opts, beat)
+		})
+	}
+	if config.CalendarSessions.Enabled {
+		server.watchdog.add("calendar-sessions", 0, func(ctx context.Context, beat func()) {
+			opts := sessionOptions{heartbeat: beat, stop: ctx.Done(), restoreOnStop: config.Daemon.ShutdownRestore}
+			watchCalendarSessions(ctx, config, opts, beat)
+		})
+	}
+	if config.ScreenLock.Enabled {
+		server.watchdog.add("screen-lock", 0, func(ctx context.Context, beat func()) {
+			// Modes are applied in turn with API commands so moves never interleave
+			watchScreenLock(ctx, config, func(ctx context.Context, args ...string) (string, error) {
+				server.mu.Lock()
+				defer server.mu.Unlock()
+				return server.run(ctx, args...)
+			}, beat)
+		})
+	}
+	if len(config.Schedule) > 0 {
+		server.watchdog.add("schedule", 0, func(ctx context.Context, beat func()) {
+			watchSchedule(ctx, config, func(ctx context.Context, args ...string) (string, error) {
+				server.mu.Lock()
+				defer server.mu.Unlock()
+				return server.run(ctx, args...)
+			}, beat)
+		})
+	}
+	server.watchdog.add("session-queue", 0, func(ctx context.Context, beat func()) {
+		watchSessionQueue(ctx, server.run, beat)
+	})
+	server.watchdog.add("pending-moves", 0, func(ctx context.Context, beat func()) {
+		watchPendingMoves(ctx, config, &server.mu, beat)
+	})
+	server.watchdog.add("restore-at", 0, func(ctx context.Context, beat func()) {
+		watchTimedRestores(ctx, config, &server.mu, beat)
+	})
+	if config.FolderWatch.Enabled {
+		server.watchdog.add("folder-watch", 0, func(ctx context.Context, beat func()) {
+			watchHiddenFolders(ctx, &server.mu, beat)
+		})
+	}
+	if config.DNSBlocking.backend() == dnsBackendProxy {
+		server.watchdog.add("dns-proxy", 0, func(ctx context.Context, beat func()) {
+			proxy := newDNSProxy(*configPath, config.DNSBlocking.upstream())
+			if err := runDNSProxy(ctx, proxy, config.DNSBlocking.listen(), beat); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: DNS blocking disabled: %v\n", err)
+				<-ctx.Done()
+			}
+		})
+	}
+	if config.Archive.Enabled {
+		server.watchdog.add("archive", 0, func(ctx context.Context, beat func()) {
+			watchArchive(ctx, config, &server.mu, beat)
+		})
+	}
+	if config.GC.Enabled {
+		server.watchdog.add("gc", 0, func(ctx context.Context, beat func()) {
+			watchGC(ctx, config, &server.mu, beat)
+		})
+	}
+	if config.AutoHide.Installers {
+		server.watchdog.add("installer-shortcuts", 0, func(ctx context.Context, beat func()) {
+			watchInstallerShortcuts(ctx, config, &server.mu, beat)
+		})
+	}
+	if bindings, err := config.Hotkeys.bindings(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: hotkeys disabled: %v\n", err)
+	} else if len(bindings) > 0 && runtime.GOOS != "windows" {
+		fmt.Fprintln(os.Stderr, "Warning: global hotkeys are only registered on Windows; bind keyboard shortcuts to \"focusmode panic\" or \"focusmode session start|pause|extend\" in your desktop settings instead")
+	} else if len(bindings) > 0 {
+		server.watchdog.add("hotkeys", 0, func(ctx context.Context, beat func()) {
+			if err := listenHotkeys(ctx, bindings, beat, server.runHotkey); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		})
+	}
+	go server.watchdog.watch(watchdogCheckInterval)
+
+	// Local control always goes through the socket, so no TCP port is needed for it
+	socket, err := socketPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	listener, err := listenSocket(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.Remove(socket)
+	fmt.Printf("FocusMode daemon listening on %s\n", socket)
+
+	// The PID file lets "daemon stop" and "daemon status" find this process
+	pidPath, err := daemonPIDPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Remove(socket)
+		os.Exit(1)
+	}
+	pid := daemonPID{PID: os.Getpid(), StartedAt: time.Now(), Config: *configPath}
+	if abs, err := filepath.Abs(*configPath); err == nil {
+		pid.Config = abs
+	}
+	if os.Getenv(daemonDetachedEnv) == "1" {
+		// Started by "daemon start": closing the terminal it came from mustn't stop it
+		signal.Ignore(syscall.SIGHUP)
+		pid.Log, _ = daemonLogPath()
+	}
+	if err := writeDaemonPID(pidPath, pid); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Remove(socket)
+		os.Exit(1)
+	}
+	defer removeDaemonPID(pidPath)
+
+	local := &http.Server{Handler: server.localHandler()}
+	servers := []*http.Server{local}
+	errs := make(chan error, 2)
+	go func() { errs <- local.Serve(listener) }()
+
+	if daemon.Listen != "" {
+		network, useTLS, err := networkServer(server, daemon, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Remove(socket)
+			removeDaemonPID(pidPath)
+			os.Exit(1)
+		}
+		servers = append(servers, network)
+		go func() { errs <- serveNetwork(network, daemon, useTLS) }()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	failed := false
+	select {
+	case sig := <-signals:
+		fmt.Printf("Received %s, shutting down (up to %s)\n", sig, daemon.shutdownTimeout())
+	case <-server.shutdown:
+		fmt.Printf("Stop requested, shutting down (up to %s)\n", daemon.shutdownTimeout())
+	case err := <-errs:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemon.shutdownTimeout())
+	defer cancel()
+	if err := shutdownDaemon(ctx, servers, server.watchdog); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed = true
+	}
+	stopTracing()
+	if failed {
+		os.Remove(socket)
+		removeDaemonPID(pidPath)
+		os.Exit(1)
+	}
+	fmt.Println("FocusMode daemon stopped")
+}
+
+// networkServer creates the token-protected control API server on daemon.Listen for remote clients
+// It reports whether the server should use TLS
+func networkServer(server *daemonServer, daemon DaemonConfig, tokensPath string) (*http.Server, bool, error) {
+	stored, err := readTokens(tokensPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if !isLoopbackAddress(daemon.Listen) && daemon.Token == "" && len(stored) == 0 {
+		return nil, false, fmt.Errorf("create a token with 'focusmode token create <name>' (or set daemon.token) to listen on %s", daemon.Listen)
+	}
+
+	httpServer := &http.Server{
+		Addr:    daemon.Listen,
+		Handler: server.handler(),
+	}
+	if daemon.ClientCA != "" {
+		tlsConfig, err := mutualTLSConfig(daemon.ClientCA)
+		if err != nil {
+			return nil, false, err
+		}
+		httpServer.TLSConfig = tlsConfig
+	}
+
+	useTLS := daemon.TLSCert != "" && daemon.TLSKey != ""
+	if daemon.ClientCA != "" && !useTLS {
+		return nil, false, fmt.Errorf("daemon.client_ca requires tls_cert and tls_key")
+	}
+	return httpServer, useTLS, nil
+}
+
+// serveNetwork serves a server created by networkServer until it is shut down
+func serveNetwork(httpServer *http.Server, daemon DaemonConfig, useTLS bool) error {
+	var err error
+	if useTLS {
+		fmt.Printf("FocusMode daemon listening on https://%s\n", daemon.Listen)
+		err = httpServer.ListenAndServeTLS(daemon.TLSCert, daemon.TLSKey)
+	} else {
+		fmt.Printf("FocusMode daemon listening on http://%s\n", daemon.Listen)
+		if !isLoopbackAddress(daemon.Listen) {
+			fmt.Fprintln(os.Stderr, "Warning: serving without TLS; tokens are sent in plain text")
+		}
+		err = httpServer.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// shutdownTimeout returns how long a shutdown may take
+func (d DaemonConfig) shutdownTimeout() time.Duration {
+	if d.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(d.ShutdownTimeout) * time.Second
+}
+
+// shutdownDaemon stops accepting requests, lets in-flight commands finish and stops background loops
+// Sessions run by the loops are suspended, or ended when daemon.shutdown_restore is set
+func shutdownDaemon(ctx context.Context, servers []*http.Server, wd *watchdog) error {
+	var errs []error
+	for _, server := range servers {
+		// Shutdown waits for running handlers, so a move in progress completes
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("in-flight requests did not finish in time: %w", err))
+		}
+	}
+	if wd != nil {
+		if err := wd.stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mutualTLSConfig returns a TLS configuration that requires client certificates signed by the CA in caFile
+func mutualTLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,309 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRunner records the arguments of each command it is asked to run
+type fakeRunner struct {
+	calls  [][]string
+	output string
+	err    error
+}
+
+func (f *fakeRunner) run(ctx context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	return f.output, f.err
+}
+
+// testDaemonConfig returns a config with two modes and a token
+func testDaemonConfig() *Config {
+	return &Config{
+		Modes:  map[string]ModeConfig{"focusmode": {}, "gamemode": {}},
+		Daemon: DaemonConfig{Token: "secret"},
+	}
+}
+
+// TestCommandArgs tests translation of API commands into command-line arguments
+func TestCommandArgs(t *testing.T) {
+	config := testDaemonConfig()
+	tests := []struct {
+		command string
+		req     commandRequest
+		want    []string
+	}{
+		{"apply", commandRequest{Mode: "focusmode"}, []string{"-mode", "focusmode"}},
+		{"apply", commandRequest{Mode: "gamemode", DryRun: true}, []string{"-mode", "gamemode", "-dry-run"}},
+		{"restore", commandRequest{}, []string{"-restore"}},
+		{"restore", commandRequest{Mode: "gamemode"}, []string{"-restore", "-mode", "gamemode"}},
+		{"restore", commandRequest{All: true}, []string{"-restore-all"}},
+		{"push", commandRequest{Mode: "gamemode", DryRun: true}, []string{"push", "-dry-run", "gamemode"}},
+		{"pop", commandRequest{}, []string{"pop"}},
+		{"panic", commandRequest{}, []string{"panic"}},
+		{"apply", commandRequest{Mode: "gamemode", User: "alice"}, []string{"--user", "alice", "-mode", "gamemode"}},
+		{"push", commandRequest{Mode: "gamemode", User: `CORP\bob`}, []string{"--user", `CORP\bob`, "push", "gamemode"}},
+	}
+	for _, tt := range tests {
+		got, err := commandArgs(config, tt.command, tt.req)
+		if err != nil {
+			t.Errorf("commandArgs(%s, %+v) returned error: %v", tt.command, tt.req, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commandArgs(%s, %+v) = %v, want %v", tt.command, tt.req, got, tt.want)
+		}
+	}
+
+	for _, bad := range []struct {
+		command string
+		req     commandRequest
+	}{
+		{"apply", commandRequest{}},
+		{"apply", commandRequest{Mode: "-restore-all"}},
+		{"shutdown", commandRequest{}},
+		{"pop", commandRequest{User: "-mode"}},
+		{"pop", commandRequest{User: "../root"}},
+	} {
+		if _, err := commandArgs(config, bad.command, bad.req); err == nil {
+			t.Errorf("Expected error for %s %+v", bad.command, bad.req)
+		}
+	}
+}
+
+// TestDaemonHandlerAuth tests that requests need the bearer token
+func TestDaemonHandlerAuth(t *testing.T) {
+	runner := &fakeRunner{}
+	handler := newDaemonHandler(testDaemonConfig(), runner.run, "")
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/pop", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for Authorization %q, got %d", header, rec.Code)
+		}
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("Expected no commands to run, got %v", runner.calls)
+	}
+}
+
+// TestDaemonHandlerCommand tests running a command and reporting failures
+func TestDaemonHandlerCommand(t *testing.T) {
+	runner := &fakeRunner{output: "✓ Moved: Steam.lnk\n"}
+	handler := newDaemonHandler(testDaemonConfig(), runner.run, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/apply", strings.NewReader(`{"mode":"gamemode"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Moved: Steam.lnk") {
+		t.Errorf("Unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(runner.calls) != 1 || !reflect.DeepEqual(runner.calls[0], []string{"-mode", "gamemode"}) {
+		t.Errorf("Unexpected commands: %v", runner.calls)
+	}
+
+	runner.err = errors.New("exit status 1")
+	req = httptest.NewRequest(http.MethodGet, "/api/apply", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/pop", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "exit status 1") {
+		t.Errorf("Expected failure to be reported, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestIsLoopbackAddress tests detection of local-only listen addresses
+func TestIsLoopbackAddress(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:7420": true,
+		"localhost:7420": true,
+		"[::1]:7420":     true,
+		":7420":          false,
+		"0.0.0.0:7420":   false,
+		"192.168.1.5:80": false,
+		"127.0.0.1":      false,
+		"bogus":          false,
+	}
+	for address, want := range tests {
+		if got := isLoopbackAddress(address); got != want {
+			t.Errorf("isLoopbackAddress(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+// TestParseRemoteArgs tests extraction of the global remote flags
+func TestParseRemoteArgs(t *testing.T) {
+	t.Setenv("FOCUSMODE_TOKEN", "from-env")
+
+	opts, rest, err := parseRemoteArgs([]string{"--remote", "desk:7420", "--ca=ca.pem", "apply", "focusmode", "-dry-run"})
+	if err != nil {
+		t.Fatalf("parseRemoteArgs() returned error: %v", err)
+	}
+	want := remoteOptions{Address: "desk:7420", Token: "from-env", CAFile: "ca.pem"}
+	if opts != want {
+		t.Errorf("Expected %+v, got %+v", want, opts)
+	}
+	if !reflect.DeepEqual(rest, []string{"apply", "focusmode", "-dry-run"}) {
+		t.Errorf("Unexpected remaining args: %v", rest)
+	}
+
+	opts, _, _ = parseRemoteArgs([]string{"-remote=desk:7420", "-token", "abc", "pop"})
+	if opts.Token != "abc" {
+		t.Errorf("Expected token flag to override environment, got %q", opts.Token)
+	}
+
+	if _, _, err := parseRemoteArgs([]string{"--remote"}); err == nil {
+		t.Error("Expected error for missing remote address")
+	}
+}
+
+// TestRemoteBaseURL tests that HTTPS is the default scheme
+func TestRemoteBaseURL(t *testing.T) {
+	tests := map[string]string{
+		"desk:7420":             "https://desk:7420",
+		"http://127.0.0.1:7420": "http://127.0.0.1:7420",
+		"https://desk:7420/":    "https://desk:7420",
+	}
+	for address, want := range tests {
+		if got := remoteBaseURL(address); got != want {
+			t.Errorf("remoteBaseURL(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+// TestRunRemoteOverTLS tests a remote command end to end against a TLS daemon
+func TestRunRemoteOverTLS(t *testing.T) {
+	runner := &fakeRunner{output: "✓ Moved: Discord.lnk\n"}
+	server := httptest.NewTLSServer(newDaemonHandler(testDaemonConfig(), runner.run, ""))
+	defer server.Close()
+
+	opts := remoteOptions{Address: strings.TrimPrefix(server.URL, "https://"), Token: "secret"}
+	var out bytes.Buffer
+	if err := runRemote(server.Client(), opts, []string{"push", "focusmode"}, &out); err != nil {
+		t.Fatalf("runRemote() returned error: %v", err)
+	}
+	if out.String() != runner.output {
+		t.Errorf("Expected daemon output to be printed, got %q", out.String())
+	}
+	if !reflect.DeepEqual(runner.calls, [][]string{{"push", "focusmode"}}) {
+		t.Errorf("Unexpected commands: %v", runner.calls)
+	}
+
+	opts.Token = "wrong"
+	if err := runRemote(server.Client(), opts, []string{"pop"}, &out); err == nil || !strings.Contains(err.Error(), "invalid or missing token") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+
+	if err := runRemote(server.Client(), opts, []string{"apply"}, &out); err == nil {
+		t.Error("Expected usage error for apply without a mode")
+	}
+}
+
+// TestDaemonUserStatus tests that the status of another user is read through a child process
+func TestDaemonUserStatus(t *testing.T) {
+	runner := &fakeRunner{output: `{"modes":[{"mode":"gamemode"}]}` + "\n"}
+	handler := newDaemonHandler(testDaemonConfig(), runner.run, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status?user=alice", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if want := [][]string{{"--user", "alice", "status", "-json"}}; !reflect.DeepEqual(runner.calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, runner.calls)
+	}
+	if !strings.Contains(rec.Body.String(), "gamemode") {
+		t.Errorf("Expected status of alice in response, got %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/status?user=-x", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid user, got %d", rec.Code)
+	}
+}
+
+// TestShutdownDaemon tests that shutdown waits for in-flight commands and background loops
+func TestShutdownDaemon(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("moved"))
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Post("http://"+listener.Addr().String()+"/api/apply", "application/json", nil)
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+	<-started
+
+	wd := newWatchdog(context.Background(), io.Discard)
+	stopped := make(chan struct{})
+	wd.add("ide-watch", time.Minute, func(ctx context.Context, beat func()) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	// A deadline that passes while the command is still running is reported
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := shutdownDaemon(ctx, []*http.Server{server}, nil); err == nil {
+		t.Error("Expected error when in-flight requests outlast the deadline")
+	}
+
+	close(release)
+	if got := <-responses; got != "moved" {
+		t.Errorf("Expected in-flight command to complete, got %q", got)
+	}
+	if err := shutdownDaemon(context.Background(), []*http.Server{server}, wd); err != nil {
+		t.Errorf("shutdownDaemon() returned error: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected background loop to be stopped")
+	}
+}
@@ -0,0 +1,387 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// daemonDetachedEnv is set for a daemon started in the background by "daemon start"
+const daemonDetachedEnv = "FOCUSMODE_DAEMON_DETACHED"
+
+// daemonStartTimeout is how long "daemon start" waits for the new daemon to be up
+const daemonStartTimeout = 10 * time.Second
+
+// daemonPID is the running daemon's PID file, so a second invocation can find and control it
+type daemonPID struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Config    string    `json:"config"`
+	Log       string    `json:"log,omitempty"` // Output of a daemon started in the background
+}
+
+// daemonPIDPath returns the path of the PID file, next to the daemon's socket
+func daemonPIDPath() (string, error) {
+	socket, err := socketPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(socket), "daemon.pid"), nil
+}
+
+// daemonLogPath returns where a daemon started in the background writes its output
+func daemonLogPath() (string, error) {
+	socket, err := socketPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(socket), "daemon.log"), nil
+}
+
+// readDaemonPID returns the recorded daemon, or nil if there is none or it is no longer running
+func readDaemonPID(path string) (*daemonPID, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading daemon PID file: %w", err)
+	}
+	var pid daemonPID
+	if err := json.Unmarshal(data, &pid); err != nil {
+		return nil, fmt.Errorf("error parsing daemon PID file: %w", err)
+	}
+	if !processAlive(pid.PID) {
+		return nil, nil
+	}
+	return &pid, nil
+}
+
+// writeDaemonPID records this process as the running daemon
+func writeDaemonPID(path string, pid daemonPID) error {
+	data, err := json.MarshalIndent(pid, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding daemon PID file: %w", err)
+	}
+	if err := prepareSocketDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := writeFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing daemon PID file: %w", err)
+	}
+	return nil
+}
+
+// removeDaemonPID removes the PID file if it still names this process
+func removeDaemonPID(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var pid daemonPID
+	if json.Unmarshal(data, &pid) == nil && pid.PID == os.Getpid() {
+		os.Remove(path)
+	}
+}
+
+// socketClient returns an HTTP client that talks to the daemon over its local socket
+func socketClient(socket string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialSocket(socket)},
+	}
+}
+
+// printDaemonUsage prints the usage of the "daemon" subcommand
+func printDaemonUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: focusmode daemon [run] [-config profile.yml]")
+	fmt.Fprintln(os.Stderr, "       focusmode daemon start [-config profile.yml]")
+	fmt.Fprintln(os.Stderr, "       focusmode daemon stop")
+	fmt.Fprintln(os.Stderr, "       focusmode daemon status [-config profile.yml]")
+	fmt.Fprintln(os.Stderr, "       focusmode daemon cancel")
+}
+
+// runDaemonStart handles "daemon start", which runs the daemon in the background
+func runDaemonStart(args []string) {
+	flags := flag.NewFlagSet("daemon start", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args)
+	startDaemon(*configPath)
+}
+
+// startDaemon starts "daemon run" as a background process and waits until it is listening
+func startDaemon(configPath string) {
+	if err := guardWrite("start the daemon"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pidPath, err := daemonPIDPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if running, err := readDaemonPID(pidPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	} else if running != nil {
+		fmt.Printf("FocusMode daemon is already running (PID %d)\n", running.PID)
+		return
+	}
+	// Load the config here, so mistakes are shown rather than only written to the log
+	if _, err := loadConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error locating executable: %v\n", err)
+		os.Exit(1)
+	}
+	logPath, err := daemonLogPath()
+	if err == nil {
+		err = prepareSocketDir(filepath.Dir(logPath))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	logFile, err := appendFile(logPath, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening daemon log: %v\n", err)
+		os.Exit(1)
+	}
+	defer logFile.Close()
+
+	// --location travels in the environment; --user has to be passed on
+	cmd := exec.Command(executable, withUser(targetUser, []string{"daemon", "run", "-config", configPath})...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.Env = append(os.Environ(), daemonDetachedEnv+"=1")
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting daemon: %v\n", err)
+		os.Exit(1)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			fmt.Fprintf(os.Stderr, "Error: the daemon exited at startup (%v); see %s\n", err, logPath)
+			os.Exit(1)
+		case <-deadline:
+			fmt.Fprintf(os.Stderr, "Warning: the daemon (PID %d) hasn't come up after %s; see %s\n", cmd.Process.Pid, daemonStartTimeout, logPath)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		if running, _ := readDaemonPID(pidPath); running != nil && running.PID == cmd.Process.Pid {
+			fmt.Printf("%sFocusMode daemon started (PID %d)\n", glyph("✅ "), running.PID)
+			fmt.Printf("Log: %s\n", logPath)
+			return
+		}
+	}
+}
+
+// runDaemonStop handles "daemon stop", which asks the running daemon to shut down and waits for it
+func runDaemonStop(args []string) {
+	flags := flag.NewFlagSet("daemon stop", flag.ExitOnError)
+	flags.Parse(args)
+
+	pidPath, err := daemonPIDPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	running, err := readDaemonPID(pidPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if running == nil {
+		fmt.Println("FocusMode daemon is not running")
+		return
+	}
+
+	timeout := defaultShutdownTimeout
+	if config, err := readConfig(running.Config); err == nil {
+		timeout = config.Daemon.shutdownTimeout()
+	}
+	// The socket works on every platform and gives the daemon its graceful shutdown;
+	// SIGTERM does the same where signals exist, for a daemon whose socket is gone
+	if err := requestDaemonShutdown(); err != nil {
+		if runtime.GOOS == "windows" {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		process, _ := os.FindProcess(running.PID)
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			fmt.Fprintf(os.Stderr, "Error stopping daemon (PID %d): %v\n", running.PID, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("Stopping FocusMode daemon (PID %d)...\n", running.PID)
+	deadline := time.Now().Add(timeout + 5*time.Second)
+	for processAlive(running.PID) {
+		if time.Now().After(deadline) {
+			fmt.Fprintf(os.Stderr, "Error: the daemon (PID %d) is still running after %s\n", running.PID, timeout)
+			os.Exit(1)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	fmt.Println("FocusMode daemon stopped")
+}
+
+// requestDaemonShutdown asks the daemon to shut down through its local socket
+func requestDaemonShutdown() error {
+	socket, err := socketPath()
+	if err != nil {
+		return err
+	}
+	resp, err := socketClient(socket, 5*time.Second).Post("http://focusmode/shutdown", "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("error reaching the daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("the daemon refused to shut down: %s", resp.Status)
+	}
+	return nil
+}
+
+// runDaemonCancel handles "daemon cancel", which cancels the countdowns before the daemon's automatic applies
+func runDaemonCancel(args []string) {
+	flags := flag.NewFlagSet("daemon cancel", flag.ExitOnError)
+	flags.Parse(args)
+
+	socket, err := socketPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	resp, err := socketClient(socket, 5*time.Second).Post("http://focusmode/cancel", "application/json", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reaching the daemon: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	var result commandResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the daemon's reply: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", result.Error)
+		os.Exit(1)
+	}
+	fmt.Println(result.Output)
+}
+
+// runDaemonStatus handles "daemon status"; it exits with 3 when the daemon isn't running, as init scripts do
+func runDaemonStatus(args []string) {
+	flags := flag.NewFlagSet("daemon status", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file (default: the running daemon's)")
+	flags.Parse(args)
+
+	pidPath, err := daemonPIDPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	running, err := readDaemonPID(pidPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path := *configPath
+	if running == nil {
+		fmt.Println("FocusMode daemon: not running")
+	} else {
+		fmt.Printf("FocusMode daemon: running (PID %d, since %s)\n", running.PID, running.StartedAt.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("Config: %s\n", running.Config)
+		if running.Log != "" {
+			fmt.Printf("Log: %s\n", running.Log)
+		}
+		if report, err := daemonHealth(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			fmt.Printf("Background loops: %s (%d)\n", report.Status, len(report.Workers))
+		}
+		if path == "" {
+			path = running.Config
+		}
+	}
+	if path == "" {
+		path = "profile.yml"
+	}
+
+	if config, err := readConfig(path); err == nil && len(config.Schedule) > 0 {
+		printSchedule(config, time.Now())
+	}
+	if queuePath, err := sessionQueuePath(); err == nil {
+		if queue, err := readSessionQueue(queuePath); err == nil && len(queue) > 0 {
+			fmt.Printf("\nQueued sessions: %d, next at %s in %s (see \"focusmode session queue\")\n", len(queue), formatQueueTime(queue[0].At, time.Now()), queue[0].Mode)
+		}
+	}
+	if running == nil {
+		os.Exit(3)
+	}
+}
+
+// daemonHealth reads the daemon's /healthz through its local socket
+func daemonHealth() (*healthReport, error) {
+	socket, err := socketPath()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://focusmode/healthz", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := socketClient(socket, 0).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reaching the daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	var report healthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("error reading daemon health: %w", err)
+	}
+	return &report, nil
+}
+
+// printSchedule lists the schedule with whether each window is open and when that changes
+func printSchedule(config *Config, now time.Time) {
+	windows, err := config.scheduleWindows()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Schedule: %v\n", err)
+		return
+	}
+	fmt.Println("\nSchedule:")
+	for _, w := range windows {
+		state := "next applied"
+		if w.active(now) {
+			state = "active, restored"
+		}
+		next := w.nextChange(now)
+		when := next.Format("Mon 15:04")
+		if next.YearDay() == now.YearDay() && next.Year() == now.Year() {
+			when = next.Format("15:04")
+		}
+		fmt.Printf("  %-14s %-24s %s %s\n", w.Mode, w.describe(), state, when)
+	}
+}
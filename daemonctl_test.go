@@ -0,0 +1,117 @@
+package focusmode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDaemonPIDFile tests writing, reading and removing the PID file, and ignoring a stale one
+func TestDaemonPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "daemon.pid")
+	if pid, err := readDaemonPID(path); err != nil || pid != nil {
+		t.Fatalf("Expected no daemon without a PID file, got %+v (err %v)", pid, err)
+	}
+
+	want := daemonPID{PID: os.Getpid(), StartedAt: time.Now().Round(time.Second), Config: "/home/me/profile.yml"}
+	if err := writeDaemonPID(path, want); err != nil {
+		t.Fatalf("writeDaemonPID() returned error: %v", err)
+	}
+	got, err := readDaemonPID(path)
+	if err != nil || got == nil || got.PID != want.PID || got.Config != want.Config || !got.StartedAt.Equal(want.StartedAt) {
+		t.Fatalf("Expected %+v, got %+v (err %v)", want, got, err)
+	}
+
+	removeDaemonPID(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed, got %v", err)
+	}
+
+	// A daemon that died without cleaning up isn't reported as running
+	if err := writeDaemonPID(path, daemonPID{PID: 999999999}); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := readDaemonPID(path); err != nil || pid != nil {
+		t.Errorf("Expected a stale PID file to be ignored, got %+v (err %v)", pid, err)
+	}
+	// Nor is it removed by this process, whose PID it doesn't hold
+	removeDaemonPID(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected another process's PID file to be left, got %v", err)
+	}
+}
+
+// TestDaemonShutdownHandler tests that POST /shutdown on the local socket asks the daemon to stop
+func TestDaemonShutdownHandler(t *testing.T) {
+	server := newDaemonServer(testDaemonConfig(), (&fakeRunner{}).run, "")
+	rec := httptest.NewRecorder()
+	server.localHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shutdown", nil))
+	if rec.Code == http.StatusAccepted {
+		t.Error("Expected no shutdown endpoint on a server without a shutdown channel")
+	}
+
+	server.shutdown = make(chan struct{})
+	handler := server.localHandler()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shutdown", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shutdown", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected 202, got %d", rec.Code)
+		}
+	}
+	select {
+	case <-server.shutdown:
+	default:
+		t.Error("Expected the shutdown channel to be closed")
+	}
+
+	// The network listener never offers it
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/shutdown", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	server.handler().ServeHTTP(rec, req)
+	if rec.Code == http.StatusAccepted {
+		t.Error("Expected no shutdown endpoint on the network handler")
+	}
+}
+
+// TestDaemonCancelHandler tests that POST /cancel on the local socket cancels pending countdowns
+func TestDaemonCancelHandler(t *testing.T) {
+	server := newDaemonServer(testDaemonConfig(), (&fakeRunner{}).run, "")
+	rec := httptest.NewRecorder()
+	server.localHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "No automatic apply") {
+		t.Errorf("Expected nothing to cancel, got %d %s", rec.Code, rec.Body)
+	}
+
+	cancelled, done := pendingGrace.add("Applying focusmode")
+	defer done()
+	rec = httptest.NewRecorder()
+	server.localHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel", nil))
+	if !strings.Contains(rec.Body.String(), "Cancelled: Applying focusmode") {
+		t.Errorf("Expected the countdown reported, got %s", rec.Body)
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Error("Expected the countdown cancelled")
+	}
+
+	// The network listener never offers it
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/cancel", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	server.handler().ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK {
+		t.Error("Expected no cancel endpoint on the network handler")
+	}
+}
@@ -0,0 +1,150 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Periods charted by the dashboard
+const (
+	dashboardDays  = 14
+	dashboardWeeks = 8
+)
+
+// dashboardPeriod is a bar of the dashboard's charts
+type dashboardPeriod struct {
+	Start          string `json:"start"` // YYYY-MM-DD
+	FocusedMinutes int    `json:"focused_minutes"`
+	Sessions       int    `json:"sessions"`
+}
+
+// dashboardStats is the summary "focusmode stats" prints, for the dashboard's charts
+type dashboardStats struct {
+	Days           []dashboardPeriod `json:"days"`
+	Weeks          []dashboardPeriod `json:"weeks"`
+	Sessions       int               `json:"sessions"`
+	Completed      int               `json:"completed"`
+	AverageMinutes int               `json:"average_minutes"`
+	AveragePauses  float64           `json:"average_pauses"`
+	LongestStreak  int               `json:"longest_streak"`
+	CurrentStreak  int               `json:"current_streak"`
+	Control        bool              `json:"control"` // The running session can be paused, extended and stopped
+}
+
+// newDashboardStats converts a stats summary for the dashboard
+func newDashboardStats(summary statsSummary) dashboardStats {
+	periods := func(totals []periodTotal) []dashboardPeriod {
+		out := make([]dashboardPeriod, len(totals))
+		for i, total := range totals {
+			out[i] = dashboardPeriod{Start: total.Start.Format("2006-01-02"), FocusedMinutes: int(total.Focused.Minutes()), Sessions: total.Sessions}
+		}
+		return out
+	}
+	return dashboardStats{
+		Days:           periods(summary.Days),
+		Weeks:          periods(summary.Weeks),
+		Sessions:       summary.Sessions,
+		Completed:      summary.Completed,
+		AverageMinutes: int(summary.Average.Minutes()),
+		AveragePauses:  summary.AveragePauses,
+		LongestStreak:  summary.LongestStreak,
+		CurrentStreak:  summary.CurrentStreak,
+	}
+}
+
+// dashboardHandler returns the routes of the read-only dashboard: its page, the GUI's state and
+// the stats behind its charts
+// With control, the running session can also be paused, extended and stopped, as from the GUI;
+// nothing else is changed
+func (s *guiServer) dashboardHandler(control bool) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("/api/state", s.handleState)
+	api.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		s.handleStats(w, r, control)
+	})
+	if control {
+		api.HandleFunc("/api/session/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/session/start" {
+				writeJSON(w, http.StatusForbidden, commandResponse{Error: "the dashboard can't start sessions; use focusmode gui"})
+				return
+			}
+			s.handleSession(w, r)
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", s.authenticate(api))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		page, err := guiFiles.ReadFile("gui/dashboard.html")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+		w.Write(page)
+	})
+	return mux
+}
+
+// handleStats returns the totals charted by the dashboard
+func (s *guiServer) handleStats(w http.ResponseWriter, r *http.Request, control bool) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use GET"})
+		return
+	}
+	records, err := loadHistory()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	stats := newDashboardStats(summarizeHistory(records, at(time.Now(), 0), dashboardDays, dashboardWeeks))
+	stats.Control = control
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// runServeCommand handles "focusmode serve -dashboard", which serves a read-only dashboard of the
+// applied modes, running session, recent history and stats on this machine
+func runServeCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	dashboard := flags.Bool("dashboard", false, "Serve the read-only dashboard")
+	control := flags.Bool("control", false, "Let the dashboard pause, extend and stop the running session")
+	addr := flags.String("addr", "127.0.0.1:0", "Address to listen on; a port of 0 picks a free one")
+	noOpen := flags.Bool("no-open", false, "Print the address without opening the browser")
+	flags.Parse(args)
+
+	if !*dashboard {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode serve -dashboard [-control] [-addr 127.0.0.1:0] [-no-open]")
+		os.Exit(1)
+	}
+	if !isLoopbackAddress(*addr) {
+		fmt.Fprintf(os.Stderr, "Error: the dashboard only listens on this machine (127.0.0.1 or localhost), not %s\n", *addr)
+		os.Exit(1)
+	}
+	if _, err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	token, err := generateToken()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	server := &guiServer{
+		configPath:     *configPath,
+		categoriesPath: *categoriesPath,
+		token:          token,
+		desktopFiles:   getAllDesktopShortcuts,
+	}
+	serveLocally("FocusMode dashboard", *addr, token, *noOpen, server.dashboardHandler(*control))
+}
@@ -0,0 +1,124 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDashboard serves the dashboard on a profile in a temp dir
+func newTestDashboard(t *testing.T, control bool) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	configPath := filepath.Join(dir, "profile.yml")
+	if err := os.WriteFile(configPath, []byte("modes:\n  work:\n    destination: Work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gui := &guiServer{
+		configPath:     configPath,
+		categoriesPath: filepath.Join(dir, "categories.yml"),
+		token:          "secret",
+		desktopFiles:   func() ([]string, error) { return []string{"Steam.lnk"}, nil },
+	}
+	server := httptest.NewServer(gui.dashboardHandler(control))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dashboardRequest sends a request with the token and returns the status
+func dashboardRequest(t *testing.T, server *httptest.Server, method, path string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, server.URL+path, strings.NewReader("{}"))
+	req.Header.Set(guiTokenHeader, "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode
+}
+
+// TestDashboardReadOnly tests that the dashboard serves its page, state and stats but changes nothing
+func TestDashboardReadOnly(t *testing.T) {
+	server := newTestDashboard(t, false)
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the page, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var stats dashboardStats
+	if status := dashboardRequest(t, server, http.MethodGet, "/api/stats", &stats); status != http.StatusOK {
+		t.Fatalf("Expected the stats, got %d", status)
+	}
+	if len(stats.Days) != dashboardDays || len(stats.Weeks) != dashboardWeeks || stats.Control {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	var state guiState
+	if status := dashboardRequest(t, server, http.MethodGet, "/api/state", &state); status != http.StatusOK || len(state.Modes) == 0 {
+		t.Errorf("Expected the state, got %d %+v", status, state)
+	}
+
+	for _, path := range []string{"/api/apply", "/api/assign", "/api/session/stop"} {
+		if status := dashboardRequest(t, server, http.MethodPost, path, nil); status != http.StatusNotFound {
+			t.Errorf("POST %s = %d, want 404 on a read-only dashboard", path, status)
+		}
+	}
+	if status := dashboardRequest(t, server, http.MethodPost, "/api/stats", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/stats = %d, want 405", status)
+	}
+}
+
+// TestDashboardControl tests that -control allows session commands but not starting sessions
+func TestDashboardControl(t *testing.T) {
+	server := newTestDashboard(t, true)
+
+	var stats dashboardStats
+	dashboardRequest(t, server, http.MethodGet, "/api/stats", &stats)
+	if !stats.Control {
+		t.Error("Expected the stats to say the session can be controlled")
+	}
+	if status := dashboardRequest(t, server, http.MethodPost, "/api/session/stop", nil); status != http.StatusConflict {
+		t.Errorf("Expected stop without a session to conflict, got %d", status)
+	}
+	if status := dashboardRequest(t, server, http.MethodPost, "/api/session/start", nil); status != http.StatusForbidden {
+		t.Errorf("Expected starting a session to be refused, got %d", status)
+	}
+	if status := dashboardRequest(t, server, http.MethodPost, "/api/apply", nil); status != http.StatusNotFound {
+		t.Errorf("Expected apply to stay unavailable, got %d", status)
+	}
+}
+
+// TestNewDashboardStats tests converting the stats summary for the charts
+func TestNewDashboardStats(t *testing.T) {
+	today := time.Date(2024, 3, 6, 0, 0, 0, 0, time.Local)
+	records := []SessionRecord{
+		{Kind: RecordSession, StartTime: today.Add(9 * time.Hour), FocusedSeconds: 1500, Completed: true},
+		{Kind: RecordSession, StartTime: today.Add(-15 * time.Hour), FocusedSeconds: 600},
+	}
+	stats := newDashboardStats(summarizeHistory(records, today, 2, 1))
+	if len(stats.Days) != 2 || stats.Days[0].Start != "2024-03-05" || stats.Days[0].FocusedMinutes != 10 || stats.Days[1].FocusedMinutes != 25 {
+		t.Errorf("Unexpected days: %+v", stats.Days)
+	}
+	if len(stats.Weeks) != 1 || stats.Weeks[0].Start != "2024-03-04" || stats.Weeks[0].Sessions != 2 {
+		t.Errorf("Unexpected weeks: %+v", stats.Weeks)
+	}
+	if stats.Sessions != 2 || stats.Completed != 1 || stats.AverageMinutes != 17 || stats.CurrentStreak != 2 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+}
@@ -0,0 +1,160 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecation describes a config key or command-line flag that still works but is going away
+type deprecation struct {
+	Name        string // dotted config path, with <name> matching any map key, or a flag name without dashes
+	Replacement string // key or flag used instead, at the same level; empty if the setting is being dropped
+	Command     string // for flags replaced by a subcommand, e.g. "modes list"; the flag is dropped and the command runs
+	Note        string // extra advice shown with the warning
+}
+
+// deprecatedConfigKeys lists profile.yml keys that are still read under their old names
+// Items of a list share the path of the list, e.g. routines.<name>.duration for routine steps
+var deprecatedConfigKeys = []deprecation{
+	// {Name: "ide_watch.minutes", Replacement: "idle_minutes"},
+}
+
+// deprecatedFlags lists command-line flags that are still accepted under their old names
+var deprecatedFlags = []deprecation{
+	// {Name: "list-modes", Command: "modes list"},
+}
+
+var warnedDeprecations sync.Map
+
+// warnDeprecatedOnce prints a deprecation warning the first time it is seen in this process
+func warnDeprecatedOnce(message string) {
+	if _, seen := warnedDeprecations.LoadOrStore(message, true); !seen {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	}
+}
+
+// deprecationMessage describes what to use instead of a deprecated setting
+func deprecationMessage(kind, name, replacement string, d deprecation) string {
+	message := fmt.Sprintf("%s %s is deprecated", kind, name)
+	if replacement != "" {
+		message += fmt.Sprintf("; use %s instead", replacement)
+	} else {
+		message += " and will be removed"
+	}
+	if d.Note != "" {
+		message += " (" + d.Note + ")"
+	}
+	return message
+}
+
+// matchConfigPath reports whether a config path matches a deprecation's dotted name
+func matchConfigPath(name string, path []string) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) != len(path) {
+		return false
+	}
+	for i, part := range parts {
+		if part != "<name>" && part != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateDeprecatedKeys renames deprecated keys in a parsed YAML document to their replacements
+// A key whose replacement is also set is left alone, so the replacement wins
+func migrateDeprecatedKeys(node *yaml.Node, table []deprecation, warn func(string)) {
+	var walk func(node *yaml.Node, path []string)
+	walk = func(node *yaml.Node, path []string) {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range node.Content {
+				walk(child, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				keyPath := append(path[:len(path):len(path)], key.Value)
+				for _, d := range table {
+					if matchConfigPath(d.Name, keyPath) {
+						migrateKey(node, key, keyPath, d, warn)
+						break
+					}
+				}
+				walk(value, keyPath)
+			}
+		}
+	}
+	walk(node, nil)
+}
+
+// migrateKey renames one deprecated key within its mapping
+func migrateKey(mapping, key *yaml.Node, path []string, d deprecation, warn func(string)) {
+	old := strings.Join(path, ".")
+	if d.Replacement == "" {
+		warn(deprecationMessage("config key", old, "", d))
+		return
+	}
+	replacement := strings.Join(append(path[:len(path)-1:len(path)-1], d.Replacement), ".")
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == d.Replacement {
+			warn(fmt.Sprintf("config key %s is deprecated and ignored because %s is also set", old, replacement))
+			return
+		}
+	}
+	warn(deprecationMessage("config key", old, replacement, d))
+	key.Value = d.Replacement
+}
+
+// rewriteDeprecatedArgs replaces deprecated flags in a command line with their replacements
+// A flag replaced by a subcommand is removed and the subcommand is put in front of the remaining arguments
+func rewriteDeprecatedArgs(args []string, table []deprecation, warn func(string)) []string {
+	rewritten := make([]string, 0, len(args))
+	var command []string
+	for i, arg := range args {
+		if arg == "--" {
+			rewritten = append(rewritten, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		d, found := findDeprecatedFlag(table, name)
+		if !strings.HasPrefix(arg, "-") || !found {
+			rewritten = append(rewritten, arg)
+			continue
+		}
+
+		switch {
+		case d.Command != "":
+			warn(deprecationMessage("flag", "-"+name, "focusmode "+d.Command, d))
+			command = strings.Fields(d.Command)
+		case d.Replacement != "":
+			warn(deprecationMessage("flag", "-"+name, "-"+d.Replacement, d))
+			if hasValue {
+				rewritten = append(rewritten, "-"+d.Replacement+"="+value)
+			} else {
+				rewritten = append(rewritten, "-"+d.Replacement)
+			}
+		default:
+			warn(deprecationMessage("flag", "-"+name, "", d))
+			rewritten = append(rewritten, arg)
+		}
+	}
+	if command != nil {
+		return append(command, rewritten...)
+	}
+	return rewritten
+}
+
+// findDeprecatedFlag returns the deprecation registered for a flag name
+func findDeprecatedFlag(table []deprecation, name string) (deprecation, bool) {
+	for _, d := range table {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return deprecation{}, false
+}
@@ -0,0 +1,141 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestMigrateDeprecatedKeys tests renaming deprecated config keys, including below map entries
+func TestMigrateDeprecatedKeys(t *testing.T) {
+	table := []deprecation{
+		{Name: "automation.grace", Replacement: "grace_seconds"},
+		{Name: "modes.<name>.folder", Replacement: "destination"},
+		{Name: "ambient.loop", Note: "sounds always loop"},
+	}
+	data := `
+automation:
+  grace: 5
+modes:
+  focusmode:
+    folder: Focus
+    shortcuts: [Steam.lnk]
+  gamemode:
+    folder: Old
+    destination: Games
+ambient:
+  loop: true
+`
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	migrateDeprecatedKeys(&document, table, func(message string) { warnings = append(warnings, message) })
+
+	var config Config
+	if err := document.Decode(&config); err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if config.Automation.GraceSeconds != 5 {
+		t.Errorf("Expected automation.grace to set grace_seconds, got %d", config.Automation.GraceSeconds)
+	}
+	if config.Modes["focusmode"].Destination != "Focus" {
+		t.Errorf("Expected folder to set destination, got %q", config.Modes["focusmode"].Destination)
+	}
+	if config.Modes["gamemode"].Destination != "Games" {
+		t.Errorf("Expected the replacement to win over the old key, got %q", config.Modes["gamemode"].Destination)
+	}
+
+	want := []string{
+		"config key automation.grace is deprecated; use automation.grace_seconds instead",
+		"config key modes.focusmode.folder is deprecated; use modes.focusmode.destination instead",
+		"config key modes.gamemode.folder is deprecated and ignored because modes.gamemode.destination is also set",
+		"config key ambient.loop is deprecated and will be removed (sounds always loop)",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("Unexpected warnings:\n got %q\nwant %q", warnings, want)
+	}
+}
+
+// TestRewriteDeprecatedArgs tests mapping deprecated flags to new flags and subcommands
+func TestRewriteDeprecatedArgs(t *testing.T) {
+	table := []deprecation{
+		{Name: "dryrun", Replacement: "dry-run"},
+		{Name: "list-modes", Command: "modes list"},
+		{Name: "verbose"},
+	}
+	tests := []struct {
+		args     []string
+		want     []string
+		warnings int
+	}{
+		{[]string{"-mode", "gamemode", "--dryrun"}, []string{"-mode", "gamemode", "-dry-run"}, 1},
+		{[]string{"-dryrun=false"}, []string{"-dry-run=false"}, 1},
+		{[]string{"-config", "my.yml", "-list-modes"}, []string{"modes", "list", "-config", "my.yml"}, 1},
+		{[]string{"-verbose"}, []string{"-verbose"}, 1},
+		{[]string{"session", "start", "--", "-dryrun"}, []string{"session", "start", "--", "-dryrun"}, 0},
+		{[]string{"dryrun"}, []string{"dryrun"}, 0},
+	}
+	for _, tt := range tests {
+		var warnings []string
+		got := rewriteDeprecatedArgs(tt.args, table, func(message string) { warnings = append(warnings, message) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rewriteDeprecatedArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+		if len(warnings) != tt.warnings {
+			t.Errorf("rewriteDeprecatedArgs(%q) warned %q, want %d warning(s)", tt.args, warnings, tt.warnings)
+		}
+	}
+}
+
+// TestReadConfigDeprecatedKeys tests that readConfig applies the registered config migrations
+func TestReadConfigDeprecatedKeys(t *testing.T) {
+	saved := deprecatedConfigKeys
+	deprecatedConfigKeys = []deprecation{{Name: "default", Replacement: "default_mode"}}
+	t.Cleanup(func() { deprecatedConfigKeys = saved })
+
+	path := filepath.Join(t.TempDir(), "profile.yml")
+	if err := os.WriteFile(path, []byte("default: gamemode\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := readConfig(path)
+	if err != nil {
+		t.Fatalf("readConfig() returned error: %v", err)
+	}
+	if config.DefaultMode != "gamemode" {
+		t.Errorf("Expected default_mode gamemode, got %q", config.DefaultMode)
+	}
+
+	// An empty file still loads
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := readConfig(path); err != nil || config.DefaultMode != "focusmode" {
+		t.Errorf("Expected empty config to load with defaults, got %v, %v", config, err)
+	}
+}
+
+// TestDeprecationTablesValid tests that registered deprecations point at settings that exist
+func TestDeprecationTablesValid(t *testing.T) {
+	for _, d := range deprecatedConfigKeys {
+		if d.Command != "" {
+			t.Errorf("config key %s: Command only applies to flags", d.Name)
+		}
+		if strings.HasPrefix(d.Name, ".") || strings.HasSuffix(d.Name, ".") {
+			t.Errorf("config key %q is not a dotted path", d.Name)
+		}
+	}
+	for _, d := range deprecatedFlags {
+		if strings.HasPrefix(d.Name, "-") {
+			t.Errorf("flag %q must be registered without dashes", d.Name)
+		}
+		if d.Command != "" && d.Replacement != "" {
+			t.Errorf("flag %s has both a replacement flag and a command", d.Name)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package focusmode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// shellFoldersKey is the registry key where Explorer records where the user's folders are,
+// including a desktop redirected to OneDrive, another drive or a server
+const shellFoldersKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`
+
+var (
+	windowsDesktopMu    sync.Mutex
+	windowsDesktopPaths = map[string]string{} // By home folder, so the registry is read once per run
+)
+
+// windowsDesktopPath returns the desktop folder of a Windows user, read from the registry,
+// or home\Desktop when the registry can't be read or names a folder that doesn't exist
+func windowsDesktopPath(ctx *userContext) string {
+	windowsDesktopMu.Lock()
+	defer windowsDesktopMu.Unlock()
+	if path, ok := windowsDesktopPaths[ctx.Home]; ok {
+		return path
+	}
+	path := filepath.Join(ctx.Home, "Desktop")
+	out, err := exec.Command("reg", "query", shellFoldersRoot(ctx)+`\`+shellFoldersKey, "/v", "Desktop").Output()
+	if err == nil {
+		if value := parseRegValue(string(out), "Desktop"); value != "" {
+			if folder, ok := expandShellFolder(value, ctx.Home, os.Getenv); ok {
+				if info, err := os.Stat(folder); err == nil && info.IsDir() {
+					path = folder
+				} else {
+					fmt.Fprintf(os.Stderr, "Warning: the desktop folder %s from the registry doesn't exist; using %s\n", folder, path)
+				}
+			}
+		}
+	}
+	windowsDesktopPaths[ctx.Home] = path
+	return path
+}
+
+// shellFoldersRoot returns the registry hive of the user: HKCU for the user running the process,
+// or the user's hive under HKU, which is only loaded while they are logged in
+func shellFoldersRoot(ctx *userContext) string {
+	if ctx.other && ctx.sid != "" {
+		return `HKU\` + ctx.sid
+	}
+	return "HKCU"
+}
+
+// parseRegValue extracts the data of a value from the output of "reg query /v"
+// Lines look like "    Desktop    REG_EXPAND_SZ    %USERPROFILE%\Desktop"
+func parseRegValue(output, name string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "    ", 3)
+		if len(fields) == 3 && strings.EqualFold(fields[0], name) && strings.HasPrefix(fields[1], "REG_") {
+			return strings.TrimSpace(fields[2])
+		}
+	}
+	return ""
+}
+
+// expandShellFolder expands the %VARIABLES% in a registry folder path, taking USERPROFILE from
+// home so another user's folders point into their profile rather than the process's
+// It fails when a variable is unset or the result isn't an absolute path
+func expandShellFolder(value, home string, getenv func(string) string) (string, bool) {
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "%")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(value[start+1:], "%")
+		if end < 0 {
+			return "", false
+		}
+		name := value[start+1 : start+1+end]
+		expanded := getenv(name)
+		if strings.EqualFold(name, "USERPROFILE") {
+			expanded = home
+		}
+		if expanded == "" {
+			return "", false
+		}
+		b.WriteString(value[:start])
+		b.WriteString(expanded)
+		value = value[start+2+end:]
+	}
+	b.WriteString(value)
+	path := b.String()
+	if !isWindowsAbs(path) {
+		return "", false
+	}
+	return filepath.Clean(path), true
+}
+
+// isWindowsAbs reports whether path is a drive or UNC path, which filepath.IsAbs only
+// recognizes when running on Windows
+func isWindowsAbs(path string) bool {
+	if strings.HasPrefix(path, `\\`) {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/')
+}
+
+// linuxDesktopPath returns the desktop folder of a Linux user from XDG_DESKTOP_DIR, which desktop
+// environments set in user-dirs.dirs, often to a localized name such as ~/Schreibtisch
+// ~/Desktop is used when neither the environment nor the file names one; a desktop set to the
+// home folder itself is how the user turns it off, and is an error
+func linuxDesktopPath(ctx *userContext) (string, error) {
+	value := ""
+	if !ctx.other {
+		value = os.Getenv("XDG_DESKTOP_DIR")
+	}
+	source := "XDG_DESKTOP_DIR"
+	if value == "" {
+		source = filepath.Join(ctx.ConfigDir, "user-dirs.dirs")
+		data, err := os.ReadFile(source)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("error reading %s: %w", source, err)
+		}
+		value = parseUserDirs(string(data))["XDG_DESKTOP_DIR"]
+	}
+	if value == "" {
+		return filepath.Join(ctx.Home, "Desktop"), nil
+	}
+	path, ok := expandUserDir(value, ctx.Home)
+	if !ok {
+		return "", fmt.Errorf("invalid desktop folder %q in %s: it must start with $HOME/ or /", value, source)
+	}
+	if path == filepath.Clean(ctx.Home) {
+		return "", fmt.Errorf("no desktop folder: it is turned off in %s (set to the home folder)", source)
+	}
+	return path, nil
+}
+
+// parseUserDirs reads the NAME="value" lines of a user-dirs.dirs file, skipping comments
+func parseUserDirs(data string) map[string]string {
+	dirs := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(strings.TrimSpace(value)); err == nil {
+			value = unquoted
+		}
+		dirs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return dirs
+}
+
+// expandUserDir expands a user-dirs path, which is either absolute or relative to $HOME
+func expandUserDir(value, home string) (string, bool) {
+	switch {
+	case value == "$HOME" || value == "${HOME}":
+		return filepath.Clean(home), true
+	case strings.HasPrefix(value, "$HOME/"):
+		return filepath.Join(home, strings.TrimPrefix(value, "$HOME/")), true
+	case strings.HasPrefix(value, "${HOME}/"):
+		return filepath.Join(home, strings.TrimPrefix(value, "${HOME}/")), true
+	case strings.HasPrefix(value, "/"):
+		return filepath.Clean(value), true
+	}
+	return "", false
+}
@@ -0,0 +1,97 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseRegValue tests reading a value from "reg query" output
+func TestParseRegValue(t *testing.T) {
+	output := "\r\nHKEY_CURRENT_USER\\" + shellFoldersKey + "\r\n" +
+		"    Desktop    REG_EXPAND_SZ    %USERPROFILE%\\OneDrive - Contoso\\Desktop\r\n\r\n"
+	if got := parseRegValue(output, "Desktop"); got != `%USERPROFILE%\OneDrive - Contoso\Desktop` {
+		t.Errorf("Unexpected value %q", got)
+	}
+	if got := parseRegValue(output, "Personal"); got != "" {
+		t.Errorf("Expected no value for a missing name, got %q", got)
+	}
+}
+
+// TestExpandShellFolder tests expanding registry folder paths for the managed user
+func TestExpandShellFolder(t *testing.T) {
+	env := map[string]string{"OneDrive": `C:\Users\me\OneDrive`, "USERPROFILE": `C:\Windows\system32\config\systemprofile`}
+	getenv := func(name string) string { return env[name] }
+	tests := []struct {
+		value, want string
+		ok          bool
+	}{
+		{`%USERPROFILE%\Desktop`, `C:\Users\me\Desktop`, true}, // The managed user's profile, not the service's
+		{`%OneDrive%\Desktop`, `C:\Users\me\OneDrive\Desktop`, true},
+		{`D:\Desk`, `D:\Desk`, true},
+		{`\\server\home\me\Desktop`, `\\server\home\me\Desktop`, true},
+		{`%NOSUCH%\Desktop`, "", false},
+		{`%USERPROFILE\Desktop`, "", false},
+		{`Desktop`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := expandShellFolder(tt.value, `C:\Users\me`, getenv)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("expandShellFolder(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestShellFoldersRoot tests that another user's folders are read from their own hive
+func TestShellFoldersRoot(t *testing.T) {
+	if got := shellFoldersRoot(&userContext{}); got != "HKCU" {
+		t.Errorf("Expected HKCU for the process user, got %s", got)
+	}
+	if got := shellFoldersRoot(&userContext{other: true, sid: "S-1-5-21-1-2-3-1001"}); got != `HKU\S-1-5-21-1-2-3-1001` {
+		t.Errorf("Expected the user's hive, got %s", got)
+	}
+}
+
+// TestLinuxDesktopPath tests finding the desktop from XDG user-dirs, localized or turned off
+func TestLinuxDesktopPath(t *testing.T) {
+	t.Setenv("XDG_DESKTOP_DIR", "")
+	home := t.TempDir()
+	ctx := &userContext{Home: home, ConfigDir: filepath.Join(home, ".config")}
+	if got, err := linuxDesktopPath(ctx); err != nil || got != filepath.Join(home, "Desktop") {
+		t.Errorf("Expected ~/Desktop without user-dirs.dirs, got %q (err %v)", got, err)
+	}
+
+	os.MkdirAll(ctx.ConfigDir, 0755)
+	userDirs := filepath.Join(ctx.ConfigDir, "user-dirs.dirs")
+	tests := []struct {
+		file, want, wantErr string
+	}{
+		{"# written by xdg-user-dirs-update\nXDG_DESKTOP_DIR=\"$HOME/Schreibtisch\"\nXDG_MUSIC_DIR=\"$HOME/Musik\"\n", filepath.Join(home, "Schreibtisch"), ""},
+		{"XDG_DESKTOP_DIR=\"/srv/desks/me\"\n", "/srv/desks/me", ""},
+		{"XDG_MUSIC_DIR=\"$HOME/Music\"\n", filepath.Join(home, "Desktop"), ""},
+		{"XDG_DESKTOP_DIR=\"$HOME/\"\n", "", "turned off"},
+		{"XDG_DESKTOP_DIR=\"Desktop\"\n", "", "must start with $HOME/ or /"},
+	}
+	for _, tt := range tests {
+		os.WriteFile(userDirs, []byte(tt.file), 0644)
+		got, err := linuxDesktopPath(ctx)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q for %q, got %q (err %v)", tt.wantErr, tt.file, got, err)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("Expected %s for %q, got %q (err %v)", tt.want, tt.file, got, err)
+		}
+	}
+
+	// The environment wins for the user running the process, but not for another user
+	t.Setenv("XDG_DESKTOP_DIR", filepath.Join(home, "Bureau"))
+	if got, _ := linuxDesktopPath(ctx); got != filepath.Join(home, "Bureau") {
+		t.Errorf("Expected XDG_DESKTOP_DIR to win, got %q", got)
+	}
+	ctx.other = true
+	if _, err := linuxDesktopPath(ctx); err == nil {
+		t.Error("Expected another user's own user-dirs.dirs to be used")
+	}
+}
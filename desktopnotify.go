@@ -0,0 +1,171 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session events that can be shown as desktop notifications
+const (
+	desktopEventStart    = "start"    // A focus session started
+	desktopEventHalfway  = "halfway"  // Half of a focus session has passed
+	desktopEventBreak    = "break"    // A break started or ended
+	desktopEventComplete = "complete" // A focus session ran to the end
+)
+
+// desktopEvents lists the events in the order they happen
+var desktopEvents = []string{desktopEventStart, desktopEventHalfway, desktopEventBreak, desktopEventComplete}
+
+// NotifyConfig represents the desktop notifications shown for session events
+type NotifyConfig struct {
+	Enabled bool     `yaml:"enabled" doc:"Show session events as desktop notifications: toasts on Windows, notify-send on Linux, terminal-notifier or osascript on macOS" default:"false"`
+	Events  []string `yaml:"events" doc:"Events to notify about: start, halfway, break (a break starting or ending) and complete" default:"[start, halfway, break, complete]"`
+}
+
+// events returns the set of events to notify about, or an error naming an unknown one
+func (c NotifyConfig) events() (map[string]bool, error) {
+	names := c.Events
+	if len(names) == 0 {
+		names = desktopEvents
+	}
+	events := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsFold(desktopEvents, name) {
+			return nil, fmt.Errorf("notify.events: unknown event '%s'; use %s", name, strings.Join(desktopEvents, ", "))
+		}
+		events[name] = true
+	}
+	return events, nil
+}
+
+// notifyCommandArgs returns the external command that shows a desktop notification on the given platform
+func notifyCommandArgs(goos, title, message string, lookPath func(string) (string, error)) (string, []string, error) {
+	switch goos {
+	case "windows":
+		// Toasts need a registered app ID; PowerShell's is present on every install
+		quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+		script := "[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null; " +
+			"$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02); " +
+			"$x = $t.GetElementsByTagName('text'); " +
+			fmt.Sprintf("$x.Item(0).AppendChild($t.CreateTextNode(%s)) > $null; ", quote(title)) +
+			fmt.Sprintf("$x.Item(1).AppendChild($t.CreateTextNode(%s)) > $null; ", quote(message)) +
+			"$id = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\\WindowsPowerShell\\v1.0\\powershell.exe'; " +
+			"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($id).Show([Windows.UI.Notifications.ToastNotification]::new($t))"
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "darwin":
+		if _, err := lookPath("terminal-notifier"); err == nil {
+			return "terminal-notifier", []string{"-title", title, "-message", message, "-group", "focusmode"}, nil
+		}
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		return "osascript", []string{"-e", fmt.Sprintf("display notification %s with title %s", quote(message), quote(title))}, nil
+	case "linux":
+		if _, err := lookPath("notify-send"); err != nil {
+			return "", nil, fmt.Errorf("notify-send not found (install libnotify-bin or libnotify)")
+		}
+		return "notify-send", []string{"--app-name", "FocusMode", title, message}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// desktopNotifier shows session events as native desktop notifications
+// It is also a notifier backend, so milestones and other notices appear on the desktop too
+type desktopNotifier struct {
+	events  map[string]bool
+	show    func(title, message string) error
+	halfway bool // Whether the halfway notification was shown
+
+	mu     sync.Mutex
+	failed bool // Showing a notification failed, which is only reported once
+}
+
+// newDesktopNotifier creates a notifier for the configured events
+func newDesktopNotifier(config NotifyConfig) (*desktopNotifier, error) {
+	events, err := config.events()
+	if err != nil {
+		return nil, err
+	}
+	return &desktopNotifier{events: events, show: showDesktopNotification}, nil
+}
+
+// showDesktopNotification shows a notification on the current platform
+func showDesktopNotification(title, message string) error {
+	return currentPlatform.Notify(title, message)
+}
+
+// notify shows a notification in the background if the event is enabled
+func (n *desktopNotifier) notify(event, title, message string) {
+	if n.events[event] {
+		go n.Notify(title, message)
+	}
+}
+
+// Notify shows a notification, warning once if notifications can't be shown
+// Errors are not returned so a missing notify-send isn't reported for every message
+func (n *desktopNotifier) Notify(title, message string) error {
+	if err := n.show(title, message); err != nil {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if !n.failed {
+			n.failed = true
+			fmt.Fprintf(os.Stderr, "\nWarning: desktop notifications unavailable: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// OnStart notifies that a session or break started
+func (n *desktopNotifier) OnStart(fs *FocusSession) {
+	if fs.Break {
+		n.notify(desktopEventBreak, "Break started", fmt.Sprintf("%s break", formatDuration(fs.Duration)))
+		return
+	}
+	message := fmt.Sprintf("%s in %s", formatDuration(fs.Duration), fs.Mode)
+	if fs.Goal != "" {
+		message += ": " + fs.Goal
+	}
+	n.notify(desktopEventStart, "Focus session started", message)
+}
+
+// OnPause does nothing
+func (n *desktopNotifier) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (n *desktopNotifier) OnResume(fs *FocusSession) {}
+
+// OnTick notifies once when half of a focus session has passed
+func (n *desktopNotifier) OnTick(fs *FocusSession) {
+	if fs.Break || n.halfway || fs.elapsed() < fs.Duration/2 {
+		return
+	}
+	n.halfway = true
+	n.notify(desktopEventHalfway, "Halfway there", fmt.Sprintf("%s remaining", formatDuration(fs.remaining().Round(time.Minute))))
+}
+
+// OnEnd notifies that a break ended or a focus session completed; stopping a session early is not announced
+// The notification is shown before returning, as the process may exit once the session ends
+func (n *desktopNotifier) OnEnd(fs *FocusSession) {
+	switch {
+	case fs.Break && n.events[desktopEventBreak]:
+		n.Notify("Break over", "Time to focus again")
+	case !fs.Break && fs.State == StateCompleted && n.events[desktopEventComplete]:
+		n.Notify("Focus session complete", fmt.Sprintf("%s in %s", formatDuration(fs.Duration), fs.Mode))
+	}
+}
+
+// DryRun reports the events that would be shown
+func (n *desktopNotifier) DryRun(fs *FocusSession) []string {
+	var events []string
+	for _, event := range desktopEvents {
+		if n.events[event] {
+			events = append(events, event)
+		}
+	}
+	return []string{fmt.Sprintf("show desktop notifications for %s", strings.Join(events, ", "))}
+}
@@ -0,0 +1,88 @@
+package focusmode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNotifyCommandArgs tests the notification command of each platform and the quoting of its text
+func TestNotifyCommandArgs(t *testing.T) {
+	found := func(string) (string, error) { return "/usr/bin/x", nil }
+	missing := func(string) (string, error) { return "", errors.New("not found") }
+
+	name, args, err := notifyCommandArgs("linux", "Break over", "Time to focus again", found)
+	if err != nil || name != "notify-send" || args[len(args)-2] != "Break over" || args[len(args)-1] != "Time to focus again" {
+		t.Errorf("linux: %s %v (err %v)", name, args, err)
+	}
+	if _, _, err := notifyCommandArgs("linux", "a", "b", missing); err == nil || !strings.Contains(err.Error(), "notify-send") {
+		t.Errorf("Expected an error naming notify-send, got %v", err)
+	}
+
+	if name, _, _ := notifyCommandArgs("darwin", "a", "b", found); name != "terminal-notifier" {
+		t.Errorf("Expected terminal-notifier when installed, got %s", name)
+	}
+	name, args, _ = notifyCommandArgs("darwin", "Done", `say "hi" \o/`, missing)
+	if name != "osascript" || args[1] != `display notification "say \"hi\" \\o/" with title "Done"` {
+		t.Errorf("darwin: %s %v", name, args)
+	}
+
+	name, args, _ = notifyCommandArgs("windows", "Done", "Dave's session", missing)
+	if name != "powershell" || !strings.Contains(args[len(args)-1], "'Dave''s session'") {
+		t.Errorf("windows: %s %v", name, args)
+	}
+}
+
+// TestDesktopNotifier tests that only the configured events are shown, and halfway only once
+func TestDesktopNotifier(t *testing.T) {
+	if _, err := (NotifyConfig{Events: []string{"start", "lunch"}}).events(); err == nil || !strings.Contains(err.Error(), "lunch") {
+		t.Errorf("Expected an unknown event error, got %v", err)
+	}
+
+	shown := make(chan string, 10)
+	n, err := newDesktopNotifier(NotifyConfig{Enabled: true, Events: []string{"halfway", "Complete"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.show = func(title, message string) error {
+		shown <- title
+		return nil
+	}
+
+	fs := &FocusSession{Mode: "focusmode", Duration: 50 * time.Minute, StartTime: time.Now(), State: StateRunning}
+	n.OnStart(fs)
+	n.OnTick(fs)
+	fs.StartTime = fs.StartTime.Add(-30 * time.Minute)
+	n.OnTick(fs)
+	n.OnTick(fs)
+	fs.State = StateCompleted
+	n.OnEnd(fs)
+
+	var titles []string
+	for len(titles) < 2 {
+		select {
+		case title := <-shown:
+			titles = append(titles, title)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 notifications, got %v", titles)
+		}
+	}
+	select {
+	case title := <-shown:
+		t.Errorf("Unexpected notification %q", title)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !containsFold(titles, "Halfway there") || !containsFold(titles, "Focus session complete") {
+		t.Errorf("Expected halfway and complete, got %v", titles)
+	}
+
+	// A session stopped early isn't reported as complete
+	fs.State = StateInterrupted
+	n.OnEnd(fs)
+	select {
+	case title := <-shown:
+		t.Errorf("Unexpected notification %q for a stopped session", title)
+	default:
+	}
+}
@@ -0,0 +1,327 @@
+package focusmode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiscordConfig represents showing the running session as Discord Rich Presence
+type DiscordConfig struct {
+	Enabled    bool   `yaml:"enabled" doc:"Show the running session's mode and time left as your Discord activity; nothing happens while Discord isn't running" default:"false"`
+	ClientID   string `yaml:"client_id" doc:"Application ID of the Discord application whose name is shown, such as one named FocusMode created at discord.com/developers/applications" example:"'1234567890123456789'"`
+	ShowGoal   bool   `yaml:"show_goal" doc:"Show the session's goal under the mode; off keeps goals to yourself" default:"false"`
+	LargeImage string `yaml:"large_image" doc:"Name of an art asset uploaded to the Discord application, shown next to the activity" example:"focus"`
+}
+
+// Frame opcodes of Discord's local IPC
+const (
+	discordOpHandshake = 0
+	discordOpFrame     = 1
+	discordOpClose     = 2
+)
+
+// discordReplyTimeout is how long Discord has to answer a handshake or an activity update
+const discordReplyTimeout = 5 * time.Second
+
+// discordMaxFrame bounds a reply from the socket, which is only ever a short JSON object
+const discordMaxFrame = 64 << 10
+
+// errDiscordNotRunning means no Discord client is listening, which is not an error for sessions
+var errDiscordNotRunning = errors.New("Discord isn't running")
+
+// discordIPCPaths returns where a Discord client may listen, in the order the clients try them:
+// a named pipe on Windows, and a socket in the runtime or temp folder elsewhere, also inside
+// the Flatpak and Snap sandboxes
+func discordIPCPaths(goos string, getenv func(string) string) []string {
+	var paths []string
+	if goos == "windows" {
+		for i := 0; i < 10; i++ {
+			paths = append(paths, fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, i))
+		}
+		return paths
+	}
+	var dirs []string
+	for _, key := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := getenv(key); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/tmp")
+	for i := 0; i < 10; i++ {
+		for _, dir := range dirs {
+			for _, sub := range []string{"", "app/com.discordapp.Discord", "snap.discord"} {
+				paths = append(paths, filepath.Join(dir, sub, fmt.Sprintf("discord-ipc-%d", i)))
+			}
+		}
+	}
+	return paths
+}
+
+// dialDiscordIPC connects to the first Discord client listening on this machine
+func dialDiscordIPC() (io.ReadWriteCloser, error) {
+	for _, path := range discordIPCPaths(runtime.GOOS, os.Getenv) {
+		if runtime.GOOS == "windows" {
+			if pipe, err := os.OpenFile(path, os.O_RDWR, 0); err == nil {
+				return pipe, nil
+			}
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, errDiscordNotRunning
+}
+
+// writeDiscordFrame sends a frame: the opcode and length as little-endian uint32s, then the JSON
+func writeDiscordFrame(w io.Writer, op uint32, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint32(frame[0:4], op)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(data)))
+	copy(frame[8:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// readDiscordFrame reads a frame sent by Discord
+func readDiscordFrame(r io.Reader) (uint32, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.LittleEndian.Uint32(header[4:8])
+	if length > discordMaxFrame {
+		return 0, nil, fmt.Errorf("frame of %d bytes is too large", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return binary.LittleEndian.Uint32(header[0:4]), data, nil
+}
+
+// discordReply is what Discord answers a handshake or a command with
+type discordReply struct {
+	Cmd  string `json:"cmd"`
+	Evt  string `json:"evt"`
+	Data struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"data"`
+	Code    int    `json:"code"`    // Of a close frame
+	Message string `json:"message"` // Of a close frame
+}
+
+// discordClient is a connection to the local Discord client
+type discordClient struct {
+	conn  io.ReadWriteCloser
+	nonce int
+}
+
+// newDiscordClient shakes hands with Discord as the given application
+func newDiscordClient(conn io.ReadWriteCloser, clientID string) (*discordClient, error) {
+	c := &discordClient{conn: conn}
+	if err := writeDiscordFrame(conn, discordOpHandshake, map[string]interface{}{"v": 1, "client_id": clientID}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error sending handshake: %w", err)
+	}
+	reply, err := c.read()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply.Evt != "READY" {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected handshake reply %s %s", reply.Cmd, reply.Evt)
+	}
+	return c, nil
+}
+
+// read returns Discord's next reply, failing on a close frame or an ERROR event
+func (c *discordClient) read() (discordReply, error) {
+	if conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+		// Named pipes opened as files can't time out; Discord answers right away anyway
+		conn.SetReadDeadline(time.Now().Add(discordReplyTimeout))
+	}
+	op, data, err := readDiscordFrame(c.conn)
+	if err != nil {
+		return discordReply{}, fmt.Errorf("error reading from Discord: %w", err)
+	}
+	var reply discordReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return discordReply{}, fmt.Errorf("error decoding Discord's reply: %w", err)
+	}
+	switch {
+	case op == discordOpClose:
+		return reply, fmt.Errorf("Discord closed the connection: %s (%d)", reply.Message, reply.Code)
+	case reply.Evt == "ERROR":
+		return reply, fmt.Errorf("Discord refused %s: %s (%d)", reply.Cmd, reply.Data.Message, reply.Data.Code)
+	}
+	return reply, nil
+}
+
+// setActivity shows the activity on the user's profile; nil clears it
+func (c *discordClient) setActivity(activity *discordActivity) error {
+	c.nonce++
+	args := map[string]interface{}{"pid": os.Getpid()}
+	if activity != nil {
+		args["activity"] = activity
+	}
+	command := map[string]interface{}{"cmd": "SET_ACTIVITY", "args": args, "nonce": strconv.Itoa(c.nonce)}
+	if err := writeDiscordFrame(c.conn, discordOpFrame, command); err != nil {
+		return fmt.Errorf("error writing to Discord: %w", err)
+	}
+	_, err := c.read()
+	return err
+}
+
+// Close ends the connection, which also makes Discord drop the activity
+func (c *discordClient) Close() error {
+	return c.conn.Close()
+}
+
+// discordActivity is the Rich Presence shown for a session
+type discordActivity struct {
+	Details    string                     `json:"details,omitempty"`
+	State      string                     `json:"state,omitempty"`
+	Timestamps *discordActivityTimestamps `json:"timestamps,omitempty"`
+	Assets     *discordActivityAssets     `json:"assets,omitempty"`
+}
+
+// discordActivityTimestamps makes Discord count down to End, in Unix seconds
+type discordActivityTimestamps struct {
+	End int64 `json:"end"`
+}
+
+// discordActivityAssets is the picture shown with the activity
+type discordActivityAssets struct {
+	LargeImage string `json:"large_image"`
+	LargeText  string `json:"large_text,omitempty"`
+}
+
+// discordText fits a line to the 2-128 characters Discord accepts
+func discordText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 128 {
+		text = string(runes[:127]) + "…"
+	}
+	if text != "" && len([]rune(text)) < 2 {
+		text += " "
+	}
+	return text
+}
+
+// newDiscordActivity describes the session: its mode, and the goal when shown, counting down
+// to its end while it runs
+func newDiscordActivity(fs *FocusSession, config DiscordConfig, now time.Time) *discordActivity {
+	activity := &discordActivity{Details: discordText("Focusing: " + fs.Mode), State: "In a focus block"}
+	if fs.Break {
+		activity.Details, activity.State = "On a break", "Between focus blocks"
+	}
+	if config.ShowGoal && fs.Goal != "" && !fs.Break {
+		activity.State = discordText(fs.Goal)
+	}
+	if fs.State == StatePaused {
+		activity.State = "Paused"
+	} else {
+		activity.Timestamps = &discordActivityTimestamps{End: now.Add(fs.remaining()).Unix()}
+	}
+	if config.LargeImage != "" {
+		activity.Assets = &discordActivityAssets{LargeImage: config.LargeImage, LargeText: "FocusMode"}
+	}
+	return activity
+}
+
+// discordPresenceHook shows the session as the user's Discord activity and clears it at the end
+// Without Discord it does nothing; a client started mid-session is picked up at the next change
+type discordPresenceHook struct {
+	config DiscordConfig
+	dial   func() (io.ReadWriteCloser, error) // dialDiscordIPC outside tests
+	client *discordClient
+}
+
+// connect returns the connection to Discord, opening it if needed; nil when Discord isn't running
+func (h *discordPresenceHook) connect() *discordClient {
+	if h.client != nil {
+		return h.client
+	}
+	conn, err := h.dial()
+	if err != nil {
+		return nil
+	}
+	client, err := newDiscordClient(conn, h.config.ClientID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not connect to Discord: %v\n", err)
+		return nil
+	}
+	h.client = client
+	return client
+}
+
+// update shows the session's current state, dropping the connection if Discord went away
+func (h *discordPresenceHook) update(fs *FocusSession) {
+	client := h.connect()
+	if client == nil {
+		return
+	}
+	if err := client.setActivity(newDiscordActivity(fs, h.config, time.Now())); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not update Discord activity: %v\n", err)
+		client.Close()
+		h.client = nil
+	}
+}
+
+// OnStart shows the session on Discord
+func (h *discordPresenceHook) OnStart(fs *FocusSession) {
+	h.update(fs)
+}
+
+// OnPause shows the session as paused, without a countdown
+func (h *discordPresenceHook) OnPause(fs *FocusSession) {
+	h.update(fs)
+}
+
+// OnResume starts the countdown again
+func (h *discordPresenceHook) OnResume(fs *FocusSession) {
+	h.update(fs)
+}
+
+// OnExtend moves the countdown to the new end of the session
+func (h *discordPresenceHook) OnExtend(fs *FocusSession) {
+	h.update(fs)
+}
+
+// OnEnd clears the activity and disconnects
+func (h *discordPresenceHook) OnEnd(fs *FocusSession) {
+	if h.client == nil {
+		return
+	}
+	if err := h.client.setActivity(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not clear Discord activity: %v\n", err)
+	}
+	h.client.Close()
+	h.client = nil
+}
+
+// DryRun reports the activity that would be shown
+func (h *discordPresenceHook) DryRun(fs *FocusSession) []string {
+	activity := newDiscordActivity(fs, h.config, time.Now())
+	return []string{fmt.Sprintf("show %q as your Discord activity for %s, if Discord is running", activity.Details+" · "+activity.State, formatDuration(fs.Duration))}
+}
@@ -0,0 +1,212 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDiscord answers a client's handshake and activity updates like the Discord client does
+type fakeDiscord struct {
+	mu         sync.Mutex
+	activities []json.RawMessage // Activity of each SET_ACTIVITY; empty when cleared
+	closed     bool
+	refuse     bool // Close the connection instead of accepting the handshake
+}
+
+// dial returns a connection to the fake, served until the client closes it
+func (d *fakeDiscord) dial() (io.ReadWriteCloser, error) {
+	client, server := net.Pipe()
+	go d.serve(server)
+	return client, nil
+}
+
+// serve answers frames until the client hangs up
+func (d *fakeDiscord) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		op, data, err := readDiscordFrame(conn)
+		if err != nil {
+			d.mu.Lock()
+			d.closed = true
+			d.mu.Unlock()
+			return
+		}
+		if op == discordOpHandshake {
+			if d.refuse {
+				writeDiscordFrame(conn, discordOpClose, map[string]interface{}{"code": 4000, "message": "Invalid Client ID"})
+				return
+			}
+			writeDiscordFrame(conn, discordOpFrame, map[string]interface{}{"cmd": "DISPATCH", "evt": "READY"})
+			continue
+		}
+		var command struct {
+			Cmd  string `json:"cmd"`
+			Args struct {
+				Activity json.RawMessage `json:"activity"`
+			} `json:"args"`
+			Nonce string `json:"nonce"`
+		}
+		json.Unmarshal(data, &command)
+		d.mu.Lock()
+		d.activities = append(d.activities, command.Args.Activity)
+		d.mu.Unlock()
+		writeDiscordFrame(conn, discordOpFrame, map[string]interface{}{"cmd": command.Cmd, "nonce": command.Nonce})
+	}
+}
+
+// last returns the last activity set and whether the connection was closed
+func (d *fakeDiscord) last(t *testing.T) (*discordActivity, int, bool) {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.activities) == 0 {
+		return nil, 0, d.closed
+	}
+	raw := d.activities[len(d.activities)-1]
+	if len(raw) == 0 {
+		return nil, len(d.activities), d.closed
+	}
+	var activity *discordActivity
+	if err := json.Unmarshal(raw, &activity); err != nil {
+		t.Fatal(err)
+	}
+	return activity, len(d.activities), d.closed
+}
+
+// TestDiscordIPCPaths tests where Discord is looked for on each platform
+func TestDiscordIPCPaths(t *testing.T) {
+	windows := discordIPCPaths("windows", func(string) string { return "" })
+	if len(windows) != 10 || windows[0] != `\\.\pipe\discord-ipc-0` {
+		t.Errorf("Unexpected Windows paths: %v", windows)
+	}
+
+	env := map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"}
+	linux := discordIPCPaths("linux", func(key string) string { return env[key] })
+	if linux[0] != "/run/user/1000/discord-ipc-0" {
+		t.Errorf("Expected the runtime folder first, got %s", linux[0])
+	}
+	for _, want := range []string{"/run/user/1000/app/com.discordapp.Discord/discord-ipc-0", "/tmp/snap.discord/discord-ipc-9"} {
+		if !containsFold(linux, want) {
+			t.Errorf("Expected %s among the paths", want)
+		}
+	}
+}
+
+// TestDiscordFrames tests encoding and decoding IPC frames
+func TestDiscordFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeDiscordFrame(&buf, discordOpFrame, map[string]string{"cmd": "SET_ACTIVITY"}); err != nil {
+		t.Fatal(err)
+	}
+	if header := buf.Bytes()[:8]; !bytes.Equal(header, []byte{1, 0, 0, 0, 22, 0, 0, 0}) {
+		t.Errorf("Unexpected header % x", header)
+	}
+	op, data, err := readDiscordFrame(&buf)
+	if err != nil || op != discordOpFrame || string(data) != `{"cmd":"SET_ACTIVITY"}` {
+		t.Errorf("readDiscordFrame() = %d, %s, %v", op, data, err)
+	}
+
+	huge := []byte{1, 0, 0, 0, 0, 0, 0, 1}
+	if _, _, err := readDiscordFrame(bytes.NewReader(huge)); err == nil {
+		t.Error("Expected an oversized frame to be refused")
+	}
+}
+
+// TestNewDiscordActivity tests the activity shown for running, paused and break sessions
+func TestNewDiscordActivity(t *testing.T) {
+	now := time.Now()
+	fs := &FocusSession{Mode: "work", Goal: "Write the report", Duration: 25 * time.Minute, StartTime: now, State: StateRunning}
+
+	activity := newDiscordActivity(fs, DiscordConfig{}, now)
+	if activity.Details != "Focusing: work" || activity.State != "In a focus block" || activity.Assets != nil {
+		t.Errorf("Unexpected activity: %+v", activity)
+	}
+	if activity.Timestamps == nil || activity.Timestamps.End < now.Add(24*time.Minute).Unix() || activity.Timestamps.End > now.Add(26*time.Minute).Unix() {
+		t.Errorf("Expected a countdown to the end of the session, got %+v", activity.Timestamps)
+	}
+
+	activity = newDiscordActivity(fs, DiscordConfig{ShowGoal: true, LargeImage: "focus"}, now)
+	if activity.State != "Write the report" || activity.Assets == nil || activity.Assets.LargeImage != "focus" {
+		t.Errorf("Expected the goal and image, got %+v", activity)
+	}
+
+	fs.State, fs.PausedAt = StatePaused, &now
+	if activity := newDiscordActivity(fs, DiscordConfig{ShowGoal: true}, now); activity.State != "Paused" || activity.Timestamps != nil {
+		t.Errorf("Expected a paused activity without countdown, got %+v", activity)
+	}
+
+	brk := &FocusSession{Mode: "work", Goal: "secret", Break: true, Duration: 5 * time.Minute, StartTime: now, State: StateRunning}
+	if activity := newDiscordActivity(brk, DiscordConfig{ShowGoal: true}, now); activity.Details != "On a break" || strings.Contains(activity.State, "secret") {
+		t.Errorf("Unexpected break activity: %+v", activity)
+	}
+}
+
+// TestDiscordPresenceHook tests showing a session on Discord and clearing it at the end
+func TestDiscordPresenceHook(t *testing.T) {
+	discord := &fakeDiscord{}
+	hook := &discordPresenceHook{config: DiscordConfig{ClientID: "123"}, dial: discord.dial}
+	fs := &FocusSession{Mode: "work", Duration: 25 * time.Minute, StartTime: time.Now(), State: StateRunning}
+
+	hook.OnStart(fs)
+	if activity, _, _ := discord.last(t); activity == nil || activity.Details != "Focusing: work" {
+		t.Fatalf("Expected the session shown, got %+v", activity)
+	}
+
+	now := time.Now()
+	fs.State, fs.PausedAt = StatePaused, &now
+	hook.OnPause(fs)
+	if activity, _, _ := discord.last(t); activity == nil || activity.State != "Paused" {
+		t.Errorf("Expected the session shown as paused, got %+v", activity)
+	}
+
+	hook.OnEnd(fs)
+	activity, count, _ := discord.last(t)
+	if activity != nil || count != 3 {
+		t.Errorf("Expected the activity cleared, got %+v after %d updates", activity, count)
+	}
+	for i := 0; i < 100; i++ {
+		if _, _, closed := discord.last(t); closed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the connection closed at the end")
+}
+
+// TestDiscordNotRunning tests that sessions go on quietly without Discord or when it refuses
+func TestDiscordNotRunning(t *testing.T) {
+	dials := 0
+	hook := &discordPresenceHook{config: DiscordConfig{ClientID: "123"}, dial: func() (io.ReadWriteCloser, error) {
+		dials++
+		return nil, errDiscordNotRunning
+	}}
+	fs := &FocusSession{Mode: "work", Duration: 25 * time.Minute, StartTime: time.Now(), State: StateRunning}
+	hook.OnStart(fs)
+	hook.OnResume(fs)
+	hook.OnEnd(fs)
+	if dials != 2 || hook.client != nil {
+		t.Errorf("Expected a try at each update and no connection, got %d tries", dials)
+	}
+
+	// Discord started mid-session is picked up at the next update
+	discord := &fakeDiscord{}
+	hook.dial = discord.dial
+	hook.OnExtend(fs)
+	if activity, _, _ := discord.last(t); activity == nil {
+		t.Error("Expected the session shown once Discord was running")
+	}
+	hook.OnEnd(fs)
+
+	refused := &fakeDiscord{refuse: true}
+	hook = &discordPresenceHook{config: DiscordConfig{ClientID: "bad"}, dial: refused.dial}
+	hook.OnStart(fs)
+	if hook.client != nil {
+		t.Error("Expected no connection after Discord refused the handshake")
+	}
+}
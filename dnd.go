@@ -0,0 +1,121 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dndStatePath returns the file recording that a session turned do-not-disturb on, so it is
+// put back as it was even after a crash
+func dndStatePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dnd.on"), nil
+}
+
+// modeWantsDND reports whether the mode turns on do-not-disturb during its sessions
+func modeWantsDND(config *Config, modeName string) bool {
+	modeConfig, exists := config.Modes[modeName]
+	return exists && modeConfig.DND
+}
+
+// dndState is what dnd.on records: when a session turned do-not-disturb on and whether it was
+// already on, so ending the session puts it back as it was
+type dndState struct {
+	Time     time.Time `json:"time"`
+	Previous bool      `json:"previous"`
+}
+
+// enableDND turns do-not-disturb on, recording it and its previous state at path first so a
+// crash partway still leaves it to be put back
+func enableDND(path string) error {
+	if err := guardWrite("turn on do-not-disturb"); err != nil {
+		return err
+	}
+	previous, err := currentPlatform.DND()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(dndState{Time: time.Now(), Previous: previous})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	ownByUser(path)
+	if previous {
+		return nil
+	}
+	return currentPlatform.SetDND(true)
+}
+
+// disableDND puts do-not-disturb back as it was before FocusMode turned it on, and forgets it
+// It reports whether there was anything to put back
+func disableDND(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	// Records from older versions hold only the time, and it was off before them
+	var state dndState
+	if err == nil {
+		json.Unmarshal(data, &state)
+	}
+	if !state.Previous {
+		if err := currentPlatform.SetDND(false); err != nil {
+			return true, err
+		}
+	}
+	if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+// clearDND puts back do-not-disturb left on by a session that crashed, warning when it can't
+func clearDND() {
+	path, err := dndStatePath()
+	if err != nil {
+		return
+	}
+	if _, err := disableDND(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not turn off do-not-disturb: %v\n", err)
+	}
+}
+
+// dndHook turns on the OS do-not-disturb mode for the length of a session whose mode has dnd
+// Pausing keeps it on, as with app and site blocking
+type dndHook struct {
+	path string
+}
+
+// OnStart turns do-not-disturb on
+func (h *dndHook) OnStart(fs *FocusSession) {
+	if err := enableDND(h.path); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not turn on do-not-disturb: %v\n", err)
+	}
+}
+
+// OnPause does nothing
+func (h *dndHook) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (h *dndHook) OnResume(fs *FocusSession) {}
+
+// OnEnd puts do-not-disturb back as it was
+func (h *dndHook) OnEnd(fs *FocusSession) {
+	if _, err := disableDND(h.path); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not turn off do-not-disturb: %v\n", err)
+	}
+}
+
+// DryRun reports the do-not-disturb changes the session would make
+func (h *dndHook) DryRun(fs *FocusSession) []string {
+	return []string{"turn on do-not-disturb", "put do-not-disturb back as it was when the session ends"}
+}
@@ -0,0 +1,112 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDNDHook tests turning do-not-disturb on for a session and off again at the end
+func TestDNDHook(t *testing.T) {
+	fake := &fakePlatform{}
+	useFakePlatform(t, fake)
+	path := filepath.Join(t.TempDir(), "dnd.on")
+	hook := &dndHook{path: path}
+	fs := &FocusSession{Mode: "focusmode"}
+
+	hook.OnStart(fs)
+	if !fake.dnd {
+		t.Fatal("Expected do-not-disturb on after the session started")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected do-not-disturb to be recorded: %v", err)
+	}
+	hook.OnPause(fs)
+	if !fake.dnd {
+		t.Error("Expected do-not-disturb to stay on while paused")
+	}
+	hook.OnEnd(fs)
+	if fake.dnd {
+		t.Error("Expected do-not-disturb off after the session ended")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the record removed, got %v", err)
+	}
+
+	// Nothing recorded means FocusMode didn't turn it on, so it is left alone
+	fake.dnd = true
+	if cleared, err := disableDND(path); cleared || err != nil || !fake.dnd {
+		t.Errorf("disableDND() without a record = %v, %v (dnd %v)", cleared, err, fake.dnd)
+	}
+}
+
+// TestDNDHookKeepsPreviousState tests that a session leaves do-not-disturb on if it already was
+func TestDNDHookKeepsPreviousState(t *testing.T) {
+	fake := &fakePlatform{dnd: true}
+	useFakePlatform(t, fake)
+	path := filepath.Join(t.TempDir(), "dnd.on")
+	hook := &dndHook{path: path}
+	fs := &FocusSession{Mode: "focusmode"}
+
+	hook.OnStart(fs)
+	hook.OnEnd(fs)
+	if !fake.dnd {
+		t.Error("Expected do-not-disturb the user had on to stay on")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the record removed, got %v", err)
+	}
+
+	// A record from an older version holds only the time; it was off before it
+	os.WriteFile(path, []byte("2026-03-02T09:00:00Z\n"), 0600)
+	if cleared, err := disableDND(path); !cleared || err != nil || fake.dnd {
+		t.Errorf("disableDND() with an old record = %v, %v (dnd %v)", cleared, err, fake.dnd)
+	}
+}
+
+// TestDNDFailureKeepsRecord tests that a failure to turn it off leaves it to be retried after a crash
+func TestDNDFailureKeepsRecord(t *testing.T) {
+	fake := &fakePlatform{}
+	useFakePlatform(t, fake)
+	path := filepath.Join(t.TempDir(), "dnd.on")
+	if err := enableDND(path); err != nil {
+		t.Fatal(err)
+	}
+	fake.err = errors.New("gsettings not found")
+	if cleared, err := disableDND(path); !cleared || err == nil {
+		t.Errorf("disableDND() = %v, %v; want true and an error", cleared, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the record kept after a failure: %v", err)
+	}
+}
+
+// TestAttachDNDHook tests that only sessions of modes with dnd turn it on
+func TestAttachDNDHook(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	config := &Config{Modes: map[string]ModeConfig{"deep": {DND: true}, "light": {}}}
+
+	for _, tc := range []struct {
+		mode  string
+		brk   bool
+		wants bool
+	}{{"deep", false, true}, {"deep", true, false}, {"light", false, false}} {
+		session := &FocusSession{Mode: tc.mode, Break: tc.brk, Config: config}
+		if err := attachSessionHooks(session, config, sessionOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, hook := range session.Hooks {
+			if _, ok := hook.(*dndHook); ok {
+				found = true
+			}
+		}
+		if found != tc.wants {
+			t.Errorf("mode %s (break %v): dnd hook attached = %v, want %v", tc.mode, tc.brk, found, tc.wants)
+		}
+	}
+}
@@ -0,0 +1,769 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DNSBlockingConfig represents blocking the session mode's block_sites through DNS, for every
+// browser and app on the machine rather than only the one with the companion extension
+type DNSBlockingConfig struct {
+	Backend  string        `yaml:"backend" doc:"How sites are blocked through DNS during sessions: proxy runs a local DNS proxy in the daemon, nextdns and pihole edit that service's denylist" enum:"off,proxy,nextdns,pihole" default:"off"`
+	Listen   string        `yaml:"listen" doc:"Address the DNS proxy listens on, for UDP and TCP; point the system's DNS server at it" default:"127.0.0.1:53"`
+	Upstream string        `yaml:"upstream" doc:"DNS server the proxy forwards queries that aren't blocked to" default:"1.1.1.1:53"`
+	NextDNS  NextDNSConfig `yaml:"nextdns" doc:"NextDNS profile whose denylist holds the blocked sites during sessions"`
+	PiHole   PiHoleConfig  `yaml:"pihole" doc:"Pi-hole (v6 or later) whose deny list holds the blocked sites during sessions"`
+}
+
+// NextDNSConfig represents the NextDNS profile edited by the nextdns backend
+type NextDNSConfig struct {
+	Profile string `yaml:"profile" doc:"ID of the NextDNS profile, as in its setup page" example:"abc123"`
+	APIKey  string `yaml:"api_key" doc:"NextDNS API key, from the bottom of the account page"`
+}
+
+// PiHoleConfig represents the Pi-hole edited by the pihole backend
+type PiHoleConfig struct {
+	URL      string `yaml:"url" doc:"Address of the Pi-hole's web interface" example:"http://pi.hole"`
+	Password string `yaml:"password" doc:"Pi-hole web interface password or app password"`
+}
+
+// DNS blocking backends
+const (
+	dnsBackendOff     = "off"
+	dnsBackendProxy   = "proxy"
+	dnsBackendNextDNS = "nextdns"
+	dnsBackendPiHole  = "pihole"
+)
+
+const (
+	defaultDNSListen   = "127.0.0.1:53"
+	defaultDNSUpstream = "1.1.1.1:53"
+	dnsQueryTimeout    = 5 * time.Second
+	dnsStateCacheTime  = time.Second // How long the proxy reuses the blocked domains between queries
+)
+
+// nextDNSAPIURL is the NextDNS API, replaced in tests
+var nextDNSAPIURL = "https://api.nextdns.io"
+
+var dnsHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// backend returns the configured backend, "off" when none is
+func (c DNSBlockingConfig) backend() string {
+	if c.Backend == "" {
+		return dnsBackendOff
+	}
+	return c.Backend
+}
+
+// listen returns the proxy's listen address
+func (c DNSBlockingConfig) listen() string {
+	if c.Listen == "" {
+		return defaultDNSListen
+	}
+	return c.Listen
+}
+
+// upstream returns the server the proxy forwards to, with port 53 when none is given
+func (c DNSBlockingConfig) upstream() string {
+	if c.Upstream == "" {
+		return defaultDNSUpstream
+	}
+	if _, _, err := net.SplitHostPort(c.Upstream); err != nil {
+		return net.JoinHostPort(strings.Trim(c.Upstream, "[]"), "53")
+	}
+	return c.Upstream
+}
+
+// validate checks the backend and the settings it needs
+func (c DNSBlockingConfig) validate() error {
+	switch c.backend() {
+	case dnsBackendOff, dnsBackendProxy:
+		return nil
+	case dnsBackendNextDNS:
+		if c.NextDNS.Profile == "" || c.NextDNS.APIKey == "" {
+			return errors.New("dns_blocking.backend nextdns needs nextdns.profile and nextdns.api_key")
+		}
+		return nil
+	case dnsBackendPiHole:
+		if c.PiHole.URL == "" || c.PiHole.Password == "" {
+			return errors.New("dns_blocking.backend pihole needs pihole.url and pihole.password")
+		}
+		return nil
+	}
+	return fmt.Errorf("dns_blocking.backend '%s' is not one of off, proxy, nextdns or pihole", c.Backend)
+}
+
+// modeBlockedDomains returns the domains of a mode's block_sites; DNS can't see paths, so
+// entries limited to part of a site are left to the browser extension
+func modeBlockedDomains(config *Config, mode string) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, entry := range config.Modes[mode].BlockSites {
+		rule, err := parseSiteRule(entry)
+		if err != nil || rule.Path != "" || seen[rule.Host] {
+			continue
+		}
+		seen[rule.Host] = true
+		domains = append(domains, rule.Host)
+	}
+	return domains
+}
+
+// sessionBlockedDomains returns the domains blocked through DNS now: those of the running
+// session's mode, or none outside sessions and during breaks
+func sessionBlockedDomains(config *Config, session *activeSession) []string {
+	if session == nil || session.Break {
+		return nil
+	}
+	return modeBlockedDomains(config, session.Mode)
+}
+
+// domainBlocked reports whether a queried name is one of the domains or a subdomain of one
+func domainBlocked(name string, domains []string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, domain := range domains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsQuestionName returns the name asked about in a DNS query, or false for anything that
+// isn't a standard query with one question
+func dnsQuestionName(packet []byte) (string, bool) {
+	if len(packet) < 12 || packet[2]&0x80 != 0 || packet[2]&0x78 != 0 || binary.BigEndian.Uint16(packet[4:6]) != 1 {
+		return "", false
+	}
+	var labels []string
+	for i := 12; i < len(packet); {
+		size := int(packet[i])
+		if size == 0 {
+			return strings.Join(labels, "."), i+5 <= len(packet)
+		}
+		// Compression pointers and extended labels don't appear in questions
+		if size&0xc0 != 0 || i+1+size > len(packet) {
+			return "", false
+		}
+		labels = append(labels, string(packet[i+1:i+1+size]))
+		i += 1 + size
+	}
+	return "", false
+}
+
+// dnsBlockedReply answers a blocked query with NXDOMAIN, keeping its ID and question
+func dnsBlockedReply(query []byte) []byte {
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		end += 1 + int(query[end])
+	}
+	end += 5 // The root label, type and class
+	reply := append([]byte(nil), query[:end]...)
+	reply[2] = 0x80 | query[2]&0x01 // Response, keeping "recursion desired"
+	reply[3] = 0x80 | 3             // Recursion available, NXDOMAIN
+	binary.BigEndian.PutUint16(reply[6:8], 0)
+	binary.BigEndian.PutUint16(reply[8:10], 0)
+	binary.BigEndian.PutUint16(reply[10:12], 0)
+	return reply
+}
+
+// dnsProxy answers queries for blocked domains itself and forwards the others upstream
+type dnsProxy struct {
+	upstream string
+	blocked  func() []string // Domains blocked now
+
+	mu        sync.Mutex
+	cached    []string
+	cacheTime time.Time
+	now       func() time.Time
+}
+
+// profileCache holds a parsed profile, parsing the file again only when its mtime changes
+type profileCache struct {
+	path    string
+	modTime time.Time
+	config  *Config
+}
+
+// get returns the profile, or nil while it can't be read
+// A profile edited into one that doesn't parse keeps the last one that did, without warnings
+// printed or parsing retried until the file changes again
+func (c *profileCache) get() *Config {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil
+	}
+	if !info.ModTime().Equal(c.modTime) {
+		c.modTime = info.ModTime()
+		if config, err := readConfig(c.path); err == nil {
+			c.config = config
+		}
+	}
+	return c.config
+}
+
+// newDNSProxy returns a proxy blocking the running session's domains from the config file
+// The file is read again when it changes, so block_sites edits apply to the next session
+func newDNSProxy(configPath string, upstream string) *dnsProxy {
+	profile := &profileCache{path: configPath}
+	return &dnsProxy{
+		upstream: upstream,
+		now:      time.Now,
+		blocked: func() []string {
+			config := profile.get()
+			if config == nil {
+				return nil
+			}
+			path, err := activeSessionPath()
+			if err != nil {
+				return nil
+			}
+			session, _ := readActiveSession(path)
+			return sessionBlockedDomains(config, session)
+		},
+	}
+}
+
+// blockedDomains returns the blocked domains, reading them at most once a second
+func (p *dnsProxy) blockedDomains() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := p.now(); now.Sub(p.cacheTime) >= dnsStateCacheTime {
+		p.cached, p.cacheTime = p.blocked(), now
+	}
+	return p.cached
+}
+
+// answer returns the reply to a query, asking the upstream server over the given network
+func (p *dnsProxy) answer(query []byte, network string) ([]byte, error) {
+	if name, ok := dnsQuestionName(query); ok && domainBlocked(name, p.blockedDomains()) {
+		return dnsBlockedReply(query), nil
+	}
+	conn, err := net.DialTimeout(network, p.upstream, dnsQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsQueryTimeout))
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		reply := make([]byte, 65535)
+		n, err := conn.Read(reply)
+		if err != nil {
+			return nil, err
+		}
+		return reply[:n], nil
+	}
+	if err := writeDNSMessage(conn, query); err != nil {
+		return nil, err
+	}
+	return readDNSMessage(conn)
+}
+
+// readDNSMessage reads a DNS message sent over TCP, which has a 16-bit length before it
+func readDNSMessage(r io.Reader) ([]byte, error) {
+	var size uint16
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	message := make([]byte, size)
+	_, err := io.ReadFull(r, message)
+	return message, err
+}
+
+// writeDNSMessage writes a DNS message over TCP
+func writeDNSMessage(w io.Writer, message []byte) error {
+	framed := make([]byte, 2+len(message))
+	binary.BigEndian.PutUint16(framed, uint16(len(message)))
+	copy(framed[2:], message)
+	_, err := w.Write(framed)
+	return err
+}
+
+// serveUDP answers queries on conn until it is closed
+func (p *dnsProxy) serveUDP(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			reply, err := p.answer(query, "udp")
+			if err != nil {
+				return // The client asks again or gives up, as with a lost packet
+			}
+			conn.WriteTo(reply, addr)
+		}()
+	}
+}
+
+// serveTCP answers queries on connections from listener until it is closed
+func (p *dnsProxy) serveTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				conn.SetDeadline(time.Now().Add(2 * dnsQueryTimeout))
+				query, err := readDNSMessage(conn)
+				if err != nil {
+					return
+				}
+				reply, err := p.answer(query, "tcp")
+				if err != nil || writeDNSMessage(conn, reply) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// runDNSProxy serves the proxy on addr until ctx ends
+func runDNSProxy(ctx context.Context, proxy *dnsProxy, addr string, beat func()) error {
+	udp, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w (ports below 1024 need root or an administrator)", addr, err)
+	}
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+	go proxy.serveUDP(udp)
+	go proxy.serveTCP(tcp)
+	fmt.Printf("DNS proxy listening on %s, forwarding to %s\n", addr, proxy.upstream)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		beat()
+		select {
+		case <-ctx.Done():
+			udp.Close()
+			tcp.Close()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dnsDenylist is a DNS service's list of blocked domains
+type dnsDenylist interface {
+	name() string
+	list() ([]string, error)
+	add(domains []string) error
+	remove(domains []string) error
+}
+
+// newDNSDenylist returns the denylist of the configured service, or nil for the proxy and off
+// Its requests are retried under the network policy retry
+func newDNSDenylist(config DNSBlockingConfig, retry RetryPolicy) dnsDenylist {
+	switch config.backend() {
+	case dnsBackendNextDNS:
+		return &nextDNSDenylist{config: config.NextDNS, baseURL: nextDNSAPIURL, retry: retry}
+	case dnsBackendPiHole:
+		return &piHoleDenylist{config: config.PiHole, retry: retry}
+	}
+	return nil
+}
+
+// dnsAPICall sends a request to a DNS service under policy and decodes the JSON reply into result, if given
+func dnsAPICall(policy RetryPolicy, service string, req *http.Request, result any) error {
+	resp, err := doWithRetry(policy, dnsHTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("error contacting %s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", service, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", service, err)
+	}
+	return nil
+}
+
+// nextDNSDenylist is the denylist of a NextDNS profile
+type nextDNSDenylist struct {
+	config  NextDNSConfig
+	baseURL string
+	retry   RetryPolicy
+}
+
+func (n *nextDNSDenylist) name() string { return "NextDNS" }
+
+// request returns a request to the profile's denylist, or to one of its domains
+func (n *nextDNSDenylist) request(method, domain string, body []byte) (*http.Request, error) {
+	endpoint := n.baseURL + "/profiles/" + url.PathEscape(n.config.Profile) + "/denylist"
+	if domain != "" {
+		endpoint += "/" + url.PathEscape(domain)
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if body == nil {
+		req.Body, req.GetBody, req.ContentLength = nil, nil, 0
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Api-Key", n.config.APIKey)
+	return req, nil
+}
+
+func (n *nextDNSDenylist) list() ([]string, error) {
+	req, err := n.request(http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := dnsAPICall(n.retry, n.name(), req, &result); err != nil {
+		return nil, err
+	}
+	var domains []string
+	for _, entry := range result.Data {
+		domains = append(domains, entry.ID)
+	}
+	return domains, nil
+}
+
+func (n *nextDNSDenylist) add(domains []string) error {
+	for _, domain := range domains {
+		body, _ := json.Marshal(map[string]any{"id": domain, "active": true})
+		req, err := n.request(http.MethodPost, "", body)
+		if err != nil {
+			return err
+		}
+		if err := dnsAPICall(n.retry, n.name(), req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *nextDNSDenylist) remove(domains []string) error {
+	for _, domain := range domains {
+		req, err := n.request(http.MethodDelete, domain, nil)
+		if err != nil {
+			return err
+		}
+		if err := dnsAPICall(n.retry, n.name(), req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// piHoleDenylist is the exact-domain deny list of a Pi-hole, through its v6 API
+type piHoleDenylist struct {
+	config PiHoleConfig
+	sid    string // Session from logging in, reused for the calls that follow
+	retry  RetryPolicy
+}
+
+func (p *piHoleDenylist) name() string { return "Pi-hole" }
+
+// request returns a request to the Pi-hole API, logging in first if needed
+func (p *piHoleDenylist) request(method, path string, body []byte) (*http.Request, error) {
+	if p.sid == "" && path != "/api/auth" {
+		if err := p.login(); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(p.config.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if body == nil {
+		req.Body, req.GetBody, req.ContentLength = nil, nil, 0
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.sid != "" {
+		req.Header.Set("X-FTL-SID", p.sid)
+	}
+	return req, nil
+}
+
+// login starts an API session with the password
+func (p *piHoleDenylist) login() error {
+	body, _ := json.Marshal(map[string]string{"password": p.config.Password})
+	req, err := p.request(http.MethodPost, "/api/auth", body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Session struct {
+			Valid bool   `json:"valid"`
+			SID   string `json:"sid"`
+		} `json:"session"`
+	}
+	if err := dnsAPICall(p.retry, p.name(), req, &result); err != nil {
+		return err
+	}
+	if !result.Session.Valid || result.Session.SID == "" {
+		return errors.New("Pi-hole refused the password")
+	}
+	p.sid = result.Session.SID
+	return nil
+}
+
+func (p *piHoleDenylist) list() ([]string, error) {
+	req, err := p.request(http.MethodGet, "/api/domains/deny/exact", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Domains []struct {
+			Domain string `json:"domain"`
+		} `json:"domains"`
+	}
+	if err := dnsAPICall(p.retry, p.name(), req, &result); err != nil {
+		return nil, err
+	}
+	var domains []string
+	for _, entry := range result.Domains {
+		domains = append(domains, entry.Domain)
+	}
+	return domains, nil
+}
+
+func (p *piHoleDenylist) add(domains []string) error {
+	if len(domains) == 0 {
+		return nil
+	}
+	body, _ := json.Marshal(map[string]any{"domain": domains, "comment": "Blocked by FocusMode during a session", "enabled": true})
+	req, err := p.request(http.MethodPost, "/api/domains/deny/exact", body)
+	if err != nil {
+		return err
+	}
+	return dnsAPICall(p.retry, p.name(), req, nil)
+}
+
+func (p *piHoleDenylist) remove(domains []string) error {
+	for _, domain := range domains {
+		req, err := p.request(http.MethodDelete, "/api/domains/deny/exact/"+url.PathEscape(domain), nil)
+		if err != nil {
+			return err
+		}
+		if err := dnsAPICall(p.retry, p.name(), req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dnsBlockedPath returns the file listing the domains FocusMode added to a denylist, so they
+// are taken off again after a crash and domains you listed yourself never are
+func dnsBlockedPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dns-blocked.json"), nil
+}
+
+// readDNSBlocked returns the domains recorded at path
+func readDNSBlocked(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var domains []string
+	if err := json.Unmarshal(data, &domains); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return domains, nil
+}
+
+// blockDomains adds the domains missing from the denylist and records them at path, with any
+// recorded by an earlier session that didn't get to take them off
+func blockDomains(list dnsDenylist, path string, domains []string) error {
+	recorded, err := readDNSBlocked(path)
+	if err != nil {
+		return err
+	}
+	existing, err := list.list()
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool)
+	for _, domain := range existing {
+		listed[strings.ToLower(domain)] = true
+	}
+	for _, domain := range recorded {
+		listed[domain] = false // Ours to take off, even though it is on the list
+	}
+	var missing []string
+	for _, domain := range domains {
+		if _, present := listed[domain]; !present {
+			missing = append(missing, domain)
+		}
+	}
+	ours := append(recorded, missing...)
+	sort.Strings(ours)
+	data, err := json.Marshal(ours)
+	if err != nil {
+		return err
+	}
+	// Recorded before adding, so a crash partway still leaves them to be taken off
+	if err := writeFile(path, data, 0600); err != nil {
+		return err
+	}
+	return list.add(missing)
+}
+
+// unblockDomains takes the recorded domains off the denylist and forgets them
+func unblockDomains(list dnsDenylist, path string) (int, error) {
+	recorded, err := readDNSBlocked(path)
+	if err != nil || len(recorded) == 0 {
+		return 0, err
+	}
+	if err := list.remove(recorded); err != nil {
+		return 0, err
+	}
+	if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return len(recorded), nil
+}
+
+// dnsBlockHook blocks the session mode's domains on a DNS service for the length of the session
+// Pausing keeps them blocked, since a pause is a short break from the countdown, not from focus
+type dnsBlockHook struct {
+	list    dnsDenylist
+	path    string
+	domains []string
+}
+
+// OnStart adds the domains to the denylist
+func (h *dnsBlockHook) OnStart(fs *FocusSession) {
+	if err := blockDomains(h.list, h.path, h.domains); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not block sites on %s: %v\n", h.list.name(), err)
+	}
+}
+
+// OnPause does nothing
+func (h *dnsBlockHook) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (h *dnsBlockHook) OnResume(fs *FocusSession) {}
+
+// OnEnd takes the domains FocusMode added off the denylist again
+func (h *dnsBlockHook) OnEnd(fs *FocusSession) {
+	if _, err := unblockDomains(h.list, h.path); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not unblock sites on %s: %v (run focusmode dns clear)\n", h.list.name(), err)
+	}
+}
+
+// DryRun reports the sites that would be blocked
+func (h *dnsBlockHook) DryRun(fs *FocusSession) []string {
+	return []string{fmt.Sprintf("block %s on %s until the session ends", strings.Join(h.domains, ", "), h.list.name())}
+}
+
+// runDNSCommand handles the "dns" subcommand
+func runDNSCommand(args []string) {
+	if len(args) == 0 || (args[0] != "proxy" && args[0] != "clear" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode dns proxy [-config profile.yml] [-listen 127.0.0.1:53]")
+		fmt.Fprintln(os.Stderr, "       focusmode dns status [-config profile.yml]")
+		fmt.Fprintln(os.Stderr, "       focusmode dns clear [-config profile.yml]")
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("dns "+args[0], flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	listen := flags.String("listen", "", "Address the proxy listens on (default dns_blocking.listen)")
+	flags.Parse(args[1:])
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := config.DNSBlocking.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "proxy":
+		addr := *listen
+		if addr == "" {
+			addr = config.DNSBlocking.listen()
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := runDNSProxy(ctx, newDNSProxy(*configPath, config.DNSBlocking.upstream()), addr, func() {}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "status":
+		fmt.Printf("Backend: %s\n", config.DNSBlocking.backend())
+		var session *activeSession
+		if path, err := activeSessionPath(); err == nil {
+			session, _ = readActiveSession(path)
+		}
+		if domains := sessionBlockedDomains(config, session); len(domains) > 0 {
+			fmt.Printf("Blocked now: %s\n", strings.Join(domains, ", "))
+		} else {
+			fmt.Println("Blocked now: nothing (no session is running, or its mode has no block_sites)")
+		}
+		if path, err := dnsBlockedPath(); err == nil {
+			if recorded, _ := readDNSBlocked(path); len(recorded) > 0 {
+				fmt.Printf("On the denylist for FocusMode: %s\n", strings.Join(recorded, ", "))
+			}
+		}
+	case "clear":
+		list := newDNSDenylist(config.DNSBlocking, config.Retry.Network)
+		if list == nil {
+			fmt.Println("The proxy stops blocking on its own when a session ends; nothing to clear.")
+			return
+		}
+		path, err := dnsBlockedPath()
+		if err == nil {
+			var n int
+			n, err = unblockDomains(list, path)
+			if err == nil {
+				fmt.Printf("Took %d domain(s) off the %s denylist\n", n, list.name())
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
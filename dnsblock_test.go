@@ -0,0 +1,251 @@
+package focusmode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dnsQuery returns a standard query for name with the given ID
+func dnsQuery(id uint16, name string) []byte {
+	query := make([]byte, 12)
+	binary.BigEndian.PutUint16(query, id)
+	query[2] = 0x01 // Recursion desired
+	binary.BigEndian.PutUint16(query[4:6], 1)
+	for _, label := range strings.Split(name, ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	return append(query, 0, 0, 1, 0, 1) // Type A, class IN
+}
+
+// TestDNSBlockedDomains tests which domains are blocked, and that breaks and paths aren't
+func TestDNSBlockedDomains(t *testing.T) {
+	config := &Config{Modes: map[string]ModeConfig{
+		"focusmode": {BlockSites: []string{"Reddit.com", "https://www.youtube.com/shorts/", "reddit.com", "news.ycombinator.com"}},
+	}}
+	want := []string{"reddit.com", "news.ycombinator.com"}
+	if got := sessionBlockedDomains(config, &activeSession{Mode: "focusmode"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := sessionBlockedDomains(config, &activeSession{Mode: "focusmode", Break: true}); got != nil {
+		t.Errorf("Expected nothing blocked during a break, got %v", got)
+	}
+	if got := sessionBlockedDomains(config, nil); got != nil {
+		t.Errorf("Expected nothing blocked outside sessions, got %v", got)
+	}
+
+	for name, blocked := range map[string]bool{
+		"reddit.com": true, "old.Reddit.com.": true, "notreddit.com": false, "ycombinator.com": false,
+	} {
+		if got := domainBlocked(name, want); got != blocked {
+			t.Errorf("domainBlocked(%q) = %v, want %v", name, got, blocked)
+		}
+	}
+}
+
+// TestDNSProxy tests that the proxy answers NXDOMAIN for blocked domains and forwards the others
+func TestDNSProxy(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on UDP: %v", err)
+	}
+	defer upstream.Close()
+	var mu sync.Mutex
+	var forwarded []string
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			name, _ := dnsQuestionName(buf[:n])
+			mu.Lock()
+			forwarded = append(forwarded, name)
+			mu.Unlock()
+			reply := append([]byte(nil), buf[:n]...)
+			reply[2] |= 0x80
+			upstream.WriteTo(reply, addr)
+		}
+	}()
+
+	proxy := &dnsProxy{upstream: upstream.LocalAddr().String(), now: time.Now, blocked: func() []string { return []string{"reddit.com"} }}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go proxy.serveUDP(conn)
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, tt := range []struct {
+		name  string
+		rcode byte
+	}{{"old.reddit.com", 3}, {"golang.org", 0}} {
+		client.Write(dnsQuery(0x1234, tt.name))
+		client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reply := make([]byte, 512)
+		n, err := client.Read(reply)
+		if err != nil {
+			t.Fatalf("No reply for %s: %v", tt.name, err)
+		}
+		if binary.BigEndian.Uint16(reply) != 0x1234 || reply[2]&0x80 == 0 || reply[3]&0x0f != tt.rcode {
+			t.Errorf("Unexpected reply for %s: % x", tt.name, reply[:n])
+		}
+		if name, _ := dnsQuestionName(append([]byte{0, 0, 0}, reply[3:n]...)); name != tt.name {
+			t.Errorf("Expected the question for %s kept in the reply, got %q", tt.name, name)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(forwarded, []string{"golang.org"}) {
+		t.Errorf("Expected only golang.org forwarded, got %v", forwarded)
+	}
+}
+
+// TestProfileCache tests that the proxy's profile is parsed again only when the file changes
+func TestProfileCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yml")
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	pins := func(config *Config) string {
+		if config == nil {
+			return "<nil>"
+		}
+		return strings.Join(config.Pinned, ",")
+	}
+
+	cache := &profileCache{path: path}
+	write("pinned: [a]\n", written)
+	if got := pins(cache.get()); got != "a" {
+		t.Fatalf("get() = %s, want a", got)
+	}
+	// Same mtime: the file isn't parsed again
+	write("pinned: [b]\n", written)
+	if got := pins(cache.get()); got != "a" {
+		t.Errorf("Expected the cached profile while the mtime is unchanged, got %s", got)
+	}
+	write("pinned: [b]\n", written.Add(time.Minute))
+	if got := pins(cache.get()); got != "b" {
+		t.Errorf("Expected the edited profile, got %s", got)
+	}
+	// An edit that doesn't parse keeps the last good profile
+	write("pinned: [\n", written.Add(2*time.Minute))
+	if got := pins(cache.get()); got != "b" {
+		t.Errorf("Expected the last good profile, got %s", got)
+	}
+	os.Remove(path)
+	if got := pins(cache.get()); got != "<nil>" {
+		t.Errorf("Expected no profile once the file is gone, got %s", got)
+	}
+}
+
+// TestNextDNSBlockDomains tests that a session adds only missing domains and takes off only those it added
+func TestNextDNSBlockDomains(t *testing.T) {
+	var mu sync.Mutex
+	denylist := map[string]bool{"youtube.com": true} // Listed by the user beforehand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/profiles/abc123/denylist":
+			var data []map[string]any
+			for domain := range denylist {
+				data = append(data, map[string]any{"id": domain, "active": true})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": data})
+		case r.Method == http.MethodPost && r.URL.Path == "/profiles/abc123/denylist":
+			var entry struct{ ID string }
+			json.NewDecoder(r.Body).Decode(&entry)
+			denylist[entry.ID] = true
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/profiles/abc123/denylist/"):
+			delete(denylist, strings.TrimPrefix(r.URL.Path, "/profiles/abc123/denylist/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	listed := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		var domains []string
+		for domain := range denylist {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		return domains
+	}
+
+	list := &nextDNSDenylist{config: NextDNSConfig{Profile: "abc123", APIKey: "key"}, baseURL: server.URL}
+	path := filepath.Join(t.TempDir(), "dns-blocked.json")
+	if err := blockDomains(list, path, []string{"reddit.com", "youtube.com"}); err != nil {
+		t.Fatalf("blockDomains() returned error: %v", err)
+	}
+	if got := listed(); !reflect.DeepEqual(got, []string{"reddit.com", "youtube.com"}) {
+		t.Errorf("Expected both domains on the denylist, got %v", got)
+	}
+	if recorded, _ := readDNSBlocked(path); !reflect.DeepEqual(recorded, []string{"reddit.com"}) {
+		t.Errorf("Expected only reddit.com recorded, got %v", recorded)
+	}
+
+	n, err := unblockDomains(list, path)
+	if err != nil || n != 1 {
+		t.Fatalf("unblockDomains() = %d, %v", n, err)
+	}
+	if got := listed(); !reflect.DeepEqual(got, []string{"youtube.com"}) {
+		t.Errorf("Expected the user's youtube.com kept, got %v", got)
+	}
+	if recorded, _ := readDNSBlocked(path); recorded != nil {
+		t.Errorf("Expected the record removed, got %v", recorded)
+	}
+
+	list.config.APIKey = "wrong"
+	if err := blockDomains(list, path, []string{"reddit.com"}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a 403 error, got %v", err)
+	}
+}
+
+// TestDNSBlockingLint tests the warnings about backends missing their settings
+func TestDNSBlockingLint(t *testing.T) {
+	for _, tt := range []struct {
+		config DNSBlockingConfig
+		want   string
+	}{
+		{DNSBlockingConfig{}, ""},
+		{DNSBlockingConfig{Backend: "proxy"}, ""},
+		{DNSBlockingConfig{Backend: "nextdns", NextDNS: NextDNSConfig{Profile: "abc123"}}, "nextdns.api_key"},
+		{DNSBlockingConfig{Backend: "pihole"}, "pihole.url"},
+		{DNSBlockingConfig{Backend: "adguard"}, "not one of"},
+	} {
+		warnings := lintConfig(&Config{DNSBlocking: tt.config})
+		if tt.want == "" && len(warnings) != 0 || tt.want != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.want)) {
+			t.Errorf("lintConfig(%+v) = %v, want a warning containing %q", tt.config, warnings, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dryRunReporter is implemented by session hooks so a dry run can report their side effects
+// Every hook added by attachSessionHooks must implement it; hooks without side effects return nil
+type dryRunReporter interface {
+	DryRun(fs *FocusSession) []string
+}
+
+// sessionMoves returns the destination folder and the shortcuts a session would move
+func sessionMoves(fs *FocusSession) (string, []string, error) {
+	modeConfig, err := fs.Config.getModeConfig(fs.Mode)
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting mode configuration: %w", err)
+	}
+	homeDir, err := hiddenFoldersRoot()
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	destination := modeFolder(homeDir, modeConfig.Destination)
+	if !modeConfig.MoveAll {
+		return destination, modeConfig.Shortcuts, nil
+	}
+	shortcuts, err := fs.Config.desktopShortcutsToMove(modeConfig)
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting desktop shortcuts: %w", err)
+	}
+	return destination, shortcuts, nil
+}
+
+// printSessionDryRun reports everything a session would do without doing any of it
+func printSessionDryRun(w io.Writer, fs *FocusSession) error {
+	fmt.Fprintf(w, "[DRY RUN] Would start a %s session in mode: %s\n", formatDuration(fs.Duration), fs.Mode)
+
+	var shortcuts []string
+	if !fs.Break {
+		destination, planned, err := sessionMoves(fs)
+		if err != nil {
+			return err
+		}
+		shortcuts = fs.Config.restorable(planned)
+		for _, shortcut := range planned {
+			fmt.Fprintf(w, "[DRY RUN] Would move: %s -> %s\n", shortcut, destination)
+		}
+	}
+
+	for _, hook := range fs.Hooks {
+		reporter, ok := hook.(dryRunReporter)
+		if !ok {
+			// Never claim a hook is harmless without it saying so
+			fmt.Fprintf(w, "[DRY RUN] Would run %T (effects unknown)\n", hook)
+			continue
+		}
+		for _, line := range reporter.DryRun(fs) {
+			fmt.Fprintf(w, "[DRY RUN] Would %s\n", line)
+		}
+	}
+
+	if fs.AutoRestore && len(shortcuts) > 0 {
+		what := fmt.Sprintf("%d shortcut(s)", len(shortcuts))
+		if len(fs.RestoreCategories) > 0 {
+			what += " in " + strings.Join(fs.RestoreCategories, ", ")
+		}
+		fmt.Fprintf(w, "[DRY RUN] Would restore %s to the desktop when the session ends\n", what)
+		categories := make([]string, 0, len(fs.RestoreAt))
+		for category := range fs.RestoreAt {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			until := at(time.Time{}, fs.RestoreAt[category]).Format("15:04")
+			fmt.Fprintf(w, "[DRY RUN] Would keep %s shortcuts hidden until %s, when the daemon restores them\n", category, until)
+		}
+	}
+	return nil
+}
+
+// printRoutineDryRun reports everything each step of a routine would do
+func printRoutineDryRun(w io.Writer, config *Config, name string, steps []RoutineStep, opts sessionOptions) error {
+	for i, step := range steps {
+		fmt.Fprintf(w, "\n%sStep %d/%d: %s\n", glyph("▶ "), i+1, len(steps), step.describe())
+		session, err := newRoutineSession(config, name, i, step, opts)
+		if err != nil {
+			return err
+		}
+		if err := printSessionDryRun(w, session); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	if path, err := historyPath(); err == nil {
+		fmt.Fprintf(w, "\n[DRY RUN] Would record the routine in %s\n", path)
+	}
+	return nil
+}
+
+// dryRunFooter is printed after a dry-run report
+const dryRunFooter = "(Dry run - nothing was moved, played, sent or recorded)"
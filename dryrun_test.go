@@ -0,0 +1,112 @@
+package focusmode
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allIntegrationsConfig returns a config with every session integration enabled
+func allIntegrationsConfig(dir string) *Config {
+	return &Config{
+		Modes: map[string]ModeConfig{
+			"focusmode": {Destination: "Games", Shortcuts: []string{"Steam.lnk", "Discord.lnk"}, ColorTemperature: 4000},
+		},
+		DefaultMode: "focusmode",
+		Ambient:     AmbientConfig{Enabled: true, Sound: "brown"},
+		TTS:         TTSConfig{Enabled: true, AnnounceAt: []int{10, 1}},
+		Milestones:  []string{"50%", "5m"},
+		Calendar:    CalendarConfig{Enabled: true, Provider: "google"},
+		WakaTime:    WakaTimeConfig{Enabled: true},
+		Policy: &Policy{
+			path:     filepath.Join(dir, "policy.yml"),
+			Guardian: GuardianPolicy{Report: true},
+		},
+	}
+}
+
+// TestSessionHooksReportDryRun tests that every session hook can describe its side effects
+// A new integration must implement dryRunReporter so previews stay trustworthy
+func TestSessionHooksReportDryRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	config := allIntegrationsConfig(dir)
+	session, err := startFocusSession(config, "focusmode", 25, true)
+	if err != nil {
+		t.Fatalf("startFocusSession() returned error: %v", err)
+	}
+	if err := attachSessionHooks(session, config, sessionOptions{heartbeat: func() {}}); err != nil {
+		t.Fatalf("attachSessionHooks() returned error: %v", err)
+	}
+
+	for _, hook := range session.Hooks {
+		if _, ok := hook.(dryRunReporter); !ok {
+			t.Errorf("%T does not implement dryRunReporter", hook)
+		}
+	}
+}
+
+// TestPrintSessionDryRun tests the report of a session with all integrations enabled
+func TestPrintSessionDryRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv("USERPROFILE", dir)
+
+	config := allIntegrationsConfig(dir)
+	session, _ := startFocusSession(config, "focusmode", 25, true)
+	if err := attachSessionHooks(session, config, sessionOptions{}); err != nil {
+		t.Fatalf("attachSessionHooks() returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := printSessionDryRun(&out, session); err != nil {
+		t.Fatalf("printSessionDryRun() returned error: %v", err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"[DRY RUN] Would start a 25m session in mode: focusmode",
+		"[DRY RUN] Would move: Steam.lnk -> " + filepath.Join(dir, "Games"),
+		"[DRY RUN] Would set screen color temperature to 4000K",
+		"[DRY RUN] Would play ambient sound brown",
+		"[DRY RUN] Would announce the session start and end aloud, and 10 minutes remaining, 1 minute remaining",
+		"[DRY RUN] Would notify at milestone 50%",
+		"[DRY RUN] Would add a 25m event to Google Calendar if the session completes",
+		"[DRY RUN] Would fetch coding activity from WakaTime",
+		"[DRY RUN] Would record the session in " + filepath.Join(dir, "guardian.jsonl"),
+		"[DRY RUN] Would restore 2 shortcut(s) to the desktop when the session ends",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected %q in report:\n%s", want, report)
+		}
+	}
+
+	// Nothing may have been written
+	if state, err := readSessionFile(filepath.Join(dir, "focusmode", "session.json")); err != nil || state != nil {
+		t.Errorf("Expected no session state to be written by a dry run, got %v, %v", state, err)
+	}
+}
+
+// TestPrintSessionDryRunUnknownHook tests that hooks without a report are not passed off as harmless
+func TestPrintSessionDryRunUnknownHook(t *testing.T) {
+	session := &FocusSession{
+		Duration: 5 * time.Minute,
+		Mode:     "focusmode",
+		Config:   &Config{},
+		Break:    true,
+		Hooks:    []SessionHook{&recordingHook{}},
+	}
+	var out bytes.Buffer
+	if err := printSessionDryRun(&out, session); err != nil {
+		t.Fatalf("printSessionDryRun() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would run *focusmode.recordingHook (effects unknown)") {
+		t.Errorf("Expected unknown hook to be flagged, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,117 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// durationUnits maps the unit names accepted in durations to their length
+var durationUnits = map[string]time.Duration{
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+}
+
+// parseDuration parses a human-friendly duration such as "1h30m", "90m", "1.5h", "1,5 hours"
+// or "1 hour 30 minutes"; a bare number such as "45" counts in minutes
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	if s == "" {
+		return 0, fmt.Errorf("empty duration: use minutes (45) or a duration such as 1h30m or 1.5h")
+	}
+	if strings.Trim(s, "0123456789.,") == "" {
+		minutes, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %q is not a number", value, s)
+		}
+		return durationOf(value, minutes, time.Minute)
+	}
+
+	var total time.Duration
+	for s != "" {
+		s = strings.TrimLeft(s, " ")
+		number := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != ',' })
+		if number < 0 {
+			return 0, fmt.Errorf("invalid duration %q: %q has no unit (use h, m or s)", value, s)
+		}
+		if number == 0 {
+			return 0, fmt.Errorf("invalid duration %q: use minutes (45) or a duration such as 1h30m or 1.5h", value)
+		}
+		amount, err := strconv.ParseFloat(strings.Replace(s[:number], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %q is not a number", value, s[:number])
+		}
+		s = strings.TrimLeft(s[number:], " ")
+		end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if end < 0 {
+			end = len(s)
+		}
+		unit, ok := durationUnits[s[:end]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q (use h, m or s)", value, s[:end])
+		}
+		part, err := durationOf(value, amount, unit)
+		if err != nil {
+			return 0, err
+		}
+		total += part
+		s = s[end:]
+	}
+	return total, nil
+}
+
+// durationOf returns amount units as a duration, rejecting amounts too large to represent
+func durationOf(value string, amount float64, unit time.Duration) (time.Duration, error) {
+	d := amount * float64(unit)
+	if d > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q: out of range", value)
+	}
+	return time.Duration(d), nil
+}
+
+// parseMinutes parses a duration with parseDuration and returns it in whole minutes,
+// which is how sessions and routines count their length
+func parseMinutes(value string) (int, error) {
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be longer than zero", value)
+	}
+	if d%time.Minute != 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be whole minutes", value)
+	}
+	return int(d / time.Minute), nil
+}
+
+// minutesFlag is a command-line flag holding a length in minutes, set from anything parseMinutes accepts
+type minutesFlag int
+
+func (m *minutesFlag) String() string {
+	if m == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*m))
+}
+
+func (m *minutesFlag) Set(value string) error {
+	minutes, err := parseMinutes(value)
+	if err != nil {
+		return err
+	}
+	*m = minutesFlag(minutes)
+	return nil
+}
+
+// minutesVar defines a minutes flag on flags, returning where its value is stored
+func minutesVar(flags *flag.FlagSet, name string, value int, usage string) *int {
+	m := minutesFlag(value)
+	flags.Var(&m, name, usage)
+	return (*int)(&m)
+}
@@ -0,0 +1,76 @@
+package focusmode
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseDuration tests the duration formats accepted by flags, commands and config
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"45":                45 * time.Minute,
+		"90m":               90 * time.Minute,
+		"1h30m":             90 * time.Minute,
+		"1.5h":              90 * time.Minute,
+		"1,5h":              90 * time.Minute,
+		"1H 30M":            90 * time.Minute,
+		"1 hour 30 minutes": 90 * time.Minute,
+		"2 hrs":             2 * time.Hour,
+		"90s":               90 * time.Second,
+		" 25 min ":          25 * time.Minute,
+		"0.5":               30 * time.Second,
+	}
+	for value, want := range tests {
+		if got, err := parseDuration(value); err != nil || got != want {
+			t.Errorf("parseDuration(%q) = %s, %v; want %s", value, got, err, want)
+		}
+	}
+
+	errors := map[string]string{
+		"":              "empty duration",
+		"soon":          "use minutes (45)",
+		"1h30":          `"30" has no unit`,
+		"5 days":        `unknown unit "days"`,
+		"-5m":           "use minutes (45)",
+		"-5":            "use minutes (45)",
+		"1e400":         `unknown unit "e"`,
+		"9999999999999": "out of range",
+		"1..5h":         "is not a number",
+		"NaN":           "use minutes (45)",
+	}
+	for value, want := range errors {
+		if _, err := parseDuration(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseDuration(%q) error = %v, want one containing %q", value, err, want)
+		}
+	}
+}
+
+// TestParseMinutes tests that session lengths must be whole, positive minutes
+func TestParseMinutes(t *testing.T) {
+	if got, err := parseMinutes("1.5h"); err != nil || got != 90 {
+		t.Errorf("parseMinutes(1.5h) = %d, %v; want 90", got, err)
+	}
+	for value, want := range map[string]string{"0": "longer than zero", "90s": "whole minutes", "x": "invalid duration"} {
+		if _, err := parseMinutes(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseMinutes(%q) error = %v, want one containing %q", value, err, want)
+		}
+	}
+}
+
+// TestMinutesFlag tests that minute flags keep their default and accept durations
+func TestMinutesFlag(t *testing.T) {
+	flags := flag.NewFlagSet("session start", flag.ContinueOnError)
+	flags.SetOutput(&strings.Builder{})
+	duration := minutesVar(flags, "duration", 25, "Session duration")
+	if *duration != 25 {
+		t.Errorf("Expected the default of 25, got %d", *duration)
+	}
+	if err := flags.Parse([]string{"-duration", "1h15m"}); err != nil || *duration != 75 {
+		t.Errorf("Expected 75 minutes, got %d (err %v)", *duration, err)
+	}
+	if err := flags.Parse([]string{"-duration", "soon"}); err == nil || !strings.Contains(err.Error(), "invalid duration") {
+		t.Errorf("Expected a clear error, got %v", err)
+	}
+}
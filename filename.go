@@ -0,0 +1,215 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Filenames are written in different Unicode normalization forms depending on where they were made:
+// macOS has long stored decomposed names (NFD, "e" followed by a combining accent) while Windows
+// and most Linux tools use composed ones (NFC, a single "é"). A desktop synced between the two can
+// hand back a recorded name in the other form, so manifests keep both forms and restores match
+// names in either.
+
+// Hangul syllables are composed and decomposed by arithmetic rather than from the tables
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// combiningClassRange is a range of characters sharing a canonical combining class
+type combiningClassRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+// combiningClass returns the canonical combining class of r, 0 for starters
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool { return combiningClasses[i].hi >= r })
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// nfd returns s in Unicode normalization form D (canonical decomposition)
+// Invalid UTF-8 has no normal form and is returned unchanged
+func nfd(s string) string {
+	if !utf8.ValidString(s) {
+		return s
+	}
+	var runes []rune
+	for _, r := range s {
+		switch {
+		case r >= hangulSBase && r < hangulSBase+hangulSCount:
+			index := r - hangulSBase
+			runes = append(runes, hangulLBase+index/hangulNCount, hangulVBase+index%hangulNCount/hangulTCount)
+			if t := index % hangulTCount; t != 0 {
+				runes = append(runes, hangulTBase+t)
+			}
+		case canonicalDecompositions[r] != "":
+			runes = append(runes, []rune(canonicalDecompositions[r])...)
+		default:
+			runes = append(runes, r)
+		}
+	}
+
+	// Put runs of combining marks in canonical order; the sort is stable for marks of the same class
+	for start := 0; start < len(runes); start++ {
+		if combiningClass(runes[start]) == 0 {
+			continue
+		}
+		end := start
+		for end < len(runes) && combiningClass(runes[end]) != 0 {
+			end++
+		}
+		run := runes[start:end]
+		sort.SliceStable(run, func(i, j int) bool { return combiningClass(run[i]) < combiningClass(run[j]) })
+		start = end
+	}
+	return string(runes)
+}
+
+// composePair returns the primary composite of a starter and the character following it
+func composePair(a, b rune) (rune, bool) {
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+(b-hangulVBase))*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 &&
+		b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + (b - hangulTBase), true
+	}
+	c, ok := canonicalCompositions[[2]rune{a, b}]
+	return c, ok
+}
+
+// nfc returns s in Unicode normalization form C (canonical decomposition, then composition)
+// Invalid UTF-8 has no normal form and is returned unchanged
+func nfc(s string) string {
+	if !utf8.ValidString(s) {
+		return s
+	}
+	var out []rune
+	starter := -1  // index in out of the last starter, which later characters may combine with
+	lastClass := 0 // combining class of the last character kept after the starter
+	for _, r := range nfd(s) {
+		class := int(combiningClass(r))
+		// A character is blocked from the starter by anything between them of the same or a higher class
+		if starter >= 0 && (starter == len(out)-1 || lastClass != 0 && lastClass < class) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// sameFileName reports whether two filenames are the same apart from their normalization form
+func sameFileName(a, b string) bool {
+	return a == b || nfc(a) == nfc(b)
+}
+
+// findFileName returns the name under which name is stored in dir, which may be in
+// a different normalization form than the one recorded; ok is false if it isn't there
+func findFileName(dir, name string) (string, bool) {
+	if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+		return name, true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if sameFileName(entry.Name(), name) {
+			return entry.Name(), true
+		}
+	}
+	return "", false
+}
+
+// storedName is a filename as written to a manifest
+// Name alone can't be trusted to round-trip: JSON replaces bytes that aren't valid UTF-8,
+// and a synced desktop may hand the name back in the other normalization form
+type storedName struct {
+	Name string `json:"name"` // Name as readable text
+	Raw  []byte `json:"raw"`  // Exact bytes of the name as it was on disk
+	NFC  string `json:"nfc"`  // Composed form, as Windows and Linux usually write it
+	NFD  string `json:"nfd"`  // Decomposed form, as macOS writes it
+}
+
+// newStoredName records a filename with its raw bytes and both normalization forms
+func newStoredName(name string) storedName {
+	return storedName{Name: strings.ToValidUTF8(name, "�"), Raw: []byte(name), NFC: nfc(name), NFD: nfd(name)}
+}
+
+// decodeStoredName reads a filename written as a storedName, or as a plain string by
+// versions of FocusMode that didn't record the raw bytes
+func decodeStoredName(data json.RawMessage) (string, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		return name, nil
+	}
+	var stored storedName
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", fmt.Errorf("invalid filename in manifest: %w", err)
+	}
+	if stored.Raw != nil {
+		return string(stored.Raw), nil
+	}
+	return stored.Name, nil
+}
+
+// manifestNames is a list of filenames stored with their raw bytes and normalization forms
+type manifestNames []string
+
+// MarshalJSON writes each name as a storedName
+func (names manifestNames) MarshalJSON() ([]byte, error) {
+	if names == nil {
+		return []byte("null"), nil
+	}
+	stored := make([]storedName, len(names))
+	for i, name := range names {
+		stored[i] = newStoredName(name)
+	}
+	return json.Marshal(stored)
+}
+
+// UnmarshalJSON reads names written as storedNames or plain strings
+func (names *manifestNames) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if items == nil {
+		*names = nil
+		return nil
+	}
+	decoded := make(manifestNames, len(items))
+	for i, item := range items {
+		name, err := decodeStoredName(item)
+		if err != nil {
+			return err
+		}
+		decoded[i] = name
+	}
+	*names = decoded
+	return nil
+}
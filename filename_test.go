@@ -0,0 +1,98 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNormalizationForms tests composition and decomposition, including Hangul and mark ordering
+func TestNormalizationForms(t *testing.T) {
+	tests := []struct {
+		input, nfc, nfd string
+	}{
+		{"Caf\u00e9.lnk", "Caf\u00e9.lnk", "Cafe\u0301.lnk"},
+		{"Cafe\u0301.lnk", "Caf\u00e9.lnk", "Cafe\u0301.lnk"},
+		{"\ud55c\uae00", "\ud55c\uae00", "\u1112\u1161\u11ab\u1100\u1173\u11af"},
+		{"\u1112\u1161\u11ab", "\ud55c", "\u1112\u1161\u11ab"},
+		{"q\u0307\u0323", "q\u0323\u0307", "q\u0323\u0307"}, // marks are put in canonical order
+		{"\u1e9b\u0323", "\u1e9b\u0323", "\u017f\u0323\u0307"},
+		{"\u212b", "\u00c5", "A\u030a"}, // the Angstrom sign comes back as the letter
+		{"plain.lnk", "plain.lnk", "plain.lnk"},
+		{"bad\xff.lnk", "bad\xff.lnk", "bad\xff.lnk"},
+	}
+	for _, tt := range tests {
+		if got := nfc(tt.input); got != tt.nfc {
+			t.Errorf("nfc(%q) = %q, want %q", tt.input, got, tt.nfc)
+		}
+		if got := nfd(tt.input); got != tt.nfd {
+			t.Errorf("nfd(%q) = %q, want %q", tt.input, got, tt.nfd)
+		}
+	}
+}
+
+// TestFindFileName tests that a recorded name is found in the other normalization form
+func TestFindFileName(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Cafe\u0301.lnk"), nil, 0644)
+
+	if got, ok := findFileName(dir, "Caf\u00e9.lnk"); !ok || got != "Cafe\u0301.lnk" {
+		t.Errorf("Expected the decomposed name on disk, got %q, %v", got, ok)
+	}
+	if _, ok := findFileName(dir, "Cafe.lnk"); ok {
+		t.Error("Expected a name without the accent not to match")
+	}
+}
+
+// TestManifestNamesJSON tests that names round-trip exactly and plain string manifests still load
+func TestManifestNamesJSON(t *testing.T) {
+	names := manifestNames{"Caf\u00e9.lnk", "bad\xff.lnk"}
+	data, err := json.Marshal(names)
+	if err != nil {
+		t.Fatalf("Marshal() returned error: %v", err)
+	}
+	var stored []storedName
+	json.Unmarshal(data, &stored)
+	if len(stored) != 2 || stored[0].NFD != "Cafe\u0301.lnk" || string(stored[1].Raw) != "bad\xff.lnk" {
+		t.Errorf("Expected raw bytes and the decomposed form to be stored, got %s", data)
+	}
+
+	var decoded manifestNames
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+	if len(decoded) != 2 || decoded[0] != names[0] || decoded[1] != names[1] {
+		t.Errorf("Expected %q back, got %q", names, decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`["Steam.lnk"]`), &decoded); err != nil || len(decoded) != 1 || decoded[0] != "Steam.lnk" {
+		t.Errorf("Expected an older manifest to load, got %q, %v", decoded, err)
+	}
+
+	move := fileMove{Name: "bad\xff.lnk", Mode: "focusmode", From: "/desk", To: "/games"}
+	data, _ = json.Marshal(move)
+	var decodedMove fileMove
+	if err := json.Unmarshal(data, &decodedMove); err != nil || decodedMove != move {
+		t.Errorf("Expected %+v back, got %+v, %v", move, decodedMove, err)
+	}
+}
+
+// TestRestoreShortcutOtherNormalization tests restoring a name recorded composed but stored decomposed
+func TestRestoreShortcutOtherNormalization(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	games := filepath.Join(dir, "Games")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(games, 0755)
+	os.WriteFile(filepath.Join(games, "Cafe\u0301.lnk"), nil, 0644)
+
+	if err := (&Config{}).restoreShortcutToDesktop("Caf\u00e9.lnk", games); err != nil {
+		t.Fatalf("restoreShortcutToDesktop() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Cafe\u0301.lnk")); err != nil {
+		t.Errorf("Expected the shortcut on the desktop: %v", err)
+	}
+}
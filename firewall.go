@@ -0,0 +1,287 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AppBlockingConfig represents cutting the session mode's block_apps off from the network, for
+// apps that reconnect or restart when closed
+type AppBlockingConfig struct {
+	Backend      string `yaml:"backend" doc:"How block_apps are blocked during sessions: firewall adds Windows Firewall rules blocking their outbound traffic, removed when the session ends (Windows, run elevated)" enum:"off,firewall" default:"off"`
+	Close        bool   `yaml:"close" doc:"Also watch for block_apps from the start of a session, acting on them as action says whenever one is found running, and counting each time one is started during it in the distraction log" default:"false"`
+	Action       string `yaml:"action" doc:"What close does to block_apps found running: close them, minimize their windows, or only warn" enum:"close,minimize,warn" default:"close"`
+	CheckSeconds int    `yaml:"check_seconds" doc:"How often close checks for block_apps started again" default:"5"`
+}
+
+// Actions taken on block_apps found running during a session
+const (
+	appActionClose    = "close"
+	appActionMinimize = "minimize"
+	appActionWarn     = "warn"
+)
+
+// action returns what is done to block_apps found running, closing them when none is configured
+func (c AppBlockingConfig) action() string {
+	if c.Action == "" {
+		return appActionClose
+	}
+	return strings.ToLower(c.Action)
+}
+
+// App blocking backends
+const (
+	appBlockingOff      = "off"
+	appBlockingFirewall = "firewall"
+)
+
+// firewallRuleName names every rule FocusMode adds, so they are all deleted together and rules
+// of your own are never touched
+const firewallRuleName = "FocusMode session block"
+
+// runNetsh runs netsh, replaced in tests
+var runNetsh = func(args ...string) (string, error) {
+	return runQuiet("netsh", args...)
+}
+
+// backend returns the configured backend, "off" when none is
+func (c AppBlockingConfig) backend() string {
+	if c.Backend == "" {
+		return appBlockingOff
+	}
+	return c.Backend
+}
+
+// validate checks the backend and that it works on goos
+func (c AppBlockingConfig) validate(goos string) error {
+	switch c.action() {
+	case appActionClose, appActionMinimize, appActionWarn:
+	default:
+		return fmt.Errorf("app_blocking.action '%s' is not one of close, minimize or warn", c.Action)
+	}
+	switch c.backend() {
+	case appBlockingOff:
+		return nil
+	case appBlockingFirewall:
+		if goos != "windows" {
+			return errors.New("app_blocking.backend firewall uses Windows Firewall and does nothing on " + goos)
+		}
+		return nil
+	}
+	return fmt.Errorf("app_blocking.backend '%s' is not one of off or firewall", c.Backend)
+}
+
+// expandProgramPath expands ~/ and %VARIABLES% in a block_apps entry
+// Windows Firewall runs as a service and can't expand per-user variables like %LOCALAPPDATA% itself
+func expandProgramPath(program, home string, getenv func(string) string) (string, error) {
+	expanded := program
+	if strings.HasPrefix(program, "~/") || strings.HasPrefix(program, `~\`) {
+		expanded = home + `\` + program[2:]
+	}
+	if strings.Contains(expanded, "%") {
+		var ok bool
+		if expanded, ok = expandShellFolder(expanded, home, getenv); !ok {
+			return "", fmt.Errorf("block_apps entry '%s' uses a variable that isn't set", program)
+		}
+	}
+	if !isWindowsAbs(expanded) {
+		return "", fmt.Errorf("block_apps entry '%s' isn't a full path to a program, such as C:\\Program Files\\Steam\\steam.exe", program)
+	}
+	return expanded, nil
+}
+
+// modeBlockedPrograms returns the expanded block_apps of a mode, skipping entries that can't be
+// expanded, which config validate reports
+func modeBlockedPrograms(config *Config, mode string) []string {
+	home, err := userHomeDir()
+	if err != nil {
+		return nil
+	}
+	var programs []string
+	for _, entry := range config.Modes[mode].BlockApps {
+		if program, err := expandProgramPath(entry, home, os.Getenv); err == nil && !containsFold(programs, program) {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// programName returns the file name of a Windows program path, also when built for other systems
+func programName(program string) string {
+	return program[strings.LastIndexAny(program, `\/`)+1:]
+}
+
+// firewallAddArgs returns the netsh arguments adding a rule that blocks program's outbound traffic
+func firewallAddArgs(program string) []string {
+	return []string{"advfirewall", "firewall", "add", "rule",
+		"name=" + firewallRuleName, "dir=out", "action=block", "enable=yes", "profile=any",
+		"program=" + program, "description=Added by FocusMode for the running session and removed when it ends"}
+}
+
+// firewallDeleteArgs returns the netsh arguments deleting every rule FocusMode added
+func firewallDeleteArgs() []string {
+	return []string{"advfirewall", "firewall", "delete", "rule", "name=" + firewallRuleName}
+}
+
+// firewallRulesPath returns the file listing the programs FocusMode added rules for, so the rules
+// are deleted again after a crash
+func firewallRulesPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "firewall-rules.json"), nil
+}
+
+// readFirewallRules returns the programs recorded at path
+func readFirewallRules(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var programs []string
+	if err := json.Unmarshal(data, &programs); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return programs, nil
+}
+
+// blockPrograms deletes rules left by an earlier session, then adds a rule per program
+// The programs are recorded first, so rules added before a failure are still deleted at the end
+func blockPrograms(path string, programs []string) error {
+	if _, err := unblockPrograms(path); err != nil {
+		return err
+	}
+	data, err := json.Marshal(programs)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, data, 0600); err != nil {
+		return err
+	}
+	for _, program := range programs {
+		if _, err := runNetsh(firewallAddArgs(program)...); err != nil {
+			return fmt.Errorf("error blocking %s (adding firewall rules needs FocusMode to run as administrator): %w", programName(program), err)
+		}
+	}
+	return nil
+}
+
+// unblockPrograms deletes the rules FocusMode added and forgets them
+// Nothing is run when nothing is recorded, since netsh fails when no rule matches
+func unblockPrograms(path string) (int, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil
+	}
+	recorded, err := readFirewallRules(path)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := runNetsh(firewallDeleteArgs()...); err != nil {
+		return 0, fmt.Errorf("error deleting the firewall rules named '%s': %w", firewallRuleName, err)
+	}
+	if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return len(recorded), nil
+}
+
+// clearFirewallRules deletes rules left by a session that crashed, warning when it can't
+func clearFirewallRules() {
+	path, err := firewallRulesPath()
+	if err != nil {
+		return
+	}
+	if _, err := unblockPrograms(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (run focusmode firewall clear as administrator)\n", err)
+	}
+}
+
+// firewallHook blocks the session mode's block_apps with Windows Firewall for the length of the session
+// Pausing keeps them blocked, as with DNS blocking
+type firewallHook struct {
+	path     string
+	programs []string
+}
+
+// OnStart adds the firewall rules
+func (h *firewallHook) OnStart(fs *FocusSession) {
+	if err := blockPrograms(h.path, h.programs); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not block apps: %v\n", err)
+	}
+}
+
+// OnPause does nothing
+func (h *firewallHook) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (h *firewallHook) OnResume(fs *FocusSession) {}
+
+// OnEnd deletes the firewall rules
+func (h *firewallHook) OnEnd(fs *FocusSession) {
+	if _, err := unblockPrograms(h.path); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not unblock apps: %v (run focusmode firewall clear as administrator)\n", err)
+	}
+}
+
+// DryRun reports the programs that would be blocked
+func (h *firewallHook) DryRun(fs *FocusSession) []string {
+	names := make([]string, len(h.programs))
+	for i, program := range h.programs {
+		names[i] = programName(program)
+	}
+	return []string{fmt.Sprintf("block network access for %s with Windows Firewall until the session ends", strings.Join(names, ", "))}
+}
+
+// runFirewallCommand handles the "firewall" subcommand
+func runFirewallCommand(args []string) {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "clear") {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode firewall status")
+		fmt.Fprintln(os.Stderr, "       focusmode firewall clear")
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("firewall "+args[0], flag.ExitOnError)
+	flags.Parse(args[1:])
+
+	if runtime.GOOS != "windows" {
+		fmt.Println("Firewall rules are only added on Windows; nothing to do.")
+		return
+	}
+	path, err := firewallRulesPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "status":
+		programs, err := readFirewallRules(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(programs) == 0 {
+			fmt.Println("No apps are blocked")
+			return
+		}
+		fmt.Printf("Blocked by firewall rules named '%s':\n", firewallRuleName)
+		for _, program := range programs {
+			fmt.Printf("  %s\n", program)
+		}
+	case "clear":
+		n, err := unblockPrograms(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted the firewall rules for %d app(s)\n", n)
+	}
+}
@@ -0,0 +1,110 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestExpandProgramPath tests expanding block_apps entries and rejecting ones that aren't full paths
+func TestExpandProgramPath(t *testing.T) {
+	getenv := func(name string) string {
+		return map[string]string{"LOCALAPPDATA": `C:\Users\me\AppData\Local`}[name]
+	}
+	tests := map[string]string{
+		`C:\Program Files (x86)\Steam\steam.exe`: `C:\Program Files (x86)\Steam\steam.exe`,
+		`%LOCALAPPDATA%\Discord\Update.exe`:      `C:\Users\me\AppData\Local\Discord\Update.exe`,
+		`%USERPROFILE%\Games\game.exe`:           `C:\Users\me\Games\game.exe`,
+		`~/Apps/slack.exe`:                       `C:\Users\me\Apps/slack.exe`,
+		`steam.exe`:                              "",
+		`%UNSET%\app.exe`:                        "",
+	}
+	for entry, want := range tests {
+		got, err := expandProgramPath(entry, `C:\Users\me`, getenv)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("expandProgramPath(%q) = %q, %v, want %q", entry, got, err, want)
+		}
+	}
+	if got := programName(`C:\Program Files\Steam\steam.exe`); got != "steam.exe" {
+		t.Errorf("Expected steam.exe, got %q", got)
+	}
+}
+
+// TestBlockPrograms tests that rules are recorded before they are added and deleted even after a failed add
+func TestBlockPrograms(t *testing.T) {
+	var calls []string
+	failOn := ""
+	runNetsh = func(args ...string) (string, error) {
+		call := strings.Join(args, " ")
+		calls = append(calls, call)
+		if failOn != "" && strings.Contains(call, failOn) {
+			return "", errors.New("The requested operation requires elevation")
+		}
+		return "Ok.", nil
+	}
+	t.Cleanup(func() { runNetsh = func(args ...string) (string, error) { return runQuiet("netsh", args...) } })
+	path := filepath.Join(t.TempDir(), "firewall-rules.json")
+
+	// Nothing recorded, so nothing is deleted
+	if n, err := unblockPrograms(path); err != nil || n != 0 || len(calls) != 0 {
+		t.Fatalf("unblockPrograms() = %d, %v with calls %v", n, err, calls)
+	}
+
+	programs := []string{`C:\Apps\steam.exe`, `C:\Apps\discord.exe`}
+	failOn = "discord"
+	if err := blockPrograms(path, programs); err == nil || !strings.Contains(err.Error(), "administrator") {
+		t.Errorf("Expected an error about running as administrator, got %v", err)
+	}
+	if recorded, _ := readFirewallRules(path); !reflect.DeepEqual(recorded, programs) {
+		t.Errorf("Expected %v recorded, got %v", programs, recorded)
+	}
+	if len(calls) != 2 || !strings.Contains(calls[0], `program=C:\Apps\steam.exe`) || !strings.Contains(calls[0], "dir=out action=block") {
+		t.Errorf("Unexpected netsh calls: %v", calls)
+	}
+
+	// Starting again deletes the leftover rules before adding them anew
+	failOn, calls = "", nil
+	if err := blockPrograms(path, programs); err != nil {
+		t.Fatalf("blockPrograms() returned error: %v", err)
+	}
+	if len(calls) != 3 || calls[0] != strings.Join(firewallDeleteArgs(), " ") {
+		t.Errorf("Expected a delete then two adds, got %v", calls)
+	}
+
+	calls = nil
+	if n, err := unblockPrograms(path); err != nil || n != 2 || len(calls) != 1 {
+		t.Errorf("unblockPrograms() = %d, %v with calls %v", n, err, calls)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the record removed, got %v", err)
+	}
+}
+
+// TestAppBlockingLint tests the warnings about block_apps entries and backends
+func TestAppBlockingLint(t *testing.T) {
+	config := &Config{DefaultMode: "work", Modes: map[string]ModeConfig{"work": {BlockApps: []string{"steam.exe", `%LOCALAPPDATA%\Discord\Update.exe`}}}}
+	if warnings := lintConfig(config); len(warnings) != 0 {
+		t.Errorf("Expected names accepted when apps are only closed, got %v", warnings)
+	}
+	config.AppBlocking.Backend = appBlockingFirewall
+	warnings := lintConfig(config)
+	if runtime.GOOS != "windows" {
+		warnings = warnings[1:] // The backend itself is reported first outside Windows
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "steam.exe") {
+		t.Errorf("Expected a warning about steam.exe, got %v", warnings)
+	}
+	if err := (AppBlockingConfig{Backend: "firewall"}).validate("linux"); err == nil {
+		t.Error("Expected the firewall backend refused outside Windows")
+	}
+	if err := (AppBlockingConfig{Backend: "firewall"}).validate("windows"); err != nil {
+		t.Errorf("Expected the firewall backend accepted on Windows, got %v", err)
+	}
+	if err := (AppBlockingConfig{Backend: "pf"}).validate("windows"); err == nil {
+		t.Error("Expected an unknown backend refused")
+	}
+}
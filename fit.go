@@ -0,0 +1,198 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// fitCandidate is a hidden shortcut that could be restored to fill the desktop
+type fitCandidate struct {
+	Mode     string
+	Folder   string
+	Name     string
+	LastUsed time.Time
+}
+
+// lastUsed returns when a file was last opened, falling back to when it was last changed
+// Access times come from the platform's stat data, which differs by OS and is read by field name;
+// they are only as fresh as the filesystem keeps them (e.g. relatime on Linux)
+func lastUsed(info os.FileInfo) time.Time {
+	used := info.ModTime()
+	sys := reflect.Indirect(reflect.ValueOf(info.Sys()))
+	if sys.Kind() != reflect.Struct {
+		return used
+	}
+	var accessed time.Time
+	for _, name := range []string{"Atim", "Atimespec"} { // Linux, macOS
+		if field := sys.FieldByName(name); field.IsValid() {
+			accessed = time.Unix(field.FieldByName("Sec").Int(), field.FieldByName("Nsec").Int())
+		}
+	}
+	if field := sys.FieldByName("LastAccessTime"); field.IsValid() { // Windows FILETIME
+		ticks := field.FieldByName("HighDateTime").Uint()<<32 | field.FieldByName("LowDateTime").Uint()
+		const unixEpochTicks = 116444736000000000 // 100ns intervals from 1601 to 1970
+		if ticks > unixEpochTicks {
+			accessed = time.Unix(0, int64(ticks-unixEpochTicks)*100)
+		}
+	}
+	if accessed.After(used) {
+		return accessed
+	}
+	return used
+}
+
+// rankFitCandidates orders candidates most recently used first, then by name
+func rankFitCandidates(candidates []fitCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].LastUsed.Equal(candidates[j].LastUsed) {
+			return candidates[i].LastUsed.After(candidates[j].LastUsed)
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+}
+
+// fitCandidates returns the restorable shortcuts in the folders of the given modes
+// A launch with focusmode launch counts as a use
+func fitCandidates(config *Config, modes []string, homeDir string) []fitCandidate {
+	launched := readLaunchUsage()
+	var candidates []fitCandidate
+	for _, modeName := range modes {
+		destination := config.Modes[modeName].Destination
+		if destination == "" {
+			destination = fmt.Sprintf("%s_Shortcuts", modeName)
+		}
+		folder := filepath.Join(homeDir, destination)
+		names, err := getShortcutsInFolder(folder)
+		if err != nil {
+			continue
+		}
+		for _, name := range config.restorable(names) {
+			info, err := os.Stat(filepath.Join(folder, name))
+			if err != nil {
+				continue
+			}
+			used := lastUsed(info)
+			if at := launched[nfc(name)]; at.After(used) {
+				used = at
+			}
+			candidates = append(candidates, fitCandidate{Mode: modeName, Folder: folder, Name: name, LastUsed: used})
+		}
+	}
+	rankFitCandidates(candidates)
+	return candidates
+}
+
+// restoreToFit restores the most used hidden shortcuts until the desktop holds target items
+func restoreToFit(config *Config, modes []string, target int, dryRun bool) error {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return fmt.Errorf("error getting desktop path: %w", err)
+	}
+	homeDir, err := hiddenFoldersRoot()
+	if err != nil {
+		return fmt.Errorf("error getting home directory: %w", err)
+	}
+	onDesktop, err := folderEntries(desktopPath)
+	if err != nil {
+		return fmt.Errorf("error reading desktop: %w", err)
+	}
+
+	slots := target - len(onDesktop)
+	if slots <= 0 {
+		fmt.Printf("The desktop already has %d item(s), at or above the target of %d. Nothing restored.\n", len(onDesktop), target)
+		return nil
+	}
+	candidates := fitCandidates(config, modes, homeDir)
+	if len(candidates) == 0 {
+		fmt.Println("No hidden shortcuts to restore.")
+		return nil
+	}
+	if slots > len(candidates) {
+		slots = len(candidates)
+	}
+	fmt.Printf("The desktop has %d item(s); restoring the %d most recently used of %d hidden shortcut(s) to reach %d\n\n",
+		len(onDesktop), slots, len(candidates), target)
+
+	var moves []fileMove
+	restored := make(map[string][]string)
+	failed := 0
+	for _, candidate := range candidates[:slots] {
+		if dryRun {
+			fmt.Printf("[DRY RUN] Would restore: %s (%s, last used %s)\n", candidate.Name, candidate.Mode, candidate.LastUsed.Format("2006-01-02"))
+			continue
+		}
+		if err := config.restoreShortcutToDesktop(candidate.Name, candidate.Folder); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring '%s': %v\n", candidate.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%sRestored: %s\n", glyph("✓ "), candidate.Name)
+		restored[candidate.Mode] = append(restored[candidate.Mode], candidate.Name)
+		moves = append(moves, desktopMoves(candidate.Mode, candidate.Folder, []string{candidate.Name}, true)...)
+	}
+	fmt.Printf("\nKept %d shortcut(s) hidden\n", len(candidates)-slots+failed)
+
+	if dryRun {
+		fmt.Println("(Dry run - no files were actually restored)")
+		return nil
+	}
+	// The modes stay applied with the rest of their shortcuts, so their layers only shrink
+	for _, modeName := range modes {
+		if names := restored[modeName]; len(names) > 0 {
+			recordShortcutsRestored(modeName, names)
+		}
+	}
+	operationMode := "all"
+	if len(modes) == 1 {
+		operationMode = modes[0]
+	}
+	recordOperation(operationRestore, operationMode, moves, "")
+	refreshHiddenMenu(config)
+	if failed > 0 {
+		return fmt.Errorf("%d shortcut(s) could not be restored", failed)
+	}
+	return nil
+}
+
+// runRestoreCommand handles the "restore" subcommand
+// Restoring everything stays with -restore and -restore-all; this restores only as much as fits
+func runRestoreCommand(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	mode := flags.String("mode", "", "Restore only from this mode's folder (default: all modes)")
+	fit := flags.Int("fit", 0, "Restore the most recently used shortcuts until the desktop holds this many items")
+	dryRun := flags.Bool("dry-run", false, "Show what would be restored without actually moving")
+	flags.Parse(args)
+
+	if *fit <= 0 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode restore -fit <items> [-mode X] [-dry-run] [-config profile.yml]")
+		fmt.Fprintln(os.Stderr, "To restore everything, use -restore -mode X or -restore-all")
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	modes := config.getAvailableModes()
+	sort.Strings(modes)
+	if *mode != "" {
+		if _, err := config.getModeConfig(*mode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		modes = []string{*mode}
+	}
+
+	if err := restoreToFit(config, modes, *fit, *dryRun); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLastUsed tests that the later of the access and modification times is used
+func TestLastUsed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Steam.lnk")
+	os.WriteFile(path, nil, 0644)
+	accessed := time.Now().Add(-time.Hour).Truncate(time.Second)
+	modified := accessed.Add(-24 * time.Hour)
+	os.Chtimes(path, accessed, modified)
+
+	info, _ := os.Stat(path)
+	if got := lastUsed(info); !got.Equal(accessed) {
+		t.Errorf("Expected the access time %v, got %v", accessed, got)
+	}
+}
+
+// TestRestoreToFit tests that the most recently used shortcuts are restored up to the target
+func TestRestoreToFit(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	desktop := filepath.Join(dir, "Desktop")
+	games := filepath.Join(dir, "Games")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(games, 0755)
+	os.WriteFile(filepath.Join(desktop, "Notes.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(desktop, ".DS_Store"), nil, 0644)
+	now := time.Now()
+	for i, name := range []string{"Steam.lnk", "Epic.lnk", "Discord.lnk"} {
+		path := filepath.Join(games, name)
+		os.WriteFile(path, nil, 0644)
+		used := now.Add(-time.Duration(i) * time.Hour)
+		os.Chtimes(path, used, used)
+	}
+	recordModeApplied("gamemode", games, []string{"Steam.lnk", "Epic.lnk", "Discord.lnk"})
+
+	config := &Config{Modes: map[string]ModeConfig{"gamemode": {Destination: "Games"}}}
+	if err := restoreToFit(config, []string{"gamemode"}, 3, false); err != nil {
+		t.Fatalf("restoreToFit() returned error: %v", err)
+	}
+	for name, want := range map[string]bool{"Steam.lnk": true, "Epic.lnk": true, "Discord.lnk": false} {
+		if _, err := os.Stat(filepath.Join(desktop, name)); (err == nil) != want {
+			t.Errorf("%s on desktop: got %v, want %v", name, err == nil, want)
+		}
+	}
+
+	path, _ := appliedModesPath()
+	modes, _ := readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Shortcuts) != 1 || modes[0].Shortcuts[0] != "Discord.lnk" {
+		t.Errorf("Expected the layer to keep Discord.lnk, got %+v", modes)
+	}
+
+	// A full desktop restores nothing more
+	if err := restoreToFit(config, []string{"gamemode"}, 3, false); err != nil {
+		t.Fatalf("restoreToFit() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(games, "Discord.lnk")); err != nil {
+		t.Errorf("Expected Discord.lnk to stay hidden: %v", err)
+	}
+}
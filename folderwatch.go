@@ -0,0 +1,246 @@
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FolderWatchConfig represents the hidden folder watch settings
+type FolderWatchConfig struct {
+	Enabled bool `yaml:"enabled" doc:"Watch the folders of applied modes and alert when files are added or removed by hand" default:"false"`
+}
+
+// folderWatchPollInterval is how often the folders of applied modes are checked
+const folderWatchPollInterval = 30 * time.Second
+
+// folderEntries returns the names in a folder, leaving out the metadata files that
+// Finder and Explorer create on their own, such as .DS_Store and desktop.ini
+func folderEntries(folder string) ([]string, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.EqualFold(name, "desktop.ini") || strings.EqualFold(name, "thumbs.db") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// folderChange is a file added to or removed from a mode's folder outside FocusMode
+type folderChange struct {
+	Mode      string
+	Folder    string
+	Name      string
+	Added     bool // Added to the folder; otherwise removed from it
+	OnDesktop bool // A removed file is on the desktop, so it was restored by hand
+}
+
+// key identifies the change between checks
+func (c folderChange) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%v", c.Folder, nfc(c.Name), c.Added)
+}
+
+// message describes the change and what was done about it
+func (c folderChange) message() string {
+	switch {
+	case c.Added:
+		return fmt.Sprintf("%s was added to %s by hand; it isn't in the %s manifest, so pop leaves it there (-restore -mode %s brings it out)", c.Name, c.Folder, c.Mode, c.Mode)
+	case c.OnDesktop:
+		return fmt.Sprintf("%s was moved back to the desktop by hand; removed it from the %s manifest", c.Name, c.Mode)
+	default:
+		return fmt.Sprintf("%s was removed from %s; removed it from the %s manifest", c.Name, c.Folder, c.Mode)
+	}
+}
+
+// folderWatcher compares the folders of applied modes with their manifests
+// A difference is only reported once it has been seen in two checks in a row,
+// so files FocusMode is moving at the time of a check aren't mistaken for tampering
+type folderWatcher struct {
+	known   map[string]map[string]bool // Files seen in each folder that no manifest lists, by NFC name
+	pending map[string]bool            // Changes seen in the last check, not yet reported
+}
+
+// newFolderWatcher returns a watcher that treats the files already in each folder as expected
+func newFolderWatcher() *folderWatcher {
+	return &folderWatcher{known: make(map[string]map[string]bool), pending: make(map[string]bool)}
+}
+
+// check returns the changes confirmed since the last check
+// list returns the files in a folder and onDesktop reports whether a file is on the desktop
+func (w *folderWatcher) check(modes []appliedMode, list func(string) ([]string, error), onDesktop func(string) bool) []folderChange {
+	var changes []folderChange
+	seen := make(map[string]bool)
+	confirm := func(change folderChange) {
+		seen[change.key()] = true
+		if w.pending[change.key()] {
+			changes = append(changes, change)
+		}
+	}
+
+	// Several layers may share a folder; a file is expected there if any of them lists it
+	listed := make(map[string]map[string]bool)
+	var folders []string
+	for _, mode := range modes {
+		folder := filepath.Clean(mode.Destination)
+		if listed[folder] == nil {
+			listed[folder] = make(map[string]bool)
+			folders = append(folders, folder)
+		}
+		for _, name := range mode.Shortcuts {
+			listed[folder][nfc(name)] = true
+		}
+	}
+
+	present := make(map[string]map[string]bool)
+	for _, folder := range folders {
+		files, err := list(folder)
+		if err != nil {
+			// A folder that can't be read is left alone rather than taken as emptied
+			continue
+		}
+		present[folder] = make(map[string]bool)
+		for _, name := range files {
+			present[folder][nfc(name)] = true
+		}
+
+		known, first := w.known[folder], w.known[folder] == nil
+		if first {
+			known = make(map[string]bool)
+			w.known[folder] = known
+		}
+		for _, name := range files {
+			key := nfc(name)
+			if listed[folder][key] || known[key] {
+				continue
+			}
+			if first {
+				known[key] = true
+				continue
+			}
+			owner := ""
+			for _, mode := range modes {
+				if filepath.Clean(mode.Destination) == folder {
+					owner = mode.Mode
+				}
+			}
+			confirm(folderChange{Mode: owner, Folder: folder, Name: name, Added: true})
+		}
+		for key := range known {
+			if !present[folder][key] {
+				delete(known, key)
+			}
+		}
+	}
+
+	// A folder no mode uses any more starts afresh if a mode is applied to it again
+	for folder := range w.known {
+		if listed[folder] == nil {
+			delete(w.known, folder)
+		}
+	}
+
+	for _, mode := range modes {
+		folder := filepath.Clean(mode.Destination)
+		if present[folder] == nil {
+			continue
+		}
+		for _, name := range mode.Shortcuts {
+			if !present[folder][nfc(name)] {
+				confirm(folderChange{Mode: mode.Mode, Folder: folder, Name: name, OnDesktop: onDesktop(name)})
+			}
+		}
+	}
+
+	// Reported additions are expected from now on; removals leave the manifest when reconciled
+	for _, change := range changes {
+		if change.Added {
+			w.known[change.Folder][nfc(change.Name)] = true
+		}
+	}
+	w.pending = seen
+	for _, change := range changes {
+		delete(w.pending, change.key())
+	}
+	return changes
+}
+
+// reconcileFolderChanges drops the files removed by hand from their manifests
+func reconcileFolderChanges(changes []folderChange) {
+	removed := make(map[string][]string)
+	var modes []string
+	for _, change := range changes {
+		if change.Added {
+			continue
+		}
+		if removed[change.Mode] == nil {
+			modes = append(modes, change.Mode)
+		}
+		removed[change.Mode] = append(removed[change.Mode], change.Name)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		recordShortcutsRestored(mode, removed[mode])
+	}
+}
+
+// watchHiddenFolders alerts when the folders of applied modes change outside FocusMode
+// and keeps their manifests in step; mu is held for each check so it can't interleave with moves
+func watchHiddenFolders(ctx context.Context, mu sync.Locker, beat func()) {
+	path, err := appliedModesPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	onDesktop := func(name string) bool {
+		desktopPath, err := getDesktopPath()
+		if err != nil {
+			return false
+		}
+		_, ok := findFileName(desktopPath, name)
+		return ok
+	}
+	notifiers := []Notifier{consoleNotifier{}}
+
+	fmt.Println("Watching the folders of applied modes...")
+	watcher := newFolderWatcher()
+	for {
+		beat()
+		// Only checks that find changes are traced, so quiet polls don't flood the collector
+		_, span := startSpan(ctx, "folder-watch")
+		mu.Lock()
+		modes, err := readAppliedModes(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			span.finish(err)
+		} else {
+			changes := watcher.check(modes, folderEntries, onDesktop)
+			for _, change := range changes {
+				notifyAll(notifiers, "Hidden folder changed", change.message())
+			}
+			reconcileFolderChanges(changes)
+			if len(changes) > 0 {
+				span.set("focusmode.changes", len(changes))
+				span.finish(nil)
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(folderWatchPollInterval):
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package focusmode
+
+import (
+	"testing"
+)
+
+// TestFolderWatcherCheck tests that only changes seen in two checks in a row are reported
+func TestFolderWatcherCheck(t *testing.T) {
+	modes := []appliedMode{{Mode: "gamemode", Destination: "/home/Games", Shortcuts: manifestNames{"Steam.lnk", "Epic.lnk"}}}
+	files := []string{"Steam.lnk", "Epic.lnk", "old.txt"}
+	list := func(string) ([]string, error) { return files, nil }
+	desktop := map[string]bool{}
+	onDesktop := func(name string) bool { return desktop[name] }
+
+	watcher := newFolderWatcher()
+	if changes := watcher.check(modes, list, onDesktop); len(changes) != 0 {
+		t.Fatalf("Expected files already in the folder to be accepted, got %+v", changes)
+	}
+
+	// A file that shows up for a single check, e.g. while FocusMode moves it, isn't reported
+	files = []string{"Steam.lnk", "old.txt"}
+	watcher.check(modes, list, onDesktop)
+	files = []string{"Steam.lnk", "Epic.lnk", "old.txt"}
+	if changes := watcher.check(modes, list, onDesktop); len(changes) != 0 {
+		t.Fatalf("Expected a passing change not to be reported, got %+v", changes)
+	}
+
+	files = []string{"Steam.lnk", "old.txt", "Cheat.exe"}
+	desktop["Epic.lnk"] = true
+	if changes := watcher.check(modes, list, onDesktop); len(changes) != 0 {
+		t.Fatalf("Expected changes to wait for a second check, got %+v", changes)
+	}
+	changes := watcher.check(modes, list, onDesktop)
+	if len(changes) != 2 {
+		t.Fatalf("Expected an addition and a removal, got %+v", changes)
+	}
+	if !changes[0].Added || changes[0].Name != "Cheat.exe" || changes[0].Mode != "gamemode" {
+		t.Errorf("Unexpected addition: %+v", changes[0])
+	}
+	if changes[1].Added || changes[1].Name != "Epic.lnk" || !changes[1].OnDesktop {
+		t.Errorf("Unexpected removal: %+v", changes[1])
+	}
+
+	// Reported additions are expected from then on
+	modes[0].Shortcuts = manifestNames{"Steam.lnk"}
+	if changes := watcher.check(modes, list, onDesktop); len(changes) != 0 {
+		t.Errorf("Expected nothing new, got %+v", changes)
+	}
+}
+
+// TestReconcileFolderChanges tests that removed files leave their manifest
+func TestReconcileFolderChanges(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	recordModeApplied("gamemode", "/home/Games", []string{"Steam.lnk", "Epic.lnk"})
+	reconcileFolderChanges([]folderChange{
+		{Mode: "gamemode", Folder: "/home/Games", Name: "Epic.lnk"},
+		{Mode: "gamemode", Folder: "/home/Games", Name: "Cheat.exe", Added: true},
+	})
+	path, _ := appliedModesPath()
+	modes, _ := readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Shortcuts) != 1 || modes[0].Shortcuts[0] != "Steam.lnk" {
+		t.Errorf("Expected only Steam.lnk left in the manifest, got %+v", modes)
+	}
+}
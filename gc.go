@@ -0,0 +1,420 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GCConfig represents how long FocusMode keeps its own records
+type GCConfig struct {
+	Enabled      bool `yaml:"enabled" doc:"Prune old records once a day from focusmode daemon; 'focusmode gc' prunes them any time" default:"false"`
+	HistoryDays  int  `yaml:"history_days" doc:"Days session history and distraction records are kept; 0 keeps them forever" default:"0" example:"365"`
+	HistoryMaxMB int  `yaml:"history_max_mb" doc:"Size in MB the session history and the distraction log are each kept under, dropping their oldest records; 0 for no limit" default:"0" example:"5"`
+	UndoDays     int  `yaml:"undo_days" doc:"Days applies and restores stay in the undo history, with their screenshots; 0 keeps the last 20 whatever their age" default:"0" example:"30"`
+	LogMaxMB     int  `yaml:"log_max_mb" doc:"Size in MB the daemon's log is cut down to, keeping its newest lines" default:"10"`
+}
+
+const (
+	defaultGCLogMaxMB = 10
+	gcPollInterval    = time.Hour
+	// Screenshots no operation lists are kept this long, since an apply takes its screenshot
+	// before it is recorded
+	orphanScreenshotAge = 24 * time.Hour
+)
+
+// logMaxBytes returns the size the daemon's log is cut down to
+func (c GCConfig) logMaxBytes() int64 {
+	if c.LogMaxMB <= 0 {
+		return defaultGCLogMaxMB << 20
+	}
+	return int64(c.LogMaxMB) << 20
+}
+
+// gcCutoff returns the time before which records kept for days are pruned at now, or zero
+// for no limit
+func gcCutoff(days int, now time.Time) time.Time {
+	if days <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -days)
+}
+
+// gcResult is what gc pruned, or would prune, from one file or folder
+type gcResult struct {
+	Path  string
+	What  string // e.g. "12 session record(s)"
+	Freed int64  // Bytes
+}
+
+// describe returns the line printed for the result
+func (r gcResult) describe(dryRun bool) string {
+	if dryRun {
+		return fmt.Sprintf("[DRY RUN] Would prune %s from %s (%s)", r.What, r.Path, gcSize(r.Freed))
+	}
+	return fmt.Sprintf("%sPruned %s from %s (%s)", glyph("✓ "), r.What, r.Path, gcSize(r.Freed))
+}
+
+// gcSize formats a number of bytes in KB or MB
+func gcSize(n int64) string {
+	if n < 1<<20 {
+		return fmt.Sprintf("%d KB", (n+1023)>>10)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// historyRecordTime returns when a session history record ended
+func historyRecordTime(line []byte) (time.Time, bool) {
+	var record SessionRecord
+	if json.Unmarshal(line, &record) != nil || record.EndTime.IsZero() {
+		return time.Time{}, false
+	}
+	return record.EndTime, true
+}
+
+// distractionRecordTime returns when a distraction was recorded
+func distractionRecordTime(line []byte) (time.Time, bool) {
+	var event DistractionEvent
+	if json.Unmarshal(line, &event) != nil || event.Time.IsZero() {
+		return time.Time{}, false
+	}
+	return event.Time, true
+}
+
+// pruneRecordLog drops the records of a JSON lines log at path from before cutoff, then the
+// oldest ones until the log is under maxBytes; a zero cutoff or maxBytes is no limit
+// Lines that aren't records are only dropped for size. It returns how many lines were dropped
+// and the bytes freed. The log's lock is held from reading it to writing it back, so a record
+// appended meanwhile isn't lost
+func pruneRecordLog(path string, recordTime func([]byte) (time.Time, bool), cutoff time.Time, maxBytes int64, dryRun bool) (int, int64, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if !dryRun {
+		unlock, err := lockRecordLog(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer unlock()
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading %s: %w", filepath.Base(path), err)
+	}
+
+	var kept [][]byte
+	var size int64
+	dropped := 0
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if t, ok := recordTime(bytes.TrimSpace(line)); ok && !cutoff.IsZero() && t.Before(cutoff) {
+			dropped++
+			continue
+		}
+		kept = append(kept, line)
+		size += int64(len(line))
+	}
+	// Records are appended as they happen, so the oldest come first
+	for maxBytes > 0 && size > maxBytes && len(kept) > 0 {
+		size -= int64(len(kept[0]))
+		kept = kept[1:]
+		dropped++
+	}
+
+	freed := int64(len(data)) - size
+	if dropped == 0 || dryRun {
+		return dropped, freed, nil
+	}
+	if err := writeFile(path, bytes.Join(kept, nil), 0644); err != nil {
+		return 0, 0, fmt.Errorf("error writing %s: %w", filepath.Base(path), err)
+	}
+	ownByUser(path)
+	return dropped, freed, nil
+}
+
+// pruneOperations drops the operations from before cutoff from the undo history at path,
+// and deletes their screenshots; it returns the operations dropped and the bytes freed
+func pruneOperations(path string, cutoff time.Time, dryRun bool) ([]operation, int64, error) {
+	log, err := readOperationLog(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var dropped []operation
+	keep := func(ops []operation) []operation {
+		var kept []operation
+		for _, op := range ops {
+			if op.Time.Before(cutoff) {
+				dropped = append(dropped, op)
+				continue
+			}
+			kept = append(kept, op)
+		}
+		return kept
+	}
+	log.Done, log.Undone = keep(log.Done), keep(log.Undone)
+	if len(dropped) == 0 {
+		return nil, 0, nil
+	}
+
+	var freed int64
+	if info, err := os.Stat(path); err == nil {
+		freed = info.Size()
+	}
+	if data, err := json.MarshalIndent(log, "", "  "); err == nil {
+		freed -= int64(len(data))
+	}
+	for _, op := range dropped {
+		if info, err := os.Stat(op.Screenshot); op.Screenshot != "" && err == nil {
+			freed += info.Size()
+		}
+	}
+	if dryRun {
+		return dropped, freed, nil
+	}
+	if err := writeOperationLog(path, log); err != nil {
+		return nil, 0, err
+	}
+	removeScreenshots(dropped)
+	return dropped, freed, nil
+}
+
+// orphanScreenshots returns the screenshots in dir taken before cutoff that no operation in
+// the undo history at logPath lists, such as those left by an apply that was interrupted
+func orphanScreenshots(dir, logPath string, cutoff time.Time) ([]string, int64, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading screenshots: %w", err)
+	}
+	log, err := readOperationLog(logPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	listed := make(map[string]bool)
+	for _, op := range append(log.Done, log.Undone...) {
+		if op.Screenshot != "" {
+			listed[filepath.Base(op.Screenshot)] = true
+		}
+	}
+
+	var orphans []string
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(entry.Name()), ".png") || listed[entry.Name()] || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		orphans = append(orphans, filepath.Join(dir, entry.Name()))
+		size += info.Size()
+	}
+	return orphans, size, nil
+}
+
+// trimLog cuts a log down to its newest lines under maxBytes and returns the bytes freed
+// The file is rewritten in place, so a daemon appending to it goes on writing to it
+func trimLog(path string, maxBytes int64, dryRun bool) (int64, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading %s: %w", filepath.Base(path), err)
+	}
+	if info.Size() <= maxBytes {
+		return 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading %s: %w", filepath.Base(path), err)
+	}
+	tail := data[len(data)-int(maxBytes):]
+	// The first line kept is a whole one
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	freed := int64(len(data) - len(tail))
+	if dryRun {
+		return freed, nil
+	}
+	if err := writeFile(path, tail, 0600); err != nil {
+		return 0, fmt.Errorf("error writing %s: %w", filepath.Base(path), err)
+	}
+	return freed, nil
+}
+
+// collectGarbage prunes FocusMode's records past the limits of config at now: the undo
+// history and its screenshots, the session history, the distraction log and the daemon's log
+// Applied modes, the running session and archived files are never touched, and with dryRun
+// nothing is. A failure with one file doesn't stop the others
+func collectGarbage(config GCConfig, now time.Time, dryRun bool) ([]gcResult, error) {
+	var results []gcResult
+	var errs []error
+	found := func(path, what string, n int, freed int64, err error) {
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case n > 0:
+			results = append(results, gcResult{Path: path, What: fmt.Sprintf("%d %s", n, what), Freed: freed})
+		}
+	}
+
+	if logPath, err := operationLogPath(); err != nil {
+		errs = append(errs, err)
+	} else {
+		if cutoff := gcCutoff(config.UndoDays, now); !cutoff.IsZero() {
+			dropped, freed, err := pruneOperations(logPath, cutoff, dryRun)
+			found(logPath, fmt.Sprintf("operation(s) older than %d days", config.UndoDays), len(dropped), freed, err)
+		}
+		if dir, err := screenshotDir(); err == nil {
+			orphans, freed, err := orphanScreenshots(dir, logPath, now.Add(-orphanScreenshotAge))
+			if err == nil && !dryRun {
+				for _, path := range orphans {
+					if err := removeFile(path); err != nil {
+						errs = append(errs, fmt.Errorf("error removing screenshot: %w", err))
+					}
+				}
+			}
+			found(dir, "screenshot(s) no operation lists", len(orphans), freed, err)
+		}
+	}
+
+	cutoff := gcCutoff(config.HistoryDays, now)
+	maxBytes := int64(config.HistoryMaxMB) << 20
+	if path, err := historyPath(); err != nil {
+		errs = append(errs, err)
+	} else {
+		n, freed, err := pruneRecordLog(path, historyRecordTime, cutoff, maxBytes, dryRun)
+		found(path, "session record(s)", n, freed, err)
+	}
+	if path, err := distractionLogPath(); err != nil {
+		errs = append(errs, err)
+	} else {
+		n, freed, err := pruneRecordLog(path, distractionRecordTime, cutoff, maxBytes, dryRun)
+		found(path, "distraction record(s)", n, freed, err)
+	}
+
+	if path, err := daemonLogPath(); err != nil {
+		errs = append(errs, err)
+	} else {
+		freed, err := trimLog(path, config.logMaxBytes(), dryRun)
+		if err != nil {
+			errs = append(errs, err)
+		} else if freed > 0 {
+			results = append(results, gcResult{Path: path, What: fmt.Sprintf("the oldest lines over %d MB", config.logMaxBytes()>>20), Freed: freed})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// gcSummary describes a collection for the notification watchGC sends after it
+func gcSummary(results []gcResult) string {
+	var freed int64
+	for _, result := range results {
+		freed += result.Freed
+	}
+	return fmt.Sprintf("Pruned old records from %d file(s), freeing %s", len(results), gcSize(freed))
+}
+
+// watchGC prunes old records once a day until ctx is cancelled; run by the daemon
+// mu is held for each collection so the undo history isn't rewritten during a move
+func watchGC(ctx context.Context, config *Config, mu sync.Locker, beat func()) {
+	var last time.Time
+	for {
+		beat()
+		if now := time.Now(); purgeDue(last, now) {
+			_, span := startSpan(ctx, "gc")
+			var results []gcResult
+			var err error
+			withLock(mu, func() { results, err = collectGarbage(config.GC, now, false) })
+			span.set("focusmode.pruned", len(results))
+			span.finish(err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			for _, result := range results {
+				fmt.Println(result.describe(false))
+			}
+			if len(results) > 0 {
+				notifyAll([]Notifier{consoleNotifier{}}, "Old records pruned", gcSummary(results))
+			}
+			last = now
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(gcPollInterval):
+		}
+	}
+}
+
+// lintGC warns about limits that can't be used
+func (c *Config) lintGC() []string {
+	var warnings []string
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"gc.history_days", c.GC.HistoryDays},
+		{"gc.history_max_mb", c.GC.HistoryMaxMB},
+		{"gc.undo_days", c.GC.UndoDays},
+		{"gc.log_max_mb", c.GC.LogMaxMB},
+	} {
+		if limit.value < 0 {
+			warnings = append(warnings, fmt.Sprintf("%s is negative and is ignored", limit.name))
+		}
+	}
+	return warnings
+}
+
+// runGCCommand handles "gc", which prunes old records now
+func runGCCommand(args []string) {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be pruned without deleting anything")
+	flags.Parse(args)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if !*dryRun {
+		if err := guardWrite("prune old records"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	results, err := collectGarbage(config.GC, time.Now(), *dryRun)
+	for _, result := range results {
+		fmt.Println(result.describe(*dryRun))
+	}
+	switch {
+	case len(results) == 0 && err == nil:
+		fmt.Println("Nothing is past its limit.")
+	case *dryRun:
+		fmt.Println("(Dry run - nothing was actually deleted)")
+	case len(results) > 0:
+		fmt.Println(gcSummary(results))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,214 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRecords writes history records ending at each of times, one per line
+func writeRecords(t *testing.T, path string, times ...time.Time) {
+	t.Helper()
+	var lines []string
+	for _, end := range times {
+		data, err := json.Marshal(SessionRecord{Kind: RecordSession, Mode: "work", StartTime: end.Add(-25 * time.Minute), EndTime: end})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(data))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPruneRecordLog tests pruning records by age and by size, oldest first
+func TestPruneRecordLog(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	writeRecords(t, path, now.AddDate(0, 0, -400), now.AddDate(0, 0, -100), now.AddDate(0, 0, -10), now)
+	before, _ := os.ReadFile(path)
+
+	dropped, freed, err := pruneRecordLog(path, historyRecordTime, now.AddDate(0, 0, -365), 0, true)
+	if err != nil || dropped != 1 || freed <= 0 {
+		t.Errorf("Dry run: dropped %d, freed %d, %v; want 1 record", dropped, freed, err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("Expected a dry run to leave the history alone")
+	}
+
+	if dropped, _, err := pruneRecordLog(path, historyRecordTime, now.AddDate(0, 0, -365), 0, false); err != nil || dropped != 1 {
+		t.Fatalf("pruneRecordLog() dropped %d, %v; want 1", dropped, err)
+	}
+	records, err := readHistory(path)
+	if err != nil || len(records) != 3 || records[0].EndTime.Before(now.AddDate(0, 0, -365)) {
+		t.Fatalf("Unexpected history after pruning by age: %+v, %v", records, err)
+	}
+
+	// Room for two records drops the oldest of three
+	line := len(strings.SplitAfter(string(before), "\n")[0])
+	if dropped, _, err := pruneRecordLog(path, historyRecordTime, time.Time{}, int64(2*line+10), false); err != nil || dropped != 1 {
+		t.Fatalf("pruneRecordLog() by size dropped %d, %v; want 1", dropped, err)
+	}
+	if records, _ := readHistory(path); len(records) != 2 || !records[1].EndTime.Equal(now) {
+		t.Errorf("Expected the newest two records kept, got %+v", records)
+	}
+
+	if dropped, _, err := pruneRecordLog(filepath.Join(t.TempDir(), "missing.jsonl"), historyRecordTime, now, 1, false); err != nil || dropped != 0 {
+		t.Errorf("Expected a missing log to be fine, got %d, %v", dropped, err)
+	}
+}
+
+// TestPruneRecordLogWaitsForAppend tests that a record appended while the log is locked isn't lost
+func TestPruneRecordLogWaitsForAppend(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	writeRecords(t, path, now.AddDate(0, 0, -400), now)
+
+	unlock, err := lockRecordLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned := make(chan error)
+	go func() {
+		_, _, err := pruneRecordLog(path, historyRecordTime, now.AddDate(0, 0, -365), 0, false)
+		pruned <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// A session appending under the lock, as appendHistory does
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(`{"kind":"session","mode":"late","start_time":"` + now.Format(time.RFC3339) + `","end_time":"` + now.Format(time.RFC3339) + `"}` + "\n")
+	file.Close()
+	unlock()
+
+	if err := <-pruned; err != nil {
+		t.Fatal(err)
+	}
+	records, _ := readHistory(path)
+	if len(records) != 2 || records[1].Mode != "late" {
+		t.Errorf("Expected the old record pruned and the appended one kept, got %+v", records)
+	}
+}
+
+// TestPruneOperations tests dropping old operations with their screenshots, and orphaned screenshots
+func TestPruneOperations(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	shot := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old, recent, orphan := shot("old.png"), shot("recent.png"), shot("orphan.png")
+	os.Chtimes(orphan, now.AddDate(0, 0, -2), now.AddDate(0, 0, -2))
+
+	path := filepath.Join(dir, "operations.json")
+	log := &operationLog{
+		Done: []operation{
+			{Kind: "apply", Mode: "work", Time: now.AddDate(0, 0, -60), Moves: []fileMove{{Name: "Steam.lnk"}}, Screenshot: old},
+			{Kind: "apply", Mode: "games", Time: now.AddDate(0, 0, -1), Moves: []fileMove{{Name: "Notes.txt"}}, Screenshot: recent},
+		},
+		Undone: []operation{{Kind: "restore", Mode: "work", Time: now.AddDate(0, 0, -45)}},
+	}
+	if err := writeOperationLog(path, log); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, _, err := pruneOperations(path, now.AddDate(0, 0, -30), true)
+	if err != nil || len(dropped) != 2 {
+		t.Fatalf("Dry run dropped %d, %v; want 2", len(dropped), err)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Error("Expected a dry run to keep the screenshot")
+	}
+
+	if dropped, _, err := pruneOperations(path, now.AddDate(0, 0, -30), false); err != nil || len(dropped) != 2 {
+		t.Fatalf("pruneOperations() dropped %d, %v; want 2", len(dropped), err)
+	}
+	log, err = readOperationLog(path)
+	if err != nil || len(log.Done) != 1 || log.Done[0].Mode != "games" || len(log.Undone) != 0 {
+		t.Errorf("Unexpected undo history: %+v, %v", log, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the dropped operation's screenshot deleted")
+	}
+
+	orphans, _, err := orphanScreenshots(dir, path, now.Add(-orphanScreenshotAge))
+	if err != nil || len(orphans) != 1 || orphans[0] != orphan {
+		t.Errorf("orphanScreenshots() = %v, %v; want only %s (recent.png is listed)", orphans, err, orphan)
+	}
+}
+
+// TestTrimLog tests cutting a log down to its newest whole lines
+func TestTrimLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, strings.Repeat("x", 9))
+	}
+	lines[99] = "last line"
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+	if freed, err := trimLog(path, 2000, false); err != nil || freed != 0 {
+		t.Errorf("Expected a small log left alone, got %d, %v", freed, err)
+	}
+	freed, err := trimLog(path, 55, false)
+	if err != nil || freed != 950 {
+		t.Errorf("trimLog() = %d, %v; want 950 bytes freed", freed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) != 50 || !strings.HasSuffix(string(data), "last line\n") || !strings.HasPrefix(string(data), "xxxxxxxxx\n") {
+		t.Errorf("Expected the newest whole lines kept, got %q", data)
+	}
+}
+
+// TestCollectGarbage tests that nothing is pruned without limits and that dry runs delete nothing
+func TestCollectGarbage(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	now := time.Now()
+
+	path, err := historyPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRecords(t, path, now.AddDate(-2, 0, 0), now)
+
+	if results, err := collectGarbage(GCConfig{}, now, false); err != nil || len(results) != 0 {
+		t.Errorf("Expected nothing pruned by default, got %+v, %v", results, err)
+	}
+
+	config := GCConfig{HistoryDays: 365}
+	results, err := collectGarbage(config, now, true)
+	if err != nil || len(results) != 1 || results[0].What != "1 session record(s)" {
+		t.Fatalf("Dry run = %+v, %v", results, err)
+	}
+	if line := results[0].describe(true); !strings.HasPrefix(line, "[DRY RUN] Would prune 1 session record(s) from ") {
+		t.Errorf("Unexpected dry run line %q", line)
+	}
+	if records, _ := readHistory(path); len(records) != 2 {
+		t.Error("Expected a dry run to keep the history")
+	}
+
+	if results, err := collectGarbage(config, now, false); err != nil || len(results) != 1 {
+		t.Fatalf("collectGarbage() = %+v, %v", results, err)
+	}
+	if records, _ := readHistory(path); len(records) != 1 {
+		t.Errorf("Expected the old session pruned, got %d records", len(records))
+	}
+}
+
+// TestLintGC tests the warning about negative limits
+func TestLintGC(t *testing.T) {
+	config := &Config{GC: GCConfig{HistoryDays: -1, LogMaxMB: 5}}
+	if warnings := config.lintGC(); len(warnings) != 1 || !strings.Contains(warnings[0], "gc.history_days") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
@@ -0,0 +1,158 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GitHubConfig represents the GitHub activity correlation settings
+type GitHubConfig struct {
+	Enabled      bool     `yaml:"enabled" doc:"Include GitHub activity in reports" default:"false"`
+	Token        string   `yaml:"token" doc:"Personal access token"`
+	User         string   `yaml:"user" doc:"GitHub login (resolved from the token if empty)"`
+	Repos        []string `yaml:"repos" doc:"Only count activity in these owner/name repos (all if empty)" example:"[chenyuan99/FocusMode]"`
+	GraceMinutes int      `yaml:"grace_minutes" doc:"Activity this long after a session still counts" default:"30"`
+}
+
+const defaultGitHubGraceMinutes = 30
+
+// githubAPIURL is the GitHub REST API base URL
+var githubAPIURL = "https://api.github.com"
+
+// githubHTTPClient is used for all GitHub API requests
+var githubHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// githubEvent is the subset of a GitHub user event used for correlation
+type githubEvent struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Repo      struct {
+		Name string `json:"name"`
+	} `json:"repo"`
+	Payload struct {
+		Size   int    `json:"size"`   // PushEvent: number of commits
+		Action string `json:"action"` // PullRequestEvent: opened, closed, ...
+	} `json:"payload"`
+}
+
+// githubActivity summarizes GitHub activity during one session
+type githubActivity struct {
+	Commits      int
+	PullRequests int
+}
+
+// String formats activity as e.g. "3 commits, 1 PR"
+func (a githubActivity) String() string {
+	var parts []string
+	if a.Commits == 1 {
+		parts = append(parts, "1 commit")
+	} else if a.Commits > 1 {
+		parts = append(parts, fmt.Sprintf("%d commits", a.Commits))
+	}
+	if a.PullRequests == 1 {
+		parts = append(parts, "1 PR")
+	} else if a.PullRequests > 1 {
+		parts = append(parts, fmt.Sprintf("%d PRs", a.PullRequests))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// githubGet performs an authenticated GET request under policy and decodes the JSON response into out
+func githubGet(policy RetryPolicy, token, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, githubAPIURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := doWithRetry(policy, githubHTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("error contacting GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error parsing GitHub response: %w", err)
+	}
+	return nil
+}
+
+// fetchGitHubEvents returns the user's public and private events since the given time
+// GitHub only keeps the last 90 days (at most 300 events) available through this API
+func fetchGitHubEvents(policy RetryPolicy, config GitHubConfig, since time.Time) ([]githubEvent, error) {
+	user := config.User
+	if user == "" {
+		var me struct {
+			Login string `json:"login"`
+		}
+		if err := githubGet(policy, config.Token, "/user", &me); err != nil {
+			return nil, err
+		}
+		user = me.Login
+	}
+
+	var events []githubEvent
+	for page := 1; page <= 3; page++ {
+		var batch []githubEvent
+		if err := githubGet(policy, config.Token, fmt.Sprintf("/users/%s/events?per_page=100&page=%d", user, page), &batch); err != nil {
+			return nil, err
+		}
+		done := len(batch) < 100
+		for _, event := range batch {
+			if event.CreatedAt.Before(since) {
+				done = true
+				continue
+			}
+			events = append(events, event)
+		}
+		if done {
+			break
+		}
+	}
+	return events, nil
+}
+
+// correlateGitHub counts commits and pull requests made during each session
+// Activity up to grace after a session ends is attributed to it, since pushes usually follow the work
+func correlateGitHub(records []SessionRecord, events []githubEvent, repos []string, grace time.Duration) []githubActivity {
+	allowed := make(map[string]bool)
+	for _, repo := range repos {
+		allowed[strings.ToLower(repo)] = true
+	}
+
+	activity := make([]githubActivity, len(records))
+	for _, event := range events {
+		if len(allowed) > 0 && !allowed[strings.ToLower(event.Repo.Name)] {
+			continue
+		}
+		for i, record := range records {
+			if event.CreatedAt.Before(record.StartTime) || event.CreatedAt.After(record.EndTime.Add(grace)) {
+				continue
+			}
+			switch event.Type {
+			case "PushEvent":
+				activity[i].Commits += event.Payload.Size
+			case "PullRequestEvent":
+				if event.Payload.Action == "opened" {
+					activity[i].PullRequests++
+				}
+			}
+			break
+		}
+	}
+	return activity
+}
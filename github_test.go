@@ -0,0 +1,100 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// pushEvent builds a PushEvent for tests
+func pushEvent(repo string, at time.Time, commits int) githubEvent {
+	event := githubEvent{Type: "PushEvent", CreatedAt: at}
+	event.Repo.Name = repo
+	event.Payload.Size = commits
+	return event
+}
+
+// TestCorrelateGitHub tests attribution of events to session windows
+func TestCorrelateGitHub(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	sessions := []SessionRecord{
+		{Kind: RecordSession, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(10 * time.Hour)},
+		{Kind: RecordSession, StartTime: day.Add(14 * time.Hour), EndTime: day.Add(15 * time.Hour)},
+	}
+
+	pr := githubEvent{Type: "PullRequestEvent", CreatedAt: day.Add(14*time.Hour + 30*time.Minute)}
+	pr.Repo.Name = "me/app"
+	pr.Payload.Action = "opened"
+	closedPR := pr
+	closedPR.Payload.Action = "closed"
+
+	events := []githubEvent{
+		pushEvent("me/app", day.Add(9*time.Hour+30*time.Minute), 2),
+		pushEvent("me/app", day.Add(10*time.Hour+20*time.Minute), 1), // within grace period
+		pushEvent("me/app", day.Add(11*time.Hour), 5),                // outside any session
+		pushEvent("other/lib", day.Add(14*time.Hour+10*time.Minute), 4),
+		pr,
+		closedPR,
+	}
+
+	activity := correlateGitHub(sessions, events, nil, 30*time.Minute)
+	if activity[0].Commits != 3 || activity[0].PullRequests != 0 {
+		t.Errorf("Unexpected first session activity: %+v", activity[0])
+	}
+	if activity[1].Commits != 4 || activity[1].PullRequests != 1 {
+		t.Errorf("Unexpected second session activity: %+v", activity[1])
+	}
+
+	activity = correlateGitHub(sessions, events, []string{"Me/App"}, 30*time.Minute)
+	if activity[1].Commits != 0 {
+		t.Errorf("Expected repo filter to exclude other/lib, got %+v", activity[1])
+	}
+
+	if s := activity[0].String(); s != "3 commits" {
+		t.Errorf("Unexpected activity string %q", s)
+	}
+	if s := (githubActivity{Commits: 1, PullRequests: 2}).String(); s != "1 commit, 2 PRs" {
+		t.Errorf("Unexpected activity string %q", s)
+	}
+	if s := (githubActivity{}).String(); s != "-" {
+		t.Errorf("Unexpected empty activity string %q", s)
+	}
+}
+
+// TestFetchGitHubEvents tests user resolution and paging stop at the since boundary
+func TestFetchGitHubEvents(t *testing.T) {
+	since := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("Missing token, got %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprint(w, `{"login":"octocat"}`)
+		case "/users/octocat/events":
+			json.NewEncoder(w).Encode([]githubEvent{
+				pushEvent("a/b", since.Add(time.Hour), 1),
+				pushEvent("a/b", since.Add(-time.Hour), 1),
+			})
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	original := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = original }()
+
+	events, err := fetchGitHubEvents(RetryPolicy{}, GitHubConfig{Token: "tok"}, since)
+	if err != nil {
+		t.Fatalf("fetchGitHubEvents() returned error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event after since, got %d", len(events))
+	}
+}
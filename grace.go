@@ -0,0 +1,128 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AutomationConfig represents settings for modes applied without user interaction
+type AutomationConfig struct {
+	GraceSeconds int    `yaml:"grace_seconds" doc:"Countdown before an automatic apply (negative disables)" default:"10"`
+	Recovery     string `yaml:"recovery" doc:"Handling of a session left by a crash" enum:"ask,restore,resume,discard" default:"ask"`
+}
+
+const defaultGraceSeconds = 10
+
+// graceSeconds returns the configured countdown length, or 0 if disabled
+func (a AutomationConfig) graceSeconds() int {
+	if a.GraceSeconds < 0 {
+		return 0
+	}
+	if a.GraceSeconds == 0 {
+		return defaultGraceSeconds
+	}
+	return a.GraceSeconds
+}
+
+// isCancelCommand reports whether an input line cancels a pending automatic apply
+func isCancelCommand(command string) bool {
+	switch strings.ToLower(strings.TrimSpace(command)) {
+	case "c", "cancel", "q", "quit", "n", "no":
+		return true
+	}
+	return false
+}
+
+// graceCountdowns holds the countdowns in progress so the daemon can cancel them
+// for "focusmode daemon cancel", whose loops have no terminal to type c into
+type graceCountdowns struct {
+	mu      sync.Mutex
+	next    int
+	pending map[int]graceCountdown
+}
+
+// graceCountdown is one countdown in progress
+type graceCountdown struct {
+	action    string
+	cancelled chan struct{}
+}
+
+// pendingGrace holds the countdowns of this process
+var pendingGrace = &graceCountdowns{}
+
+// add registers a countdown; the returned channel is closed if it is cancelled, and done
+// unregisters it
+func (g *graceCountdowns) add(action string) (<-chan struct{}, func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.pending == nil {
+		g.pending = make(map[int]graceCountdown)
+	}
+	id := g.next
+	g.next++
+	countdown := graceCountdown{action: action, cancelled: make(chan struct{})}
+	g.pending[id] = countdown
+	return countdown.cancelled, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		delete(g.pending, id)
+	}
+}
+
+// cancelAll cancels every countdown in progress and returns their actions
+func (g *graceCountdowns) cancelAll() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var actions []string
+	for id, countdown := range g.pending {
+		close(countdown.cancelled)
+		actions = append(actions, countdown.action)
+		delete(g.pending, id)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// waitGracePeriod counts down before an automatic apply, one second per tick
+// It returns false if a cancel command is received, or the countdown is cancelled through
+// the daemon, before the countdown ends
+func waitGracePeriod(action string, seconds int, commands <-chan string, tick <-chan time.Time, out io.Writer) bool {
+	var cancelled <-chan struct{}
+	if seconds > 0 {
+		var done func()
+		cancelled, done = pendingGrace.add(action)
+		defer done()
+	}
+	for left := seconds; left > 0; {
+		if !accessible {
+			fmt.Fprintf(out, "\r⏳ %s in %ds (type c + Enter to cancel) ", action, left)
+		} else if left == seconds {
+			// Announced once rather than redrawn every second
+			fmt.Fprintf(out, "%s in %d seconds. Type c and press Enter to cancel.", action, left)
+		}
+		select {
+		case <-tick:
+			left--
+		case <-cancelled:
+			fmt.Fprintf(out, "\n%sCancelled: %s\n", glyph("✋ "), action)
+			return false
+		case command, ok := <-commands:
+			if !ok {
+				commands = nil
+				continue
+			}
+			if isCancelCommand(command) {
+				fmt.Fprintf(out, "\n%sCancelled: %s\n", glyph("✋ "), action)
+				return false
+			}
+		}
+	}
+	if seconds > 0 {
+		fmt.Fprintln(out)
+	}
+	return true
+}
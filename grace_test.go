@@ -0,0 +1,75 @@
+package focusmode
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGraceSeconds tests the default and disabled countdown lengths
+func TestGraceSeconds(t *testing.T) {
+	tests := map[int]int{0: defaultGraceSeconds, 5: 5, -1: 0}
+	for configured, want := range tests {
+		if got := (AutomationConfig{GraceSeconds: configured}).graceSeconds(); got != want {
+			t.Errorf("graceSeconds() with %d = %d, want %d", configured, got, want)
+		}
+	}
+}
+
+// TestWaitGracePeriodCompletes tests that the countdown proceeds after all ticks
+func TestWaitGracePeriodCompletes(t *testing.T) {
+	tick := make(chan time.Time, 3)
+	for i := 0; i < 3; i++ {
+		tick <- time.Now()
+	}
+	commands := make(chan string, 1)
+	commands <- "x" // unrelated input is ignored
+	close(commands)
+
+	if !waitGracePeriod("Applying focusmode", 3, commands, tick, io.Discard) {
+		t.Error("Expected countdown to proceed")
+	}
+	if len(tick) != 0 {
+		t.Errorf("Expected all ticks to be consumed, %d left", len(tick))
+	}
+}
+
+// TestWaitGracePeriodCancel tests that a cancel command aborts the countdown
+func TestWaitGracePeriodCancel(t *testing.T) {
+	tick := make(chan time.Time) // never ticks
+	commands := make(chan string, 1)
+	commands <- "c"
+
+	if waitGracePeriod("Applying focusmode", 10, commands, tick, io.Discard) {
+		t.Error("Expected countdown to be cancelled")
+	}
+}
+
+// TestWaitGracePeriodDisabled tests that a zero countdown proceeds immediately
+func TestWaitGracePeriodDisabled(t *testing.T) {
+	if !waitGracePeriod("Applying focusmode", 0, nil, nil, io.Discard) {
+		t.Error("Expected zero-length countdown to proceed")
+	}
+}
+
+// TestWaitGracePeriodCancelAll tests that the daemon can cancel a countdown in progress
+func TestWaitGracePeriodCancelAll(t *testing.T) {
+	result := make(chan bool)
+	go func() { result <- waitGracePeriod("Applying focusmode", 10, nil, make(chan time.Time), io.Discard) }()
+
+	var cancelled []string
+	for deadline := time.Now().Add(5 * time.Second); len(cancelled) == 0 && time.Now().Before(deadline); {
+		cancelled = pendingGrace.cancelAll()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !reflect.DeepEqual(cancelled, []string{"Applying focusmode"}) {
+		t.Fatalf("cancelAll() = %v", cancelled)
+	}
+	if <-result {
+		t.Error("Expected the countdown to be cancelled")
+	}
+	if cancelled := pendingGrace.cancelAll(); len(cancelled) != 0 {
+		t.Errorf("Expected nothing left to cancel, got %v", cancelled)
+	}
+}
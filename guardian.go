@@ -0,0 +1,199 @@
+package focusmode
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GuardianPolicy protects modes with a guardian PIN and keeps a session report for the guardian
+// It is part of the machine policy so the user's profile cannot change it
+type GuardianPolicy struct {
+	ProtectedModes []string `yaml:"protected_modes"` // Modes that need the guardian PIN to apply
+	PINHash        string   `yaml:"pin_hash"`        // Output of "focusmode guardian hash-pin"
+	Report         bool     `yaml:"report"`          // Log focus sessions to the guardian report
+	ReportPath     string   `yaml:"report_path"`     // Report location (default: guardian.jsonl next to the policy)
+}
+
+const (
+	pinHashScheme     = "sha256-iter"
+	pinHashIterations = 200000
+	maxPINAttempts    = 3
+)
+
+// pinRetryDelay slows down guessing after each wrong PIN, overridden in tests
+var pinRetryDelay = time.Second
+
+// hashPIN derives a salted, iterated SHA-256 hash of a PIN
+func hashPIN(pin string, salt []byte, iterations int) string {
+	sum := sha256.Sum256(append(append([]byte(nil), salt...), pin...))
+	for i := 1; i < iterations; i++ {
+		sum = sha256.Sum256(append(sum[:], salt...))
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", pinHashScheme, iterations, hex.EncodeToString(salt), hex.EncodeToString(sum[:]))
+}
+
+// newPINHash hashes a PIN with a random salt
+func newPINHash(pin string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error generating salt: %w", err)
+	}
+	return hashPIN(pin, salt, pinHashIterations), nil
+}
+
+// checkPIN reports whether pin matches a hash created by newPINHash
+func checkPIN(pin string, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != pinHashScheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashPIN(pin, salt, iterations)), []byte(encoded)) == 1
+}
+
+// guardian returns the guardian policy in effect, or nil
+func (c *Config) guardian() *GuardianPolicy {
+	if c == nil || c.Policy == nil {
+		return nil
+	}
+	g := &c.Policy.Guardian
+	if len(g.ProtectedModes) == 0 && !g.Report {
+		return nil
+	}
+	return g
+}
+
+// guardianProtects reports whether applying modeName needs the guardian PIN
+func (c *Config) guardianProtects(modeName string) bool {
+	g := c.guardian()
+	return g != nil && containsFold(g.ProtectedModes, modeName)
+}
+
+// guardianReportPath returns where guardian-visible session records are written
+func (c *Config) guardianReportPath() string {
+	g := c.guardian()
+	if g == nil || !g.Report {
+		return ""
+	}
+	if g.ReportPath != "" {
+		return g.ReportPath
+	}
+	return filepath.Join(filepath.Dir(c.Policy.path), "guardian.jsonl")
+}
+
+// requireGuardianPIN asks for the guardian PIN if any of the modes is protected
+func (c *Config) requireGuardianPIN(modes []string, answers <-chan string, out io.Writer) error {
+	var protected []string
+	for _, mode := range modes {
+		if c.guardianProtects(mode) {
+			protected = append(protected, mode)
+		}
+	}
+	if len(protected) == 0 {
+		return nil
+	}
+
+	hash := c.guardian().PINHash
+	if hash == "" {
+		return fmt.Errorf("%s is protected by the guardian but no PIN is configured", strings.Join(protected, ", "))
+	}
+	for attempt := 1; attempt <= maxPINAttempts; attempt++ {
+		fmt.Fprintf(out, "%sGuardian PIN required for %s: ", glyph("🔑 "), strings.Join(protected, ", "))
+		pin, ok := <-answers
+		if !ok {
+			fmt.Fprintln(out)
+			break
+		}
+		if checkPIN(strings.TrimSpace(pin), hash) {
+			return nil
+		}
+		fmt.Fprintln(out, "Incorrect PIN")
+		time.Sleep(time.Duration(attempt) * pinRetryDelay)
+	}
+	return fmt.Errorf("guardian PIN not accepted")
+}
+
+// runGuardianCommand handles the "guardian" subcommand
+func runGuardianCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode guardian hash-pin")
+		fmt.Fprintln(os.Stderr, "       focusmode guardian report [-weeks-ago N]")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "hash-pin":
+		fmt.Print("New guardian PIN: ")
+		pin, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		pin = strings.TrimSpace(pin)
+		if err != nil && pin == "" {
+			fmt.Fprintln(os.Stderr, "\nError: no PIN given")
+			os.Exit(1)
+		}
+		if len(pin) < 4 {
+			fmt.Fprintln(os.Stderr, "Error: the PIN must have at least 4 characters")
+			os.Exit(1)
+		}
+		hash, err := newPINHash(pin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nAdd this to the guardian section of %s:\n\n  pin_hash: \"%s\"\n", machinePolicyPath, hash)
+	case "report":
+		flags := flag.NewFlagSet("guardian report", flag.ExitOnError)
+		configPath := flags.String("config", "profile.yml", "Path to configuration file")
+		weeksAgo := flags.Int("weeks-ago", 0, "Report on an earlier week (0 = this week)")
+		flags.Parse(args[1:])
+
+		config, err := readConfig(*configPath)
+		if err != nil {
+			config = &Config{}
+			config.applyPolicy(mustLoadPolicy())
+		}
+		path := config.guardianReportPath()
+		if path == "" {
+			fmt.Fprintln(os.Stderr, "Guardian reporting is not enabled in the machine policy (guardian.report: true)")
+			os.Exit(1)
+		}
+		records, err := readHistory(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading guardian report: %v\n", err)
+			os.Exit(1)
+		}
+		start := startOfWeek(time.Now()).AddDate(0, 0, -7*(*weeksAgo))
+		end := start.AddDate(0, 0, 7)
+		printWeeklyReport(sessionsBetween(records, start, end), start, end, nil)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown guardian command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// mustLoadPolicy loads the machine policy or exits
+func mustLoadPolicy() *Policy {
+	policy, err := loadPolicy(machinePolicyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return policy
+}
@@ -0,0 +1,105 @@
+package focusmode
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPINHash tests hashing and checking guardian PINs
+func TestPINHash(t *testing.T) {
+	hash, err := newPINHash("4321")
+	if err != nil {
+		t.Fatalf("newPINHash() returned error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "sha256-iter$") || strings.Contains(hash, "4321") {
+		t.Errorf("Unexpected hash format: %s", hash)
+	}
+	if !checkPIN("4321", hash) {
+		t.Error("Expected correct PIN to match")
+	}
+	if checkPIN("1234", hash) {
+		t.Error("Expected wrong PIN not to match")
+	}
+	for _, bad := range []string{"", "plain", "sha256-iter$x$00$00", "md5$1$00$00"} {
+		if checkPIN("4321", bad) {
+			t.Errorf("Expected malformed hash %q not to match", bad)
+		}
+	}
+
+	other, _ := newPINHash("4321")
+	if other == hash {
+		t.Error("Expected different salts for each hash")
+	}
+}
+
+// guardianConfig returns a config whose policy protects gamemode with PIN 4321
+func guardianConfig(t *testing.T) *Config {
+	t.Helper()
+	hash := hashPIN("4321", []byte("salt"), 10)
+	return &Config{
+		Modes: map[string]ModeConfig{"focusmode": {}, "gamemode": {}},
+		Policy: &Policy{
+			path:     filepath.Join("/etc", "focusmode", "policy.yml"),
+			Guardian: GuardianPolicy{ProtectedModes: []string{"GameMode"}, PINHash: hash, Report: true},
+		},
+	}
+}
+
+// TestRequireGuardianPIN tests that protected modes need the PIN and others don't
+func TestRequireGuardianPIN(t *testing.T) {
+	pinRetryDelay = 0
+	config := guardianConfig(t)
+
+	if err := config.requireGuardianPIN([]string{"focusmode"}, nil, io.Discard); err != nil {
+		t.Errorf("Expected unprotected mode to need no PIN, got %v", err)
+	}
+
+	answers := make(chan string, 2)
+	answers <- "0000"
+	answers <- "4321"
+	if err := config.requireGuardianPIN([]string{"focusmode", "gamemode"}, answers, io.Discard); err != nil {
+		t.Errorf("Expected correct PIN on second attempt to be accepted, got %v", err)
+	}
+
+	answers = make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		answers <- "0000"
+	}
+	if err := config.requireGuardianPIN([]string{"gamemode"}, answers, io.Discard); err == nil {
+		t.Error("Expected three wrong PINs to be rejected")
+	}
+
+	closed := make(chan string)
+	close(closed)
+	if err := config.requireGuardianPIN([]string{"gamemode"}, closed, io.Discard); err == nil {
+		t.Error("Expected missing input to be rejected")
+	}
+
+	config.Policy.Guardian.PINHash = ""
+	if err := config.requireGuardianPIN([]string{"gamemode"}, nil, io.Discard); err == nil {
+		t.Error("Expected protected mode without a configured PIN to be rejected")
+	}
+}
+
+// TestGuardianReportPath tests the default and configured report locations
+func TestGuardianReportPath(t *testing.T) {
+	config := guardianConfig(t)
+	if got := config.guardianReportPath(); got != filepath.Join("/etc", "focusmode", "guardian.jsonl") {
+		t.Errorf("Unexpected default report path: %s", got)
+	}
+
+	config.Policy.Guardian.ReportPath = "/srv/report.jsonl"
+	if got := config.guardianReportPath(); got != "/srv/report.jsonl" {
+		t.Errorf("Unexpected configured report path: %s", got)
+	}
+
+	config.Policy.Guardian.Report = false
+	if got := config.guardianReportPath(); got != "" {
+		t.Errorf("Expected no report path when reporting is off, got %s", got)
+	}
+	if got := (&Config{}).guardianReportPath(); got != "" {
+		t.Errorf("Expected no report path without a policy, got %s", got)
+	}
+}
@@ -0,0 +1,470 @@
+package focusmode
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guiFiles holds the page of the browser GUI
+//
+//go:embed gui
+var guiFiles embed.FS
+
+// guiTokenHeader carries the token of a GUI server with every API request; pages on other sites
+// can't send it, so they can't drive the GUI from the user's browser
+const guiTokenHeader = "X-FocusMode-Token"
+
+// guiRecentSessions is how many finished sessions the dashboard lists
+const guiRecentSessions = 5
+
+// guiServer serves the browser GUI: a card per mode with apply and restore buttons, desktop files
+// dragged onto modes to add them to profile.yml, and a dashboard of the running session
+// Applies, restores and sessions run as child processes, as the daemon runs them
+type guiServer struct {
+	configPath     string
+	categoriesPath string
+	token          string
+	run            commandRunner
+	desktopFiles   func() ([]string, error)
+	mu             sync.Mutex // serializes commands and config edits
+}
+
+// guiMode is a mode card
+type guiMode struct {
+	Name        string   `json:"name"`
+	Destination string   `json:"destination"`
+	Shortcuts   []string `json:"shortcuts"`          // As listed in the config
+	MoveAll     bool     `json:"move_all,omitempty"` // The mode moves every desktop file, so files aren't dropped on it
+	Applied     bool     `json:"applied"`
+	Editable    bool     `json:"editable"` // Defined in profile.yml rather than built in
+	Protected   bool     `json:"protected,omitempty"`
+}
+
+// guiSession is the running session on the dashboard
+type guiSession struct {
+	*activeSession
+	RemainingSeconds int64 `json:"remaining_seconds"`
+}
+
+// guiState is everything the page shows, fetched again every few seconds
+type guiState struct {
+	DefaultMode  string          `json:"default_mode"`
+	Modes        []guiMode       `json:"modes"`
+	Desktop      []string        `json:"desktop"`
+	Session      *guiSession     `json:"session"`
+	Strict       bool            `json:"strict,omitempty"`
+	FocusedToday int64           `json:"focused_today_seconds"`
+	Recent       []SessionRecord `json:"recent"`
+	Warnings     []string        `json:"warnings,omitempty"`
+}
+
+// guiRequest is the body of the GUI's POST requests
+type guiRequest struct {
+	Mode    string `json:"mode"`
+	File    string `json:"file"`
+	Remove  bool   `json:"remove"`
+	Minutes int    `json:"minutes"`
+	Goal    string `json:"goal"`
+}
+
+// handler returns the GUI's page and API routes
+func (s *guiServer) handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("/api/state", s.handleState)
+	api.HandleFunc("/api/apply", s.handleApply)
+	api.HandleFunc("/api/restore", s.handleApply)
+	api.HandleFunc("/api/assign", s.handleAssign)
+	api.HandleFunc("/api/session/", s.handleSession)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", s.authenticate(api))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		page, err := guiFiles.ReadFile("gui/index.html")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+		w.Write(page)
+	})
+	return mux
+}
+
+// authenticate rejects API requests without the server's token
+func (s *guiServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(guiTokenHeader)), []byte(s.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, commandResponse{Error: "invalid or missing token; open the address focusmode gui printed"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeGUIRequest reads the body of a POST request, answering the request itself on failure
+func decodeGUIRequest(w http.ResponseWriter, r *http.Request) (guiRequest, bool) {
+	var req guiRequest
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use POST"})
+		return req, false
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, commandResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// state gathers the modes, desktop files, running session and recent history
+func (s *guiServer) state() (*guiState, error) {
+	config, err := loadConfig(s.configPath)
+	if err != nil {
+		return nil, err
+	}
+	status, err := currentStatus()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool)
+	for _, m := range status.Modes {
+		applied[m.Mode] = true
+	}
+	// Only modes written in profile.yml can be edited; built-in ones exist without it
+	editable := make(map[string]bool)
+	state := &guiState{DefaultMode: config.DefaultMode, Strict: config.isStrict()}
+	if editor, err := newConfigEditor(s.configPath, s.categoriesPath, nil, io.Discard); err == nil {
+		for name := range editor.config.Modes {
+			editable[name] = true
+		}
+	} else {
+		state.Warnings = append(state.Warnings, err.Error())
+	}
+
+	for _, name := range config.getAvailableModes() {
+		mode := config.Modes[name]
+		destination := mode.Destination
+		if modeConfig, err := config.getModeConfig(name); err == nil {
+			destination = modeConfig.Destination
+		} else {
+			state.Warnings = append(state.Warnings, err.Error())
+		}
+		state.Modes = append(state.Modes, guiMode{
+			Name:        name,
+			Destination: destination,
+			Shortcuts:   append([]string{}, mode.Shortcuts...),
+			MoveAll:     mode.MoveAll,
+			Applied:     applied[name],
+			Editable:    editable[name],
+			Protected:   config.guardianProtects(name),
+		})
+	}
+	sort.Slice(state.Modes, func(i, j int) bool { return state.Modes[i].Name < state.Modes[j].Name })
+
+	if state.Desktop, err = s.desktopFiles(); err != nil {
+		state.Warnings = append(state.Warnings, err.Error())
+	}
+	if state.Desktop == nil {
+		state.Desktop = []string{}
+	}
+
+	now := time.Now()
+	if status.Session != nil {
+		state.Session = &guiSession{activeSession: status.Session, RemainingSeconds: int64(status.Session.remaining(now).Seconds())}
+	}
+	records, err := loadHistory()
+	if err != nil {
+		state.Warnings = append(state.Warnings, err.Error())
+	}
+	state.Recent = []SessionRecord{}
+	midnight := at(now, 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Kind != RecordSession {
+			continue
+		}
+		if !record.StartTime.Before(midnight) {
+			state.FocusedToday += record.FocusedSeconds
+		}
+		if len(state.Recent) < guiRecentSessions {
+			state.Recent = append(state.Recent, record)
+		}
+	}
+	return state, nil
+}
+
+// handleState returns the page's state
+func (s *guiServer) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, commandResponse{Error: "use GET"})
+		return
+	}
+	state, err := s.state()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// knownMode checks that mode is a configured mode, so requests can't pass flags to commands
+func (s *guiServer) knownMode(mode string) (*Config, error) {
+	config, err := loadConfig(s.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := config.Modes[mode]; !exists {
+		return nil, fmt.Errorf("mode '%s' %w. Available modes: %v", mode, errModeNotFound, config.getAvailableModes())
+	}
+	return config, nil
+}
+
+// handleApply applies or restores a mode
+func (s *guiServer) handleApply(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeGUIRequest(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.knownMode(req.Mode); err != nil {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: err.Error()})
+		return
+	}
+	args := []string{"-mode", req.Mode}
+	if strings.HasSuffix(r.URL.Path, "/restore") {
+		args = []string{"-restore", "-mode", req.Mode}
+	}
+
+	ctx, span := startSpan(r.Context(), "gui "+strings.TrimPrefix(r.URL.Path, "/api/"))
+	span.set("focusmode.mode", req.Mode)
+	s.mu.Lock()
+	output, err := s.run(ctx, args...)
+	s.mu.Unlock()
+	span.finish(err)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Output: output, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Output: output})
+}
+
+// handleAssign adds a desktop file to a mode's shortcuts in profile.yml, or with remove takes it off
+// The file is edited like "config edit" edits it, so comments in other sections are kept
+func (s *guiServer) handleAssign(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeGUIRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.File == "" || strings.ContainsAny(req.File, `/\`) {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: fmt.Sprintf("invalid file name: %q", req.File)})
+		return
+	}
+	if err := guardWrite("edit " + s.configPath); err != nil {
+		writeJSON(w, http.StatusForbidden, commandResponse{Error: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	editor, err := newConfigEditor(s.configPath, s.categoriesPath, nil, io.Discard)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	mode, exists := editor.config.Modes[req.Mode]
+	if !exists {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: fmt.Sprintf("mode '%s' isn't defined in %s", req.Mode, s.configPath)})
+		return
+	}
+
+	var shortcuts []string
+	for _, name := range mode.Shortcuts {
+		if nfc(name) != nfc(req.File) {
+			shortcuts = append(shortcuts, name)
+		}
+	}
+	if listed := len(shortcuts) < len(mode.Shortcuts); listed != req.Remove {
+		writeJSON(w, http.StatusOK, commandResponse{Output: "Nothing to change"})
+		return
+	}
+	if !req.Remove {
+		shortcuts = append(shortcuts, req.File)
+	}
+	mode.Shortcuts = shortcuts
+	editor.config.Modes[req.Mode] = mode
+
+	profile, _, err := editor.encode()
+	if err == nil {
+		err = writeFile(s.configPath, profile, 0644)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	if req.Remove {
+		writeJSON(w, http.StatusOK, commandResponse{Output: fmt.Sprintf("Removed %s from %s", req.File, req.Mode)})
+		return
+	}
+	writeJSON(w, http.StatusOK, commandResponse{Output: fmt.Sprintf("Added %s to %s", req.File, req.Mode)})
+}
+
+// guiSessionCommands maps the dashboard's session buttons to the commands typed during a session
+var guiSessionCommands = map[string]string{"pause": "p", "extend": "e", "stop": "q", "widget": "w"}
+
+// handleSession starts a session, or controls the running one like "session pause" does
+func (s *guiServer) handleSession(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeGUIRequest(w, r)
+	if !ok {
+		return
+	}
+	action := strings.TrimPrefix(r.URL.Path, "/api/session/")
+	if action == "start" {
+		s.startSession(w, req)
+		return
+	}
+	command, ok := guiSessionCommands[action]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, commandResponse{Error: fmt.Sprintf("unknown session command: %s", action)})
+		return
+	}
+
+	path, err := activeSessionPath()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	state, err := readActiveSession(path)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	if state == nil {
+		writeJSON(w, http.StatusConflict, commandResponse{Error: "no focus session is running"})
+		return
+	}
+	if (command == "p" || command == "q") && state.Strict {
+		writeJSON(w, http.StatusConflict, commandResponse{Error: "strict mode: the session can't be paused or ended early"})
+		return
+	}
+	if err := sendSessionCommand(command); err != nil {
+		writeJSON(w, http.StatusInternalServerError, commandResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, commandResponse{Output: fmt.Sprintf("Sent %s to the %s session", action, state.Mode)})
+}
+
+// startSession starts a session in its own process, as the daemon starts one from a hotkey
+func (s *guiServer) startSession(w http.ResponseWriter, req guiRequest) {
+	config, err := s.knownMode(req.Mode)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: err.Error()})
+		return
+	}
+	if req.Minutes <= 0 {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: fmt.Sprintf("duration must be positive, got: %d minutes", req.Minutes)})
+		return
+	}
+	if err := config.checkSessionDuration(req.Minutes); err != nil {
+		writeJSON(w, http.StatusBadRequest, commandResponse{Error: err.Error()})
+		return
+	}
+	// Nobody is at the terminal of the session's process to type the PIN
+	if config.guardianProtects(req.Mode) {
+		writeJSON(w, http.StatusForbidden, commandResponse{Error: fmt.Sprintf("%s is protected by the guardian; start it from a terminal", req.Mode)})
+		return
+	}
+	if isSessionRunning() {
+		writeJSON(w, http.StatusConflict, commandResponse{Error: "a focus session is already running"})
+		return
+	}
+
+	args := []string{"session", "start", "-mode", req.Mode, "-duration", strconv.Itoa(req.Minutes)}
+	if goal := strings.TrimSpace(req.Goal); goal != "" {
+		args = append(args, "-goal", goal)
+	}
+	ctx, span := startSpan(context.Background(), "gui session")
+	span.set("focusmode.mode", req.Mode)
+	go func() {
+		output, err := s.run(ctx, args...)
+		span.finish(err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running the %s session: %v\n%s", req.Mode, err, output)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, commandResponse{Output: fmt.Sprintf("Starting a %dm session in %s", req.Minutes, req.Mode)})
+}
+
+// runGUICommand handles "focusmode gui", which serves the GUI on this machine and opens it in the browser
+func runGUICommand(args []string) {
+	flags := flag.NewFlagSet("gui", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	addr := flags.String("addr", "127.0.0.1:0", "Address to listen on; a port of 0 picks a free one")
+	noOpen := flags.Bool("no-open", false, "Print the address without opening the browser")
+	flags.Parse(args)
+
+	if !isLoopbackAddress(*addr) {
+		fmt.Fprintf(os.Stderr, "Error: the GUI only listens on this machine (127.0.0.1 or localhost), not %s\n", *addr)
+		os.Exit(1)
+	}
+	if _, err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	token, err := generateToken()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	server := &guiServer{
+		configPath:     *configPath,
+		categoriesPath: *categoriesPath,
+		token:          token,
+		run:            selfRunner(*configPath),
+		desktopFiles:   getAllDesktopShortcuts,
+	}
+	serveLocally("FocusMode GUI", *addr, token, *noOpen, server.handler())
+}
+
+// serveLocally serves handler on addr until the process is stopped, printing the page's address
+// and opening it in the browser; the token goes in the address's fragment, where the page reads it
+func serveLocally(name, addr, token string, noOpen bool, handler http.Handler) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	url := fmt.Sprintf("http://%s/#%s", listener.Addr(), token)
+	fmt.Printf("%s at %s (Ctrl+C to stop)\n", name, url)
+	if !noOpen {
+		name, openArgs := launchCommand(runtime.GOOS, url)
+		if err := exec.Command(name, openArgs...).Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't open the browser: %v\n", err)
+		}
+	}
+	if err := http.Serve(listener, handler); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,148 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestGUIServer writes a profile to a temp dir and serves the GUI on it, recording the
+// commands it runs instead of running them
+func newTestGUIServer(t *testing.T, profile string) (*httptest.Server, string, *[][]string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	configPath := filepath.Join(dir, "profile.yml")
+	if err := os.WriteFile(configPath, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var ran [][]string
+	gui := &guiServer{
+		configPath:     configPath,
+		categoriesPath: filepath.Join(dir, "categories.yml"),
+		token:          "secret",
+		run: func(ctx context.Context, args ...string) (string, error) {
+			ran = append(ran, args)
+			return "ok", nil
+		},
+		desktopFiles: func() ([]string, error) { return []string{"Steam.lnk", "Notes.txt"}, nil },
+	}
+	server := httptest.NewServer(gui.handler())
+	t.Cleanup(server.Close)
+	return server, configPath, &ran
+}
+
+// guiPost sends a POST to the GUI API with the token and decodes the response
+func guiPost(t *testing.T, server *httptest.Server, path, body string) (int, commandResponse) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+	req.Header.Set(guiTokenHeader, "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result commandResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// TestGUIToken tests that the page is served to anyone but the API needs the token
+func TestGUIToken(t *testing.T) {
+	server, _, _ := newTestGUIServer(t, "modes:\n  work:\n    destination: Work\n")
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the page, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Get(server.URL + "/api/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/state", nil)
+	req.Header.Set(guiTokenHeader, "secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var state guiState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !reflect.DeepEqual(state.Desktop, []string{"Steam.lnk", "Notes.txt"}) {
+		t.Errorf("Expected the state with the desktop files, got %d %+v", resp.StatusCode, state)
+	}
+}
+
+// TestGUIApply tests that apply and restore run the mode's command and unknown modes are refused
+func TestGUIApply(t *testing.T) {
+	server, _, ran := newTestGUIServer(t, "modes:\n  work:\n    destination: Work\n")
+
+	if status, _ := guiPost(t, server, "/api/apply", `{"mode":"work"}`); status != http.StatusOK {
+		t.Errorf("Expected apply to succeed, got %d", status)
+	}
+	if status, _ := guiPost(t, server, "/api/restore", `{"mode":"work"}`); status != http.StatusOK {
+		t.Errorf("Expected restore to succeed, got %d", status)
+	}
+	if status, _ := guiPost(t, server, "/api/apply", `{"mode":"-restore-all"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown mode to be refused, got %d", status)
+	}
+	want := [][]string{{"-mode", "work"}, {"-restore", "-mode", "work"}}
+	if !reflect.DeepEqual(*ran, want) {
+		t.Errorf("Expected commands %v, got %v", want, *ran)
+	}
+}
+
+// TestGUIAssign tests adding and removing a desktop file from a mode, keeping comments in profile.yml
+func TestGUIAssign(t *testing.T) {
+	server, configPath, _ := newTestGUIServer(t, "# my settings\nmodes:\n  work:\n    destination: Work\n    shortcuts: [Steam.lnk]\n")
+
+	if status, result := guiPost(t, server, "/api/assign", `{"mode":"work","file":"Notes.txt"}`); status != http.StatusOK {
+		t.Fatalf("Expected assign to succeed, got %d %+v", status, result)
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Modes["work"].Shortcuts; !reflect.DeepEqual(got, []string{"Steam.lnk", "Notes.txt"}) {
+		t.Errorf("Expected Notes.txt added, got %v", got)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "# my settings") {
+		t.Errorf("Expected the comment kept, got:\n%s", data)
+	}
+
+	if status, _ := guiPost(t, server, "/api/assign", `{"mode":"work","file":"Steam.lnk","remove":true}`); status != http.StatusOK {
+		t.Fatalf("Expected removing to succeed, got %d", status)
+	}
+	config, _ = loadConfig(configPath)
+	if got := config.Modes["work"].Shortcuts; !reflect.DeepEqual(got, []string{"Notes.txt"}) {
+		t.Errorf("Expected only Notes.txt left, got %v", got)
+	}
+
+	if status, _ := guiPost(t, server, "/api/assign", `{"mode":"work","file":"../profile.yml"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected a path to be refused, got %d", status)
+	}
+	if status, _ := guiPost(t, server, "/api/assign", `{"mode":"nosuch","file":"Notes.txt"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown mode to be refused, got %d", status)
+	}
+}
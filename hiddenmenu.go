@@ -0,0 +1,164 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// HiddenMenuConfig represents the settings of the folder of links to hidden shortcuts
+type HiddenMenuConfig struct {
+	Enabled bool `yaml:"enabled" doc:"Keep a \"FocusMode Hidden\" folder of links to the hidden shortcuts, refreshed on every apply and restore" default:"false"`
+}
+
+// hiddenMenuName is the name of the folder of links to hidden shortcuts
+const hiddenMenuName = "FocusMode Hidden"
+
+// hiddenMenuFolder returns where the folder of links goes: the Start Menu on Windows,
+// ~/Applications on macOS (shown in Finder and searched by Spotlight) and the home folder elsewhere
+func hiddenMenuFolder(goos string, ctx *userContext) string {
+	switch goos {
+	case "windows":
+		return filepath.Join(ctx.ConfigDir, "Microsoft", "Windows", "Start Menu", "Programs", hiddenMenuName)
+	case "darwin":
+		return filepath.Join(ctx.Home, "Applications", hiddenMenuName)
+	default:
+		return filepath.Join(ctx.Home, hiddenMenuName)
+	}
+}
+
+// hiddenMenuLink is a link in the menu folder and the hidden file it opens
+type hiddenMenuLink struct {
+	Name   string
+	Target string
+}
+
+// hiddenMenuLinks returns the links for the files in every mode's folder
+// Files kept hidden by machine policy get no link, and a name hidden by several modes
+// gets the mode in brackets after the first
+func hiddenMenuLinks(config *Config, homeDir, goos string) []hiddenMenuLink {
+	modes := config.getAvailableModes()
+	sort.Strings(modes)
+	var links []hiddenMenuLink
+	used := make(map[string]bool)
+	for _, modeName := range modes {
+		destination := config.Modes[modeName].Destination
+		if destination == "" {
+			destination = fmt.Sprintf("%s_Shortcuts", modeName)
+		}
+		folder := filepath.Join(homeDir, destination)
+		names, err := folderEntries(folder)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if config.Policy != nil && containsFold(config.Policy.AlwaysHidden, name) {
+				continue
+			}
+			linkName := name
+			if goos == "windows" && !isWindowsShortcut(name) {
+				// Other files get a shortcut to them, since symlinks need admin rights on Windows
+				linkName += ".lnk"
+			}
+			if used[strings.ToLower(nfc(linkName))] {
+				ext := filepath.Ext(linkName)
+				linkName = fmt.Sprintf("%s (%s)%s", strings.TrimSuffix(linkName, ext), modeName, ext)
+			}
+			used[strings.ToLower(nfc(linkName))] = true
+			links = append(links, hiddenMenuLink{Name: linkName, Target: filepath.Join(folder, name)})
+		}
+	}
+	return links
+}
+
+// isWindowsShortcut reports whether a file is a Windows shortcut, which is copied as it is
+func isWindowsShortcut(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".lnk" || ext == ".url"
+}
+
+// windowsShortcutScript creates shortcuts to files with the Windows Script Host
+func windowsShortcutScript(links []hiddenMenuLink, folder string) string {
+	var script strings.Builder
+	script.WriteString("$shell = New-Object -ComObject WScript.Shell; ")
+	for _, link := range links {
+		fmt.Fprintf(&script, "$s = $shell.CreateShortcut('%s'); $s.TargetPath = '%s'; $s.Save(); ",
+			strings.ReplaceAll(filepath.Join(folder, link.Name), "'", "''"), strings.ReplaceAll(link.Target, "'", "''"))
+	}
+	return script.String()
+}
+
+// writeHiddenMenu replaces the contents of folder with the links, removing the folder when there are none
+// The folder is FocusMode's alone, so everything in it is replaced
+func writeHiddenMenu(folder, goos string, links []hiddenMenuLink) error {
+	if entries, err := os.ReadDir(folder); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err := removeFile(filepath.Join(folder, entry.Name())); err != nil {
+				return fmt.Errorf("error clearing %s: %w", folder, err)
+			}
+		}
+	}
+	if len(links) == 0 {
+		if err := removeFile(folder); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %s: %w", folder, err)
+		}
+		return nil
+	}
+	if err := mkdirAll(folder, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", folder, err)
+	}
+	ownByUser(folder)
+
+	var shortcuts []hiddenMenuLink
+	for _, link := range links {
+		path := filepath.Join(folder, link.Name)
+		switch {
+		case goos != "windows":
+			if err := symlinkFile(link.Target, path); err != nil {
+				return fmt.Errorf("error linking %s: %w", link.Name, err)
+			}
+			ownByUser(path)
+		case isWindowsShortcut(link.Target):
+			data, err := os.ReadFile(link.Target)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", link.Target, err)
+			}
+			if err := writeFile(path, data, 0644); err != nil {
+				return fmt.Errorf("error copying %s: %w", link.Name, err)
+			}
+		default:
+			shortcuts = append(shortcuts, link)
+		}
+	}
+	if len(shortcuts) > 0 {
+		out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsShortcutScript(shortcuts, folder)).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error creating shortcuts: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// refreshHiddenMenu brings the folder of links in line with what is hidden, warning on failure
+// It does nothing unless hidden_menu.enabled is set, and only follows the OS desktop:
+// one menu folder rebuilt from each --location in turn would only ever show the last
+func refreshHiddenMenu(config *Config) {
+	if config == nil || !config.HiddenMenu.Enabled || locationActive() {
+		return
+	}
+	ctx, err := currentUserContext()
+	if err == nil {
+		folder := hiddenMenuFolder(runtime.GOOS, ctx)
+		err = writeHiddenMenu(folder, runtime.GOOS, hiddenMenuLinks(config, ctx.Home, runtime.GOOS))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error updating the %s folder: %v\n", hiddenMenuName, err)
+	}
+}
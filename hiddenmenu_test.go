@@ -0,0 +1,71 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHiddenMenuLinks tests link names for duplicates, policy and non-shortcut files on Windows
+func TestHiddenMenuLinks(t *testing.T) {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, "Games"), 0755)
+	os.MkdirAll(filepath.Join(home, "Work"), 0755)
+	for _, path := range []string{"Games/Steam.lnk", "Games/Roblox.lnk", "Games/.DS_Store", "Work/Steam.lnk", "Work/budget.xlsx"} {
+		os.WriteFile(filepath.Join(home, path), nil, 0644)
+	}
+	config := &Config{
+		Modes: map[string]ModeConfig{
+			"gamemode":  {Destination: "Games"},
+			"focusmode": {Destination: "Work"},
+		},
+		Policy: &Policy{AlwaysHidden: []string{"Roblox.lnk"}},
+	}
+
+	var got []string
+	for _, link := range hiddenMenuLinks(config, home, "windows") {
+		got = append(got, link.Name+" -> "+filepath.Base(filepath.Dir(link.Target)))
+	}
+	want := []string{"Steam.lnk -> Work", "budget.xlsx.lnk -> Work", "Steam (gamemode).lnk -> Games"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Unexpected links: %v, want %v", got, want)
+	}
+}
+
+// TestWriteHiddenMenu tests that the folder is replaced on refresh and removed when nothing is hidden
+func TestWriteHiddenMenu(t *testing.T) {
+	dir := t.TempDir()
+	folder := filepath.Join(dir, hiddenMenuName)
+	target := filepath.Join(dir, "Steam.lnk")
+	os.WriteFile(target, nil, 0644)
+
+	if err := writeHiddenMenu(folder, "linux", []hiddenMenuLink{{Name: "Old.lnk", Target: target}}); err != nil {
+		t.Fatalf("writeHiddenMenu() returned error: %v", err)
+	}
+	if err := writeHiddenMenu(folder, "linux", []hiddenMenuLink{{Name: "Steam.lnk", Target: target}}); err != nil {
+		t.Fatalf("writeHiddenMenu() returned error: %v", err)
+	}
+	entries, _ := os.ReadDir(folder)
+	if len(entries) != 1 || entries[0].Name() != "Steam.lnk" {
+		t.Fatalf("Expected only the Steam.lnk link, got %v", entries)
+	}
+	if got, err := os.Readlink(filepath.Join(folder, "Steam.lnk")); err != nil || got != target {
+		t.Errorf("Expected a link to %s, got %q, %v", target, got, err)
+	}
+
+	if err := writeHiddenMenu(folder, "linux", nil); err != nil {
+		t.Fatalf("writeHiddenMenu() returned error: %v", err)
+	}
+	if _, err := os.Stat(folder); !os.IsNotExist(err) {
+		t.Errorf("Expected the empty folder to be removed, got %v", err)
+	}
+}
+
+// TestWindowsShortcutScript tests that paths are quoted for PowerShell
+func TestWindowsShortcutScript(t *testing.T) {
+	script := windowsShortcutScript([]hiddenMenuLink{{Name: "Bob's notes.txt.lnk", Target: `C:\Work\Bob's notes.txt`}}, `C:\Menu`)
+	if !strings.Contains(script, `$s.TargetPath = 'C:\Work\Bob''s notes.txt'`) {
+		t.Errorf("Expected the target quoted, got %s", script)
+	}
+}
@@ -0,0 +1,211 @@
+package focusmode
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record kinds stored in the session history
+const (
+	RecordSession = "session"
+	RecordBreak   = "break"
+	RecordRoutine = "routine"
+)
+
+// SessionRecord is one entry of the session history log
+type SessionRecord struct {
+	Kind           string       `json:"kind"`
+	Mode           string       `json:"mode,omitempty"`
+	Routine        string       `json:"routine,omitempty"`
+	RoutineStep    int          `json:"routine_step,omitempty"`
+	StartTime      time.Time    `json:"start_time"`
+	EndTime        time.Time    `json:"end_time"`
+	PlannedSeconds int64        `json:"planned_seconds"`
+	FocusedSeconds int64        `json:"focused_seconds"`
+	PausedSeconds  int64        `json:"paused_seconds"`
+	Pauses         int          `json:"pauses,omitempty"`
+	Completed      bool         `json:"completed"`
+	MovedShortcuts []string     `json:"moved_shortcuts,omitempty"`
+	Coding         *CodingStats `json:"coding,omitempty"`
+}
+
+// dataDir returns the directory where FocusMode keeps its state files, creating it if needed
+// It belongs to the managed user, which may differ from the account running the process
+func dataDir() (string, error) {
+	ctx, err := currentUserContext()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(ctx.ConfigDir, "focusmode")
+	// In read-only mode a missing directory just means nothing has been recorded yet
+	if _, err := os.Stat(dir); os.IsNotExist(err) && !readOnly {
+		if err := mkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("error creating data directory: %w", err)
+		}
+		ownByUser(dir)
+	}
+	return dir, nil
+}
+
+// historyPath returns the path of the session history log
+func historyPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// newSessionRecord builds a history record from a finished session
+func newSessionRecord(fs *FocusSession) SessionRecord {
+	kind := RecordSession
+	if fs.Break {
+		kind = RecordBreak
+	}
+	return SessionRecord{
+		Kind:           kind,
+		Mode:           fs.Mode,
+		StartTime:      fs.StartTime,
+		EndTime:        time.Now(),
+		PlannedSeconds: int64(fs.Duration.Seconds()),
+		FocusedSeconds: int64(fs.elapsed().Seconds()),
+		PausedSeconds:  int64(fs.PausedTotal.Seconds()),
+		Completed:      fs.State == StateCompleted,
+		MovedShortcuts: fs.MovedShortcuts,
+		Coding:         fs.Coding,
+	}
+}
+
+const (
+	recordLogLockWait  = 5 * time.Second  // How long a writer waits for a record log's lock
+	recordLogLockStale = 30 * time.Second // Age of a lock file taken as left by a crash
+)
+
+// lockRecordLog takes the lock of the JSON lines log at path, a file next to it that only one
+// process can create, so gc doesn't rewrite the log while a session appends a record
+// The returned function releases it
+func lockRecordLog(path string) (func(), error) {
+	if err := guardWrite("lock " + path); err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(recordLogLockWait)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("error locking %s: %w", filepath.Base(path), err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > recordLogLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another process; delete %s if none is running", filepath.Base(path), lockPath)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// appendHistory appends a record to the history log at path
+func appendHistory(path string, record SessionRecord) error {
+	unlock, err := lockRecordLog(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := appendFile(path, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history file: %w", err)
+	}
+	defer file.Close()
+	ownByUser(path)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding history record: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing history file: %w", err)
+	}
+	return nil
+}
+
+// readHistory reads all records from the history log at path
+// A missing file is treated as an empty history; malformed lines are skipped
+func readHistory(path string) ([]SessionRecord, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening history file: %w", err)
+	}
+	defer file.Close()
+
+	var records []SessionRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record SessionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history file: %w", err)
+	}
+	return records, nil
+}
+
+// loadHistory reads all records from the default history log
+func loadHistory() ([]SessionRecord, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	return readHistory(path)
+}
+
+// historyRecorder is a session hook that writes a history record when a session ends
+type historyRecorder struct {
+	path        string
+	routine     string
+	routineStep int
+	pauses      int
+}
+
+// OnStart does nothing
+func (h *historyRecorder) OnStart(fs *FocusSession) {}
+
+// OnPause counts the pause for the record
+func (h *historyRecorder) OnPause(fs *FocusSession) {
+	h.pauses++
+}
+
+// OnResume does nothing
+func (h *historyRecorder) OnResume(fs *FocusSession) {}
+
+// OnEnd appends the finished session to the history log
+func (h *historyRecorder) OnEnd(fs *FocusSession) {
+	record := newSessionRecord(fs)
+	record.Routine = h.routine
+	record.RoutineStep = h.routineStep
+	record.Pauses = h.pauses
+	if err := appendHistory(h.path, record); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not record session history: %v\n", err)
+	}
+}
+
+// DryRun reports the history log that would be written
+func (h *historyRecorder) DryRun(fs *FocusSession) []string {
+	return []string{fmt.Sprintf("record the session in %s", h.path)}
+}
@@ -0,0 +1,144 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAppendAndReadHistory tests round-tripping records through the history log
+func TestAppendAndReadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	records, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory() on missing file returned error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("Expected empty history, got %d records", len(records))
+	}
+
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	first := SessionRecord{
+		Kind:           RecordSession,
+		Mode:           "focusmode",
+		StartTime:      start,
+		EndTime:        start.Add(25 * time.Minute),
+		PlannedSeconds: 1500,
+		FocusedSeconds: 1500,
+		Completed:      true,
+		MovedShortcuts: []string{"Steam.lnk"},
+	}
+	second := SessionRecord{
+		Kind:        RecordBreak,
+		Routine:     "deepworkday",
+		RoutineStep: 2,
+		StartTime:   start.Add(25 * time.Minute),
+		EndTime:     start.Add(30 * time.Minute),
+	}
+
+	for _, record := range []SessionRecord{first, second} {
+		if err := appendHistory(path, record); err != nil {
+			t.Fatalf("appendHistory() returned error: %v", err)
+		}
+	}
+
+	// Malformed lines are skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open history: %v", err)
+	}
+	file.WriteString("not json\n")
+	file.Close()
+
+	records, err = readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory() returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Mode != "focusmode" || !records[0].Completed || len(records[0].MovedShortcuts) != 1 {
+		t.Errorf("Unexpected first record: %+v", records[0])
+	}
+	if !records[0].StartTime.Equal(start) {
+		t.Errorf("Expected start time %v, got %v", start, records[0].StartTime)
+	}
+	if records[1].Kind != RecordBreak || records[1].Routine != "deepworkday" || records[1].RoutineStep != 2 {
+		t.Errorf("Unexpected second record: %+v", records[1])
+	}
+}
+
+// TestHistoryRecorder tests that finished sessions are written to the history log
+func TestHistoryRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	recorder := &historyRecorder{path: path, routine: "morning", routineStep: 1}
+
+	fs := &FocusSession{
+		Duration:    25 * time.Minute,
+		Mode:        "focusmode",
+		StartTime:   time.Now().Add(-10 * time.Minute),
+		PausedTotal: 2 * time.Minute,
+		State:       StateRunning,
+		Hooks:       []SessionHook{recorder},
+	}
+	fs.finish(StateInterrupted)
+
+	records, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory() returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record.Kind != RecordSession || record.Completed {
+		t.Errorf("Expected interrupted session record, got %+v", record)
+	}
+	if record.PlannedSeconds != 1500 || record.PausedSeconds != 120 {
+		t.Errorf("Unexpected durations: %+v", record)
+	}
+	if record.FocusedSeconds < 479 || record.FocusedSeconds > 481 {
+		t.Errorf("Expected ~480 focused seconds, got %d", record.FocusedSeconds)
+	}
+	if record.Routine != "morning" || record.RoutineStep != 1 {
+		t.Errorf("Expected routine metadata, got %+v", record)
+	}
+}
+
+// TestLockRecordLog tests that the lock is held by one writer at a time and a stale one is taken over
+func TestLockRecordLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	unlock, err := lockRecordLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		if unlock, err := lockRecordLog(path); err == nil {
+			unlock()
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Expected the second writer to wait for the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	// A lock file left by a crash doesn't block writers for good
+	os.WriteFile(path+".lock", nil, 0644)
+	old := time.Now().Add(-2 * recordLogLockStale)
+	os.Chtimes(path+".lock", old, old)
+	if err := appendHistory(path, SessionRecord{Mode: "focusmode", EndTime: time.Now()}); err != nil {
+		t.Errorf("Expected a stale lock taken over, got %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock released, got %v", err)
+	}
+}
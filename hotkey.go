@@ -0,0 +1,202 @@
+package focusmode
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// HotkeysConfig represents the global hotkeys registered by the daemon
+type HotkeysConfig struct {
+	Panic  string `yaml:"panic" doc:"End the session and restore every mode's shortcuts at once" example:"ctrl+alt+shift+r"`
+	Start  string `yaml:"start" doc:"Start a session with the default mode and duration" example:"ctrl+alt+shift+s"`
+	Pause  string `yaml:"pause" doc:"Pause or resume the running session" example:"ctrl+alt+shift+p"`
+	Extend string `yaml:"extend" doc:"Add 5 minutes to the running session" example:"ctrl+alt+shift+e"`
+	Widget string `yaml:"widget" doc:"Show or hide the countdown widget of the running session" example:"ctrl+alt+shift+w"`
+}
+
+// Modifier bits and flags of the Win32 RegisterHotKey API
+const (
+	hotkeyModAlt      = 0x1
+	hotkeyModControl  = 0x2
+	hotkeyModShift    = 0x4
+	hotkeyModWin      = 0x8
+	hotkeyModNoRepeat = 0x4000
+)
+
+// hotkeyNamedKeys maps key names to Windows virtual-key codes
+var hotkeyNamedKeys = map[string]uint{
+	"space": 0x20, "escape": 0x1B, "esc": 0x1B, "pause": 0x13, "insert": 0x2D, "delete": 0x2E,
+	"home": 0x24, "end": 0x23, "pageup": 0x21, "pagedown": 0x22,
+}
+
+// hotkey is a parsed key combination
+type hotkey struct {
+	modifiers uint // hotkeyMod* bits
+	key       uint // Windows virtual-key code
+}
+
+// parseHotkey parses a combination like "ctrl+alt+shift+r" or "win+f9"
+// At least one modifier is required so the key keeps working in other applications
+func parseHotkey(combo string) (hotkey, error) {
+	var k hotkey
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(combo, " ", "")), "+")
+	for i, part := range parts {
+		if i < len(parts)-1 {
+			switch part {
+			case "ctrl", "control":
+				k.modifiers |= hotkeyModControl
+			case "alt", "option":
+				k.modifiers |= hotkeyModAlt
+			case "shift":
+				k.modifiers |= hotkeyModShift
+			case "win", "super", "cmd", "meta":
+				k.modifiers |= hotkeyModWin
+			default:
+				return hotkey{}, fmt.Errorf("hotkey %q: unknown modifier %q", combo, part)
+			}
+			continue
+		}
+
+		switch {
+		case len(part) == 1 && part[0] >= 'a' && part[0] <= 'z':
+			k.key = uint(part[0]-'a') + 'A'
+		case len(part) == 1 && part[0] >= '0' && part[0] <= '9':
+			k.key = uint(part[0])
+		case len(part) > 1 && part[0] == 'f':
+			n, err := strconv.Atoi(part[1:])
+			if err != nil || n < 1 || n > 24 {
+				return hotkey{}, fmt.Errorf("hotkey %q: unknown key %q", combo, part)
+			}
+			k.key = 0x70 + uint(n-1)
+		default:
+			code, ok := hotkeyNamedKeys[part]
+			if !ok {
+				return hotkey{}, fmt.Errorf("hotkey %q: unknown key %q", combo, part)
+			}
+			k.key = code
+		}
+	}
+	if k.key == 0 {
+		return hotkey{}, fmt.Errorf("hotkey %q has no key", combo)
+	}
+	if k.modifiers == 0 {
+		return hotkey{}, fmt.Errorf("hotkey %q needs at least one of ctrl, alt, shift or win", combo)
+	}
+	return k, nil
+}
+
+// hotkeyBinding is a hotkey and the command it runs
+type hotkeyBinding struct {
+	name   string   // setting name, e.g. "panic"
+	combo  string   // the combination as configured
+	hotkey hotkey   // parsed combination
+	args   []string // command-line arguments run by the daemon when pressed
+	detach bool     // the command runs for a long time (a session), so the daemon doesn't wait for it
+}
+
+// bindings returns the configured hotkeys in a fixed order
+func (c HotkeysConfig) bindings() ([]hotkeyBinding, error) {
+	settings := []struct {
+		name, combo string
+		args        []string
+		detach      bool
+	}{
+		{"panic", c.Panic, []string{"panic"}, false},
+		{"start", c.Start, []string{"session", "start"}, true},
+		{"pause", c.Pause, []string{"session", "pause", "-toggle"}, false},
+		{"extend", c.Extend, []string{"session", "extend"}, false},
+		{"widget", c.Widget, []string{"session", "widget"}, false},
+	}
+
+	var bindings []hotkeyBinding
+	seen := make(map[hotkey]string)
+	for _, setting := range settings {
+		if setting.combo == "" {
+			continue
+		}
+		k, err := parseHotkey(setting.combo)
+		if err != nil {
+			return nil, fmt.Errorf("hotkeys.%s: %w", setting.name, err)
+		}
+		if other, taken := seen[k]; taken {
+			return nil, fmt.Errorf("hotkeys.%s: %s is already used by hotkeys.%s", setting.name, setting.combo, other)
+		}
+		seen[k] = setting.name
+		bindings = append(bindings, hotkeyBinding{name: setting.name, combo: setting.combo, hotkey: k, args: setting.args, detach: setting.detach})
+	}
+	return bindings, nil
+}
+
+// hotkeyListenerScript registers hotkeys with RegisterHotKey and reports them on stdout:
+// "ok <id>" or "taken <id>" after registering, "hotkey <id>" when pressed and "beat" every 30 seconds
+// {{KEYS}} is replaced with "id,modifiers,key" entries separated by semicolons
+const hotkeyListenerScript = `Add-Type @"
+using System;
+using System.Runtime.InteropServices;
+public static class FocusModeHotkeys {
+  [StructLayout(LayoutKind.Sequential)]
+  public struct MSG { public IntPtr hwnd; public uint message; public IntPtr wParam; public IntPtr lParam; public uint time; public int x; public int y; }
+  [DllImport("user32.dll")] public static extern bool RegisterHotKey(IntPtr hWnd, int id, uint modifiers, uint key);
+  [DllImport("user32.dll")] public static extern UIntPtr SetTimer(IntPtr hWnd, UIntPtr id, uint elapse, IntPtr func);
+  [DllImport("user32.dll")] public static extern int GetMessage(out MSG msg, IntPtr hWnd, uint min, uint max);
+}
+"@
+$out = [Console]::Out
+foreach ($entry in "{{KEYS}}".Split(";")) {
+  $k = $entry.Split(",")
+  if ([FocusModeHotkeys]::RegisterHotKey([IntPtr]::Zero, [int]$k[0], [uint32]$k[1], [uint32]$k[2])) { $out.WriteLine("ok " + $k[0]) } else { $out.WriteLine("taken " + $k[0]) }
+}
+$out.Flush()
+[void][FocusModeHotkeys]::SetTimer([IntPtr]::Zero, [UIntPtr]::Zero, 30000, [IntPtr]::Zero)
+$msg = New-Object FocusModeHotkeys+MSG
+while ([FocusModeHotkeys]::GetMessage([ref]$msg, [IntPtr]::Zero, 0, 0) -gt 0) {
+  if ($msg.message -eq 0x0312) { $out.WriteLine("hotkey " + $msg.wParam) } elseif ($msg.message -eq 0x0113) { $out.WriteLine("beat") }
+  $out.Flush()
+}`
+
+// hotkeyScript returns the listener script for the given bindings; hotkey IDs are 1-based indexes
+func hotkeyScript(bindings []hotkeyBinding) string {
+	keys := make([]string, len(bindings))
+	for i, b := range bindings {
+		keys[i] = fmt.Sprintf("%d,%d,%d", i+1, b.hotkey.modifiers|hotkeyModNoRepeat, b.hotkey.key)
+	}
+	return strings.Replace(hotkeyListenerScript, "{{KEYS}}", strings.Join(keys, ";"), 1)
+}
+
+// listenHotkeys registers the hotkeys system-wide and calls pressed for each press until ctx is cancelled
+// It is only available on Windows, where it needs an interactive desktop session
+func listenHotkeys(ctx context.Context, bindings []hotkeyBinding, beat func(), pressed func(hotkeyBinding)) error {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", hotkeyScript(bindings))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error starting hotkey listener: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting hotkey listener: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		beat()
+		event, id, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		index, err := strconv.Atoi(id)
+		if event == "beat" || err != nil || index < 1 || index > len(bindings) {
+			continue
+		}
+		b := bindings[index-1]
+		switch event {
+		case "ok":
+			fmt.Printf("Registered hotkey %s for %s\n", b.combo, b.name)
+		case "taken":
+			fmt.Fprintf(os.Stderr, "Warning: hotkey %s for %s is already used by another application\n", b.combo, b.name)
+		case "hotkey":
+			pressed(b)
+		}
+	}
+	return cmd.Wait()
+}
@@ -0,0 +1,73 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseHotkey tests parsing key combinations into RegisterHotKey arguments
+func TestParseHotkey(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  hotkey
+	}{
+		{"ctrl+alt+shift+r", hotkey{hotkeyModControl | hotkeyModAlt | hotkeyModShift, 'R'}},
+		{"Ctrl + F9", hotkey{hotkeyModControl, 0x78}},
+		{"win+5", hotkey{hotkeyModWin, '5'}},
+		{"alt+escape", hotkey{hotkeyModAlt, 0x1B}},
+	}
+	for _, tt := range tests {
+		got, err := parseHotkey(tt.combo)
+		if err != nil || got != tt.want {
+			t.Errorf("parseHotkey(%q) = %+v, %v, want %+v", tt.combo, got, err, tt.want)
+		}
+	}
+
+	for _, combo := range []string{"r", "ctrl+", "hyper+r", "ctrl+f25", "ctrl+enterkey", ""} {
+		if _, err := parseHotkey(combo); err == nil {
+			t.Errorf("parseHotkey(%q) should fail", combo)
+		}
+	}
+}
+
+// TestHotkeyBindings tests that configured hotkeys are validated and listed in order
+func TestHotkeyBindings(t *testing.T) {
+	bindings, err := HotkeysConfig{Panic: "ctrl+alt+shift+r"}.bindings()
+	if err != nil || len(bindings) != 1 || bindings[0].name != "panic" || strings.Join(bindings[0].args, " ") != "panic" {
+		t.Fatalf("Unexpected bindings: %+v, %v", bindings, err)
+	}
+	if bindings, err := (HotkeysConfig{}).bindings(); err != nil || len(bindings) != 0 {
+		t.Errorf("Expected no bindings, got %+v, %v", bindings, err)
+	}
+	bindings, err = HotkeysConfig{Panic: "ctrl+alt+shift+r", Start: "ctrl+alt+shift+s", Pause: "ctrl+alt+shift+p", Extend: "ctrl+alt+shift+e", Widget: "ctrl+alt+shift+w"}.bindings()
+	if err != nil || len(bindings) != 5 {
+		t.Fatalf("Unexpected bindings: %+v, %v", bindings, err)
+	}
+	if b := bindings[1]; b.name != "start" || !b.detach || strings.Join(b.args, " ") != "session start" {
+		t.Errorf("Expected the start hotkey to run a detached session, got %+v", b)
+	}
+	if b := bindings[3]; b.name != "extend" || b.detach || strings.Join(b.args, " ") != "session extend" {
+		t.Errorf("Unexpected extend binding: %+v", b)
+	}
+	if b := bindings[4]; b.name != "widget" || b.detach || strings.Join(b.args, " ") != "session widget" {
+		t.Errorf("Unexpected widget binding: %+v", b)
+	}
+	if _, err := (HotkeysConfig{Pause: "ctrl+alt+p", Extend: "Ctrl+Alt+P"}).bindings(); err == nil || !strings.Contains(err.Error(), "hotkeys.extend") {
+		t.Errorf("Expected duplicate hotkeys to be rejected, got %v", err)
+	}
+	if _, err := (HotkeysConfig{Panic: "shift+nothing"}).bindings(); err == nil || !strings.Contains(err.Error(), "hotkeys.panic") {
+		t.Errorf("Expected an error naming the setting, got %v", err)
+	}
+}
+
+// TestHotkeyScript tests that the listener script registers every binding without repeats
+func TestHotkeyScript(t *testing.T) {
+	bindings, _ := HotkeysConfig{Panic: "ctrl+alt+shift+r"}.bindings()
+	script := hotkeyScript(bindings)
+	if !strings.Contains(script, `"1,16391,82".Split(";")`) {
+		t.Errorf("Expected the panic hotkey with MOD_NOREPEAT in the script:\n%s", script)
+	}
+	if strings.Contains(script, "{{KEYS}}") {
+		t.Error("Expected the key placeholder to be replaced")
+	}
+}
@@ -0,0 +1,420 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// TZID names in calendar files are looked up on Windows too, which has no zoneinfo of its own
+	_ "time/tzdata"
+)
+
+// icsMaxSize bounds a downloaded calendar; years of events fit in a few megabytes
+const icsMaxSize = 16 << 20
+
+// icsMaxOccurrences bounds the expansion of one recurring event, so a rule without an end
+// starting long ago can't keep a poll busy
+const icsMaxOccurrences = 20000
+
+// icsEvent is a VEVENT of an iCalendar file, before its recurrences are expanded
+type icsEvent struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time // Set on an event that replaces one occurrence of a recurring event
+	Cancelled    bool
+}
+
+// unfoldICS splits a calendar into content lines, joining the lines folded onto the next ones
+func unfoldICS(data []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// parseICSLine splits a content line such as DTSTART;TZID=Europe/Paris:20240306T090000 into its
+// name, parameters and value
+func parseICSLine(line string) (string, map[string]string, string) {
+	// The value starts at the first colon outside a quoted parameter value
+	quoted, colon := false, -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// icsUnescape decodes the backslash escapes of a text value
+func icsUnescape(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+// parseICSTime reads a DATE or DATE-TIME value: in UTC with a Z, in the TZID time zone, or in
+// local time when floating; a date is an all-day value at local midnight
+func parseICSTime(value string, params map[string]string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		// Outlook writes Windows zone names, which Go doesn't know; local time is the best guess
+		if named, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = named
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseICSDuration reads a DURATION value such as PT1H30M or P1D
+func parseICSDuration(value string) (time.Duration, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if rest == value || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, r := range rest {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			number += string(r)
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			number = ""
+			switch {
+			case r == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return total, nil
+}
+
+// parseICS reads the events of an iCalendar file; events it can't read are skipped
+func parseICS(data []byte) ([]icsEvent, error) {
+	lines := unfoldICS(data)
+	if len(lines) == 0 || !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(lines[0])), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file")
+	}
+
+	var events []icsEvent
+	var event *icsEvent
+	var duration time.Duration
+	depth := 0 // Components nested in the event, such as alarms, whose properties aren't the event's
+	for _, line := range lines {
+		name, params, value := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT") && event == nil:
+			event, duration, depth = &icsEvent{}, 0, 0
+			continue
+		case event == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event.End.IsZero() {
+				switch {
+				case duration > 0:
+					event.End = event.Start.Add(duration)
+				case event.AllDay:
+					event.End = event.Start.AddDate(0, 0, 1)
+				default:
+					event.End = event.Start
+				}
+			}
+			if !event.Start.IsZero() {
+				events = append(events, *event)
+			}
+			event = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = icsUnescape(value)
+		case "STATUS":
+			event.Cancelled = strings.EqualFold(value, "CANCELLED")
+		case "DTSTART":
+			event.Start, event.AllDay, _ = parseICSTime(value, params)
+		case "DTEND":
+			event.End, _, _ = parseICSTime(value, params)
+		case "DURATION":
+			duration, _ = parseICSDuration(value)
+		case "RRULE":
+			event.RRule = value
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, _ = parseICSTime(value, params)
+		case "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				if t, _, err := parseICSTime(date, params); err == nil {
+					event.ExDates = append(event.ExDates, t)
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+// icsRule is the part of an RRULE that is expanded
+type icsRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// icsWeekdays maps BYDAY values to weekdays
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseICSRule reads an RRULE; rules that pick days other than by weekday in a weekly rule,
+// such as the second Tuesday of each month, aren't supported
+func parseICSRule(value string) (icsRule, error) {
+	rule := icsRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, _, err := parseICSTime(val, nil)
+			if err != nil {
+				return rule, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, ok := icsWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("BYDAY=%s isn't supported", val)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "WKST", "":
+		default:
+			return rule, fmt.Errorf("%s isn't supported", strings.ToUpper(key))
+		}
+	}
+	switch rule.Freq {
+	case "DAILY", "MONTHLY", "YEARLY":
+		if len(rule.ByDay) > 0 {
+			return rule, fmt.Errorf("BYDAY with FREQ=%s isn't supported", rule.Freq)
+		}
+	case "WEEKLY":
+	default:
+		return rule, fmt.Errorf("FREQ=%s isn't supported", rule.Freq)
+	}
+	return rule, nil
+}
+
+// starts returns the starts of the rule's occurrences from the first, start, that begin
+// before to, in start's time zone so they keep their time of day across daylight saving changes
+func (r icsRule) starts(start, to time.Time) []time.Time {
+	y, m, d := start.Date()
+	h, mi, s := start.Clock()
+	loc := start.Location()
+	onDay := func(offsetDays int) time.Time {
+		return time.Date(y, m, d+offsetDays, h, mi, s, 0, loc)
+	}
+
+	var starts []time.Time
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if (!r.Until.IsZero() && t.After(r.Until)) || !t.Before(to) || (r.Count > 0 && len(starts) >= r.Count) || len(starts) >= icsMaxOccurrences {
+			return false
+		}
+		starts = append(starts, t)
+		return true
+	}
+
+	for period := 0; ; period += r.Interval {
+		var candidates []time.Time
+		switch r.Freq {
+		case "DAILY":
+			candidates = []time.Time{onDay(period)}
+		case "WEEKLY":
+			if len(r.ByDay) == 0 {
+				candidates = []time.Time{onDay(7 * period)}
+				break
+			}
+			// The week of the first occurrence starts on Monday
+			monday := (int(start.Weekday()) + 6) % 7
+			for _, weekday := range r.ByDay {
+				candidates = append(candidates, onDay(7*period-monday+(int(weekday)+6)%7))
+			}
+			sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+		case "MONTHLY":
+			candidates = []time.Time{time.Date(y, m+time.Month(period), d, h, mi, s, 0, loc)}
+		case "YEARLY":
+			candidates = []time.Time{time.Date(y+period, m, d, h, mi, s, 0, loc)}
+		}
+		for _, t := range candidates {
+			// A 31st or a 29th of February that a month or year doesn't have is skipped
+			if (r.Freq == "MONTHLY" || r.Freq == "YEARLY") && t.Day() != d {
+				continue
+			}
+			if !emit(t) {
+				return starts
+			}
+		}
+	}
+}
+
+// expandICS returns the timed events that overlap from..to, with recurring events expanded
+// into their occurrences; all-day and cancelled events are left out. It also returns the titles
+// of recurring events whose rules it can't expand, of which only the first occurrence is used
+func expandICS(events []icsEvent, from, to time.Time) ([]calendarMeeting, []string) {
+	// Occurrences moved or cancelled one at a time replace those of the rule
+	overridden := make(map[string]bool)
+	for _, event := range events {
+		if !event.RecurrenceID.IsZero() {
+			overridden[event.UID+"@"+strconv.FormatInt(event.RecurrenceID.Unix(), 10)] = true
+		}
+	}
+
+	var meetings []calendarMeeting
+	var unsupported []string
+	for _, event := range events {
+		if event.AllDay || event.Cancelled {
+			continue
+		}
+		length := event.End.Sub(event.Start)
+		starts := []time.Time{event.Start}
+		if event.RRule != "" && event.RecurrenceID.IsZero() {
+			rule, err := parseICSRule(event.RRule)
+			if err != nil {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%v)", event.Summary, err))
+			} else {
+				starts = rule.starts(event.Start, to)
+			}
+		}
+		for _, start := range starts {
+			key := event.UID + "@" + strconv.FormatInt(start.Unix(), 10)
+			if event.RecurrenceID.IsZero() && event.RRule != "" && overridden[key] {
+				continue
+			}
+			if icsExcluded(event.ExDates, start) || !start.Add(length).After(from) || !start.Before(to) {
+				continue
+			}
+			meetings = append(meetings, calendarMeeting{ID: key, Title: event.Summary, Start: start, End: start.Add(length)})
+		}
+	}
+	sort.Slice(meetings, func(i, j int) bool { return meetings[i].Start.Before(meetings[j].Start) })
+	return meetings, unsupported
+}
+
+// icsExcluded reports whether an occurrence was taken out of its rule with EXDATE
+func icsExcluded(exDates []time.Time, start time.Time) bool {
+	for _, t := range exDates {
+		if t.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchICS downloads a calendar under policy; webcal:// addresses are fetched over HTTPS
+func fetchICS(policy RetryPolicy, address string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(address, "webcal://"); ok {
+		address = "https://" + rest
+	}
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := doWithRetry(policy, calendarHTTPClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching the calendar: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, icsMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the calendar: %w", err)
+	}
+	if len(data) > icsMaxSize {
+		return nil, fmt.Errorf("the calendar is larger than %d MB", icsMaxSize>>20)
+	}
+	return data, nil
+}
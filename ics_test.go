@@ -0,0 +1,209 @@
+package focusmode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// icsCalendarOf wraps events in a calendar with CRLF line endings, as servers send them
+func icsCalendarOf(events ...string) []byte {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//EN"}
+	for _, event := range events {
+		lines = append(lines, strings.Split(strings.TrimSpace(event), "\n")...)
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// TestParseICS tests reading events, folded lines, time zones and nested alarms
+func TestParseICS(t *testing.T) {
+	data := icsCalendarOf(`
+BEGIN:VEVENT
+UID:one
+SUMMARY:Deep Work\, part 1
+DTSTART:20240305T090000Z
+DTEND:20240305T110000Z
+BEGIN:VALARM
+SUMMARY:Reminder
+TRIGGER:-PT10M
+END:VALARM
+END:VEVENT`, `
+BEGIN:VEVENT
+UID:two
+SUMMARY:Planning with a very long title that the server fol
+ ded onto the next line
+DTSTART;TZID=America/New_York:20240306T090000
+DURATION:PT1H30M
+END:VEVENT`, `
+BEGIN:VEVENT
+UID:three
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20240307
+END:VEVENT`)
+
+	events, err := parseICS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d: %+v", len(events), events)
+	}
+	if events[0].Summary != "Deep Work, part 1" || events[0].End.Sub(events[0].Start) != 2*time.Hour {
+		t.Errorf("Unexpected first event (alarm summary leaked?): %+v", events[0])
+	}
+	if events[1].Summary != "Planning with a very long title that the server folded onto the next line" {
+		t.Errorf("Expected the folded title joined, got %q", events[1].Summary)
+	}
+	if want := time.Date(2024, 3, 6, 14, 0, 0, 0, time.UTC); !events[1].Start.Equal(want) || events[1].End.Sub(events[1].Start) != 90*time.Minute {
+		t.Errorf("Expected 14:00 UTC for 1h30, got %v to %v", events[1].Start.UTC(), events[1].End.UTC())
+	}
+	if !events[2].AllDay || events[2].End.Sub(events[2].Start) != 24*time.Hour {
+		t.Errorf("Expected an all-day event, got %+v", events[2])
+	}
+
+	if _, err := parseICS([]byte("<html>Sign in</html>")); err == nil {
+		t.Error("Expected a page that isn't a calendar refused")
+	}
+}
+
+// TestParseICSDuration tests DURATION values
+func TestParseICSDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"PT45M":   45 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+	} {
+		if got, err := parseICSDuration(value); err != nil || got != want {
+			t.Errorf("parseICSDuration(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseICSDuration("1 hour"); err == nil {
+		t.Error("Expected an invalid duration refused")
+	}
+}
+
+// TestParseICSRule tests which recurrence rules are expanded
+func TestParseICSRule(t *testing.T) {
+	rule, err := parseICSRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=4;WKST=MO")
+	if err != nil || rule.Freq != "WEEKLY" || rule.Interval != 2 || rule.Count != 4 || len(rule.ByDay) != 2 {
+		t.Errorf("parseICSRule() = %+v, %v", rule, err)
+	}
+	for _, value := range []string{"FREQ=MONTHLY;BYDAY=2TU", "FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=HOURLY", "FREQ=DAILY;INTERVAL=0"} {
+		if _, err := parseICSRule(value); err == nil {
+			t.Errorf("Expected %s refused", value)
+		}
+	}
+}
+
+// TestExpandICS tests recurring events with exceptions, moved occurrences and the window
+func TestExpandICS(t *testing.T) {
+	data := icsCalendarOf(`
+BEGIN:VEVENT
+UID:focus
+SUMMARY:Deep Work
+DTSTART;TZID=Europe/Paris:20240304T090000
+DTEND;TZID=Europe/Paris:20240304T110000
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20240401T000000Z
+EXDATE;TZID=Europe/Paris:20240306T090000
+END:VEVENT`, `
+BEGIN:VEVENT
+UID:focus
+RECURRENCE-ID;TZID=Europe/Paris:20240311T090000
+SUMMARY:Deep Work (moved)
+DTSTART;TZID=Europe/Paris:20240311T140000
+DTEND;TZID=Europe/Paris:20240311T160000
+END:VEVENT`, `
+BEGIN:VEVENT
+UID:cancelled
+SUMMARY:Deep Work
+STATUS:CANCELLED
+DTSTART:20240305T090000Z
+DTEND:20240305T100000Z
+END:VEVENT`, `
+BEGIN:VEVENT
+UID:monthly
+SUMMARY:Review
+DTSTART:20240305T150000Z
+DTEND:20240305T160000Z
+RRULE:FREQ=MONTHLY;BYDAY=1TU
+END:VEVENT`)
+	events, err := parseICS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, paris)
+	meetings, unsupported := expandICS(events, from, from.AddDate(0, 0, 28))
+
+	var got []string
+	for _, m := range meetings {
+		got = append(got, m.Start.In(paris).Format("Jan 2 15:04")+" "+m.Title)
+	}
+	// Mar 6 is excluded, Mar 11 moved to the afternoon, and Mar 31 is the first day of summer time
+	want := []string{
+		"Mar 4 09:00 Deep Work",
+		"Mar 5 16:00 Review",
+		"Mar 11 14:00 Deep Work (moved)",
+		"Mar 13 09:00 Deep Work",
+		"Mar 18 09:00 Deep Work",
+		"Mar 20 09:00 Deep Work",
+		"Mar 25 09:00 Deep Work",
+		"Mar 27 09:00 Deep Work",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected occurrences:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(unsupported) != 1 || !strings.HasPrefix(unsupported[0], "Review") {
+		t.Errorf("Expected the monthly rule reported, got %v", unsupported)
+	}
+	if meetings[0].ID == meetings[3].ID {
+		t.Error("Expected each occurrence to have its own ID")
+	}
+
+	// Occurrences in progress at the start of the window are included
+	meetings, _ = expandICS(events, time.Date(2024, 3, 4, 10, 0, 0, 0, paris), time.Date(2024, 3, 4, 10, 1, 0, 0, paris))
+	if len(meetings) != 1 || meetings[0].Title != "Deep Work" {
+		t.Errorf("Expected the event in progress, got %+v", meetings)
+	}
+}
+
+// TestICSRuleStarts tests counts, intervals and days a month doesn't have
+func TestICSRuleStarts(t *testing.T) {
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	to := start.AddDate(1, 0, 0)
+
+	monthly := icsRule{Freq: "MONTHLY", Interval: 1}
+	if starts := monthly.starts(start, to); len(starts) != 7 || starts[1].Month() != time.March {
+		t.Errorf("Expected months without a 31st skipped, got %v", starts)
+	}
+	daily := icsRule{Freq: "DAILY", Interval: 3, Count: 4}
+	if starts := daily.starts(start, to); len(starts) != 4 || !starts[3].Equal(start.AddDate(0, 0, 9)) {
+		t.Errorf("Expected 4 occurrences every 3 days, got %v", starts)
+	}
+}
+
+// TestFetchICS tests downloading a calendar and refusing error pages
+func TestFetchICS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/basic.ics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(icsCalendarOf())
+	}))
+	defer server.Close()
+
+	data, err := fetchICS(RetryPolicy{}, server.URL+"/basic.ics")
+	if err != nil || !strings.HasPrefix(string(data), "BEGIN:VCALENDAR") {
+		t.Errorf("fetchICS() = %q, %v", data, err)
+	}
+	if _, err := fetchICS(RetryPolicy{}, server.URL+"/missing.ics"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a missing calendar reported, got %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IDEWatchConfig represents the IDE presence trigger settings
+type IDEWatchConfig struct {
+	Enabled   bool     `yaml:"enabled" doc:"Watch for IDE use" default:"false"`
+	Apps      []string `yaml:"apps" doc:"Process names of IDEs, matched case-insensitively" default:"[code, goland, idea, pycharm, webstorm, devenv, cursor, zed]"`
+	Minutes   int      `yaml:"minutes" doc:"Continuous foreground minutes before prompting" default:"10"`
+	Mode      string   `yaml:"mode" doc:"Mode to apply (uses default mode if empty)"`
+	Duration  int      `yaml:"duration" doc:"Session length in minutes, counted from when the IDE came to the foreground" default:"50"`
+	AutoStart bool     `yaml:"auto_start" doc:"Start without asking, after the automation grace period" default:"false"`
+}
+
+const (
+	defaultIDEWatchMinutes  = 10
+	defaultIDEWatchDuration = 50
+	ideWatchPollInterval    = 30 * time.Second
+)
+
+// defaultIDEApps is used when no IDE process names are configured
+var defaultIDEApps = []string{"code", "goland", "idea", "pycharm", "webstorm", "devenv", "cursor", "zed"}
+
+// foregroundWindowScript prints the process name of the foreground window on Windows
+const foregroundWindowScript = `Add-Type @"
+using System;
+using System.Runtime.InteropServices;
+public class FocusModeFG {
+  [DllImport("user32.dll")] public static extern IntPtr GetForegroundWindow();
+  [DllImport("user32.dll")] public static extern uint GetWindowThreadProcessId(IntPtr h, out uint pid);
+}
+"@
+$fgpid = 0
+[void][FocusModeFG]::GetWindowThreadProcessId([FocusModeFG]::GetForegroundWindow(), [ref]$fgpid)
+(Get-Process -Id $fgpid).ProcessName`
+
+// foregroundApp returns the process name of the application owning the foreground window
+func foregroundApp() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", foregroundWindowScript).Output()
+		if err != nil {
+			return "", fmt.Errorf("error querying foreground window: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	case "darwin":
+		out, err := exec.Command("osascript", "-e",
+			`tell application "System Events" to get name of first application process whose frontmost is true`).Output()
+		if err != nil {
+			return "", fmt.Errorf("error querying frontmost application: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	case "linux":
+		out, err := exec.Command("xdotool", "getactivewindow", "getwindowpid").Output()
+		if err != nil {
+			return "", fmt.Errorf("error querying active window (is xdotool installed?): %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return "", fmt.Errorf("unexpected xdotool output: %q", out)
+		}
+		comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+		if err != nil {
+			return "", fmt.Errorf("error reading process name: %w", err)
+		}
+		return strings.TrimSpace(string(comm)), nil
+	default:
+		return "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// matchesIDE reports whether a foreground process name is one of the configured IDEs
+func matchesIDE(app string, ides []string) bool {
+	name := strings.ToLower(strings.TrimSuffix(strings.ToLower(app), ".exe"))
+	if name == "" {
+		return false
+	}
+	for _, ide := range ides {
+		if strings.Contains(name, strings.ToLower(ide)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ideTracker tracks how long an IDE has continuously been in the foreground
+type ideTracker struct {
+	threshold time.Duration
+	since     time.Time // when the current IDE streak began (zero if none)
+	prompted  bool      // whether the current streak already triggered a prompt
+}
+
+// observe records the foreground state at now and reports whether a prompt is due
+// A prompt is due once per streak, when the IDE has been in front for the threshold
+func (t *ideTracker) observe(inIDE bool, now time.Time) (bool, time.Time) {
+	if !inIDE {
+		t.since = time.Time{}
+		t.prompted = false
+		return false, time.Time{}
+	}
+	if t.since.IsZero() {
+		t.since = now
+	}
+	if t.prompted || now.Sub(t.since) < t.threshold {
+		return false, t.since
+	}
+	t.prompted = true
+	return true, t.since
+}
+
+// ideOffer is a session the IDE watch offered, kept so it can be started from a desktop
+// notification or another terminal with "focusmode watch ide start"
+type ideOffer struct {
+	App      string    `json:"app"`
+	Mode     string    `json:"mode"`
+	Duration int       `json:"duration"` // Minutes, counted from Since
+	Since    time.Time `json:"since"`    // When the IDE came to the front
+}
+
+// errNoIDEOffer is returned when no offered session is waiting to be started
+var errNoIDEOffer = errors.New("no IDE session offer is waiting; the IDE watch offers one after continuous IDE use")
+
+// ideOfferPath returns the path of the session the IDE watch last offered
+func ideOfferPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ide-offer.json"), nil
+}
+
+// writeIDEOffer records an offered session, replacing any earlier offer
+func writeIDEOffer(offer ideOffer) error {
+	path, err := ideOfferPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(offer, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding IDE offer: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing IDE offer: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// takeIDEOffer removes and returns the offered session, so only one answer starts it
+// An offer whose session would already be over at now is removed and errNoIDEOffer returned
+func takeIDEOffer(now time.Time) (ideOffer, error) {
+	path, err := ideOfferPath()
+	if err != nil {
+		return ideOffer{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ideOffer{}, errNoIDEOffer
+	}
+	if err != nil {
+		return ideOffer{}, fmt.Errorf("error reading IDE offer: %w", err)
+	}
+	// Removing first means a second answer finds nothing, whichever comes first
+	if err := removeFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return ideOffer{}, errNoIDEOffer
+		}
+		return ideOffer{}, fmt.Errorf("error removing IDE offer: %w", err)
+	}
+	var offer ideOffer
+	if err := json.Unmarshal(data, &offer); err != nil {
+		return ideOffer{}, fmt.Errorf("error parsing IDE offer: %w", err)
+	}
+	if !now.Before(offer.Since.Add(time.Duration(offer.Duration) * time.Minute)) {
+		return ideOffer{}, errNoIDEOffer
+	}
+	return offer, nil
+}
+
+// ideOfferMessage returns the notification text of an offer, naming the command that accepts it
+func ideOfferMessage(offer ideOffer) string {
+	return fmt.Sprintf("%s has been in the foreground since %s. Run 'focusmode watch ide start' to start a %dm %s session from then.",
+		offer.App, offer.Since.Format("15:04"), offer.Duration, offer.Mode)
+}
+
+// watchIDE polls the foreground application and offers to start a session when an IDE
+// has been used continuously, backdating the session to when the IDE came to the front
+// Offers are shown as desktop notifications and accepted with "focusmode watch ide start";
+// with a terminal they can also be answered where the watch runs
+// It calls beat after every poll and returns when ctx is cancelled
+func watchIDE(ctx context.Context, config *Config, opts sessionOptions, terminal bool, beat func()) {
+	watch := config.IDEWatch
+	apps := watch.Apps
+	if len(apps) == 0 {
+		apps = defaultIDEApps
+	}
+	minutes := watch.Minutes
+	if minutes <= 0 {
+		minutes = defaultIDEWatchMinutes
+	}
+	duration := watch.Duration
+	if duration <= 0 {
+		duration = defaultIDEWatchDuration
+	}
+	modeName := watch.Mode
+	if modeName == "" {
+		modeName = config.DefaultMode
+	}
+
+	fmt.Printf("Watching for %s in the foreground (prompt after %dm)...\n", strings.Join(apps, ", "), minutes)
+	tracker := &ideTracker{threshold: time.Duration(minutes) * time.Minute}
+	answers := stdinLines()
+	notifiers := []Notifier{consoleNotifier{}, &desktopNotifier{show: showDesktopNotification}}
+
+	for {
+		beat()
+		app, err := foregroundApp()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		due, since := tracker.observe(err == nil && matchesIDE(app, apps) && !isSessionRunning(), time.Now())
+		if due {
+			if watch.AutoStart {
+				notifyAll(notifiers, "FocusMode", fmt.Sprintf("%s has been in the foreground since %s", app, since.Format("15:04")))
+				action := fmt.Sprintf("Starting a %dm %s session from %s", duration, modeName, since.Format("15:04"))
+				ticker := time.NewTicker(time.Second)
+				proceed := waitGracePeriod(action, config.Automation.graceSeconds(), answers, ticker.C, os.Stdout)
+				ticker.Stop()
+				if proceed {
+					startRetroactiveSession(config, modeName, duration, since, answers, opts)
+				}
+			} else {
+				offer := ideOffer{App: app, Mode: modeName, Duration: duration, Since: since}
+				if err := writeIDEOffer(offer); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+				notifyAll(notifiers, "Start a focus session?", ideOfferMessage(offer))
+				question := fmt.Sprintf("Start a %dm %s session from %s?", duration, modeName, since.Format("15:04"))
+				if terminal {
+					accepted := confirm(question, true, answers)
+					// Either answer uses up the offer, unless the notification was answered meanwhile
+					if _, err := takeIDEOffer(time.Now()); errors.Is(err, errNoIDEOffer) {
+						if accepted {
+							fmt.Println("The session was already started or the offer ran out.")
+						}
+					} else if accepted {
+						startRetroactiveSession(config, modeName, duration, since, answers, opts)
+					}
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ideWatchPollInterval):
+		}
+	}
+}
+
+// startRetroactiveSession runs a session whose start time is in the past
+func startRetroactiveSession(config *Config, modeName string, duration int, since time.Time, commands <-chan string, opts sessionOptions) {
+	session, err := startFocusSession(config, modeName, duration, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	session.StartTime = since
+	session.Stop = opts.stop
+	session.RestoreOnStop = opts.restoreOnStop
+	if session.remaining() == 0 {
+		fmt.Println("The session would already be over; not starting.")
+		return
+	}
+
+	if err := attachSessionHooks(session, config, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if err := session.run(commands); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// runWatchCommand handles the "watch" subcommand
+func runWatchCommand(args []string) {
+	if len(args) == 0 || (args[0] != "ide" && args[0] != "meetings" && args[0] != "calendar" && args[0] != "lock" && args[0] != "folders") {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode watch ide|meetings|calendar|lock|folders [-config profile.yml]")
+		fmt.Fprintln(os.Stderr, "       focusmode watch ide start [-config profile.yml]")
+		os.Exit(1)
+	}
+
+	name, rest := args[0], args[1:]
+	start := name == "ide" && len(rest) > 0 && rest[0] == "start"
+	if start {
+		name, rest = "ide start", rest[1:]
+	}
+	flags := flag.NewFlagSet("watch "+name, flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(rest)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if args[0] == "folders" {
+		if !config.FolderWatch.Enabled {
+			fmt.Fprintln(os.Stderr, "The hidden folder watch is disabled. Set folder_watch.enabled: true in your config to opt in.")
+			os.Exit(1)
+		}
+		watchHiddenFolders(context.Background(), &sync.Mutex{}, func() {})
+		return
+	}
+	if args[0] == "lock" {
+		if !config.ScreenLock.Enabled {
+			fmt.Fprintln(os.Stderr, "The screen lock trigger is disabled. Set screen_lock.enabled: true in your config to opt in.")
+			os.Exit(1)
+		}
+		watchScreenLock(context.Background(), config, selfRunner(*configPath), func() {})
+		return
+	}
+	if args[0] == "meetings" {
+		if !config.Meeting.Enabled {
+			fmt.Fprintln(os.Stderr, "Meeting mode is disabled. Set meeting.enabled: true in your config to opt in.")
+			os.Exit(1)
+		}
+		if _, err := getCalendarProvider(config.Calendar, config.Retry.Network); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		watchMeetings(context.Background(), config, sessionOptions{}, func() {})
+		return
+	}
+	if args[0] == "calendar" {
+		if !config.CalendarSessions.Enabled {
+			fmt.Fprintln(os.Stderr, "Calendar sessions are disabled. Set calendar_sessions.enabled: true in your config to opt in.")
+			os.Exit(1)
+		}
+		source, name, err := newCalendarEventSource(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		pollCalendarSessions(context.Background(), config, sessionOptions{}, func() {}, source, name)
+		return
+	}
+	if !config.IDEWatch.Enabled {
+		fmt.Fprintln(os.Stderr, "IDE watch is disabled. Set ide_watch.enabled: true in your config to opt in.")
+		os.Exit(1)
+	}
+	if start {
+		startOfferedSession(config)
+		return
+	}
+
+	watchIDE(context.Background(), config, sessionOptions{}, true, func() {})
+}
+
+// startOfferedSession runs the session the IDE watch offered, answering its notification
+func startOfferedSession(config *Config) {
+	if isSessionRunning() {
+		fmt.Fprintln(os.Stderr, "Error: a session is already running")
+		os.Exit(1)
+	}
+	offer, err := takeIDEOffer(time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Starting a %dm %s session from %s\n", offer.Duration, offer.Mode, offer.Since.Format("15:04"))
+	startRetroactiveSession(config, offer.Mode, offer.Duration, offer.Since, stdinLines(), sessionOptions{})
+}
@@ -0,0 +1,97 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMatchesIDE tests matching of foreground process names against IDE names
+func TestMatchesIDE(t *testing.T) {
+	ides := []string{"code", "GoLand"}
+	tests := map[string]bool{
+		"Code.exe":      true,
+		"code":          true,
+		"goland64.exe":  true,
+		"GoLand":        true,
+		"chrome.exe":    false,
+		"":              false,
+		"Discord":       false,
+		"code-insiders": true,
+	}
+	for app, want := range tests {
+		if got := matchesIDE(app, ides); got != want {
+			t.Errorf("matchesIDE(%q) = %v, want %v", app, got, want)
+		}
+	}
+}
+
+// TestIDETracker tests continuous foreground tracking and one prompt per streak
+func TestIDETracker(t *testing.T) {
+	tracker := &ideTracker{threshold: 10 * time.Minute}
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+
+	if due, _ := tracker.observe(true, start); due {
+		t.Fatal("Expected no prompt at streak start")
+	}
+	if due, _ := tracker.observe(true, start.Add(9*time.Minute)); due {
+		t.Fatal("Expected no prompt before threshold")
+	}
+
+	due, since := tracker.observe(true, start.Add(10*time.Minute))
+	if !due || !since.Equal(start) {
+		t.Fatalf("Expected prompt backdated to %v, got due=%v since=%v", start, due, since)
+	}
+	if due, _ := tracker.observe(true, start.Add(20*time.Minute)); due {
+		t.Error("Expected only one prompt per streak")
+	}
+
+	// Switching away resets the streak
+	tracker.observe(false, start.Add(21*time.Minute))
+	tracker.observe(true, start.Add(22*time.Minute))
+	if due, _ := tracker.observe(true, start.Add(31*time.Minute)); due {
+		t.Error("Expected new streak to need the full threshold")
+	}
+	due, since = tracker.observe(true, start.Add(32*time.Minute))
+	if !due || !since.Equal(start.Add(22*time.Minute)) {
+		t.Errorf("Expected prompt for new streak, got due=%v since=%v", due, since)
+	}
+}
+
+// TestIDEOffer tests that an offered session can be taken once, and not after it would be over
+func TestIDEOffer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	if path, err := ideOfferPath(); err == nil {
+		os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	since := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	offer := ideOffer{App: "Code", Mode: "focusmode", Duration: 50, Since: since}
+
+	if _, err := takeIDEOffer(since); !errors.Is(err, errNoIDEOffer) {
+		t.Errorf("Expected no offer before one is made, got %v", err)
+	}
+	if err := writeIDEOffer(offer); err != nil {
+		t.Fatal(err)
+	}
+	got, err := takeIDEOffer(since.Add(10 * time.Minute))
+	if err != nil || got.Mode != "focusmode" || got.Duration != 50 || !got.Since.Equal(since) {
+		t.Fatalf("takeIDEOffer() = %+v, %v", got, err)
+	}
+	if _, err := takeIDEOffer(since.Add(11 * time.Minute)); !errors.Is(err, errNoIDEOffer) {
+		t.Errorf("Expected an offer to be taken only once, got %v", err)
+	}
+
+	writeIDEOffer(offer)
+	if _, err := takeIDEOffer(since.Add(50 * time.Minute)); !errors.Is(err, errNoIDEOffer) {
+		t.Errorf("Expected an offer whose session would be over to run out, got %v", err)
+	}
+
+	if message := ideOfferMessage(offer); !strings.Contains(message, "focusmode watch ide start") || !strings.Contains(message, "09:00") {
+		t.Errorf("Expected the message to name the command and start time, got %q", message)
+	}
+}
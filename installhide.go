@@ -0,0 +1,238 @@
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AutoHideConfig represents the rules that hide new desktop files as they appear, outside sessions too
+type AutoHideConfig struct {
+	Installers    bool     `yaml:"installers" doc:"Hide shortcuts that appear on the desktop while an installer runs or soon after, from focusmode daemon" default:"false"`
+	WindowMinutes int      `yaml:"window_minutes" doc:"Minutes after an installer was last seen running that a new shortcut is still taken to be its own" default:"10"`
+	Processes     []string `yaml:"processes" doc:"Parts of process names, without case, that mark an installer" default:"[msiexec, setup, install]"`
+	Folder        string   `yaml:"folder" doc:"Folder in the home directory the shortcuts are moved to, in a folder per category from categories.yml" default:"Installed_Shortcuts"`
+}
+
+const (
+	defaultAutoHideWindowMinutes = 10
+	defaultAutoHideFolder        = "Installed_Shortcuts"
+	autoHidePollInterval         = 5 * time.Second
+	autoHideLabel                = "installer-shortcuts" // Recorded as the mode of the moves, as archive sweeps record their date
+)
+
+// defaultInstallerProcesses mark an installer when auto_hide.processes is empty
+var defaultInstallerProcesses = []string{"msiexec", "setup", "install"}
+
+// notInstallers are Windows services whose names look like installers but run on their own
+var notInstallers = []string{"trustedinstaller", "tiworker", "focusmode"}
+
+// window returns how long after an installer was last seen its shortcuts are hidden
+func (c AutoHideConfig) window() time.Duration {
+	if c.WindowMinutes <= 0 {
+		return defaultAutoHideWindowMinutes * time.Minute
+	}
+	return time.Duration(c.WindowMinutes) * time.Minute
+}
+
+// folder returns the folder holding the hidden shortcuts, e.g. ~/Installed_Shortcuts
+func (c AutoHideConfig) folder() (string, error) {
+	root, err := hiddenFoldersRoot()
+	if err != nil {
+		return "", err
+	}
+	folder := c.Folder
+	if folder == "" {
+		folder = defaultAutoHideFolder
+	}
+	return filepath.Join(root, folder), nil
+}
+
+// isInstaller reports whether a process name marks an installer
+func (c AutoHideConfig) isInstaller(name string) bool {
+	key := appKey(name)
+	if key == "" || containsFold(notInstallers, key) {
+		return false
+	}
+	patterns := c.Processes
+	if len(patterns) == 0 {
+		patterns = defaultInstallerProcesses
+	}
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" && strings.Contains(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDesktopShortcut reports whether a file is a shortcut an installer would put on the desktop
+func isDesktopShortcut(name string) bool {
+	return isWindowsShortcut(name) || strings.EqualFold(filepath.Ext(name), ".desktop")
+}
+
+// installerWatcher finds the shortcuts installers add to a desktop folder
+type installerWatcher struct {
+	config        AutoHideConfig
+	known         map[string]map[string]bool // Names in each folder at the last check
+	hidden        map[string]bool            // Shortcuts hidden before, left alone if put back
+	lastInstaller time.Time                  // When an installer was last seen running
+}
+
+// newInstallerWatcher creates a watcher that takes the folders as they are at the first check
+func newInstallerWatcher(config AutoHideConfig) *installerWatcher {
+	return &installerWatcher{config: config, known: make(map[string]map[string]bool), hidden: make(map[string]bool)}
+}
+
+// check records the names in folder at now and returns the new shortcuts to hide
+// A shortcut is hidden if it appeared within the window of an installer running and was written in
+// that window too, so shortcuts restored from a mode's folder, which keep their times, stay put;
+// one put back with undo isn't hidden again
+// keep lists the names the modes look for, which are left to them
+func (w *installerWatcher) check(now time.Time, folder string, names []string, installerRunning bool, modTime func(string) (time.Time, bool), keep []string) []string {
+	if installerRunning {
+		w.lastInstaller = now
+	}
+	known, seen := w.known[folder]
+	current := make(map[string]bool, len(names))
+	var hide []string
+	for _, name := range names {
+		key := strings.ToLower(nfc(name))
+		current[key] = true
+		if !seen || known[key] || w.hidden[key] || !isDesktopShortcut(name) || containsFold(keep, name) {
+			continue
+		}
+		if w.lastInstaller.IsZero() || now.Sub(w.lastInstaller) > w.config.window() {
+			continue
+		}
+		if written, ok := modTime(name); ok && now.Sub(written) <= w.config.window() {
+			w.hidden[key] = true
+			hide = append(hide, name)
+		}
+	}
+	w.known[folder] = current
+	sort.Strings(hide)
+	return hide
+}
+
+// hideInstallerShortcuts moves the shortcuts from a desktop folder into the category folders of the
+// auto_hide folder and records them as one operation, so "focusmode undo" puts them back
+// Each shortcut is moved on its own, so one that can't be moved, e.g. from the Public Desktop
+// without administrator rights, doesn't keep the others on the desktop
+func hideInstallerShortcuts(config *Config, categories *CategoriesConfig, desktopPath string, names []string) ([]fileMove, error) {
+	folder, err := config.AutoHide.folder()
+	if err != nil {
+		return nil, err
+	}
+	var moves []fileMove
+	for _, name := range names {
+		if config.checkPinned(desktopPath, name) != nil {
+			fmt.Println(describePinned(name))
+			continue
+		}
+		to := filepath.Join(folder, string(categorizeFile(filepath.Join(desktopPath, name), categories)))
+		if _, ok := findFileName(to, name); ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s is already in %s; left on the desktop\n", name, to)
+			continue
+		}
+		move := []fileMove{{Name: name, Mode: autoHideLabel, From: desktopPath, To: to}}
+		if err := performMoves(config, move, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		moves = append(moves, move[0])
+	}
+	if len(moves) > 0 {
+		ownByUser(folder)
+		recordOperation(operationAutoHide, autoHideLabel, moves, "")
+	}
+	return moves, nil
+}
+
+// autoHideSummary describes hidden shortcuts for the notification sent after they are moved
+func autoHideSummary(moves []fileMove) string {
+	names := make([]string, len(moves))
+	for i, move := range moves {
+		names[i] = move.Name
+	}
+	return fmt.Sprintf("Hid %s, added by an installer; run 'focusmode undo' to put them back", strings.Join(names, ", "))
+}
+
+// watchInstallerShortcuts hides the shortcuts installers add to the desktop until ctx is cancelled;
+// run by the daemon
+// mu is held while shortcuts are moved so it can't interleave with other moves
+func watchInstallerShortcuts(ctx context.Context, config *Config, mu sync.Locker, beat func()) {
+	notifiers := []Notifier{consoleNotifier{}}
+	categories, err := loadCategoriesConfig("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using the default categories for installer shortcuts\n", err)
+		categories = getDefaultCategoriesConfig()
+	}
+	fmt.Printf("Hiding shortcuts added by installers within %s of one running\n", formatDuration(config.AutoHide.window()))
+	watcher := newInstallerWatcher(config.AutoHide)
+	failed := false
+	for {
+		beat()
+		running := false
+		if processes, err := listProcesses(); err != nil {
+			if !failed {
+				failed = true
+				fmt.Fprintf(os.Stderr, "Warning: can't check for installers: %v\n", err)
+			}
+		} else {
+			for _, process := range processes {
+				if config.AutoHide.isInstaller(process.Name) {
+					running = true
+					break
+				}
+			}
+		}
+
+		var folders []string
+		if desktopPath, err := getDesktopPath(); err == nil {
+			folders = append(folders, desktopPath)
+		}
+		if public := publicDesktopPath(); public != "" {
+			folders = append(folders, public)
+		}
+		mu.Lock()
+		for _, folder := range folders {
+			names, err := folderEntries(folder)
+			if err != nil {
+				continue
+			}
+			modTime := func(name string) (time.Time, bool) {
+				info, err := os.Stat(filepath.Join(folder, name))
+				if err != nil {
+					return time.Time{}, false
+				}
+				return info.ModTime(), true
+			}
+			hide := watcher.check(time.Now(), folder, names, running, modTime, config.modeShortcuts())
+			if len(hide) == 0 {
+				continue
+			}
+			_, span := startSpan(ctx, "auto-hide installer shortcuts")
+			moves, err := hideInstallerShortcuts(config, categories, folder, hide)
+			span.set("focusmode.hidden", len(moves))
+			span.finish(err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error hiding installer shortcuts: %v\n", err)
+			} else if len(moves) > 0 {
+				notifyAll(notifiers, "Installer shortcuts hidden", autoHideSummary(moves))
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(autoHidePollInterval):
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestIsInstaller tests which processes mark an installer
+func TestIsInstaller(t *testing.T) {
+	config := AutoHideConfig{}
+	for name, want := range map[string]bool{
+		"msiexec.exe": true, "DiscordSetup.exe": true, "Install Spotify.exe": true, "setup": true,
+		"TrustedInstaller.exe": false, "TiWorker.exe": false, "explorer.exe": false, "focusmode.exe": false,
+	} {
+		if got := config.isInstaller(name); got != want {
+			t.Errorf("isInstaller(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if !(AutoHideConfig{Processes: []string{"Squirrel"}}).isInstaller("squirrel.exe") || (AutoHideConfig{Processes: []string{"squirrel"}}).isInstaller("setup.exe") {
+		t.Error("Expected auto_hide.processes to replace the default patterns")
+	}
+}
+
+// TestInstallerWatcher tests that only shortcuts written within the window of an installer are hidden
+func TestInstallerWatcher(t *testing.T) {
+	start := time.Now()
+	written := map[string]time.Time{}
+	modTime := func(name string) (time.Time, bool) {
+		at, ok := written[name]
+		return at, ok
+	}
+	w := newInstallerWatcher(AutoHideConfig{WindowMinutes: 10})
+	desktop := "/home/me/Desktop"
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	// The first check takes the desktop as it is
+	written["Old.lnk"] = at(0)
+	if hide := w.check(at(0), desktop, []string{"Old.lnk"}, true, modTime, nil); len(hide) != 0 {
+		t.Errorf("Expected nothing hidden at the first check, got %v", hide)
+	}
+
+	// Added while an installer runs: the shortcut, not the document, restored shortcut or mode's shortcut
+	written["Spotify.lnk"], written["notes.txt"], written["Steam.lnk"] = at(1), at(1), at(1)
+	written["Restored.lnk"] = start.Add(-24 * time.Hour)
+	names := []string{"Old.lnk", "Spotify.lnk", "notes.txt", "Restored.lnk", "Steam.lnk"}
+	if hide := w.check(at(1), desktop, names, true, modTime, []string{"steam.lnk"}); !reflect.DeepEqual(hide, []string{"Spotify.lnk"}) {
+		t.Errorf("Expected Spotify.lnk hidden, got %v", hide)
+	}
+
+	// Still within the window after the installer exits, then past it
+	written["Zoom.lnk"] = at(5)
+	if hide := w.check(at(5), desktop, append(names, "Zoom.lnk"), false, modTime, nil); !reflect.DeepEqual(hide, []string{"Zoom.lnk"}) {
+		t.Errorf("Expected Zoom.lnk hidden within the window, got %v", hide)
+	}
+	written["Mine.url"] = at(20)
+	if hide := w.check(at(20), desktop, append(names, "Mine.url"), false, modTime, nil); len(hide) != 0 {
+		t.Errorf("Expected nothing hidden past the window, got %v", hide)
+	}
+
+	// A shortcut put back with undo stays, even with an installer running
+	w.check(at(21), desktop, names[:1], true, modTime, nil)
+	if hide := w.check(at(22), desktop, []string{"Old.lnk", "Spotify.lnk"}, true, modTime, nil); len(hide) != 0 {
+		t.Errorf("Expected Spotify.lnk left on the desktop after undo, got %v", hide)
+	}
+}
+
+// TestHideInstallerShortcuts tests moving shortcuts into category folders as one undoable operation
+func TestHideInstallerShortcuts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	os.MkdirAll(desktop, 0755)
+	for _, name := range []string{"Epic Games.lnk", "Spotify.lnk"} {
+		os.WriteFile(filepath.Join(desktop, name), nil, 0644)
+	}
+
+	config := &Config{}
+	moves, err := hideInstallerShortcuts(config, getDefaultCategoriesConfig(), desktop, []string{"Epic Games.lnk", "Spotify.lnk"})
+	if err != nil || len(moves) != 2 {
+		t.Fatalf("Expected two shortcuts hidden, got %+v (err %v)", moves, err)
+	}
+	for _, path := range []string{filepath.Join("game", "Epic Games.lnk"), filepath.Join("other", "Spotify.lnk")} {
+		if _, err := os.Stat(filepath.Join(dir, defaultAutoHideFolder, path)); err != nil {
+			t.Errorf("Expected %s in the auto_hide folder: %v", path, err)
+		}
+	}
+
+	path, _ := operationLogPath()
+	log, err := readOperationLog(path)
+	if err != nil || len(log.Done) != 1 || log.Done[0].Kind != operationAutoHide {
+		t.Fatalf("Expected one auto-hide operation, got %+v (err %v)", log, err)
+	}
+	if err := replayOperation(config, "undo", log.Done[0], log.Done[0].reversed(), false); err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Spotify.lnk")); err != nil {
+		t.Errorf("Expected Spotify.lnk back on the desktop: %v", err)
+	}
+	path, _ = appliedModesPath()
+	if modes, _ := readAppliedModes(path); len(modes) != 0 {
+		t.Errorf("Expected the undo to leave the applied modes alone, got %+v", modes)
+	}
+}
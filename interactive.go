@@ -0,0 +1,206 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// plannedMove is one entry of the move list shown by an interactive apply
+type plannedMove struct {
+	Name     string
+	Selected bool
+	Missing  bool   // not found on the desktop
+	Category string // heading the entry is listed under, if the list is grouped
+}
+
+// planMoves builds the move list, deselecting shortcuts that are not on the desktop
+func planMoves(shortcuts []string, desktopPath string) []plannedMove {
+	moves := make([]plannedMove, len(shortcuts))
+	for i, name := range shortcuts {
+		_, err := os.Stat(filepath.Join(desktopPath, name))
+		missing := err != nil
+		moves[i] = plannedMove{Name: name, Selected: !missing, Missing: missing}
+	}
+	return moves
+}
+
+// addDesktopMoves appends the desktop files the mode doesn't list, deselected, so a one-off
+// apply can hide them too
+func addDesktopMoves(moves []plannedMove, files []string) []plannedMove {
+	listed := make(map[string]bool)
+	for _, move := range moves {
+		listed[strings.ToLower(move.Name)] = true
+	}
+	for _, name := range files {
+		if !listed[strings.ToLower(name)] {
+			listed[strings.ToLower(name)] = true
+			moves = append(moves, plannedMove{Name: name})
+		}
+	}
+	return moves
+}
+
+// groupMoves labels each entry with its category and orders the list by category_order
+// Entries keep their order within a category, so the mode's own shortcuts stay first
+func groupMoves(moves []plannedMove, categoriesConfig *CategoriesConfig) {
+	rank := make(map[string]int)
+	for i, categoryID := range categoriesConfig.CategoryOrder {
+		rank[categoryID] = i
+	}
+	ranks := make(map[string]int)
+	for i := range moves {
+		categoryID := string(categorizeShortcut(moves[i].Name, categoriesConfig))
+		label := categoryID
+		if categoryID == "other" {
+			label = "Other"
+		} else if category, exists := categoriesConfig.Categories[categoryID]; exists {
+			label = category.Name
+		}
+		moves[i].Category = label
+		if r, ok := rank[categoryID]; ok {
+			ranks[label] = r
+		} else {
+			ranks[label] = len(rank)
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return ranks[moves[i].Category] < ranks[moves[j].Category]
+	})
+}
+
+// printMovePlan prints the numbered move list with the selection state of each entry
+func printMovePlan(out io.Writer, moves []plannedMove, destination string) {
+	fmt.Fprintf(out, "\nPlanned moves to %s:\n", destination)
+	for i, move := range moves {
+		if move.Category != "" && (i == 0 || moves[i-1].Category != move.Category) {
+			fmt.Fprintf(out, "  %s\n", move.Category)
+		}
+		mark := " "
+		if move.Selected {
+			mark = "x"
+		}
+		note := ""
+		if move.Missing {
+			note = " (not on desktop)"
+		}
+		fmt.Fprintf(out, "  [%s] %2d. %s%s\n", mark, i+1, move.Name, note)
+	}
+}
+
+// toggleMoves applies a selection command to the move list
+// Commands are entry numbers or ranges to toggle (e.g. "2 5-7"), "a" to select all and "n" to select none
+func toggleMoves(moves []plannedMove, input string) error {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "a", "all":
+		for i := range moves {
+			moves[i].Selected = !moves[i].Missing
+		}
+		return nil
+	case "n", "none":
+		for i := range moves {
+			moves[i].Selected = false
+		}
+		return nil
+	}
+
+	var indexes []int
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		first, last := field, field
+		if from, to, ok := strings.Cut(field, "-"); ok {
+			first, last = from, to
+		}
+		start, err1 := strconv.Atoi(first)
+		end, err2 := strconv.Atoi(last)
+		if err1 != nil || err2 != nil || start < 1 || end > len(moves) || start > end {
+			return fmt.Errorf("invalid selection: %s", field)
+		}
+		for i := start; i <= end; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+	for _, i := range indexes {
+		if moves[i].Missing {
+			continue
+		}
+		moves[i].Selected = !moves[i].Selected
+	}
+	return nil
+}
+
+// selectMoves lets the user review and toggle the move list before applying
+// It returns the selected shortcut names, or false if the user cancelled
+func selectMoves(moves []plannedMove, destination string, lines <-chan string, out io.Writer) ([]string, bool) {
+	for {
+		printMovePlan(out, moves, destination)
+		fmt.Fprint(out, "Toggle numbers (e.g. \"2 5-7\"), a = all, n = none, Enter = apply, q = cancel: ")
+
+		line, ok := <-lines
+		if !ok {
+			fmt.Fprintln(out)
+			return nil, false
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			var selected []string
+			for _, move := range moves {
+				if move.Selected {
+					selected = append(selected, move.Name)
+				}
+			}
+			return selected, true
+		case "q", "quit", "cancel":
+			return nil, false
+		}
+		if err := toggleMoves(moves, line); err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+	}
+}
+
+// pickMode asks which mode an interactive apply moves the files with, numbering the modes
+// by name; Enter picks the default mode
+func pickMode(config *Config, lines <-chan string, out io.Writer) (string, bool) {
+	modes := config.getAvailableModes()
+	sort.Strings(modes)
+	for {
+		fmt.Fprintln(out, "\nModes:")
+		for i, name := range modes {
+			modeConfig, _ := config.getModeConfig(name)
+			note := ""
+			if name == config.DefaultMode {
+				note = " (default)"
+			}
+			destination := ""
+			if modeConfig != nil {
+				destination = " -> " + modeConfig.Destination
+			}
+			fmt.Fprintf(out, "  %2d. %s%s%s\n", i+1, name, destination, note)
+		}
+		fmt.Fprint(out, "Mode number or name, Enter = default, q = cancel: ")
+
+		line, ok := <-lines
+		if !ok {
+			fmt.Fprintln(out)
+			return "", false
+		}
+		answer := strings.TrimSpace(line)
+		switch strings.ToLower(answer) {
+		case "":
+			return config.DefaultMode, true
+		case "q", "quit", "cancel":
+			return "", false
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(modes) {
+			return modes[n-1], true
+		}
+		if _, exists := config.Modes[answer]; exists {
+			return answer, true
+		}
+		fmt.Fprintf(out, "Unknown mode: %s\n", answer)
+	}
+}
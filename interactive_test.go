@@ -0,0 +1,135 @@
+package focusmode
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestPlanMoves tests that shortcuts missing from the desktop start deselected
+func TestPlanMoves(t *testing.T) {
+	desktop := t.TempDir()
+	if err := os.WriteFile(filepath.Join(desktop, "Steam.lnk"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	moves := planMoves([]string{"Steam.lnk", "Gone.lnk"}, desktop)
+	want := []plannedMove{
+		{Name: "Steam.lnk", Selected: true},
+		{Name: "Gone.lnk", Selected: false, Missing: true},
+	}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("Expected %+v, got %+v", want, moves)
+	}
+}
+
+// TestToggleMoves tests toggling single entries, ranges, all and none
+func TestToggleMoves(t *testing.T) {
+	moves := []plannedMove{
+		{Name: "a", Selected: true},
+		{Name: "b", Selected: true},
+		{Name: "c", Selected: true},
+		{Name: "d", Missing: true},
+	}
+
+	if err := toggleMoves(moves, "1, 2-3"); err != nil {
+		t.Fatalf("toggleMoves() returned error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if moves[i].Selected {
+			t.Errorf("Expected entry %d to be deselected", i+1)
+		}
+	}
+
+	if err := toggleMoves(moves, "a"); err != nil {
+		t.Fatalf("toggleMoves() returned error: %v", err)
+	}
+	if !moves[0].Selected || moves[3].Selected {
+		t.Errorf("Expected all but missing entries selected, got %+v", moves)
+	}
+
+	if err := toggleMoves(moves, "4"); err != nil || moves[3].Selected {
+		t.Errorf("Expected missing entry to stay deselected, got %+v (err %v)", moves[3], err)
+	}
+
+	toggleMoves(moves, "n")
+	for _, move := range moves {
+		if move.Selected {
+			t.Errorf("Expected none selected, got %+v", moves)
+		}
+	}
+
+	for _, input := range []string{"0", "5", "3-1", "x"} {
+		if err := toggleMoves(moves, input); err == nil {
+			t.Errorf("Expected error for selection %q", input)
+		}
+	}
+}
+
+// TestSelectMoves tests the review loop from toggling to applying or cancelling
+func TestSelectMoves(t *testing.T) {
+	lines := make(chan string, 3)
+	lines <- "2"
+	lines <- "bogus"
+	lines <- ""
+	moves := []plannedMove{{Name: "a", Selected: true}, {Name: "b", Selected: true}}
+
+	selected, ok := selectMoves(moves, "/dest", lines, io.Discard)
+	if !ok || !reflect.DeepEqual(selected, []string{"a"}) {
+		t.Errorf("Expected [a] to be applied, got %v (ok %v)", selected, ok)
+	}
+
+	lines = make(chan string, 1)
+	lines <- "q"
+	if _, ok := selectMoves(moves, "/dest", lines, io.Discard); ok {
+		t.Error("Expected q to cancel")
+	}
+
+	closed := make(chan string)
+	close(closed)
+	if _, ok := selectMoves(moves, "/dest", closed, io.Discard); ok {
+		t.Error("Expected closed input to cancel")
+	}
+}
+
+// TestGroupMoves tests that desktop files the mode doesn't list are offered and grouped by category
+func TestGroupMoves(t *testing.T) {
+	moves := []plannedMove{{Name: "Notes.txt", Selected: true}, {Name: "Steam.lnk", Selected: true}}
+	moves = addDesktopMoves(moves, []string{"steam.lnk", "VS Code.lnk", "Word.lnk"})
+	groupMoves(moves, getDefaultCategoriesConfig())
+
+	var got []string
+	for _, move := range moves {
+		got = append(got, move.Category+": "+move.Name)
+	}
+	want := []string{"Games: Steam.lnk", "Development Tools: VS Code.lnk", "Work/Productivity: Word.lnk", "Other: Notes.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if !moves[0].Selected || moves[1].Selected || !moves[3].Selected {
+		t.Errorf("Expected only the mode's shortcuts selected, got %+v", moves)
+	}
+}
+
+// TestPickMode tests picking the mode by number, by name and by default
+func TestPickMode(t *testing.T) {
+	config := &Config{DefaultMode: "focusmode", Modes: map[string]ModeConfig{
+		"focusmode": {Destination: "Hidden"},
+		"gamemode":  {Destination: "Games"},
+	}}
+	for answer, want := range map[string]string{"2": "gamemode", "gamemode": "gamemode", "": "focusmode"} {
+		lines := make(chan string, 2)
+		lines <- "9"
+		lines <- answer
+		if got, ok := pickMode(config, lines, io.Discard); !ok || got != want {
+			t.Errorf("pickMode(%q) = %q, %v, want %q", answer, got, ok, want)
+		}
+	}
+	lines := make(chan string, 1)
+	lines <- "q"
+	if _, ok := pickMode(config, lines, io.Discard); ok {
+		t.Error("Expected q to cancel")
+	}
+}
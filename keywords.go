@@ -0,0 +1,293 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minKeywordShare is the share of corpus names containing a keyword that must carry the suggested label
+const minKeywordShare = 0.75
+
+// keywordStopwords are name tokens too generic to say anything about a shortcut
+var keywordStopwords = map[string]bool{
+	"and": true, "the": true, "for": true, "with": true, "new": true, "app": true,
+	"desktop": true, "shortcut": true, "setup": true, "x64": true, "x86": true, "beta": true,
+}
+
+// nameTokens splits a file name without its extension into lowercase words worth suggesting
+// Numbers, words under three letters and stopwords are dropped
+func nameTokens(name string) []string {
+	base := strings.ToLower(nfc(strings.TrimSuffix(name, filepath.Ext(name))))
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len([]rune(word)) < 3 || keywordStopwords[word] || seen[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// keywordSuggestion is a keyword that would move uncategorized shortcuts into a category
+type keywordSuggestion struct {
+	Keyword  string
+	Category string
+	Files    []string // Uncategorized shortcuts the keyword would catch
+	Support  int      // Corpus names containing the keyword and labeled with the category
+	Matches  int      // All corpus names containing the keyword
+}
+
+// suggestKeywords looks for words in the names of uncategorized shortcuts that the labeled corpus
+// ties to one category, matching keywords the way categorizeShortcut does (as substrings)
+// Suggestions catching the most shortcuts come first
+func suggestKeywords(others []string, corpus []labeledShortcut, config *CategoriesConfig) []keywordSuggestion {
+	files := make(map[string][]string)
+	var tokens []string
+	for _, name := range others {
+		for _, token := range nameTokens(name) {
+			if files[token] == nil {
+				tokens = append(tokens, token)
+			}
+			files[token] = append(files[token], name)
+		}
+	}
+
+	var suggestions []keywordSuggestion
+	for _, token := range tokens {
+		labels := make(map[string]int)
+		total := 0
+		for _, item := range corpus {
+			if strings.Contains(strings.ToLower(nfc(item.Name)), token) {
+				labels[item.Category]++
+				total++
+			}
+		}
+		best, support := "", 0
+		for _, category := range sortedLabelKeys(labels) {
+			if labels[category] > support {
+				best, support = category, labels[category]
+			}
+		}
+		if best == "" || best == string(CategoryOther) {
+			continue
+		}
+		if _, ok := config.Categories[best]; !ok {
+			continue
+		}
+		if float64(support)/float64(total) < minKeywordShare {
+			continue
+		}
+		suggestions = append(suggestions, keywordSuggestion{Keyword: token, Category: best, Files: files[token], Support: support, Matches: total})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if len(suggestions[i].Files) != len(suggestions[j].Files) {
+			return len(suggestions[i].Files) > len(suggestions[j].Files)
+		}
+		if suggestions[i].Support != suggestions[j].Support {
+			return suggestions[i].Support > suggestions[j].Support
+		}
+		return suggestions[i].Keyword < suggestions[j].Keyword
+	})
+	return suggestions
+}
+
+// sortedLabelKeys returns the labels of a corpus count in order, so ties always go the same way
+func sortedLabelKeys(labels map[string]int) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addCategoryKeywords appends keywords to categories in a categories file
+// New entries are inserted as lines after each category's last keyword so comments, blank lines and
+// quoting are kept; a category without a block list of keywords has the file re-encoded instead
+// A missing file is created from the default categories first
+func addCategoryKeywords(path string, additions map[string][]string) error {
+	data, err := readYAMLFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = yaml.Marshal(getDefaultCategoriesConfig())
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	document, err := parseYAMLDocument(data)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return fmt.Errorf("%s has no categories", path)
+	}
+	categories := mappingValue(document.Content[0], "categories")
+
+	lines := strings.Split(string(data), "\n")
+	inserts := make(map[int][]string) // Lines to add after the given 0-based line
+	reencode := false
+	for _, category := range sortedKeywordKeys(additions) {
+		node := mappingValue(categories, category)
+		if node == nil {
+			return fmt.Errorf("%s has no category %q", path, category)
+		}
+		keywords := mappingValue(node, "keywords")
+		if keywords == nil {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "keywords"},
+				&yaml.Node{Kind: yaml.SequenceNode})
+			keywords = node.Content[len(node.Content)-1]
+		}
+		var prefix, eol string
+		last := len(keywords.Content) - 1
+		if last >= 0 && keywords.Style&yaml.FlowStyle == 0 && keywords.Content[last].Line <= len(lines) {
+			line := lines[keywords.Content[last].Line-1]
+			if dash := strings.Index(line, "- "); dash >= 0 && strings.TrimSpace(line[:dash]) == "" {
+				prefix = line[:dash+2]
+			}
+			if strings.HasSuffix(line, "\r") {
+				eol = "\r"
+			}
+		}
+		for _, keyword := range additions[category] {
+			keywords.Content = append(keywords.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: keyword, Style: yaml.DoubleQuotedStyle})
+			if prefix != "" {
+				at := keywords.Content[last].Line - 1
+				inserts[at] = append(inserts[at], fmt.Sprintf("%s%q%s", prefix, keyword, eol))
+			}
+		}
+		if prefix == "" {
+			reencode = true
+		}
+	}
+
+	var out strings.Builder
+	if reencode {
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+		encoder.Close()
+	} else {
+		for i, line := range lines {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString(line)
+			for _, added := range inserts[i] {
+				out.WriteString("\n" + added)
+			}
+		}
+	}
+	if err := writeFile(path, []byte(out.String()), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sortedKeywordKeys returns the categories of a keyword addition in order
+func sortedKeywordKeys(additions map[string][]string) []string {
+	keys := make([]string, 0, len(additions))
+	for key := range additions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stdinIsTerminal reports whether standard input is an interactive terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// offerKeywordSuggestions suggests keywords for the shortcuts that ended up in "other" and,
+// when answers is not nil, asks whether to add each one to the categories file
+// It returns the number of keywords added
+func offerKeywordSuggestions(categoriesPath string, config *CategoriesConfig, shortcuts []string, answers <-chan string) int {
+	var others []string
+	for _, shortcut := range shortcuts {
+		if categorizeShortcut(shortcut, config) == CategoryOther {
+			others = append(others, shortcut)
+		}
+	}
+	if len(others) == 0 {
+		return 0
+	}
+	corpus, err := parseShortcutCorpus(builtinShortcutCorpus)
+	if err != nil {
+		return 0
+	}
+	suggestions := suggestKeywords(others, corpus, config)
+	if len(suggestions) == 0 {
+		return 0
+	}
+
+	fmt.Printf("\n%s%d shortcut(s) matched no category. Keywords that could place them:\n", glyph("💡 "), len(others))
+	for _, s := range suggestions {
+		fmt.Printf("  %-16s → %-12s catches %s (corpus: %d of %d %s)\n",
+			s.Keyword, s.Category, strings.Join(s.Files, ", "), s.Support, s.Matches, s.Category)
+	}
+	if answers == nil {
+		fmt.Printf("Add them to %s to use them.\n", categoriesPath)
+		return 0
+	}
+
+	fmt.Println()
+	additions := make(map[string][]string)
+	added := 0
+	for _, s := range suggestions {
+		label := s.Category
+		if category, ok := config.Categories[s.Category]; ok && category.Name != "" {
+			label = category.Name
+		}
+		if confirm(fmt.Sprintf("Add %q to %s?", s.Keyword, label), false, answers) {
+			additions[s.Category] = append(additions[s.Category], s.Keyword)
+			added++
+		}
+	}
+	if added == 0 {
+		return 0
+	}
+	if err := addCategoryKeywords(categoriesPath, additions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 0
+	}
+	fmt.Printf("%sAdded %d keyword(s) to %s\n", glyph("✅ "), added, categoriesPath)
+	return added
+}
+
+// keywordAnswers returns the line reader to ask about suggestions with, or nil when nobody can answer
+func keywordAnswers() <-chan string {
+	if readOnly || !stdinIsTerminal() {
+		return nil
+	}
+	return stdinLines()
+}
@@ -0,0 +1,134 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestNameTokens tests that names are split into words worth suggesting
+func TestNameTokens(t *testing.T) {
+	got := nameTokens("Legends of Runeterra - Setup x64 2.lnk")
+	want := []string{"legends", "runeterra"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nameTokens() = %v, want %v", got, want)
+	}
+}
+
+// TestSuggestKeywords tests that only words the corpus ties to one category are suggested
+func TestSuggestKeywords(t *testing.T) {
+	corpus := []labeledShortcut{
+		{Name: "Apex Legends.url", Category: "game"},
+		{Name: "League of Legends.lnk", Category: "game"},
+		{Name: "Unity Hub.lnk", Category: "development"},
+		{Name: "Blender.lnk", Category: "personal"},
+		{Name: "Hub Notes.lnk", Category: "work"},
+		{Name: "Hub Photos.lnk", Category: "personal"},
+	}
+	others := []string{"Legends of Runeterra.lnk", "Apex Legends.url", "Unity Hub.lnk", "Calculator.lnk"}
+
+	suggestions := suggestKeywords(others, corpus, getDefaultCategoriesConfig())
+	var got []string
+	for _, s := range suggestions {
+		got = append(got, s.Keyword+"→"+s.Category)
+	}
+	// "hub" is split between categories, "blender"'s category isn't configured, "calculator" isn't in the corpus
+	want := []string{"legends→game", "apex→game", "unity→development"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("suggestKeywords() = %v, want %v", got, want)
+	}
+	if first := suggestions[0]; len(first.Files) != 2 || first.Support != 2 || first.Matches != 2 {
+		t.Errorf("Unexpected first suggestion: %+v", first)
+	}
+}
+
+// TestAddCategoryKeywords tests that keywords are inserted without disturbing the rest of the file
+func TestAddCategoryKeywords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.yml")
+	original := `# My categories
+categories:
+  game:
+    name: "Games"
+    icon: "🎮"
+    keywords:
+      - "steam" # the big one
+
+  work:
+    name: "Work"
+    keywords: [office]
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := addCategoryKeywords(path, map[string][]string{"game": {"legends", "apex"}}); err != nil {
+		t.Fatalf("addCategoryKeywords() returned error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := strings.Replace(original, "# the big one\n", "# the big one\n      - \"legends\"\n      - \"apex\"\n", 1)
+	if string(data) != want {
+		t.Errorf("Unexpected file:\n%s", data)
+	}
+
+	// A flow list can't take a line, so the file is re-encoded
+	if err := addCategoryKeywords(path, map[string][]string{"work": {"jira"}}); err != nil {
+		t.Fatalf("addCategoryKeywords() returned error: %v", err)
+	}
+	config, err := loadCategoriesConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Categories["work"].Keywords; !reflect.DeepEqual(got, []string{"office", "jira"}) {
+		t.Errorf("work keywords = %v", got)
+	}
+	if got := config.Categories["game"].Keywords; !reflect.DeepEqual(got, []string{"steam", "legends", "apex"}) {
+		t.Errorf("game keywords = %v", got)
+	}
+
+	if err := addCategoryKeywords(path, map[string][]string{"music": {"spotify"}}); err == nil {
+		t.Error("Expected an error for an unknown category")
+	}
+}
+
+// TestAddCategoryKeywordsMissingFile tests that a missing file starts from the default categories
+func TestAddCategoryKeywordsMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.yml")
+	if err := addCategoryKeywords(path, map[string][]string{"development": {"unity"}}); err != nil {
+		t.Fatalf("addCategoryKeywords() returned error: %v", err)
+	}
+	config, err := loadCategoriesConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Categories["development"].Keywords; !reflect.DeepEqual(got, []string{"code", "docker", "git", "unity"}) {
+		t.Errorf("development keywords = %v", got)
+	}
+	if categorizeShortcut("Unity Hub.lnk", config) != "development" {
+		t.Error("Expected the added keyword to categorize Unity Hub")
+	}
+}
+
+// TestOfferKeywordSuggestions tests that only accepted suggestions are written
+func TestOfferKeywordSuggestions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.yml")
+	answers := make(chan string, 8)
+	answers <- "y" // legends
+	close(answers) // the rest take the default, no
+
+	added := offerKeywordSuggestions(path, getDefaultCategoriesConfig(), []string{"Apex Legends.url", "Legends of Runeterra.lnk", "Steam.lnk"}, answers)
+	if added != 1 {
+		t.Fatalf("offerKeywordSuggestions() added %d keyword(s), want 1", added)
+	}
+	config, err := loadCategoriesConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Categories["game"].Keywords; !reflect.DeepEqual(got, []string{"game", "steam", "epic", "legends"}) {
+		t.Errorf("game keywords = %v", got)
+	}
+
+	if offerKeywordSuggestions(path, config, []string{"Apex Legends.url"}, nil) != 0 {
+		t.Error("Expected nothing to be added without answers")
+	}
+}
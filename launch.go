@@ -0,0 +1,238 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// launchCandidate is a shortcut on the desktop or in a mode's folder
+type launchCandidate struct {
+	Name   string
+	Path   string
+	Mode   string // Mode whose folder holds the shortcut, empty on the desktop
+	Score  int
+	Hidden bool
+}
+
+// location describes where the shortcut is
+func (c launchCandidate) location() string {
+	if c.Hidden {
+		return "hidden by " + c.Mode
+	}
+	return "desktop"
+}
+
+// fuzzyScore rates how well a shortcut name matches a query, ignoring case, the file
+// extension and normalization form; ok is false when the query's letters aren't all in the name in order
+// Exact names score highest, then prefixes, then substrings, then scattered letters with the fewest gaps
+func fuzzyScore(query, name string) (int, bool) {
+	q := strings.ToLower(nfc(strings.TrimSpace(query)))
+	n := strings.ToLower(nfc(strings.TrimSuffix(name, filepath.Ext(name))))
+	if q == "" {
+		return 0, false
+	}
+	switch {
+	case n == q:
+		return 1000, true
+	case strings.HasPrefix(n, q):
+		return 800 - len(n), true
+	case strings.Contains(n, q):
+		return 600 - strings.Index(n, q), true
+	}
+
+	queryRunes := []rune(q)
+	matched, gaps, last := 0, 0, -1
+	for i, r := range []rune(n) {
+		if matched < len(queryRunes) && r == queryRunes[matched] {
+			if last >= 0 {
+				gaps += i - last - 1
+			}
+			last = i
+			matched++
+		}
+	}
+	if matched < len(queryRunes) {
+		return 0, false
+	}
+	return 400 - gaps, true
+}
+
+// launchCandidates returns the shortcuts on the desktop and in every mode's folder matching query, best first
+func launchCandidates(config *Config, query, desktopPath, homeDir string) []launchCandidate {
+	var candidates []launchCandidate
+	add := func(folder, mode string, names []string) {
+		for _, name := range names {
+			if score, ok := fuzzyScore(query, name); ok {
+				candidates = append(candidates, launchCandidate{Name: name, Path: filepath.Join(folder, name), Mode: mode, Score: score, Hidden: mode != ""})
+			}
+		}
+	}
+	if names, err := folderEntries(desktopPath); err == nil {
+		add(desktopPath, "", names)
+	}
+	modes := config.getAvailableModes()
+	sort.Strings(modes)
+	for _, modeName := range modes {
+		destination := config.Modes[modeName].Destination
+		if destination == "" {
+			destination = fmt.Sprintf("%s_Shortcuts", modeName)
+		}
+		folder := filepath.Join(homeDir, destination)
+		if names, err := getShortcutsInFolder(folder); err == nil {
+			add(folder, modeName, names)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates
+}
+
+// launchCommand returns the command that opens a shortcut the way double-clicking it would
+func launchCommand(goos, path string) (string, []string) {
+	switch goos {
+	case "windows":
+		// Goes through the shell like Explorer, so .lnk and .url files open their targets
+		return "rundll32.exe", []string{"url.dll,FileProtocolHandler", path}
+	case "darwin":
+		return "open", []string{path}
+	default:
+		if strings.HasSuffix(strings.ToLower(path), ".desktop") {
+			// xdg-open would open a launcher in a text editor rather than run it
+			return "gio", []string{"launch", path}
+		}
+		return "xdg-open", []string{path}
+	}
+}
+
+// launchUsage records when hidden shortcuts were last launched, by NFC name
+// restore -fit counts a launch as a use, since it may not change the shortcut's access time
+type launchUsage map[string]time.Time
+
+// launchUsagePath returns the path of the launch record
+func launchUsagePath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "launches.json"), nil
+}
+
+// readLaunchUsage returns the launch record, empty if nothing has been launched yet
+func readLaunchUsage() launchUsage {
+	usage := make(launchUsage)
+	path, err := launchUsagePath()
+	if err != nil {
+		return usage
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &usage)
+	}
+	return usage
+}
+
+// recordLaunch notes that a shortcut was launched, warning on failure
+func recordLaunch(name string, now time.Time) {
+	path, err := launchUsagePath()
+	if err == nil {
+		usage := readLaunchUsage()
+		usage[nfc(name)] = now
+		var data []byte
+		if data, err = json.MarshalIndent(usage, "", "  "); err == nil {
+			if err = writeFile(path, data, 0644); err == nil {
+				ownByUser(path)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error recording launch: %v\n", err)
+	}
+}
+
+// runLaunchCommand handles the "launch" subcommand, which opens a shortcut without restoring it
+func runLaunchCommand(args []string) {
+	flags := flag.NewFlagSet("launch", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	list := flags.Bool("list", false, "List the matching shortcuts instead of launching one")
+	dryRun := flags.Bool("dry-run", false, "Show which shortcut would be launched")
+	flags.Parse(args)
+	query := strings.Join(flags.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode launch [-list] [-dry-run] [-config profile.yml] <query>")
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting desktop path: %v\n", err)
+		os.Exit(1)
+	}
+	homeDir, err := hiddenFoldersRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	candidates := launchCandidates(config, query, desktopPath, homeDir)
+	if len(candidates) == 0 {
+		fmt.Fprintf(os.Stderr, "No shortcut matches %q\n", query)
+		os.Exit(1)
+	}
+	if *list {
+		for _, candidate := range candidates {
+			fmt.Printf("  %-30s %s\n", candidate.Name, candidate.location())
+		}
+		return
+	}
+
+	// Equally good matches with different names need a more specific query
+	best := candidates[0]
+	var tied []string
+	for _, candidate := range candidates[1:] {
+		if candidate.Score == best.Score && !sameFileName(candidate.Name, best.Name) {
+			tied = append(tied, candidate.Name)
+		}
+	}
+	if len(tied) > 0 {
+		fmt.Fprintf(os.Stderr, "%q matches %s and %s; be more specific\n", query, best.Name, strings.Join(tied, ", "))
+		os.Exit(1)
+	}
+
+	if best.Hidden && config.Policy != nil && containsFold(config.Policy.AlwaysHidden, best.Name) {
+		fmt.Fprintf(os.Stderr, "Error: %s is kept hidden by machine policy and can't be launched\n", best.Name)
+		os.Exit(1)
+	}
+
+	name, cmdArgs := launchCommand(runtime.GOOS, best.Path)
+	if *dryRun {
+		fmt.Printf("[DRY RUN] Would launch: %s (%s)\n", best.Name, best.location())
+		return
+	}
+	cmd := exec.Command(name, cmdArgs...)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error launching %s with %s: %v\n", best.Name, name, err)
+		os.Exit(1)
+	}
+	cmd.Process.Release()
+	fmt.Printf("Launched: %s (%s)\n", best.Name, best.location())
+	if best.Hidden && !readOnly {
+		recordLaunch(best.Name, time.Now())
+	}
+}
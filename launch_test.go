@@ -0,0 +1,80 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFuzzyScore tests that closer matches score higher and non-matches are rejected
+func TestFuzzyScore(t *testing.T) {
+	// Exact, prefix, substring and scattered matches, best first
+	ranked := []string{"Steam.lnk", "Steam Link.lnk", "Epic Steamworks.lnk", "Sorted Team Maps.lnk"}
+	last := 1 << 30
+	for _, name := range ranked {
+		score, ok := fuzzyScore("steam", name)
+		if !ok || score >= last {
+			t.Errorf("%s: expected a lower score than the previous name, got %d, %v", name, score, ok)
+		}
+		last = score
+	}
+	if _, ok := fuzzyScore("steam", "Discord.lnk"); ok {
+		t.Error("Expected no match for Discord.lnk")
+	}
+	if _, ok := fuzzyScore("cafe\u0301", "Caf\u00e9.lnk"); !ok {
+		t.Error("Expected a match in the other normalization form")
+	}
+}
+
+// TestLaunchCommand tests the command used to open a shortcut on each platform
+func TestLaunchCommand(t *testing.T) {
+	tests := []struct {
+		goos, path, want string
+	}{
+		{"windows", `C:\Games\Steam.lnk`, `rundll32.exe url.dll,FileProtocolHandler C:\Games\Steam.lnk`},
+		{"darwin", "/Games/Steam.app", "open /Games/Steam.app"},
+		{"linux", "/Games/steam.desktop", "gio launch /Games/steam.desktop"},
+		{"linux", "/Games/notes.txt", "xdg-open /Games/notes.txt"},
+	}
+	for _, tt := range tests {
+		name, args := launchCommand(tt.goos, tt.path)
+		if got := strings.Join(append([]string{name}, args...), " "); got != tt.want {
+			t.Errorf("launchCommand(%s) = %q, want %q", tt.goos, got, tt.want)
+		}
+	}
+}
+
+// TestLaunchCandidates tests searching the desktop and hidden folders, and that launches count for restore -fit
+func TestLaunchCandidates(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	games := filepath.Join(dir, "Games")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(games, 0755)
+	os.WriteFile(filepath.Join(desktop, "Steam Guide.pdf"), nil, 0644)
+	for _, name := range []string{"Steam.lnk", "Epic.lnk"} {
+		path := filepath.Join(games, name)
+		os.WriteFile(path, nil, 0644)
+		old := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(path, old, old)
+	}
+
+	config := &Config{Modes: map[string]ModeConfig{"gamemode": {Destination: "Games"}}}
+	candidates := launchCandidates(config, "steam", desktop, dir)
+	if len(candidates) != 2 || candidates[0].Name != "Steam.lnk" || !candidates[0].Hidden || candidates[0].Mode != "gamemode" {
+		t.Fatalf("Expected the hidden Steam.lnk first, got %+v", candidates)
+	}
+	if candidates[1].location() != "desktop" {
+		t.Errorf("Expected the guide on the desktop, got %s", candidates[1].location())
+	}
+
+	recordLaunch("Epic.lnk", time.Now())
+	if fit := fitCandidates(config, []string{"gamemode"}, dir); len(fit) != 2 || fit[0].Name != "Epic.lnk" {
+		t.Errorf("Expected the launched Epic.lnk to rank first, got %+v", fit)
+	}
+}
@@ -0,0 +1,244 @@
+package focusmode
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// lintConfig returns warnings about configuration that loads but is likely to misbehave
+func lintConfig(c *Config) []string {
+	var warnings []string
+
+	if len(c.Modes) > 0 {
+		if _, exists := c.Modes[c.DefaultMode]; !exists {
+			warnings = append(warnings, fmt.Sprintf("default_mode '%s' is not defined in modes", c.DefaultMode))
+		}
+	}
+	if c.Meeting.Enabled && c.Meeting.AutoStart && !c.Calendar.Enabled {
+		warnings = append(warnings, "meeting.auto_start reads meetings from your calendar; set calendar.enabled and run 'focusmode calendar login'")
+	}
+
+	if c.Discord.Enabled && c.Discord.ClientID == "" {
+		warnings = append(warnings, "discord.enabled needs discord.client_id, the ID of a Discord application; sessions aren't shown on Discord")
+	}
+
+	if c.ScreenLock.Enabled {
+		if _, err := c.ScreenLock.endOfDay(); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		if _, exists := c.Modes[c.screenLockMode()]; !exists {
+			warnings = append(warnings, fmt.Sprintf("screen_lock.mode '%s' is not defined in modes", c.screenLockMode()))
+		}
+	}
+
+	if _, invalid := c.ShellHook.shellHookCommands(); len(invalid) > 0 {
+		warnings = append(warnings, fmt.Sprintf("shell_hook.commands %v aren't plain command names and are skipped", invalid))
+	}
+
+	if _, err := c.Notify.events(); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	if err := c.AppBlocking.validate(runtime.GOOS); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	if err := c.DNSBlocking.validate(); err != nil {
+		warnings = append(warnings, err.Error()+"; sites won't be blocked through DNS")
+	}
+
+	var locations []string
+	for name := range c.Locations {
+		locations = append(locations, name)
+	}
+	sort.Strings(locations)
+	for _, name := range locations {
+		switch {
+		case name == defaultLocation:
+			warnings = append(warnings, "locations.desktop is ignored; --location desktop is always the OS desktop")
+		case !locationNamePattern.MatchString(name):
+			warnings = append(warnings, fmt.Sprintf("locations.%s can't be used with --location; names are letters, digits, - and _", name))
+		case strings.TrimSpace(c.Locations[name]) == "":
+			warnings = append(warnings, fmt.Sprintf("locations.%s has no folder", name))
+		}
+	}
+
+	warnings = append(warnings, c.lintPinned()...)
+	warnings = append(warnings, c.lintZones()...)
+	warnings = append(warnings, c.lintRestoreHandlers()...)
+	warnings = append(warnings, c.lintCalendarSessions()...)
+	warnings = append(warnings, c.lintGC()...)
+
+	if _, err := c.Hotkeys.bindings(); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+
+	if !validRecoveryAction(c.Automation.Recovery) {
+		warnings = append(warnings, fmt.Sprintf("automation.recovery '%s' is not one of ask, restore, resume or discard; crashed sessions will be left alone", c.Automation.Recovery))
+	}
+
+	// The same shortcut moved to different folders by different modes can only be
+	// restored by the mode that moved it last
+	type placement struct {
+		mode        string
+		destination string
+	}
+	placements := make(map[string][]placement)
+	names := make(map[string]string)
+	for _, modeName := range c.getAvailableModes() {
+		modeConfig, err := c.getModeConfig(modeName)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, shortcut := range modeConfig.Shortcuts {
+			key := strings.ToLower(shortcut)
+			if seen[key] {
+				warnings = append(warnings, fmt.Sprintf("mode '%s' lists shortcut '%s' more than once", modeName, shortcut))
+				continue
+			}
+			seen[key] = true
+			if _, exists := names[key]; !exists {
+				names[key] = shortcut
+			}
+			placements[key] = append(placements[key], placement{modeName, filepath.Clean(modeConfig.Destination)})
+		}
+	}
+
+	keys := make([]string, 0, len(placements))
+	for key := range placements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		destinations := make(map[string]bool)
+		var modes []string
+		for _, p := range placements[key] {
+			destinations[strings.ToLower(p.destination)] = true
+			modes = append(modes, fmt.Sprintf("%s (%s)", p.mode, p.destination))
+		}
+		if len(destinations) > 1 {
+			warnings = append(warnings, fmt.Sprintf("shortcut '%s' is listed in modes with different destinations: %s; restoring one mode will not find it if another moved it",
+				names[key], strings.Join(modes, ", ")))
+		}
+	}
+
+	for _, modeName := range c.getAvailableModes() {
+		modeConfig := c.Modes[modeName]
+		for _, other := range append(append([]string(nil), modeConfig.Requires...), modeConfig.ConflictsWith...) {
+			if _, exists := c.Modes[other]; !exists {
+				warnings = append(warnings, fmt.Sprintf("mode '%s' refers to undefined mode '%s'", modeName, other))
+			}
+		}
+		if _, _, err := c.planModeChange(modeName, nil); err != nil && !errors.Is(err, errModeNotFound) {
+			warnings = append(warnings, err.Error())
+		}
+		for _, excluded := range modeConfig.Exclude {
+			switch {
+			case c.Policy != nil && containsFold(c.Policy.AlwaysHidden, excluded):
+				warnings = append(warnings, fmt.Sprintf("mode '%s' excludes '%s', but the machine policy always hides it", modeName, excluded))
+			case containsFold(modeConfig.Shortcuts, excluded):
+				warnings = append(warnings, fmt.Sprintf("mode '%s' both lists and excludes '%s'; it won't be moved", modeName, excluded))
+			}
+		}
+		if err := validConflictPolicy(modeConfig.OnConflict); err != nil {
+			warnings = append(warnings, fmt.Sprintf("mode '%s' on_conflict: %v; restores will fail on names taken on the desktop", modeName, err))
+		}
+		// Closing apps matches them by name, so only the firewall needs full paths
+		// Variables are only known on the machine the session runs on, so any is taken as set
+		if c.AppBlocking.backend() == appBlockingFirewall {
+			for _, program := range modeConfig.BlockApps {
+				if _, err := expandProgramPath(program, `C:\Users\me`, func(string) string { return `C:\set` }); err != nil {
+					warnings = append(warnings, fmt.Sprintf("mode '%s': %v", modeName, err))
+				}
+			}
+		}
+		for _, site := range modeConfig.BlockSites {
+			if _, err := parseSiteRule(site); err != nil {
+				warnings = append(warnings, fmt.Sprintf("mode '%s': %v", modeName, err))
+			}
+		}
+	}
+
+	for _, name := range c.getAvailableRoutines() {
+		if _, err := c.getRoutine(name); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	if _, err := c.scheduleWindows(); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	for _, name := range c.getAvailablePresets() {
+		preset, err := c.getPreset(name)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		warnings = append(warnings, preset.lintRestoreAt(name)...)
+	}
+
+	return warnings
+}
+
+// runConfigCommand handles the "config" subcommand
+func runConfigCommand(args []string) {
+	if len(args) == 0 {
+		printConfigUsage()
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "validate":
+		runConfigValidate(args[1:])
+	case "edit":
+		runConfigEdit(args[1:])
+	case "docs":
+		runConfigDocs(args[1:])
+	case "schema":
+		runConfigSchema(args[1:])
+	default:
+		printConfigUsage()
+		os.Exit(1)
+	}
+}
+
+// printConfigUsage prints the usage of the "config" subcommand
+func printConfigUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: focusmode config validate [-config profile.yml]")
+	fmt.Fprintln(os.Stderr, "       focusmode config edit [-tui] [-config profile.yml] [-categories categories.yml]")
+	fmt.Fprintln(os.Stderr, "       focusmode config docs [-o config.md]")
+	fmt.Fprintln(os.Stderr, "       focusmode config schema [-file profile|categories] [-o profile.schema.json]")
+}
+
+// runConfigValidate handles "config validate", which reports problems in the config file
+func runConfigValidate(args []string) {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args)
+
+	config, err := readConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.Policy != nil {
+		fmt.Printf("Machine policy: %s (strict: %v, minimum session: %dm, always hidden: %d)\n",
+			config.Policy.path, config.Policy.Strict, config.Policy.MinSessionMinutes, len(config.Policy.AlwaysHidden))
+	}
+
+	warnings := lintConfig(config)
+	if len(warnings) == 0 {
+		fmt.Printf("%s%s is valid\n", glyph("✓ "), *configPath)
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Printf("%s%s\n", glyphOr("⚠ ", "Warning: "), warning)
+	}
+	fmt.Printf("\n%d problem(s) found in %s\n", len(warnings), *configPath)
+	os.Exit(1)
+}
@@ -0,0 +1,89 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLintConfigOverlappingModes tests warnings for shortcuts moved to different folders by different modes
+func TestLintConfigOverlappingModes(t *testing.T) {
+	config := &Config{
+		DefaultMode: "focusmode",
+		Modes: map[string]ModeConfig{
+			"focusmode":   {Destination: "Focus", Shortcuts: []string{"Steam.lnk", "Discord.lnk"}},
+			"gamemode":    {Destination: "Game", Shortcuts: []string{"steam.lnk"}},
+			"meetingmode": {Destination: "Focus/", Shortcuts: []string{"Discord.lnk", "Discord.lnk"}},
+		},
+	}
+
+	warnings := lintConfig(config)
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], "'meetingmode' lists shortcut 'Discord.lnk' more than once") {
+		t.Errorf("Expected duplicate warning, got %q", warnings[0])
+	}
+	if !strings.Contains(warnings[1], "'Steam.lnk'") || !strings.Contains(warnings[1], "focusmode (Focus), gamemode (Game)") {
+		t.Errorf("Expected overlap warning for Steam.lnk, got %q", warnings[1])
+	}
+}
+
+// TestLintConfigReferences tests warnings for undefined default modes and broken routines and presets
+func TestLintConfigReferences(t *testing.T) {
+	config := &Config{
+		DefaultMode: "missing",
+		Modes: map[string]ModeConfig{
+			"focusmode": {ConflictsWith: []string{"nomode"}},
+			"a":         {Requires: []string{"b"}},
+			"b":         {Requires: []string{"a"}},
+		},
+		Routines: map[string][]RoutineStep{"day": {{Mode: "focusmode", Duration: 0}}},
+		Presets:  map[string]SessionPreset{"quick": {Mode: "gone", Duration: 15}},
+	}
+
+	warnings := lintConfig(config)
+	if len(warnings) != 6 {
+		t.Fatalf("Expected 6 warnings, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], "default_mode 'missing'") {
+		t.Errorf("Expected default mode warning, got %q", warnings[0])
+	}
+}
+
+// TestLintConfigClean tests that a consistent configuration has no warnings
+func TestLintConfigClean(t *testing.T) {
+	config := &Config{
+		DefaultMode: "focusmode",
+		Modes: map[string]ModeConfig{
+			"focusmode": {Destination: "Hidden", Shortcuts: []string{"Steam.lnk"}},
+			"gamemode":  {Destination: "Hidden", Shortcuts: []string{"Steam.lnk"}},
+		},
+	}
+	if warnings := lintConfig(config); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+}
+
+// TestLintConfigExclude tests warnings for exclusions that can't take effect or cancel a listed shortcut
+func TestLintConfigExclude(t *testing.T) {
+	config := &Config{
+		DefaultMode: "focusmode",
+		Modes: map[string]ModeConfig{
+			"focusmode": {Shortcuts: []string{"Steam.lnk"}, Exclude: []string{"steam.lnk", "Recycle Bin.lnk"}},
+			"clean":     {MoveAll: true, Exclude: []string{"Solitaire.lnk"}},
+		},
+		Policy: &Policy{AlwaysHidden: []string{"Solitaire.lnk"}},
+	}
+
+	warnings := lintConfig(config)
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d: %v", len(warnings), warnings)
+	}
+	all := strings.Join(warnings, "\n")
+	if !strings.Contains(all, "mode 'clean' excludes 'Solitaire.lnk', but the machine policy always hides it") {
+		t.Errorf("Expected policy warning, got %v", warnings)
+	}
+	if !strings.Contains(all, "mode 'focusmode' both lists and excludes 'steam.lnk'") {
+		t.Errorf("Expected listed and excluded warning, got %v", warnings)
+	}
+}
@@ -0,0 +1,189 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// defaultLocation is the name of the OS desktop, which every config has
+const defaultLocation = "desktop"
+
+// locationEnv carries the --location flag to commands the daemon runs in child processes
+const locationEnv = "FOCUSMODE_LOCATION"
+
+// targetLocation is the named location commands act on, set by the global --location flag
+// When empty (or "desktop") commands act on the OS desktop
+var targetLocation string
+
+// targetLocationPath is the folder of targetLocation, set once the config has been read
+var targetLocationPath string
+
+// locationNamePattern limits location names, which are also used as folder names for their state
+var locationNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// parseValueArg extracts a leading --name flag with a value and returns the value and remaining arguments
+// args are returned unchanged when they don't start with the flag
+func parseValueArg(args []string, name string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", args, nil
+	}
+	flagName, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+	if !strings.HasPrefix(args[0], "-") || flagName != name {
+		return "", args, nil
+	}
+	if !hasValue {
+		if len(args) < 2 {
+			return "", nil, fmt.Errorf("flag needs an argument: --%s", name)
+		}
+		value, args = args[1], args[1:]
+	}
+	return value, args[1:], nil
+}
+
+// parseLocationArg extracts a leading --location flag and returns the location name and remaining arguments
+func parseLocationArg(args []string) (string, []string, error) {
+	value, rest, err := parseValueArg(args, "location")
+	if err != nil || len(rest) == len(args) {
+		return "", rest, err
+	}
+	if !locationNamePattern.MatchString(value) {
+		return "", nil, fmt.Errorf("invalid location name: %q", value)
+	}
+	return value, rest, nil
+}
+
+// locationNames returns the locations commands can target, the OS desktop first
+func (c *Config) locationNames() []string {
+	names := []string{defaultLocation}
+	var configured []string
+	for name := range c.Locations {
+		if name != defaultLocation {
+			configured = append(configured, name)
+		}
+	}
+	sort.Strings(configured)
+	return append(names, configured...)
+}
+
+// locationPath returns the folder of a configured location, with a leading ~ expanded to the home directory
+// Relative paths are taken from the home directory, so "WorkDesk" and "~/WorkDesk" are the same folder
+func (c *Config) locationPath(name string) (string, error) {
+	path, ok := c.Locations[name]
+	if !ok || name == defaultLocation {
+		return "", fmt.Errorf("unknown location %q (available: %s)", name, strings.Join(c.locationNames(), ", "))
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) || !filepath.IsAbs(path) && !isUNCPath(path) {
+		homeDir, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(homeDir, strings.TrimLeft(strings.TrimPrefix(path, "~"), `/\`))
+	}
+	return filepath.Clean(path), nil
+}
+
+// isUNCPath reports whether path is a Windows network path such as \\server\share
+func isUNCPath(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// resolveTargetLocation looks up the --location folder in the config and checks that it is there
+// It does nothing when the OS desktop is targeted
+func resolveTargetLocation(configPath string) error {
+	if targetLocation == "" || targetLocation == defaultLocation {
+		return nil
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("error loading config for --location: %w", err)
+	}
+	path, err := config.locationPath(targetLocation)
+	if err != nil {
+		return err
+	}
+	info, err := statWithTimeout(path)
+	if shareUnavailable(err) {
+		// Commands still run: moves wait for the share and restores say it is offline
+		fmt.Fprintf(os.Stderr, "Warning: location %s (%s) is on a share that is offline\n", targetLocation, path)
+		targetLocationPath = path
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("location %s (%s) is not available: %w", targetLocation, path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("location %s (%s) is not a folder", targetLocation, path)
+	}
+	targetLocationPath = path
+	return nil
+}
+
+// locationActive reports whether commands act on a named location rather than the OS desktop
+func locationActive() bool {
+	return targetLocation != "" && targetLocation != defaultLocation
+}
+
+// hiddenFoldersRoot returns the folder that mode destinations are relative to
+// For the OS desktop that is the home directory, which holds the desktop; a named location
+// likewise keeps its hidden folders next to it, on the same drive or share
+func hiddenFoldersRoot() (string, error) {
+	if !locationActive() {
+		return userHomeDir()
+	}
+	if targetLocationPath == "" {
+		return "", fmt.Errorf("location %s has not been resolved", targetLocation)
+	}
+	return filepath.Dir(targetLocationPath), nil
+}
+
+// modeFolder returns the folder a mode's shortcuts are moved into: destination under root,
+// or destination itself when it's absolute, such as D:\Hidden_Shortcuts on another drive
+func modeFolder(root, destination string) string {
+	if filepath.IsAbs(destination) {
+		return filepath.Clean(destination)
+	}
+	return filepath.Join(root, destination)
+}
+
+// expandDestination expands a destination starting with ~ to the user's home directory and
+// rejects paths that would land somewhere other than intended: ~user, which only a shell knows,
+// and Windows paths such as D:Shortcuts or \Shortcuts that depend on the current drive or folder
+func expandDestination(destination string) (string, error) {
+	if destination == "~" || strings.HasPrefix(destination, "~/") || strings.HasPrefix(destination, `~\`) {
+		home, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, destination[1:]), nil
+	}
+	if strings.HasPrefix(destination, "~") {
+		return "", fmt.Errorf("destination %q: only ~/ is expanded; use an absolute path for another user's folder", destination)
+	}
+	if runtime.GOOS == "windows" && !filepath.IsAbs(destination) &&
+		(filepath.VolumeName(destination) != "" || strings.HasPrefix(destination, `\`) || strings.HasPrefix(destination, "/")) {
+		return "", fmt.Errorf(`destination %q depends on the current drive or folder; give the full path, such as D:\Hidden_Shortcuts`, destination)
+	}
+	return destination, nil
+}
+
+// stateDir returns the directory of the manifests describing what was moved: applied modes,
+// the running session and undo history. Each named location has its own, under the data directory
+func stateDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil || !locationActive() {
+		return dir, err
+	}
+	dir = filepath.Join(dir, "locations", targetLocation)
+	if _, err := os.Stat(dir); os.IsNotExist(err) && !readOnly {
+		if err := mkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("error creating location state directory: %w", err)
+		}
+		ownByUser(dir)
+	}
+	return dir, nil
+}
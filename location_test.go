@@ -0,0 +1,182 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseLocationArg tests extraction of the global --location flag
+func TestParseLocationArg(t *testing.T) {
+	tests := []struct {
+		args         []string
+		wantLocation string
+		wantRest     []string
+	}{
+		{[]string{"--location", "workdesk", "-mode", "gamemode"}, "workdesk", []string{"-mode", "gamemode"}},
+		{[]string{"-location=vm-desktop", "status"}, "vm-desktop", []string{"status"}},
+		{[]string{"status"}, "", []string{"status"}},
+		{nil, "", nil},
+	}
+	for _, tt := range tests {
+		location, rest, err := parseLocationArg(tt.args)
+		if err != nil {
+			t.Errorf("parseLocationArg(%v) returned error: %v", tt.args, err)
+			continue
+		}
+		if location != tt.wantLocation || !reflect.DeepEqual(rest, tt.wantRest) {
+			t.Errorf("parseLocationArg(%v) = %q, %v, want %q, %v", tt.args, location, rest, tt.wantLocation, tt.wantRest)
+		}
+	}
+
+	for _, bad := range [][]string{{"--location"}, {"--location="}, {"--location", "../etc"}} {
+		if _, _, err := parseLocationArg(bad); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+}
+
+// TestLocationPath tests how configured folders are expanded
+func TestLocationPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	abs := filepath.Join(home, "elsewhere", "WorkDesk")
+	config := &Config{Locations: map[string]string{
+		"workdesk": abs,
+		"tilde":    "~/Desks/Tilde",
+		"relative": "Desks/Relative",
+		"desktop":  "/ignored",
+	}}
+
+	for name, want := range map[string]string{
+		"workdesk": abs,
+		"tilde":    filepath.Join(home, "Desks", "Tilde"),
+		"relative": filepath.Join(home, "Desks", "Relative"),
+	} {
+		if got, err := config.locationPath(name); err != nil || got != want {
+			t.Errorf("locationPath(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"nope", "desktop"} {
+		if _, err := config.locationPath(name); err == nil || !strings.Contains(err.Error(), "available: desktop, relative, tilde, workdesk") {
+			t.Errorf("locationPath(%q) error = %v", name, err)
+		}
+	}
+}
+
+// TestTargetLocation tests that a resolved location replaces the desktop, hidden folders and manifests
+func TestTargetLocation(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	workdesk := filepath.Join(home, "D", "WorkDesk")
+	if err := os.MkdirAll(workdesk, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(home, "profile.yml")
+	if err := os.WriteFile(configPath, []byte("locations:\n  workdesk: "+workdesk+"\n  gone: "+filepath.Join(home, "gone")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { targetLocation, targetLocationPath = "", "" }()
+
+	targetLocation = "gone"
+	if err := resolveTargetLocation(configPath); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("Expected a missing folder to be reported, got %v", err)
+	}
+
+	targetLocation = "workdesk"
+	if err := resolveTargetLocation(configPath); err != nil {
+		t.Fatalf("resolveTargetLocation() returned error: %v", err)
+	}
+	if desktop, _ := getDesktopPath(); desktop != workdesk {
+		t.Errorf("getDesktopPath() = %q, want %q", desktop, workdesk)
+	}
+	if root, _ := hiddenFoldersRoot(); root != filepath.Join(home, "D") {
+		t.Errorf("hiddenFoldersRoot() = %q, want the folder holding the location", root)
+	}
+	modes, _ := appliedModesPath()
+	session, _ := activeSessionPath()
+	for _, path := range []string{modes, session} {
+		if filepath.Dir(path) != filepath.Join(home, "focusmode", "locations", "workdesk") {
+			t.Errorf("Expected %s in the location's state directory", path)
+		}
+	}
+
+	targetLocation = defaultLocation
+	if root, _ := hiddenFoldersRoot(); root != home {
+		t.Errorf("hiddenFoldersRoot() = %q for the OS desktop, want %q", root, home)
+	}
+	if modes, _ := appliedModesPath(); filepath.Dir(modes) != filepath.Join(home, "focusmode") {
+		t.Errorf("Expected the OS desktop's manifests in the data directory, got %s", modes)
+	}
+}
+
+// TestLintConfigLocations tests warnings about location names and folders
+func TestLintConfigLocations(t *testing.T) {
+	config := &Config{DefaultMode: "focusmode", Locations: map[string]string{
+		"desktop":    "/somewhere",
+		"work desk":  "/work",
+		"empty":      " ",
+		"vm-desktop": `\\vmhost\Users\me\Desktop`,
+	}}
+	warnings := strings.Join(lintConfig(config), "\n")
+	for _, want := range []string{"locations.desktop is ignored", "locations.work desk can't be used", "locations.empty has no folder"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected warning %q, got:\n%s", want, warnings)
+		}
+	}
+	if strings.Contains(warnings, "vm-desktop") {
+		t.Errorf("Unexpected warning for vm-desktop:\n%s", warnings)
+	}
+}
+
+// TestModeFolder tests that absolute destinations aren't put under the home directory
+func TestModeFolder(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	if got := modeFolder(home, "Hidden_Shortcuts"); got != filepath.Join(home, "Hidden_Shortcuts") {
+		t.Errorf("Expected a relative destination under home, got %s", got)
+	}
+	other := filepath.Join(t.TempDir(), "other-drive", "Hidden_Shortcuts")
+	if got := modeFolder(home, other+string(filepath.Separator)); got != other {
+		t.Errorf("Expected %s, got %s", other, got)
+	}
+}
+
+// TestExpandDestination tests ~ expansion and the destinations getModeConfig rejects
+func TestExpandDestination(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	for destination, want := range map[string]string{
+		"~":                     home,
+		"~/Archive/Shortcuts":   filepath.Join(home, "Archive", "Shortcuts"),
+		"Hidden_Shortcuts":      "Hidden_Shortcuts",
+		"~Hidden":               "",
+		"~bob/Hidden_Shortcuts": "",
+	} {
+		got, err := expandDestination(destination)
+		if want == "" {
+			if err == nil {
+				t.Errorf("expandDestination(%q) = %q, want an error", destination, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("expandDestination(%q) = %q, %v; want %q", destination, got, err, want)
+		}
+	}
+
+	config := &Config{Modes: map[string]ModeConfig{
+		"archive": {Destination: "~/Archive"},
+		"broken":  {Destination: "~root/Archive"},
+	}}
+	if mode, err := config.getModeConfig("archive"); err != nil || mode.Destination != filepath.Join(home, "Archive") {
+		t.Errorf("Expected the destination expanded, got %+v (err %v)", mode, err)
+	}
+	if _, err := config.getModeConfig("broken"); err == nil || !strings.Contains(err.Error(), "mode 'broken'") {
+		t.Errorf("Expected an error naming the mode, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ScreenLockConfig represents the screen lock trigger settings
+type ScreenLockConfig struct {
+	Enabled bool   `yaml:"enabled" doc:"Apply a mode when the screen locks at the end of the day and restore it on the first unlock of the next day" default:"false"`
+	Mode    string `yaml:"mode" doc:"Mode to apply (uses default mode if empty)"`
+	After   string `yaml:"after" doc:"Time of day (HH:MM) from which a lock counts as the end of the day" default:"'17:00'"`
+}
+
+const (
+	defaultScreenLockAfter = "17:00"
+	screenLockPollInterval = 15 * time.Second
+)
+
+// endOfDay returns how far into the day a lock starts counting as the end of the day
+func (s ScreenLockConfig) endOfDay() (time.Duration, error) {
+	after := s.After
+	if after == "" {
+		after = defaultScreenLockAfter
+	}
+	t, err := time.Parse("15:04", after)
+	if err != nil {
+		return 0, fmt.Errorf("screen_lock.after %q is not a time like 17:00", after)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// screenLockMode returns the mode applied when the screen locks at the end of the day
+func (c *Config) screenLockMode() string {
+	if c.ScreenLock.Mode != "" {
+		return c.ScreenLock.Mode
+	}
+	return c.DefaultMode
+}
+
+// screenLockedScript prints True while the lock screen is shown on Windows
+const screenLockedScript = `[bool](Get-Process LogonUI -ErrorAction SilentlyContinue)`
+
+// screenLocked reports whether the screen of the current desktop session is locked
+func screenLocked() (bool, error) {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", screenLockedScript).Output()
+		if err != nil {
+			return false, fmt.Errorf("error querying lock screen: %w", err)
+		}
+		return strings.EqualFold(strings.TrimSpace(string(out)), "true"), nil
+	case "darwin":
+		out, err := exec.Command("ioreg", "-n", "Root", "-d1").Output()
+		if err != nil {
+			return false, fmt.Errorf("error querying lock screen: %w", err)
+		}
+		return strings.Contains(string(out), `"CGSSessionScreenIsLocked"=Yes`), nil
+	case "linux":
+		session := os.Getenv("XDG_SESSION_ID")
+		if session == "" {
+			session = "self"
+		}
+		out, err := exec.Command("loginctl", "show-session", session, "-p", "LockedHint", "--value").Output()
+		if err != nil {
+			return false, fmt.Errorf("error querying lock screen (is systemd-logind running?): %w", err)
+		}
+		return strings.TrimSpace(string(out)) == "yes", nil
+	default:
+		return false, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// lockAction is what the screen lock trigger does after an observation
+type lockAction int
+
+const (
+	lockNothing lockAction = iota
+	lockApply
+	lockRestore
+)
+
+// lockTracker decides when a lock ends the day and when an unlock starts the next one
+type lockTracker struct {
+	endOfDay  time.Duration // time of day from which a lock counts
+	wasLocked bool
+	appliedAt time.Time // when the mode was applied, zero while it isn't
+}
+
+// observe records whether the screen is locked and returns what to do about it
+// The mode is applied when the screen locks after the end of the day, and restored on the
+// first unlock of a later day; unlocking again the same evening leaves it applied
+func (t *lockTracker) observe(locked bool, now time.Time) lockAction {
+	justLocked := locked && !t.wasLocked
+	t.wasLocked = locked
+
+	if t.appliedAt.IsZero() {
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if justLocked && now.Sub(midnight) >= t.endOfDay {
+			t.appliedAt = now
+			return lockApply
+		}
+		return lockNothing
+	}
+
+	y1, m1, d1 := t.appliedAt.Date()
+	y2, m2, d2 := now.Date()
+	if !locked && (y1 != y2 || m1 != m2 || d1 != d2) {
+		t.appliedAt = time.Time{}
+		return lockRestore
+	}
+	return lockNothing
+}
+
+// lockState is the mode applied by the screen lock trigger, kept so it is restored after a restart
+type lockState struct {
+	Mode      string    `json:"mode"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// lockStatePath returns the path of the screen lock trigger's state
+func lockStatePath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lock.json"), nil
+}
+
+// readLockState returns the recorded state, or nil if the trigger hasn't applied a mode
+func readLockState(path string) (*lockState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading screen lock state: %w", err)
+	}
+	var state lockState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing screen lock state: %w", err)
+	}
+	return &state, nil
+}
+
+// writeLockState records the mode applied by the trigger
+func writeLockState(path string, state lockState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding screen lock state: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing screen lock state: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// watchScreenLock applies the configured mode when the screen locks at the end of the day and
+// restores it on the first unlock of the next day, running both through run until ctx is cancelled
+func watchScreenLock(ctx context.Context, config *Config, run commandRunner, beat func()) {
+	endOfDay, err := config.ScreenLock.endOfDay()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	path, err := lockStatePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	modeName := config.screenLockMode()
+
+	tracker := &lockTracker{endOfDay: endOfDay}
+	state, err := readLockState(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if state != nil {
+		// Restore what an earlier run applied, even if the mode setting has changed since
+		tracker.appliedAt = state.AppliedAt
+		modeName = state.Mode
+	}
+
+	fmt.Printf("Watching the screen lock (applying %s after %s)...\n", modeName, time.Time{}.Add(endOfDay).Format("15:04"))
+	for {
+		beat()
+		locked, err := screenLocked()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			switch tracker.observe(locked, time.Now()) {
+			case lockApply:
+				fmt.Printf("%s screen locked: applying %s\n", time.Now().Format(time.RFC3339), modeName)
+				spanCtx, span := startSpan(ctx, "screen-lock apply")
+				span.set("focusmode.mode", modeName)
+				output, err := run(spanCtx, "-mode", modeName)
+				span.finish(err)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error applying %s: %v\n%s", modeName, err, output)
+				}
+				if err := writeLockState(path, lockState{Mode: modeName, AppliedAt: tracker.appliedAt}); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			case lockRestore:
+				fmt.Printf("%s first unlock of the day: restoring %s\n", time.Now().Format(time.RFC3339), modeName)
+				spanCtx, span := startSpan(ctx, "screen-lock restore")
+				span.set("focusmode.mode", modeName)
+				output, err := run(spanCtx, "-restore", "-mode", modeName)
+				span.finish(err)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error restoring %s: %v\n%s", modeName, err, output)
+				}
+				if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+					fmt.Fprintf(os.Stderr, "Warning: error removing screen lock state: %v\n", err)
+				}
+				modeName = config.screenLockMode()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(screenLockPollInterval):
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package focusmode
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLockTracker tests that a mode is applied by an evening lock and restored the next morning
+func TestLockTracker(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2024, 3, d, h, m, 0, 0, time.Local) }
+	tracker := &lockTracker{endOfDay: 17 * time.Hour}
+
+	steps := []struct {
+		locked bool
+		now    time.Time
+		want   lockAction
+	}{
+		{true, day(4, 12, 30), lockNothing},  // lunch break
+		{false, day(4, 13, 30), lockNothing}, // back to work
+		{true, day(4, 18, 0), lockApply},     // end of day
+		{true, day(4, 18, 1), lockNothing},   // still locked
+		{false, day(4, 21, 0), lockNothing},  // checking something in the evening
+		{true, day(4, 21, 5), lockNothing},   // already applied
+		{false, day(5, 8, 30), lockRestore},  // first unlock in the morning
+		{false, day(5, 8, 31), lockNothing},
+		{true, day(5, 12, 0), lockNothing},
+	}
+	for i, step := range steps {
+		if got := tracker.observe(step.locked, step.now); got != step.want {
+			t.Errorf("step %d: observe(%v, %s) = %v, want %v", i, step.locked, step.now.Format("Jan 2 15:04"), got, step.want)
+		}
+	}
+}
+
+// TestScreenLockEndOfDay tests parsing the end of day time
+func TestScreenLockEndOfDay(t *testing.T) {
+	if got, err := (ScreenLockConfig{}).endOfDay(); err != nil || got != 17*time.Hour {
+		t.Errorf("Expected the 17:00 default, got %v, %v", got, err)
+	}
+	if got, err := (ScreenLockConfig{After: "18:30"}).endOfDay(); err != nil || got != 18*time.Hour+30*time.Minute {
+		t.Errorf("Expected 18:30, got %v, %v", got, err)
+	}
+	if _, err := (ScreenLockConfig{After: "6pm"}).endOfDay(); err == nil {
+		t.Error("Expected an error for a time that isn't HH:MM")
+	}
+}
+
+// TestLockState tests that the applied mode survives a restart
+func TestLockState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock.json")
+	if state, err := readLockState(path); err != nil || state != nil {
+		t.Fatalf("Expected no state, got %+v, %v", state, err)
+	}
+
+	applied := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	if err := writeLockState(path, lockState{Mode: "focusmode", AppliedAt: applied}); err != nil {
+		t.Fatalf("writeLockState() returned error: %v", err)
+	}
+	state, err := readLockState(path)
+	if err != nil || state == nil || state.Mode != "focusmode" || !state.AppliedAt.Equal(applied) {
+		t.Errorf("Unexpected state: %+v, %v", state, err)
+	}
+}
@@ -0,0 +1,284 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MeetingConfig represents the built-in meeting mode and what it does besides hiding shortcuts
+type MeetingConfig struct {
+	Enabled        bool     `yaml:"enabled" doc:"Add the built-in meetingmode mode and meeting preset" default:"false"`
+	HideCategories []string `yaml:"hide_categories" doc:"Categories from categories.yml hidden while in a meeting" default:"[game, personal]"`
+	Duration       int      `yaml:"duration" doc:"Length in minutes of the meeting preset" default:"30"`
+	AutoStart      bool     `yaml:"auto_start" doc:"Start meeting mode when a calendar meeting with a video link begins (needs calendar login)" default:"false"`
+	SlackToken     string   `yaml:"slack_token" doc:"Slack user token with users.profile:write and dnd:write scopes, to set your status and pause notifications"`
+	SlackStatus    string   `yaml:"slack_status" doc:"Slack status text while in a meeting" default:"In a meeting"`
+	SlackEmoji     string   `yaml:"slack_emoji" doc:"Slack status emoji while in a meeting" default:"':spiral_calendar_pad:'"`
+}
+
+const (
+	meetingModeName        = "meetingmode"
+	meetingPresetName      = "meeting"
+	defaultMeetingDuration = 30
+	defaultSlackStatus     = "In a meeting"
+	defaultSlackEmoji      = ":spiral_calendar_pad:"
+	meetingPollInterval    = time.Minute
+)
+
+// defaultMeetingCategories is hidden when no categories are configured; add a "personal"
+// category to categories.yml for chat, music and other apps you don't want to screen-share
+var defaultMeetingCategories = []string{"game", "personal"}
+
+// slackAPIURL is the base URL of the Slack Web API
+var slackAPIURL = "https://slack.com/api"
+
+// slackHTTPClient is used for all Slack API requests
+var slackHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// hideCategories returns the categories hidden by meeting mode
+func (m MeetingConfig) hideCategories() []string {
+	if len(m.HideCategories) == 0 {
+		return defaultMeetingCategories
+	}
+	return m.HideCategories
+}
+
+// duration returns the length of the meeting preset in minutes
+func (m MeetingConfig) duration() int {
+	if m.Duration <= 0 {
+		return defaultMeetingDuration
+	}
+	return m.Duration
+}
+
+// addBuiltinModes adds the meeting mode and preset when enabled
+// A mode or preset of the same name in the profile takes precedence
+func (c *Config) addBuiltinModes() {
+	if !c.Meeting.Enabled {
+		return
+	}
+	if _, exists := c.Modes[meetingModeName]; !exists {
+		if c.Modes == nil {
+			c.Modes = make(map[string]ModeConfig)
+		}
+		c.Modes[meetingModeName] = ModeConfig{Destination: "Meeting_Shortcuts", Categories: c.Meeting.hideCategories()}
+	}
+	if _, exists := c.Presets[meetingPresetName]; !exists {
+		if c.Presets == nil {
+			c.Presets = make(map[string]SessionPreset)
+		}
+		c.Presets[meetingPresetName] = SessionPreset{Mode: meetingModeName, Duration: c.Meeting.duration()}
+	}
+}
+
+// videoLinkPattern matches join links of common video meeting services
+var videoLinkPattern = regexp.MustCompile(`(?i)https://([a-z0-9-]+\.)*(zoom\.us/(j|my|w)/|meet\.google\.com/[a-z]|teams\.microsoft\.com/l/meetup-join|teams\.live\.com/meet|webex\.com/|whereby\.com/|meet\.jit\.si/|chime\.aws/)\S*`)
+
+// videoLink returns the first video meeting link in a calendar event, or ""
+func (m calendarMeeting) videoLink() string {
+	for _, detail := range m.Details {
+		if link := videoLinkPattern.FindString(detail); link != "" {
+			return link
+		}
+	}
+	return ""
+}
+
+// meetingTracker remembers which meetings already triggered meeting mode
+type meetingTracker struct {
+	handled map[string]time.Time // meeting ID to end time
+}
+
+// due returns the video meeting in progress at now that hasn't been handled yet, and marks it handled
+func (t *meetingTracker) due(meetings []calendarMeeting, now time.Time) *calendarMeeting {
+	if t.handled == nil {
+		t.handled = make(map[string]time.Time)
+	}
+	for id, end := range t.handled {
+		if end.Before(now) {
+			delete(t.handled, id)
+		}
+	}
+	for i, meeting := range meetings {
+		if _, done := t.handled[meeting.ID]; done || now.Before(meeting.Start) || !now.Before(meeting.End) {
+			continue
+		}
+		if meeting.videoLink() == "" {
+			continue
+		}
+		t.handled[meeting.ID] = meeting.End
+		return &meetings[i]
+	}
+	return nil
+}
+
+// watchMeetings polls the calendar and starts meeting mode when a meeting with a video link begins
+// The session is backdated to the start of the meeting so it ends with it
+// It calls beat after every poll and returns when ctx is cancelled
+func watchMeetings(ctx context.Context, config *Config, opts sessionOptions, beat func()) {
+	provider, err := getCalendarProvider(config.Calendar, config.Retry.Network)
+	if err == nil {
+		var tokenPath string
+		if tokenPath, err = calendarTokenPath(); err == nil {
+			pollMeetings(ctx, config, opts, beat, provider, tokenPath)
+			return
+		}
+	}
+	// Retrying can't fix the configuration; wait without beating so the daemon reports the loop as unhealthy
+	fmt.Fprintf(os.Stderr, "Error: cannot watch for meetings: %v\n", err)
+	<-ctx.Done()
+}
+
+// pollMeetings is the loop of watchMeetings
+func pollMeetings(ctx context.Context, config *Config, opts sessionOptions, beat func(), provider *calendarProvider, tokenPath string) {
+
+	fmt.Printf("Watching %s for meetings with a video link...\n", provider.name)
+	tracker := &meetingTracker{}
+	answers := stdinLines()
+
+	for {
+		beat()
+		now := time.Now()
+		meetings, err := currentMeetings(provider, config.Calendar, tokenPath, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		if meeting := tracker.due(meetings, now); meeting != nil && !isSessionRunning() {
+			duration := int((meeting.End.Sub(meeting.Start) + time.Minute - 1) / time.Minute)
+			notifyAll([]Notifier{consoleNotifier{}}, "FocusMode", fmt.Sprintf("%s has started", meeting.Title))
+			if config.Meeting.AutoStart {
+				action := fmt.Sprintf("Starting meeting mode until %s", meeting.End.Local().Format("15:04"))
+				ticker := time.NewTicker(time.Second)
+				proceed := waitGracePeriod(action, config.Automation.graceSeconds(), answers, ticker.C, os.Stdout)
+				ticker.Stop()
+				if proceed {
+					startRetroactiveSession(config, meetingModeName, duration, meeting.Start, answers, opts)
+				}
+			} else if confirm(fmt.Sprintf("Start meeting mode until %s?", meeting.End.Local().Format("15:04")), true, answers) {
+				startRetroactiveSession(config, meetingModeName, duration, meeting.Start, answers, opts)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(meetingPollInterval):
+		}
+	}
+}
+
+// currentMeetings returns the calendar events in progress at now
+func currentMeetings(provider *calendarProvider, config CalendarConfig, tokenPath string, now time.Time) ([]calendarMeeting, error) {
+	accessToken, err := validAccessToken(provider, config, tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	return listMeetings(provider, accessToken, now, now.Add(time.Minute))
+}
+
+// slackStatusHook sets a Slack status and pauses Slack notifications for the length of a meeting
+type slackStatusHook struct {
+	config MeetingConfig
+	retry  RetryPolicy
+}
+
+// slackCall calls a Slack Web API method with form values, retrying under policy
+func slackCall(policy RetryPolicy, token, method string, values url.Values) error {
+	req, err := http.NewRequest(http.MethodPost, slackAPIURL+"/"+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := doWithRetry(policy, slackHTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("error contacting Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error decoding Slack response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack %s failed: %s", method, result.Error)
+	}
+	return nil
+}
+
+// setStatus sets or clears the Slack status; an expiration makes Slack clear it even if FocusMode can't
+func (h *slackStatusHook) setStatus(text, emoji string, expiration time.Time) error {
+	profile := map[string]interface{}{"status_text": text, "status_emoji": emoji, "status_expiration": 0}
+	if !expiration.IsZero() {
+		profile["status_expiration"] = expiration.Unix()
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return slackCall(h.retry, h.config.SlackToken, "users.profile.set", url.Values{"profile": {string(data)}})
+}
+
+// OnStart sets the meeting status and snoozes notifications until the session ends
+func (h *slackStatusHook) OnStart(fs *FocusSession) {
+	text, emoji := h.config.SlackStatus, h.config.SlackEmoji
+	if text == "" {
+		text = defaultSlackStatus
+	}
+	if emoji == "" {
+		emoji = defaultSlackEmoji
+	}
+	remaining := fs.remaining()
+	if err := h.setStatus(text, emoji, time.Now().Add(remaining)); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not set Slack status: %v\n", err)
+	}
+	minutes := strconv.Itoa(int((remaining + time.Minute - 1) / time.Minute))
+	if err := slackCall(h.retry, h.config.SlackToken, "dnd.setSnooze", url.Values{"num_minutes": {minutes}}); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not pause Slack notifications: %v\n", err)
+	}
+}
+
+// OnExtend moves the status expiry and the snooze to the new end of the session
+func (h *slackStatusHook) OnExtend(fs *FocusSession) {
+	h.OnStart(fs)
+}
+
+// OnPause does nothing
+func (h *slackStatusHook) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (h *slackStatusHook) OnResume(fs *FocusSession) {}
+
+// OnEnd clears the status and ends the snooze, which matters when a meeting ends early
+func (h *slackStatusHook) OnEnd(fs *FocusSession) {
+	if err := h.setStatus("", "", time.Time{}); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not clear Slack status: %v\n", err)
+	}
+	if err := slackCall(h.retry, h.config.SlackToken, "dnd.endSnooze", nil); err != nil && !strings.Contains(err.Error(), "snooze_not_active") {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not resume Slack notifications: %v\n", err)
+	}
+}
+
+// DryRun reports the Slack changes
+func (h *slackStatusHook) DryRun(fs *FocusSession) []string {
+	text := h.config.SlackStatus
+	if text == "" {
+		text = defaultSlackStatus
+	}
+	return []string{
+		fmt.Sprintf("set your Slack status to %q for %s", text, formatDuration(fs.Duration)),
+		fmt.Sprintf("pause Slack notifications for %s", formatDuration(fs.Duration)),
+	}
+}
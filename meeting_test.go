@@ -0,0 +1,216 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAddBuiltinModes tests the opt-in meeting mode and preset and that the profile can override them
+func TestAddBuiltinModes(t *testing.T) {
+	config := &Config{}
+	config.addBuiltinModes()
+	if len(config.Modes) != 0 || len(config.Presets) != 0 {
+		t.Fatalf("Expected nothing added while disabled, got %v %v", config.Modes, config.Presets)
+	}
+
+	config.Meeting.Enabled = true
+	config.addBuiltinModes()
+	mode, exists := config.Modes[meetingModeName]
+	if !exists || mode.Destination != "Meeting_Shortcuts" || strings.Join(mode.Categories, ",") != "game,personal" {
+		t.Errorf("Unexpected built-in mode: %+v", mode)
+	}
+	if preset := config.Presets[meetingPresetName]; preset.Mode != meetingModeName || preset.Duration != defaultMeetingDuration {
+		t.Errorf("Unexpected built-in preset: %+v", preset)
+	}
+
+	own := &Config{
+		Modes:   map[string]ModeConfig{meetingModeName: {Destination: "Mine"}},
+		Meeting: MeetingConfig{Enabled: true, Duration: 45, HideCategories: []string{"game"}},
+	}
+	own.addBuiltinModes()
+	if own.Modes[meetingModeName].Destination != "Mine" {
+		t.Errorf("Expected the profile's meetingmode to win, got %+v", own.Modes[meetingModeName])
+	}
+	if own.Presets[meetingPresetName].Duration != 45 {
+		t.Errorf("Expected configured duration, got %+v", own.Presets[meetingPresetName])
+	}
+}
+
+// TestGetModeConfigCategories tests that a mode with categories picks up matching desktop files
+func TestGetModeConfigCategories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	desktop := filepath.Join(home, "Desktop")
+	os.MkdirAll(desktop, 0755)
+	for _, name := range []string{"Steam.lnk", "Notes.txt", "Epic Games.lnk"} {
+		os.WriteFile(filepath.Join(desktop, name), nil, 0644)
+	}
+
+	config := &Config{Modes: map[string]ModeConfig{
+		"meetingmode": {Shortcuts: []string{"steam.lnk", "Slack.lnk"}, Categories: []string{"game"}},
+	}}
+	modeConfig, err := config.getModeConfig("meetingmode")
+	if err != nil {
+		t.Fatalf("getModeConfig() returned error: %v", err)
+	}
+	if got := strings.Join(modeConfig.Shortcuts, ","); got != "steam.lnk,Slack.lnk,Epic Games.lnk" {
+		t.Errorf("Unexpected shortcuts: %s", got)
+	}
+	if len(config.Modes["meetingmode"].Shortcuts) != 2 {
+		t.Error("Expected the configured shortcut list to be left unchanged")
+	}
+}
+
+// TestVideoLink tests recognizing video meeting links in event details
+func TestVideoLink(t *testing.T) {
+	tests := []struct {
+		details []string
+		want    string
+	}{
+		{[]string{"Room 4", "Join: https://example.zoom.us/j/123456?pwd=abc"}, "https://example.zoom.us/j/123456?pwd=abc"},
+		{[]string{"", "", "https://meet.google.com/abc-defg-hij"}, "https://meet.google.com/abc-defg-hij"},
+		{[]string{"https://teams.microsoft.com/l/meetup-join/19%3ameeting"}, "https://teams.microsoft.com/l/meetup-join/19%3ameeting"},
+		{[]string{"Lunch at https://zoom.us/pricing", "Cafeteria"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := (calendarMeeting{Details: tt.details}).videoLink(); got != tt.want {
+			t.Errorf("videoLink(%q) = %q, want %q", tt.details, got, tt.want)
+		}
+	}
+}
+
+// TestListMeetingsGoogle tests querying and decoding Google Calendar events
+func TestListMeetingsGoogle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("singleEvents") != "true" {
+			t.Errorf("Unexpected request: %v %v", r.Header, r.URL)
+		}
+		w.Write([]byte(`{"items": [
+			{"id": "a", "summary": "Standup", "hangoutLink": "https://meet.google.com/abc-defg-hij",
+			 "start": {"dateTime": "2024-05-01T09:00:00Z"}, "end": {"dateTime": "2024-05-01T09:15:00Z"}},
+			{"id": "b", "summary": "Holiday", "start": {"date": "2024-05-01"}, "end": {"date": "2024-05-02"}},
+			{"id": "c", "summary": "1:1", "conferenceData": {"entryPoints": [{"uri": "https://acme.zoom.us/j/1"}]},
+			 "start": {"dateTime": "2024-05-01T11:00:00+02:00"}, "end": {"dateTime": "2024-05-01T11:30:00+02:00"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider, _ := getCalendarProvider(CalendarConfig{Provider: "google"}, RetryPolicy{})
+	provider.meetingsURL = func(from, to time.Time) string { return server.URL + "/events?singleEvents=true" }
+	meetings, err := listMeetings(provider, "token", time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("listMeetings() returned error: %v", err)
+	}
+	if len(meetings) != 2 {
+		t.Fatalf("Expected all-day events to be skipped, got %+v", meetings)
+	}
+	if meetings[0].videoLink() != "https://meet.google.com/abc-defg-hij" || meetings[1].videoLink() != "https://acme.zoom.us/j/1" {
+		t.Errorf("Unexpected links: %q %q", meetings[0].videoLink(), meetings[1].videoLink())
+	}
+	if !meetings[1].Start.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected start: %v", meetings[1].Start)
+	}
+}
+
+// TestListMeetingsOutlook tests querying and decoding a Microsoft Graph calendar view
+func TestListMeetingsOutlook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Prefer") != `outlook.timezone="UTC"` {
+			t.Errorf("Expected UTC times to be requested, got %q", r.Header.Get("Prefer"))
+		}
+		w.Write([]byte(`{"value": [
+			{"id": "x", "subject": "Review", "isOnlineMeeting": true,
+			 "onlineMeeting": {"joinUrl": "https://teams.microsoft.com/l/meetup-join/abc"},
+			 "start": {"dateTime": "2024-05-01T13:00:00.0000000", "timeZone": "UTC"},
+			 "end": {"dateTime": "2024-05-01T14:00:00.0000000", "timeZone": "UTC"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider, _ := getCalendarProvider(CalendarConfig{Provider: "outlook"}, RetryPolicy{})
+	if url := provider.meetingsURL(time.Now(), time.Now()); !strings.HasPrefix(url, "https://graph.microsoft.com/v1.0/me/calendarView?") {
+		t.Errorf("Unexpected calendar view URL: %s", url)
+	}
+	provider.meetingsURL = func(from, to time.Time) string { return server.URL }
+	meetings, err := listMeetings(provider, "token", time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("listMeetings() returned error: %v", err)
+	}
+	if len(meetings) != 1 || meetings[0].videoLink() == "" || !meetings[0].End.Equal(time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected meetings: %+v", meetings)
+	}
+}
+
+// TestMeetingTrackerDue tests that each video meeting triggers once, while it is in progress
+func TestMeetingTrackerDue(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	meetings := []calendarMeeting{
+		{ID: "focus", Start: start, End: start.Add(time.Hour), Details: []string{"Desk"}},
+		{ID: "call", Start: start, End: start.Add(30 * time.Minute), Details: []string{"https://meet.google.com/abc-defg-hij"}},
+	}
+	tracker := &meetingTracker{}
+
+	if due := tracker.due(meetings, start.Add(-time.Minute)); due != nil {
+		t.Errorf("Expected nothing before the meeting, got %+v", due)
+	}
+	if due := tracker.due(meetings, start.Add(time.Minute)); due == nil || due.ID != "call" {
+		t.Fatalf("Expected the video call to be due, got %+v", due)
+	}
+	if due := tracker.due(meetings, start.Add(2*time.Minute)); due != nil {
+		t.Errorf("Expected the call to trigger only once, got %+v", due)
+	}
+	tracker.due(nil, start.Add(time.Hour))
+	if len(tracker.handled) != 0 {
+		t.Errorf("Expected finished meetings to be forgotten, got %v", tracker.handled)
+	}
+}
+
+// TestSlackStatusHook tests setting and clearing the Slack status and snooze
+func TestSlackStatusHook(t *testing.T) {
+	var calls []string
+	var profiles []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Header.Get("Authorization") != "Bearer xoxp-test" {
+			t.Errorf("Unexpected authorization: %q", r.Header.Get("Authorization"))
+		}
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/")+" "+r.Form.Get("num_minutes"))
+		if profile := r.Form.Get("profile"); profile != "" {
+			var p map[string]interface{}
+			json.Unmarshal([]byte(profile), &p)
+			profiles = append(profiles, p)
+		}
+		if r.URL.Path == "/dnd.endSnooze" {
+			w.Write([]byte(`{"ok": false, "error": "snooze_not_active"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+	saved := slackAPIURL
+	slackAPIURL = server.URL
+	t.Cleanup(func() { slackAPIURL = saved })
+
+	hook := &slackStatusHook{config: MeetingConfig{SlackToken: "xoxp-test"}}
+	fs := &FocusSession{Duration: 30 * time.Minute, StartTime: time.Now(), State: StateRunning}
+	hook.OnStart(fs)
+	hook.OnEnd(fs)
+
+	want := []string{"users.profile.set ", "dnd.setSnooze 30", "users.profile.set ", "dnd.endSnooze "}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected calls: %q", calls)
+	}
+	if len(profiles) != 2 || profiles[0]["status_text"] != defaultSlackStatus || profiles[0]["status_emoji"] != defaultSlackEmoji {
+		t.Fatalf("Unexpected profiles: %v", profiles)
+	}
+	if profiles[0]["status_expiration"].(float64) == 0 || profiles[1]["status_text"] != "" {
+		t.Errorf("Expected an expiring status that is cleared at the end, got %v", profiles)
+	}
+}
@@ -0,0 +1,118 @@
+package focusmode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// milestone is a point in a session at which a notification is emitted
+// It is either a percentage of the session elapsed or a fixed time remaining
+type milestone struct {
+	label     string
+	percent   float64       // Percentage elapsed (0 if remaining is used)
+	remaining time.Duration // Time remaining (0 if percent is used)
+}
+
+// parseMilestone parses a milestone such as "50%", "10m" or "90s"
+func parseMilestone(value string) (milestone, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent <= 0 || percent >= 100 {
+			return milestone{}, fmt.Errorf("invalid milestone %q: percentage must be between 0 and 100", value)
+		}
+		return milestone{label: value, percent: percent}, nil
+	}
+
+	remaining, err := parseDuration(value)
+	if err != nil || remaining <= 0 {
+		return milestone{}, fmt.Errorf("invalid milestone %q: use a percentage (50%%) or time remaining (10m)", value)
+	}
+	return milestone{label: value, remaining: remaining}, nil
+}
+
+// reached reports whether the session has passed this milestone
+func (m milestone) reached(fs *FocusSession) bool {
+	if m.percent > 0 {
+		return fs.elapsed() >= time.Duration(float64(fs.Duration)*m.percent/100)
+	}
+	return fs.remaining() <= m.remaining
+}
+
+// applies reports whether the milestone falls inside a session of the given length
+func (m milestone) applies(duration time.Duration) bool {
+	return m.percent > 0 || m.remaining < duration
+}
+
+// message returns the notification text for this milestone
+func (m milestone) message(fs *FocusSession) string {
+	remaining := formatDuration(fs.remaining().Round(time.Minute))
+	if m.percent == 50 {
+		return fmt.Sprintf("Halfway there, %s remaining", remaining)
+	}
+	if m.percent > 0 {
+		return fmt.Sprintf("%s of session done, %s remaining", m.label, remaining)
+	}
+	return fmt.Sprintf("%s remaining", formatDuration(m.remaining))
+}
+
+// milestoneNotifier emits configured milestones through the enabled notifier backends
+type milestoneNotifier struct {
+	milestones []milestone
+	fired      map[string]bool
+	notifiers  []Notifier
+}
+
+// newMilestoneNotifier parses the configured milestones
+func newMilestoneNotifier(values []string, notifiers []Notifier) (*milestoneNotifier, error) {
+	var milestones []milestone
+	for _, value := range values {
+		m, err := parseMilestone(value)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, m)
+	}
+	return &milestoneNotifier{
+		milestones: milestones,
+		fired:      make(map[string]bool),
+		notifiers:  notifiers,
+	}, nil
+}
+
+// OnStart does nothing; milestones are only checked while the session runs
+func (n *milestoneNotifier) OnStart(fs *FocusSession) {}
+
+// OnPause does nothing
+func (n *milestoneNotifier) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (n *milestoneNotifier) OnResume(fs *FocusSession) {}
+
+// OnEnd does nothing
+func (n *milestoneNotifier) OnEnd(fs *FocusSession) {}
+
+// OnTick notifies each milestone once when it is reached
+func (n *milestoneNotifier) OnTick(fs *FocusSession) {
+	for _, m := range n.milestones {
+		if n.fired[m.label] || !m.applies(fs.Duration) || !m.reached(fs) {
+			continue
+		}
+		n.fired[m.label] = true
+		notifyAll(n.notifiers, "FocusMode", m.message(fs))
+	}
+}
+
+// DryRun reports the milestone notifications that would be sent
+func (n *milestoneNotifier) DryRun(fs *FocusSession) []string {
+	var lines []string
+	for _, m := range n.milestones {
+		if m.applies(fs.Duration) {
+			lines = append(lines, fmt.Sprintf("notify at milestone %s", m.label))
+		}
+	}
+	return lines
+}
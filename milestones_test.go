@@ -0,0 +1,100 @@
+package focusmode
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingNotifier records the messages it is asked to deliver
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) Notify(title, message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+// TestParseMilestone tests parsing of percentage and time-remaining milestones
+func TestParseMilestone(t *testing.T) {
+	tests := []struct {
+		value         string
+		wantPercent   float64
+		wantRemaining time.Duration
+		wantErr       bool
+	}{
+		{"50%", 50, 0, false},
+		{" 75% ", 75, 0, false},
+		{"10m", 0, 10 * time.Minute, false},
+		{"90s", 0, 90 * time.Second, false},
+		{"1.5 min", 0, 90 * time.Second, false},
+		{"0%", 0, 0, true},
+		{"100%", 0, 0, true},
+		{"abc%", 0, 0, true},
+		{"-5m", 0, 0, true},
+		{"soon", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			m, err := parseMilestone(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMilestone(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if m.percent != tt.wantPercent || m.remaining != tt.wantRemaining {
+				t.Errorf("parseMilestone(%q) = %+v", tt.value, m)
+			}
+		})
+	}
+}
+
+// TestMilestoneNotifier tests that milestones fire once through all notifiers
+func TestMilestoneNotifier(t *testing.T) {
+	first := &recordingNotifier{}
+	second := &recordingNotifier{}
+	notifier, err := newMilestoneNotifier([]string{"50%", "10m", "2m", "60m"}, []Notifier{first, second})
+	if err != nil {
+		t.Fatalf("newMilestoneNotifier() returned error: %v", err)
+	}
+
+	fs := &FocusSession{
+		Duration:  30 * time.Minute,
+		StartTime: time.Now().Add(-5 * time.Minute),
+		State:     StateRunning,
+	}
+	notifier.OnTick(fs)
+	if len(first.messages) != 0 {
+		t.Fatalf("Expected no milestones after 5m, got %v", first.messages)
+	}
+
+	fs.StartTime = time.Now().Add(-15 * time.Minute)
+	notifier.OnTick(fs)
+	notifier.OnTick(fs)
+	if len(first.messages) != 1 {
+		t.Fatalf("Expected halfway milestone once, got %v", first.messages)
+	}
+
+	fs.StartTime = time.Now().Add(-29 * time.Minute)
+	notifier.OnTick(fs)
+	if len(first.messages) != 3 {
+		t.Fatalf("Expected 10m and 2m milestones, got %v", first.messages)
+	}
+	if len(second.messages) != len(first.messages) {
+		t.Errorf("Expected every notifier to receive milestones, got %v and %v", first.messages, second.messages)
+	}
+}
+
+// TestNewMilestoneNotifierInvalid tests that invalid milestones are rejected
+func TestNewMilestoneNotifierInvalid(t *testing.T) {
+	if _, err := newMilestoneNotifier([]string{"50%", "later"}, nil); err == nil {
+		t.Error("Expected error for invalid milestone")
+	}
+}
+
+// TestBuildNotifiers tests that notifier hooks are picked up as backends
+func TestBuildNotifiers(t *testing.T) {
+	notifiers := buildNotifiers([]SessionHook{newAmbientPlayer(AmbientConfig{}), newTTSAnnouncer(TTSConfig{})})
+	if len(notifiers) != 2 {
+		t.Errorf("Expected console and TTS notifiers, got %d", len(notifiers))
+	}
+}
@@ -0,0 +1,367 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// appliedMode is one layer of the applied mode stack: a mode applied from the command
+// line and the manifest of shortcuts it moved
+type appliedMode struct {
+	Mode        string        `json:"mode"`
+	AppliedAt   time.Time     `json:"applied_at"`
+	Destination string        `json:"destination"` // Folder the shortcuts were moved to
+	Shortcuts   manifestNames `json:"shortcuts"`
+	Pending     manifestNames `json:"pending,omitempty"` // Shortcuts still on the desktop, to move once an offline share returns
+}
+
+// appliedModesPath returns the path of the applied mode list
+func appliedModesPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "modes.json"), nil
+}
+
+// readAppliedModes returns the applied modes recorded at path, oldest first
+func readAppliedModes(path string) ([]appliedMode, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied modes: %w", err)
+	}
+
+	var modes []appliedMode
+	if err := json.Unmarshal(data, &modes); err != nil {
+		return nil, fmt.Errorf("error parsing applied modes: %w", err)
+	}
+	return modes, nil
+}
+
+// writeAppliedModes records the applied modes at path
+func writeAppliedModes(path string, modes []appliedMode) error {
+	data, err := json.MarshalIndent(modes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding applied modes: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing applied modes: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// updateAppliedModes reads, changes and writes back the applied mode list, warning on failure
+func updateAppliedModes(change func([]appliedMode) []appliedMode) {
+	path, err := appliedModesPath()
+	if err == nil {
+		var modes []appliedMode
+		if modes, err = readAppliedModes(path); err == nil {
+			err = writeAppliedModes(path, change(modes))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// withoutMode returns the applied modes other than modeName
+func withoutMode(modes []appliedMode, modeName string) []appliedMode {
+	var kept []appliedMode
+	for _, mode := range modes {
+		if mode.Mode != modeName {
+			kept = append(kept, mode)
+		}
+	}
+	return kept
+}
+
+// recordModeApplied puts a mode on top of the applied mode list
+// Reapplying a mode keeps the shortcuts its earlier layer moved in the manifest
+func recordModeApplied(modeName string, destination string, shortcuts []string) {
+	updateAppliedModes(func(modes []appliedMode) []appliedMode {
+		for _, mode := range modes {
+			if mode.Mode == modeName {
+				shortcuts = append(append([]string(nil), mode.Shortcuts...), shortcuts...)
+			}
+		}
+		layer := appliedMode{Mode: modeName, AppliedAt: time.Now(), Destination: destination, Shortcuts: shortcuts}
+		return append(withoutMode(modes, modeName), layer)
+	})
+}
+
+// recordModeRestored removes a mode from the applied mode list
+func recordModeRestored(modeName string) {
+	updateAppliedModes(func(modes []appliedMode) []appliedMode {
+		return withoutMode(modes, modeName)
+	})
+}
+
+// recordShortcutsRestored removes shortcuts from a mode's layer, and the layer once it is empty
+// Names match in either normalization form, since a restore moves the name as it is on disk
+func recordShortcutsRestored(modeName string, names []string) {
+	restored := make(map[string]bool)
+	for _, name := range names {
+		restored[nfc(name)] = true
+	}
+	updateAppliedModes(func(modes []appliedMode) []appliedMode {
+		var kept []appliedMode
+		for _, mode := range modes {
+			if mode.Mode == modeName {
+				var shortcuts []string
+				for _, name := range mode.Shortcuts {
+					if !restored[nfc(name)] {
+						shortcuts = append(shortcuts, name)
+					}
+				}
+				if len(shortcuts) == 0 && len(mode.Pending) == 0 {
+					continue
+				}
+				mode.Shortcuts = shortcuts
+			}
+			kept = append(kept, mode)
+		}
+		return kept
+	})
+}
+
+// activeModeNames returns the names of the applied modes, oldest first
+func activeModeNames() []string {
+	path, err := appliedModesPath()
+	if err != nil {
+		return nil
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = mode.Mode
+	}
+	return names
+}
+
+// modesConflict reports whether either mode declares a conflict with the other
+func (c *Config) modesConflict(a, b string) bool {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		for _, other := range c.Modes[pair[0]].ConflictsWith {
+			if other == pair[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// planModeChange works out how to apply modeName given the currently active modes
+// It returns the active modes to restore first because they conflict, and the modes
+// to apply in order, with required modes before the modes that need them
+func (c *Config) planModeChange(modeName string, active []string) ([]string, []string, error) {
+	isActive := make(map[string]bool)
+	for _, name := range active {
+		isActive[name] = true
+	}
+
+	var toApply []string
+	visited := make(map[string]bool)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		for _, p := range path {
+			if p == name {
+				return fmt.Errorf("mode dependency cycle: %s", strings.Join(append(path, name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		modeConfig, exists := c.Modes[name]
+		if !exists {
+			return fmt.Errorf("mode '%s' (required by %s) %w", name, path[len(path)-1], errModeNotFound)
+		}
+		for _, required := range modeConfig.Requires {
+			if err := visit(required, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visited[name] = true
+		// Required modes that are already active stay as they are
+		if name == modeName || !isActive[name] {
+			toApply = append(toApply, name)
+		}
+		return nil
+	}
+	if _, exists := c.Modes[modeName]; !exists {
+		return nil, nil, fmt.Errorf("mode '%s' %w. Available modes: %v", modeName, errModeNotFound, c.getAvailableModes())
+	}
+	if err := visit(modeName, nil); err != nil {
+		return nil, nil, err
+	}
+
+	for i, a := range toApply {
+		for _, b := range toApply[i+1:] {
+			if c.modesConflict(a, b) {
+				return nil, nil, fmt.Errorf("modes '%s' and '%s' are both needed for %s but conflict with each other", a, b, modeName)
+			}
+		}
+	}
+
+	// Restore the most recently applied conflicting modes first
+	var toRestore []string
+	for i := len(active) - 1; i >= 0; i-- {
+		for _, name := range toApply {
+			if active[i] != name && c.modesConflict(active[i], name) {
+				toRestore = append(toRestore, active[i])
+				break
+			}
+		}
+	}
+	return toRestore, toApply, nil
+}
+
+// applyModeWithDependencies applies a mode after restoring conflicting modes and applying required ones
+// It returns false if the user cancelled an interactive apply or an apply failed
+func applyModeWithDependencies(config *Config, modeName string, dryRun, interactive bool) (applied bool, err error) {
+	_, span := startSpan(context.Background(), "apply")
+	span.set("focusmode.mode", modeName)
+	span.set("focusmode.dry_run", dryRun)
+	defer func() {
+		span.set("focusmode.applied", applied)
+		span.finish(err)
+	}()
+
+	toRestore, toApply, err := config.planModeChange(modeName, activeModeNames())
+	if err != nil {
+		return false, err
+	}
+	if !dryRun {
+		if err := config.requireGuardianPIN(toApply, stdinLines(), os.Stdout); err != nil {
+			return false, err
+		}
+	}
+
+	for _, conflicting := range toRestore {
+		fmt.Printf("Mode %s conflicts with %s - restoring it first\n\n", conflicting, modeName)
+		if err := restoreShortcutsForMode(config, conflicting, dryRun); err != nil {
+			return false, err
+		}
+		fmt.Println()
+	}
+	for i, name := range toApply {
+		if i > 0 {
+			fmt.Println()
+		}
+		if name != modeName {
+			fmt.Printf("Mode %s requires %s - applying it first\n", modeName, name)
+		}
+		if applied, err := applyMode(config, name, dryRun, interactive); !applied || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// statusReport is the current state shown by the status command and the daemon API
+type statusReport struct {
+	Location string         `json:"location,omitempty"` // Named location, empty for the OS desktop
+	Modes    []appliedMode  `json:"modes"`
+	Session  *activeSession `json:"session"`
+}
+
+// currentStatus reads the applied mode stack and running session
+func currentStatus() (*statusReport, error) {
+	path, err := appliedModesPath()
+	if err != nil {
+		return nil, err
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &statusReport{Modes: modes}
+	if locationActive() {
+		status.Location = targetLocation
+	}
+	if sessionPath, err := activeSessionPath(); err == nil {
+		status.Session, err = readActiveSession(sessionPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// printStatus writes the applied modes and running session
+// config is optional and only used to show requirements
+func printStatus(w io.Writer, status *statusReport, config *Config) {
+	if status.Location != "" {
+		fmt.Fprintf(w, "Location: %s\n", status.Location)
+	}
+	if len(status.Modes) == 0 {
+		fmt.Fprintln(w, "Active modes: none")
+	} else {
+		fmt.Fprintln(w, "Active modes (bottom to top):")
+		for _, mode := range status.Modes {
+			line := fmt.Sprintf("  %-14s applied %s, %d shortcut(s)", mode.Mode, mode.AppliedAt.Local().Format("Mon 15:04"), len(mode.Shortcuts))
+			if len(mode.Pending) > 0 {
+				line += fmt.Sprintf(", %d pending until its share is back", len(mode.Pending))
+			}
+			if config != nil {
+				if requires := config.Modes[mode.Mode].Requires; len(requires) > 0 {
+					line += fmt.Sprintf(", requires %s", strings.Join(requires, ", "))
+				}
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	session := status.Session
+	if session == nil {
+		fmt.Fprintln(w, "Session: none running")
+		return
+	}
+	remaining := session.remaining(time.Now())
+	paused := ""
+	if session.PausedAt != nil {
+		paused = " (paused)"
+	}
+	if session.Break {
+		fmt.Fprintf(w, "Session: break, %s remaining%s\n", formatDuration(remaining.Round(time.Second)), paused)
+	} else {
+		fmt.Fprintf(w, "Session: %s, %s remaining%s\n", session.Mode, formatDuration(remaining.Round(time.Second)), paused)
+	}
+}
+
+// runStatusCommand handles the "status" subcommand
+func runStatusCommand(args []string) {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	asJSON := flags.Bool("json", false, "Print the status as JSON")
+	flags.Parse(args)
+
+	status, err := currentStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(status)
+		return
+	}
+
+	// The config is optional here; it is only used to show requirements
+	config, _ := readConfig(*configPath)
+	printStatus(os.Stdout, status, config)
+}
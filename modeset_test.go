@@ -0,0 +1,145 @@
+package focusmode
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPlanModeChange tests conflict and requirement resolution when applying a mode
+func TestPlanModeChange(t *testing.T) {
+	config := &Config{
+		Modes: map[string]ModeConfig{
+			"basemode":    {},
+			"focusmode":   {Requires: []string{"basemode"}, ConflictsWith: []string{"gamemode"}},
+			"gamemode":    {},
+			"meetingmode": {Requires: []string{"focusmode"}},
+			"streammode":  {ConflictsWith: []string{"basemode"}},
+			"loop1":       {Requires: []string{"loop2"}},
+			"loop2":       {Requires: []string{"loop1"}},
+			"broken":      {Requires: []string{"missing"}},
+			"clash":       {Requires: []string{"basemode", "streammode"}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		active      []string
+		wantRestore []string
+		wantApply   []string
+	}{
+		{"requirements first", "meetingmode", nil, nil, []string{"basemode", "focusmode", "meetingmode"}},
+		{"active requirement kept", "focusmode", []string{"basemode"}, nil, []string{"focusmode"}},
+		{"conflict restored", "focusmode", []string{"gamemode", "basemode"}, []string{"gamemode"}, []string{"focusmode"}},
+		{"declared on the other side", "gamemode", []string{"basemode", "focusmode"}, []string{"focusmode"}, []string{"gamemode"}},
+		{"conflict with requirement", "streammode", []string{"basemode"}, []string{"basemode"}, []string{"streammode"}},
+		{"reapply", "gamemode", []string{"gamemode"}, nil, []string{"gamemode"}},
+	}
+	for _, tt := range tests {
+		restore, apply, err := config.planModeChange(tt.mode, tt.active)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(restore, tt.wantRestore) || !reflect.DeepEqual(apply, tt.wantApply) {
+			t.Errorf("%s: got restore %v apply %v, want restore %v apply %v", tt.name, restore, apply, tt.wantRestore, tt.wantApply)
+		}
+	}
+
+	for mode, want := range map[string]string{
+		"loop1":   "cycle: loop1 -> loop2 -> loop1",
+		"broken":  "'missing' (required by broken)",
+		"clash":   "conflict with each other",
+		"nothere": "not found",
+	} {
+		_, _, err := config.planModeChange(mode, nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("planModeChange(%s) error = %v, want containing %q", mode, err, want)
+		}
+		// Lint tells missing modes, reported on their own, from the other errors
+		if missing := mode == "broken" || mode == "nothere"; errors.Is(err, errModeNotFound) != missing {
+			t.Errorf("planModeChange(%s): errors.Is(err, errModeNotFound) = %v, want %v", mode, !missing, missing)
+		}
+	}
+}
+
+// TestAppliedModesRoundTrip tests reading and writing the applied mode list
+func TestAppliedModesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modes.json")
+
+	modes, err := readAppliedModes(path)
+	if err != nil || modes != nil {
+		t.Fatalf("Expected no modes for missing file, got %v (err %v)", modes, err)
+	}
+
+	want := []appliedMode{{Mode: "basemode", Shortcuts: []string{"a.lnk"}}, {Mode: "focusmode"}}
+	if err := writeAppliedModes(path, want); err != nil {
+		t.Fatalf("writeAppliedModes() returned error: %v", err)
+	}
+	modes, err = readAppliedModes(path)
+	if err != nil {
+		t.Fatalf("readAppliedModes() returned error: %v", err)
+	}
+	if len(modes) != 2 || modes[0].Mode != "basemode" || modes[0].Shortcuts[0] != "a.lnk" {
+		t.Errorf("Unexpected modes: %+v", modes)
+	}
+
+	if kept := withoutMode(modes, "basemode"); len(kept) != 1 || kept[0].Mode != "focusmode" {
+		t.Errorf("Expected only focusmode after removal, got %+v", kept)
+	}
+}
+
+// TestRecordModeAppliedLayers tests that layers stack and reapplying a mode moves it to the top
+func TestRecordModeAppliedLayers(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	recordModeApplied("focusmode", "/home/Focus", []string{"a.lnk"})
+	recordModeApplied("meetingmode", "/home/Meeting", []string{"b.lnk"})
+	recordModeApplied("focusmode", "/home/Focus", []string{"c.lnk"})
+
+	path, err := appliedModesPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modes) != 2 || modes[0].Mode != "meetingmode" || modes[1].Mode != "focusmode" {
+		t.Fatalf("Unexpected layers: %+v", modes)
+	}
+	if !reflect.DeepEqual(modes[1].Shortcuts, manifestNames{"a.lnk", "c.lnk"}) {
+		t.Errorf("Expected reapplied layer to keep earlier shortcuts, got %v", modes[1].Shortcuts)
+	}
+
+	layer, below, ok := topLayer(modes)
+	if !ok || layer.Mode != "focusmode" || len(below) != 1 || below[0].Mode != "meetingmode" {
+		t.Errorf("Unexpected top layer %+v with %+v below", layer, below)
+	}
+	if _, _, ok := topLayer(nil); ok {
+		t.Error("Expected no top layer for an empty stack")
+	}
+}
+
+// TestAfterPop tests that a pop drops the layer unless shortcuts failed, which stay on top
+func TestAfterPop(t *testing.T) {
+	modes := []appliedMode{{Mode: "focusmode", Shortcuts: manifestNames{"a.lnk"}}, {Mode: "meetingmode", Shortcuts: manifestNames{"b.lnk", "c.lnk"}}}
+	layer, below, _ := topLayer(modes)
+
+	if left := afterPop(layer, below, nil); len(left) != 1 || left[0].Mode != "focusmode" {
+		t.Errorf("Expected the layer dropped, got %+v", left)
+	}
+	left := afterPop(layer, below, []string{"c.lnk"})
+	if len(left) != 2 || left[1].Mode != "meetingmode" || !reflect.DeepEqual(left[1].Shortcuts, manifestNames{"c.lnk"}) {
+		t.Errorf("Expected meetingmode kept with only c.lnk, got %+v", left)
+	}
+	if !reflect.DeepEqual(modes[1].Shortcuts, manifestNames{"b.lnk", "c.lnk"}) {
+		t.Errorf("Expected the stack read from disk left alone, got %+v", modes)
+	}
+}
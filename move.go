@@ -1,11 +1,13 @@
-package main
+package focusmode
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,15 +16,63 @@ import (
 
 // ModeConfig represents the configuration for a specific mode
 type ModeConfig struct {
-	Destination string   `yaml:"destination"`
-	Shortcuts   []string `yaml:"shortcuts"`
-	MoveAll     bool     `yaml:"move_all"`
+	Destination      string   `yaml:"destination" doc:"Folder the mode's shortcuts are moved into, under the home directory unless absolute (it may be on another drive)" example:"Hidden_Shortcuts"`
+	Shortcuts        []string `yaml:"shortcuts" doc:"File names on the desktop moved by the mode" example:"[Steam.lnk, Discord.lnk]"`
+	MoveAll          bool     `yaml:"move_all" doc:"Move every file on the desktop instead of only the listed shortcuts" default:"false"`
+	ColorTemperature int      `yaml:"color_temperature,omitempty" doc:"Screen color temperature in Kelvin while the mode is applied (0 = unchanged)" default:"0" example:"4000"`
+	ConflictsWith    []string `yaml:"conflicts_with,omitempty" doc:"Modes restored before this mode is applied" example:"[gamemode]"`
+	Requires         []string `yaml:"requires,omitempty" doc:"Modes applied before this mode" example:"[focusmode]"`
+	Categories       []string `yaml:"categories,omitempty" doc:"Also move desktop files in these categories from categories.yml" example:"[game]"`
+	Exclude          []string `yaml:"exclude,omitempty" doc:"Desktop files the mode never moves, even with move_all or categories (case-insensitive)" example:"[Recycle Bin.lnk, ThisPC.lnk]"`
+	PublicDesktop    bool     `yaml:"public_desktop,omitempty" doc:"On Windows, also move the mode's files from the Public Desktop shared by all users; needs an elevated FocusMode" default:"false"`
+	OnConflict       string   `yaml:"on_conflict,omitempty" doc:"What a restore does when a file of the same name is already on the desktop" enum:"fail,skip,overwrite,rename-with-suffix,keep-newer" default:"fail" example:"rename-with-suffix"`
+	DND              bool     `yaml:"dnd,omitempty" doc:"Turn on the OS do-not-disturb mode during the mode's sessions: Focus Assist on Windows, Focus on macOS and GNOME's on Linux" default:"false"`
+	BlockSites       []string `yaml:"block_sites,omitempty" doc:"Sites the companion browser extension blocks while the mode is applied, with their subdomains; a path limits an entry to that part of the site. dns_blocking also blocks them during sessions" example:"[reddit.com, youtube.com/shorts]"`
+	Zones            []string `yaml:"zones,omitempty" doc:"Only move the move_all and categories files whose icons sit in these zones (Windows); listed shortcuts are moved wherever they are" example:"[sweepable]"`
+	BlockApps        []string `yaml:"block_apps,omitempty" doc:"Programs blocked during sessions: cut off from the network with app_blocking.backend firewall, by full path with ~/ and %VARIABLES% expanded, and closed with app_blocking.close, by name" example:"['%LOCALAPPDATA%\\Discord\\Update.exe', 'C:\\Program Files (x86)\\Steam\\steam.exe']"`
 }
 
 // Config represents the YAML configuration structure
 type Config struct {
-	Modes       map[string]ModeConfig `yaml:"modes"`
-	DefaultMode string                `yaml:"default_mode"`
+	Modes            map[string]ModeConfig     `yaml:"modes" doc:"Modes by name; each names the shortcuts it hides and where they go"`
+	DefaultMode      string                    `yaml:"default_mode" doc:"Mode used when no -mode is given" example:"focusmode"`
+	Ambient          AmbientConfig             `yaml:"ambient" doc:"Ambient sound played during sessions"`
+	TTS              TTSConfig                 `yaml:"tts" doc:"Spoken announcements during sessions"`
+	Chime            ChimeConfig               `yaml:"chime" doc:"Sound played when a focus session completes and when a break starts"`
+	Notify           NotifyConfig              `yaml:"notify" doc:"Desktop notifications when a session starts, reaches halfway, breaks and completes"`
+	Milestones       []string                  `yaml:"milestones" doc:"Points at which a session notifies, as a percentage or time remaining" example:"[50%, 5m]"`
+	Routines         map[string][]RoutineStep  `yaml:"routines" doc:"Routines by name; each is a list of sessions and breaks run in order"`
+	Schedule         []ScheduleEntry           `yaml:"schedule" doc:"Times of the week the daemon applies modes and restores them again"`
+	Calendar         CalendarConfig            `yaml:"calendar" doc:"Calendar events written for completed sessions"`
+	GitHub           GitHubConfig              `yaml:"github" doc:"GitHub activity shown in session reports"`
+	WakaTime         WakaTimeConfig            `yaml:"wakatime" doc:"WakaTime coding activity shown in session reports"`
+	IDEWatch         IDEWatchConfig            `yaml:"ide_watch" doc:"Offer a session after a while of continuous IDE use"`
+	Presets          map[string]SessionPreset  `yaml:"presets" doc:"Session presets by name, started with session start -preset"`
+	Automation       AutomationConfig          `yaml:"automation" doc:"Behaviour of modes applied without user interaction"`
+	Daemon           DaemonConfig              `yaml:"daemon" doc:"Control API served by focusmode daemon"`
+	Meeting          MeetingConfig             `yaml:"meeting" doc:"Built-in meeting mode, its Slack status and calendar trigger"`
+	CalendarSessions CalendarSessionsConfig    `yaml:"calendar_sessions" doc:"Sessions started by calendar events whose title matches a pattern"`
+	Hotkeys          HotkeysConfig             `yaml:"hotkeys" doc:"Global hotkeys registered by focusmode daemon (Windows)"`
+	ScreenLock       ScreenLockConfig          `yaml:"screen_lock" doc:"Apply a mode overnight, from an end-of-day screen lock to the next morning's unlock"`
+	HiddenMenu       HiddenMenuConfig          `yaml:"hidden_menu" doc:"Folder of links to the hidden shortcuts, so they are one deliberate click away"`
+	Screenshots      ScreenshotsConfig         `yaml:"screenshots" doc:"Screenshots taken around applies and restores, to check the icon layout later"`
+	Archive          ArchiveConfig             `yaml:"archive" doc:"Weekly sweep of desktop files left untouched into a dated archive folder"`
+	GC               GCConfig                  `yaml:"gc" doc:"How long the undo history, session history and logs are kept"`
+	AutoHide         AutoHideConfig            `yaml:"auto_hide" doc:"Shortcuts hidden as soon as they appear on the desktop, outside sessions too"`
+	Tracing          TracingConfig             `yaml:"tracing" doc:"OpenTelemetry traces of applies, restores and daemon work"`
+	Retry            RetryConfig               `yaml:"retry" doc:"How file moves and network requests that fail for a passing reason are tried again"`
+	ShellHook        ShellHookConfig           `yaml:"shell_hook" doc:"Commands the shell hook refuses in the terminal during strict sessions"`
+	FolderWatch      FolderWatchConfig         `yaml:"folder_watch" doc:"Alert when files are added to or removed from the folders of applied modes by hand"`
+	AppBlocking      AppBlockingConfig         `yaml:"app_blocking" doc:"Block the session mode's block_apps from the network with Windows Firewall rules"`
+	DNSBlocking      DNSBlockingConfig         `yaml:"dns_blocking" doc:"Block the session mode's block_sites for every app through a local DNS proxy, NextDNS or Pi-hole"`
+	Discord          DiscordConfig             `yaml:"discord" doc:"Show the running session as your Discord activity (Rich Presence)"`
+	Widget           WidgetConfig              `yaml:"widget" doc:"Small always-on-top window with the countdown and goal of the running session"`
+	RestoreHandlers  map[string]RestoreHandler `yaml:"restore_handlers" doc:"Folders that restores send files of an extension to instead of the desktop, such as .url files to a bookmarks folder; keys are extensions like .url"`
+	Pinned           []string                  `yaml:"pinned" doc:"Desktop files nothing ever moves: no mode, session, archive sweep, installer rule or undo, whatever the modes list" example:"[Recycle Bin.lnk, Current Project.lnk]"`
+	Zones            map[string]ZoneConfig     `yaml:"zones" doc:"Areas of the desktop's icon grid that modes can limit themselves to (Windows); pinned is the first column and sweepable the rest unless defined here"`
+	Locations        map[string]string         `yaml:"locations" doc:"Folders other than the OS desktop that commands can act on with --location; each keeps its hidden folders next to it" example:"{workdesk: 'D:\\WorkDesk', vm-desktop: '\\\\vmhost\\Users\\me\\Desktop'}"`
+
+	Policy *Policy `yaml:"-"` // Machine policy, never read from the user's profile
 }
 
 // SessionState represents the state of a focus session
@@ -46,6 +96,20 @@ type FocusSession struct {
 	Config         *Config       // Reference to loaded config
 	State          SessionState  // Current state of the session
 	MovedShortcuts []string      // List of shortcuts that were moved during session start
+	Hooks          []SessionHook // Listeners notified of session lifecycle events
+	Break          bool          // Whether this is a break (no shortcuts are moved)
+	Goal           string        // What the session is for, shown by the countdown widget
+	Coding         *CodingStats  // Coding activity during the session (when WakaTime is enabled)
+
+	RestoreCategories []string                 // Categories restored when the session completes (empty restores all)
+	RestoreAt         map[string]time.Duration // Times of day, by category, the daemon restores what RestoreCategories kept
+	Categories        *CategoriesConfig        // Categories used to classify shortcuts for RestoreCategories
+	Allowlist         *SessionAllowlist        // Apps and sites the session expects, from its preset (nil allows all)
+
+	Stop          <-chan struct{} // Closed to end the session from outside, e.g. on daemon shutdown
+	RestoreOnStop bool            // End a stopped session normally instead of suspending it
+	Suspended     bool            // Stopped from outside with its state kept for crash recovery
+	ControlPath   string          // File polled for commands sent by other processes (see sendSessionCommand)
 }
 
 // elapsed returns the time elapsed since the session started, excluding paused time
@@ -75,6 +139,10 @@ func startFocusSession(config *Config, modeName string, duration int, autoRestor
 		return nil, fmt.Errorf("duration must be positive, got: %d minutes", duration)
 	}
 
+	if err := config.checkSessionDuration(duration); err != nil {
+		return nil, err
+	}
+
 	// Validate mode exists in configuration
 	_, err := config.getModeConfig(modeName)
 	if err != nil {
@@ -107,19 +175,20 @@ func (fs *FocusSession) organizeShortcuts() ([]string, error) {
 	}
 
 	// Get destination folder
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := hiddenFoldersRoot()
 	if err != nil {
 		return nil, fmt.Errorf("error getting home directory: %w", err)
 	}
 
-	destinationFolder := filepath.Join(homeDir, modeConfig.Destination)
+	destinationFolder := modeFolder(homeDir, modeConfig.Destination)
 
 	// Create the destination folder if it doesn't exist
 	if _, err := os.Stat(destinationFolder); os.IsNotExist(err) {
-		err := os.MkdirAll(destinationFolder, 0755)
+		err := mkdirAll(destinationFolder, 0755)
 		if err != nil {
 			return nil, fmt.Errorf("error creating destination folder: %w", err)
 		}
+		ownByUser(destinationFolder)
 		fmt.Printf("Created destination folder: %s\n", destinationFolder)
 	}
 
@@ -128,7 +197,7 @@ func (fs *FocusSession) organizeShortcuts() ([]string, error) {
 
 	if modeConfig.MoveAll {
 		// Get all shortcuts from desktop
-		allShortcuts, err := getAllDesktopShortcuts()
+		allShortcuts, err := fs.Config.desktopShortcutsToMove(modeConfig)
 		if err != nil {
 			return nil, fmt.Errorf("error getting desktop shortcuts: %w", err)
 		}
@@ -145,12 +214,16 @@ func (fs *FocusSession) organizeShortcuts() ([]string, error) {
 	failCount := 0
 
 	for _, shortcutName := range shortcutsToMove {
-		err := moveDesktopShortcut(shortcutName, destinationFolder)
+		err := fs.Config.moveModeShortcut(modeConfig, shortcutName, destinationFolder)
+		if errors.Is(err, errPinned) {
+			fmt.Println(describePinned(shortcutName))
+			continue
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error moving '%s': %v\n", shortcutName, err)
 			failCount++
 		} else {
-			fmt.Printf("✓ Moved: %s\n", shortcutName)
+			fmt.Printf("%sMoved: %s\n", glyph("✓ "), shortcutName)
 			movedShortcuts = append(movedShortcuts, shortcutName)
 			successCount++
 		}
@@ -226,37 +299,23 @@ func displayProgress(elapsed, remaining time.Duration, paused bool) {
 
 // getDesktopPath returns the desktop path for the current operating system
 func getDesktopPath() (string, error) {
-	switch runtime.GOOS {
-	case "windows":
-		desktopPath := filepath.Join(os.Getenv("USERPROFILE"), "Desktop")
-		return desktopPath, nil
-	case "darwin":
-		// On macOS, the desktop path is typically ~/Desktop.
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
+	if locationActive() {
+		if targetLocationPath == "" {
+			return "", fmt.Errorf("location %s has not been resolved", targetLocation)
 		}
-		return filepath.Join(homeDir, "Desktop"), nil
-	case "linux":
-		// On Linux, it can vary, but a common location is ~/Desktop.
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
-		}
-		return filepath.Join(homeDir, "Desktop"), nil
-	default:
-		return "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+		return targetLocationPath, nil
 	}
+	return currentPlatform.DesktopPath()
 }
 
 // moveDesktopShortcut moves a shortcut from desktop to destination directory
-func moveDesktopShortcut(shortcutName string, destinationDir string) error {
-	return moveDesktopShortcutFromPath(shortcutName, destinationDir, "")
+func (c *Config) moveDesktopShortcut(shortcutName string, destinationDir string) error {
+	return c.moveDesktopShortcutFromPath(shortcutName, destinationDir, "")
 }
 
 // moveDesktopShortcutFromPath moves a shortcut from a specific desktop path to destination directory
 // If desktopPath is empty, it uses getDesktopPath()
-func moveDesktopShortcutFromPath(shortcutName string, destinationDir string, desktopPath string) error {
+func (c *Config) moveDesktopShortcutFromPath(shortcutName string, destinationDir string, desktopPath string) error {
 	var err error
 	if desktopPath == "" {
 		desktopPath, err = getDesktopPath()
@@ -265,44 +324,112 @@ func moveDesktopShortcutFromPath(shortcutName string, destinationDir string, des
 		}
 	}
 
+	if err := c.checkPinned(desktopPath, shortcutName); err != nil {
+		return err
+	}
+
 	oldPath := filepath.Join(desktopPath, shortcutName)
 	newPath := filepath.Join(destinationDir, shortcutName)
 
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
+	missing := false
+	err = withFileRetry(c.retry().Files, func() error {
+		if _, err := os.Stat(oldPath); err != nil {
+			missing = os.IsNotExist(err)
+			if missing {
+				return nil
+			}
+			return err
+		}
+		return moveFile(oldPath, newPath)
+	})
+	if missing {
 		return fmt.Errorf("shortcut '%s' not found on desktop", shortcutName)
 	}
-
-	err = os.Rename(oldPath, newPath)
 	if err != nil {
 		return fmt.Errorf("error moving shortcut: %w", err)
 	}
 	return nil
 }
 
-// restoreShortcutToDesktop moves a shortcut from destination directory back to desktop
-func restoreShortcutToDesktop(shortcutName string, sourceDir string) error {
-	desktopPath, err := getDesktopPath()
+// restoreShortcutToDesktop moves a shortcut from destination directory back to desktop,
+// or to the public desktop when it was moved from there; it fails if the name is taken
+func (c *Config) restoreShortcutToDesktop(shortcutName string, sourceDir string) error {
+	_, err := c.restoreShortcutWithPolicy(shortcutName, sourceDir, conflictFail)
+	return err
+}
+
+// restoreShortcutWithPolicy restores a shortcut like restoreShortcutToDesktop, settling a name
+// already taken on the desktop by the given conflict policy
+func (c *Config) restoreShortcutWithPolicy(shortcutName string, sourceDir string, policy string) (restoreResult, error) {
+	desktopPath, err := desktopFor(sourceDir, shortcutName)
 	if err != nil {
-		return fmt.Errorf("error getting desktop path: %w", err)
+		return restoreResult{}, fmt.Errorf("error getting desktop path: %w", err)
 	}
+	return c.restoreShortcutTo(shortcutName, sourceDir, desktopPath, policy)
+}
 
-	sourcePath := filepath.Join(sourceDir, shortcutName)
-	destPath := filepath.Join(desktopPath, shortcutName)
+// restoreShortcutTo moves a shortcut from its hidden folder to desktopPath, which is the desktop
+// unless a restore handler sends its extension elsewhere
+func (c *Config) restoreShortcutTo(shortcutName string, sourceDir string, desktopPath string, policy string) (restoreResult, error) {
+	// An offline share would otherwise look like a missing file
+	policies := c.retry()
+	if err := checkShare(policies.Files, sourceDir); err != nil {
+		return restoreResult{}, err
+	}
+	if err := checkShare(policies.Files, desktopPath); err != nil {
+		return restoreResult{}, err
+	}
 
-	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-		return fmt.Errorf("shortcut '%s' not found in source directory", shortcutName)
+	// The folder may hold the name in another normalization form, e.g. after syncing from a Mac
+	diskName, ok := findFileName(sourceDir, shortcutName)
+	if !ok {
+		return restoreResult{}, fmt.Errorf("shortcut '%s' not found in source directory", shortcutName)
 	}
+	sourcePath := filepath.Join(sourceDir, diskName)
+	destPath := filepath.Join(desktopPath, diskName)
+	result := restoreResult{Name: diskName}
 
 	// Check if file already exists on desktop
-	if _, err := os.Stat(destPath); err == nil {
-		return fmt.Errorf("shortcut '%s' already exists on desktop", shortcutName)
+	if existing, exists := findFileName(desktopPath, diskName); exists {
+		existingPath := filepath.Join(desktopPath, existing)
+		if policy == conflictKeepNewer {
+			newer, err := hiddenIsNewer(sourcePath, existingPath)
+			if err != nil {
+				return restoreResult{}, fmt.Errorf("error comparing '%s' with the desktop's copy: %w", shortcutName, err)
+			}
+			policy = conflictSkip
+			if newer {
+				policy = conflictOverwrite
+			}
+		}
+		// Overwriting would take a pinned file off the desktop
+		if policy == conflictOverwrite && c.checkPinned(desktopPath, existing) != nil {
+			policy = conflictSkip
+		}
+		switch policy {
+		case conflictSkip:
+			return restoreResult{Name: existing, Skipped: true}, nil
+		case conflictOverwrite:
+			if err := replaceFile(policies.Files, sourcePath, existingPath); err != nil {
+				return restoreResult{}, fmt.Errorf("error replacing '%s' on desktop: %w", shortcutName, err)
+			}
+			return restoreResult{Name: existing, Replaced: true}, nil
+		case conflictRename:
+			result.Name = freeDesktopName(desktopPath, diskName)
+			destPath = filepath.Join(desktopPath, result.Name)
+		default:
+			return restoreResult{}, fmt.Errorf("shortcut '%s' already exists in %s (-on-conflict or on_conflict can skip, overwrite, rename-with-suffix or keep-newer)", shortcutName, desktopPath)
+		}
 	}
 
-	err = os.Rename(sourcePath, destPath)
+	err := withFileRetry(policies.Files, func() error { return moveFile(sourcePath, destPath) })
 	if err != nil {
-		return fmt.Errorf("error restoring shortcut: %w", err)
+		if desktopPath == publicDesktopPath() {
+			err = publicDesktopError(shortcutName, err)
+		}
+		return restoreResult{}, fmt.Errorf("error restoring shortcut: %w", err)
 	}
-	return nil
+	return result, nil
 }
 
 // getShortcutsInFolder returns all files in a given folder
@@ -322,42 +449,162 @@ func getShortcutsInFolder(folderPath string) ([]string, error) {
 	return shortcuts, nil
 }
 
-// loadConfig loads the configuration from profile.yml
+// loadConfig loads the configuration from profile.yml and prints lint warnings
 func loadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
+	config, err := readConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Warn about likely mistakes without refusing to run
+	for _, warning := range lintConfig(config) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	return config, nil
+}
+
+// readConfig loads and parses the YAML configuration file without linting it
+func readConfig(configPath string) (*Config, error) {
+	data, err := readYAMLFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
+	config, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
+	policy, err := loadPolicy(machinePolicyPath)
+	if err != nil {
+		return nil, err
+	}
+	config.applyPolicy(policy)
+
+	return config, nil
+}
+
+// parseConfig parses a profile, migrating deprecated keys and adding the defaults and built-in modes
+// The profile may be someone else's, so it is checked against the YAML limits first
+func parseConfig(data []byte) (*Config, error) {
+	document, err := parseYAMLDocument(data)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing YAML: %w", err)
 	}
+	migrateDeprecatedKeys(document, deprecatedConfigKeys, warnDeprecatedOnce)
+
+	var config Config
+	if document.Kind != 0 {
+		if err := document.Decode(&config); err != nil {
+			return nil, fmt.Errorf("error parsing YAML: %w", err)
+		}
+	}
 
 	// Set default mode if not specified
 	if config.DefaultMode == "" {
 		config.DefaultMode = "focusmode"
 	}
-
+	config.addBuiltinModes()
 	return &config, nil
 }
 
+// errModeNotFound is returned for a mode name the config doesn't define
+var errModeNotFound = errors.New("not found in configuration")
+
 // getModeConfig returns the configuration for a specific mode
 func (c *Config) getModeConfig(modeName string) (*ModeConfig, error) {
 	modeConfig, exists := c.Modes[modeName]
 	if !exists {
-		return nil, fmt.Errorf("mode '%s' not found in configuration. Available modes: %v", modeName, c.getAvailableModes())
+		return nil, fmt.Errorf("mode '%s' %w. Available modes: %v", modeName, errModeNotFound, c.getAvailableModes())
 	}
 
 	// Set default destination if not specified
 	if modeConfig.Destination == "" {
 		modeConfig.Destination = fmt.Sprintf("%s_Shortcuts", modeName)
 	}
+	destination, err := expandDestination(modeConfig.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("mode '%s': %w", modeName, err)
+	}
+	modeConfig.Destination = destination
+
+	if len(modeConfig.Categories) > 0 {
+		modeConfig.Shortcuts = append(modeConfig.Shortcuts[:len(modeConfig.Shortcuts):len(modeConfig.Shortcuts)],
+			c.inModeZones(&modeConfig, desktopShortcutsInCategories(modeConfig.Categories, modeConfig.Shortcuts))...)
+	}
+	modeConfig.Shortcuts = c.withoutExcluded(&modeConfig, modeConfig.Shortcuts)
 
 	return &modeConfig, nil
 }
 
+// exclusions returns the files a mode never moves
+// Shortcuts the machine policy always hides can't be excluded
+func (c *Config) exclusions(modeConfig *ModeConfig) []string {
+	var excluded []string
+	for _, name := range modeConfig.Exclude {
+		if c.Policy == nil || !containsFold(c.Policy.AlwaysHidden, name) {
+			excluded = append(excluded, name)
+		}
+	}
+	return excluded
+}
+
+// withoutExcluded returns names less the files the mode excludes
+func (c *Config) withoutExcluded(modeConfig *ModeConfig, names []string) []string {
+	excluded := c.exclusions(modeConfig)
+	if len(excluded) == 0 {
+		return names
+	}
+	var kept []string
+	for _, name := range names {
+		if !containsFold(excluded, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// desktopShortcutsToMove returns every file on the desktop that a move_all mode moves,
+// including the public desktop's for a mode that opts in
+func (c *Config) desktopShortcutsToMove(modeConfig *ModeConfig) ([]string, error) {
+	shortcuts, err := getAllDesktopShortcuts()
+	if err != nil {
+		return nil, err
+	}
+	shortcuts = append(shortcuts, publicDesktopShortcuts(modeConfig, shortcuts)...)
+	return c.inModeZones(modeConfig, c.withoutExcluded(modeConfig, shortcuts)), nil
+}
+
+// desktopShortcutsInCategories returns the desktop files in the given categories that aren't already listed
+// Categories come from categories.yml in the working directory, like -list-desktop
+func desktopShortcutsInCategories(categories []string, listed []string) []string {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return nil
+	}
+	files, err := getShortcutsInFolder(desktopPath)
+	if err != nil {
+		return nil
+	}
+	categoriesConfig, err := loadCategoriesConfig("")
+	if err != nil {
+		categoriesConfig = getDefaultCategoriesConfig()
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range listed {
+		seen[strings.ToLower(name)] = true
+	}
+	var extra []string
+	matched, _ := splitShortcutsByCategory(files, categories, categoriesConfig)
+	for _, name := range matched {
+		if !seen[strings.ToLower(name)] {
+			extra = append(extra, name)
+		}
+	}
+	return extra
+}
+
 // getAvailableModes returns a list of available mode names
 func (c *Config) getAvailableModes() []string {
 	modes := make([]string, 0, len(c.Modes))
@@ -407,15 +654,18 @@ const (
 
 // CategoryConfig represents the configuration for a category
 type CategoryConfig struct {
-	Name     string   `yaml:"name"`
-	Icon     string   `yaml:"icon"`
-	Keywords []string `yaml:"keywords"`
+	Name     string   `yaml:"name" doc:"Display name of the category" example:"Games"`
+	Icon     string   `yaml:"icon" doc:"Emoji shown next to the category" example:"🎮"`
+	Keywords []string `yaml:"keywords" doc:"Case-insensitive substrings of file names that belong to the category" example:"[steam, epic]"`
 }
 
 // CategoriesConfig represents the categories configuration structure
 type CategoriesConfig struct {
-	Categories    map[string]CategoryConfig `yaml:"categories"`
-	CategoryOrder []string                  `yaml:"category_order"`
+	Categories    map[string]CategoryConfig `yaml:"categories" doc:"Categories by key"`
+	CategoryOrder []string                  `yaml:"category_order" doc:"Order in which categories are matched and listed" example:"[games, work]"`
+	Categorizer   CategorizerConfig         `yaml:"categorizer" doc:"External program that categorizes files ahead of the keywords"`
+
+	plugin *categorizerPlugin // Set by SetCategorizer, or from Categorizer when loaded
 }
 
 // loadCategoriesConfig loads the categories configuration from categories.yml
@@ -425,23 +675,44 @@ func loadCategoriesConfig(configPath string) (*CategoriesConfig, error) {
 		configPath = "categories.yml"
 	}
 
-	data, err := os.ReadFile(configPath)
+	data, err := readYAMLFile(configPath)
+	if errors.Is(err, errYAMLTooLarge) {
+		return nil, fmt.Errorf("error reading categories: %w", err)
+	}
 	if err != nil {
 		// Return default categories if file doesn't exist
 		return getDefaultCategoriesConfig(), nil
 	}
+	config, err := parseCategoriesConfig(data)
+	if err != nil {
+		return nil, err
+	}
 
-	var config CategoriesConfig
-	err = yaml.Unmarshal(data, &config)
+	if command := config.Categorizer.Command; len(command) > 0 {
+		config.SetCategorizer(execCategorizer{command: command, timeout: config.Categorizer.timeout()}, config.Categorizer.minConfidence())
+	}
+
+	return config, nil
+}
+
+// parseCategoriesConfig parses a categories file, checked against the YAML limits first, and
+// adds the default category order
+func parseCategoriesConfig(data []byte) (*CategoriesConfig, error) {
+	document, err := parseYAMLDocument(data)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing categories YAML: %w", err)
 	}
+	var config CategoriesConfig
+	if document.Kind != 0 {
+		if err := document.Decode(&config); err != nil {
+			return nil, fmt.Errorf("error parsing categories YAML: %w", err)
+		}
+	}
 
 	// Ensure category_order is set
 	if len(config.CategoryOrder) == 0 {
 		config.CategoryOrder = []string{"game", "development", "work", "other"}
 	}
-
 	return &config, nil
 }
 
@@ -469,9 +740,24 @@ func getDefaultCategoriesConfig() *CategoriesConfig {
 	}
 }
 
-// categorizeShortcut attempts to categorize a shortcut based on its name using the config
+// categorizeShortcut attempts to categorize a desktop shortcut based on its name using the config
 func categorizeShortcut(name string, categoriesConfig *CategoriesConfig) ShortcutCategory {
-	nameLower := strings.ToLower(name)
+	path := name
+	if categoriesConfig.plugin != nil {
+		if desktopPath, err := getDesktopPath(); err == nil {
+			path = filepath.Join(desktopPath, name)
+		}
+	}
+	return categorizeFile(path, categoriesConfig)
+}
+
+// categorizeFile categorizes the file at path, asking the configured categorizer first and
+// matching the keywords against its name otherwise
+func categorizeFile(path string, categoriesConfig *CategoriesConfig) ShortcutCategory {
+	if category, ok := categoriesConfig.pluginCategory(path); ok {
+		return category
+	}
+	nameLower := strings.ToLower(filepath.Base(path))
 
 	// Check categories in order (first match wins)
 	for _, categoryID := range categoriesConfig.CategoryOrder {
@@ -561,7 +847,7 @@ func listDesktopFilesWithConfig(categoriesConfig *CategoriesConfig) {
 			}
 		}
 
-		fmt.Printf("%s %s (%d):\n", icon, label, len(files))
+		fmt.Printf("%s%s (%d):\n", glyph(icon+" "), label, len(files))
 		for i, file := range files {
 			// Show file type indicator
 			ext := filepath.Ext(file)
@@ -579,9 +865,9 @@ func listDesktopFilesWithConfig(categoriesConfig *CategoriesConfig) {
 			suggestedMode := getModeForCategory(fileCategory)
 			modeIndicator := ""
 			if suggestedMode == "gamemode" {
-				modeIndicator = " → 🎮 GameMode (moves work tools)"
+				modeIndicator = glyphOr(" → 🎮 ", ", ") + "GameMode (moves work tools)"
 			} else {
-				modeIndicator = " → 💼 FocusMode (moves games/distractions)"
+				modeIndicator = glyphOr(" → 💼 ", ", ") + "FocusMode (moves games/distractions)"
 			}
 
 			fmt.Printf("  %d. %s%s%s\n", i+1, file, typeIndicator, modeIndicator)
@@ -608,7 +894,7 @@ func listDesktopFilesWithConfig(categoriesConfig *CategoriesConfig) {
 					icon = "📁"
 				}
 			}
-			fmt.Printf("%s %s: %d\n", icon, label, len(files))
+			fmt.Printf("%s%s: %d\n", glyph(icon+" "), label, len(files))
 		}
 	}
 	fmt.Printf("\nTotal: %d file(s)\n", len(shortcuts))
@@ -719,14 +1005,14 @@ func generateProfileFromDesktop(configPath string, categoriesPath string) {
 	fullYAML := header + string(yamlData)
 
 	// Write to file
-	err = os.WriteFile(configPath, []byte(fullYAML), 0644)
+	err = writeFile(configPath, []byte(fullYAML), 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing config file: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Print summary
-	fmt.Printf("✅ Generated %s\n\n", configPath)
+	fmt.Printf("%sGenerated %s\n\n", glyph("✅ "), configPath)
 	fmt.Println("Summary:")
 	fmt.Printf("  FocusMode: %d shortcut(s) (games and other distractions - moved when focusing)\n", len(focusmodeShortcuts))
 	fmt.Printf("  GameMode: %d shortcut(s) (work/development tools - moved when gaming)\n", len(gamemodeShortcuts))
@@ -737,159 +1023,520 @@ func generateProfileFromDesktop(configPath string, categoriesPath string) {
 	fmt.Printf("\nReview and edit %s if needed, then run:\n", configPath)
 	fmt.Printf("  ./focusmode -mode focusmode -dry-run\n")
 	fmt.Printf("  ./focusmode -mode gamemode -dry-run\n")
+
+	if categoriesPath == "" {
+		categoriesPath = "categories.yml"
+	}
+	if offerKeywordSuggestions(categoriesPath, categoriesConfig, shortcuts, keywordAnswers()) > 0 {
+		fmt.Println("Run -auto-config again to regenerate the profile with them.")
+	}
 }
 
 // restoreShortcutsForMode restores shortcuts from a specific mode's folder back to desktop
-func restoreShortcutsForMode(config *Config, modeName string, dryRun bool) {
-	// Get mode-specific configuration
-	modeConfig, err := config.getModeConfig(modeName)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		fmt.Fprintf(os.Stderr, "Use -list-modes to see available modes\n")
-		os.Exit(1)
-	}
+func restoreShortcutsForMode(config *Config, modeName string, dryRun bool) (err error) {
+	_, span := startSpan(context.Background(), "restore")
+	span.set("focusmode.mode", modeName)
+	span.set("focusmode.dry_run", dryRun)
+	defer func() { span.finish(err) }()
 
 	fmt.Printf("Restoring shortcuts from mode: %s\n", modeName)
+	if !dryRun {
+		// A restore that fails before moving anything leaves the mode applied
+		defer func() {
+			if err == nil {
+				recordModeRestored(modeName)
+			}
+		}()
+	}
+
+	op := &restoreOperation{config: config, modes: []string{modeName}, label: modeName, dryRun: dryRun}
+	return runOperation(config, op, &operationReport{Action: "restore", Verb: "restored", Mode: modeName}, dryRun)
+}
+
+// restoreAllShortcuts restores shortcuts from all modes back to desktop
+func restoreAllShortcuts(config *Config, dryRun bool) (err error) {
+	_, span := startSpan(context.Background(), "restore all")
+	span.set("focusmode.dry_run", dryRun)
+	defer func() { span.finish(err) }()
+
+	fmt.Println("Restoring shortcuts from all modes...")
+
+	modes := config.getAvailableModes()
+	sort.Strings(modes)
+	op := &restoreOperation{config: config, modes: modes, all: true, label: "all", dryRun: dryRun, colorTemp: true}
+	return runOperation(config, op, &operationReport{Action: "restore", Verb: "restored"}, dryRun)
+}
 
-	// Get source folder
-	homeDir, err := os.UserHomeDir()
+// restoreOperation moves the shortcuts in modes' folders back to the desktop
+type restoreOperation struct {
+	config    *Config
+	modes     []string
+	all       bool   // Restoring every mode, which skips the modes it can't read rather than failing
+	label     string // Mode recorded in the operation log: the mode, or "all"
+	dryRun    bool
+	colorTemp bool // Put back the screen's color temperature
+
+	source   string            // Folder of a single mode's shortcuts
+	policies map[string]string // Conflict policy of each mode
+}
+
+// Plan lists the shortcuts in each mode's folder that may go back to the desktop
+func (op *restoreOperation) Plan() ([]fileMove, error) {
+	homeDir, err := hiddenFoldersRoot()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	if _, err := getDesktopPath(); err != nil {
+		return nil, fmt.Errorf("error getting desktop path: %w", err)
+	}
+
+	op.policies = make(map[string]string)
+	var plan []fileMove
+	for _, modeName := range op.modes {
+		modeConfig, err := op.config.getModeConfig(modeName)
+		if err != nil && op.all {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", modeName, err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sourceFolder := modeFolder(homeDir, modeConfig.Destination)
+		if !op.all {
+			op.source = sourceFolder
+			op.colorTemp = modeConfig.ColorTemperature != 0
+			// Restores aren't deferred like moves: the files are out of reach until the share is back
+			if err := checkShare(op.config.retry().Files, sourceFolder); err != nil && !op.dryRun {
+				return nil, fmt.Errorf("%w\nNothing was restored; run this again when the share is back", err)
+			}
+		}
+
+		if _, err := os.Stat(sourceFolder); os.IsNotExist(err) {
+			if op.all {
+				fmt.Printf("Skipping %s (folder does not exist: %s)\n", modeName, sourceFolder)
+				continue
+			}
+			fmt.Printf("Source folder does not exist: %s\n", sourceFolder)
+			fmt.Println("Nothing to restore.")
+			return nil, errNothingToDo
+		}
+
+		shortcuts, err := getShortcutsInFolder(sourceFolder)
+		if err != nil && op.all {
+			fmt.Fprintf(os.Stderr, "Error reading folder %s: %v\n", sourceFolder, err)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading source folder: %w", err)
+		}
+		shortcuts = op.config.restorable(shortcuts)
+
+		switch {
+		case len(shortcuts) == 0 && op.all:
+			fmt.Printf("No shortcuts in %s\n", modeName)
+			continue
+		case len(shortcuts) == 0:
+			fmt.Printf("No shortcuts found in %s\n", sourceFolder)
+			return nil, errNothingToDo
+		case !op.all:
+			fmt.Printf("Found %d shortcut(s) to restore from %s\n\n", len(shortcuts), sourceFolder)
+		}
+		op.policies[modeName] = modeConfig.restoreConflictPolicy()
+		moves, err := op.config.withRestoreHandlers(desktopMoves(modeName, sourceFolder, shortcuts, true))
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, moves...)
+	}
+	return plan, nil
+}
+
+// Execute restores one shortcut, to its restore handler's folder or the desktop, resolving a
+// conflict there with its mode's policy
+func (op *restoreOperation) Execute(move *fileMove) (stepOutcome, string, error) {
+	result, err := op.config.restoreShortcut(move.Name, move.From, op.policies[move.Mode])
+	switch {
+	case err != nil:
+		return stepDone, "", err
+	case result.Skipped:
+		return stepSkipped, result.describe(move.Name), nil
+	case result.undoable(move.Name):
+		return stepDone, result.describe(move.Name), nil
+	default:
+		return stepChanged, result.describe(move.Name), nil
 	}
+}
 
-	sourceFolder := filepath.Join(homeDir, modeConfig.Destination)
+// Rollback keeps the shortcuts restored so far; the others stay in their folders
+func (op *restoreOperation) Rollback(done []fileMove) bool {
+	return false
+}
 
-	// Check if source folder exists
-	if _, err := os.Stat(sourceFolder); os.IsNotExist(err) {
-		fmt.Printf("Source folder does not exist: %s\n", sourceFolder)
-		fmt.Println("Nothing to restore.")
+// Finish records the restore for undo and takes the restored modes off the applied mode list
+func (op *restoreOperation) Finish(report *operationReport) {
+	if op.colorTemp {
+		restoreColorTemperature(op.dryRun)
+	}
+	if op.all {
+		report.SkippedIn = "their mode folders"
+		report.DoneLine = "All shortcuts restored to desktop from all modes"
+	} else {
+		report.SkippedIn = op.source
+		report.DoneLine = fmt.Sprintf("All shortcuts restored to desktop from: %s", op.source)
+	}
+	if op.dryRun {
 		return
 	}
+	if op.all {
+		updateAppliedModes(func([]appliedMode) []appliedMode { return nil })
+	}
+	var screenshot string
+	if len(report.Done) > 0 {
+		screenshot = takeScreenshot(op.config, operationRestore, op.label)
+	}
+	recordOperation(operationRestore, op.label, report.Done, screenshot)
+	refreshHiddenMenu(op.config)
+}
 
-	// Get all shortcuts in the source folder
-	shortcutsToRestore, err := getShortcutsInFolder(sourceFolder)
+// errApplyCancelled is returned by an interactive apply's plan when the user cancels it
+var errApplyCancelled = errors.New("apply cancelled")
+
+// applyMode moves the shortcuts of a single mode to its destination folder
+// It returns false if the user cancelled an interactive apply
+func applyMode(config *Config, modeName string, dryRun, interactive bool) (bool, error) {
+	// Get mode-specific configuration
+	modeConfig, err := config.getModeConfig(modeName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading source folder: %v\n", err)
-		os.Exit(1)
+		return false, err
 	}
 
-	if len(shortcutsToRestore) == 0 {
-		fmt.Printf("No shortcuts found in %s\n", sourceFolder)
-		return
+	fmt.Printf("Using mode: %s\n", modeName)
+
+	op := &applyOperation{config: config, mode: modeName, modeConfig: modeConfig, interactive: interactive, dryRun: dryRun}
+	err = runOperation(config, op, &operationReport{Action: "move", Verb: "moved", Mode: modeName}, dryRun)
+	if errors.Is(err, errApplyCancelled) {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	fmt.Printf("Found %d shortcut(s) to restore from %s\n\n", len(shortcutsToRestore), sourceFolder)
+// applyOperation moves a mode's shortcuts off the desktop into its folder
+type applyOperation struct {
+	config      *Config
+	mode        string
+	modeConfig  *ModeConfig
+	interactive bool // Let the user review the plan and deselect files
+	dryRun      bool
+
+	destination string
+	started     bool   // The first move was made, after the folder was readied and the screen captured
+	startErr    error  // Why the folder couldn't be readied, failing every move
+	offline     bool   // The destination's share is offline, so the moves wait for it
+	screenshot  string // The screen as it was, for comparing the icon layout later
+}
 
-	// Restore shortcuts
-	successCount := 0
-	failCount := 0
+// Plan lists the mode's shortcuts, or every desktop file for move_all, less the ones deselected
+func (op *applyOperation) Plan() ([]fileMove, error) {
+	homeDir, err := hiddenFoldersRoot()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	op.destination = modeFolder(homeDir, op.modeConfig.Destination)
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return nil, fmt.Errorf("error getting desktop path: %w", err)
+	}
 
-	for _, shortcutName := range shortcutsToRestore {
-		if dryRun {
-			fmt.Printf("[DRY RUN] Would restore: %s -> Desktop\n", shortcutName)
-			successCount++
-		} else {
-			err := restoreShortcutToDesktop(shortcutName, sourceFolder)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error restoring '%s': %v\n", shortcutName, err)
-				failCount++
-			} else {
-				fmt.Printf("✓ Restored: %s\n", shortcutName)
-				successCount++
-			}
+	// Determine which shortcuts to move
+	var shortcutsToMove []string
+	if op.modeConfig.MoveAll {
+		allShortcuts, err := op.config.desktopShortcutsToMove(op.modeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error getting desktop shortcuts: %w", err)
 		}
+		shortcutsToMove = allShortcuts
+		fmt.Printf("Moving ALL shortcuts from desktop (%d found)\n", len(shortcutsToMove))
+	} else {
+		shortcutsToMove = op.modeConfig.Shortcuts
+		fmt.Printf("Moving specified shortcuts (%d configured)\n", len(shortcutsToMove))
 	}
 
-	// Summary
-	fmt.Println("\n--- Summary ---")
-	fmt.Printf("Mode: %s\n", modeName)
-	fmt.Printf("Successfully restored: %d\n", successCount)
-	if failCount > 0 {
-		fmt.Printf("Failed: %d\n", failCount)
+	if op.interactive {
+		// Every desktop file is offered, grouped by category, with the mode's own selected
+		moves := planMoves(shortcutsToMove, desktopPath)
+		if files, err := op.config.desktopShortcutsToMove(op.modeConfig); err == nil {
+			moves = addDesktopMoves(moves, files)
+		}
+		categoriesConfig, err := loadCategoriesConfig("")
+		if err != nil {
+			categoriesConfig = getDefaultCategoriesConfig()
+		}
+		groupMoves(moves, categoriesConfig)
+		selected, ok := selectMoves(moves, op.destination, stdinLines(), os.Stdout)
+		if !ok {
+			fmt.Println("Cancelled - nothing was moved")
+			return nil, errApplyCancelled
+		}
+		shortcutsToMove = selected
+		fmt.Printf("Moving %d selected shortcut(s)\n", len(shortcutsToMove))
 	}
-	if dryRun {
-		fmt.Println("(Dry run - no files were actually restored)")
-	} else {
-		fmt.Printf("All shortcuts restored to desktop from: %s\n", sourceFolder)
+
+	plan := make([]fileMove, len(shortcutsToMove))
+	for i, name := range shortcutsToMove {
+		plan[i] = fileMove{Name: name, Mode: op.mode, From: desktopPath, To: op.destination}
 	}
+	return plan, nil
 }
 
-// restoreAllShortcuts restores shortcuts from all modes back to desktop
-func restoreAllShortcuts(config *Config, dryRun bool) {
-	fmt.Println("Restoring shortcuts from all modes...")
+// start creates the destination folder if it doesn't exist and captures the screen
+// An offline share leaves the moves pending rather than failing them
+func (op *applyOperation) start() error {
+	var created bool
+	err := withShareRetry(op.config.retry().Files, func() error {
+		_, err := os.Stat(op.destination)
+		if !os.IsNotExist(err) {
+			return err
+		}
+		created = true
+		return mkdirAll(op.destination, 0755)
+	})
+	switch {
+	case errors.Is(err, errShareOffline):
+		op.offline = true
+		fmt.Fprintf(os.Stderr, "Warning: %s is on a share that is offline; the shortcuts will be moved when it is back\n", op.destination)
+		return nil
+	case err != nil:
+		return fmt.Errorf("error creating destination folder: %w", err)
+	case created:
+		ownByUser(op.destination)
+		fmt.Printf("Created destination folder: %s\n", op.destination)
+	}
+	op.screenshot = takeScreenshot(op.config, operationApply, op.mode)
+	return nil
+}
 
-	homeDir, err := os.UserHomeDir()
+// Execute moves one shortcut into the mode's folder, or leaves it pending while the share is offline
+func (op *applyOperation) Execute(move *fileMove) (stepOutcome, string, error) {
+	if !op.started {
+		op.started = true
+		op.startErr = op.start()
+	}
+	if op.startErr != nil {
+		return stepDone, "", op.startErr
+	}
+	if op.offline {
+		return stepPending, "", nil
+	}
+	err := op.config.moveModeShortcut(op.modeConfig, move.Name, op.destination)
+	if errors.Is(err, errShareOffline) {
+		// The share went away midway; the rest would only wait for the retries too
+		fmt.Fprintf(os.Stderr, "Warning: the share went offline; the remaining shortcuts will be moved when it is back\n")
+		op.offline = true
+		return stepPending, "", nil
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
+		return stepDone, "", err
 	}
+	return stepDone, fmt.Sprintf("%sMoved: %s", glyph("✓ "), move.Name), nil
+}
 
-	totalRestored := 0
-	totalFailed := 0
-
-	// Restore from each mode
-	for modeName, modeConfig := range config.Modes {
-		destination := modeConfig.Destination
-		if destination == "" {
-			destination = fmt.Sprintf("%s_Shortcuts", modeName)
-		}
+// Rollback keeps the shortcuts moved so far; the others stay on the desktop
+func (op *applyOperation) Rollback(done []fileMove) bool {
+	return false
+}
 
-		sourceFolder := filepath.Join(homeDir, destination)
+// Finish puts the mode on the applied mode list and records the moves for undo
+func (op *applyOperation) Finish(report *operationReport) {
+	applyModeColorTemperature(op.mode, op.modeConfig, op.dryRun)
+	report.DoneLine = fmt.Sprintf("All shortcuts moved to: %s", op.destination)
+	if op.dryRun {
+		return
+	}
+	names := report.names()
+	recordModeApplied(op.mode, op.destination, names)
+	recordPendingMoves(op.mode, op.destination, report.Pending)
+	recordOperation(operationApply, op.mode, desktopMoves(op.mode, op.destination, names, false), op.screenshot)
+	refreshHiddenMenu(op.config)
+}
 
-		// Check if folder exists
-		if _, err := os.Stat(sourceFolder); os.IsNotExist(err) {
-			fmt.Printf("Skipping %s (folder does not exist: %s)\n", modeName, sourceFolder)
+// Main runs the focusmode command line with os.Args; cmd/focusmode is a thin wrapper around it
+// Commands exit the process when they fail, so programs embedding FocusMode should use Mover
+// and the other exported API instead
+func Main() {
+	// A crash leaves its trace for bug-report
+	defer recordCrash()
+
+	// Global options come before the command, in any order:
+	// --user acts for another user's desktop, e.g. from an elevated daemon or scheduled task,
+	// --location acts on a folder named in the config instead of the OS desktop,
+	// --accessible writes screen-reader friendly output and --read-only refuses every change
+	var accessibleFlag, readOnlyFlag bool
+	rest := os.Args[1:]
+	for {
+		var on bool
+		if on, rest = parseSwitchArg(rest, "accessible"); on {
+			accessibleFlag = true
 			continue
 		}
-
-		// Get shortcuts in folder
-		shortcuts, err := getShortcutsInFolder(sourceFolder)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading folder %s: %v\n", sourceFolder, err)
+		if on, rest = parseSwitchArg(rest, "read-only"); on {
+			readOnlyFlag = true
 			continue
 		}
-
-		if len(shortcuts) == 0 {
-			fmt.Printf("No shortcuts in %s\n", modeName)
+		location, remaining, err := parseLocationArg(rest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if location != "" {
+			targetLocation, rest = location, remaining
 			continue
 		}
+		user, remaining, err := parseUserArg(rest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if user == "" {
+			break
+		}
+		targetUser, rest = user, remaining
+	}
+	accessible = accessibleFlag || accessibleFromEnv()
+	readOnly = readOnlyFlag || readOnlyFromEnv()
+	// Commands run by the daemon in child processes follow the same settings
+	if accessibleFlag {
+		os.Setenv(accessibleEnv, "1")
+	}
+	if readOnlyFlag {
+		os.Setenv(readOnlyEnv, "1")
+	}
+	if targetLocation != "" {
+		os.Setenv(locationEnv, targetLocation)
+	} else if location := os.Getenv(locationEnv); locationNamePattern.MatchString(location) {
+		targetLocation = location
+	}
+	os.Args = append(os.Args[:1], rewriteDeprecatedArgs(rest, deprecatedFlags, warnDeprecatedOnce)...)
+	// Commands the daemon runs are traced under its spans
+	startTracingFromEnv()
 
-		fmt.Printf("Mode: %s (%d shortcut(s))\n", modeName, len(shortcuts))
+	// Commands sent to a daemon on another machine or this one
+	if len(os.Args) > 1 && isRemoteFlag(os.Args[1]) {
+		runRemoteCommand(os.Args[1:])
+		return
+	}
 
-		// Restore each shortcut
-		for _, shortcutName := range shortcuts {
-			if dryRun {
-				fmt.Printf("  [DRY RUN] Would restore: %s\n", shortcutName)
-				totalRestored++
-			} else {
-				err := restoreShortcutToDesktop(shortcutName, sourceFolder)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "  Error restoring '%s': %v\n", shortcutName, err)
-					totalFailed++
-				} else {
-					fmt.Printf("  ✓ Restored: %s\n", shortcutName)
-					totalRestored++
-				}
-			}
-		}
-		fmt.Println()
+	if err := resolveTargetLocation(configPathFromArgs(os.Args[1:])); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Summary
-	fmt.Println("--- Summary ---")
-	fmt.Printf("Successfully restored: %d\n", totalRestored)
-	if totalFailed > 0 {
-		fmt.Printf("Failed: %d\n", totalFailed)
+	// Moves left pending by an offline share go first, so the command sees them done
+	if completesPendingMoves(os.Args[1:]) {
+		reportPendingMoves(configPathFromArgs(os.Args[1:]))
 	}
-	if dryRun {
-		fmt.Println("(Dry run - no files were actually restored)")
-	} else {
-		fmt.Println("All shortcuts restored to desktop from all modes")
+
+	// Offer to recover a session left behind by a crash before doing anything else
+	if needsRecoveryCheck(os.Args[1:]) && checkSessionRecovery(configPathFromArgs(os.Args[1:])) {
+		return
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "session":
+			runSessionCommand(os.Args[2:])
+			return
+		case "routine":
+			runRoutineCommand(os.Args[2:])
+			return
+		case "calendar":
+			runCalendarCommand(os.Args[2:])
+			return
+		case "report":
+			runReportCommand(os.Args[2:])
+			return
+		case "watch":
+			runWatchCommand(os.Args[2:])
+			return
+		case "stats":
+			runStatsCommand(os.Args[2:])
+			return
+		case "config":
+			runConfigCommand(os.Args[2:])
+			return
+		case "status":
+			runStatusCommand(os.Args[2:])
+			return
+		case "push":
+			runPushCommand(os.Args[2:])
+			return
+		case "pop":
+			runPopCommand(os.Args[2:])
+			return
+		case "daemon":
+			runDaemonCommand(os.Args[2:])
+			return
+		case "token":
+			runTokenCommand(os.Args[2:])
+			return
+		case "guardian":
+			runGuardianCommand(os.Args[2:])
+			return
+		case "panic":
+			runPanicCommand(os.Args[2:])
+			return
+		case "prompt":
+			runPromptCommand(os.Args[2:])
+			return
+		case "undo", "redo":
+			runUndoCommand(os.Args[1], os.Args[2:])
+			return
+		case "archive":
+			runArchiveCommand(os.Args[2:])
+			return
+		case "restore":
+			runRestoreCommand(os.Args[2:])
+			return
+		case "launch":
+			runLaunchCommand(os.Args[2:])
+			return
+		case "rules":
+			runRulesCommand(os.Args[2:])
+			return
+		case "template":
+			runTemplateCommand(os.Args[2:])
+			return
+		case "shell-hook":
+			runShellHookCommand(os.Args[2:])
+			return
+		case "shell-guard":
+			runShellGuardCommand(os.Args[2:])
+			return
+		case "browser-host":
+			runBrowserHostCommand(os.Args[2:])
+			return
+		case "dns":
+			runDNSCommand(os.Args[2:])
+			return
+		case "firewall":
+			runFirewallCommand(os.Args[2:])
+			return
+		case "gui":
+			runGUICommand(os.Args[2:])
+			return
+		case "serve":
+			runServeCommand(os.Args[2:])
+			return
+		case "gc":
+			runGCCommand(os.Args[2:])
+			return
+		case "zones":
+			runZonesCommand(os.Args[2:])
+			return
+		case "bug-report":
+			runBugReportCommand(os.Args[2:])
+			return
+		}
 	}
-}
 
-func main() {
 	// Command-line flags
 	configPath := flag.String("config", "profile.yml", "Path to configuration file")
 	categoriesPath := flag.String("categories", "categories.yml", "Path to categories configuration file")
@@ -900,7 +1547,31 @@ func main() {
 	autoConfig := flag.Bool("auto-config", false, "Auto-generate profile.yml based on desktop shortcuts and categories")
 	restore := flag.Bool("restore", false, "Restore shortcuts from organized folder back to desktop")
 	restoreAll := flag.Bool("restore-all", false, "Restore shortcuts from all modes back to desktop")
+	interactive := flag.Bool("interactive", false, "Pick the mode and which desktop files to hide from a list grouped by category, then apply")
+	daemonFlag := flag.Bool("daemon", false, "Start the daemon in the background, same as 'daemon start'")
+	resume := flag.Bool("resume", false, "Reattach to a session interrupted by a crash, or offer to restore its shortcuts")
+	undo := flag.Bool("undo", false, "Put back exactly the files moved by the last apply or restore, same as 'undo'")
+	flag.StringVar(&onConflictFlag, "on-conflict", "", "When restoring onto a desktop file of the same name: fail, skip, overwrite, rename-with-suffix or keep-newer (overrides on_conflict)")
 	flag.Parse()
+	if err := validConflictPolicy(onConflictFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *daemonFlag {
+		startDaemon(*configPath)
+		return
+	}
+
+	if *resume {
+		resumeSession(*configPath)
+		return
+	}
+
+	if *undo {
+		undoLastOperation("undo", *configPath, *dryRun)
+		return
+	}
 
 	// Auto-generate profile if requested
 	if *autoConfig {
@@ -918,14 +1589,23 @@ func main() {
 		}
 
 		if *restoreAll {
-			restoreAllShortcuts(config, *dryRun)
+			err = restoreAllShortcuts(config, *dryRun)
 		} else {
 			// Determine which mode to restore
 			modeName := *mode
 			if modeName == "" {
 				modeName = config.DefaultMode
 			}
-			restoreShortcutsForMode(config, modeName, *dryRun)
+			if _, err := config.getModeConfig(modeName); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Use -list-modes to see available modes\n")
+				os.Exit(1)
+			}
+			err = restoreShortcutsForMode(config, modeName, *dryRun)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 		return
 	}
@@ -940,6 +1620,9 @@ func main() {
 			categoriesConfig = getDefaultCategoriesConfig()
 		}
 		listDesktopFilesWithConfig(categoriesConfig)
+		if shortcuts, err := getAllDesktopShortcuts(); err == nil {
+			offerKeywordSuggestions(*categoriesPath, categoriesConfig, shortcuts, keywordAnswers())
+		}
 		return
 	}
 
@@ -965,88 +1648,29 @@ func main() {
 
 	// Determine which mode to use
 	modeName := *mode
+	if modeName == "" && *interactive {
+		picked, ok := pickMode(config, stdinLines(), os.Stdout)
+		if !ok {
+			fmt.Println("Cancelled - nothing was moved")
+			return
+		}
+		modeName = picked
+	}
 	if modeName == "" {
 		modeName = config.DefaultMode
 	}
 
-	// Get mode-specific configuration
-	modeConfig, err := config.getModeConfig(modeName)
-	if err != nil {
+	if _, err := config.getModeConfig(modeName); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Use -list-modes to see available modes\n")
 		os.Exit(1)
 	}
-
-	fmt.Printf("Using mode: %s\n", modeName)
-
-	// Get destination folder
-	homeDir, err := os.UserHomeDir()
+	applied, err := applyModeWithDependencies(config, modeName, *dryRun, *interactive)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	destinationFolder := filepath.Join(homeDir, modeConfig.Destination)
-
-	// Create the destination folder if it doesn't exist
-	if !*dryRun {
-		if _, err := os.Stat(destinationFolder); os.IsNotExist(err) {
-			err := os.MkdirAll(destinationFolder, 0755)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating destination folder: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Printf("Created destination folder: %s\n", destinationFolder)
-		}
-	}
-
-	// Determine which shortcuts to move
-	var shortcutsToMove []string
-
-	if modeConfig.MoveAll {
-		// Get all shortcuts from desktop
-		allShortcuts, err := getAllDesktopShortcuts()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting desktop shortcuts: %v\n", err)
-			os.Exit(1)
-		}
-		shortcutsToMove = allShortcuts
-		fmt.Printf("Moving ALL shortcuts from desktop (%d found)\n", len(shortcutsToMove))
-	} else {
-		shortcutsToMove = modeConfig.Shortcuts
-		fmt.Printf("Moving specified shortcuts (%d configured)\n", len(shortcutsToMove))
-	}
-
-	// Move shortcuts
-	successCount := 0
-	failCount := 0
-
-	for _, shortcutName := range shortcutsToMove {
-		if *dryRun {
-			fmt.Printf("[DRY RUN] Would move: %s -> %s\n", shortcutName, destinationFolder)
-			successCount++
-		} else {
-			err := moveDesktopShortcut(shortcutName, destinationFolder)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error moving '%s': %v\n", shortcutName, err)
-				failCount++
-			} else {
-				fmt.Printf("✓ Moved: %s\n", shortcutName)
-				successCount++
-			}
-		}
-	}
-
-	// Summary
-	fmt.Println("\n--- Summary ---")
-	fmt.Printf("Mode: %s\n", modeName)
-	fmt.Printf("Successfully moved: %d\n", successCount)
-	if failCount > 0 {
-		fmt.Printf("Failed: %d\n", failCount)
-	}
-	if *dryRun {
-		fmt.Println("(Dry run - no files were actually moved)")
-	} else {
-		fmt.Printf("All shortcuts moved to: %s\n", destinationFolder)
+	if applied && *dryRun && !*interactive {
+		printModePreview(config, modeName)
 	}
 }
@@ -1,4 +1,4 @@
-package main
+package focusmode
 
 import (
 	"fmt"
@@ -241,7 +241,7 @@ func TestMoveDesktopShortcut(t *testing.T) {
 	}
 
 	// Test moving the file using the testable function
-	err = moveDesktopShortcutFromPath("test.lnk", destDir, desktopDir)
+	err = (&Config{}).moveDesktopShortcutFromPath("test.lnk", destDir, desktopDir)
 	if err != nil {
 		t.Fatalf("moveDesktopShortcutFromPath() returned error: %v", err)
 	}
@@ -257,7 +257,7 @@ func TestMoveDesktopShortcut(t *testing.T) {
 	}
 
 	// Test moving nonexistent file
-	err = moveDesktopShortcutFromPath("nonexistent.lnk", destDir, desktopDir)
+	err = (&Config{}).moveDesktopShortcutFromPath("nonexistent.lnk", destDir, desktopDir)
 	if err == nil {
 		t.Error("Expected error when moving nonexistent file")
 	}
@@ -501,7 +501,7 @@ func TestRestoreShortcutToDesktop(t *testing.T) {
 	}
 
 	// Test restoring the file
-	err = restoreShortcutToDesktop("test.lnk", sourceDir)
+	err = (&Config{}).restoreShortcutToDesktop("test.lnk", sourceDir)
 	if err != nil {
 		t.Fatalf("restoreShortcutToDesktop() returned error: %v", err)
 	}
@@ -517,7 +517,7 @@ func TestRestoreShortcutToDesktop(t *testing.T) {
 	}
 
 	// Test restoring nonexistent file
-	err = restoreShortcutToDesktop("nonexistent.lnk", sourceDir)
+	err = (&Config{}).restoreShortcutToDesktop("nonexistent.lnk", sourceDir)
 	if err == nil {
 		t.Error("Expected error when restoring nonexistent file")
 	}
@@ -532,7 +532,7 @@ func TestRestoreShortcutToDesktop(t *testing.T) {
 		t.Fatalf("Failed to create desktop file: %v", err)
 	}
 
-	err = restoreShortcutToDesktop("existing.lnk", sourceDir)
+	err = (&Config{}).restoreShortcutToDesktop("existing.lnk", sourceDir)
 	if err == nil {
 		t.Error("Expected error when file already exists on desktop")
 	}
@@ -1416,3 +1416,31 @@ func TestFormatDuration(t *testing.T) {
 		})
 	}
 }
+
+// TestApplyModeMoveAllExclude tests that move_all leaves excluded files on the desktop, except
+// ones the machine policy always hides
+func TestApplyModeMoveAllExclude(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	t.Setenv("AppData", dir)
+	desktop := filepath.Join(dir, "Desktop")
+	os.MkdirAll(desktop, 0755)
+	for _, name := range []string{"Recycle Bin.lnk", "Project.lnk", "Steam.lnk", "Solitaire.lnk"} {
+		os.WriteFile(filepath.Join(desktop, name), nil, 0644)
+	}
+
+	config := &Config{
+		Modes:  map[string]ModeConfig{"clean": {Destination: "Clean", MoveAll: true, Exclude: []string{"recycle bin.lnk", "Project.lnk", "Solitaire.lnk"}}},
+		Policy: &Policy{AlwaysHidden: []string{"Solitaire.lnk"}},
+	}
+	if _, err := applyMode(config, "clean", false, false); err != nil {
+		t.Fatal(err)
+	}
+	for name, hidden := range map[string]bool{"Recycle Bin.lnk": false, "Project.lnk": false, "Steam.lnk": true, "Solitaire.lnk": true} {
+		if _, err := os.Stat(filepath.Join(dir, "Clean", name)); (err == nil) != hidden {
+			t.Errorf("Expected %s hidden: %v, got err %v", name, hidden, err)
+		}
+	}
+}
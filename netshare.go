@@ -0,0 +1,283 @@
+package focusmode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errShareOffline reports that a network share stayed unreachable after retrying
+var errShareOffline = errors.New("network share is offline")
+
+// shareStatTimeout bounds how long checking a share may take; Windows can wait
+// most of a minute on a server that doesn't answer
+const shareStatTimeout = 10 * time.Second
+
+// pendingMovesInterval is how often the daemon retries moves waiting for a share
+const pendingMovesInterval = time.Minute
+
+// unreachableErrnos are the errors a file operation gets when the network under it is gone
+// Local disks don't return them, so no check of the path is needed
+var unreachableErrnos = []syscall.Errno{
+	syscall.ENETDOWN, syscall.ENETUNREACH, syscall.EHOSTDOWN, syscall.EHOSTUNREACH,
+	syscall.ETIMEDOUT, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.ESTALE,
+}
+
+// windowsUnreachableErrnos are the Windows error codes for a share that can't be reached:
+// ERROR_BAD_NETPATH, ERROR_NETWORK_BUSY, ERROR_UNEXP_NET_ERR, ERROR_NETNAME_DELETED,
+// ERROR_BAD_NET_NAME, ERROR_SEM_TIMEOUT, ERROR_NETWORK_UNREACHABLE, ERROR_HOST_UNREACHABLE
+var windowsUnreachableErrnos = []syscall.Errno{53, 54, 59, 64, 67, 121, 1231, 1232}
+
+// shareUnavailable reports whether err means a network share couldn't be reached,
+// as opposed to a file being missing or access being denied
+func shareUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errShareOffline) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	codes := unreachableErrnos
+	if runtime.GOOS == "windows" {
+		codes = windowsUnreachableErrnos
+	}
+	for _, code := range codes {
+		if errno == code {
+			return true
+		}
+	}
+	return false
+}
+
+// withShareRetry runs op under policy, the files retry policy, while the share under it is unreachable
+// A share that drops for a few seconds (Wi-Fi roaming, VPN reconnecting) is usually back within
+// the default policy's pauses; an error that still means the share is unreachable wraps errShareOffline
+func withShareRetry(policy RetryPolicy, op func() error) error {
+	return shareOffline(policy.do(op, func(err error) bool {
+		return shareUnavailable(err) && !errors.Is(err, errShareOffline)
+	}))
+}
+
+// shareOffline wraps errShareOffline around an error that means a share is unreachable
+func shareOffline(err error) error {
+	if shareUnavailable(err) && !errors.Is(err, errShareOffline) {
+		return fmt.Errorf("%w: %w", errShareOffline, err)
+	}
+	return err
+}
+
+// statWithTimeout stats a path, giving up after shareStatTimeout
+func statWithTimeout(path string) (os.FileInfo, error) {
+	type result struct {
+		info os.FileInfo
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		info, err := os.Stat(path)
+		done <- result{info, err}
+	}()
+	select {
+	case r := <-done:
+		return r.info, r.err
+	case <-time.After(shareStatTimeout):
+		return nil, fmt.Errorf("checking %s: %w", path, context.DeadlineExceeded)
+	}
+}
+
+// checkShare returns an error wrapping errShareOffline when the folder's share can't be reached,
+// trying again under policy; a missing folder on a reachable share is not an error here
+func checkShare(policy RetryPolicy, folder string) error {
+	err := withShareRetry(policy, func() error {
+		_, err := statWithTimeout(folder)
+		return err
+	})
+	if errors.Is(err, errShareOffline) {
+		return err
+	}
+	return nil
+}
+
+// shareReachable checks once, without retrying, whether the share holding folder answers
+func shareReachable(folder string) bool {
+	_, err := statWithTimeout(folder)
+	return !shareUnavailable(err)
+}
+
+// recordPendingMoves notes shortcuts that a mode couldn't move because a share was offline
+// They stay on the desktop, listed in the layer's manifest as pending until completePendingMoves moves them
+func recordPendingMoves(modeName, destination string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	updateAppliedModes(func(modes []appliedMode) []appliedMode {
+		for i := range modes {
+			if modes[i].Mode == modeName {
+				modes[i].Pending = append(modes[i].Pending, names...)
+				return modes
+			}
+		}
+		return append(modes, appliedMode{Mode: modeName, AppliedAt: time.Now(), Destination: destination, Pending: names})
+	})
+}
+
+// pendingMoveResult is what became of the moves waiting for a share
+type pendingMoveResult struct {
+	Moved   map[string][]string // Shortcuts moved, by mode
+	Dropped map[string][]string // Shortcuts no longer on the desktop, by mode
+	Waiting int                 // Shortcuts still waiting
+}
+
+// completePendingMoves moves the pending shortcuts of every layer whose share is reachable again
+// Shortcuts no longer on the desktop are dropped from the manifest; the rest wait for the next try
+// It only reads the manifest when nothing is pending, so it is cheap to run before commands
+// Files config pins are left on the desktop
+func completePendingMoves(config *Config) (pendingMoveResult, error) {
+	result := pendingMoveResult{Moved: make(map[string][]string), Dropped: make(map[string][]string)}
+	path, err := appliedModesPath()
+	if err != nil {
+		return result, err
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		return result, err
+	}
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return result, err
+	}
+
+	changed := false
+	for i := range modes {
+		layer := &modes[i]
+		if len(layer.Pending) == 0 {
+			continue
+		}
+		// One quick check each, since commands run by hand wait for this
+		if !shareReachable(desktopPath) || !shareReachable(layer.Destination) {
+			result.Waiting += len(layer.Pending)
+			continue
+		}
+		if err := mkdirAll(layer.Destination, 0755); err != nil {
+			return result, fmt.Errorf("error creating %s: %w", layer.Destination, err)
+		}
+		var waiting []string
+		for _, name := range layer.Pending {
+			diskName, ok := findFileName(desktopPath, name)
+			if !ok {
+				result.Dropped[layer.Mode] = append(result.Dropped[layer.Mode], name)
+				continue
+			}
+			if err := config.moveDesktopShortcutFromPath(diskName, layer.Destination, desktopPath); err != nil {
+				if !errors.Is(err, errShareOffline) {
+					fmt.Fprintf(os.Stderr, "Error moving '%s': %v\n", name, err)
+				}
+				waiting = append(waiting, name)
+				continue
+			}
+			result.Moved[layer.Mode] = append(result.Moved[layer.Mode], diskName)
+			layer.Shortcuts = append(layer.Shortcuts, diskName)
+		}
+		result.Waiting += len(waiting)
+		layer.Pending = waiting
+		changed = true
+	}
+	if !changed {
+		return result, nil
+	}
+	if err := writeAppliedModes(path, modes); err != nil {
+		return result, err
+	}
+	for _, layer := range modes {
+		if names := result.Moved[layer.Mode]; len(names) > 0 {
+			recordOperation(operationApply, layer.Mode, desktopMoves(layer.Mode, layer.Destination, names, false), "")
+		}
+	}
+	return result, nil
+}
+
+// completesPendingMoves reports whether a command finishes pending moves before running
+// The same commands that check for a crashed session do, unless nothing may change
+func completesPendingMoves(args []string) bool {
+	if readOnly || !needsRecoveryCheck(args) {
+		return false
+	}
+	for _, arg := range args {
+		if name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "="); strings.HasPrefix(arg, "-") && name == "dry-run" {
+			return false
+		}
+	}
+	return true
+}
+
+// reportPendingMoves completes pending moves and says what happened, for commands run by hand
+// Nothing is moved when the config at configPath can't be read, since its pins aren't known;
+// the command reports the config's error itself
+func reportPendingMoves(configPath string) {
+	config, err := readConfig(configPath)
+	if err != nil {
+		return
+	}
+	result, err := completePendingMoves(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error completing pending moves: %v\n", err)
+		return
+	}
+	for _, mode := range sortedModeKeys(result.Moved) {
+		fmt.Printf("%sThe share is back: moved %d pending shortcut(s) for %s\n", glyph("✓ "), len(result.Moved[mode]), mode)
+	}
+	for _, mode := range sortedModeKeys(result.Dropped) {
+		fmt.Printf("No longer on the desktop, dropped from %s: %v\n", mode, result.Dropped[mode])
+	}
+}
+
+// sortedModeKeys returns the modes of a pending move result in order
+func sortedModeKeys(names map[string][]string) []string {
+	keys := make([]string, 0, len(names))
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// watchPendingMoves retries pending moves until ctx is cancelled; run by the daemon
+func watchPendingMoves(ctx context.Context, config *Config, mu sync.Locker, beat func()) {
+	notifiers := []Notifier{consoleNotifier{}}
+	ticker := time.NewTicker(pendingMovesInterval)
+	defer ticker.Stop()
+	for {
+		beat()
+		// Only retries that move something or fail are traced
+		_, span := startSpan(ctx, "pending-moves")
+		mu.Lock()
+		result, err := completePendingMoves(config)
+		mu.Unlock()
+		if err != nil || len(result.Moved) > 0 {
+			span.set("focusmode.modes", sortedModeKeys(result.Moved))
+			span.finish(err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: error completing pending moves: %v\n", err)
+		}
+		for _, mode := range sortedModeKeys(result.Moved) {
+			notifyAll(notifiers, "Share back online", fmt.Sprintf("Moved %d pending shortcut(s) for %s", len(result.Moved[mode]), mode))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
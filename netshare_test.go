@@ -0,0 +1,143 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestShareUnavailable tests which errors mean an unreachable share
+func TestShareUnavailable(t *testing.T) {
+	unreachable := syscall.ENETUNREACH
+	if runtime.GOOS == "windows" {
+		unreachable = syscall.Errno(53) // ERROR_BAD_NETPATH
+	}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&os.PathError{Op: "stat", Path: `\\nas\desk`, Err: unreachable}, true},
+		{fmt.Errorf("error moving shortcut: %w", &os.LinkError{Op: "rename", Err: unreachable}), true},
+		{errShareOffline, true},
+		{&os.PathError{Op: "stat", Path: "Steam.lnk", Err: os.ErrNotExist}, false},
+		{&os.PathError{Op: "open", Path: "Steam.lnk", Err: os.ErrPermission}, false},
+	}
+	for _, tt := range tests {
+		if got := shareUnavailable(tt.err); got != tt.want {
+			t.Errorf("shareUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestWithShareRetry tests that only unreachable shares are retried, and reported as offline
+func TestWithShareRetry(t *testing.T) {
+	defer func(sleep func(time.Duration)) { retrySleep = sleep }(retrySleep)
+	retrySleep = func(time.Duration) {}
+	unreachable := &os.PathError{Op: "rename", Err: syscall.ETIMEDOUT}
+	if runtime.GOOS == "windows" {
+		unreachable.Err = syscall.Errno(64) // ERROR_NETNAME_DELETED
+	}
+
+	calls := 0
+	err := withShareRetry(RetryPolicy{}, func() error {
+		if calls++; calls < 3 {
+			return unreachable
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third attempt, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = withShareRetry(RetryPolicy{}, func() error { calls++; return unreachable })
+	if !errors.Is(err, errShareOffline) || calls != 4 {
+		t.Errorf("Expected errShareOffline after 4 attempts, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = withShareRetry(RetryPolicy{}, func() error { calls++; return os.ErrPermission })
+	if !errors.Is(err, os.ErrPermission) || errors.Is(err, errShareOffline) || calls != 1 {
+		t.Errorf("Expected other errors to be returned at once, got %v after %d", err, calls)
+	}
+}
+
+// TestCompletePendingMoves tests that pending shortcuts are moved and the manifest updated
+func TestCompletePendingMoves(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	desktop := filepath.Join(dir, "Desktop")
+	games := filepath.Join(dir, "Games")
+	os.MkdirAll(desktop, 0755)
+	os.WriteFile(filepath.Join(desktop, "Steam.lnk"), nil, 0644)
+	recordModeApplied("gamemode", games, nil)
+	recordPendingMoves("gamemode", games, []string{"Steam.lnk", "Epic.lnk"})
+
+	path, _ := appliedModesPath()
+	modes, _ := readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Pending) != 2 {
+		t.Fatalf("Expected two pending shortcuts, got %+v", modes)
+	}
+
+	result, err := completePendingMoves(&Config{})
+	if err != nil {
+		t.Fatalf("completePendingMoves() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Moved["gamemode"], []string{"Steam.lnk"}) || !reflect.DeepEqual(result.Dropped["gamemode"], []string{"Epic.lnk"}) || result.Waiting != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(games, "Steam.lnk")); err != nil {
+		t.Errorf("Expected Steam.lnk in the mode's folder: %v", err)
+	}
+	modes, _ = readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Pending) != 0 || !reflect.DeepEqual([]string(modes[0].Shortcuts), []string{"Steam.lnk"}) {
+		t.Errorf("Expected Steam.lnk in the manifest and nothing pending, got %+v", modes)
+	}
+}
+
+// TestRecordShortcutsRestoredKeepsPending tests that a layer with pending moves outlives its moved shortcuts
+func TestRecordShortcutsRestoredKeepsPending(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	recordModeApplied("gamemode", filepath.Join(dir, "Games"), []string{"Steam.lnk"})
+	recordPendingMoves("gamemode", filepath.Join(dir, "Games"), []string{"Epic.lnk"})
+	recordShortcutsRestored("gamemode", []string{"Steam.lnk"})
+
+	path, _ := appliedModesPath()
+	modes, _ := readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Shortcuts) != 0 || len(modes[0].Pending) != 1 {
+		t.Errorf("Expected the layer to keep its pending move, got %+v", modes)
+	}
+}
+
+// TestCompletesPendingMoves tests which command lines finish pending moves first
+func TestCompletesPendingMoves(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		want bool
+	}{
+		"apply":   {[]string{"-mode", "gamemode"}, true},
+		"dry run": {[]string{"-mode", "gamemode", "-dry-run"}, false},
+		"status":  {[]string{"status"}, false},
+		"prompt":  {[]string{"prompt"}, false},
+	}
+	for name, tt := range tests {
+		if got := completesPendingMoves(tt.args); got != tt.want {
+			t.Errorf("%s: completesPendingMoves(%v) = %v, want %v", name, tt.args, got, tt.want)
+		}
+	}
+}
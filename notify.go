@@ -0,0 +1,41 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+)
+
+// Notifier delivers a short message to the user through one backend
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// consoleNotifier prints notifications to the terminal
+type consoleNotifier struct{}
+
+// Notify prints the message on its own line below the progress display
+func (consoleNotifier) Notify(title, message string) error {
+	fmt.Printf("\n%s%s: %s\n", glyph("🔔 "), title, message)
+	return nil
+}
+
+// notifyAll sends a message through every notifier, reporting failures as warnings
+func notifyAll(notifiers []Notifier, title, message string) {
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(title, message); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: notification failed: %v\n", err)
+		}
+	}
+}
+
+// buildNotifiers returns the notifier backends enabled for a session
+// The console is always enabled; hooks that also act as notifiers (such as TTS) are added as well
+func buildNotifiers(hooks []SessionHook) []Notifier {
+	notifiers := []Notifier{consoleNotifier{}}
+	for _, hook := range hooks {
+		if notifier, ok := hook.(Notifier); ok {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	return notifiers
+}
@@ -0,0 +1,170 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// fileOperation is a command that moves files for the user: applying a mode, restoring one
+// mode or all of them, or sweeping stale files into the archive
+// runOperation drives each the same way, so their dry runs, progress, summaries and the
+// manifests recorded for undo look alike
+type fileOperation interface {
+	// Plan returns the files to move, From where they are To where they go
+	// It reads the desktop and folders but changes nothing; errNothingToDo ends the
+	// operation quietly, after Plan has said why
+	Plan() ([]fileMove, error)
+
+	// Execute moves one planned file, creating what it needs on the first, and returns the
+	// line describing it; it may change the move's name to the one found on disk
+	Execute(move *fileMove) (stepOutcome, string, error)
+
+	// Rollback puts back the files moved so far after a move failed, returning false if the
+	// operation keeps them instead and carries on with the rest
+	Rollback(done []fileMove) bool
+
+	// Finish records the operation after its files were moved: the manifest for undo, the
+	// applied modes and whatever else the command keeps; it is called in dry runs too
+	Finish(report *operationReport)
+}
+
+// stepOutcome is what became of one planned file
+type stepOutcome int
+
+const (
+	stepDone    stepOutcome = iota // Moved under its own name, so undo can put it back
+	stepChanged                    // Moved, but renamed or replacing another file on the way
+	stepSkipped                    // Left where it was by a conflict policy
+	stepPending                    // Waiting for an offline share to come back
+)
+
+// errNothingToDo is returned by Plan when there is nothing to move and nothing to report
+var errNothingToDo = errors.New("nothing to do")
+
+// operationReport is the tally of an operation, printed as its summary
+type operationReport struct {
+	Action    string // What is done to each file, e.g. "move"
+	Verb      string // The same in the past tense, e.g. "moved"
+	Mode      string // Mode shown in the summary, if the operation is of a single mode
+	SkippedIn string // Where skipped files were left, for the summary
+	DoneLine  string // Last line of the summary when files were moved, set by Finish
+
+	Done    []fileMove // Files moved under their own names, or that would be in a dry run
+	Changed []string   // Files moved but renamed or replacing another
+	Pending []string   // Files waiting for an offline share
+	Skipped int
+	Failed  int
+}
+
+// names returns the names of the files moved under their own names
+func (r *operationReport) names() []string {
+	names := make([]string, len(r.Done))
+	for i, move := range r.Done {
+		names[i] = move.Name
+	}
+	return names
+}
+
+// print writes the summary of the operation
+func (r *operationReport) print(dryRun bool) {
+	fmt.Println("\n--- Summary ---")
+	if r.Mode != "" {
+		fmt.Printf("Mode: %s\n", r.Mode)
+	}
+	fmt.Printf("Successfully %s: %d\n", r.Verb, len(r.Done)+len(r.Changed))
+	if r.Skipped > 0 {
+		fmt.Printf("Skipped: %d (left in %s)\n", r.Skipped, r.SkippedIn)
+	}
+	if r.Failed > 0 {
+		fmt.Printf("Failed: %d\n", r.Failed)
+	}
+	if len(r.Pending) > 0 {
+		fmt.Printf("Pending until the share is back: %d\n", len(r.Pending))
+	}
+	if dryRun {
+		fmt.Printf("(Dry run - no files were actually %s)\n", r.Verb)
+	} else if r.DoneLine != "" {
+		fmt.Println(r.DoneLine)
+	}
+}
+
+// runOperation plans op and moves its files one by one, then has it record what it did and
+// prints the summary; a dry run prints the plan and moves nothing. Files config pins are skipped
+// A plan spanning several modes is printed under a heading per mode
+func runOperation(config *Config, op fileOperation, report *operationReport, dryRun bool) error {
+	plan, err := op.Plan()
+	if errors.Is(err, errNothingToDo) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	grouped := false
+	for _, move := range plan {
+		grouped = grouped || move.Mode != plan[0].Mode
+	}
+	indent, mode := "", ""
+	for i := range plan {
+		move := &plan[i]
+		if grouped {
+			indent = "  "
+			if move.Mode != mode {
+				if mode != "" {
+					fmt.Println()
+				}
+				mode = move.Mode
+				fmt.Printf("Mode: %s (%d shortcut(s))\n", mode, countModeMoves(plan, mode))
+			}
+		}
+		if errors.Is(config.checkPinned(move.From, move.Name), errPinned) {
+			fmt.Printf("%s%s\n", indent, describePinned(move.Name))
+			report.Skipped++
+			continue
+		}
+		if dryRun {
+			fmt.Printf("%s[DRY RUN] Would %s: %s -> %s\n", indent, report.Action, move.Name, move.To)
+			report.Done = append(report.Done, *move)
+			continue
+		}
+
+		outcome, line, err := op.Execute(move)
+		if err != nil {
+			if op.Rollback(report.Done) {
+				return fmt.Errorf("%w; nothing was changed", err)
+			}
+			fmt.Fprintf(os.Stderr, "%sFailed to %s '%s': %v\n", indent, report.Action, move.Name, err)
+			report.Failed++
+			continue
+		}
+		if line != "" {
+			fmt.Printf("%s%s\n", indent, line)
+		}
+		switch outcome {
+		case stepDone:
+			report.Done = append(report.Done, *move)
+		case stepChanged:
+			report.Changed = append(report.Changed, move.Name)
+		case stepSkipped:
+			report.Skipped++
+		case stepPending:
+			report.Pending = append(report.Pending, move.Name)
+		}
+	}
+
+	op.Finish(report)
+	report.print(dryRun)
+	return nil
+}
+
+// countModeMoves returns how many moves of plan are of mode
+func countModeMoves(plan []fileMove, mode string) int {
+	count := 0
+	for _, move := range plan {
+		if move.Mode == mode {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,79 @@
+package focusmode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOperation is a fileOperation whose steps end as listed in outcomes
+type fakeOperation struct {
+	plan       []fileMove
+	outcomes   map[string]stepOutcome
+	fail       string // Name of the file whose move fails
+	atomic     bool   // Roll back when a move fails
+	rolledBack []fileMove
+	finished   bool
+}
+
+func (op *fakeOperation) Plan() ([]fileMove, error) {
+	if len(op.plan) == 0 {
+		return nil, errNothingToDo
+	}
+	return append([]fileMove(nil), op.plan...), nil
+}
+
+func (op *fakeOperation) Execute(move *fileMove) (stepOutcome, string, error) {
+	if move.Name == op.fail {
+		return stepDone, "", errors.New("disk full")
+	}
+	return op.outcomes[move.Name], "", nil
+}
+
+func (op *fakeOperation) Rollback(done []fileMove) bool {
+	op.rolledBack = done
+	return op.atomic
+}
+
+func (op *fakeOperation) Finish(report *operationReport) {
+	op.finished = true
+}
+
+// TestRunOperation tests the tally of an operation's steps, and rolling back one that is all or nothing
+func TestRunOperation(t *testing.T) {
+	plan := []fileMove{
+		{Name: "a.lnk", Mode: "work"}, {Name: "b.lnk", Mode: "work"}, {Name: "c.lnk", Mode: "work"},
+		{Name: "d.lnk", Mode: "work"}, {Name: "e.lnk", Mode: "work"},
+	}
+	op := &fakeOperation{plan: plan, fail: "e.lnk", outcomes: map[string]stepOutcome{"b.lnk": stepChanged, "c.lnk": stepSkipped, "d.lnk": stepPending}}
+	report := &operationReport{Action: "move", Verb: "moved"}
+	if err := runOperation(&Config{}, op, report, false); err != nil {
+		t.Fatalf("runOperation() returned error: %v", err)
+	}
+	if !op.finished || !reflect.DeepEqual(report.names(), []string{"a.lnk"}) || !reflect.DeepEqual(report.Changed, []string{"b.lnk"}) ||
+		report.Skipped != 1 || !reflect.DeepEqual(report.Pending, []string{"d.lnk"}) || report.Failed != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	op = &fakeOperation{plan: plan[:2], fail: "b.lnk", atomic: true}
+	err := runOperation(&Config{}, op, &operationReport{Action: "move", Verb: "moved"}, false)
+	if err == nil || !strings.Contains(err.Error(), "nothing was changed") {
+		t.Errorf("Expected the failed move to roll the operation back, got %v", err)
+	}
+	if op.finished || !reflect.DeepEqual(op.rolledBack, plan[:1]) {
+		t.Errorf("Expected a.lnk rolled back and nothing recorded, got %v (finished %v)", op.rolledBack, op.finished)
+	}
+
+	// A dry run plans every move without executing any
+	op = &fakeOperation{plan: plan, fail: "a.lnk"}
+	report = &operationReport{Action: "move", Verb: "moved"}
+	if err := runOperation(&Config{}, op, report, true); err != nil || len(report.Done) != len(plan) {
+		t.Errorf("Expected %d planned moves, got %v (err %v)", len(plan), report.Done, err)
+	}
+
+	op = &fakeOperation{}
+	if err := runOperation(&Config{}, op, &operationReport{}, false); err != nil || op.finished {
+		t.Errorf("Expected an empty plan to end quietly, got %v (finished %v)", err, op.finished)
+	}
+}
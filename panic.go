@@ -0,0 +1,113 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// panicWait is how long "panic" waits for a running session to end before restoring everything
+const panicWait = 5 * time.Second
+
+// sessionControlPath returns the path where commands for the running session are left
+func sessionControlPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session.control"), nil
+}
+
+// sendSessionCommand leaves a command for the running session, which picks it up within a second
+// Commands are the ones typed during a session, e.g. "p" or "q"
+func sendSessionCommand(command string) error {
+	path, err := sessionControlPath()
+	if err != nil {
+		return err
+	}
+	// Write then rename so the session never reads half a command
+	tmp := path + ".tmp"
+	if err := writeFile(tmp, []byte(command+"\n"), 0600); err != nil {
+		return fmt.Errorf("error writing session command: %w", err)
+	}
+	if err := renameFile(tmp, path); err != nil {
+		return fmt.Errorf("error writing session command: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// pollControl runs the commands left for the session by sendSessionCommand
+func (fs *FocusSession) pollControl() {
+	if fs.ControlPath == "" {
+		return
+	}
+	data, err := os.ReadFile(fs.ControlPath)
+	if err != nil {
+		return
+	}
+	removeFile(fs.ControlPath)
+	for _, command := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(command) != "" && fs.isActive() {
+			fs.handleCommand(command)
+		}
+	}
+}
+
+// panicRestore ends a running session and restores the shortcuts of every mode
+// Strict mode is honored: a strict session can't be ended this way either
+func panicRestore(config *Config, dryRun bool) error {
+	path, err := activeSessionPath()
+	if err != nil {
+		return err
+	}
+	state, err := readActiveSession(path)
+	if err != nil {
+		return err
+	}
+	if state != nil && !state.Break && config.isStrict() {
+		return fmt.Errorf("strict mode: the running %s session cannot be ended early", state.Mode)
+	}
+
+	if state != nil && !dryRun {
+		fmt.Printf("Ending the running %s session...\n", state.Mode)
+		if err := sendSessionCommand("panic"); err != nil {
+			return err
+		}
+		deadline := time.Now().Add(panicWait)
+		for isSessionRunning() && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if isSessionRunning() {
+			fmt.Fprintln(os.Stderr, "Warning: the session did not end in time; restoring anyway")
+		}
+	} else if state != nil {
+		fmt.Printf("[DRY RUN] Would end the running %s session\n", state.Mode)
+	} else if stale, _ := readStaleSession(path); stale != nil && !dryRun {
+		// Everything is restored below, so there is nothing left to recover
+		removeFile(path)
+	}
+
+	return restoreAllShortcuts(config, dryRun)
+}
+
+// runPanicCommand handles the "panic" subcommand, which puts the normal desktop back at once
+func runPanicCommand(args []string) {
+	flags := flag.NewFlagSet("panic", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be restored without actually moving")
+	flags.Parse(args)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := panicRestore(config, *dryRun); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,137 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPollControl tests that commands from other processes reach the running session
+func TestPollControl(t *testing.T) {
+	dir := t.TempDir()
+	fs := &FocusSession{
+		Duration:          25 * time.Minute,
+		StartTime:         time.Now(),
+		Config:            &Config{},
+		State:             StateRunning,
+		RestoreCategories: []string{"work"},
+		ControlPath:       filepath.Join(dir, "session.control"),
+	}
+
+	fs.pollControl()
+	if fs.State != StateRunning {
+		t.Fatal("Expected nothing to happen without a command")
+	}
+
+	os.WriteFile(fs.ControlPath, []byte("p\n"), 0600)
+	fs.pollControl()
+	if fs.State != StatePaused {
+		t.Errorf("Expected the session to pause, got state %v", fs.State)
+	}
+	if _, err := os.Stat(fs.ControlPath); !os.IsNotExist(err) {
+		t.Error("Expected the command to be consumed")
+	}
+
+	os.WriteFile(fs.ControlPath, []byte("panic\n"), 0600)
+	fs.pollControl()
+	if fs.State != StateInterrupted || !fs.AutoRestore || fs.RestoreCategories != nil {
+		t.Errorf("Expected panic to end the session restoring everything, got %+v", fs)
+	}
+}
+
+// TestPollControlPauseResume tests that "pause" and "resume" from "focusmode session" only go one way
+func TestPollControlPauseResume(t *testing.T) {
+	fs := &FocusSession{
+		Duration:    25 * time.Minute,
+		StartTime:   time.Now(),
+		Config:      &Config{},
+		State:       StateRunning,
+		ControlPath: filepath.Join(t.TempDir(), "session.control"),
+	}
+	steps := []struct {
+		command string
+		want    SessionState
+	}{
+		{"resume", StateRunning},
+		{"pause", StatePaused},
+		{"pause", StatePaused},
+		{"resume", StateRunning},
+		{"e 10", StateRunning},
+		{"stop", StateInterrupted},
+	}
+	for _, step := range steps {
+		os.WriteFile(fs.ControlPath, []byte(step.command+"\n"), 0600)
+		fs.pollControl()
+		if fs.State != step.want {
+			t.Errorf("After %q: state %v, want %v", step.command, fs.State, step.want)
+		}
+	}
+	if fs.Duration != 35*time.Minute {
+		t.Errorf("Expected the session extended to 35m, got %s", fs.Duration)
+	}
+}
+
+// TestPollControlStrict tests that a strict session can't be ended by panic
+func TestPollControlStrict(t *testing.T) {
+	fs := &FocusSession{
+		Duration:    25 * time.Minute,
+		StartTime:   time.Now(),
+		Config:      &Config{Policy: &Policy{Strict: true}},
+		State:       StateRunning,
+		ControlPath: filepath.Join(t.TempDir(), "session.control"),
+	}
+	os.WriteFile(fs.ControlPath, []byte("panic\n"), 0600)
+	fs.pollControl()
+	if fs.State != StateRunning {
+		t.Errorf("Expected strict session to keep running, got state %v", fs.State)
+	}
+}
+
+// TestPanicRestore tests restoring all modes and clearing a crashed session without a running one
+func TestPanicRestore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	os.MkdirAll(filepath.Join(home, "Desktop"), 0755)
+	os.MkdirAll(filepath.Join(home, "Games"), 0755)
+	os.WriteFile(filepath.Join(home, "Games", "Steam.lnk"), nil, 0644)
+
+	path, err := activeSessionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeActiveSession(path, activeSession{PID: 999999999, Mode: "focusmode", MovedShortcuts: []string{"Steam.lnk"}})
+
+	config := &Config{Modes: map[string]ModeConfig{"focusmode": {Destination: "Games"}}}
+	if err := panicRestore(config, false); err != nil {
+		t.Fatalf("panicRestore() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "Desktop", "Steam.lnk")); err != nil {
+		t.Errorf("Expected Steam.lnk back on the desktop: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the crashed session to be cleared")
+	}
+}
+
+// TestPanicRestoreStrict tests that panic refuses to end a running strict session
+func TestPanicRestoreStrict(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	path, err := activeSessionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeActiveSession(path, activeSession{PID: os.Getpid(), Mode: "focusmode"})
+
+	config := &Config{Policy: &Policy{Strict: true}}
+	if err := panicRestore(config, false); err == nil || !strings.Contains(err.Error(), "strict mode") {
+		t.Errorf("Expected strict mode error, got %v", err)
+	}
+}
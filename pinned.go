@@ -0,0 +1,103 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// errPinned is returned for a move of a pinned file off the desktop
+var errPinned = errors.New("pinned to the desktop")
+
+// pinned returns the files the config pins to the desktop
+// Shortcuts the machine policy always hides can't be pinned
+func (c *Config) pinned() []string {
+	var pinned []string
+	for _, name := range c.Pinned {
+		name = strings.TrimSpace(name)
+		if name != "" && (c.Policy == nil || !containsFold(c.Policy.AlwaysHidden, name)) {
+			pinned = append(pinned, name)
+		}
+	}
+	return pinned
+}
+
+// isPinned reports whether a file name is pinned, matched without regard to case or
+// normalization form like the other lists of names; a nil config pins nothing
+func (c *Config) isPinned(name string) bool {
+	if c == nil {
+		return false
+	}
+	for _, pin := range c.pinned() {
+		if strings.EqualFold(nfc(pin), nfc(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFolder reports whether two paths name the same folder, ignoring case on Windows
+func sameFolder(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// isDesktopFolder reports whether dir is the desktop being acted on or the public desktop
+func isDesktopFolder(dir string) bool {
+	if desktop, err := getDesktopPath(); err == nil && sameFolder(dir, desktop) {
+		return true
+	}
+	public := publicDesktopPath()
+	return public != "" && sameFolder(dir, public)
+}
+
+// checkPinned refuses to move a file the config pins out of a desktop folder
+// Every move off the desktop checks it, whatever planned it: modes, sessions, the archive
+// sweep, installer rules and undo
+func (c *Config) checkPinned(fromDir, name string) error {
+	if !c.isPinned(name) || !isDesktopFolder(fromDir) {
+		return nil
+	}
+	return fmt.Errorf("'%s' is %w", name, errPinned)
+}
+
+// describePinned returns the line printed for a pinned file left where it is
+func describePinned(name string) string {
+	return fmt.Sprintf("%sPinned: %s (left on the desktop)", glyph("📌 "), name)
+}
+
+// lintPinned warns about pins that have no effect
+func (c *Config) lintPinned() []string {
+	var warnings []string
+	var seen []string
+	for _, name := range c.Pinned {
+		switch trimmed := strings.TrimSpace(name); {
+		case trimmed == "":
+			warnings = append(warnings, "pinned lists an empty name")
+		case strings.ContainsAny(trimmed, `/\`):
+			warnings = append(warnings, fmt.Sprintf("pinned '%s' is a path; list the file's name as it appears on the desktop", name))
+		case c.Policy != nil && containsFold(c.Policy.AlwaysHidden, trimmed):
+			warnings = append(warnings, fmt.Sprintf("pinned '%s' is always hidden by machine policy and is moved anyway", name))
+		case containsFold(seen, trimmed):
+			warnings = append(warnings, fmt.Sprintf("pinned lists '%s' more than once", name))
+		default:
+			seen = append(seen, trimmed)
+		}
+	}
+	modes := c.getAvailableModes()
+	sort.Strings(modes)
+	for _, modeName := range modes {
+		for _, name := range c.Modes[modeName].Shortcuts {
+			if containsFold(seen, name) {
+				warnings = append(warnings, fmt.Sprintf("mode '%s' lists pinned shortcut '%s'; it stays on the desktop", modeName, name))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,159 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useFakeDesktop makes a temp folder the desktop, without a public desktop
+func useFakeDesktop(t *testing.T) string {
+	t.Helper()
+	desktop := t.TempDir()
+	useFakePlatform(t, &fakePlatform{desktop: desktop})
+	old := publicDesktopPath
+	publicDesktopPath = func() string { return "" }
+	t.Cleanup(func() { publicDesktopPath = old })
+	return desktop
+}
+
+// TestCheckPinned tests that only moves of pinned files off the desktop are refused
+func TestCheckPinned(t *testing.T) {
+	desktop := useFakeDesktop(t)
+	config := &Config{Pinned: []string{"Current Project.lnk"}}
+
+	if err := config.checkPinned(desktop, "current project.LNK"); !errors.Is(err, errPinned) {
+		t.Errorf("Expected a pinned file refused, got %v", err)
+	}
+	if err := config.checkPinned(desktop, "Steam.lnk"); err != nil {
+		t.Errorf("Expected other files allowed, got %v", err)
+	}
+	if err := config.checkPinned(t.TempDir(), "Current Project.lnk"); err != nil {
+		t.Errorf("Expected a pinned file allowed back out of a hidden folder, got %v", err)
+	}
+	// Pins belong to the config being acted on, not whichever was loaded last
+	if err := (&Config{}).checkPinned(desktop, "Current Project.lnk"); err != nil {
+		t.Errorf("Expected a config without pins to allow the move, got %v", err)
+	}
+	var none *Config
+	if err := none.checkPinned(desktop, "Current Project.lnk"); err != nil {
+		t.Errorf("Expected no config to pin nothing, got %v", err)
+	}
+}
+
+// TestMovePinnedShortcut tests that the mover leaves pinned files on the desktop whoever asks
+func TestMovePinnedShortcut(t *testing.T) {
+	desktop := useFakeDesktop(t)
+	config := &Config{Pinned: []string{"Notes.txt"}}
+	hidden := t.TempDir()
+	for _, name := range []string{"Notes.txt", "Steam.lnk"} {
+		if err := os.WriteFile(filepath.Join(desktop, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := config.moveDesktopShortcut("Notes.txt", hidden); !errors.Is(err, errPinned) {
+		t.Errorf("moveDesktopShortcut() = %v, want a pinned error", err)
+	}
+	move := fileMove{Name: "Notes.txt", From: desktop, To: hidden}
+	if err := moveOne(config, &move); !errors.Is(err, errPinned) {
+		t.Errorf("moveOne() = %v, want a pinned error", err)
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Notes.txt")); err != nil {
+		t.Errorf("Expected the pinned file still on the desktop: %v", err)
+	}
+	if err := config.moveDesktopShortcut("Steam.lnk", hidden); err != nil {
+		t.Errorf("Expected other files moved, got %v", err)
+	}
+
+	conflicts := moveConflicts(config, []fileMove{{Name: "Notes.txt", From: desktop, To: hidden}}, desktop)
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0], "pinned") {
+		t.Errorf("Expected undo to refuse the pinned file, got %v", conflicts)
+	}
+}
+
+// pinnedTestOperation plans fixed moves and records the ones executed
+type pinnedTestOperation struct {
+	plan     []fileMove
+	executed []string
+}
+
+func (op *pinnedTestOperation) Plan() ([]fileMove, error) { return op.plan, nil }
+
+func (op *pinnedTestOperation) Execute(move *fileMove) (stepOutcome, string, error) {
+	op.executed = append(op.executed, move.Name)
+	return stepDone, "", nil
+}
+
+func (op *pinnedTestOperation) Rollback(done []fileMove) bool { return false }
+
+func (op *pinnedTestOperation) Finish(report *operationReport) {}
+
+// TestRunOperationSkipsPinned tests that every operation skips pinned files, dry runs included
+func TestRunOperationSkipsPinned(t *testing.T) {
+	desktop := useFakeDesktop(t)
+	config := &Config{Pinned: []string{"Notes.txt"}}
+	hidden := t.TempDir()
+
+	for _, dryRun := range []bool{false, true} {
+		op := &pinnedTestOperation{plan: []fileMove{
+			{Name: "Notes.txt", Mode: "clean", From: desktop, To: hidden},
+			{Name: "Steam.lnk", Mode: "clean", From: desktop, To: hidden},
+			{Name: "Notes.txt", Mode: "clean", From: hidden, To: desktop},
+		}}
+		report := &operationReport{Action: "move", Verb: "moved"}
+		if err := runOperation(config, op, report, dryRun); err != nil {
+			t.Fatal(err)
+		}
+		if report.Skipped != 1 {
+			t.Errorf("dry run %v: skipped %d, want 1", dryRun, report.Skipped)
+		}
+		if !dryRun && strings.Join(op.executed, ",") != "Steam.lnk,Notes.txt" {
+			t.Errorf("Unexpected moves: %v", op.executed)
+		}
+	}
+}
+
+// TestRestoreDoesNotOverwritePinned tests that a restore with overwrite keeps a pinned file
+func TestRestoreDoesNotOverwritePinned(t *testing.T) {
+	desktop := useFakeDesktop(t)
+	config := &Config{Pinned: []string{"Notes.txt"}}
+	hidden := t.TempDir()
+	os.WriteFile(filepath.Join(desktop, "Notes.txt"), []byte("pinned"), 0644)
+	os.WriteFile(filepath.Join(hidden, "Notes.txt"), []byte("hidden"), 0644)
+
+	result, err := config.restoreShortcutWithPolicy("Notes.txt", hidden, conflictOverwrite)
+	if err != nil || !result.Skipped {
+		t.Errorf("restoreShortcutWithPolicy() = %+v, %v; want skipped", result, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(desktop, "Notes.txt")); string(data) != "pinned" {
+		t.Errorf("Expected the pinned file kept, got %q", data)
+	}
+}
+
+// TestPinnedConfig tests which pins take effect and the warnings about the others
+func TestPinnedConfig(t *testing.T) {
+	config := &Config{
+		Pinned: []string{"Notes.txt", "Steam.lnk", "notes.txt", " ", `C:\Users\me\Desktop\Mail.lnk`},
+		Modes:  map[string]ModeConfig{"games": {Shortcuts: []string{"Notes.txt"}}},
+		Policy: &Policy{AlwaysHidden: []string{"steam.lnk"}},
+	}
+	if pinned := config.pinned(); strings.Join(pinned, ",") != `Notes.txt,notes.txt,C:\Users\me\Desktop\Mail.lnk` {
+		t.Errorf("Unexpected pins: %v", pinned)
+	}
+
+	warnings := strings.Join(config.lintPinned(), "\n")
+	for _, want := range []string{
+		"pinned 'Steam.lnk' is always hidden by machine policy",
+		"pinned lists 'notes.txt' more than once",
+		"pinned lists an empty name",
+		`pinned 'C:\Users\me\Desktop\Mail.lnk' is a path`,
+		"mode 'games' lists pinned shortcut 'Notes.txt'",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected a warning %q, got:\n%s", want, warnings)
+		}
+	}
+}
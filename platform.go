@@ -0,0 +1,300 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// platform is what FocusMode asks of the operating system besides moving files
+// systemPlatform is the real one; tests replace currentPlatform with a fake
+type platform interface {
+	DesktopPath() (string, error)
+	Notify(title, message string) error
+	DND() (bool, error)
+	SetDND(on bool) error
+	SetWallpaper(path string) error
+	IsProcessRunning(name string) (bool, error)
+	MinimizeProcess(pid int) error
+	DesktopIcons() (*iconLayout, error)
+}
+
+// currentPlatform is the platform FocusMode runs on
+var currentPlatform platform = newSystemPlatform(runtime.GOOS)
+
+// systemPlatform carries out platform requests with the commands of the OS named by goos
+// Its commands are built by functions of goos and lookPath and run through run, so a test on
+// any OS can check those of the others
+type systemPlatform struct {
+	goos     string
+	lookPath func(file string) (string, error)
+	run      func(command []string) error
+	output   func(command []string) ([]byte, error)
+}
+
+// newSystemPlatform returns the platform for goos, running its commands
+func newSystemPlatform(goos string) *systemPlatform {
+	return &systemPlatform{goos: goos, lookPath: exec.LookPath, run: runPlatformCommand, output: platformCommandOutput}
+}
+
+// runPlatformCommand runs a command, returning its output with the error if it fails
+func runPlatformCommand(command []string) error {
+	if out, err := exec.Command(command[0], command[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// platformCommandOutput runs a command and returns its output, with the error output if it fails
+func platformCommandOutput(command []string) ([]byte, error) {
+	out, err := exec.Command(command[0], command[1:]...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s: %v %s", command[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// DesktopPath returns the managed user's desktop folder
+func (p *systemPlatform) DesktopPath() (string, error) {
+	switch p.goos {
+	case "windows":
+		// The managed user rather than USERPROFILE, which belongs to the service account
+		// when run from a scheduled task or service; the registry knows where the desktop
+		// is when it has been moved to OneDrive or another folder
+		ctx, err := currentUserContext()
+		if err != nil {
+			return "", err
+		}
+		return windowsDesktopPath(ctx), nil
+	case "darwin":
+		// On macOS, the desktop path is typically ~/Desktop.
+		homeDir, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(homeDir, "Desktop"), nil
+	case "linux":
+		// Named by XDG user-dirs, and often localized
+		ctx, err := currentUserContext()
+		if err != nil {
+			return "", err
+		}
+		return linuxDesktopPath(ctx)
+	default:
+		return "", fmt.Errorf("unsupported operating system: %s", p.goos)
+	}
+}
+
+// Notify shows a desktop notification
+func (p *systemPlatform) Notify(title, message string) error {
+	name, args, err := notifyCommandArgs(p.goos, title, message, p.lookPath)
+	if err != nil {
+		return err
+	}
+	return p.run(append([]string{name}, args...))
+}
+
+// DND reports whether the desktop's do-not-disturb mode is on
+func (p *systemPlatform) DND() (bool, error) {
+	command, err := dndStateCommand(p.goos, p.lookPath)
+	if err != nil || command == nil {
+		return false, err
+	}
+	out, err := p.output(command)
+	if err != nil {
+		if p.goos == "windows" {
+			// The value is only there once notifications have been turned off and on again
+			return false, nil
+		}
+		return false, err
+	}
+	return parseDNDState(p.goos, string(out))
+}
+
+// SetDND turns the desktop's do-not-disturb mode on or off
+func (p *systemPlatform) SetDND(on bool) error {
+	command, err := dndCommand(p.goos, on, p.lookPath)
+	if err != nil {
+		return err
+	}
+	return p.run(command)
+}
+
+// SetWallpaper shows an image file as the desktop background
+func (p *systemPlatform) SetWallpaper(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("wallpaper not found: %s", path)
+	}
+	commands, err := wallpaperCommands(p.goos, path, p.lookPath)
+	if err != nil {
+		return err
+	}
+	if err := p.run(commands[0]); err != nil {
+		return err
+	}
+	// The rest set the same image for other variants, such as GNOME's dark style, which
+	// older desktops don't have
+	for _, command := range commands[1:] {
+		p.run(command)
+	}
+	return nil
+}
+
+// IsProcessRunning reports whether a process of the named program is running, matched as
+// block_apps entries are, without case or .exe
+func (p *systemPlatform) IsProcessRunning(name string) (bool, error) {
+	processes, err := listProcesses()
+	if err != nil {
+		return false, err
+	}
+	key := appKey(name)
+	for _, process := range processes {
+		if appKey(process.Name) == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MinimizeProcess minimizes the windows of a process
+func (p *systemPlatform) MinimizeProcess(pid int) error {
+	command, err := minimizeCommand(p.goos, pid, p.lookPath)
+	if err != nil {
+		return err
+	}
+	return p.run(command)
+}
+
+// DesktopIcons returns where the icons on the desktop sit
+func (p *systemPlatform) DesktopIcons() (*iconLayout, error) {
+	command, err := desktopIconsCommand(p.goos)
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.output(command)
+	if err != nil {
+		return nil, fmt.Errorf("error reading desktop icon positions: %w", err)
+	}
+	return parseIconLayout(string(out))
+}
+
+// macDNDShortcuts are the Shortcuts run to turn Focus on and off on macOS, which has no
+// command of its own for it; each holds a single Set Focus action
+var macDNDShortcuts = map[bool]string{true: "FocusMode DND On", false: "FocusMode DND Off"}
+
+// dndCommand returns the command that turns do-not-disturb on or off on the given platform
+// Windows has no interface for Focus Assist, so its notification banners are turned off instead
+func dndCommand(goos string, on bool, lookPath func(string) (string, error)) ([]string, error) {
+	switch goos {
+	case "windows":
+		enabled := "1"
+		if on {
+			enabled = "0"
+		}
+		return []string{"reg", "add", `HKCU\Software\Microsoft\Windows\CurrentVersion\PushNotifications`,
+			"/v", "ToastEnabled", "/t", "REG_DWORD", "/d", enabled, "/f"}, nil
+	case "darwin":
+		if _, err := lookPath("shortcuts"); err != nil {
+			return nil, fmt.Errorf("shortcuts not found (Do Not Disturb needs macOS 12 or later)")
+		}
+		return []string{"shortcuts", "run", macDNDShortcuts[on]}, nil
+	case "linux":
+		if _, err := lookPath("gsettings"); err != nil {
+			return nil, fmt.Errorf("gsettings not found (Do Not Disturb needs GNOME)")
+		}
+		return []string{"gsettings", "set", "org.gnome.desktop.notifications", "show-banners", fmt.Sprint(!on)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// dndStateCommand returns the command that reads whether do-not-disturb is on, or nil on macOS,
+// whose Focus state no command reads; it is then taken to be off
+func dndStateCommand(goos string, lookPath func(string) (string, error)) ([]string, error) {
+	switch goos {
+	case "windows":
+		return []string{"reg", "query", `HKCU\Software\Microsoft\Windows\CurrentVersion\PushNotifications`, "/v", "ToastEnabled"}, nil
+	case "darwin":
+		return nil, nil
+	case "linux":
+		if _, err := lookPath("gsettings"); err != nil {
+			return nil, fmt.Errorf("gsettings not found (Do Not Disturb needs GNOME)")
+		}
+		return []string{"gsettings", "get", "org.gnome.desktop.notifications", "show-banners"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// parseDNDState reads the output of dndStateCommand: banners or toasts turned off mean
+// do-not-disturb is on
+func parseDNDState(goos, out string) (bool, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return false, fmt.Errorf("no do-not-disturb state in %q", out)
+	}
+	value := fields[len(fields)-1]
+	switch {
+	case goos == "windows" && value == "0x0":
+		return true, nil
+	case goos == "windows" && strings.HasPrefix(value, "0x"):
+		return false, nil
+	case value == "false":
+		return true, nil
+	case value == "true":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected do-not-disturb state %q", value)
+}
+
+// wallpaperCommands returns the commands that set the desktop background to the image at path
+// Commands after the first are for variants the desktop may not have
+func wallpaperCommands(goos, path string, lookPath func(string) (string, error)) ([][]string, error) {
+	switch goos {
+	case "windows":
+		script := `Add-Type -TypeDefinition 'using System.Runtime.InteropServices; public class Wallpaper { [DllImport("user32.dll", CharSet = CharSet.Unicode)] public static extern bool SystemParametersInfo(int action, int param, string value, int flags); }'; ` +
+			fmt.Sprintf("if (-not [Wallpaper]::SystemParametersInfo(20, 0, '%s', 3)) { exit 1 }", strings.ReplaceAll(path, "'", "''"))
+		return [][]string{{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}}, nil
+	case "darwin":
+		quoted := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path) + `"`
+		return [][]string{{"osascript", "-e", fmt.Sprintf("tell application \"System Events\" to tell every desktop to set picture to %s", quoted)}}, nil
+	case "linux":
+		if _, err := lookPath("gsettings"); err != nil {
+			return nil, fmt.Errorf("gsettings not found (setting the wallpaper needs GNOME)")
+		}
+		uri := "file://" + filepath.ToSlash(path)
+		return [][]string{
+			{"gsettings", "set", "org.gnome.desktop.background", "picture-uri", uri},
+			{"gsettings", "set", "org.gnome.desktop.background", "picture-uri-dark", uri},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// minimizeCommand returns the command that minimizes the windows of a process on the given platform
+// macOS hides the app instead, which is what it does for a whole app rather than one window
+func minimizeCommand(goos string, pid int, lookPath func(string) (string, error)) ([]string, error) {
+	switch goos {
+	case "windows":
+		script := `Add-Type -TypeDefinition 'using System; using System.Runtime.InteropServices; public class Minimizer { [DllImport("user32.dll")] public static extern bool ShowWindowAsync(IntPtr window, int show); }'; ` +
+			fmt.Sprintf("$window = (Get-Process -Id %d).MainWindowHandle; if ($window -ne 0) { [Minimizer]::ShowWindowAsync($window, 6) > $null }", pid)
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "darwin":
+		return []string{"osascript", "-e", fmt.Sprintf("tell application \"System Events\" to set visible of (first process whose unix id is %d) to false", pid)}, nil
+	case "linux":
+		if _, err := lookPath("xdotool"); err != nil {
+			return nil, fmt.Errorf("xdotool not found (minimizing apps needs X11 and xdotool)")
+		}
+		return []string{"xdotool", "search", "--pid", fmt.Sprint(pid), "windowminimize", "%@"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
@@ -0,0 +1,174 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakePlatform is a platform that records what it was asked instead of changing the desktop
+type fakePlatform struct {
+	desktop   string
+	running   []string
+	notified  []string
+	dnd       bool
+	wallpaper string
+	minimized []int
+	icons     *iconLayout
+	err       error // Returned by every request when set
+}
+
+func (p *fakePlatform) DesktopPath() (string, error) { return p.desktop, p.err }
+
+func (p *fakePlatform) Notify(title, message string) error {
+	p.notified = append(p.notified, title+": "+message)
+	return p.err
+}
+
+func (p *fakePlatform) DND() (bool, error) { return p.dnd, p.err }
+
+func (p *fakePlatform) SetDND(on bool) error {
+	if p.err == nil {
+		p.dnd = on
+	}
+	return p.err
+}
+
+func (p *fakePlatform) SetWallpaper(path string) error {
+	if p.err == nil {
+		p.wallpaper = path
+	}
+	return p.err
+}
+
+func (p *fakePlatform) IsProcessRunning(name string) (bool, error) {
+	return containsFold(p.running, name), p.err
+}
+
+func (p *fakePlatform) MinimizeProcess(pid int) error {
+	if p.err == nil {
+		p.minimized = append(p.minimized, pid)
+	}
+	return p.err
+}
+
+func (p *fakePlatform) DesktopIcons() (*iconLayout, error) {
+	if p.icons == nil && p.err == nil {
+		return nil, errors.New("no icon layout")
+	}
+	return p.icons, p.err
+}
+
+// useFakePlatform replaces the current platform with a fake for the test
+func useFakePlatform(t *testing.T, fake *fakePlatform) {
+	saved := currentPlatform
+	currentPlatform = fake
+	t.Cleanup(func() { currentPlatform = saved })
+}
+
+// TestGetDesktopPathFromPlatform tests that the desktop and notifications come from the current platform
+func TestGetDesktopPathFromPlatform(t *testing.T) {
+	fake := &fakePlatform{desktop: filepath.Join(t.TempDir(), "Bureau")}
+	useFakePlatform(t, fake)
+	if path, err := getDesktopPath(); err != nil || path != fake.desktop {
+		t.Errorf("getDesktopPath() = %q, %v; want %q", path, err, fake.desktop)
+	}
+	if err := showDesktopNotification("Done", "25m in focusmode"); err != nil || len(fake.notified) != 1 {
+		t.Errorf("Expected the notification shown by the platform, got %v (err %v)", fake.notified, err)
+	}
+}
+
+// TestSystemPlatformCommands tests the commands each platform runs for do-not-disturb, wallpapers and minimizing
+func TestSystemPlatformCommands(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "calm.png")
+	os.WriteFile(image, nil, 0644)
+	found := func(string) (string, error) { return "/usr/bin/x", nil }
+	var ran [][]string
+	platformFor := func(goos string) *systemPlatform {
+		ran = nil
+		return &systemPlatform{goos: goos, lookPath: found, run: func(command []string) error {
+			ran = append(ran, command)
+			return nil
+		}}
+	}
+
+	p := platformFor("linux")
+	p.SetDND(true)
+	p.SetWallpaper(image)
+	if len(ran) != 3 || !reflect.DeepEqual(ran[0], []string{"gsettings", "set", "org.gnome.desktop.notifications", "show-banners", "false"}) ||
+		ran[1][len(ran[1])-1] != "file://"+filepath.ToSlash(image) || ran[2][3] != "picture-uri-dark" {
+		t.Errorf("linux: %v", ran)
+	}
+
+	p = platformFor("darwin")
+	p.SetDND(false)
+	p.SetWallpaper(image)
+	if len(ran) != 2 || !reflect.DeepEqual(ran[0], []string{"shortcuts", "run", "FocusMode DND Off"}) || !strings.Contains(ran[1][2], `set picture to "`+image+`"`) {
+		t.Errorf("darwin: %v", ran)
+	}
+
+	p = platformFor("windows")
+	p.SetDND(true)
+	p.SetWallpaper(image)
+	if len(ran) != 2 || ran[0][0] != "reg" || ran[0][8] != "0" || !strings.Contains(ran[1][4], "SystemParametersInfo(20, 0, '"+image+"', 3)") {
+		t.Errorf("windows: %v", ran)
+	}
+
+	if err := platformFor("linux").SetWallpaper(filepath.Join(filepath.Dir(image), "missing.png")); err == nil || len(ran) != 0 {
+		t.Errorf("Expected a missing wallpaper to be reported without running anything, got %v", err)
+	}
+	p = platformFor("linux")
+	p.lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	if err := p.SetDND(true); err == nil || !strings.Contains(err.Error(), "GNOME") {
+		t.Errorf("Expected an error naming GNOME, got %v", err)
+	}
+	p = platformFor("linux")
+	p.MinimizeProcess(42)
+	if len(ran) != 1 || !reflect.DeepEqual(ran[0], []string{"xdotool", "search", "--pid", "42", "windowminimize", "%@"}) {
+		t.Errorf("linux minimize: %v", ran)
+	}
+	if err := platformFor("plan9").SetDND(true); err == nil {
+		t.Error("Expected an error for an unsupported OS")
+	}
+}
+
+// TestParseDNDState tests reading whether do-not-disturb is on from each platform's command
+func TestParseDNDState(t *testing.T) {
+	for _, tc := range []struct {
+		goos, out string
+		want      bool
+	}{
+		{"linux", "false\n", true},
+		{"linux", "true\n", false},
+		{"windows", "\r\nHKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\PushNotifications\r\n    ToastEnabled    REG_DWORD    0x0\r\n\r\n", true},
+		{"windows", "    ToastEnabled    REG_DWORD    0x1\r\n", false},
+	} {
+		if got, err := parseDNDState(tc.goos, tc.out); err != nil || got != tc.want {
+			t.Errorf("parseDNDState(%s, %q) = %v, %v; want %v", tc.goos, tc.out, got, err, tc.want)
+		}
+	}
+	if _, err := parseDNDState("linux", ""); err == nil {
+		t.Error("Expected empty output refused")
+	}
+	if command, err := dndStateCommand("darwin", nil); command != nil || err != nil {
+		t.Errorf("Expected no state command on macOS, got %v, %v", command, err)
+	}
+}
+
+// TestIsProcessRunning tests matching processes by program name without case or .exe
+func TestIsProcessRunning(t *testing.T) {
+	saved := listProcesses
+	defer func() { listProcesses = saved }()
+	listProcesses = func() ([]runningProcess, error) {
+		return []runningProcess{{PID: 1, Name: "explorer.exe"}, {PID: 2, Name: "Discord.exe"}}, nil
+	}
+	p := newSystemPlatform("windows")
+	for name, want := range map[string]bool{"discord": true, "Discord.exe": true, "steam.exe": false} {
+		if got, err := p.IsProcessRunning(name); err != nil || got != want {
+			t.Errorf("IsProcessRunning(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is machine-level configuration set by an administrator or accountability partner
+// It is read from a system location and cannot be overridden by the user's profile
+type Policy struct {
+	Strict            bool           `yaml:"strict"`              // Sessions cannot be paused or stopped early
+	MinSessionMinutes int            `yaml:"min_session_minutes"` // Shortest allowed focus session
+	AlwaysHidden      []string       `yaml:"always_hidden"`       // Shortcuts moved by every mode and never restored
+	Guardian          GuardianPolicy `yaml:"guardian"`            // Guardian PIN and session report
+
+	path string // file the policy was read from
+}
+
+// machinePolicyPath is the location of the machine policy, overridden in tests
+var machinePolicyPath = defaultPolicyPath(runtime.GOOS)
+
+// defaultPolicyPath returns the system-wide policy location for the platform
+func defaultPolicyPath(goos string) string {
+	switch goos {
+	case "windows":
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "FocusMode", "policy.yml")
+	case "darwin":
+		return "/Library/Application Support/FocusMode/policy.yml"
+	default:
+		return "/etc/focusmode/policy.yml"
+	}
+}
+
+// loadPolicy reads the machine policy, returning nil if none is installed
+func loadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading machine policy: %w", err)
+	}
+
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("error parsing machine policy %s: %w", path, err)
+	}
+	policy.path = path
+	return &policy, nil
+}
+
+// applyPolicy enforces a machine policy on a loaded configuration
+// Always-hidden shortcuts are added to every mode that lists its shortcuts
+func (c *Config) applyPolicy(policy *Policy) {
+	c.Policy = policy
+	if policy == nil || len(policy.AlwaysHidden) == 0 {
+		return
+	}
+	for name, modeConfig := range c.Modes {
+		if modeConfig.MoveAll {
+			continue
+		}
+		shortcuts := append([]string(nil), modeConfig.Shortcuts...)
+		for _, hidden := range policy.AlwaysHidden {
+			if !containsFold(shortcuts, hidden) {
+				shortcuts = append(shortcuts, hidden)
+			}
+		}
+		modeConfig.Shortcuts = shortcuts
+		c.Modes[name] = modeConfig
+	}
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStrict reports whether the machine policy forbids pausing or stopping sessions
+func (c *Config) isStrict() bool {
+	return c != nil && c.Policy != nil && c.Policy.Strict
+}
+
+// restorable returns the shortcuts the machine policy allows to be restored
+// Shortcuts held back are reported once on standard output
+func (c *Config) restorable(shortcuts []string) []string {
+	if c.Policy == nil || len(c.Policy.AlwaysHidden) == 0 {
+		return shortcuts
+	}
+	var allowed []string
+	held := 0
+	for _, shortcut := range shortcuts {
+		if containsFold(c.Policy.AlwaysHidden, shortcut) {
+			held++
+			continue
+		}
+		allowed = append(allowed, shortcut)
+	}
+	if held > 0 {
+		fmt.Printf("%s%d shortcut(s) kept hidden by machine policy\n", glyph("🔒 "), held)
+	}
+	return allowed
+}
+
+// checkSessionDuration rejects focus sessions shorter than the policy minimum
+func (c *Config) checkSessionDuration(minutes int) error {
+	if c.Policy != nil && c.Policy.MinSessionMinutes > 0 && minutes < c.Policy.MinSessionMinutes {
+		return fmt.Errorf("machine policy requires sessions of at least %d minutes, got: %d minutes", c.Policy.MinSessionMinutes, minutes)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestLoadPolicy tests reading the machine policy and its absence
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	if policy, err := loadPolicy(filepath.Join(dir, "missing.yml")); policy != nil || err != nil {
+		t.Errorf("Expected no policy for missing file, got %+v (err %v)", policy, err)
+	}
+
+	path := filepath.Join(dir, "policy.yml")
+	os.WriteFile(path, []byte("strict: true\nmin_session_minutes: 25\nalways_hidden: [Steam.lnk]\n"), 0644)
+	policy, err := loadPolicy(path)
+	if err != nil {
+		t.Fatalf("loadPolicy() returned error: %v", err)
+	}
+	if !policy.Strict || policy.MinSessionMinutes != 25 || len(policy.AlwaysHidden) != 1 {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+}
+
+// TestReadConfigAppliesPolicy tests that the user's profile cannot override the machine policy
+func TestReadConfigAppliesPolicy(t *testing.T) {
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.yml")
+	os.WriteFile(policyPath, []byte("strict: true\nalways_hidden: [steam.lnk, Epic.lnk]\n"), 0644)
+
+	original := machinePolicyPath
+	machinePolicyPath = policyPath
+	defer func() { machinePolicyPath = original }()
+
+	configPath := filepath.Join(dir, "profile.yml")
+	os.WriteFile(configPath, []byte(`
+policy:
+  strict: false
+modes:
+  focusmode:
+    shortcuts: [Steam.lnk]
+  everything:
+    move_all: true
+`), 0644)
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() returned error: %v", err)
+	}
+	if !config.isStrict() {
+		t.Error("Expected machine policy to stay strict")
+	}
+	if want := []string{"Steam.lnk", "Epic.lnk"}; !reflect.DeepEqual(config.Modes["focusmode"].Shortcuts, want) {
+		t.Errorf("Expected %v, got %v", want, config.Modes["focusmode"].Shortcuts)
+	}
+	if len(config.Modes["everything"].Shortcuts) != 0 {
+		t.Errorf("Expected move_all mode to be unchanged, got %v", config.Modes["everything"].Shortcuts)
+	}
+	if got := config.restorable([]string{"STEAM.lnk", "Word.lnk"}); !reflect.DeepEqual(got, []string{"Word.lnk"}) {
+		t.Errorf("Expected only Word.lnk to be restorable, got %v", got)
+	}
+}
+
+// TestPolicySessionRules tests the minimum duration and strict session commands
+func TestPolicySessionRules(t *testing.T) {
+	config := &Config{
+		Modes:  map[string]ModeConfig{"focusmode": {}},
+		Policy: &Policy{Strict: true, MinSessionMinutes: 25},
+	}
+
+	if _, err := startFocusSession(config, "focusmode", 15, true); err == nil {
+		t.Error("Expected session shorter than the policy minimum to be rejected")
+	}
+	session, err := startFocusSession(config, "focusmode", 25, true)
+	if err != nil {
+		t.Fatalf("startFocusSession() returned error: %v", err)
+	}
+
+	session.handleCommand("p")
+	session.handleCommand("q")
+	if session.State != StateRunning {
+		t.Errorf("Expected strict session to keep running, got state %v", session.State)
+	}
+
+	session.Break = true
+	session.handleCommand("q")
+	if session.State != StateInterrupted {
+		t.Errorf("Expected break to be stoppable in strict mode, got state %v", session.State)
+	}
+}
+
+// TestDefaultPolicyPath tests the system-wide policy locations
+func TestDefaultPolicyPath(t *testing.T) {
+	if got := defaultPolicyPath("linux"); got != "/etc/focusmode/policy.yml" {
+		t.Errorf("Unexpected linux path: %s", got)
+	}
+	t.Setenv("ProgramData", `D:\Data`)
+	if got := defaultPolicyPath("windows"); got != filepath.Join(`D:\Data`, "FocusMode", "policy.yml") {
+		t.Errorf("Unexpected windows path: %s", got)
+	}
+}
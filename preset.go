@@ -0,0 +1,73 @@
+package focusmode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SessionPreset is a named set of session options, e.g. "deepwork" for 50 minutes in focusmode
+type SessionPreset struct {
+	Mode              string            `yaml:"mode" doc:"Mode to apply (uses default mode if empty)"`
+	Duration          int               `yaml:"duration" doc:"Session length in minutes" example:"50"`
+	AutoRestore       *bool             `yaml:"auto_restore" doc:"Restore shortcuts at the end" default:"true"`
+	OnCompleteRestore []string          `yaml:"on_complete_restore" doc:"Categories restored when the session completes (empty restores all); the others stay hidden until restore_at or until restored by hand" example:"[work, development]"`
+	RestoreAt         map[string]string `yaml:"restore_at" doc:"Times of day (HH:MM) the daemon restores categories on_complete_restore kept hidden, by category" example:"{game: '18:00'}"`
+	Allowlist         *SessionAllowlist `yaml:"allowlist" doc:"Only these apps and sites are expected during the session; anything else is warned about"`
+}
+
+// getPreset returns a named session preset after validating it
+func (c *Config) getPreset(name string) (*SessionPreset, error) {
+	preset, exists := c.Presets[name]
+	if !exists {
+		return nil, fmt.Errorf("preset '%s' not found in configuration. Available presets: %v", name, c.getAvailablePresets())
+	}
+	if preset.Duration < 0 {
+		return nil, fmt.Errorf("preset '%s': duration must be positive, got: %d minutes", name, preset.Duration)
+	}
+	if preset.Allowlist != nil {
+		if err := preset.Allowlist.validate(); err != nil {
+			return nil, fmt.Errorf("preset '%s': %w", name, err)
+		}
+	}
+	for category, value := range preset.RestoreAt {
+		if _, err := parseClock(fmt.Sprintf("preset '%s': restore_at.%s", name, category), value); err != nil {
+			return nil, err
+		}
+	}
+	if preset.Mode == "" {
+		preset.Mode = c.DefaultMode
+	}
+	if _, err := c.getModeConfig(preset.Mode); err != nil {
+		return nil, fmt.Errorf("preset '%s': %w", name, err)
+	}
+	return &preset, nil
+}
+
+// getAvailablePresets returns the sorted list of preset names
+func (c *Config) getAvailablePresets() []string {
+	names := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitShortcutsByCategory separates shortcuts in the given categories from the rest
+func splitShortcutsByCategory(shortcuts []string, categories []string, categoriesConfig *CategoriesConfig) ([]string, []string) {
+	wanted := make(map[string]bool)
+	for _, category := range categories {
+		wanted[strings.ToLower(category)] = true
+	}
+
+	var matched, rest []string
+	for _, shortcut := range shortcuts {
+		if wanted[string(categorizeShortcut(shortcut, categoriesConfig))] {
+			matched = append(matched, shortcut)
+		} else {
+			rest = append(rest, shortcut)
+		}
+	}
+	return matched, rest
+}
@@ -0,0 +1,46 @@
+package focusmode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestConfigGetPreset tests preset lookup, defaults and validation
+func TestConfigGetPreset(t *testing.T) {
+	config := &Config{
+		Modes:       map[string]ModeConfig{"focusmode": {Destination: "Focus"}},
+		DefaultMode: "focusmode",
+		Presets: map[string]SessionPreset{
+			"deepwork": {Duration: 50, OnCompleteRestore: []string{"work"}},
+			"badmode":  {Mode: "nonexistent", Duration: 25},
+		},
+	}
+
+	preset, err := config.getPreset("deepwork")
+	if err != nil {
+		t.Fatalf("getPreset() returned error: %v", err)
+	}
+	if preset.Mode != "focusmode" || preset.Duration != 50 {
+		t.Errorf("Unexpected preset: %+v", preset)
+	}
+
+	if _, err := config.getPreset("badmode"); err == nil {
+		t.Error("Expected error for preset with unknown mode")
+	}
+	if _, err := config.getPreset("missing"); err == nil {
+		t.Error("Expected error for missing preset")
+	}
+}
+
+// TestSplitShortcutsByCategory tests that only shortcuts in the listed categories are selected
+func TestSplitShortcutsByCategory(t *testing.T) {
+	shortcuts := []string{"Steam.lnk", "Visual Studio Code.lnk", "Word.lnk", "Notes.txt"}
+	matched, rest := splitShortcutsByCategory(shortcuts, []string{"Work", "development"}, getDefaultCategoriesConfig())
+
+	if want := []string{"Visual Studio Code.lnk", "Word.lnk"}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("Expected matched %v, got %v", want, matched)
+	}
+	if want := []string{"Steam.lnk", "Notes.txt"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("Expected rest %v, got %v", want, rest)
+	}
+}
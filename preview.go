@@ -0,0 +1,254 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI colors used by the desktop preview
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// previewColumnWidth is the widest a column of the desktop preview gets; longer names are cut short
+const previewColumnWidth = 38
+
+// previewStatus is what a dry run would do to a file on the desktop
+type previewStatus int
+
+const (
+	previewKept     previewStatus = iota // stays on the desktop
+	previewMoved                         // leaves the desktop for a mode's folder
+	previewRestored                      // comes back to the desktop from a mode's folder
+	previewConflict                      // a file of the same name is already where it would go
+)
+
+// previewEntry is one file in the before/after preview
+type previewEntry struct {
+	Name   string
+	Status previewStatus
+	Folder string // Folder the file would go to or come from, or that holds its namesake
+	Before bool   // On the desktop now
+	After  bool   // On the desktop afterwards
+}
+
+// previewStep is one restore or apply of the plan being previewed, in the order it would run
+type previewStep struct {
+	Folder  string
+	Names   []string // Files to move; for restores, the files to bring back
+	Restore bool
+	MoveAll bool     // Move whatever is on the desktop by the time the step runs, ignoring Names
+	Exclude []string // Files a MoveAll step leaves on the desktop
+}
+
+// buildDesktopPreview works out which desktop files each step would move, restore or collide with
+// The file system is only read: the desktop listing is given and folders are checked for namesakes
+func buildDesktopPreview(desktop []string, steps []previewStep) []previewEntry {
+	entries := make([]*previewEntry, 0, len(desktop))
+	for _, name := range desktop {
+		entries = append(entries, &previewEntry{Name: name, Status: previewKept, Before: true, After: true})
+	}
+	// onDesktop finds the file of that name on the desktop as it would be at this point of the plan
+	onDesktop := func(name string) *previewEntry {
+		for _, entry := range entries {
+			if entry.After && strings.EqualFold(entry.Name, name) {
+				return entry
+			}
+		}
+		return nil
+	}
+
+	for _, step := range steps {
+		folder := filepath.Base(step.Folder)
+		if step.Restore {
+			for _, name := range step.Names {
+				if entry := onDesktop(name); entry != nil {
+					entry.Status, entry.Folder = previewConflict, folder
+					continue
+				}
+				entries = append(entries, &previewEntry{Name: name, Status: previewRestored, Folder: folder, After: true})
+			}
+			continue
+		}
+
+		names := step.Names
+		if step.MoveAll {
+			names = nil
+			for _, entry := range entries {
+				if entry.After && !containsFold(step.Exclude, entry.Name) {
+					names = append(names, entry.Name)
+				}
+			}
+		}
+		for _, name := range names {
+			entry := onDesktop(name)
+			if entry == nil {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(step.Folder, entry.Name)); err == nil {
+				entry.Status, entry.Folder = previewConflict, folder
+				continue
+			}
+			entry.Status, entry.Folder, entry.After = previewMoved, folder, false
+		}
+	}
+
+	// A file restored and then moved again by a later step never shows up on the desktop
+	var result []previewEntry
+	for _, entry := range entries {
+		if entry.Before || entry.After {
+			result = append(result, *entry)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// modePreviewSteps returns the restores and applies that applying a mode would run, as applyModeWithDependencies does
+func modePreviewSteps(config *Config, modeName string) ([]previewStep, error) {
+	toRestore, toApply, err := config.planModeChange(modeName, activeModeNames())
+	if err != nil {
+		return nil, err
+	}
+	root, err := hiddenFoldersRoot()
+	if err != nil {
+		return nil, err
+	}
+	var steps []previewStep
+	for _, name := range toRestore {
+		modeConfig, err := config.getModeConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		folder := modeFolder(root, modeConfig.Destination)
+		files, err := getShortcutsInFolder(folder)
+		if err != nil {
+			continue // Nothing to restore, as restoreShortcutsForMode finds
+		}
+		steps = append(steps, previewStep{Folder: folder, Names: config.restorable(files), Restore: true})
+	}
+	for _, name := range toApply {
+		modeConfig, err := config.getModeConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, previewStep{Folder: modeFolder(root, modeConfig.Destination), Names: modeConfig.Shortcuts, MoveAll: modeConfig.MoveAll, Exclude: config.exclusions(modeConfig)})
+	}
+	return steps, nil
+}
+
+// colorOutput reports whether output to w may be colored: only a terminal on standard output,
+// and not when NO_COLOR is set, TERM is dumb or accessible output is on
+func colorOutput(w io.Writer) bool {
+	if w != io.Writer(os.Stdout) || accessible || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return stdoutIsTerminal()
+}
+
+// previewCell fits text to the column width, padding it or cutting it short with an ellipsis
+func previewCell(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		return string([]rune(text)[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
+
+// printDesktopPreview prints the desktop now and after side by side
+// Colors only add to the arrows and marks, so the preview reads the same without them;
+// accessible output lists each file on a line instead, since columns don't read out well
+func printDesktopPreview(w io.Writer, entries []previewEntry, color bool) {
+	counts := make(map[previewStatus]int)
+	for _, entry := range entries {
+		counts[entry.Status]++
+	}
+
+	if accessible {
+		fmt.Fprintln(w, "\nDesktop preview:")
+		for _, entry := range entries {
+			switch entry.Status {
+			case previewKept:
+				fmt.Fprintf(w, "  %s: stays\n", entry.Name)
+			case previewMoved:
+				fmt.Fprintf(w, "  %s: moved to %s\n", entry.Name, entry.Folder)
+			case previewRestored:
+				fmt.Fprintf(w, "  %s: restored from %s\n", entry.Name, entry.Folder)
+			case previewConflict:
+				fmt.Fprintf(w, "  %s: stays, conflict: %s has a file of the same name\n", entry.Name, entry.Folder)
+			}
+		}
+	} else {
+		width := len("Desktop now")
+		for _, entry := range entries {
+			if n := utf8.RuneCountInString(entry.Name) + utf8.RuneCountInString(entry.Folder) + 3; n > width {
+				width = n
+			}
+		}
+		if width > previewColumnWidth {
+			width = previewColumnWidth
+		}
+
+		fmt.Fprintf(w, "\n%s  %s\n", previewCell("Desktop now", width), "Desktop after")
+		fmt.Fprintf(w, "%s  %s\n", strings.Repeat("─", width), strings.Repeat("─", width))
+		for _, entry := range entries {
+			var left, right, paint string
+			switch entry.Status {
+			case previewKept:
+				left, right = entry.Name, entry.Name
+			case previewMoved:
+				left, paint = entry.Name+" → "+entry.Folder, ansiRed
+			case previewRestored:
+				right, paint = entry.Name+" ← "+entry.Folder, ansiGreen
+			case previewConflict:
+				left, right, paint = entry.Name, entry.Name+" ! "+entry.Folder, ansiYellow
+			}
+			left, right = previewCell(left, width), strings.TrimRight(previewCell(right, width), " ")
+			if color && paint != "" {
+				if left != strings.Repeat(" ", width) {
+					left = paint + left + ansiReset
+				}
+				if right != "" {
+					right = paint + right + ansiReset
+				}
+			}
+			fmt.Fprintln(w, strings.TrimRight(left+"  "+right, " "))
+		}
+	}
+
+	fmt.Fprintf(w, "\nMoved: %d  Kept: %d  Restored: %d  Conflicts: %d\n",
+		counts[previewMoved], counts[previewKept], counts[previewRestored], counts[previewConflict])
+	if counts[previewConflict] > 0 {
+		fmt.Fprintln(w, "A conflict is a file whose namesake is already in the folder it would go to; it may fail to move or replace the other.")
+	}
+}
+
+// printModePreview prints the desktop before and after applying a mode, for -dry-run
+func printModePreview(config *Config, modeName string) {
+	steps, err := modePreviewSteps(config, modeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no desktop preview: %v\n", err)
+		return
+	}
+	printStepsPreview(steps)
+}
+
+// printStepsPreview lists the desktop and prints what the steps would leave on it
+func printStepsPreview(steps []previewStep) {
+	desktop, err := getAllDesktopShortcuts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no desktop preview: %v\n", err)
+		return
+	}
+	printDesktopPreview(os.Stdout, buildDesktopPreview(desktop, steps), colorOutput(os.Stdout))
+}
@@ -0,0 +1,97 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBuildDesktopPreview tests moves, kept files, restores and conflicts across the steps of a plan
+func TestBuildDesktopPreview(t *testing.T) {
+	games := t.TempDir()
+	work := t.TempDir()
+	// Discord.lnk was hidden before and copied back by hand, so the folder still has one
+	if err := os.WriteFile(filepath.Join(games, "Discord.lnk"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	desktop := []string{"steam.lnk", "Discord.lnk", "Notes.txt", "Slack.lnk"}
+	steps := []previewStep{
+		{Folder: work, Names: []string{"Slack.lnk", "Word.lnk"}, Restore: true},
+		{Folder: games, Names: []string{"Steam.lnk", "Discord.lnk", "Gone.lnk"}},
+	}
+	got := buildDesktopPreview(desktop, steps)
+	want := []previewEntry{
+		{Name: "Discord.lnk", Status: previewConflict, Folder: filepath.Base(games), Before: true, After: true},
+		{Name: "Notes.txt", Status: previewKept, Before: true, After: true},
+		{Name: "Slack.lnk", Status: previewConflict, Folder: filepath.Base(work), Before: true, After: true},
+		{Name: "steam.lnk", Status: previewMoved, Folder: filepath.Base(games), Before: true},
+		{Name: "Word.lnk", Status: previewRestored, Folder: filepath.Base(work), After: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected:\n%+v\ngot:\n%+v", want, got)
+	}
+}
+
+// TestBuildDesktopPreviewMoveAll tests that move_all takes what is on the desktop when its step runs,
+// including files restored by an earlier step
+func TestBuildDesktopPreviewMoveAll(t *testing.T) {
+	steps := []previewStep{
+		{Folder: t.TempDir(), Names: []string{"Word.lnk"}, Restore: true},
+		{Folder: t.TempDir(), MoveAll: true},
+	}
+	got := buildDesktopPreview([]string{"Notes.txt"}, steps)
+	if len(got) != 1 || got[0].Name != "Notes.txt" || got[0].Status != previewMoved {
+		t.Errorf("Expected only Notes.txt, moved, got %+v", got)
+	}
+
+	// Excluded files stay where they are
+	steps = []previewStep{{Folder: t.TempDir(), MoveAll: true, Exclude: []string{"recycle bin.lnk"}}}
+	got = buildDesktopPreview([]string{"Notes.txt", "Recycle Bin.lnk"}, steps)
+	if got[0].Status != previewMoved || got[1].Status != previewKept {
+		t.Errorf("Expected Recycle Bin.lnk kept, got %+v", got)
+	}
+}
+
+// TestPrintDesktopPreview tests the columns with and without color, and the accessible list
+func TestPrintDesktopPreview(t *testing.T) {
+	entries := []previewEntry{
+		{Name: "Notes.txt", Status: previewKept, Before: true, After: true},
+		{Name: "Steam.lnk", Status: previewMoved, Folder: "Games", Before: true},
+		{Name: "Word.lnk", Status: previewRestored, Folder: "Work", After: true},
+	}
+
+	var plain strings.Builder
+	printDesktopPreview(&plain, entries, false)
+	want := "\n" +
+		"Desktop now        Desktop after\n" +
+		"─────────────────  ─────────────────\n" +
+		"Notes.txt          Notes.txt\n" +
+		"Steam.lnk → Games\n" +
+		"                   Word.lnk ← Work\n" +
+		"\nMoved: 1  Kept: 1  Restored: 1  Conflicts: 0\n"
+	if plain.String() != want {
+		t.Errorf("Expected:\n%q\ngot:\n%q", want, plain.String())
+	}
+
+	var colored strings.Builder
+	printDesktopPreview(&colored, entries, true)
+	for _, part := range []string{ansiRed + "Steam.lnk → Games" + ansiReset, ansiGreen + "Word.lnk ← Work" + ansiReset} {
+		if !strings.Contains(colored.String(), part) {
+			t.Errorf("Expected %q in:\n%q", part, colored.String())
+		}
+	}
+	if strings.Contains(colored.String(), ansiReset+"Notes.txt") || strings.Count(colored.String(), "\x1b[") != 4 {
+		t.Errorf("Expected only moved and restored files colored, got:\n%q", colored.String())
+	}
+
+	accessible = true
+	defer func() { accessible = false }()
+	var list strings.Builder
+	printDesktopPreview(&list, entries, true)
+	if !strings.Contains(list.String(), "Steam.lnk: moved to Games\n") || strings.Contains(list.String(), "\x1b[") {
+		t.Errorf("Expected a plain list in accessible mode, got:\n%s", list.String())
+	}
+}
@@ -0,0 +1,86 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// promptRemaining formats the time left compactly for a prompt, e.g. "12m", "1h05m" or "40s"
+// Minutes are rounded up so the segment never shows 0m while time is left
+func promptRemaining(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int((d+time.Second-1)/time.Second))
+	}
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+// promptSegment returns the prompt segment for the running session, or the applied modes
+// when no session is running; it is empty when there is nothing to show
+func promptSegment(session *activeSession, modes []appliedMode, now time.Time) string {
+	if session != nil {
+		name := session.Mode
+		if session.Break {
+			name = "break"
+		}
+		segment := name + " " + promptRemaining(session.remaining(now))
+		if session.PausedAt != nil {
+			segment += " paused"
+		}
+		return segment
+	}
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = mode.Mode
+	}
+	return strings.Join(names, "+")
+}
+
+// starshipModule returns a starship custom module that shows the segment
+// The module is only shown when "focusmode prompt" exits 0
+func starshipModule(command string) string {
+	return fmt.Sprintf(`[custom.focusmode]
+description = "FocusMode session and applied modes"
+command = '%[1]s prompt'
+when = '%[1]s prompt'
+format = "[$output]($style) "
+style = "bold yellow"
+`, command)
+}
+
+// runPromptCommand handles the "prompt" subcommand, which prints a segment for shell prompts
+// It only reads the state files, never the config, so it stays fast enough to run on every prompt
+// and exits 1 when there is nothing to show
+func runPromptCommand(args []string) {
+	flags := flag.NewFlagSet("prompt", flag.ExitOnError)
+	starship := flags.Bool("starship", false, "Print a starship custom module that shows the segment")
+	command := flags.String("command", "focusmode", "Command the starship module runs, if focusmode isn't on PATH")
+	flags.Parse(args)
+
+	if *starship {
+		fmt.Print(starshipModule(*command))
+		return
+	}
+
+	var session *activeSession
+	var modes []appliedMode
+	if path, err := activeSessionPath(); err == nil {
+		session, _ = readActiveSession(path)
+	}
+	if path, err := appliedModesPath(); err == nil {
+		modes, _ = readAppliedModes(path)
+	}
+
+	// Errors are treated as nothing to show; a prompt is no place for error messages
+	segment := promptSegment(session, modes, time.Now())
+	if segment == "" {
+		os.Exit(1)
+	}
+	fmt.Println(segment)
+}
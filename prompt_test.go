@@ -0,0 +1,63 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPromptRemaining tests the compact remaining time format
+func TestPromptRemaining(t *testing.T) {
+	tests := map[time.Duration]string{
+		40 * time.Second:                "40s",
+		12*time.Minute + 10*time.Second: "13m",
+		25 * time.Minute:                "25m",
+		65 * time.Minute:                "1h05m",
+		2*time.Hour - 30*time.Second:    "2h00m",
+		500 * time.Millisecond:          "1s",
+	}
+	for d, want := range tests {
+		if got := promptRemaining(d); got != want {
+			t.Errorf("promptRemaining(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// TestPromptSegment tests the segment for sessions, breaks, pauses and applied modes
+func TestPromptSegment(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	session := &activeSession{Mode: "focusmode", StartTime: now.Add(-10 * time.Minute), DurationSeconds: 1500}
+	if got := promptSegment(session, nil, now); got != "focusmode 15m" {
+		t.Errorf("Unexpected running segment: %q", got)
+	}
+
+	pausedAt := now.Add(-5 * time.Minute)
+	session.PausedAt = &pausedAt
+	session.PausedSeconds = 60
+	if got := promptSegment(session, nil, now); got != "focusmode 21m paused" {
+		t.Errorf("Unexpected paused segment: %q", got)
+	}
+
+	breakSession := &activeSession{Mode: "focusmode", Break: true, StartTime: now, DurationSeconds: 300}
+	if got := promptSegment(breakSession, nil, now); got != "break 5m" {
+		t.Errorf("Unexpected break segment: %q", got)
+	}
+
+	modes := []appliedMode{{Mode: "work"}, {Mode: "focusmode"}}
+	if got := promptSegment(nil, modes, now); got != "work+focusmode" {
+		t.Errorf("Unexpected modes segment: %q", got)
+	}
+	if got := promptSegment(nil, nil, now); got != "" {
+		t.Errorf("Expected an empty segment, got %q", got)
+	}
+}
+
+// TestStarshipModule tests that the generated module is driven by the prompt's exit code
+func TestStarshipModule(t *testing.T) {
+	module := starshipModule("/usr/local/bin/focusmode")
+	for _, want := range []string{"[custom.focusmode]", "command = '/usr/local/bin/focusmode prompt'", "when = '/usr/local/bin/focusmode prompt'"} {
+		if !strings.Contains(module, want) {
+			t.Errorf("Expected %q in module:\n%s", want, module)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// publicDesktopPath returns the Windows Public Desktop, whose shortcuts every user sees on their
+// desktop, or "" where there is none; replaced in tests
+var publicDesktopPath = func() string {
+	// A location is another machine's desktop, which the public desktop isn't part of
+	if runtime.GOOS != "windows" || locationActive() {
+		return ""
+	}
+	if public := os.Getenv("PUBLIC"); public != "" {
+		return filepath.Join(public, "Desktop")
+	}
+	return `C:\Users\Public\Desktop`
+}
+
+// publicOrigins records the hidden files that came from the public desktop, by the folder they
+// were moved to, so restores put them back there rather than on the user's own desktop
+type publicOrigins map[string]manifestNames
+
+// publicOriginsPath returns the path of the public desktop origins record
+func publicOriginsPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "public-desktop.json"), nil
+}
+
+// readPublicOrigins returns the recorded origins, empty if there are none
+func readPublicOrigins() publicOrigins {
+	origins := make(publicOrigins)
+	path, err := publicOriginsPath()
+	if err != nil {
+		return origins
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return origins
+	}
+	if err := json.Unmarshal(data, &origins); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error parsing %s: %v\n", path, err)
+	}
+	return origins
+}
+
+// has reports whether the file name in folder came from the public desktop
+func (o publicOrigins) has(folder, name string) bool {
+	for _, recorded := range o[filepath.Clean(folder)] {
+		if nfc(recorded) == nfc(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPublicOrigin records whether the file just moved to folder came from the public desktop
+// A file moved from the user's desktop clears any record left by an earlier file of the same name
+func recordPublicOrigin(folder, name string, public bool) {
+	origins := readPublicOrigins()
+	if origins.has(folder, name) == public {
+		return
+	}
+	folder = filepath.Clean(folder)
+	var names manifestNames
+	for _, recorded := range origins[folder] {
+		if nfc(recorded) != nfc(name) {
+			names = append(names, recorded)
+		}
+	}
+	if public {
+		names = append(names, name)
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		delete(origins, folder)
+	} else {
+		origins[folder] = names
+	}
+
+	path, err := publicOriginsPath()
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(origins, "", "  "); err == nil {
+			err = writeFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error recording where %s came from: %v\n", name, err)
+		return
+	}
+	ownByUser(path)
+}
+
+// desktopFor returns the desktop a hidden file in folder belongs on: the public desktop
+// when it was moved from there, otherwise the user's own
+func desktopFor(folder, name string) (string, error) {
+	if public := publicDesktopPath(); public != "" && readPublicOrigins().has(folder, name) {
+		return public, nil
+	}
+	return getDesktopPath()
+}
+
+// publicDesktopShortcuts returns the files on the public desktop that aren't also on the user's own,
+// which keeps the same name from being moved twice; none when the mode doesn't opt in
+func publicDesktopShortcuts(modeConfig *ModeConfig, own []string) []string {
+	public := publicDesktopPath()
+	if !modeConfig.PublicDesktop || public == "" {
+		return nil
+	}
+	files, err := getAllDesktopShortcutsFromPath(public)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error reading the Public Desktop: %v\n", err)
+		return nil
+	}
+	var extra []string
+	for _, name := range files {
+		if !containsFold(own, name) {
+			extra = append(extra, name)
+		}
+	}
+	return extra
+}
+
+// moveModeShortcut moves a shortcut of a mode to destination, from the user's desktop or,
+// for a mode that opts in, from the public desktop when it isn't on the user's
+func (c *Config) moveModeShortcut(modeConfig *ModeConfig, shortcutName, destination string) error {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return fmt.Errorf("error getting desktop path: %w", err)
+	}
+	public := publicDesktopPath()
+	if modeConfig.PublicDesktop && public != "" {
+		if _, err := os.Stat(filepath.Join(desktopPath, shortcutName)); os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(public, shortcutName)); err == nil {
+				if err := publicDesktopError(shortcutName, c.moveDesktopShortcutFromPath(shortcutName, destination, public)); err != nil {
+					return err
+				}
+				recordPublicOrigin(destination, shortcutName, true)
+				return nil
+			}
+		}
+	}
+	if err := c.moveDesktopShortcutFromPath(shortcutName, destination, desktopPath); err != nil {
+		return err
+	}
+	if public != "" {
+		recordPublicOrigin(destination, shortcutName, false)
+	}
+	return nil
+}
+
+// publicDesktopError explains a permission error on the public desktop, which belongs to all
+// users and can only be changed from an elevated FocusMode
+func publicDesktopError(name string, err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("'%s' is on the Public Desktop, which only an elevated FocusMode (Run as administrator) can change: %w", name, err)
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPublicDesktop makes a user desktop and a public desktop holding the given files
+func testPublicDesktop(t *testing.T, own, public []string) (dir, desktop, publicDesktop string) {
+	dir = t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	t.Setenv("AppData", dir)
+	desktop = filepath.Join(dir, "Desktop")
+	publicDesktop = filepath.Join(dir, "Public", "Desktop")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(publicDesktop, 0755)
+	for _, name := range own {
+		os.WriteFile(filepath.Join(desktop, name), []byte("own"), 0644)
+	}
+	for _, name := range public {
+		os.WriteFile(filepath.Join(publicDesktop, name), []byte("public"), 0644)
+	}
+	old := publicDesktopPath
+	publicDesktopPath = func() string { return publicDesktop }
+	t.Cleanup(func() { publicDesktopPath = old })
+	return dir, desktop, publicDesktop
+}
+
+// TestPublicDesktopApplyRestore tests that a mode opting in hides public shortcuts and
+// restores them to the public desktop, while other modes leave them alone
+func TestPublicDesktopApplyRestore(t *testing.T) {
+	dir, desktop, public := testPublicDesktop(t, []string{"Notes.lnk", "Zoom.lnk"}, []string{"Chrome.lnk", "Zoom.lnk"})
+	config := &Config{Modes: map[string]ModeConfig{
+		"work":  {Destination: "Work", Shortcuts: []string{"Chrome.lnk", "Notes.lnk"}},
+		"clean": {Destination: "Clean", MoveAll: true, PublicDesktop: true},
+	}}
+
+	// Without the opt-in the public shortcut isn't found
+	if _, err := applyMode(config, "work", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(public, "Chrome.lnk")); err != nil {
+		t.Error("Expected a mode without public_desktop to leave the public desktop alone")
+	}
+	if err := restoreShortcutsForMode(config, "work", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := applyMode(config, "clean", false, false); err != nil {
+		t.Fatal(err)
+	}
+	clean := filepath.Join(dir, "Clean")
+	for _, name := range []string{"Notes.lnk", "Zoom.lnk", "Chrome.lnk"} {
+		if _, err := os.Stat(filepath.Join(clean, name)); err != nil {
+			t.Errorf("Expected %s hidden: %v", name, err)
+		}
+	}
+	// The user's own Zoom was moved; the public one of the same name stays
+	if data, _ := os.ReadFile(filepath.Join(clean, "Zoom.lnk")); string(data) != "own" {
+		t.Errorf("Expected the user's own Zoom.lnk hidden, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(public, "Zoom.lnk")); err != nil {
+		t.Error("Expected the public Zoom.lnk to stay")
+	}
+
+	moves := desktopMoves("clean", clean, []string{"Chrome.lnk", "Notes.lnk"}, false)
+	if len(moves) != 2 || moves[0].From != public || moves[1].From != desktop {
+		t.Errorf("Expected the history to record where each came from, got %+v", moves)
+	}
+
+	if err := restoreShortcutsForMode(config, "clean", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(public, "Chrome.lnk")); err != nil {
+		t.Error("Expected Chrome.lnk back on the public desktop")
+	}
+	for _, name := range []string{"Notes.lnk", "Zoom.lnk"} {
+		if _, err := os.Stat(filepath.Join(desktop, name)); err != nil {
+			t.Errorf("Expected %s back on the user's desktop", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Chrome.lnk")); err == nil {
+		t.Error("Expected Chrome.lnk not to end up on the user's desktop")
+	}
+}
+
+// TestPublicOriginsCleared tests that a file moved from the user's desktop replaces the record
+// of a public file of the same name hidden in the same folder before
+func TestPublicOriginsCleared(t *testing.T) {
+	dir, _, _ := testPublicDesktop(t, nil, nil)
+	folder := filepath.Join(dir, "Clean")
+	recordPublicOrigin(folder, "Chrome.lnk", true)
+	recordPublicOrigin(folder, "Edge.lnk", true)
+	if !readPublicOrigins().has(folder+string(filepath.Separator), "Chrome.lnk") {
+		t.Fatal("Expected Chrome.lnk recorded as public")
+	}
+	recordPublicOrigin(folder, "Chrome.lnk", false)
+	origins := readPublicOrigins()
+	if origins.has(folder, "Chrome.lnk") || !origins.has(folder, "Edge.lnk") {
+		t.Errorf("Expected only Edge.lnk left, got %v", origins)
+	}
+}
+
+// TestPublicDesktopError tests the explanation given when elevation is needed
+func TestPublicDesktopError(t *testing.T) {
+	err := publicDesktopError("Chrome.lnk", fmt.Errorf("error moving shortcut: %w", &os.PathError{Op: "rename", Err: os.ErrPermission}))
+	if !strings.Contains(err.Error(), "Run as administrator") || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Unexpected error %v", err)
+	}
+	other := errors.New("disk full")
+	if publicDesktopError("Chrome.lnk", other) != other {
+		t.Error("Expected other errors unchanged")
+	}
+}
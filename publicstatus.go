@@ -0,0 +1,123 @@
+package focusmode
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	publicStatusInterval = 10 * time.Second // one request per interval per client once the burst is used
+	publicStatusBurst    = 5
+	maxRateLimitClients  = 1024
+)
+
+// rateLimiter is a per-client token bucket
+type rateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu      sync.Mutex
+	clients map[string]*rateBucket
+}
+
+// rateBucket tracks the available requests of one client
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows burst requests at once, refilling one every interval
+func newRateLimiter(interval time.Duration, burst int) *rateLimiter {
+	return &rateLimiter{interval: interval, burst: burst, clients: make(map[string]*rateBucket)}
+}
+
+// allow reports whether a request from key may proceed, and otherwise how long to wait
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, exists := l.clients[key]
+	if !exists {
+		// Forget full buckets so the map can't grow without bound
+		if len(l.clients) >= maxRateLimitClients {
+			for k, b := range l.clients {
+				if now.Sub(b.last) >= l.interval*time.Duration(l.burst) {
+					delete(l.clients, k)
+				}
+			}
+		}
+		if len(l.clients) >= maxRateLimitClients {
+			return false, l.interval
+		}
+		bucket = &rateBucket{tokens: float64(l.burst), last: now}
+		l.clients[key] = bucket
+	}
+
+	bucket.tokens += float64(now.Sub(bucket.last)) / float64(l.interval)
+	if bucket.tokens > float64(l.burst) {
+		bucket.tokens = float64(l.burst)
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(l.interval))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// publicStatusText describes a running session without revealing anything else
+func publicStatusText(session *activeSession) string {
+	if session == nil {
+		return "not focusing"
+	}
+	end := session.StartTime.Add(time.Duration(session.DurationSeconds) * time.Second).Local().Format("15:04")
+	if session.Break {
+		return "on a break until " + end
+	}
+	return "focusing until " + end
+}
+
+// publicStatusHandler serves the unauthenticated, rate-limited status line
+func publicStatusHandler(limiter *rateLimiter, status func() (*activeSession, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, wait := limiter.allow(client, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		session, err := status()
+		if err != nil {
+			http.Error(w, "status unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		fmt.Fprintln(w, publicStatusText(session))
+	})
+}
+
+// readCurrentSession returns the running session, if any
+func readCurrentSession() (*activeSession, error) {
+	path, err := activeSessionPath()
+	if err != nil {
+		return nil, err
+	}
+	return readActiveSession(path)
+}
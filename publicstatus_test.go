@@ -0,0 +1,88 @@
+package focusmode
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRateLimiter tests the burst, refill and per-client separation
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(10*time.Second, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("a", now); !ok {
+			t.Fatalf("Expected request %d within burst to be allowed", i+1)
+		}
+	}
+	ok, wait := limiter.allow("a", now)
+	if ok || wait <= 0 || wait > 10*time.Second {
+		t.Errorf("Expected request over burst to be limited with a wait, got %v %v", ok, wait)
+	}
+	if ok, _ := limiter.allow("b", now); !ok {
+		t.Error("Expected another client to be allowed")
+	}
+	if ok, _ := limiter.allow("a", now.Add(10*time.Second)); !ok {
+		t.Error("Expected request to be allowed after refill")
+	}
+}
+
+// TestPublicStatusText tests that only the session end time is revealed
+func TestPublicStatusText(t *testing.T) {
+	start := time.Date(2024, 5, 6, 14, 0, 0, 0, time.Local)
+	if got := publicStatusText(nil); got != "not focusing" {
+		t.Errorf("Unexpected text without session: %q", got)
+	}
+	if got := publicStatusText(&activeSession{Mode: "secretmode", StartTime: start, DurationSeconds: 3000}); got != "focusing until 14:50" {
+		t.Errorf("Unexpected focus text: %q", got)
+	}
+	if got := publicStatusText(&activeSession{StartTime: start, DurationSeconds: 600, Break: true}); got != "on a break until 14:10" {
+		t.Errorf("Unexpected break text: %q", got)
+	}
+}
+
+// TestPublicStatusHandler tests the unauthenticated endpoint and its rate limit
+func TestPublicStatusHandler(t *testing.T) {
+	session := &activeSession{StartTime: time.Now(), DurationSeconds: 1500}
+	handler := publicStatusHandler(newRateLimiter(time.Hour, 1), func() (*activeSession, error) { return session, nil })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public-status", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "focusing until ") {
+		t.Errorf("Unexpected response %d: %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public-status", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d", rec.Code)
+	}
+
+	failing := publicStatusHandler(newRateLimiter(time.Hour, 1), func() (*activeSession, error) { return nil, errors.New("disk error") })
+	rec = httptest.NewRecorder()
+	failing.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public-status", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "disk error") {
+		t.Errorf("Expected a generic error, got %d: %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestDaemonPublicStatusOptIn tests that /public-status is only served when enabled
+func TestDaemonPublicStatusOptIn(t *testing.T) {
+	config := testDaemonConfig()
+	rec := httptest.NewRecorder()
+	newDaemonHandler(config, (&fakeRunner{}).run, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public-status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected /public-status to require auth when disabled, got %d", rec.Code)
+	}
+
+	config.Daemon.PublicStatus = true
+	rec = httptest.NewRecorder()
+	newDaemonHandler(config, (&fakeRunner{}).run, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public-status", nil))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected /public-status to be served without auth, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package focusmode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// readOnly is set by the global --read-only flag or FOCUSMODE_READ_ONLY=1
+// Every change to files or OS settings then fails instead of being made, so FocusMode
+// can be run on someone else's machine to look around without touching anything
+var readOnly bool
+
+// readOnlyEnv enables read-only mode for scripts without passing the flag
+const readOnlyEnv = "FOCUSMODE_READ_ONLY"
+
+// errReadOnly is wrapped by every error returned for a change refused in read-only mode
+var errReadOnly = errors.New("read-only mode is on (--read-only or FOCUSMODE_READ_ONLY)")
+
+// readOnlyFromEnv reports whether FOCUSMODE_READ_ONLY asks for read-only mode
+func readOnlyFromEnv() bool {
+	return envSwitch(readOnlyEnv)
+}
+
+// guardWrite returns an error naming the refused action in read-only mode, and nil otherwise
+func guardWrite(action string) error {
+	if readOnly {
+		return fmt.Errorf("refusing to %s: %w", action, errReadOnly)
+	}
+	return nil
+}
+
+// writeFile is os.WriteFile, refused in read-only mode
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := guardWrite("write " + path); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// createFile is os.Create, refused in read-only mode
+func createFile(path string) (*os.File, error) {
+	if err := guardWrite("create " + path); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// appendFile opens a file for appending, creating it if needed; refused in read-only mode
+func appendFile(path string, perm os.FileMode) (*os.File, error) {
+	if err := guardWrite("write " + path); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, perm)
+}
+
+// renameFile is os.Rename, refused in read-only mode
+func renameFile(from, to string) error {
+	if err := guardWrite(fmt.Sprintf("move %s to %s", from, to)); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// mkdirAll is os.MkdirAll, refused in read-only mode
+func mkdirAll(path string, perm os.FileMode) error {
+	if err := guardWrite("create " + path); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// symlinkFile is os.Symlink, refused in read-only mode
+func symlinkFile(target, link string) error {
+	if err := guardWrite("create " + link); err != nil {
+		return err
+	}
+	return os.Symlink(target, link)
+}
+
+// removeFile is os.Remove, refused in read-only mode
+func removeFile(path string) error {
+	if err := guardWrite("remove " + path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,62 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadOnlyRefusesChanges tests that every file change fails and leaves the file alone
+func TestReadOnlyRefusesChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := writeFile(path, []byte("before"), 0644); err != nil {
+		t.Fatalf("writeFile() returned error: %v", err)
+	}
+
+	readOnly = true
+	t.Cleanup(func() { readOnly = false })
+
+	checks := map[string]error{
+		"writeFile":  writeFile(path, []byte("after"), 0644),
+		"renameFile": renameFile(path, filepath.Join(dir, "moved.json")),
+		"removeFile": removeFile(path),
+		"mkdirAll":   mkdirAll(filepath.Join(dir, "sub"), 0755),
+	}
+	_, checks["createFile"] = createFile(filepath.Join(dir, "new.json"))
+	_, checks["appendFile"] = appendFile(path, 0644)
+	_, checks["setColorTemperature"] = setColorTemperature(4000)
+	for name, err := range checks {
+		if !errors.Is(err, errReadOnly) {
+			t.Errorf("%s: expected a read-only error, got %v", name, err)
+		}
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "before" {
+		t.Errorf("Expected the file to be untouched, got %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected nothing created, found %d entries", len(entries))
+	}
+}
+
+// TestReadOnlyDataDir tests that the data directory isn't created in read-only mode
+func TestReadOnlyDataDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv(readOnlyEnv, "1")
+	readOnly = readOnlyFromEnv()
+	t.Cleanup(func() { readOnly = false })
+
+	path, err := dataDir()
+	if err != nil {
+		t.Fatalf("dataDir() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created, got %v", path, err)
+	}
+}
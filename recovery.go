@@ -0,0 +1,277 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Actions for a session left behind by a crash
+const (
+	recoveryAsk     = "ask"     // prompt on the next start (default)
+	recoveryRestore = "restore" // put the hidden shortcuts back on the desktop
+	recoveryResume  = "resume"  // continue the session for its remaining time
+	recoveryDiscard = "discard" // forget the session and leave the shortcuts where they are
+)
+
+// validRecoveryAction reports whether action is a known recovery setting
+func validRecoveryAction(action string) bool {
+	switch action {
+	case "", recoveryAsk, recoveryRestore, recoveryResume, recoveryDiscard:
+		return true
+	}
+	return false
+}
+
+// readStaleSession returns the session recorded at path if the process that recorded it is gone
+// It returns nil if no session is recorded or the session is still running
+func readStaleSession(path string) (*activeSession, error) {
+	state, err := readSessionFile(path)
+	if err != nil || state == nil || processAlive(state.PID) {
+		return nil, err
+	}
+	return state, nil
+}
+
+// staleRemaining returns how much of a crashed session would be left if resumed now
+func staleRemaining(state *activeSession, now time.Time) time.Duration {
+	end := state.StartTime.Add(time.Duration(state.DurationSeconds) * time.Second)
+	if remaining := end.Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// chooseRecovery decides what to do with a crashed session
+// A configured action other than "ask" is used without prompting; otherwise the user is asked
+// An empty result leaves the session for next time, e.g. when there is no terminal to ask on
+// In strict mode a session that still has time left can only be resumed
+func chooseRecovery(config *Config, state *activeSession, action string, answers <-chan string, out io.Writer) string {
+	remaining := staleRemaining(state, time.Now())
+	if remaining > 0 && config.isStrict() {
+		return recoveryResume
+	}
+	if action == recoveryResume && remaining == 0 {
+		return recoveryRestore
+	}
+	if action != "" && action != recoveryAsk {
+		return action
+	}
+
+	fmt.Fprintf(out, "%sA %s session started at %s did not end cleanly; %d shortcut(s) are still hidden.\n", glyph("⚠️  "),
+		state.Mode, state.StartTime.Format("Mon 15:04"), len(state.MovedShortcuts))
+	prompt := "[r]estore them, [d]iscard the session or [l]eave it for later"
+	if remaining > 0 {
+		prompt = fmt.Sprintf("[r]estore them, re[s]ume the session (%s left), [d]iscard it or [l]eave it for later", formatDuration(remaining))
+	}
+	fmt.Fprintf(out, "%s: ", prompt)
+
+	answer, ok := <-answers
+	if !ok {
+		fmt.Fprintln(out)
+		return ""
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "r", "restore":
+		return recoveryRestore
+	case "s", "resume":
+		if remaining > 0 {
+			return recoveryResume
+		}
+	case "d", "discard":
+		return recoveryDiscard
+	}
+	return ""
+}
+
+// recoverSession carries out a recovery action for the crashed session recorded at path
+func recoverSession(config *Config, path string, state *activeSession, action string) error {
+	switch action {
+	case recoveryRestore:
+		fs := &FocusSession{Mode: state.Mode, Config: config, State: StateInterrupted, MovedShortcuts: state.MovedShortcuts}
+		fs.restoreMovedShortcuts()
+		clearFirewallRules()
+		clearDND()
+	case recoveryResume:
+		remaining := staleRemaining(state, time.Now())
+		if remaining == 0 {
+			return recoverSession(config, path, state, recoveryRestore)
+		}
+		if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing session state: %w", err)
+		}
+		fmt.Printf("%sResuming %s session: %s left\n", glyph("▶ "), state.Mode, formatDuration(remaining))
+		fs := &FocusSession{
+			Duration:       remaining,
+			Mode:           state.Mode,
+			StartTime:      time.Now(),
+			AutoRestore:    state.AutoRestore,
+			Config:         config,
+			State:          StateRunning,
+			MovedShortcuts: state.MovedShortcuts,
+		}
+		if err := attachSessionHooks(fs, config, sessionOptions{}); err != nil {
+			return err
+		}
+		return fs.run(stdinLines())
+	case recoveryDiscard:
+		clearFirewallRules()
+		clearDND()
+		fmt.Printf("Discarded the %s session; use -restore -mode %s to bring its shortcuts back\n", state.Mode, state.Mode)
+	default:
+		return nil
+	}
+	if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing session state: %w", err)
+	}
+	return nil
+}
+
+// checkSessionRecovery handles a session left behind by a crash before a command runs
+// A stale marker without hidden shortcuts, e.g. from a break, is removed silently
+// It returns true if the session was resumed, which takes the place of the command
+func checkSessionRecovery(configPath string) bool {
+	path, err := activeSessionPath()
+	if err != nil {
+		return false
+	}
+	state, err := readStaleSession(path)
+	if err != nil || state == nil {
+		return false
+	}
+	if len(state.MovedShortcuts) == 0 {
+		removeFile(path)
+		return false
+	}
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: a crashed %s session still hides shortcuts, but the config could not be loaded: %v\n", state.Mode, err)
+		return false
+	}
+	action := chooseRecovery(config, state, config.Automation.Recovery, stdinLines(), os.Stdout)
+	if err := recoverSession(config, path, state, action); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return action == recoveryResume
+}
+
+// needsRecoveryCheck reports whether a command line should look for a crashed session first
+// Commands that only read or manage settings, and the daemon, are left alone
+func needsRecoveryCheck(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "config", "template", "status", "daemon", "-daemon", "--daemon", "-resume", "--resume", "token", "guardian", "panic", "prompt", "shell-hook", "shell-guard", "serve", "gc", "zones", "bug-report":
+		return false
+	case "session":
+		return len(args) < 2 || args[1] != "recover"
+	}
+	return true
+}
+
+// configPathFromArgs returns the value of a -config flag anywhere in args, or the default
+func configPathFromArgs(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return "profile.yml"
+}
+
+// runSessionRecover handles "session recover", which deals with a crashed session explicitly
+func runSessionRecover(args []string) {
+	flags := flag.NewFlagSet("session recover", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	action := flags.String("action", recoveryAsk, "What to do: ask, restore, resume or discard")
+	flags.Parse(args)
+
+	if !validRecoveryAction(*action) {
+		fmt.Fprintf(os.Stderr, "Error: unknown recovery action %q (use ask, restore, resume or discard)\n", *action)
+		os.Exit(1)
+	}
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	path, err := activeSessionPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	state, err := readStaleSession(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if state == nil {
+		fmt.Println("No crashed session to recover")
+		return
+	}
+	if len(state.MovedShortcuts) == 0 {
+		removeFile(path)
+		fmt.Println("Removed the state of a crashed session that hid no shortcuts")
+		return
+	}
+
+	if err := recoverSession(config, path, state, chooseRecovery(config, state, *action, stdinLines(), os.Stdout)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// resumeSession handles -resume, which reattaches to a session interrupted by a crash
+// A session with time left continues; one whose time ran out while FocusMode was gone
+// offers to restore its shortcuts instead
+func resumeSession(configPath string) {
+	path, err := activeSessionPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if running, err := readActiveSession(path); err == nil && running != nil {
+		fmt.Printf("The %s session is still running (pid %d); nothing to resume\n", running.Mode, running.PID)
+		return
+	}
+	state, err := readStaleSession(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if state == nil || len(state.MovedShortcuts) == 0 {
+		if state != nil {
+			removeFile(path)
+		}
+		fmt.Println("No interrupted session to resume")
+		return
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	action := recoveryResume
+	if staleRemaining(state, time.Now()) == 0 {
+		fmt.Printf("The %s session's time ran out while FocusMode wasn't running.\n", state.Mode)
+		action = chooseRecovery(config, state, recoveryAsk, stdinLines(), os.Stdout)
+	}
+	if err := recoverSession(config, path, state, action); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
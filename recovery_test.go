@@ -0,0 +1,163 @@
+package focusmode
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crashedSession returns the state of a session whose process is gone
+func crashedSession(start time.Time, minutes int) *activeSession {
+	return &activeSession{
+		PID:             0,
+		Mode:            "gamemode",
+		StartTime:       start,
+		DurationSeconds: int64(minutes * 60),
+		MovedShortcuts:  []string{"Steam.lnk", "Discord.lnk"},
+	}
+}
+
+// TestReadStaleSession tests that only sessions of dead processes are stale
+func TestReadStaleSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	if state, err := readStaleSession(path); err != nil || state != nil {
+		t.Errorf("Expected nothing without a state file, got %+v, %v", state, err)
+	}
+
+	writeActiveSession(path, *crashedSession(time.Now(), 25))
+	state, err := readStaleSession(path)
+	if err != nil || state == nil {
+		t.Fatalf("Expected stale session, got %+v, %v", state, err)
+	}
+	if len(state.MovedShortcuts) != 2 {
+		t.Errorf("Expected manifest to be kept, got %v", state.MovedShortcuts)
+	}
+
+	live := *crashedSession(time.Now(), 25)
+	live.PID = os.Getpid()
+	writeActiveSession(path, live)
+	if state, err := readStaleSession(path); err != nil || state != nil {
+		t.Errorf("Expected running session not to be stale, got %+v, %v", state, err)
+	}
+}
+
+// TestChooseRecovery tests configured actions, prompting and the strict mode rule
+func TestChooseRecovery(t *testing.T) {
+	config := &Config{}
+	running := crashedSession(time.Now().Add(-10*time.Minute), 25)
+	over := crashedSession(time.Now().Add(-time.Hour), 25)
+
+	answer := func(lines ...string) <-chan string {
+		ch := make(chan string, len(lines))
+		for _, line := range lines {
+			ch <- line
+		}
+		close(ch)
+		return ch
+	}
+
+	tests := []struct {
+		name    string
+		state   *activeSession
+		action  string
+		answers <-chan string
+		want    string
+	}{
+		{"configured restore", running, recoveryRestore, nil, recoveryRestore},
+		{"configured discard", over, recoveryDiscard, nil, recoveryDiscard},
+		{"resume without time left", over, recoveryResume, nil, recoveryRestore},
+		{"answer restore", running, recoveryAsk, answer("r"), recoveryRestore},
+		{"answer resume", running, "", answer("s"), recoveryResume},
+		{"answer resume without time left", over, recoveryAsk, answer("s"), ""},
+		{"answer discard", running, recoveryAsk, answer("discard"), recoveryDiscard},
+		{"answer leave", running, recoveryAsk, answer("l"), ""},
+		{"no terminal", running, recoveryAsk, answer(), ""},
+	}
+	for _, tt := range tests {
+		if got := chooseRecovery(config, tt.state, tt.action, tt.answers, io.Discard); got != tt.want {
+			t.Errorf("%s: chooseRecovery() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	chooseRecovery(config, running, recoveryAsk, answer("l"), &out)
+	if !strings.Contains(out.String(), "2 shortcut(s) are still hidden") || !strings.Contains(out.String(), "re[s]ume") {
+		t.Errorf("Unexpected prompt: %s", out.String())
+	}
+
+	strict := &Config{Policy: &Policy{Strict: true}}
+	if got := chooseRecovery(strict, running, recoveryRestore, nil, io.Discard); got != recoveryResume {
+		t.Errorf("Expected strict mode to resume a session with time left, got %q", got)
+	}
+}
+
+// TestRecoverSessionDiscard tests that discarding forgets the session but leaves shortcuts alone
+func TestRecoverSessionDiscard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	state := crashedSession(time.Now(), 25)
+	writeActiveSession(path, *state)
+
+	if err := recoverSession(&Config{}, path, state, recoveryDiscard); err != nil {
+		t.Fatalf("recoverSession() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected session state to be removed")
+	}
+
+	writeActiveSession(path, *state)
+	if err := recoverSession(&Config{}, path, state, ""); err != nil {
+		t.Fatalf("recoverSession() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Expected session state to be kept when left for later")
+	}
+}
+
+// TestNeedsRecoveryCheck tests which command lines look for a crashed session
+func TestNeedsRecoveryCheck(t *testing.T) {
+	tests := map[string]bool{
+		"":                      true,
+		"-mode gamemode":        true,
+		"session start":         true,
+		"session recover":       false,
+		"status":                false,
+		"config validate":       false,
+		"daemon":                false,
+		"prompt":                false,
+		"shell-guard steam":     false,
+		"bug-report -yes":       false,
+		"zones":                 false,
+		"serve -dashboard":      false,
+		"gc -dry-run":           false,
+		"template apply writer": false,
+		"-daemon -config x.yml": false,
+		"-resume":               false,
+		"routine start -n day":  true,
+	}
+	for line, want := range tests {
+		if got := needsRecoveryCheck(strings.Fields(line)); got != want {
+			t.Errorf("needsRecoveryCheck(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+// TestConfigPathFromArgs tests finding the config flag of any command
+func TestConfigPathFromArgs(t *testing.T) {
+	tests := map[string]string{
+		"-mode gamemode":                   "profile.yml",
+		"-config work.yml -mode gamemode":  "work.yml",
+		"session start --config=home.yml":  "home.yml",
+		"routine start -config":            "profile.yml",
+		"-mode config -config /etc/fm.yml": "/etc/fm.yml",
+	}
+	for line, want := range tests {
+		if got := configPathFromArgs(strings.Fields(line)); got != want {
+			t.Errorf("configPathFromArgs(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
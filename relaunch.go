@@ -0,0 +1,421 @@
+package focusmode
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAppCheckSeconds = 5
+
+// Kinds of distraction log events
+const (
+	distractionRelaunch         = "relaunch"          // A closed app was started again
+	distractionOutsideAllowlist = "outside_allowlist" // An app off the allowlist stayed in the foreground
+)
+
+// runningProcess is a process found by listProcesses
+type runningProcess struct {
+	PID  int
+	Name string // Executable file name, e.g. Discord.exe or Discord
+}
+
+// listProcesses returns the running processes, replaced in tests
+var listProcesses = func() ([]runningProcess, error) {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("tasklist", "/FO", "CSV", "/NH").Output()
+		if err != nil {
+			return nil, fmt.Errorf("error listing processes: %w", err)
+		}
+		return parseTasklist(string(out)), nil
+	case "linux":
+		return procProcesses("/proc")
+	default:
+		out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+		if err != nil {
+			return nil, fmt.Errorf("error listing processes: %w", err)
+		}
+		return parsePS(string(out)), nil
+	}
+}
+
+// killProcess ends a process, replaced in tests
+var killProcess = func(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// parseTasklist parses the CSV output of tasklist /FO CSV /NH: image name, PID, session and memory
+func parseTasklist(out string) []runningProcess {
+	records, _ := csv.NewReader(strings.NewReader(out)).ReadAll()
+	var processes []runningProcess
+	for _, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		if pid, err := strconv.Atoi(record[1]); err == nil {
+			processes = append(processes, runningProcess{PID: pid, Name: record[0]})
+		}
+	}
+	return processes
+}
+
+// parsePS parses the output of ps -axo pid=,comm=, whose command is a full path on macOS
+func parsePS(out string) []runningProcess {
+	var processes []runningProcess
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			processes = append(processes, runningProcess{PID: pid, Name: filepath.Base(strings.TrimSpace(fields[1]))})
+		}
+	}
+	return processes
+}
+
+// procProcesses lists the processes under a Linux /proc, naming each by its executable and
+// falling back to comm, which is cut to 15 characters, for processes of other users
+func procProcesses(root string) ([]runningProcess, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("error listing processes: %w", err)
+	}
+	var processes []runningProcess
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		name := ""
+		if exe, err := os.Readlink(filepath.Join(root, entry.Name(), "exe")); err == nil {
+			name = filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+		} else if comm, err := os.ReadFile(filepath.Join(root, entry.Name(), "comm")); err == nil {
+			name = strings.TrimSpace(string(comm))
+		}
+		if name != "" {
+			processes = append(processes, runningProcess{PID: pid, Name: name})
+		}
+	}
+	return processes, nil
+}
+
+// appKey returns the name a block_apps entry or process is matched by: its file name without
+// .exe, in lower case, so C:\...\Discord.exe matches the Discord process on every system
+func appKey(program string) string {
+	return strings.TrimSuffix(strings.ToLower(programName(program)), ".exe")
+}
+
+// modeClosedApps returns the names of a mode's block_apps, for closing their processes
+func modeClosedApps(config *Config, mode string) []string {
+	var apps []string
+	for _, entry := range config.Modes[mode].BlockApps {
+		if name := programName(entry); name != "" && !containsFold(apps, name) {
+			apps = append(apps, name)
+		}
+	}
+	return apps
+}
+
+// checkInterval returns how often the running processes are checked
+func (c AppBlockingConfig) checkInterval() time.Duration {
+	if c.CheckSeconds <= 0 {
+		return defaultAppCheckSeconds * time.Second
+	}
+	return time.Duration(c.CheckSeconds) * time.Second
+}
+
+// DistractionEvent is one entry of the distraction log
+type DistractionEvent struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"` // distractionRelaunch or distractionOutsideAllowlist
+	Mode    string    `json:"mode"`
+	App     string    `json:"app"`
+	Attempt int       `json:"attempt"` // Events of this kind for the app so far in the session
+}
+
+// distractionLogPath returns the path of the distraction log
+func distractionLogPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "distractions.jsonl"), nil
+}
+
+// appendDistraction appends an event to the distraction log at path
+func appendDistraction(path string, event DistractionEvent) error {
+	unlock, err := lockRecordLog(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := appendFile(path, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening distraction log: %w", err)
+	}
+	defer file.Close()
+	ownByUser(path)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// readDistractions reads the distraction log at path, skipping malformed lines
+func readDistractions(path string) ([]DistractionEvent, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening distraction log: %w", err)
+	}
+	defer file.Close()
+	var events []DistractionEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event DistractionEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// relaunchNotice returns the notification for an app's nth relaunch, or false when none is due
+// Notices are sent on the 1st, 3rd and 10th relaunch and every 10th after, each firmer than the last
+func relaunchNotice(app, action string, n int) (string, bool) {
+	switch {
+	case n == 1 && action == appActionMinimize:
+		return fmt.Sprintf("%s was started again and has been minimized", app), true
+	case n == 1 && action == appActionWarn:
+		return fmt.Sprintf("%s was started again during your focus session", app), true
+	case n == 1:
+		return fmt.Sprintf("%s was started again and has been closed", app), true
+	case n == 3:
+		return fmt.Sprintf("%s has been started again 3 times; something may be reopening it, such as its launcher or a startup entry", app), true
+	case n >= 10 && n%10 == 0:
+		return fmt.Sprintf("%s has been started again %d times this session; turn off whatever relaunches it, or block it with app_blocking.backend firewall", app, n), true
+	}
+	return "", false
+}
+
+// appSupervisor closes, minimizes or warns about the session mode's block_apps when the session
+// starts and whenever they are found running while it runs, counting each time one was started again
+type appSupervisor struct {
+	apps     []string
+	action   string // appActionClose, appActionMinimize or appActionWarn
+	interval time.Duration
+	logPath  string
+
+	mu         sync.Mutex
+	relaunches map[string]int // By app, as named in block_apps
+	seen       map[int]bool   // Processes found before, which a minimized or warned app keeps running
+	failed     bool           // Minimizing failed, which is only reported once
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// sweep acts on the running processes of the apps and returns the apps that had any not seen before
+// Closed apps are closed again and minimized ones minimized again every sweep
+func (s *appSupervisor) sweep() []string {
+	processes, err := listProcesses()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not check for blocked apps: %v\n", err)
+		return nil
+	}
+	var found []string
+	for _, app := range s.apps {
+		key := appKey(app)
+		acted := false
+		for _, process := range processes {
+			if appKey(process.Name) != key || process.PID == os.Getpid() {
+				continue
+			}
+			if err := s.act(app, process.PID); err != nil {
+				continue
+			}
+			if s.action == appActionClose || s.action == "" || !s.seen[process.PID] {
+				s.seen[process.PID] = true
+				acted = true
+			}
+		}
+		if acted {
+			found = append(found, app)
+		}
+	}
+	return found
+}
+
+// act closes or minimizes one process of an app, warning when that fails
+func (s *appSupervisor) act(app string, pid int) error {
+	switch s.action {
+	case appActionWarn:
+		return nil
+	case appActionMinimize:
+		err := currentPlatform.MinimizeProcess(pid)
+		if err != nil && !s.failed {
+			s.failed = true
+			fmt.Fprintf(os.Stderr, "\nWarning: could not minimize %s (PID %d): %v\n", app, pid, err)
+		}
+		// Left running, so it still counts as found
+		return nil
+	}
+	if err := killProcess(pid); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWarning: could not close %s (PID %d): %v\n", app, pid, err)
+		return err
+	}
+	return nil
+}
+
+// check runs one sweep during the session, logging and announcing the apps started again
+func (s *appSupervisor) check(fs *FocusSession) {
+	for _, app := range s.sweep() {
+		s.mu.Lock()
+		s.relaunches[app]++
+		n := s.relaunches[app]
+		s.mu.Unlock()
+		event := DistractionEvent{Time: time.Now(), Kind: distractionRelaunch, Mode: fs.Mode, App: app, Attempt: n}
+		if err := appendDistraction(s.logPath, event); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: could not record distraction: %v\n", err)
+		}
+		if message, ok := relaunchNotice(app, s.action, n); ok {
+			notifyAll(buildNotifiers(fs.Hooks), "Blocked app", message)
+		}
+	}
+}
+
+// OnStart acts on the apps and starts checking for them every interval
+func (s *appSupervisor) OnStart(fs *FocusSession) {
+	s.relaunches = make(map[string]int)
+	s.seen = make(map[int]bool)
+	if found := s.sweep(); len(found) > 0 {
+		switch s.action {
+		case appActionMinimize:
+			fmt.Printf("%sMinimized %s for the session\n", glyph("🚫 "), strings.Join(found, ", "))
+		case appActionWarn:
+			fmt.Printf("%s%s running; close them to stay focused\n", glyph("⚠️ "), strings.Join(found, ", "))
+		default:
+			fmt.Printf("%sClosed %s for the session\n", glyph("🚫 "), strings.Join(found, ", "))
+		}
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.check(fs)
+			}
+		}
+	}()
+}
+
+// OnPause does nothing; apps stay closed while paused
+func (s *appSupervisor) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (s *appSupervisor) OnResume(fs *FocusSession) {}
+
+// OnEnd stops checking and reports the relaunches
+func (s *appSupervisor) OnEnd(fs *FocusSession) {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, app := range s.apps {
+		if n := s.relaunches[app]; n > 0 {
+			fmt.Printf("%s%s was started again %d time(s) and %s\n", glyph("🚫 "), app, n, appActionPast(s.action))
+		}
+	}
+}
+
+// DryRun reports the apps that would be closed, minimized or warned about
+func (s *appSupervisor) DryRun(fs *FocusSession) []string {
+	verb := s.action
+	if verb == appActionWarn {
+		verb = "warn about"
+	}
+	return []string{fmt.Sprintf("%s %s and check every %s for them being started again", verb, strings.Join(s.apps, ", "), s.interval)}
+}
+
+// appActionPast describes what was done to the apps found running, for the end of session report
+func appActionPast(action string) string {
+	switch action {
+	case appActionMinimize:
+		return "minimized"
+	case appActionWarn:
+		return "left running"
+	}
+	return "closed"
+}
+
+// printDistractions prints the events of each kind per app since a time, most first
+func printDistractions(out io.Writer, events []DistractionEvent, since time.Time) {
+	sections := []struct{ kind, title string }{
+		{distractionRelaunch, "Relaunch attempts of blocked apps:"},
+		{distractionOutsideAllowlist, "Apps used outside the allowlist:"},
+	}
+	printed := false
+	for _, section := range sections {
+		counts := make(map[string]int)
+		for _, event := range events {
+			if event.Kind == section.kind && !event.Time.Before(since) {
+				counts[event.App]++
+			}
+		}
+		if len(counts) > 0 {
+			printDistractionCounts(out, section.title, counts)
+			printed = true
+		}
+	}
+	if !printed {
+		fmt.Fprintln(out, "No distractions recorded")
+	}
+}
+
+// printDistractionCounts prints a titled count per app, most first
+func printDistractionCounts(out io.Writer, title string, counts map[string]int) {
+	apps := make([]string, 0, len(counts))
+	for app := range counts {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if counts[apps[i]] != counts[apps[j]] {
+			return counts[apps[i]] > counts[apps[j]]
+		}
+		return apps[i] < apps[j]
+	})
+	fmt.Fprintln(out, title)
+	for _, app := range apps {
+		fmt.Fprintf(out, "  %-24s %d\n", app, counts[app])
+	}
+}
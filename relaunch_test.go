@@ -0,0 +1,142 @@
+package focusmode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseProcessLists tests reading process names from tasklist, ps and /proc
+func TestParseProcessLists(t *testing.T) {
+	tasklist := "\"System Idle Process\",\"0\",\"Services\",\"0\",\"8 K\"\r\n\"Discord.exe\",\"4312\",\"Console\",\"1\",\"120,004 K\"\r\n"
+	want := []runningProcess{{PID: 0, Name: "System Idle Process"}, {PID: 4312, Name: "Discord.exe"}}
+	if got := parseTasklist(tasklist); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTasklist() = %+v, want %+v", got, want)
+	}
+
+	ps := "    1 /sbin/launchd\n  812 /Applications/Discord.app/Contents/MacOS/Discord\n"
+	want = []runningProcess{{PID: 1, Name: "launchd"}, {PID: 812, Name: "Discord"}}
+	if got := parsePS(ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePS() = %+v, want %+v", got, want)
+	}
+
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "42"), 0755)
+	os.WriteFile(filepath.Join(root, "42", "comm"), []byte("Discord\n"), 0644)
+	os.MkdirAll(filepath.Join(root, "self"), 0755)
+	got, err := procProcesses(root)
+	if err != nil || !reflect.DeepEqual(got, []runningProcess{{PID: 42, Name: "Discord"}}) {
+		t.Errorf("procProcesses() = %+v, %v", got, err)
+	}
+
+	for _, name := range []string{`C:\Users\me\AppData\Local\Discord\app-1.0.9\Discord.exe`, "discord", "Discord.exe"} {
+		if appKey(name) != "discord" {
+			t.Errorf("appKey(%q) = %q, want discord", name, appKey(name))
+		}
+	}
+}
+
+// TestAppSupervisor tests closing apps at the start, then counting and logging each relaunch
+func TestAppSupervisor(t *testing.T) {
+	running := []runningProcess{{PID: 10, Name: "Discord.exe"}, {PID: 11, Name: "Discord.exe"}, {PID: 12, Name: "code.exe"}}
+	var killed []int
+	saveList, saveKill := listProcesses, killProcess
+	t.Cleanup(func() { listProcesses, killProcess = saveList, saveKill })
+	listProcesses = func() ([]runningProcess, error) { return running, nil }
+	killProcess = func(pid int) error {
+		killed = append(killed, pid)
+		var left []runningProcess
+		for _, process := range running {
+			if process.PID != pid {
+				left = append(left, process)
+			}
+		}
+		running = left
+		return nil
+	}
+
+	logPath := filepath.Join(t.TempDir(), "distractions.jsonl")
+	supervisor := &appSupervisor{apps: modeClosedApps(&Config{Modes: map[string]ModeConfig{"focusmode": {BlockApps: []string{`C:\Apps\Discord.exe`, "discord.exe"}}}}, "focusmode"), interval: time.Hour, logPath: logPath}
+	fs := &FocusSession{Mode: "focusmode"}
+	supervisor.OnStart(fs)
+	if !reflect.DeepEqual(killed, []int{10, 11}) {
+		t.Errorf("Expected both Discord processes closed at the start, got %v", killed)
+	}
+
+	// Started again twice; a check with nothing running counts nothing
+	for _, pid := range []int{20, 0, 21} {
+		if pid != 0 {
+			running = append(running, runningProcess{PID: pid, Name: "Discord.exe"})
+		}
+		supervisor.check(fs)
+	}
+	supervisor.OnEnd(fs)
+
+	events, err := readDistractions(logPath)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Expected 2 relaunches logged, got %+v (err %v)", events, err)
+	}
+	if events[1].App != "Discord.exe" || events[1].Attempt != 2 || events[1].Mode != "focusmode" {
+		t.Errorf("Unexpected event: %+v", events[1])
+	}
+
+	var out bytes.Buffer
+	printDistractions(&out, events, time.Now().Add(-time.Hour))
+	if !strings.Contains(out.String(), "Discord.exe") || !strings.Contains(out.String(), " 2\n") {
+		t.Errorf("Unexpected summary:\n%s", out.String())
+	}
+}
+
+// TestRelaunchNotice tests that notices escalate and are not sent for every relaunch
+func TestRelaunchNotice(t *testing.T) {
+	var sent []int
+	for n := 1; n <= 30; n++ {
+		if _, ok := relaunchNotice("Discord", appActionClose, n); ok {
+			sent = append(sent, n)
+		}
+	}
+	if !reflect.DeepEqual(sent, []int{1, 3, 10, 20, 30}) {
+		t.Errorf("Expected notices on relaunches 1, 3, 10, 20 and 30, got %v", sent)
+	}
+	if message, _ := relaunchNotice("Discord", appActionClose, 10); !strings.Contains(message, "firewall") {
+		t.Errorf("Expected the 10th notice to suggest the firewall, got %q", message)
+	}
+}
+
+// TestAppSupervisorMinimize tests that minimized apps stay running and only new processes count as relaunches
+func TestAppSupervisorMinimize(t *testing.T) {
+	running := []runningProcess{{PID: 10, Name: "Discord"}, {PID: 12, Name: "code"}}
+	saveList, saveKill := listProcesses, killProcess
+	t.Cleanup(func() { listProcesses, killProcess = saveList, saveKill })
+	listProcesses = func() ([]runningProcess, error) { return running, nil }
+	killProcess = func(pid int) error {
+		t.Errorf("Expected nothing closed, got PID %d", pid)
+		return nil
+	}
+	fake := &fakePlatform{}
+	useFakePlatform(t, fake)
+
+	logPath := filepath.Join(t.TempDir(), "distractions.jsonl")
+	supervisor := &appSupervisor{apps: []string{"Discord.exe"}, action: appActionMinimize, interval: time.Hour, logPath: logPath}
+	fs := &FocusSession{Mode: "focusmode"}
+	supervisor.OnStart(fs)
+	supervisor.check(fs) // Still the same process, minimized again but not a relaunch
+	running = append(running, runningProcess{PID: 20, Name: "Discord"})
+	supervisor.check(fs)
+	supervisor.OnEnd(fs)
+
+	if !reflect.DeepEqual(fake.minimized, []int{10, 10, 10, 20}) {
+		t.Errorf("Expected Discord minimized at every check, got %v", fake.minimized)
+	}
+	events, err := readDistractions(logPath)
+	if err != nil || len(events) != 1 || events[0].Attempt != 1 {
+		t.Errorf("Expected the new process logged as one relaunch, got %+v (err %v)", events, err)
+	}
+	if err := (AppBlockingConfig{Action: "hide"}).validate("linux"); err == nil || !strings.Contains(err.Error(), "app_blocking.action") {
+		t.Errorf("Expected an unknown action reported, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package focusmode
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// remoteOptions are the global flags that send a command to another machine's daemon
+type remoteOptions struct {
+	Address   string // host:port or URL of the daemon, or unix:path of a local daemon socket
+	Token     string // bearer token (defaults to $FOCUSMODE_TOKEN)
+	TokenName string // name of a token in the local keychain, used when Token is empty
+	CAFile    string // CA certificate used to verify the daemon's certificate
+	CertFile  string // client certificate for mutual TLS
+	KeyFile   string // client private key for mutual TLS
+	User      string // user on the daemon's machine whose desktop is managed
+}
+
+// parseRemoteArgs extracts leading --remote, --local, --token, --token-name, --ca, --cert, --key and --user flags
+// --local selects the daemon's socket on this machine
+// It returns the options and the remaining command arguments
+func parseRemoteArgs(args []string) (remoteOptions, []string, error) {
+	opts := remoteOptions{Token: os.Getenv("FOCUSMODE_TOKEN"), User: targetUser}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		var target *string
+		switch name {
+		case "local":
+			opts.Address = localAddressPrefix
+			args = args[1:]
+			continue
+		case "remote":
+			target = &opts.Address
+		case "token":
+			target = &opts.Token
+		case "token-name":
+			target = &opts.TokenName
+		case "ca":
+			target = &opts.CAFile
+		case "cert":
+			target = &opts.CertFile
+		case "key":
+			target = &opts.KeyFile
+		case "user":
+			target = &opts.User
+		default:
+			return opts, args, nil
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return opts, nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		*target = value
+		args = args[1:]
+	}
+	return opts, args, nil
+}
+
+// remoteBaseURL returns the daemon URL, using HTTPS unless a scheme is given
+// Requests to a local socket use plain HTTP; the host name is ignored
+func remoteBaseURL(address string) string {
+	if isLocalAddress(address) {
+		return "http://focusmode"
+	}
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		return strings.TrimSuffix(address, "/")
+	}
+	return "https://" + strings.TrimSuffix(address, "/")
+}
+
+// remoteRequest translates command-line arguments into a daemon API call
+func remoteRequest(args []string) (string, string, *commandRequest, error) {
+	if len(args) == 0 {
+		return "", "", nil, fmt.Errorf("no command given")
+	}
+
+	req := &commandRequest{}
+	var positional []string
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-dry-run", "--dry-run":
+			req.DryRun = true
+		case "-all", "--all":
+			req.All = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	command := args[0]
+	switch command {
+	case "status":
+		return http.MethodGet, "/api/status", nil, nil
+	case "apply", "push":
+		if len(positional) != 1 {
+			return "", "", nil, fmt.Errorf("usage: focusmode --remote host:port %s <mode>", command)
+		}
+		req.Mode = positional[0]
+	case "restore":
+		if len(positional) > 1 {
+			return "", "", nil, fmt.Errorf("usage: focusmode --remote host:port restore [--all] [mode]")
+		}
+		if len(positional) == 1 {
+			req.Mode = positional[0]
+		}
+	case "pop", "panic":
+	default:
+		return "", "", nil, fmt.Errorf("unsupported remote command: %s (use apply, restore, push, pop, panic or status)", command)
+	}
+	return http.MethodPost, "/api/" + command, req, nil
+}
+
+// remoteHTTPClient returns a client that trusts the CA file in addition to the system roots
+// and presents the client certificate, if given, for mutual TLS
+func remoteHTTPClient(opts remoteOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if isLocalAddress(opts.Address) {
+		transport.Proxy = nil
+		transport.DialContext = dialSocket(strings.TrimPrefix(opts.Address, localAddressPrefix))
+		return &http.Client{Transport: transport, Timeout: 2 * time.Minute}, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: 2 * time.Minute}, nil
+}
+
+// runRemote sends a command to a daemon and prints its result
+func runRemote(client *http.Client, opts remoteOptions, args []string, out io.Writer) error {
+	method, path, req, err := remoteRequest(args)
+	if err != nil {
+		return err
+	}
+	if opts.User != "" {
+		if req != nil {
+			req.User = opts.User
+		} else {
+			path += "?user=" + url.QueryEscape(opts.User)
+		}
+	}
+
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequest(method, remoteBaseURL(opts.Address)+path, body)
+	if err != nil {
+		return err
+	}
+	if opts.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("error contacting daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(path, "/api/status") && resp.StatusCode == http.StatusOK {
+		var status statusReport
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return fmt.Errorf("error parsing daemon response: %w", err)
+		}
+		printStatus(out, &status, nil)
+		return nil
+	}
+
+	var result commandResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error parsing daemon response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	fmt.Fprint(out, result.Output)
+	if result.Error != "" {
+		return fmt.Errorf("daemon: %s", result.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("daemon returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// isRemoteFlag reports whether a command line starts with a flag that sends it to a daemon
+func isRemoteFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return strings.HasPrefix(arg, "-") && (name == "remote" || name == "local")
+}
+
+// runRemoteCommand handles "focusmode --remote host:port <command>" and "focusmode --local <command>"
+func runRemoteCommand(args []string) {
+	opts, rest, err := parseRemoteArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Address == localAddressPrefix {
+		socket, err := socketPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Address = localAddressPrefix + socket
+	}
+
+	if opts.Token == "" && opts.TokenName != "" {
+		opts.Token, err = runKeychain("get", opts.TokenName, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	client, err := remoteHTTPClient(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := runRemote(client, opts, rest, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
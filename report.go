@@ -0,0 +1,153 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// startOfWeek returns midnight on the Monday of the week containing t
+func startOfWeek(t time.Time) time.Time {
+	weekday := (int(t.Weekday()) + 6) % 7 // Monday = 0
+	year, month, day := t.Date()
+	return time.Date(year, month, day-weekday, 0, 0, 0, 0, t.Location())
+}
+
+// sessionsBetween returns focus session records that started in [start, end)
+func sessionsBetween(records []SessionRecord, start, end time.Time) []SessionRecord {
+	var sessions []SessionRecord
+	for _, record := range records {
+		if record.Kind != RecordSession {
+			continue
+		}
+		if record.StartTime.Before(start) || !record.StartTime.Before(end) {
+			continue
+		}
+		sessions = append(sessions, record)
+	}
+	return sessions
+}
+
+// printWeeklyReport prints one line per session of the week plus totals
+// activity may be nil when GitHub correlation is disabled
+func printWeeklyReport(sessions []SessionRecord, start, end time.Time, activity []githubActivity) {
+	fmt.Printf("Weekly report: %s – %s\n\n", start.Format("Mon Jan 2"), end.Add(-time.Second).Format("Mon Jan 2, 2006"))
+
+	if len(sessions) == 0 {
+		fmt.Println("No focus sessions recorded this week.")
+		return
+	}
+
+	// Aggregate WakaTime stats when any session has them
+	var coding *CodingStats
+	for _, record := range sessions {
+		if record.Coding == nil {
+			continue
+		}
+		if coding == nil {
+			coding = &CodingStats{Languages: make(map[string]int64), Projects: make(map[string]int64)}
+		}
+		coding.TotalSeconds += record.Coding.TotalSeconds
+		for name, seconds := range record.Coding.Languages {
+			coding.Languages[name] += seconds
+		}
+		for name, seconds := range record.Coding.Projects {
+			coding.Projects[name] += seconds
+		}
+	}
+
+	header := fmt.Sprintf("%-10s %-6s %-12s %-9s %-5s", "Date", "Time", "Mode", "Focused", "Done")
+	if activity != nil {
+		header += fmt.Sprintf(" %-18s", "GitHub")
+	}
+	if coding != nil {
+		header += " Coding"
+	}
+	fmt.Println(header)
+
+	var focused time.Duration
+	completed, productive := 0, 0
+	for i, record := range sessions {
+		duration := time.Duration(record.FocusedSeconds) * time.Second
+		focused += duration
+
+		done := glyphOr("✗", "no")
+		if record.Completed {
+			done = glyphOr("✓", "yes")
+			completed++
+		}
+
+		line := fmt.Sprintf("%-10s %-6s %-12s %-9s %-5s",
+			record.StartTime.Local().Format("Mon 01-02"), record.StartTime.Local().Format("15:04"),
+			record.Mode, formatDuration(duration.Round(time.Minute)), done)
+		if activity != nil {
+			line += fmt.Sprintf(" %-18s", activity[i].String())
+			if activity[i].Commits > 0 || activity[i].PullRequests > 0 {
+				productive++
+			}
+		}
+		if coding != nil {
+			line += " " + record.Coding.String()
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println("\n--- Summary ---")
+	fmt.Printf("Sessions: %d (%d completed)\n", len(sessions), completed)
+	fmt.Printf("Focused: %s\n", formatDuration(focused.Round(time.Minute)))
+	if activity != nil {
+		fmt.Printf("Sessions with GitHub activity: %d/%d\n", productive, len(sessions))
+	}
+	if coding != nil {
+		fmt.Printf("Coding: %s\n", formatDuration((time.Duration(coding.TotalSeconds) * time.Second).Round(time.Minute)))
+		if languages := topEntries(coding.Languages, 5); len(languages) > 0 {
+			fmt.Printf("  Languages: %s\n", strings.Join(languages, ", "))
+		}
+		if projects := topEntries(coding.Projects, 5); len(projects) > 0 {
+			fmt.Printf("  Projects: %s\n", strings.Join(projects, ", "))
+		}
+	}
+}
+
+// runReportCommand handles the "report" subcommand
+func runReportCommand(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	weeksAgo := flags.Int("weeks-ago", 0, "Report on an earlier week (0 = this week)")
+	noGitHub := flags.Bool("no-github", false, "Skip GitHub activity correlation")
+	flags.Parse(args)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	records, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(1)
+	}
+
+	start := startOfWeek(time.Now()).AddDate(0, 0, -7*(*weeksAgo))
+	end := start.AddDate(0, 0, 7)
+	sessions := sessionsBetween(records, start, end)
+
+	var activity []githubActivity
+	if config.GitHub.Enabled && !*noGitHub && len(sessions) > 0 {
+		events, err := fetchGitHubEvents(config.Retry.Network, config.GitHub, start)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: GitHub activity unavailable: %v\n\n", err)
+		} else {
+			grace := config.GitHub.GraceMinutes
+			if grace <= 0 {
+				grace = defaultGitHubGraceMinutes
+			}
+			activity = correlateGitHub(sessions, events, config.GitHub.Repos, time.Duration(grace)*time.Minute)
+		}
+	}
+
+	printWeeklyReport(sessions, start, end, activity)
+}
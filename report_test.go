@@ -0,0 +1,43 @@
+package focusmode
+
+import (
+	"testing"
+	"time"
+)
+
+// TestStartOfWeek tests that weeks start on Monday at midnight
+func TestStartOfWeek(t *testing.T) {
+	tests := []struct {
+		in   time.Time
+		want time.Time
+	}{
+		{time.Date(2024, 5, 8, 15, 30, 0, 0, time.UTC), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 12, 23, 59, 0, 0, time.UTC), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := startOfWeek(tt.in); !got.Equal(tt.want) {
+			t.Errorf("startOfWeek(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestSessionsBetween tests filtering of history records to a week
+func TestSessionsBetween(t *testing.T) {
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	records := []SessionRecord{
+		{Kind: RecordSession, StartTime: start.Add(-time.Minute)},
+		{Kind: RecordSession, StartTime: start},
+		{Kind: RecordBreak, StartTime: start.Add(time.Hour)},
+		{Kind: RecordRoutine, StartTime: start.Add(time.Hour)},
+		{Kind: RecordSession, StartTime: end.Add(-time.Minute)},
+		{Kind: RecordSession, StartTime: end},
+	}
+
+	sessions := sessionsBetween(records, start, end)
+	if len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions in week, got %d", len(sessions))
+	}
+}
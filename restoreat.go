@@ -0,0 +1,248 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// restoreAtPollInterval is how often the daemon looks for shortcuts whose restore_at time came
+const restoreAtPollInterval = 30 * time.Second
+
+// timedRestore is shortcuts of one category a completed session kept hidden until a time of
+// day, from its preset's restore_at; the daemon brings them back then
+type timedRestore struct {
+	Mode     string    `json:"mode"`
+	Category string    `json:"category"`
+	Folder   string    `json:"folder"`
+	Names    []string  `json:"names"`
+	At       time.Time `json:"at"`
+}
+
+// restoreTimes returns the preset's restore_at times since midnight, by lowercase category
+// getPreset has checked them, so times that don't parse are left out
+func (p *SessionPreset) restoreTimes() map[string]time.Duration {
+	if len(p.RestoreAt) == 0 {
+		return nil
+	}
+	times := make(map[string]time.Duration, len(p.RestoreAt))
+	for category, value := range p.RestoreAt {
+		if clock, err := parseClock("restore_at", value); err == nil {
+			times[strings.ToLower(category)] = clock
+		}
+	}
+	return times
+}
+
+// lintRestoreAt warns about restore_at times that never hold anything back: those of categories
+// on_complete_restore already brings back, and all of them when it brings back everything
+func (p *SessionPreset) lintRestoreAt(name string) []string {
+	if len(p.RestoreAt) == 0 {
+		return nil
+	}
+	if len(p.OnCompleteRestore) == 0 {
+		return []string{fmt.Sprintf("preset '%s' has restore_at but no on_complete_restore, so everything is restored when the session completes", name)}
+	}
+	categories := make([]string, 0, len(p.RestoreAt))
+	for category := range p.RestoreAt {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	var warnings []string
+	for _, category := range categories {
+		for _, restored := range p.OnCompleteRestore {
+			if strings.EqualFold(category, restored) {
+				warnings = append(warnings, fmt.Sprintf("preset '%s' restores '%s' when the session completes; its restore_at time has no effect", name, category))
+			}
+		}
+	}
+	return warnings
+}
+
+// planTimedRestores takes the shortcuts kept hidden at the end of a session whose category has a
+// restore time, returning those due already, those held until later and the rest
+func planTimedRestores(mode, folder string, kept []string, times map[string]time.Duration, categoriesConfig *CategoriesConfig, now time.Time) (due []string, held []timedRestore, rest []string) {
+	byCategory := make(map[string]int)
+	for _, name := range kept {
+		category := string(categorizeShortcut(name, categoriesConfig))
+		clock, ok := times[category]
+		switch {
+		case !ok:
+			rest = append(rest, name)
+		case !at(now, clock).After(now):
+			// "Hidden until 18:00" is over for a session ending later than that
+			due = append(due, name)
+		default:
+			i, seen := byCategory[category]
+			if !seen {
+				i = len(held)
+				byCategory[category] = i
+				held = append(held, timedRestore{Mode: mode, Category: category, Folder: folder, At: at(now, clock)})
+			}
+			held[i].Names = append(held[i].Names, name)
+		}
+	}
+	return due, held, rest
+}
+
+// timedRestoresPath returns the path of the shortcuts held until their restore_at time
+func timedRestoresPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "restore-at.json"), nil
+}
+
+// readTimedRestores returns the shortcuts held until their restore_at time, or none if there is no file
+func readTimedRestores(path string) ([]timedRestore, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading timed restores: %w", err)
+	}
+	var restores []timedRestore
+	if err := json.Unmarshal(data, &restores); err != nil {
+		return nil, fmt.Errorf("error parsing timed restores: %w", err)
+	}
+	return restores, nil
+}
+
+// writeTimedRestores records the shortcuts held until their restore_at time, removing the file when there are none
+func writeTimedRestores(path string, restores []timedRestore) error {
+	if len(restores) == 0 {
+		if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing timed restores: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(restores, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding timed restores: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing timed restores: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// recordTimedRestores adds held shortcuts for the daemon to bring back, replacing what an
+// earlier session of the mode held in the same category
+func recordTimedRestores(held []timedRestore) error {
+	path, err := timedRestoresPath()
+	if err != nil {
+		return err
+	}
+	restores, err := readTimedRestores(path)
+	if err != nil {
+		return err
+	}
+	for _, h := range held {
+		kept := restores[:0]
+		for _, r := range restores {
+			if r.Mode != h.Mode || r.Category != h.Category {
+				kept = append(kept, r)
+			}
+		}
+		restores = append(kept, h)
+	}
+	return writeTimedRestores(path, restores)
+}
+
+// runTimedRestores restores the held shortcuts whose time has come and keeps the others for later
+// A restore is dropped without moving anything while a session runs in its mode, since that
+// session's own end decides what comes back; shortcuts already restored by hand are passed over
+// It returns the restores that came due, each with the names actually restored
+func runTimedRestores(config *Config, path string, now time.Time) ([]timedRestore, error) {
+	restores, err := readTimedRestores(path)
+	if err != nil || len(restores) == 0 {
+		return nil, err
+	}
+	var session *activeSession
+	if sessionPath, err := activeSessionPath(); err == nil {
+		session, _ = readActiveSession(sessionPath)
+	}
+
+	var due, waiting []timedRestore
+	for _, r := range restores {
+		if r.At.After(now) {
+			waiting = append(waiting, r)
+			continue
+		}
+		restored := r
+		restored.Names = nil
+		if session == nil || session.Mode != r.Mode {
+			policy := config.Modes[r.Mode].restoreConflictPolicy()
+			for _, name := range config.restorable(r.Names) {
+				if _, ok := findFileName(r.Folder, name); !ok {
+					continue
+				}
+				result, err := config.restoreShortcut(name, r.Folder, policy)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error restoring '%s': %v\n", name, err)
+					continue
+				}
+				if result.Skipped {
+					fmt.Println(result.describe(name))
+					continue
+				}
+				restored.Names = append(restored.Names, name)
+			}
+		}
+		due = append(due, restored)
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	refreshHiddenMenu(config)
+	return due, writeTimedRestores(path, waiting)
+}
+
+// watchTimedRestores brings back the shortcuts sessions kept hidden until their restore_at time
+// Restores that came due while the daemon was stopped run when it starts
+func watchTimedRestores(ctx context.Context, config *Config, mu sync.Locker, beat func()) {
+	notifiers := []Notifier{consoleNotifier{}}
+	path, err := timedRestoresPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	for {
+		beat()
+		var due []timedRestore
+		withLock(mu, func() { due, err = runTimedRestores(config, path, time.Now()) })
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		for _, r := range due {
+			if len(r.Names) > 0 {
+				notifyAll(notifiers, "Shortcuts restored", fmt.Sprintf("Restored %d %s shortcut(s) kept hidden after the %s session", len(r.Names), r.Category, r.Mode))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restoreAtPollInterval):
+		}
+	}
+}
+
+// describeTimedRestores returns a line per held category saying when it comes back
+func describeTimedRestores(held []timedRestore) []string {
+	sort.Slice(held, func(i, j int) bool { return held[i].At.Before(held[j].At) })
+	lines := make([]string, 0, len(held))
+	for _, h := range held {
+		lines = append(lines, fmt.Sprintf("Keeping %d %s shortcut(s) hidden until %s; the daemon restores them then", len(h.Names), h.Category, h.At.Format("15:04")))
+	}
+	return lines
+}
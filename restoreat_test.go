@@ -0,0 +1,145 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPresetRestoreAt tests that restore_at times are checked, and warned about when they hold nothing back
+func TestPresetRestoreAt(t *testing.T) {
+	config := &Config{
+		Modes:       map[string]ModeConfig{"focusmode": {Destination: "Focus"}},
+		DefaultMode: "focusmode",
+		Presets: map[string]SessionPreset{
+			"deepwork": {Duration: 50, OnCompleteRestore: []string{"work"}, RestoreAt: map[string]string{"Game": "18:00", "work": "12:00"}},
+			"badtime":  {Duration: 50, OnCompleteRestore: []string{"work"}, RestoreAt: map[string]string{"game": "6pm"}},
+			"all":      {Duration: 50, RestoreAt: map[string]string{"game": "18:00"}},
+		},
+	}
+
+	preset, err := config.getPreset("deepwork")
+	if err != nil {
+		t.Fatalf("getPreset() returned error: %v", err)
+	}
+	if times := preset.restoreTimes(); !reflect.DeepEqual(times, map[string]time.Duration{"game": 18 * time.Hour, "work": 12 * time.Hour}) {
+		t.Errorf("Unexpected restore times: %v", times)
+	}
+	if _, err := config.getPreset("badtime"); err == nil || !strings.Contains(err.Error(), "restore_at.game") {
+		t.Errorf("Expected an error naming restore_at.game, got %v", err)
+	}
+
+	warnings := strings.Join(lintConfig(config), "\n")
+	for _, want := range []string{
+		"preset 'deepwork' restores 'work' when the session completes",
+		"preset 'all' has restore_at but no on_complete_restore",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected a warning %q, got:\n%s", want, warnings)
+		}
+	}
+}
+
+// TestPlanTimedRestores tests which kept shortcuts wait for their restore time and which come back now
+func TestPlanTimedRestores(t *testing.T) {
+	kept := []string{"Steam.lnk", "Notes.txt", "Epic Games.lnk"}
+	times := map[string]time.Duration{"game": 18 * time.Hour}
+	now := scheduleTime(16, 17, 0)
+
+	due, held, rest := planTimedRestores("focusmode", "/hidden", kept, times, getDefaultCategoriesConfig(), now)
+	want := []timedRestore{{Mode: "focusmode", Category: "game", Folder: "/hidden", Names: []string{"Steam.lnk", "Epic Games.lnk"}, At: scheduleTime(16, 18, 0)}}
+	if len(due) != 0 || !reflect.DeepEqual(held, want) || !reflect.DeepEqual(rest, []string{"Notes.txt"}) {
+		t.Errorf("planTimedRestores() = %v, %+v, %v", due, held, rest)
+	}
+
+	// A session ending after 18:00 has nothing left to hold
+	due, held, _ = planTimedRestores("focusmode", "/hidden", kept, times, getDefaultCategoriesConfig(), scheduleTime(16, 19, 0))
+	if !reflect.DeepEqual(due, []string{"Steam.lnk", "Epic Games.lnk"}) || len(held) != 0 {
+		t.Errorf("Expected the games due at once, got %v, %+v", due, held)
+	}
+}
+
+// TestSessionRestoreAt tests that a completed session leaves the held category for the daemon
+func TestSessionRestoreAt(t *testing.T) {
+	now := time.Now()
+	if sinceMidnight(now) >= 23*time.Hour+58*time.Minute {
+		t.Skip("Too close to midnight for a restore time later today")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	os.MkdirAll(filepath.Join(home, "Desktop"), 0755)
+	os.MkdirAll(filepath.Join(home, "Focus"), 0755)
+	for _, name := range []string{"Steam.lnk", "Word.lnk", "Notes.txt"} {
+		os.WriteFile(filepath.Join(home, "Focus", name), nil, 0644)
+	}
+
+	fs := &FocusSession{
+		Mode:              "focusmode",
+		Config:            &Config{Modes: map[string]ModeConfig{"focusmode": {Destination: "Focus"}}},
+		State:             StateCompleted,
+		MovedShortcuts:    []string{"Steam.lnk", "Word.lnk", "Notes.txt"},
+		RestoreCategories: []string{"work"},
+		RestoreAt:         map[string]time.Duration{"game": 23*time.Hour + 59*time.Minute},
+	}
+	fs.restoreMovedShortcuts()
+
+	if _, err := os.Stat(filepath.Join(home, "Desktop", "Word.lnk")); err != nil {
+		t.Errorf("Expected Word.lnk restored: %v", err)
+	}
+	for _, name := range []string{"Steam.lnk", "Notes.txt"} {
+		if _, err := os.Stat(filepath.Join(home, "Focus", name)); err != nil {
+			t.Errorf("Expected %s kept hidden: %v", name, err)
+		}
+	}
+	path, _ := timedRestoresPath()
+	restores, err := readTimedRestores(path)
+	if err != nil || len(restores) != 1 || restores[0].Category != "game" || !reflect.DeepEqual(restores[0].Names, []string{"Steam.lnk"}) {
+		t.Fatalf("Expected Steam.lnk held for the daemon, got %+v, %v", restores, err)
+	}
+	if restores[0].At.Format("15:04") != "23:59" {
+		t.Errorf("Expected the restore at 23:59, got %v", restores[0].At)
+	}
+}
+
+// TestRunTimedRestores tests that the daemon restores held shortcuts once their time comes
+func TestRunTimedRestores(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	desktop := filepath.Join(home, "Desktop")
+	folder := filepath.Join(home, "Focus")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(folder, 0755)
+	os.WriteFile(filepath.Join(folder, "Steam.lnk"), nil, 0644)
+
+	path, err := timedRestoresPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	// Epic Games.lnk was brought back by hand in the meantime
+	held := timedRestore{Mode: "focusmode", Category: "game", Folder: folder, Names: []string{"Steam.lnk", "Epic Games.lnk"}, At: scheduleTime(16, 18, 0)}
+	later := timedRestore{Mode: "focusmode", Category: "social", Folder: folder, Names: []string{"Slack.lnk"}, At: scheduleTime(16, 20, 0)}
+	if err := recordTimedRestores([]timedRestore{held, later}); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{Modes: map[string]ModeConfig{"focusmode": {Destination: "Focus"}}}
+
+	if due, err := runTimedRestores(config, path, scheduleTime(16, 17, 59)); err != nil || len(due) != 0 {
+		t.Fatalf("Expected nothing due before 18:00, got %+v, %v", due, err)
+	}
+	due, err := runTimedRestores(config, path, scheduleTime(16, 18, 0))
+	if err != nil || len(due) != 1 || !reflect.DeepEqual(due[0].Names, []string{"Steam.lnk"}) {
+		t.Fatalf("Expected Steam.lnk restored at 18:00, got %+v, %v", due, err)
+	}
+	if _, err := os.Stat(filepath.Join(desktop, "Steam.lnk")); err != nil {
+		t.Errorf("Expected Steam.lnk on the desktop: %v", err)
+	}
+	if restores, _ := readTimedRestores(path); len(restores) != 1 || restores[0].Category != "social" || !restores[0].At.Equal(later.At) {
+		t.Errorf("Expected only the later restore left, got %+v", restores)
+	}
+}
@@ -0,0 +1,134 @@
+package focusmode
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RestoreHandler sends the restored files of an extension somewhere other than the desktop
+type RestoreHandler struct {
+	To string `yaml:"to" doc:"Folder the files are restored to instead of the desktop; ~/ is the home folder and a relative path is under it" example:"~/Bookmarks"`
+}
+
+// handlerExtension returns a restore_handlers key as the extension it matches: lowercase with a dot
+func handlerExtension(key string) string {
+	ext := strings.ToLower(strings.TrimSpace(key))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// restoreHandlerKeys returns the keys of restore_handlers in order, so the first of two handlers
+// of one extension always wins
+func (c *Config) restoreHandlerKeys() []string {
+	keys := make([]string, 0, len(c.RestoreHandlers))
+	for key := range c.RestoreHandlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// restoreHandler returns the handler of a file's extension, if one is configured
+func (c *Config) restoreHandler(name string) (string, RestoreHandler, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if c == nil || ext == "" {
+		return "", RestoreHandler{}, false
+	}
+	for _, key := range c.restoreHandlerKeys() {
+		if handler := c.RestoreHandlers[key]; handlerExtension(key) == ext && handler.To != "" {
+			return key, handler, true
+		}
+	}
+	return "", RestoreHandler{}, false
+}
+
+// folder returns the handler's folder with ~/ expanded and a relative path put under the home folder
+func (h RestoreHandler) folder() (string, error) {
+	to, err := expandDestination(h.To)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(to) {
+		home, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		to = filepath.Join(home, to)
+	}
+	return filepath.Clean(to), nil
+}
+
+// handlerFolder returns the folder a file is restored to by its extension's handler; false when
+// it has none and goes back to the desktop
+func (c *Config) handlerFolder(name string) (string, bool, error) {
+	key, handler, ok := c.restoreHandler(name)
+	if !ok {
+		return "", false, nil
+	}
+	folder, err := handler.folder()
+	if err != nil {
+		return "", false, fmt.Errorf("restore_handlers.%s: %w", key, err)
+	}
+	return folder, true, nil
+}
+
+// withRestoreHandlers points the restores of files with a handler at its folder instead of the desktop
+func (c *Config) withRestoreHandlers(moves []fileMove) ([]fileMove, error) {
+	for i := range moves {
+		folder, ok, err := c.handlerFolder(moves[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			moves[i].To = folder
+		}
+	}
+	return moves, nil
+}
+
+// restoreShortcut restores a hidden file to its extension's handler folder, or to the desktop it
+// came from when it has none; policy settles a name already taken there
+func (c *Config) restoreShortcut(name, sourceDir, policy string) (restoreResult, error) {
+	folder, ok, err := c.handlerFolder(name)
+	if err != nil {
+		return restoreResult{}, err
+	}
+	if !ok {
+		return c.restoreShortcutWithPolicy(name, sourceDir, policy)
+	}
+	if err := mkdirAll(folder, 0755); err != nil {
+		return restoreResult{}, fmt.Errorf("error creating %s: %w", folder, err)
+	}
+	result, err := c.restoreShortcutTo(name, sourceDir, folder, policy)
+	result.Folder = folder
+	return result, err
+}
+
+// lintRestoreHandlers warns about handlers that can't send files anywhere
+func (c *Config) lintRestoreHandlers() []string {
+	var warnings []string
+	seen := make(map[string]string)
+	for _, key := range c.restoreHandlerKeys() {
+		ext := handlerExtension(key)
+		switch {
+		case ext == "." || strings.ContainsAny(ext, `/\*? `) || strings.Count(ext, ".") > 1:
+			warnings = append(warnings, fmt.Sprintf("restore_handlers.%s: expected a file extension such as .url", key))
+			continue
+		case c.RestoreHandlers[key].To == "":
+			warnings = append(warnings, fmt.Sprintf("restore_handlers.%s: no folder in to; its files go back to the desktop", key))
+			continue
+		case seen[ext] != "":
+			warnings = append(warnings, fmt.Sprintf("restore_handlers.%s: %s is already handled by restore_handlers.%s, which wins", key, ext, seen[ext]))
+		default:
+			seen[ext] = key
+		}
+		if _, err := c.RestoreHandlers[key].folder(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("restore_handlers.%s: %v", key, err))
+		}
+	}
+	return warnings
+}
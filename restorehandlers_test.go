@@ -0,0 +1,127 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHandlerFolder tests matching files to restore handlers by extension
+func TestHandlerFolder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	docs := filepath.Join(t.TempDir(), "Docs")
+	config := &Config{RestoreHandlers: map[string]RestoreHandler{
+		"url":   {To: "~/Bookmarks"},
+		".PDF":  {To: docs},
+		".docx": {To: "Documents"},
+		".txt":  {},
+	}}
+
+	for name, want := range map[string]string{
+		"Site.url":    filepath.Join(home, "Bookmarks"),
+		"Report.pdf":  docs,
+		"Letter.DOCX": filepath.Join(home, "Documents"),
+		"notes.txt":   "",
+		"Steam.lnk":   "",
+		"README":      "",
+	} {
+		folder, ok, err := config.handlerFolder(name)
+		if err != nil {
+			t.Fatalf("handlerFolder(%q) returned error: %v", name, err)
+		}
+		if ok != (want != "") || folder != want {
+			t.Errorf("handlerFolder(%q) = %q, %v; want %q", name, folder, ok, want)
+		}
+	}
+
+	var none *Config
+	if _, ok, _ := none.handlerFolder("Site.url"); ok {
+		t.Error("Expected no handler without a config")
+	}
+}
+
+// TestRestoreShortcutWithHandler tests that a handled file is restored to its folder, created if needed
+func TestRestoreShortcutWithHandler(t *testing.T) {
+	source := t.TempDir()
+	bookmarks := filepath.Join(t.TempDir(), "Bookmarks")
+	if err := os.WriteFile(filepath.Join(source, "Site.url"), []byte("[InternetShortcut]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{RestoreHandlers: map[string]RestoreHandler{".url": {To: bookmarks}}}
+
+	result, err := config.restoreShortcut("Site.url", source, conflictFail)
+	if err != nil {
+		t.Fatalf("restoreShortcut() returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bookmarks, "Site.url")); err != nil {
+		t.Errorf("Expected the file in the handler's folder: %v", err)
+	}
+	if result.Folder != bookmarks || !strings.Contains(result.describe("Site.url"), bookmarks) {
+		t.Errorf("Unexpected result: %+v (%s)", result, result.describe("Site.url"))
+	}
+
+	// The handler's folder takes the conflict policy like the desktop does
+	if err := os.WriteFile(filepath.Join(source, "Site.url"), []byte("again"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.restoreShortcut("Site.url", source, conflictFail); err == nil || !strings.Contains(err.Error(), bookmarks) {
+		t.Errorf("Expected a conflict in %s, got %v", bookmarks, err)
+	}
+	result, err = config.restoreShortcut("Site.url", source, conflictRename)
+	if err != nil || result.Name != "Site (2).url" {
+		t.Errorf("restoreShortcut() with rename = %+v, %v", result, err)
+	}
+}
+
+// TestWithRestoreHandlers tests that planned restores of handled files point at their folders
+func TestWithRestoreHandlers(t *testing.T) {
+	bookmarks := filepath.Join(t.TempDir(), "Bookmarks")
+	config := &Config{RestoreHandlers: map[string]RestoreHandler{".url": {To: bookmarks}}}
+	moves, err := config.withRestoreHandlers([]fileMove{
+		{Name: "Site.url", From: "hidden", To: "desktop"},
+		{Name: "Steam.lnk", From: "hidden", To: "desktop"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves[0].To != bookmarks || moves[1].To != "desktop" {
+		t.Errorf("Unexpected moves: %+v", moves)
+	}
+
+	config.RestoreHandlers[".url"] = RestoreHandler{To: "~someone/Bookmarks"}
+	if _, err := config.withRestoreHandlers([]fileMove{{Name: "Site.url"}}); err == nil || !strings.Contains(err.Error(), "restore_handlers..url") {
+		t.Errorf("Expected an error naming the handler, got %v", err)
+	}
+}
+
+// TestLintRestoreHandlers tests the warnings about handlers that can't work
+func TestLintRestoreHandlers(t *testing.T) {
+	config := &Config{RestoreHandlers: map[string]RestoreHandler{
+		".url":     {To: "~/Bookmarks"},
+		"URL":      {To: "~/Links"},
+		".pdf":     {},
+		"*.doc":    {To: "~/Documents"},
+		".tar.gz":  {To: "~/Archives"},
+		".txt":     {To: "~other/Notes"},
+		".md":      {To: "~/Notes"},
+		"markdown": {To: "Notes"},
+	}}
+	warnings := strings.Join(config.lintRestoreHandlers(), "\n")
+	for _, want := range []string{
+		"restore_handlers.URL: .url is already handled by restore_handlers..url",
+		"restore_handlers..pdf: no folder",
+		"restore_handlers.*.doc: expected a file extension",
+		"restore_handlers..tar.gz: expected a file extension",
+		"restore_handlers..txt: destination",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected a warning %q, got:\n%s", want, warnings)
+		}
+	}
+	if strings.Contains(warnings, ".md") || strings.Contains(warnings, "markdown") {
+		t.Errorf("Expected no warning for valid handlers, got:\n%s", warnings)
+	}
+}
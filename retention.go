@@ -0,0 +1,218 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archivedFile is a file in one of the dated folders of the archive
+type archivedFile struct {
+	Path     string
+	Name     string
+	Category string
+	Archived time.Time // Day of the sweep that archived it
+	Expires  time.Time // When it is deleted; zero if kept forever
+}
+
+// retentionDays returns how many days archived files of a category are kept, or 0 for forever
+func (c ArchiveConfig) retentionDays(category string) int {
+	for key, days := range c.Retention {
+		if strings.EqualFold(key, category) && days > 0 {
+			return days
+		}
+	}
+	return 0
+}
+
+// archivedFiles returns the files in the dated folders under root with their categories and
+// expiry dates, oldest first; other folders under root are left out
+func archivedFiles(root string, config ArchiveConfig, categories *CategoriesConfig) ([]archivedFile, error) {
+	folders, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	var files []archivedFile
+	for _, folder := range folders {
+		archived, err := time.ParseInLocation(archiveDateLayout, folder.Name(), time.Local)
+		if !folder.IsDir() || err != nil {
+			continue
+		}
+		dir := filepath.Join(root, folder.Name())
+		names, err := folderEntries(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			file := archivedFile{
+				Path:     path,
+				Name:     name,
+				Category: string(categorizeFile(path, categories)),
+				Archived: archived,
+			}
+			if days := config.retentionDays(file.Category); days > 0 {
+				file.Expires = archived.AddDate(0, 0, days)
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// expiringFiles returns the files whose retention ends by t, soonest first
+func expiringFiles(files []archivedFile, t time.Time) []archivedFile {
+	var expiring []archivedFile
+	for _, file := range files {
+		if !file.Expires.IsZero() && !file.Expires.After(t) {
+			expiring = append(expiring, file)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool { return expiring[i].Expires.Before(expiring[j].Expires) })
+	return expiring
+}
+
+// purgeArchive deletes the archived files past their retention at now, and the dated folders
+// left empty; the files deleted are returned
+func purgeArchive(config ArchiveConfig, categories *CategoriesConfig, now time.Time, dryRun bool) ([]archivedFile, error) {
+	root, err := config.root()
+	if err != nil {
+		return nil, err
+	}
+	files, err := archivedFiles(root, config, categories)
+	if err != nil {
+		return nil, err
+	}
+	var purged []archivedFile
+	for _, file := range expiringFiles(files, now) {
+		if dryRun {
+			fmt.Printf("[DRY RUN] Would delete: %s (%s, archived %s)\n", file.Path, file.Category, file.Archived.Format(archiveDateLayout))
+			purged = append(purged, file)
+			continue
+		}
+		if err := removeFile(file.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting %s: %v\n", file.Path, err)
+			continue
+		}
+		fmt.Printf("%sDeleted: %s\n", glyph("✓ "), file.Path)
+		purged = append(purged, file)
+	}
+	if !dryRun {
+		for _, file := range purged {
+			// Fails, as it should, while the folder still holds files
+			removeFile(filepath.Dir(file.Path))
+		}
+	}
+	return purged, nil
+}
+
+// purgeDue reports whether the daily purge is due at now, given the last one
+func purgeDue(last, now time.Time) bool {
+	return last.Before(at(now, 0))
+}
+
+// purgeSummary describes a purge for the notification sent after it
+func purgeSummary(purged []archivedFile) string {
+	return fmt.Sprintf("Deleted %d archived file(s) past their retention", len(purged))
+}
+
+// purgeArchiveNow purges the archive and records it, warning on failure; used by the daemon
+func purgeArchiveNow(config *Config, now time.Time) []archivedFile {
+	if err := guardWrite("delete archived files"); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	// Categories come from categories.yml in the working directory, like a mode's categories
+	categories, err := loadCategoriesConfig("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	purged, err := purgeArchive(config.Archive, categories, now, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error purging the archive: %v\n", err)
+	}
+	if err := updateArchiveState(func(state *archiveState) { state.LastPurge = now }); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return purged
+}
+
+// runArchivePurge handles "archive purge", which deletes archived files past their retention now
+func runArchivePurge(config *Config, categories *CategoriesConfig, dryRun bool) {
+	if len(config.Archive.Retention) == 0 {
+		fmt.Println("No archive.retention is configured; archived files are kept forever.")
+		return
+	}
+	if !dryRun {
+		if err := guardWrite("delete archived files"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	now := time.Now()
+	purged, err := purgeArchive(config.Archive, categories, now, dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	switch {
+	case len(purged) == 0:
+		fmt.Println("No archived files are past their retention.")
+	case dryRun:
+		fmt.Println("(Dry run - no files were actually deleted)")
+	default:
+		fmt.Println(purgeSummary(purged))
+		if err := updateArchiveState(func(state *archiveState) { state.LastPurge = now }); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// runArchiveReport handles "archive report", which lists the archived files deleted in the
+// next days days, and how long each category is kept
+func runArchiveReport(config *Config, categories *CategoriesConfig, days int) {
+	if days <= 0 {
+		days = 7
+	}
+	root, err := config.Archive.root()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, err := archivedFiles(root, config.Archive, categories)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Archive: %s (%d file(s))\n", root, len(files))
+	keys := make([]string, 0, len(config.Archive.Retention))
+	for key := range config.Archive.Retention {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if days := config.Archive.retentionDays(key); days > 0 {
+			fmt.Printf("  %s: kept %d day(s)\n", key, days)
+		}
+	}
+	fmt.Println("  Everything else: kept forever")
+
+	expiring := expiringFiles(files, at(time.Now(), 0).AddDate(0, 0, days+1))
+	if len(expiring) == 0 {
+		fmt.Printf("\nNothing is deleted in the next %d day(s).\n", days)
+		return
+	}
+	fmt.Printf("\nDeleted in the next %d day(s):\n", days)
+	for _, file := range expiring {
+		fmt.Printf("  %s  %s (%s, archived %s)\n", file.Expires.Format("Mon 2006-01-02"), file.Name, file.Category, file.Archived.Format(archiveDateLayout))
+	}
+}
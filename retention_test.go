@@ -0,0 +1,85 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPurgeArchive tests that archived files are deleted once their category's retention ends,
+// and that categories without one are kept forever
+func TestPurgeArchive(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	categories := &CategoriesConfig{
+		Categories: map[string]CategoryConfig{
+			"installers": {Keywords: []string{"setup", ".msi"}},
+			"documents":  {Keywords: []string{".pdf"}},
+		},
+		CategoryOrder: []string{"installers", "documents"},
+	}
+	config := ArchiveConfig{Retention: map[string]int{"Installers": 7}}
+	root := filepath.Join(dir, "Archive")
+	for folder, names := range map[string][]string{
+		"2026-10-04":  {"setup.exe", "report.pdf"},
+		"2026-10-11":  {"tool.msi"},
+		"not-a-sweep": {"setup-old.exe"},
+	} {
+		os.MkdirAll(filepath.Join(root, folder), 0755)
+		for _, name := range names {
+			os.WriteFile(filepath.Join(root, folder, name), nil, 0644)
+		}
+	}
+
+	files, err := archivedFiles(root, config, categories)
+	if err != nil || len(files) != 3 {
+		t.Fatalf("Expected three archived files, got %+v (err %v)", files, err)
+	}
+	soon := expiringFiles(files, scheduleTime(19, 0, 0))
+	if len(soon) != 2 || soon[0].Name != "setup.exe" || soon[1].Name != "tool.msi" || !soon[1].Expires.Equal(scheduleTime(18, 0, 0)) {
+		t.Errorf("Expected both installers to expire by the 19th, got %+v", soon)
+	}
+
+	purged, err := purgeArchive(config, categories, scheduleTime(12, 9, 0), false)
+	if err != nil || len(purged) != 1 || purged[0].Name != "setup.exe" {
+		t.Fatalf("Expected setup.exe purged, got %+v (err %v)", purged, err)
+	}
+	for path, kept := range map[string]bool{
+		filepath.Join(root, "2026-10-04", "setup.exe"):      false,
+		filepath.Join(root, "2026-10-04", "report.pdf"):     true, // documents are kept forever
+		filepath.Join(root, "2026-10-11", "tool.msi"):       true, // a week isn't up yet
+		filepath.Join(root, "not-a-sweep", "setup-old.exe"): true,
+	} {
+		if _, err := os.Stat(path); (err == nil) != kept {
+			t.Errorf("Expected %s kept=%v, got %v", path, kept, err)
+		}
+	}
+
+	// The folder of a sweep is removed once it is empty
+	if _, err := purgeArchive(config, categories, scheduleTime(18, 0, 0), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2026-10-11")); !os.IsNotExist(err) {
+		t.Errorf("Expected the emptied folder to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2026-10-04")); err != nil {
+		t.Errorf("Expected the folder still holding report.pdf to be kept, got %v", err)
+	}
+}
+
+// TestPurgeDue tests that the purge runs once a day
+func TestPurgeDue(t *testing.T) {
+	if !purgeDue(time.Time{}, scheduleTime(12, 9, 0)) {
+		t.Error("Expected a first purge to be due")
+	}
+	if purgeDue(scheduleTime(12, 0, 5), scheduleTime(12, 23, 0)) {
+		t.Error("Expected no second purge the same day")
+	}
+	if !purgeDue(scheduleTime(11, 23, 0), scheduleTime(12, 0, 1)) {
+		t.Error("Expected a purge due the next day")
+	}
+}
@@ -0,0 +1,176 @@
+package focusmode
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy represents how an operation that fails for a passing reason is tried again
+type RetryPolicy struct {
+	MaxAttempts int `yaml:"max_attempts" doc:"Attempts in all, including the first; 1 disables retrying" default:"4"`
+	BaseDelayMS int `yaml:"base_delay_ms" doc:"Pause before the second attempt in milliseconds, doubled before each further one (negative for none)" default:"1000"`
+	Jitter      int `yaml:"jitter" doc:"How much each pause may vary either way, in percent, so clients don't retry in step (negative for none)" default:"20"`
+}
+
+// RetryConfig represents the retry policies of the operations that can fail for a passing reason
+type RetryConfig struct {
+	Files   RetryPolicy `yaml:"files" doc:"Moving files that are busy or on a network share that is unreachable"`
+	Network RetryPolicy `yaml:"network" doc:"Requests to calendars, Slack, GitHub and WakaTime that time out, are rate limited or hit a server error"`
+}
+
+const (
+	defaultRetryAttempts    = 4
+	defaultRetryBaseDelayMS = 1000
+	defaultRetryJitter      = 20
+
+	// maxRetryAfter caps how long a server's Retry-After may hold up a command
+	maxRetryAfter = 30 * time.Second
+)
+
+// retrySleep pauses between attempts; replaced in tests
+var retrySleep = time.Sleep
+
+// retry returns the config's retry policies; a nil config has the defaults
+func (c *Config) retry() RetryConfig {
+	if c == nil {
+		return RetryConfig{}
+	}
+	return c.Retry
+}
+
+// attempts returns how many times an operation is tried in all
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+// delay returns the pause before the given retry, 1 for the second attempt, with jitter applied
+func (p RetryPolicy) delay(retry int) time.Duration {
+	base := p.BaseDelayMS
+	if base == 0 {
+		base = defaultRetryBaseDelayMS
+	}
+	if base < 0 || retry < 1 {
+		return 0
+	}
+	delay := time.Duration(base) * time.Millisecond << (retry - 1)
+	jitter := p.Jitter
+	if jitter == 0 {
+		jitter = defaultRetryJitter
+	}
+	if jitter > 0 {
+		spread := float64(delay) * float64(min(jitter, 100)) / 100
+		delay += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// do runs op until it succeeds, fails with an error retryable doesn't accept, or runs out of attempts
+// The last error is returned
+func (p RetryPolicy) do(op func() error, retryable func(error) bool) error {
+	err := op()
+	for retry := 1; retry < p.attempts() && err != nil && retryable(err); retry++ {
+		retrySleep(p.delay(retry))
+		err = op()
+	}
+	return err
+}
+
+// fileBusy reports whether err means another program has the file open, as antivirus
+// scanners and sync clients briefly do on Windows
+func fileBusy(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return errno == 32 || errno == 33 // ERROR_SHARING_VIOLATION, ERROR_LOCK_VIOLATION
+	}
+	return errno == syscall.EBUSY || errno == syscall.ETXTBSY
+}
+
+// withFileRetry runs a file operation under policy, trying again while the file is busy or the
+// share under it is unreachable; a share still unreachable afterwards wraps errShareOffline
+func withFileRetry(policy RetryPolicy, op func() error) error {
+	return shareOffline(policy.do(op, func(err error) bool {
+		return fileBusy(err) || shareUnavailable(err) && !errors.Is(err, errShareOffline)
+	}))
+}
+
+// idempotentMethod reports whether a request can be sent twice without doing its work twice
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryableStatus reports whether a response means the request can be sent again
+// Rate limits and 503s mean the request wasn't handled; other server errors only for idempotent requests
+func retryableStatus(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotentMethod(method)
+	}
+	return false
+}
+
+// retryableRequestError reports whether a request that got no response can be sent again:
+// always when the connection was never made, otherwise only when it is idempotent
+func retryableRequestError(method string, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var netErr net.Error
+	return idempotentMethod(method) && errors.As(err, &netErr)
+}
+
+// retryAfter returns the pause a response asks for with Retry-After in seconds, capped at maxRetryAfter
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+}
+
+// doWithRetry sends req with client under policy, sending it again when it fails for a passing
+// reason; the last response or error is returned for the caller to handle
+// Requests with a body must be made with http.NewRequest from a bytes or strings reader so it can be resent
+func doWithRetry(policy RetryPolicy, client *http.Client, req *http.Request) (*http.Response, error) {
+	for retry := 1; ; retry++ {
+		resp, err := client.Do(req)
+		last := retry >= policy.attempts() || (req.Body != nil && req.GetBody == nil)
+		if err != nil {
+			if last || !retryableRequestError(req.Method, err) {
+				return nil, err
+			}
+			retrySleep(policy.delay(retry))
+		} else {
+			if last || !retryableStatus(req.Method, resp.StatusCode) {
+				return resp, nil
+			}
+			resp.Body.Close()
+			retrySleep(max(policy.delay(retry), retryAfter(resp)))
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
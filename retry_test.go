@@ -0,0 +1,143 @@
+package focusmode
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRetryPolicyDelay tests the backoff, with and without jitter
+func TestRetryPolicyDelay(t *testing.T) {
+	exact := RetryPolicy{BaseDelayMS: 100, Jitter: -1}
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond} {
+		if got := exact.delay(retry); got != want {
+			t.Errorf("delay(%d) = %s, want %s", retry, got, want)
+		}
+	}
+	if got := (RetryPolicy{BaseDelayMS: -1}).delay(2); got != 0 {
+		t.Errorf("Expected no pause with a negative base delay, got %s", got)
+	}
+	for i := 0; i < 20; i++ {
+		if got := (RetryPolicy{}).delay(1); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("Expected the default 1s delay within 20%%, got %s", got)
+		}
+	}
+}
+
+// TestRetryPolicyDo tests that only retryable errors are retried, up to the attempts allowed
+func TestRetryPolicyDo(t *testing.T) {
+	defer func(sleep func(time.Duration)) { retrySleep = sleep }(retrySleep)
+	var pauses []time.Duration
+	retrySleep = func(d time.Duration) { pauses = append(pauses, d) }
+	transient := errors.New("busy")
+	retryable := func(err error) bool { return err == transient }
+
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 3, Jitter: -1}.do(func() error { calls++; return transient }, retryable)
+	if err != transient || calls != 3 || len(pauses) != 2 || pauses[1] != 2*time.Second {
+		t.Errorf("Expected 3 attempts with pauses of 1s and 2s, got %v after %d (pauses %v)", err, calls, pauses)
+	}
+
+	calls = 0
+	err = RetryPolicy{}.do(func() error { calls++; return os.ErrPermission }, retryable)
+	if err != os.ErrPermission || calls != 1 {
+		t.Errorf("Expected no retry of a permanent error, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = RetryPolicy{MaxAttempts: 1}.do(func() error { calls++; return transient }, retryable)
+	if calls != 1 {
+		t.Errorf("Expected max_attempts 1 to disable retrying, got %d attempts", calls)
+	}
+}
+
+// TestWithFileRetry tests that moves of busy files are retried
+func TestWithFileRetry(t *testing.T) {
+	defer func(sleep func(time.Duration)) { retrySleep = sleep }(retrySleep)
+	retrySleep = func(time.Duration) {}
+	busy := &os.LinkError{Op: "rename", Err: syscall.EBUSY}
+	if runtime.GOOS == "windows" {
+		busy.Err = syscall.Errno(32) // ERROR_SHARING_VIOLATION
+	}
+
+	calls := 0
+	err := withFileRetry(RetryPolicy{}, func() error {
+		if calls++; calls < 2 {
+			return busy
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Expected success on the second attempt, got %v after %d", err, calls)
+	}
+	if fileBusy(os.ErrNotExist) {
+		t.Error("Expected a missing file not to count as busy")
+	}
+}
+
+// TestDoWithRetry tests which responses are retried, and that bodies are sent again
+func TestDoWithRetry(t *testing.T) {
+	defer func(sleep func(time.Duration)) { retrySleep = sleep }(retrySleep)
+	var pauses []time.Duration
+	retrySleep = func(d time.Duration) { pauses = append(pauses, d) }
+
+	var statuses []int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	// A rate-limited POST is sent again with its body, after the pause the server asks for
+	statuses = []int{http.StatusTooManyRequests, http.StatusOK}
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("event"))
+	resp, err := doWithRetry(RetryPolicy{}, server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected success after a retry, got %v (err %v)", resp, err)
+	}
+	resp.Body.Close()
+	if strings.Join(bodies, ",") != "event,event" || len(pauses) != 1 || pauses[0] != 7*time.Second {
+		t.Errorf("Expected the body sent twice after 7s, got %q (pauses %v)", bodies, pauses)
+	}
+
+	// A POST that hit a server error may have been handled, so it isn't sent again
+	statuses, bodies = []int{http.StatusInternalServerError, http.StatusOK}, nil
+	req, _ = http.NewRequest(http.MethodPost, server.URL, strings.NewReader("event"))
+	resp, err = doWithRetry(RetryPolicy{}, server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusInternalServerError || len(bodies) != 1 {
+		t.Errorf("Expected the error returned without a retry, got %v after %d requests", resp, len(bodies))
+	}
+	resp.Body.Close()
+
+	// A GET is retried until the attempts run out, returning the last response
+	statuses, bodies = []int{502, 502, 502, 502, 200}, nil
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = doWithRetry(RetryPolicy{}, server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || len(bodies) != defaultRetryAttempts {
+		t.Errorf("Expected %d attempts, got %d ending in %v", defaultRetryAttempts, len(bodies), resp)
+	}
+	resp.Body.Close()
+
+	// The policy passed in sets the attempts, not the defaults
+	statuses, bodies = []int{502, 502, 200}, nil
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = doWithRetry(RetryPolicy{MaxAttempts: 2}, server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || len(bodies) != 2 {
+		t.Errorf("Expected 2 attempts, got %d ending in %v", len(bodies), resp)
+	}
+	resp.Body.Close()
+}
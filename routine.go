@@ -0,0 +1,257 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RoutineStep is one step of a routine: a timed session in a mode, or a break
+type RoutineStep struct {
+	Mode     string `yaml:"mode" doc:"Mode to apply (uses default mode if empty)"`
+	Duration int    `yaml:"duration" doc:"Step length in minutes" example:"50"`
+	Break    bool   `yaml:"break" doc:"Whether this step is a break (no mode is applied)" default:"false"`
+}
+
+// getRoutine returns the steps of a named routine after validating them
+func (c *Config) getRoutine(name string) ([]RoutineStep, error) {
+	steps, exists := c.Routines[name]
+	if !exists {
+		return nil, fmt.Errorf("routine '%s' not found in configuration. Available routines: %v", name, c.getAvailableRoutines())
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("routine '%s' has no steps", name)
+	}
+
+	resolved := make([]RoutineStep, len(steps))
+	for i, step := range steps {
+		if step.Duration <= 0 {
+			return nil, fmt.Errorf("routine '%s' step %d: duration must be positive, got: %d minutes", name, i+1, step.Duration)
+		}
+		if !step.Break {
+			if step.Mode == "" {
+				step.Mode = c.DefaultMode
+			}
+			if _, err := c.getModeConfig(step.Mode); err != nil {
+				return nil, fmt.Errorf("routine '%s' step %d: %w", name, i+1, err)
+			}
+			if err := c.checkSessionDuration(step.Duration); err != nil {
+				return nil, fmt.Errorf("routine '%s' step %d: %w", name, i+1, err)
+			}
+		}
+		resolved[i] = step
+	}
+	return resolved, nil
+}
+
+// getAvailableRoutines returns the sorted list of routine names
+func (c *Config) getAvailableRoutines() []string {
+	names := make([]string, 0, len(c.Routines))
+	for name := range c.Routines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describe returns a short human-readable description of the step
+func (s RoutineStep) describe() string {
+	if s.Break {
+		return fmt.Sprintf("break %dm", s.Duration)
+	}
+	return fmt.Sprintf("%s %dm", s.Mode, s.Duration)
+}
+
+// newRoutineSession creates the session for the step at index i of a routine, with its hooks attached
+func newRoutineSession(config *Config, name string, i int, step RoutineStep, opts sessionOptions) (*FocusSession, error) {
+	session := &FocusSession{
+		Duration:    time.Duration(step.Duration) * time.Minute,
+		Mode:        step.Mode,
+		StartTime:   time.Now(),
+		AutoRestore: true,
+		Config:      config,
+		State:       StateRunning,
+		Break:       step.Break,
+	}
+
+	opts.routine = name
+	opts.routineStep = i + 1
+	if err := attachSessionHooks(session, config, opts); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// runRoutine executes each step of a routine in order, applying and restoring modes between steps
+// The routine stops at the first step that is interrupted
+func runRoutine(config *Config, name string, steps []RoutineStep, opts sessionOptions) error {
+	commands := stdinLines()
+	start := time.Now()
+	var focused, paused time.Duration
+	completed := true
+
+	for i, step := range steps {
+		fmt.Printf("\n%sStep %d/%d: %s\n", glyph("▶ "), i+1, len(steps), step.describe())
+
+		session, err := newRoutineSession(config, name, i, step, opts)
+		if err != nil {
+			return err
+		}
+
+		if err := session.run(commands); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		if !session.Break {
+			focused += session.elapsed()
+		}
+		paused += session.PausedTotal
+
+		if session.State != StateCompleted {
+			completed = false
+			fmt.Printf("Routine '%s' stopped at step %d/%d\n", name, i+1, len(steps))
+			break
+		}
+	}
+
+	if completed {
+		fmt.Printf("\n%sRoutine '%s' complete\n", glyph("🏁 "), name)
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		return nil
+	}
+	var planned time.Duration
+	for _, step := range steps {
+		planned += time.Duration(step.Duration) * time.Minute
+	}
+	record := SessionRecord{
+		Kind:           RecordRoutine,
+		Routine:        name,
+		StartTime:      start,
+		EndTime:        time.Now(),
+		PlannedSeconds: int64(planned.Seconds()),
+		FocusedSeconds: int64(focused.Seconds()),
+		PausedSeconds:  int64(paused.Seconds()),
+		Completed:      completed,
+	}
+	if err := appendHistory(path, record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record routine history: %v\n", err)
+	}
+	return nil
+}
+
+// runRoutineCommand handles the "routine" subcommand
+func runRoutineCommand(args []string) {
+	if len(args) == 0 {
+		printRoutineUsage()
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("routine "+args[0], flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	noAmbient := flags.Bool("no-ambient", false, "Disable ambient sound for this routine")
+	noTTS := flags.Bool("no-tts", false, "Disable spoken announcements for this routine")
+	silent := flags.Bool("silent", false, "Don't play the chime for this routine")
+	dryRun := flags.Bool("dry-run", false, "Show what each step would do without doing it")
+	flags.Usage = func() {
+		printRoutineUsage()
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		flags.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "list":
+		flags.Parse(args[1:])
+		config, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		listRoutines(config)
+	case "start":
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			printRoutineUsage()
+			os.Exit(1)
+		}
+		name := flags.Arg(0)
+
+		config, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		steps, err := config.getRoutine(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts := sessionOptions{noAmbient: *noAmbient, noTTS: *noTTS, silent: *silent}
+		if *dryRun {
+			if err := printRoutineDryRun(os.Stdout, config, name, steps, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(dryRunFooter)
+			return
+		}
+
+		var modes []string
+		for _, step := range steps {
+			if !step.Break {
+				modes = append(modes, step.Mode)
+			}
+		}
+		if err := config.requireGuardianPIN(modes, stdinLines(), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Starting routine '%s' (%d steps)\n", name, len(steps))
+		fmt.Println("Commands: p = pause/resume, d = duck audio, q = stop routine")
+		err = runRoutine(config, name, steps, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown routine command: %s\n\n", args[0])
+		printRoutineUsage()
+		os.Exit(1)
+	}
+}
+
+// printRoutineUsage prints help for the "routine" subcommand
+func printRoutineUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  focusmode routine list [options]")
+	fmt.Fprintln(os.Stderr, "  focusmode routine start [options] <name>")
+}
+
+// listRoutines prints the configured routines and their steps
+func listRoutines(config *Config) {
+	names := config.getAvailableRoutines()
+	if len(names) == 0 {
+		fmt.Println("No routines configured.")
+		return
+	}
+
+	fmt.Println("Available routines:")
+	for _, name := range names {
+		var total int
+		var parts []string
+		for _, step := range config.Routines[name] {
+			if !step.Break && step.Mode == "" {
+				step.Mode = config.DefaultMode
+			}
+			parts = append(parts, step.describe())
+			total += step.Duration
+		}
+		fmt.Printf("  %s (%s): %s\n", name, formatDuration(time.Duration(total)*time.Minute), strings.Join(parts, glyphOr(" → ", ", then ")))
+	}
+}
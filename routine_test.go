@@ -0,0 +1,56 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestConfigGetRoutine tests routine lookup and validation
+func TestConfigGetRoutine(t *testing.T) {
+	config := &Config{
+		Modes: map[string]ModeConfig{
+			"focusmode": {Destination: "Focus"},
+			"gamemode":  {Destination: "Game"},
+		},
+		DefaultMode: "focusmode",
+		Routines: map[string][]RoutineStep{
+			"deepworkday": {
+				{Duration: 50},
+				{Break: true, Duration: 10},
+				{Mode: "focusmode", Duration: 50},
+				{Mode: "gamemode", Duration: 60},
+			},
+			"badmode":     {{Mode: "nonexistent", Duration: 25}},
+			"badduration": {{Mode: "focusmode", Duration: 0}},
+			"empty":       {},
+		},
+	}
+
+	steps, err := config.getRoutine("deepworkday")
+	if err != nil {
+		t.Fatalf("getRoutine() returned error: %v", err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("Expected 4 steps, got %d", len(steps))
+	}
+	if steps[0].Mode != "focusmode" {
+		t.Errorf("Expected default mode for first step, got %q", steps[0].Mode)
+	}
+	if !steps[1].Break || steps[1].Mode != "" {
+		t.Errorf("Expected break step without mode, got %+v", steps[1])
+	}
+	if steps[3].describe() != "gamemode 60m" || steps[1].describe() != "break 10m" {
+		t.Errorf("Unexpected step descriptions: %q, %q", steps[3].describe(), steps[1].describe())
+	}
+
+	for _, name := range []string{"badmode", "badduration", "empty", "missing"} {
+		if _, err := config.getRoutine(name); err == nil {
+			t.Errorf("Expected error for routine %q", name)
+		}
+	}
+
+	_, err = config.getRoutine("missing")
+	if err == nil || !strings.Contains(err.Error(), "deepworkday") {
+		t.Errorf("Expected error listing available routines, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package focusmode
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// builtinShortcutCorpus is the labeled corpus shipped with FocusMode
+//
+//go:embed shortcut_corpus.tsv
+var builtinShortcutCorpus string
+
+// labeledShortcut is a shortcut name and the category a person would file it under
+type labeledShortcut struct {
+	Name     string
+	Category string
+}
+
+// parseShortcutCorpus reads a corpus of "category<TAB>name" lines; blank lines and # comments are skipped
+func parseShortcutCorpus(data string) ([]labeledShortcut, error) {
+	var corpus []labeledShortcut
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		category, name, ok := strings.Cut(line, "\t")
+		if !ok || strings.TrimSpace(category) == "" || name == "" {
+			return nil, fmt.Errorf("line %d: expected a category, a tab and a name, got %q", i+1, line)
+		}
+		corpus = append(corpus, labeledShortcut{Name: name, Category: strings.TrimSpace(category)})
+	}
+	if len(corpus) == 0 {
+		return nil, fmt.Errorf("corpus has no labeled shortcuts")
+	}
+	return corpus, nil
+}
+
+// categoryScore counts how one category fared against the corpus
+type categoryScore struct {
+	Category  string
+	Labeled   int // Shortcuts labeled with the category
+	Predicted int // Shortcuts the keywords put in the category
+	Correct   int // Shortcuts both labeled with and put in the category
+}
+
+// precision returns the share of shortcuts put in the category that belong there
+// ok is false when nothing was put in it
+func (s categoryScore) precision() (float64, bool) {
+	if s.Predicted == 0 {
+		return 0, false
+	}
+	return float64(s.Correct) / float64(s.Predicted), true
+}
+
+// recall returns the share of shortcuts belonging to the category that were put there
+// ok is false when the corpus has none
+func (s categoryScore) recall() (float64, bool) {
+	if s.Labeled == 0 {
+		return 0, false
+	}
+	return float64(s.Correct) / float64(s.Labeled), true
+}
+
+// categoryMiss is a corpus shortcut the keywords put in the wrong category
+type categoryMiss struct {
+	labeledShortcut
+	Predicted ShortcutCategory
+}
+
+// accuracyReport is the result of categorizing a corpus
+type accuracyReport struct {
+	Total      int
+	Correct    int
+	Categories []categoryScore // In category order, with "other" last
+	Misses     []categoryMiss
+}
+
+// scoreCategories categorizes every corpus shortcut and compares the result with its label
+func scoreCategories(corpus []labeledShortcut, config *CategoriesConfig) accuracyReport {
+	scores := make(map[string]*categoryScore)
+	score := func(category string) *categoryScore {
+		if scores[category] == nil {
+			scores[category] = &categoryScore{Category: category}
+		}
+		return scores[category]
+	}
+
+	report := accuracyReport{Total: len(corpus)}
+	for _, item := range corpus {
+		predicted := categorizeShortcut(item.Name, config)
+		score(item.Category).Labeled++
+		score(string(predicted)).Predicted++
+		if string(predicted) == item.Category {
+			score(item.Category).Correct++
+			report.Correct++
+		} else {
+			report.Misses = append(report.Misses, categoryMiss{labeledShortcut: item, Predicted: predicted})
+		}
+	}
+
+	// Categories in matching order, then any only the corpus knows, then other
+	seen := make(map[string]bool)
+	for _, category := range append(append([]string(nil), config.CategoryOrder...), sortedKeys(scores)...) {
+		if seen[category] || category == string(CategoryOther) || scores[category] == nil {
+			continue
+		}
+		seen[category] = true
+		report.Categories = append(report.Categories, *scores[category])
+	}
+	if other := scores[string(CategoryOther)]; other != nil {
+		report.Categories = append(report.Categories, *other)
+	}
+	return report
+}
+
+// sortedKeys returns the keys of a map of category scores in order
+func sortedKeys(scores map[string]*categoryScore) []string {
+	keys := make([]string, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatShare formats a share as a percentage, or "-" when there is nothing to measure
+func formatShare(share float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", share*100)
+}
+
+// printAccuracyReport writes the scores per category, and the misses if asked
+func printAccuracyReport(w io.Writer, report accuracyReport, showMisses bool) {
+	fmt.Fprintf(w, "Accuracy: %d of %d shortcuts (%s)\n\n", report.Correct, report.Total,
+		formatShare(float64(report.Correct)/float64(report.Total), report.Total > 0))
+	fmt.Fprintf(w, "  %-14s %9s %7s %9s %6s\n", "Category", "Precision", "Recall", "Predicted", "Labeled")
+	for _, s := range report.Categories {
+		precision, hasPrecision := s.precision()
+		recall, hasRecall := s.recall()
+		fmt.Fprintf(w, "  %-14s %9s %7s %9d %6d\n", s.Category,
+			formatShare(precision, hasPrecision), formatShare(recall, hasRecall), s.Predicted, s.Labeled)
+	}
+
+	if !showMisses {
+		if len(report.Misses) > 0 {
+			fmt.Fprintf(w, "\n%d miss(es); run with -misses to list them\n", len(report.Misses))
+		}
+		return
+	}
+	if len(report.Misses) > 0 {
+		fmt.Fprintln(w, "\nMisses:")
+	}
+	for _, miss := range report.Misses {
+		fmt.Fprintf(w, "  %-40s labeled %s, categorized %s\n", miss.Name, miss.Category, miss.Predicted)
+	}
+}
+
+// runRulesCommand handles the "rules" subcommand
+func runRulesCommand(args []string) {
+	if len(args) == 0 || args[0] != "accuracy" {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode rules accuracy [-categories categories.yml] [-corpus file.tsv] [-misses]")
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("rules accuracy", flag.ExitOnError)
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	corpusPath := flags.String("corpus", "", "Labeled corpus of category<TAB>name lines (default: the built-in corpus)")
+	showMisses := flags.Bool("misses", false, "List the shortcuts categorized differently from their label")
+	flags.Parse(args[1:])
+
+	config, err := loadCategoriesConfig(*categoriesPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading categories: %v\n", err)
+		os.Exit(1)
+	}
+
+	data := builtinShortcutCorpus
+	if *corpusPath != "" {
+		raw, err := os.ReadFile(*corpusPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading corpus: %v\n", err)
+			os.Exit(1)
+		}
+		data = string(raw)
+	}
+	corpus, err := parseShortcutCorpus(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing corpus: %v\n", err)
+		os.Exit(1)
+	}
+
+	printAccuracyReport(os.Stdout, scoreCategories(corpus, config), *showMisses)
+}
@@ -0,0 +1,74 @@
+package focusmode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinShortcutCorpus tests that the shipped corpus parses and only uses known categories
+func TestBuiltinShortcutCorpus(t *testing.T) {
+	corpus, err := parseShortcutCorpus(builtinShortcutCorpus)
+	if err != nil {
+		t.Fatalf("parseShortcutCorpus() returned error: %v", err)
+	}
+	config, err := loadCategoriesConfig("categories.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, item := range corpus {
+		if _, ok := config.Categories[item.Category]; !ok && item.Category != string(CategoryOther) {
+			t.Errorf("%s is labeled with unknown category %q", item.Name, item.Category)
+		}
+		if seen[item.Name] {
+			t.Errorf("%s is in the corpus more than once", item.Name)
+		}
+		seen[item.Name] = true
+	}
+}
+
+// TestParseShortcutCorpusInvalid tests that malformed lines are reported with their line number
+func TestParseShortcutCorpusInvalid(t *testing.T) {
+	if _, err := parseShortcutCorpus("# header\ngame\tSteam.lnk\nno tab here\n"); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected an error for line 3, got %v", err)
+	}
+	if _, err := parseShortcutCorpus("# only comments\n"); err == nil {
+		t.Error("Expected an error for an empty corpus")
+	}
+}
+
+// TestScoreCategories tests precision, recall and misses against the default categories
+func TestScoreCategories(t *testing.T) {
+	corpus := []labeledShortcut{
+		{Name: "Steam.lnk", Category: "game"},
+		{Name: "Minecraft.lnk", Category: "game"},
+		{Name: "Game Dev Notes.docx", Category: "work"},
+		{Name: "Calculator.lnk", Category: "other"},
+	}
+	report := scoreCategories(corpus, getDefaultCategoriesConfig())
+	if report.Total != 4 || report.Correct != 2 || len(report.Misses) != 2 {
+		t.Fatalf("Unexpected totals: %+v", report)
+	}
+
+	var game categoryScore
+	for _, s := range report.Categories {
+		if s.Category == "game" {
+			game = s
+		}
+	}
+	precision, _ := game.precision()
+	recall, _ := game.recall()
+	if precision != 0.5 || recall != 0.5 {
+		t.Errorf("Expected game precision and recall of 50%%, got %v and %v", precision, recall)
+	}
+	if last := report.Categories[len(report.Categories)-1]; last.Category != "other" {
+		t.Errorf("Expected other to be listed last, got %s", last.Category)
+	}
+
+	var out bytes.Buffer
+	printAccuracyReport(&out, report, true)
+	if !strings.Contains(out.String(), "Accuracy: 2 of 4 shortcuts (50%)") || !strings.Contains(out.String(), "labeled work, categorized game") {
+		t.Errorf("Unexpected report:\n%s", out.String())
+	}
+}
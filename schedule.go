@@ -0,0 +1,358 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScheduleEntry applies a mode during a window of time on some days of the week
+type ScheduleEntry struct {
+	Mode string   `yaml:"mode" doc:"Mode to apply (uses default mode if empty)"`
+	Days []string `yaml:"days" doc:"Days the window starts on: weekdays, weekends, daily or day names such as mon (every day if empty)" example:"[weekdays]"`
+	From string   `yaml:"from" doc:"Time of day (HH:MM) the mode is applied" example:"'09:00'"`
+	To   string   `yaml:"to,omitempty" doc:"Time of day (HH:MM) the mode is restored; a time before from ends the next day" example:"'17:00'"`
+	For  string   `yaml:"for,omitempty" doc:"How long the mode stays applied instead of to, such as 8h, 1h30m or 90 (minutes); under a day" example:"8h"`
+}
+
+// schedulePollInterval is how often the daemon compares the schedule with the clock
+const schedulePollInterval = 30 * time.Second
+
+// scheduleDayNames maps the day names accepted in schedule days to the days they stand for
+var scheduleDayNames = map[string][]time.Weekday{
+	"daily":    {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+	"sun":      {time.Sunday}, "sunday": {time.Sunday},
+	"mon": {time.Monday}, "monday": {time.Monday},
+	"tue": {time.Tuesday}, "tuesday": {time.Tuesday},
+	"wed": {time.Wednesday}, "wednesday": {time.Wednesday},
+	"thu": {time.Thursday}, "thursday": {time.Thursday},
+	"fri": {time.Friday}, "friday": {time.Friday},
+	"sat": {time.Saturday}, "saturday": {time.Saturday},
+}
+
+// scheduleWindow is a schedule entry with its days and times parsed
+type scheduleWindow struct {
+	Mode string
+	Days [7]bool       // Indexed by time.Weekday; the day a window starts on
+	From time.Duration // Since midnight
+	To   time.Duration // Since midnight; not after From means the window ends the next day
+}
+
+// parseClock parses a time of day such as 09:00 into the time since midnight
+func parseClock(field, value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a time like 09:00", field, value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// window parses a schedule entry, the i-th in the list, filling in the default mode
+func (e ScheduleEntry) window(i int, defaultMode string) (scheduleWindow, error) {
+	w := scheduleWindow{Mode: e.Mode}
+	if w.Mode == "" {
+		w.Mode = defaultMode
+	}
+	var err error
+	if w.From, err = parseClock(fmt.Sprintf("schedule[%d].from", i), e.From); err != nil {
+		return w, err
+	}
+	switch {
+	case e.For != "" && e.To != "":
+		return w, fmt.Errorf("schedule[%d] sets both to and for; use one", i)
+	case e.For != "":
+		length, err := parseDuration(e.For)
+		if err != nil {
+			return w, fmt.Errorf("schedule[%d].for: %w", i, err)
+		}
+		if length < time.Minute || length >= 24*time.Hour {
+			return w, fmt.Errorf("schedule[%d].for %q must be at least a minute and under a day", i, e.For)
+		}
+		w.To = (w.From + length.Truncate(time.Minute)) % (24 * time.Hour)
+	default:
+		if w.To, err = parseClock(fmt.Sprintf("schedule[%d].to", i), e.To); err != nil {
+			return w, err
+		}
+	}
+	if w.From == w.To {
+		return w, fmt.Errorf("schedule[%d] starts and ends at %s", i, e.From)
+	}
+	days := e.Days
+	if len(days) == 0 {
+		days = []string{"daily"}
+	}
+	for _, day := range days {
+		weekdays, ok := scheduleDayNames[strings.ToLower(strings.TrimSpace(day))]
+		if !ok {
+			return w, fmt.Errorf("schedule[%d].days: %q is not weekdays, weekends, daily or a day name such as mon", i, day)
+		}
+		for _, weekday := range weekdays {
+			w.Days[weekday] = true
+		}
+	}
+	return w, nil
+}
+
+// scheduleWindows parses the schedule, checking that every entry names a configured mode
+func (c *Config) scheduleWindows() ([]scheduleWindow, error) {
+	windows := make([]scheduleWindow, 0, len(c.Schedule))
+	for i, entry := range c.Schedule {
+		w, err := entry.window(i, c.DefaultMode)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.getModeConfig(w.Mode); err != nil {
+			return nil, fmt.Errorf("schedule[%d]: %w", i, err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// sinceMidnight returns how far into its day t is
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// active reports whether the window is open at now
+func (w scheduleWindow) active(now time.Time) bool {
+	clock := sinceMidnight(now)
+	if w.From < w.To {
+		return w.Days[now.Weekday()] && clock >= w.From && clock < w.To
+	}
+	// Past midnight: open from From on a listed day, or until To on the day after one
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+	return w.Days[now.Weekday()] && clock >= w.From || w.Days[yesterday] && clock < w.To
+}
+
+// at returns the time of day d on the date of day
+func at(day time.Time, d time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()).Add(d)
+}
+
+// nextChange returns when the window next opens or, while it is open, closes
+func (w scheduleWindow) nextChange(now time.Time) time.Time {
+	if w.active(now) {
+		end := at(now, w.To)
+		if !end.After(now) {
+			end = at(now.AddDate(0, 0, 1), w.To)
+		}
+		return end
+	}
+	for days := 0; days <= 7; days++ {
+		day := now.AddDate(0, 0, days)
+		if start := at(day, w.From); w.Days[day.Weekday()] && start.After(now) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// describe returns the window's days and times, e.g. "weekdays 09:00-17:00"
+func (w scheduleWindow) describe() string {
+	var days string
+	switch w.Days {
+	case [7]bool{true, true, true, true, true, true, true}:
+		days = "daily"
+	case [7]bool{false, true, true, true, true, true, false}:
+		days = "weekdays"
+	case [7]bool{true, false, false, false, false, false, true}:
+		days = "weekends"
+	default:
+		var names []string
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if w.Days[day] {
+				names = append(names, day.String()[:3])
+			}
+		}
+		days = strings.Join(names, ",")
+	}
+	clock := func(d time.Duration) string { return time.Time{}.Add(d).Format("15:04") }
+	return fmt.Sprintf("%s %s-%s", days, clock(w.From), clock(w.To))
+}
+
+// scheduledModes returns the modes whose windows are open at now, in order
+func scheduledModes(windows []scheduleWindow, now time.Time) []string {
+	seen := make(map[string]bool)
+	var modes []string
+	for _, w := range windows {
+		if w.active(now) && !seen[w.Mode] {
+			seen[w.Mode] = true
+			modes = append(modes, w.Mode)
+		}
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// scheduleState is the modes the scheduler applied, kept so they are restored after a restart,
+// and the modes whose countdown was cancelled, which are left alone until their window closes
+type scheduleState struct {
+	Modes   []string `json:"modes"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// scheduleStatePath returns the path of the scheduler's state
+func scheduleStatePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "schedule.json"), nil
+}
+
+// readScheduleState returns what the scheduler applied and skipped, or nothing if there is no state
+func readScheduleState(path string) (scheduleState, error) {
+	var state scheduleState
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return scheduleState{}, fmt.Errorf("error parsing schedule state: %w", err)
+	}
+	return state, nil
+}
+
+// writeScheduleState records what the scheduler applied and skipped
+func writeScheduleState(path string, state scheduleState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding schedule state: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing schedule state: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// scheduleChanges compares the modes that should be applied with those the scheduler applied
+// Only modes entering or leaving their window change, so a scheduled mode restored by hand
+// stays restored until its next window
+func scheduleChanges(want, applied []string) (apply, restore []string) {
+	for _, mode := range want {
+		if !containsFold(applied, mode) {
+			apply = append(apply, mode)
+		}
+	}
+	for _, mode := range applied {
+		if !containsFold(want, mode) {
+			restore = append(restore, mode)
+		}
+	}
+	return apply, restore
+}
+
+// runSchedule applies and restores modes as their windows open and close, running the
+// commands through run under a span each; the new state is returned
+// A mode whose countdown is cancelled is never recorded as applied, so closing its window
+// doesn't restore it; it is skipped until then
+func runSchedule(ctx context.Context, windows []scheduleWindow, state scheduleState, now time.Time, run commandRunner, grace func(action string) bool) scheduleState {
+	want := scheduledModes(windows, now)
+	var next scheduleState
+	for _, mode := range state.Skipped {
+		if containsFold(want, mode) {
+			next.Skipped = append(next.Skipped, mode)
+		}
+	}
+	var open []string
+	for _, mode := range want {
+		if !containsFold(next.Skipped, mode) {
+			open = append(open, mode)
+		}
+	}
+	apply, restore := scheduleChanges(open, state.Modes)
+	for _, mode := range state.Modes {
+		if !containsFold(restore, mode) {
+			next.Modes = append(next.Modes, mode)
+		}
+	}
+	for _, mode := range restore {
+		fmt.Printf("%s schedule: restoring %s\n", now.Format(time.RFC3339), mode)
+		spanCtx, span := startSpan(ctx, "schedule restore")
+		span.set("focusmode.mode", mode)
+		output, err := run(spanCtx, "-restore", "-mode", mode)
+		span.finish(err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring %s: %v\n%s", mode, err, output)
+			// Tried again on the next check
+			next.Modes = append(next.Modes, mode)
+		}
+	}
+	for _, mode := range apply {
+		if !grace("Applying " + mode) {
+			fmt.Printf("%s schedule: %s cancelled until its window closes\n", now.Format(time.RFC3339), mode)
+			next.Skipped = append(next.Skipped, mode)
+			continue
+		}
+		fmt.Printf("%s schedule: applying %s\n", now.Format(time.RFC3339), mode)
+		spanCtx, span := startSpan(ctx, "schedule apply")
+		span.set("focusmode.mode", mode)
+		output, err := run(spanCtx, "-mode", mode)
+		span.finish(err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying %s: %v\n%s", mode, err, output)
+			continue
+		}
+		next.Modes = append(next.Modes, mode)
+	}
+	return next
+}
+
+// watchSchedule applies and restores scheduled modes until ctx is cancelled; run by the daemon
+func watchSchedule(ctx context.Context, config *Config, run commandRunner, beat func()) {
+	windows, err := config.scheduleWindows()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	path, err := scheduleStatePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		<-ctx.Done()
+		return
+	}
+	state, err := readScheduleState(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	for _, w := range windows {
+		fmt.Printf("Scheduled: %s %s\n", w.Mode, w.describe())
+	}
+	answers := stdinLines()
+	grace := func(action string) bool {
+		notifyAll([]Notifier{consoleNotifier{}}, "FocusMode", action+" on schedule (focusmode daemon cancel to stop it)")
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		return waitGracePeriod(action, config.Automation.graceSeconds(), answers, ticker.C, os.Stdout)
+	}
+	for {
+		beat()
+		next := runSchedule(ctx, windows, state, time.Now(), run, grace)
+		if !reflect.DeepEqual(next, state) {
+			state = next
+			if err := writeScheduleState(path, state); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(schedulePollInterval):
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package focusmode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scheduleTime returns a time in October 2026; the 12th is a Monday
+func scheduleTime(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+}
+
+// TestScheduleWindowActive tests daytime and overnight windows on their days
+func TestScheduleWindowActive(t *testing.T) {
+	work, err := ScheduleEntry{Mode: "focusmode", Days: []string{"weekdays"}, From: "09:00", To: "17:00"}.window(0, "")
+	if err != nil {
+		t.Fatalf("window() returned error: %v", err)
+	}
+	night, err := ScheduleEntry{Mode: "gamemode", Days: []string{"Fri"}, From: "22:00", To: "02:00"}.window(1, "")
+	if err != nil {
+		t.Fatalf("window() returned error: %v", err)
+	}
+
+	// A length instead of an end time, running past midnight
+	lateNight, err := ScheduleEntry{Mode: "gamemode", Days: []string{"Fri"}, From: "22:00", For: "4h30m"}.window(1, "")
+	if err != nil || lateNight.To != 2*time.Hour+30*time.Minute {
+		t.Fatalf("Expected the window to end at 02:30, got %s (err %v)", lateNight.To, err)
+	}
+
+	tests := []struct {
+		window scheduleWindow
+		at     time.Time
+		want   bool
+	}{
+		{work, scheduleTime(12, 9, 0), true},    // Monday, opening minute
+		{work, scheduleTime(12, 16, 59), true},  // Monday, just before closing
+		{work, scheduleTime(12, 17, 0), false},  // Monday, closed
+		{work, scheduleTime(12, 8, 59), false},  // Monday, not open yet
+		{work, scheduleTime(17, 10, 0), false},  // Saturday
+		{night, scheduleTime(16, 23, 0), true},  // Friday night
+		{night, scheduleTime(17, 1, 30), true},  // early Saturday, still Friday's window
+		{night, scheduleTime(17, 2, 0), false},  // Saturday, closed
+		{night, scheduleTime(17, 23, 0), false}, // Saturday night isn't listed
+		{night, scheduleTime(16, 1, 0), false},  // early Friday belongs to Thursday
+	}
+	for _, tt := range tests {
+		if got := tt.window.active(tt.at); got != tt.want {
+			t.Errorf("%s active at %s = %v, want %v", tt.window.describe(), tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+// TestScheduleWindowNextChange tests when an open window closes and a closed one opens next
+func TestScheduleWindowNextChange(t *testing.T) {
+	work, _ := ScheduleEntry{Days: []string{"weekdays"}, From: "09:00", To: "17:00"}.window(0, "focusmode")
+	tests := []struct {
+		at   time.Time
+		want time.Time
+	}{
+		{scheduleTime(12, 10, 0), scheduleTime(12, 17, 0)}, // Monday, open: closes at five
+		{scheduleTime(12, 18, 0), scheduleTime(13, 9, 0)},  // Monday evening: opens Tuesday
+		{scheduleTime(16, 18, 0), scheduleTime(19, 9, 0)},  // Friday evening: opens Monday
+	}
+	for _, tt := range tests {
+		if got := work.nextChange(tt.at); !got.Equal(tt.want) {
+			t.Errorf("nextChange(%s) = %s, want %s", tt.at.Format("Mon 15:04"), got.Format("Mon 15:04"), tt.want.Format("Mon 15:04"))
+		}
+	}
+	if work.Mode != "focusmode" || work.describe() != "weekdays 09:00-17:00" {
+		t.Errorf("Unexpected window %s %s", work.Mode, work.describe())
+	}
+}
+
+// TestScheduleWindowsInvalid tests the errors for bad times, days and modes
+func TestScheduleWindowsInvalid(t *testing.T) {
+	tests := []struct {
+		entry ScheduleEntry
+		want  string
+	}{
+		{ScheduleEntry{From: "9am", To: "17:00"}, "schedule[0].from"},
+		{ScheduleEntry{From: "09:00", To: "09:00"}, "starts and ends at 09:00"},
+		{ScheduleEntry{Days: []string{"someday"}, From: "09:00", To: "17:00"}, "schedule[0].days"},
+		{ScheduleEntry{Mode: "nosuch", From: "09:00", To: "17:00"}, "mode 'nosuch' not found"},
+		{ScheduleEntry{From: "09:00", To: "17:00", For: "8h"}, "both to and for"},
+		{ScheduleEntry{From: "09:00", For: "soon"}, "schedule[0].for"},
+		{ScheduleEntry{From: "09:00", For: "24h"}, "under a day"},
+	}
+	for _, tt := range tests {
+		config := &Config{Modes: map[string]ModeConfig{"focusmode": {}}, DefaultMode: "focusmode", Schedule: []ScheduleEntry{tt.entry}}
+		if _, err := config.scheduleWindows(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %+v, got %v", tt.want, tt.entry, err)
+		}
+	}
+}
+
+// proceed lets every scheduled apply through without a countdown
+func proceed(string) bool { return true }
+
+// TestRunSchedule tests that modes are applied once as their window opens and restored as it closes,
+// leaving a mode restored by hand alone and retrying a failed restore
+func TestRunSchedule(t *testing.T) {
+	work, _ := ScheduleEntry{Mode: "focusmode", From: "09:00", To: "17:00"}.window(0, "")
+	windows := []scheduleWindow{work}
+	runner := &fakeRunner{}
+
+	state := runSchedule(context.Background(), windows, scheduleState{}, scheduleTime(12, 9, 0), runner.run, proceed)
+	if !reflect.DeepEqual(state.Modes, []string{"focusmode"}) || len(runner.calls) != 1 || !reflect.DeepEqual(runner.calls[0], []string{"-mode", "focusmode"}) {
+		t.Fatalf("Expected focusmode applied, got %v after %v", state.Modes, runner.calls)
+	}
+	state = runSchedule(context.Background(), windows, state, scheduleTime(12, 12, 0), runner.run, proceed)
+	if len(runner.calls) != 1 {
+		t.Errorf("Expected no command while the window stays open, got %v", runner.calls)
+	}
+
+	runner.err = errors.New("exit status 1")
+	state = runSchedule(context.Background(), windows, state, scheduleTime(12, 17, 0), runner.run, proceed)
+	if !reflect.DeepEqual(state.Modes, []string{"focusmode"}) {
+		t.Errorf("Expected a failed restore to be kept for another try, got %v", state.Modes)
+	}
+	runner.err = nil
+	state = runSchedule(context.Background(), windows, state, scheduleTime(12, 17, 1), runner.run, proceed)
+	if len(state.Modes) != 0 || !reflect.DeepEqual(runner.calls[len(runner.calls)-1], []string{"-restore", "-mode", "focusmode"}) {
+		t.Errorf("Expected focusmode restored, got %v after %v", state.Modes, runner.calls)
+	}
+}
+
+// TestRunScheduleCancelled tests that a cancelled countdown skips the mode until its window closes,
+// without restoring it then, and that the next window applies it again
+func TestRunScheduleCancelled(t *testing.T) {
+	work, _ := ScheduleEntry{Mode: "focusmode", From: "09:00", To: "17:00"}.window(0, "")
+	windows := []scheduleWindow{work}
+	runner := &fakeRunner{}
+	var actions []string
+	cancel := func(action string) bool {
+		actions = append(actions, action)
+		return false
+	}
+
+	state := runSchedule(context.Background(), windows, scheduleState{}, scheduleTime(12, 9, 0), runner.run, cancel)
+	if len(runner.calls) != 0 || !reflect.DeepEqual(actions, []string{"Applying focusmode"}) {
+		t.Fatalf("Expected the apply cancelled, got %v after %v", runner.calls, actions)
+	}
+	if len(state.Modes) != 0 || !reflect.DeepEqual(state.Skipped, []string{"focusmode"}) {
+		t.Errorf("Expected focusmode skipped and not applied, got %+v", state)
+	}
+	state = runSchedule(context.Background(), windows, state, scheduleTime(12, 9, 1), runner.run, cancel)
+	if len(runner.calls) != 0 || len(actions) != 1 {
+		t.Errorf("Expected no second countdown in the same window, got %v", actions)
+	}
+
+	state = runSchedule(context.Background(), windows, state, scheduleTime(12, 17, 1), runner.run, cancel)
+	if len(runner.calls) != 0 {
+		t.Errorf("Expected no restore of a mode that was never applied, got %v", runner.calls)
+	}
+	if len(state.Modes) != 0 || len(state.Skipped) != 0 {
+		t.Errorf("Expected the skip forgotten once the window closed, got %+v", state)
+	}
+
+	state = runSchedule(context.Background(), windows, state, scheduleTime(13, 9, 0), runner.run, proceed)
+	if !reflect.DeepEqual(state.Modes, []string{"focusmode"}) || len(runner.calls) != 1 {
+		t.Errorf("Expected the next window to apply focusmode, got %+v after %v", state, runner.calls)
+	}
+}
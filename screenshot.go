@@ -0,0 +1,131 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ScreenshotsConfig represents the settings of the screenshots kept with the operation history
+type ScreenshotsConfig struct {
+	Enabled bool `yaml:"enabled" doc:"Capture the screen before applying a mode and after restoring one, kept with the undo history" default:"false"`
+}
+
+// screenshotCapture captures the screen into a PNG file; replaced in tests
+var screenshotCapture = captureScreen
+
+// windowsScreenshotScript returns a PowerShell script that saves the whole virtual screen as a PNG
+func windowsScreenshotScript(path string) string {
+	return fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$bounds = [System.Windows.Forms.SystemInformation]::VirtualScreen
+$bitmap = New-Object System.Drawing.Bitmap $bounds.Width, $bounds.Height
+$graphics = [System.Drawing.Graphics]::FromImage($bitmap)
+$graphics.CopyFromScreen($bounds.Left, $bounds.Top, 0, 0, $bitmap.Size)
+$bitmap.Save('%s', [System.Drawing.Imaging.ImageFormat]::Png)
+$graphics.Dispose()
+$bitmap.Dispose()`, strings.ReplaceAll(path, "'", "''"))
+}
+
+// linuxScreenshotCommands are the screenshot tools tried in order, each followed by its
+// arguments before the output path; grim is for Wayland, the others for X11 and GNOME
+var linuxScreenshotCommands = [][]string{
+	{"grim"},
+	{"gnome-screenshot", "-f"},
+	{"scrot", "-o"},
+	{"import", "-window", "root"},
+}
+
+// screenshotCommand returns the command that captures the screen into path on goos
+func screenshotCommand(goos, path string, lookPath func(string) (string, error)) ([]string, error) {
+	switch goos {
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", windowsScreenshotScript(path)}, nil
+	case "darwin":
+		return []string{"screencapture", "-x", path}, nil
+	}
+	for _, command := range linuxScreenshotCommands {
+		if _, err := lookPath(command[0]); err == nil {
+			return append(append([]string(nil), command...), path), nil
+		}
+	}
+	return nil, fmt.Errorf("no screenshot tool found (install grim, gnome-screenshot, scrot or ImageMagick)")
+}
+
+// captureScreen captures the screen into path using the platform's screenshot tool
+func captureScreen(path string) error {
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return fmt.Errorf("no display to capture")
+	}
+	command, err := screenshotCommand(runtime.GOOS, path, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	if out, err := exec.Command(command[0], command[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v %s", command[0], err, strings.TrimSpace(string(out)))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s wrote no image", command[0])
+	}
+	return nil
+}
+
+// screenshotDir returns the folder the screenshots of operations are kept in
+func screenshotDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "screenshots"), nil
+}
+
+// screenshotName returns the file name of a screenshot taken at t around an operation
+func screenshotName(kind, mode string, t time.Time) string {
+	mode = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?* `, r) {
+			return '-'
+		}
+		return r
+	}, mode)
+	return fmt.Sprintf("%s-%s-%s.png", t.Format("20060102-150405.000"), kind, mode)
+}
+
+// takeScreenshot captures the screen around an operation, returning the image's path
+// It does nothing and returns "" unless screenshots.enabled is set, and only captures the
+// OS desktop: another --location isn't on screen. Failures are warnings, never errors
+func takeScreenshot(config *Config, kind, mode string) string {
+	if config == nil || !config.Screenshots.Enabled || locationActive() || readOnly {
+		return ""
+	}
+	dir, err := screenshotDir()
+	if err == nil {
+		err = mkdirAll(dir, 0755)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error capturing the screen: %v\n", err)
+		return ""
+	}
+	path := filepath.Join(dir, screenshotName(kind, mode, time.Now()))
+	if err := screenshotCapture(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error capturing the screen: %v\n", err)
+		os.Remove(path)
+		return ""
+	}
+	ownByUser(path)
+	return path
+}
+
+// removeScreenshots deletes the screenshots of operations dropped from the history
+func removeScreenshots(ops []operation) {
+	for _, op := range ops {
+		if op.Screenshot == "" {
+			continue
+		}
+		if err := removeFile(op.Screenshot); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: error removing screenshot: %v\n", err)
+		}
+	}
+}
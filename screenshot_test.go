@@ -0,0 +1,107 @@
+package focusmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestScreenshotCommand tests the screenshot tool chosen on each platform
+func TestScreenshotCommand(t *testing.T) {
+	only := func(tool string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			if name == tool {
+				return "/usr/bin/" + name, nil
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	got, err := screenshotCommand("darwin", "/tmp/a.png", only(""))
+	if err != nil || strings.Join(got, " ") != "screencapture -x /tmp/a.png" {
+		t.Errorf("Unexpected macOS command %v (err %v)", got, err)
+	}
+	got, err = screenshotCommand("windows", `C:\it's\a.png`, only(""))
+	if err != nil || got[0] != "powershell" || !strings.Contains(got[len(got)-1], `'C:\it''s\a.png'`) {
+		t.Errorf("Unexpected Windows command %v (err %v)", got, err)
+	}
+	got, err = screenshotCommand("linux", "/tmp/a.png", only("scrot"))
+	if err != nil || strings.Join(got, " ") != "scrot -o /tmp/a.png" {
+		t.Errorf("Unexpected Linux command %v (err %v)", got, err)
+	}
+	if _, err := screenshotCommand("linux", "/tmp/a.png", only("")); err == nil {
+		t.Error("Expected an error without a screenshot tool")
+	}
+}
+
+// TestScreenshotName tests that mode names are made safe for file names
+func TestScreenshotName(t *testing.T) {
+	got := screenshotName(operationApply, "deep work/2", time.Date(2026, time.October, 12, 9, 30, 5, 0, time.UTC))
+	if got != "20261012-093005.000-apply-deep-work-2.png" {
+		t.Errorf("Unexpected name %q", got)
+	}
+}
+
+// TestRecordOperationScreenshots tests that screenshots are taken only when enabled, and removed
+// with the operations that drop off the history
+func TestRecordOperationScreenshots(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	captured := 0
+	defer func(capture func(string) error) { screenshotCapture = capture }(screenshotCapture)
+	screenshotCapture = func(path string) error {
+		captured++
+		return os.WriteFile(path, []byte("png"), 0644)
+	}
+
+	config := &Config{}
+	if path := takeScreenshot(config, operationApply, "focusmode"); path != "" || captured != 0 {
+		t.Fatalf("Expected no screenshot unless enabled, got %q", path)
+	}
+	config.Screenshots.Enabled = true
+
+	// An operation that moved nothing keeps no screenshot
+	path := takeScreenshot(config, operationApply, "focusmode")
+	if path == "" {
+		t.Fatal("Expected a screenshot")
+	}
+	recordOperation(operationApply, "focusmode", nil, path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the screenshot of an empty operation to be removed, got %v", err)
+	}
+
+	move := []fileMove{{Name: "Steam.lnk", Mode: "focusmode", From: "/desk", To: "/games"}}
+	var paths []string
+	for i := 0; i < operationHistoryLimit+1; i++ {
+		path := takeScreenshot(config, operationApply, "focusmode")
+		paths = append(paths, path)
+		recordOperation(operationApply, "focusmode", move, path)
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest operation's screenshot to be removed, got %v", err)
+	}
+	logPath, _ := operationLogPath()
+	log, err := readOperationLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := log.Done[len(log.Done)-1]
+	if last.Screenshot != paths[len(paths)-1] || filepath.Ext(last.Screenshot) != ".png" {
+		t.Errorf("Expected the screenshot recorded with the operation, got %q", last.Screenshot)
+	}
+	if _, err := os.Stat(last.Screenshot); err != nil {
+		t.Errorf("Expected the latest screenshot to be kept, got %v", err)
+	}
+
+	// A failed capture is only a warning
+	screenshotCapture = func(string) error { return errors.New("no display") }
+	if path := takeScreenshot(config, operationRestore, "focusmode"); path != "" {
+		t.Errorf("Expected no screenshot when capture fails, got %q", path)
+	}
+}
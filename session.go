@@ -0,0 +1,801 @@
+package focusmode
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionExtendStep is how much time the "e" command and "session extend" add to a session
+// when not given a duration
+const sessionExtendStep = 5 * time.Minute
+
+// SessionHook receives lifecycle events from a running focus session
+type SessionHook interface {
+	OnStart(fs *FocusSession)
+	OnPause(fs *FocusSession)
+	OnResume(fs *FocusSession)
+	OnEnd(fs *FocusSession)
+}
+
+// tickHook is implemented by hooks that want to observe the countdown every second
+type tickHook interface {
+	OnTick(fs *FocusSession)
+}
+
+// extendHook is implemented by hooks that keep track of the session's planned duration
+type extendHook interface {
+	OnExtend(fs *FocusSession)
+}
+
+// duckable is implemented by hooks whose audio output can be lowered on demand
+type duckable interface {
+	toggleDuck() bool
+}
+
+// widgetToggler is implemented by hooks whose window can be shown and hidden during a session
+type widgetToggler interface {
+	toggleWidget() (bool, error)
+}
+
+// pause pauses a running session and notifies hooks
+func (fs *FocusSession) pause() {
+	if fs.State != StateRunning {
+		return
+	}
+	now := time.Now()
+	fs.PausedAt = &now
+	fs.State = StatePaused
+	for _, hook := range fs.Hooks {
+		hook.OnPause(fs)
+	}
+}
+
+// resume resumes a paused session and notifies hooks
+func (fs *FocusSession) resume() {
+	if fs.State != StatePaused {
+		return
+	}
+	if fs.PausedAt != nil {
+		fs.PausedTotal += time.Since(*fs.PausedAt)
+	}
+	fs.PausedAt = nil
+	fs.State = StateRunning
+	for _, hook := range fs.Hooks {
+		hook.OnResume(fs)
+	}
+}
+
+// extend adds time to the session and notifies hooks that track its duration
+func (fs *FocusSession) extend(d time.Duration) {
+	fs.Duration += d
+	for _, hook := range fs.Hooks {
+		if e, ok := hook.(extendHook); ok {
+			e.OnExtend(fs)
+		}
+	}
+}
+
+// finish moves the session into a terminal state and notifies hooks
+func (fs *FocusSession) finish(state SessionState) {
+	if fs.State == StateCompleted || fs.State == StateInterrupted {
+		return
+	}
+	if fs.State == StatePaused && fs.PausedAt != nil {
+		fs.PausedTotal += time.Since(*fs.PausedAt)
+		fs.PausedAt = nil
+	}
+	fs.State = state
+	for _, hook := range fs.Hooks {
+		hook.OnEnd(fs)
+	}
+}
+
+// isActive reports whether the session is still running or paused
+func (fs *FocusSession) isActive() bool {
+	return fs.State == StateRunning || fs.State == StatePaused
+}
+
+// handleCommand applies a single interactive command to the session
+// Supported commands: p (pause/resume), pause, resume, e [duration] (extend), d (duck/unduck audio), w (countdown widget), q (stop)
+func (fs *FocusSession) handleCommand(command string) {
+	command, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(command)), " ")
+	switch command {
+	case "p", "pause", "r", "resume":
+		if fs.Config.isStrict() && !fs.Break {
+			fmt.Print("\n" + glyph("🔒 ") + "Strict mode: sessions cannot be paused\n")
+			return
+		}
+		// "pause" and "resume", sent by "session pause" and "session resume", only go one way
+		switch {
+		case fs.State == StatePaused && command != "pause":
+			fs.resume()
+		case fs.State == StateRunning && command != "r" && command != "resume":
+			fs.pause()
+		}
+	case "e", "extend":
+		step := sessionExtendStep
+		if arg = strings.TrimSpace(arg); arg != "" {
+			minutes, err := parseMinutes(arg)
+			if err != nil {
+				fmt.Printf("\n%v\n", err)
+				return
+			}
+			step = time.Duration(minutes) * time.Minute
+		}
+		fs.extend(step)
+		fmt.Printf("\n%sSession extended by %s\n", glyph("⏩ "), formatDuration(step))
+	case "d", "duck":
+		for _, hook := range fs.Hooks {
+			if d, ok := hook.(duckable); ok {
+				if d.toggleDuck() {
+					fmt.Print("\n" + glyph("🔉 ") + "Audio ducked\n")
+				} else {
+					fmt.Print("\n" + glyph("🔊 ") + "Audio restored\n")
+				}
+			}
+		}
+	case "w", "widget":
+		for _, hook := range fs.Hooks {
+			if w, ok := hook.(widgetToggler); ok {
+				shown, err := w.toggleWidget()
+				switch {
+				case err != nil:
+					fmt.Printf("\nCountdown widget unavailable: %v\n", err)
+				case shown:
+					fmt.Print("\n" + glyph("🪟 ") + "Countdown widget shown\n")
+				default:
+					fmt.Print("\n" + glyph("🪟 ") + "Countdown widget hidden\n")
+				}
+			}
+		}
+	case "q", "quit", "stop":
+		if fs.Config.isStrict() && !fs.Break {
+			fmt.Print("\n" + glyph("🔒 ") + "Strict mode: sessions cannot be stopped early\n")
+			return
+		}
+		fs.finish(StateInterrupted)
+	case "panic":
+		// Sent by "focusmode panic": stop and bring everything back, whatever the session's settings
+		if fs.Config.isStrict() && !fs.Break {
+			fmt.Print("\n" + glyph("🔒 ") + "Strict mode: sessions cannot be stopped early\n")
+			return
+		}
+		fs.AutoRestore = true
+		fs.RestoreCategories = nil
+		fs.finish(StateInterrupted)
+	}
+}
+
+// readSessionCommands reads interactive commands line by line from r
+func readSessionCommands(r io.Reader) <-chan string {
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			commands <- scanner.Text()
+		}
+	}()
+	return commands
+}
+
+var (
+	stdinOnce   sync.Once
+	stdinReader <-chan string
+)
+
+// stdinLines returns the shared line reader for standard input
+// All prompts read from the same channel so no line is swallowed by another reader
+func stdinLines() <-chan string {
+	stdinOnce.Do(func() { stdinReader = readSessionCommands(os.Stdin) })
+	return stdinReader
+}
+
+// run organizes shortcuts, counts down the session and restores shortcuts at the end
+// Commands are read from the provided channel until the session completes or is stopped
+// A resumed session already has its moved shortcuts and doesn't organize them again
+func (fs *FocusSession) run(commands <-chan string) error {
+	if !fs.Break && fs.MovedShortcuts == nil {
+		moved, err := fs.organizeShortcuts()
+		if err != nil {
+			return err
+		}
+		fs.MovedShortcuts = moved
+		refreshHiddenMenu(fs.Config)
+	}
+
+	for _, hook := range fs.Hooks {
+		hook.OnStart(fs)
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Commands left for an earlier session must not end this one
+	if fs.ControlPath != "" {
+		removeFile(fs.ControlPath)
+	}
+
+	fs.showProgress()
+	for fs.isActive() {
+		select {
+		case <-ticker.C:
+			fs.pollControl()
+			if !fs.isActive() {
+				continue
+			}
+			if fs.State == StateRunning && fs.remaining() == 0 {
+				fs.finish(StateCompleted)
+				continue
+			}
+			if fs.State == StateRunning {
+				for _, hook := range fs.Hooks {
+					if t, ok := hook.(tickHook); ok {
+						t.OnTick(fs)
+					}
+				}
+			}
+		case command, ok := <-commands:
+			if !ok {
+				// Input closed (e.g. not attached to a terminal); keep counting down
+				commands = nil
+				continue
+			}
+			fs.handleCommand(command)
+		case <-fs.Stop:
+			// Keep the hidden shortcuts and the session state so the next start offers recovery
+			fs.Suspended = !fs.RestoreOnStop && !fs.Break
+			fs.Stop = nil
+			fs.finish(StateInterrupted)
+			continue
+		case <-interrupts:
+			if fs.Config.isStrict() && !fs.Break {
+				fmt.Print("\n" + glyph("🔒 ") + "Strict mode: sessions cannot be stopped early\n")
+				break
+			}
+			fs.finish(StateInterrupted)
+			continue
+		}
+		fs.showProgress()
+	}
+	if !accessible {
+		fmt.Println()
+	}
+
+	if fs.Suspended {
+		fmt.Printf("%sSession suspended after %s; it will be offered for recovery on the next start\n", glyph("⏸ "), formatDuration(fs.elapsed()))
+		return nil
+	}
+	if fs.Break {
+		fmt.Println(glyph("☕ ") + "Break over")
+	} else if fs.State == StateCompleted {
+		fmt.Printf("%sFocus session complete (%s)\n", glyph("✅ "), formatDuration(fs.Duration))
+	} else {
+		fmt.Printf("%sFocus session stopped after %s\n", glyph("⏹ "), formatDuration(fs.elapsed()))
+	}
+
+	if fs.AutoRestore && len(fs.MovedShortcuts) > 0 {
+		fs.restoreMovedShortcuts()
+	}
+	return nil
+}
+
+// showProgress displays the countdown line for the session
+// In accessible mode the accessibleAnnouncer hook reports progress instead
+func (fs *FocusSession) showProgress() {
+	if accessible {
+		return
+	}
+	if fs.Break {
+		fmt.Printf("\r☕ Break: %s remaining", formatDuration(fs.remaining()))
+		return
+	}
+	displayProgress(fs.elapsed(), fs.remaining(), fs.State == StatePaused)
+}
+
+// restoreMovedShortcuts moves the shortcuts moved at session start back to the desktop
+func (fs *FocusSession) restoreMovedShortcuts() {
+	modeConfig, err := fs.Config.getModeConfig(fs.Mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting mode configuration: %v\n", err)
+		return
+	}
+
+	homeDir, err := hiddenFoldersRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		return
+	}
+	sourceFolder := modeFolder(homeDir, modeConfig.Destination)
+
+	shortcuts, kept := fs.MovedShortcuts, []string(nil)
+	var held []timedRestore
+	if fs.State == StateCompleted && len(fs.RestoreCategories) > 0 {
+		categoriesConfig := fs.Categories
+		if categoriesConfig == nil {
+			categoriesConfig = getDefaultCategoriesConfig()
+		}
+		shortcuts, kept = splitShortcutsByCategory(fs.MovedShortcuts, fs.RestoreCategories, categoriesConfig)
+		if len(fs.RestoreAt) > 0 {
+			var due []string
+			due, held, kept = planTimedRestores(fs.Mode, sourceFolder, kept, fs.RestoreAt, categoriesConfig, time.Now())
+			shortcuts = append(shortcuts, due...)
+			if err := recordTimedRestores(held); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				for _, h := range held {
+					kept = append(kept, h.Names...)
+				}
+				held = nil
+			}
+		}
+	}
+
+	shortcuts = fs.Config.restorable(shortcuts)
+
+	restored := 0
+	policy := fs.Config.Modes[fs.Mode].restoreConflictPolicy()
+	for _, shortcutName := range shortcuts {
+		result, err := fs.Config.restoreShortcut(shortcutName, sourceFolder, policy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring '%s': %v\n", shortcutName, err)
+			continue
+		}
+		if result.Skipped {
+			fmt.Println(result.describe(shortcutName))
+			continue
+		}
+		restored++
+	}
+	fmt.Printf("Restored %d of %d shortcut(s) to desktop\n", restored, len(shortcuts))
+	refreshHiddenMenu(fs.Config)
+	for _, line := range describeTimedRestores(held) {
+		fmt.Println(line)
+	}
+	if len(kept) > 0 {
+		fmt.Printf("Kept %d shortcut(s) hidden in %s (use -restore -mode %s to bring them back)\n", len(kept), sourceFolder, fs.Mode)
+	}
+}
+
+// sessionOptions holds per-invocation overrides for session integrations
+type sessionOptions struct {
+	noAmbient   bool
+	noTTS       bool
+	silent      bool
+	routine     string // routine this session belongs to, if any
+	routineStep int    // 1-based step number within the routine
+	heartbeat   func() // called every second while the session runs, e.g. for the daemon watchdog
+
+	stop          <-chan struct{} // ends the session from outside when closed (see FocusSession.Stop)
+	restoreOnStop bool            // end a stopped session normally instead of suspending it
+	fromCalendar  bool            // started by a calendar event, which is already on the calendar
+}
+
+// attachSessionHooks adds the integrations enabled in config to a session
+func attachSessionHooks(session *FocusSession, config *Config, opts sessionOptions) error {
+	if path, err := activeSessionPath(); err == nil {
+		session.Hooks = append(session.Hooks, &sessionStateWriter{path: path})
+	}
+	if path, err := sessionControlPath(); err == nil {
+		session.ControlPath = path
+	}
+	session.Hooks = append(session.Hooks, colorTemperatureHook{})
+	if accessible {
+		session.Hooks = append(session.Hooks, &accessibleAnnouncer{w: os.Stdout})
+	}
+	if stdoutIsTerminal() {
+		session.Hooks = append(session.Hooks, &terminalTitleHook{w: os.Stdout})
+	}
+	if widget := newCountdownWidget(config.Widget); widget != nil {
+		session.Hooks = append(session.Hooks, widget)
+	}
+	if opts.heartbeat != nil {
+		session.Hooks = append(session.Hooks, heartbeatHook(opts.heartbeat))
+	}
+	if config.Ambient.Enabled && !opts.noAmbient {
+		session.Hooks = append(session.Hooks, newAmbientPlayer(config.Ambient))
+	}
+	if config.TTS.Enabled && !opts.noTTS {
+		session.Hooks = append(session.Hooks, newTTSAnnouncer(config.TTS))
+	}
+	if config.Chime.Enabled && !opts.silent {
+		session.Hooks = append(session.Hooks, newChimePlayer(config.Chime))
+	}
+	if config.Notify.Enabled {
+		notifier, err := newDesktopNotifier(config.Notify)
+		if err != nil {
+			return err
+		}
+		session.Hooks = append(session.Hooks, notifier)
+	}
+
+	if len(config.Milestones) > 0 {
+		milestones, err := newMilestoneNotifier(config.Milestones, buildNotifiers(session.Hooks))
+		if err != nil {
+			return err
+		}
+		session.Hooks = append(session.Hooks, milestones)
+	}
+
+	// A meeting is already on the calendar, so it isn't written back as deep work
+	meeting := config.Meeting.Enabled && session.Mode == meetingModeName
+	if meeting && config.Meeting.SlackToken != "" {
+		session.Hooks = append(session.Hooks, &slackStatusHook{config: config.Meeting, retry: config.Retry.Network})
+	}
+
+	if config.Discord.Enabled && config.Discord.ClientID != "" {
+		session.Hooks = append(session.Hooks, &discordPresenceHook{config: config.Discord, dial: dialDiscordIPC})
+	}
+
+	if config.AppBlocking.backend() == appBlockingFirewall && runtime.GOOS == "windows" && !session.Break {
+		if programs := modeBlockedPrograms(config, session.Mode); len(programs) > 0 {
+			if path, err := firewallRulesPath(); err == nil {
+				session.Hooks = append(session.Hooks, &firewallHook{path: path, programs: programs})
+			}
+		}
+	}
+
+	if session.Allowlist != nil && len(session.Allowlist.Apps) > 0 && !session.Break {
+		if path, err := distractionLogPath(); err == nil {
+			session.Hooks = append(session.Hooks, &allowlistWatcher{allowlist: session.Allowlist, foreground: foregroundApp, logPath: path})
+		}
+	}
+
+	if config.AppBlocking.Close && !session.Break {
+		if apps := modeClosedApps(config, session.Mode); len(apps) > 0 {
+			if path, err := distractionLogPath(); err == nil {
+				session.Hooks = append(session.Hooks, &appSupervisor{apps: apps, action: config.AppBlocking.action(), interval: config.AppBlocking.checkInterval(), logPath: path})
+			}
+		}
+	}
+
+	if modeWantsDND(config, session.Mode) && !session.Break {
+		if path, err := dndStatePath(); err == nil {
+			session.Hooks = append(session.Hooks, &dndHook{path: path})
+		}
+	}
+
+	if list := newDNSDenylist(config.DNSBlocking, config.Retry.Network); list != nil && !session.Break {
+		if domains := modeBlockedDomains(config, session.Mode); len(domains) > 0 {
+			if path, err := dnsBlockedPath(); err == nil {
+				session.Hooks = append(session.Hooks, &dnsBlockHook{list: list, path: path, domains: domains})
+			}
+		}
+	}
+
+	if config.Calendar.Enabled && !meeting && !opts.fromCalendar {
+		writer, err := newCalendarWriter(config.Calendar, config.Retry.Network)
+		if err != nil {
+			return err
+		}
+		session.Hooks = append(session.Hooks, writer)
+	}
+
+	if config.WakaTime.Enabled {
+		session.Hooks = append(session.Hooks, &wakatimeCollector{config: config.WakaTime, retry: config.Retry.Network})
+	}
+
+	// History recorders go last so they see data collected by the other hooks
+	if path := config.guardianReportPath(); path != "" {
+		session.Hooks = append(session.Hooks, &historyRecorder{path: path, routine: opts.routine, routineStep: opts.routineStep})
+	}
+	path, err := historyPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: session history disabled: %v\n", err)
+	} else {
+		session.Hooks = append(session.Hooks, &historyRecorder{path: path, routine: opts.routine, routineStep: opts.routineStep})
+	}
+	return nil
+}
+
+// runSessionCommand handles the "session" subcommand
+func runSessionCommand(args []string) {
+	if len(args) == 0 {
+		printSessionUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "start":
+		runSessionStart(args[1:])
+	case "recover":
+		runSessionRecover(args[1:])
+	case "pause":
+		runSessionControl("pause", "pause", args[1:])
+	case "resume":
+		runSessionControl("resume", "resume", args[1:])
+	case "extend":
+		runSessionControl("extend", "e", args[1:])
+	case "stop":
+		runSessionControl("stop", "q", args[1:])
+	case "widget":
+		runSessionControl("widget", "w", args[1:])
+	case "queue":
+		runSessionQueue(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown session command: %s\n\n", args[0])
+		printSessionUsage()
+		os.Exit(1)
+	}
+}
+
+// printSessionUsage prints help for the "session" subcommand
+func printSessionUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: focusmode session start [-preset name] [options] [\"for 2 hours starting at 3pm in focusmode\"]")
+	fmt.Fprintln(os.Stderr, "       focusmode session queue [list]")
+	fmt.Fprintln(os.Stderr, "       focusmode session queue add [-force] [options] [\"for 2 hours starting at 3pm\"]")
+	fmt.Fprintln(os.Stderr, "       focusmode session queue rm <number>... | all")
+	fmt.Fprintln(os.Stderr, "       focusmode session recover [-action ask|restore|resume|discard]")
+	fmt.Fprintln(os.Stderr, "       focusmode session pause [-toggle]")
+	fmt.Fprintln(os.Stderr, "       focusmode session resume")
+	fmt.Fprintln(os.Stderr, "       focusmode session extend [duration]")
+	fmt.Fprintln(os.Stderr, "       focusmode session stop")
+	fmt.Fprintln(os.Stderr, "       focusmode session widget")
+	fmt.Fprintln(os.Stderr, "\nWhile a session is running, type a command and press Enter:")
+	fmt.Fprintln(os.Stderr, "  p  pause/resume")
+	fmt.Fprintln(os.Stderr, "  e  extend by 5 minutes, or e.g. e 15m or e 1h")
+	fmt.Fprintln(os.Stderr, "  d  duck/unduck ambient sound")
+	fmt.Fprintln(os.Stderr, "  w  show/hide the countdown widget")
+	fmt.Fprintln(os.Stderr, "  q  stop the session")
+}
+
+// runSessionControl handles "session pause", "resume", "extend", "stop" and "widget", which control a session
+// running in another terminal or started by the daemon
+func runSessionControl(name, command string, args []string) {
+	flags := flag.NewFlagSet("session "+name, flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	var toggle *bool
+	if command == "pause" {
+		toggle = flags.Bool("toggle", false, "Resume the session instead if it is paused, as the pause hotkey does")
+	}
+	flags.Parse(args)
+	if toggle != nil && *toggle {
+		command = "p"
+	}
+	step := sessionExtendStep
+	if command == "e" && flags.NArg() > 0 {
+		minutes, err := parseMinutes(strings.Join(flags.Args(), " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		step = time.Duration(minutes) * time.Minute
+		command = "e " + strconv.Itoa(minutes)
+	}
+
+	path, err := activeSessionPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	state, err := readActiveSession(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if state == nil {
+		fmt.Fprintln(os.Stderr, "Error: no focus session is running")
+		os.Exit(1)
+	}
+	switch {
+	case command == "pause" && state.PausedAt != nil:
+		fmt.Printf("The %s session is already paused\n", state.Mode)
+		return
+	case command == "resume" && state.PausedAt == nil:
+		fmt.Printf("The %s session isn't paused\n", state.Mode)
+		return
+	}
+	if (command == "p" || command == "pause" || command == "q") && !state.Break {
+		config, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.isStrict() || state.Strict {
+			if command == "q" {
+				fmt.Fprintln(os.Stderr, "Error: strict mode: sessions cannot be stopped early")
+			} else {
+				fmt.Fprintln(os.Stderr, "Error: strict mode: sessions cannot be paused")
+			}
+			os.Exit(1)
+		}
+	}
+
+	if err := sendSessionCommand(command); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	verb, _, _ := strings.Cut(command, " ")
+	switch verb {
+	case "p":
+		fmt.Printf("Pausing or resuming the %s session\n", state.Mode)
+	case "pause":
+		fmt.Printf("Pausing the %s session\n", state.Mode)
+	case "resume":
+		fmt.Printf("Resuming the %s session\n", state.Mode)
+	case "q":
+		fmt.Printf("Stopping the %s session\n", state.Mode)
+	case "e":
+		fmt.Printf("Extending the %s session by %s\n", state.Mode, formatDuration(step))
+	case "w":
+		fmt.Printf("Showing or hiding the countdown widget of the %s session\n", state.Mode)
+	}
+}
+
+// runSessionStart starts a timed focus session in the foreground
+func runSessionStart(args []string) {
+	flags := flag.NewFlagSet("session start", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to categories configuration file")
+	presetName := flags.String("preset", "", "Session preset to use (mode, duration and restore options)")
+	mode := flags.String("mode", "", "Mode to apply during the session (uses default if not specified)")
+	duration := minutesVar(flags, "duration", 25, "Session duration: minutes (45) or a duration such as 1h30m or 1.5h")
+	flags.Var((*minutesFlag)(duration), "for", "Same as -duration")
+	startAt := flags.String("at", "", "Time of day to start, such as 15:00 or 3pm; the daemon starts the session then")
+	autoRestore := flags.Bool("auto-restore", true, "Restore moved shortcuts when the session ends")
+	noAmbient := flags.Bool("no-ambient", false, "Disable ambient sound for this session")
+	noTTS := flags.Bool("no-tts", false, "Disable spoken announcements for this session")
+	silent := flags.Bool("silent", false, "Don't play the chime for this session")
+	goal := flags.String("goal", "", "What the session is for, shown in the countdown widget")
+	suggest := flags.Bool("suggest", false, "Suggest a duration and mode based on session history")
+	dryRun := flags.Bool("dry-run", false, "Show what the session would do without doing it")
+	flags.Usage = func() {
+		printSessionUsage()
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	explicit["duration"] = explicit["duration"] || explicit["for"]
+
+	// A session described in words, e.g. "for 2 hours starting at 3pm in focusmode"
+	at, err := applySessionDescription(flags.Args(), *startAt, mode, duration, explicit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !*dryRun && at.IsZero() && isSessionRunning() {
+		fmt.Fprintln(os.Stderr, "Error: a focus session is already running")
+		os.Exit(1)
+	}
+	// A session moves shortcuts and records state, so only its dry run is allowed
+	if !*dryRun {
+		if err := guardWrite("start a focus session"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (use -dry-run to preview it)\n", err)
+			os.Exit(1)
+		}
+	}
+
+	modeName := *mode
+	var preset *SessionPreset
+	if *presetName != "" {
+		preset, err = config.getPreset(*presetName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// Explicit flags take precedence over the preset
+		if !explicit["mode"] {
+			modeName = preset.Mode
+		}
+		if !explicit["duration"] && preset.Duration > 0 {
+			*duration = preset.Duration
+		}
+		if !explicit["auto-restore"] && preset.AutoRestore != nil {
+			*autoRestore = *preset.AutoRestore
+		}
+	}
+	if modeName == "" {
+		modeName = config.DefaultMode
+	}
+
+	if !at.IsZero() {
+		queued := queuedSession{At: at, Mode: modeName, Minutes: *duration, AutoRestore: *autoRestore, Preset: *presetName, NoAmbient: *noAmbient, NoTTS: *noTTS, Silent: *silent, Goal: *goal}
+		if err := queueSessionStart(config, queued, *dryRun, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	commands := stdinLines()
+
+	if *suggest {
+		records, err := loadHistory()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if suggestion, ok := printSessionSuggestion(records, time.Now()); ok {
+			if !explicit["duration"] {
+				*duration = suggestion.Minutes
+			}
+			if !explicit["mode"] {
+				modeName = suggestion.Mode
+			}
+		}
+		question := fmt.Sprintf("Start a %dm session in %s?", *duration, modeName)
+		if !confirm(question, true, commands) {
+			return
+		}
+	}
+
+	if !*dryRun {
+		if err := config.requireGuardianPIN([]string{modeName}, commands, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	session, err := startFocusSession(config, modeName, *duration, *autoRestore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if preset != nil && len(preset.OnCompleteRestore) > 0 {
+		categoriesConfig, err := loadCategoriesConfig(*categoriesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading categories: %v\n", err)
+			os.Exit(1)
+		}
+		session.RestoreCategories = preset.OnCompleteRestore
+		session.RestoreAt = preset.restoreTimes()
+		session.Categories = categoriesConfig
+	}
+	session.Goal = strings.TrimSpace(*goal)
+	if preset != nil {
+		session.Allowlist = preset.Allowlist
+	}
+
+	err = attachSessionHooks(session, config, sessionOptions{noAmbient: *noAmbient, noTTS: *noTTS, silent: *silent})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		if err := printSessionDryRun(os.Stdout, session); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !session.Break {
+			if destination, planned, err := sessionMoves(session); err == nil {
+				printStepsPreview([]previewStep{{Folder: destination, Names: planned}})
+			}
+		}
+		fmt.Println(dryRunFooter)
+		return
+	}
+
+	fmt.Printf("Starting %s session in mode: %s\n", formatDuration(session.Duration), modeName)
+	if session.Goal != "" {
+		fmt.Printf("Goal: %s\n", session.Goal)
+	}
+	fmt.Println("Commands: p = pause/resume, d = duck audio, w = countdown widget, q = stop")
+
+	if err := session.run(commands); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
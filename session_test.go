@@ -0,0 +1,152 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingHook records the lifecycle events it receives
+type recordingHook struct {
+	events []string
+}
+
+func (h *recordingHook) OnStart(fs *FocusSession)  { h.events = append(h.events, "start") }
+func (h *recordingHook) OnPause(fs *FocusSession)  { h.events = append(h.events, "pause") }
+func (h *recordingHook) OnResume(fs *FocusSession) { h.events = append(h.events, "resume") }
+func (h *recordingHook) OnEnd(fs *FocusSession)    { h.events = append(h.events, "end") }
+
+// TestFocusSessionPauseResume tests pause/resume state transitions and hook notifications
+func TestFocusSessionPauseResume(t *testing.T) {
+	hook := &recordingHook{}
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		StartTime: time.Now().Add(-5 * time.Minute),
+		State:     StateRunning,
+		Hooks:     []SessionHook{hook},
+	}
+
+	fs.pause()
+	if fs.State != StatePaused || fs.PausedAt == nil {
+		t.Fatalf("Expected paused session, got state %v", fs.State)
+	}
+
+	// Pausing twice should be a no-op
+	fs.pause()
+
+	*fs.PausedAt = fs.PausedAt.Add(-2 * time.Minute)
+	fs.resume()
+	if fs.State != StateRunning || fs.PausedAt != nil {
+		t.Fatalf("Expected running session, got state %v", fs.State)
+	}
+	if fs.PausedTotal < 2*time.Minute {
+		t.Errorf("Expected at least 2m paused, got %v", fs.PausedTotal)
+	}
+
+	fs.finish(StateCompleted)
+	fs.finish(StateInterrupted)
+	if fs.State != StateCompleted {
+		t.Errorf("Expected completed state to be final, got %v", fs.State)
+	}
+
+	want := []string{"pause", "resume", "end"}
+	if len(hook.events) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, hook.events)
+	}
+	for i := range want {
+		if hook.events[i] != want[i] {
+			t.Errorf("Expected events %v, got %v", want, hook.events)
+			break
+		}
+	}
+}
+
+// TestFocusSessionHandleCommand tests interactive session commands
+func TestFocusSessionHandleCommand(t *testing.T) {
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		StartTime: time.Now(),
+		State:     StateRunning,
+	}
+
+	fs.handleCommand("p")
+	if fs.State != StatePaused {
+		t.Errorf("Expected paused after 'p', got %v", fs.State)
+	}
+	fs.handleCommand(" P ")
+	if fs.State != StateRunning {
+		t.Errorf("Expected running after second 'p', got %v", fs.State)
+	}
+	fs.handleCommand("unknown")
+	if fs.State != StateRunning {
+		t.Errorf("Expected unknown command to be ignored, got %v", fs.State)
+	}
+	fs.handleCommand("q")
+	if fs.State != StateInterrupted {
+		t.Errorf("Expected interrupted after 'q', got %v", fs.State)
+	}
+	if fs.isActive() {
+		t.Error("Expected stopped session to be inactive")
+	}
+}
+
+// TestFocusSessionExtend tests that "e" adds time and the recorded duration follows
+func TestFocusSessionExtend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	writer := &sessionStateWriter{path: path}
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		Mode:      "focusmode",
+		StartTime: time.Now(),
+		Config:    &Config{Policy: &Policy{Strict: true}},
+		State:     StateRunning,
+		Hooks:     []SessionHook{writer},
+	}
+	writer.OnStart(fs)
+
+	// Extending is allowed in strict mode, it only makes the session longer
+	fs.handleCommand("e")
+	if fs.Duration != 30*time.Minute {
+		t.Errorf("Expected 30m after 'e', got %v", fs.Duration)
+	}
+	state, err := readActiveSession(path)
+	if err != nil || state == nil || state.DurationSeconds != 1800 {
+		t.Errorf("Expected the recorded duration to be extended, got %+v (%v)", state, err)
+	}
+}
+
+// TestFocusSessionStop tests that a session stopped from outside is suspended or ended
+func TestFocusSessionStop(t *testing.T) {
+	for _, restoreOnStop := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "session.json")
+		stop := make(chan struct{})
+		close(stop)
+		fs := &FocusSession{
+			Duration:       25 * time.Minute,
+			Mode:           "focusmode",
+			StartTime:      time.Now(),
+			Config:         &Config{},
+			State:          StateRunning,
+			MovedShortcuts: []string{}, // already organized, as for a resumed session
+			Hooks:          []SessionHook{&sessionStateWriter{path: path}},
+			Stop:           stop,
+			RestoreOnStop:  restoreOnStop,
+		}
+
+		if err := fs.run(nil); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+		if fs.State != StateInterrupted {
+			t.Errorf("Expected interrupted state, got %v", fs.State)
+		}
+		_, err := os.Stat(path)
+		if restoreOnStop {
+			if fs.Suspended || !os.IsNotExist(err) {
+				t.Errorf("Expected session to end and remove its state, suspended=%v, stat error=%v", fs.Suspended, err)
+			}
+		} else if !fs.Suspended || err != nil {
+			t.Errorf("Expected session to be suspended with its state kept, suspended=%v, stat error=%v", fs.Suspended, err)
+		}
+	}
+}
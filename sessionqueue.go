@@ -0,0 +1,630 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queuedSession is a session started later by the daemon, queued with "session start -at"
+type queuedSession struct {
+	ID          int       `json:"id"`
+	At          time.Time `json:"at"`
+	Mode        string    `json:"mode"`
+	Minutes     int       `json:"minutes"`
+	AutoRestore bool      `json:"auto_restore"`
+	Preset      string    `json:"preset,omitempty"`
+	NoAmbient   bool      `json:"no_ambient,omitempty"`
+	NoTTS       bool      `json:"no_tts,omitempty"`
+	Silent      bool      `json:"silent,omitempty"`
+	Goal        string    `json:"goal,omitempty"`
+}
+
+// sessionQueueInterval is how often the daemon looks for queued sessions that are due
+const sessionQueueInterval = 30 * time.Second
+
+// end returns when the queued session is planned to end
+func (q queuedSession) end() time.Time {
+	return q.At.Add(time.Duration(q.Minutes) * time.Minute)
+}
+
+// args returns the "session start" arguments the daemon runs the queued session with,
+// shortened to end as planned when the daemon gets to it late
+func (q queuedSession) args(now time.Time) []string {
+	minutes := q.Minutes
+	if late := now.Sub(q.At); late >= time.Minute {
+		minutes -= int(late / time.Minute)
+	}
+	args := []string{"session", "start", "-mode", q.Mode, "-duration", strconv.Itoa(minutes), "-auto-restore=" + strconv.FormatBool(q.AutoRestore)}
+	if q.Preset != "" {
+		args = append(args, "-preset", q.Preset)
+	}
+	if q.NoAmbient {
+		args = append(args, "-no-ambient")
+	}
+	if q.NoTTS {
+		args = append(args, "-no-tts")
+	}
+	if q.Silent {
+		args = append(args, "-silent")
+	}
+	if q.Goal != "" {
+		args = append(args, "-goal", q.Goal)
+	}
+	return args
+}
+
+// sessionQueuePath returns the path of the list of queued sessions
+func sessionQueuePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session-queue.json"), nil
+}
+
+// readSessionQueue returns the queued sessions at path, soonest first
+func readSessionQueue(path string) ([]queuedSession, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading session queue: %w", err)
+	}
+	var queue []queuedSession
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, fmt.Errorf("error parsing session queue: %w", err)
+	}
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].At.Before(queue[j].At) })
+	return queue, nil
+}
+
+// writeSessionQueue records the queued sessions at path, removing the file when none are left
+func writeSessionQueue(path string, queue []queuedSession) error {
+	if len(queue) == 0 {
+		if err := removeFile(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error writing session queue: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding session queue: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing session queue: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// queueSession adds a session to the queue at path, numbering it after the others
+// A session overlapping one already queued is refused, since only one session runs at a time
+func queueSession(path string, session queuedSession) (queuedSession, error) {
+	queue, err := readSessionQueue(path)
+	if err != nil {
+		return session, err
+	}
+	session.ID = 1
+	for _, q := range queue {
+		if session.At.Before(q.end()) && q.At.Before(session.end()) {
+			return session, fmt.Errorf("it would overlap session #%d, %s in %s at %s", q.ID, formatDuration(time.Duration(q.Minutes)*time.Minute), q.Mode, formatQueueTime(q.At, session.At))
+		}
+		session.ID = max(session.ID, q.ID+1)
+	}
+	return session, writeSessionQueue(path, append(queue, session))
+}
+
+// takeDueSessions removes the sessions due at now from the queue at path and returns them
+// Sessions whose whole time passed while the daemon was stopped are dropped and returned as missed
+func takeDueSessions(path string, now time.Time) (due, missed []queuedSession, err error) {
+	queue, err := readSessionQueue(path)
+	if err != nil || len(queue) == 0 {
+		return nil, nil, err
+	}
+	var waiting []queuedSession
+	for _, q := range queue {
+		switch {
+		case q.At.After(now):
+			waiting = append(waiting, q)
+		case q.end().Sub(now) < time.Minute:
+			missed = append(missed, q)
+		default:
+			due = append(due, q)
+		}
+	}
+	if len(due) == 0 && len(missed) == 0 {
+		return nil, nil, nil
+	}
+	return due, missed, writeSessionQueue(path, waiting)
+}
+
+// watchSessionQueue starts queued sessions as they come due until ctx is cancelled; run by the daemon
+// Sessions run in child processes of their own, like those started from a hotkey
+func watchSessionQueue(ctx context.Context, run commandRunner, beat func()) {
+	notifiers := []Notifier{consoleNotifier{}}
+	ticker := time.NewTicker(sessionQueueInterval)
+	defer ticker.Stop()
+	for {
+		beat()
+		if path, err := sessionQueuePath(); err == nil {
+			startDueSessions(ctx, path, time.Now(), run, notifiers)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDueSessions starts the sessions in the queue at path that are due at now
+func startDueSessions(ctx context.Context, path string, now time.Time, run commandRunner, notifiers []Notifier) {
+	due, missed, err := takeDueSessions(path, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	for _, q := range missed {
+		notifyAll(notifiers, "Queued session missed", fmt.Sprintf("The %s session queued for %s was over before the daemon could start it", q.Mode, q.At.Format("15:04")))
+	}
+	for _, q := range due {
+		if isSessionRunning() {
+			notifyAll(notifiers, "Queued session skipped", fmt.Sprintf("The %s session queued for %s wasn't started because another session is running", q.Mode, q.At.Format("15:04")))
+			continue
+		}
+		fmt.Printf("%s session queue: starting #%d, %dm in %s\n", now.Format(time.RFC3339), q.ID, q.Minutes, q.Mode)
+		spanCtx, span := startSpan(ctx, "queued session")
+		span.set("focusmode.mode", q.Mode)
+		go func(q queuedSession) {
+			output, err := run(spanCtx, q.args(now)...)
+			span.finish(err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error running queued session #%d: %v\n%s", q.ID, err, output)
+			}
+		}(q)
+		// Queued sessions don't overlap, so any earlier one due with it was missed
+		return
+	}
+}
+
+// queueSessionStart checks a session to be started later and queues it for the daemon
+// Unless forced, a session is refused when the schedule or a calendar meeting would act during it
+func queueSessionStart(config *Config, session queuedSession, dryRun, force bool) error {
+	if session.At.IsZero() {
+		return fmt.Errorf("give a start time, with -at 15:00 or a description such as \"for 2 hours at 3pm\"")
+	}
+	if session.Minutes <= 0 {
+		return fmt.Errorf("duration must be positive, got: %d minutes", session.Minutes)
+	}
+	if err := config.checkSessionDuration(session.Minutes); err != nil {
+		return err
+	}
+	if _, err := config.getModeConfig(session.Mode); err != nil {
+		return fmt.Errorf("invalid mode '%s'. Available modes: %v", session.Mode, config.getAvailableModes())
+	}
+	// Nobody is there to type the PIN when the daemon starts the session
+	if config.guardianProtects(session.Mode) {
+		return fmt.Errorf("%s is protected by the guardian, so it can't be queued; start it when it's due instead", session.Mode)
+	}
+
+	meetings, err := upcomingMeetings(config, session.At, session.end())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't check the calendar for meetings: %v\n", err)
+	}
+	if conflicts := sessionConflicts(config, session, meetings); len(conflicts) > 0 {
+		if !force {
+			return fmt.Errorf("the session would clash with:\n  - %s\nPick another time, or queue it anyway with \"session queue add -force\"", strings.Join(conflicts, "\n  - "))
+		}
+		for _, conflict := range conflicts {
+			fmt.Fprintf(os.Stderr, "Warning: the session clashes with %s\n", conflict)
+		}
+	}
+
+	now := time.Now()
+	length := formatDuration(time.Duration(session.Minutes) * time.Minute)
+	if dryRun {
+		fmt.Printf("[DRY RUN] Would queue a %s session in %s, started by the daemon at %s\n", length, session.Mode, formatQueueTime(session.At, now))
+		fmt.Println(dryRunFooter)
+		return nil
+	}
+	path, err := sessionQueuePath()
+	if err != nil {
+		return err
+	}
+	session, err = queueSession(path, session)
+	if err != nil {
+		return fmt.Errorf("can't queue the session: %w", err)
+	}
+	fmt.Printf("%sQueued session #%d: %s in %s at %s\n", glyph("⏰ "), session.ID, length, session.Mode, formatQueueTime(session.At, now))
+	if pidPath, err := daemonPIDPath(); err == nil {
+		if running, _ := readDaemonPID(pidPath); running == nil {
+			fmt.Fprintln(os.Stderr, "Warning: the daemon isn't running; start it with \"focusmode daemon start\" so the session starts on time")
+		}
+	}
+	return nil
+}
+
+// sessionConflicts returns what else would act on the desktop during a session to be queued:
+// schedule windows opening or closing, and calendar meetings that start meeting mode
+// Other queued sessions are checked by queueSession, since those can never overlap
+func sessionConflicts(config *Config, session queuedSession, meetings []calendarMeeting) []string {
+	var conflicts []string
+	end := session.end()
+	if windows, err := config.scheduleWindows(); err == nil {
+		for _, w := range windows {
+			action := "applies"
+			if w.active(session.At) {
+				action = "restores"
+			}
+			if next := w.nextChange(session.At); !next.IsZero() && next.Before(end) {
+				conflicts = append(conflicts, fmt.Sprintf("the schedule %s, which %s %s at %s", w.describe(), action, w.Mode, formatQueueTime(next, session.At)))
+			}
+		}
+	}
+	if config.Meeting.Enabled && config.Meeting.AutoStart {
+		for _, m := range meetings {
+			if m.videoLink() != "" && m.Start.Before(end) && session.At.Before(m.End) {
+				conflicts = append(conflicts, fmt.Sprintf("the meeting %q at %s, which starts meeting mode", m.Title, formatQueueTime(m.Start.Local(), session.At)))
+			}
+		}
+	}
+	return conflicts
+}
+
+// upcomingMeetings returns the calendar meetings between from and to when meetings start
+// sessions of their own, which is the only time they can clash with a queued session
+func upcomingMeetings(config *Config, from, to time.Time) ([]calendarMeeting, error) {
+	if !config.Meeting.Enabled || !config.Meeting.AutoStart {
+		return nil, nil
+	}
+	provider, err := getCalendarProvider(config.Calendar, config.Retry.Network)
+	if err != nil {
+		return nil, err
+	}
+	tokenPath, err := calendarTokenPath()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := validAccessToken(provider, config.Calendar, tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	return listMeetings(provider, accessToken, from, to)
+}
+
+// applySessionDescription fills in mode and duration from a session described in words after
+// the options, and returns when it starts, from the description or -at (zero for now)
+// Options given explicitly win over the description
+func applySessionDescription(words []string, startAt string, mode *string, duration *int, explicit map[string]bool) (time.Time, error) {
+	var at time.Time
+	if len(words) > 0 {
+		for _, word := range words {
+			if strings.HasPrefix(word, "-") {
+				return at, fmt.Errorf("options such as %s go before the session's description", word)
+			}
+		}
+		req, err := parseSessionRequest(strings.Join(words, " "), time.Now())
+		if err != nil {
+			return at, err
+		}
+		if req.Mode != "" && !explicit["mode"] {
+			*mode = req.Mode
+			explicit["mode"] = true
+		}
+		if req.Minutes > 0 && !explicit["duration"] {
+			*duration = req.Minutes
+			explicit["duration"] = true
+		}
+		at = req.At
+	}
+	if startAt != "" {
+		clock, err := parseStartClock(startAt)
+		if err != nil {
+			return at, fmt.Errorf("-at: %w", err)
+		}
+		at = nextClock(time.Now(), clock)
+	}
+	return at, nil
+}
+
+// formatQueueTime formats when a queued session starts, with the day when it isn't the same as now's
+func formatQueueTime(t, now time.Time) string {
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Format("15:04")
+	}
+	return t.Format("Mon 15:04")
+}
+
+// runSessionQueue handles "session queue", which lists, adds and removes queued sessions
+func runSessionQueue(args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		if len(args) > 1 {
+			printSessionUsage()
+			os.Exit(1)
+		}
+		listSessionQueue()
+		return
+	}
+	switch args[0] {
+	case "add":
+		runSessionQueueAdd(args[1:])
+	case "rm", "remove":
+		runSessionQueueRemove(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown session queue command: %s\n\n", args[0])
+		printSessionUsage()
+		os.Exit(1)
+	}
+}
+
+// listSessionQueue prints the queued sessions, soonest first
+func listSessionQueue() {
+	path, err := sessionQueuePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queue, err := readSessionQueue(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(queue) == 0 {
+		fmt.Println("No sessions queued")
+		return
+	}
+	now := time.Now()
+	fmt.Println("Queued sessions:")
+	for _, q := range queue {
+		goal := ""
+		if q.Goal != "" {
+			goal = fmt.Sprintf(" (%s)", q.Goal)
+		}
+		fmt.Printf("  #%-3d %-10s %-8s %s%s\n", q.ID, formatQueueTime(q.At, now), formatDuration(time.Duration(q.Minutes)*time.Minute), q.Mode, goal)
+	}
+}
+
+// runSessionQueueAdd handles "session queue add", which queues a session like "session start -at"
+// but can be forced past clashes with the schedule and calendar
+func runSessionQueueAdd(args []string) {
+	flags := flag.NewFlagSet("session queue add", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	presetName := flags.String("preset", "", "Session preset to use (mode, duration and restore options)")
+	mode := flags.String("mode", "", "Mode to apply during the session (uses default if not specified)")
+	duration := minutesVar(flags, "duration", 25, "Session duration: minutes (45) or a duration such as 1h30m or 1.5h")
+	flags.Var((*minutesFlag)(duration), "for", "Same as -duration")
+	startAt := flags.String("at", "", "Time of day to start, such as 15:00 or 3pm")
+	autoRestore := flags.Bool("auto-restore", true, "Restore moved shortcuts when the session ends")
+	noAmbient := flags.Bool("no-ambient", false, "Disable ambient sound for this session")
+	noTTS := flags.Bool("no-tts", false, "Disable spoken announcements for this session")
+	silent := flags.Bool("silent", false, "Don't play the chime for this session")
+	goal := flags.String("goal", "", "What the session is for, shown in the countdown widget")
+	force := flags.Bool("force", false, "Queue the session even if the schedule or a calendar meeting acts during it")
+	dryRun := flags.Bool("dry-run", false, "Show what would be queued without queueing it")
+	flags.Parse(args)
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	explicit["duration"] = explicit["duration"] || explicit["for"]
+	at, err := applySessionDescription(flags.Args(), *startAt, mode, duration, explicit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if !*dryRun {
+		if err := guardWrite("queue a focus session"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (use -dry-run to preview it)\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Explicit flags take precedence over the preset, as with "session start"
+	if *presetName != "" {
+		preset, err := config.getPreset(*presetName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !explicit["mode"] {
+			*mode = preset.Mode
+		}
+		if !explicit["duration"] && preset.Duration > 0 {
+			*duration = preset.Duration
+		}
+		if !explicit["auto-restore"] && preset.AutoRestore != nil {
+			*autoRestore = *preset.AutoRestore
+		}
+	}
+	if *mode == "" {
+		*mode = config.DefaultMode
+	}
+
+	session := queuedSession{At: at, Mode: *mode, Minutes: *duration, AutoRestore: *autoRestore, Preset: *presetName, NoAmbient: *noAmbient, NoTTS: *noTTS, Silent: *silent, Goal: strings.TrimSpace(*goal)}
+	if err := queueSessionStart(config, session, *dryRun, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runSessionQueueRemove handles "session queue rm", which removes queued sessions by number, or all of them
+func runSessionQueueRemove(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode session queue rm <number>... | all")
+		os.Exit(1)
+	}
+	if err := guardWrite("remove a queued session"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path, err := sessionQueuePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queue, err := readSessionQueue(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	remove := make(map[string]bool)
+	for _, arg := range args {
+		remove[strings.TrimPrefix(arg, "#")] = true
+	}
+	var kept []queuedSession
+	for _, q := range queue {
+		id := strconv.Itoa(q.ID)
+		if remove["all"] || remove[id] {
+			delete(remove, id)
+			continue
+		}
+		kept = append(kept, q)
+	}
+	delete(remove, "all")
+	for id := range remove {
+		fmt.Fprintf(os.Stderr, "Warning: no queued session #%s\n", id)
+	}
+	if len(kept) == len(queue) {
+		os.Exit(1)
+	}
+	if err := writeSessionQueue(path, kept); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%sRemoved %d queued session(s)\n", glyph("✓ "), len(queue)-len(kept))
+}
+
+// sessionRequest is a session described in words, e.g. "for 2 hours starting at 3pm in focusmode"
+type sessionRequest struct {
+	Mode    string
+	Minutes int
+	At      time.Time // Zero to start now
+}
+
+// sessionRequestWords are the words that begin a part of a session request
+var sessionRequestWords = map[string]bool{
+	"for": true, "at": true, "from": true, "starting": true, "start": true,
+	"until": true, "till": true, "in": true, "using": true, "mode": true,
+}
+
+// parseSessionRequest parses a session described in words, relative to now:
+//
+//	for 2 hours starting at 3pm in focusmode
+//	in gamemode from 21:00 until 23:30
+//	for 45m starting in 10 minutes
+//
+// "in" followed by a duration is a delay; followed by anything else it names the mode
+func parseSessionRequest(text string, now time.Time) (sessionRequest, error) {
+	var req sessionRequest
+	var until time.Duration
+	hasUntil := false
+	words := strings.Fields(strings.ToLower(text))
+	for i := 0; i < len(words); {
+		word := words[i]
+		if !sessionRequestWords[word] {
+			return req, fmt.Errorf("%q not understood in %q; describe a session like \"for 2 hours starting at 3pm in focusmode\"", word, text)
+		}
+		i++
+		if word == "starting" || word == "start" {
+			continue // "starting at" and "starting in" mean the same as "at" and "in"
+		}
+		end := i
+		for end < len(words) && !sessionRequestWords[words[end]] {
+			end++
+		}
+		part := strings.Join(words[i:end], " ")
+		if part == "" {
+			return req, fmt.Errorf("%q in %q is missing what follows it", word, text)
+		}
+		i = end
+
+		switch word {
+		case "for":
+			minutes, err := parseMinutes(part)
+			if err != nil {
+				return req, err
+			}
+			req.Minutes = minutes
+		case "at", "from":
+			clock, err := parseStartClock(part)
+			if err != nil {
+				return req, err
+			}
+			req.At = nextClock(now, clock)
+		case "until", "till":
+			clock, err := parseStartClock(part)
+			if err != nil {
+				return req, err
+			}
+			until, hasUntil = clock, true
+		case "in":
+			if delay, err := parseDuration(part); err == nil {
+				req.At = now.Add(delay).Truncate(time.Minute)
+				continue
+			}
+			if strings.Contains(part, " ") {
+				return req, fmt.Errorf("%q is neither a delay such as 10 minutes nor a mode name", part)
+			}
+			req.Mode = part
+		case "using", "mode":
+			req.Mode = part
+		}
+	}
+
+	if hasUntil {
+		if req.Minutes > 0 {
+			return req, fmt.Errorf("%q gives both a length and an end time; use one", text)
+		}
+		start := req.At
+		if start.IsZero() {
+			start = now
+		}
+		end := at(start, until)
+		if !end.After(start) {
+			end = at(start.AddDate(0, 0, 1), until)
+		}
+		req.Minutes = int(end.Sub(start) / time.Minute)
+	}
+	return req, nil
+}
+
+// parseStartClock parses a time of day such as 15:00, 3pm, 3:30 pm, noon or midnight into the time since midnight
+func parseStartClock(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), " ", ""))
+	switch value {
+	case "noon":
+		return 12 * time.Hour, nil
+	case "midnight":
+		return 0, nil
+	}
+	for _, layout := range []string{"15:04", "3pm", "3:04pm", "3.04pm", "15.04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a time like 15:00 or 3pm", value)
+}
+
+// nextClock returns the next time the clock shows the given time of day, today or tomorrow
+func nextClock(now time.Time, clock time.Duration) time.Time {
+	t := at(now, clock)
+	if !t.After(now) {
+		t = at(now.AddDate(0, 0, 1), clock)
+	}
+	return t
+}
@@ -0,0 +1,147 @@
+package focusmode
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseSessionRequest tests sessions described in words
+func TestParseSessionRequest(t *testing.T) {
+	now := time.Date(2026, 10, 12, 13, 20, 0, 0, time.Local)
+	tests := []struct {
+		text string
+		want sessionRequest
+	}{
+		{"for 2 hours starting at 3pm in focusmode", sessionRequest{Mode: "focusmode", Minutes: 120, At: time.Date(2026, 10, 12, 15, 0, 0, 0, time.Local)}},
+		{"in gamemode from 21:00 until 23:30", sessionRequest{Mode: "gamemode", Minutes: 150, At: time.Date(2026, 10, 12, 21, 0, 0, 0, time.Local)}},
+		{"for 45m starting in 10 minutes", sessionRequest{Minutes: 45, At: time.Date(2026, 10, 12, 13, 30, 0, 0, time.Local)}},
+		{"at 9:30 am for 1.5h", sessionRequest{Minutes: 90, At: time.Date(2026, 10, 13, 9, 30, 0, 0, time.Local)}}, // Already past, so tomorrow
+		{"until 23:00 using deep", sessionRequest{Mode: "deep", Minutes: 580}},
+		{"For 25", sessionRequest{Minutes: 25}},
+	}
+	for _, tt := range tests {
+		got, err := parseSessionRequest(tt.text, now)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSessionRequest(%q) = %+v, %v; want %+v", tt.text, got, err, tt.want)
+		}
+	}
+
+	errors := map[string]string{
+		"tomorrow morning":           `"tomorrow" not understood`,
+		"for":                        "missing what follows",
+		"at teatime":                 "not a time like 15:00",
+		"for 2h until 17:00":         "both a length and an end time",
+		"in deep work":               "neither a delay",
+		"for 2 fortnights at 3pm":    `unknown unit "fortnights"`,
+		"for 2 hours starting at 25": "not a time like 15:00",
+	}
+	for text, want := range errors {
+		if _, err := parseSessionRequest(text, now); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseSessionRequest(%q) error = %v, want one containing %q", text, err, want)
+		}
+	}
+}
+
+// TestSessionQueue tests queueing sessions and taking them as they come due
+func TestSessionQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session-queue.json")
+	three := time.Date(2026, 10, 12, 15, 0, 0, 0, time.Local)
+
+	first, err := queueSession(path, queuedSession{At: three, Mode: "focusmode", Minutes: 120})
+	if err != nil || first.ID != 1 {
+		t.Fatalf("Expected session #1, got %+v (err %v)", first, err)
+	}
+	if _, err := queueSession(path, queuedSession{At: three.Add(time.Hour), Mode: "gamemode", Minutes: 30}); err == nil || !strings.Contains(err.Error(), "overlap session #1") {
+		t.Errorf("Expected an overlap error, got %v", err)
+	}
+	second, err := queueSession(path, queuedSession{At: three.Add(-time.Hour), Mode: "gamemode", Minutes: 60})
+	if err != nil || second.ID != 2 {
+		t.Fatalf("Expected session #2 to fit before #1, got %+v (err %v)", second, err)
+	}
+	if queue, _ := readSessionQueue(path); len(queue) != 2 || queue[0].ID != 2 {
+		t.Errorf("Expected the queue soonest first, got %+v", queue)
+	}
+
+	// #2 was over while the daemon was stopped; #1 is due
+	due, missed, err := takeDueSessions(path, three.Add(10*time.Minute))
+	if err != nil || len(due) != 1 || due[0].ID != 1 || len(missed) != 1 || missed[0].ID != 2 {
+		t.Fatalf("Expected #1 due and #2 missed, got %+v and %+v (err %v)", due, missed, err)
+	}
+	if queue, _ := readSessionQueue(path); len(queue) != 0 {
+		t.Errorf("Expected an empty queue, got %+v", queue)
+	}
+
+	// Started late, a session still ends when planned
+	args := due[0].args(three.Add(10*time.Minute + 30*time.Second))
+	want := []string{"session", "start", "-mode", "focusmode", "-duration", "110", "-auto-restore=false"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+}
+
+// TestStartDueSessions tests that the daemon starts a due session in its own process
+func TestStartDueSessions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "session-queue.json")
+	now := time.Date(2026, 10, 12, 15, 0, 0, 0, time.Local)
+	queueSession(path, queuedSession{At: now, Mode: "focusmode", Minutes: 25, AutoRestore: true, Preset: "pomodoro", NoTTS: true, Silent: true})
+	queueSession(path, queuedSession{At: now.Add(time.Hour), Mode: "gamemode", Minutes: 25})
+
+	calls := make(chan []string, 2)
+	run := func(ctx context.Context, args ...string) (string, error) {
+		calls <- args
+		return "", nil
+	}
+	startDueSessions(context.Background(), path, now, run, nil)
+	select {
+	case args := <-calls:
+		want := []string{"session", "start", "-mode", "focusmode", "-duration", "25", "-auto-restore=true", "-preset", "pomodoro", "-no-tts", "-silent"}
+		if !reflect.DeepEqual(args, want) {
+			t.Errorf("Expected %v, got %v", want, args)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the due session to be started")
+	}
+	if queue, _ := readSessionQueue(path); len(queue) != 1 || queue[0].Mode != "gamemode" {
+		t.Errorf("Expected only the later session left, got %+v", queue)
+	}
+}
+
+// TestSessionConflicts tests that a queued session is checked against the schedule and meetings
+func TestSessionConflicts(t *testing.T) {
+	config := &Config{
+		Modes:    map[string]ModeConfig{"focusmode": {}, "gamemode": {}},
+		Schedule: []ScheduleEntry{{Mode: "gamemode", Days: []string{"daily"}, From: "17:00", To: "19:00"}},
+		Meeting:  MeetingConfig{Enabled: true, AutoStart: true},
+	}
+	session := queuedSession{At: time.Date(2026, 10, 12, 15, 0, 0, 0, time.Local), Mode: "focusmode", Minutes: 60}
+	meetings := []calendarMeeting{
+		{Title: "Standup", Start: session.At.Add(30 * time.Minute), End: session.At.Add(45 * time.Minute), Details: []string{"https://meet.google.com/abc-defg-hij"}},
+		{Title: "Lunch", Start: session.At.Add(15 * time.Minute), End: session.At.Add(time.Hour)},
+	}
+
+	conflicts := sessionConflicts(config, session, meetings)
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0], `"Standup"`) {
+		t.Errorf("Expected only the video meeting to clash, got %v", conflicts)
+	}
+
+	// Running into the schedule window, which applies gamemode at 17:00
+	session.Minutes = 150
+	conflicts = sessionConflicts(config, session, nil)
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0], "applies gamemode") {
+		t.Errorf("Expected the schedule to clash, got %v", conflicts)
+	}
+
+	// Meetings don't start sessions of their own without auto_start
+	config.Meeting.AutoStart = false
+	session.Minutes = 60
+	if conflicts := sessionConflicts(config, session, meetings); len(conflicts) != 0 {
+		t.Errorf("Expected no clashes, got %v", conflicts)
+	}
+}
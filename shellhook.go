@@ -0,0 +1,125 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ShellHookConfig represents the commands refused by the shell hook
+type ShellHookConfig struct {
+	Commands []string `yaml:"commands" doc:"Commands refused in the terminal during strict sessions, once the hook from focusmode shell-hook is loaded" example:"[steam, discord]"`
+}
+
+// shellCommandPattern limits blocked command names to ones safe to define as shell functions
+var shellCommandPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._+-]*$`)
+
+// shellHookCommands returns the valid command names to block, reporting the others
+func (c ShellHookConfig) shellHookCommands() ([]string, []string) {
+	var valid, invalid []string
+	for _, command := range c.Commands {
+		if shellCommandPattern.MatchString(command) {
+			valid = append(valid, command)
+		} else {
+			invalid = append(invalid, command)
+		}
+	}
+	return valid, invalid
+}
+
+// shellHook returns a script for the shell that wraps each command in a function
+// which asks focusmode shell-guard first; only wrapped commands pay for the check
+func shellHook(shell, focusmode string, commands []string) (string, error) {
+	var script strings.Builder
+	switch shell {
+	case "bash", "zsh":
+		fmt.Fprintf(&script, "# FocusMode shell hook: refuses these commands during strict sessions\n")
+		for _, command := range commands {
+			fmt.Fprintf(&script, "%s() { %s shell-guard %s && command %s \"$@\"; }\n", command, shellQuote(focusmode), command, command)
+		}
+	case "pwsh":
+		fmt.Fprintf(&script, "# FocusMode shell hook: refuses these commands during strict sessions\n")
+		for _, command := range commands {
+			fmt.Fprintf(&script, "function global:%s { & %s shell-guard %s; if ($LASTEXITCODE -eq 0) { & (Get-Command %s -CommandType Application | Select-Object -First 1) @args } }\n",
+				command, powershellQuote(focusmode), command, command)
+		}
+	default:
+		return "", fmt.Errorf("unknown shell %q (expected zsh, bash or pwsh)", shell)
+	}
+	return script.String(), nil
+}
+
+// shellQuote quotes a word for bash and zsh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote quotes a word for PowerShell
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// shellGuardMessage returns why a command is refused, or "" when it may run
+// Only strict sessions refuse commands; other sessions can be ended anyway
+func shellGuardMessage(session *activeSession, command string, now time.Time) string {
+	if session == nil || !session.Strict {
+		return ""
+	}
+	remaining := session.remaining(now)
+	if remaining <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s is blocked during your %s session: %s left", command, session.Mode, promptRemaining(remaining))
+}
+
+// runShellGuardCommand handles the "shell-guard" subcommand run by the shell hook
+// Like prompt, it only reads the session marker so it adds little to each wrapped command
+func runShellGuardCommand(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode shell-guard <command>")
+		os.Exit(2)
+	}
+	var session *activeSession
+	if path, err := activeSessionPath(); err == nil {
+		session, _ = readActiveSession(path)
+	}
+	if message := shellGuardMessage(session, args[0], time.Now()); message != "" {
+		fmt.Fprintf(os.Stderr, "%s%s\n", glyph("🔒 "), message)
+		os.Exit(1)
+	}
+}
+
+// runShellHookCommand handles the "shell-hook" subcommand, which prints the hook for a shell
+func runShellHookCommand(args []string) {
+	flags := flag.NewFlagSet("shell-hook", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	command := flags.String("command", "focusmode", "Command the hook runs, if focusmode isn't on PATH")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode shell-hook [-config profile.yml] [-command focusmode] zsh|bash|pwsh")
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	commands, invalid := config.ShellHook.shellHookCommands()
+	for _, name := range invalid {
+		fmt.Fprintf(os.Stderr, "Warning: skipping shell_hook command %q, which isn't a plain command name\n", name)
+	}
+	if len(commands) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: shell_hook.commands is empty, so the hook blocks nothing")
+	}
+
+	script, err := shellHook(flags.Arg(0), *command, commands)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(script)
+}
@@ -0,0 +1,54 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestShellHook tests the functions generated for each shell
+func TestShellHook(t *testing.T) {
+	script, err := shellHook("zsh", "/opt/focus mode/focusmode", []string{"steam"})
+	if err != nil {
+		t.Fatalf("shellHook() returned error: %v", err)
+	}
+	if want := `steam() { '/opt/focus mode/focusmode' shell-guard steam && command steam "$@"; }`; !strings.Contains(script, want) {
+		t.Errorf("Expected %q in:\n%s", want, script)
+	}
+
+	script, _ = shellHook("pwsh", "focusmode", []string{"discord"})
+	if want := "function global:discord { & 'focusmode' shell-guard discord;"; !strings.Contains(script, want) {
+		t.Errorf("Expected %q in:\n%s", want, script)
+	}
+
+	if _, err := shellHook("fish", "focusmode", nil); err == nil {
+		t.Error("Expected an error for an unknown shell")
+	}
+}
+
+// TestShellHookCommands tests that names unsafe to define as functions are skipped
+func TestShellHookCommands(t *testing.T) {
+	valid, invalid := ShellHookConfig{Commands: []string{"steam", "g++", "rm -rf", "$(x)"}}.shellHookCommands()
+	if strings.Join(valid, ",") != "steam,g++" || len(invalid) != 2 {
+		t.Errorf("Unexpected split: %v, %v", valid, invalid)
+	}
+}
+
+// TestShellGuardMessage tests that only running strict sessions refuse commands
+func TestShellGuardMessage(t *testing.T) {
+	now := time.Now()
+	session := &activeSession{Mode: "focusmode", StartTime: now.Add(-5 * time.Minute), DurationSeconds: 25 * 60, Strict: true}
+	if got := shellGuardMessage(session, "steam", now); got != "steam is blocked during your focusmode session: 20m left" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+
+	for name, s := range map[string]*activeSession{
+		"no session": nil,
+		"not strict": {Mode: "focusmode", StartTime: now, DurationSeconds: 25 * 60},
+		"over":       {Mode: "focusmode", StartTime: now.Add(-time.Hour), DurationSeconds: 25 * 60, Strict: true},
+	} {
+		if got := shellGuardMessage(s, "steam", now); got != "" {
+			t.Errorf("%s: expected the command to be allowed, got %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// localAddressPrefix marks a --remote address as the path of a local daemon socket
+const localAddressPrefix = "unix:"
+
+// socketPath returns the path of the daemon's local control socket
+// The socket lives in a private directory so only its owner can reach it
+func socketPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "run", "daemon.sock"), nil
+}
+
+// prepareSocketDir creates the socket's directory and makes sure it is private to its owner
+// On Windows the directory inherits the owner-only ACL of the user's profile instead
+func prepareSocketDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating socket directory: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("error checking socket directory: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return fmt.Errorf("socket directory %s is accessible to other users: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// listenSocket listens on a UNIX domain socket that only the current user can connect to
+// A socket left behind by a daemon that didn't shut down cleanly is replaced,
+// but a live socket or any other kind of file is never removed
+func listenSocket(path string) (net.Listener, error) {
+	if err := prepareSocketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", path, err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("error restricting socket permissions: %w", err)
+		}
+	}
+	return listener, nil
+}
+
+// isLocalAddress reports whether a --remote address names a local socket
+func isLocalAddress(address string) bool {
+	return strings.HasPrefix(address, localAddressPrefix)
+}
+
+// dialSocket returns a dial function that connects to the socket at path whatever address is asked for
+func dialSocket(path string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
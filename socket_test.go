@@ -0,0 +1,113 @@
+package focusmode
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// shortTempDir returns a temporary directory with a path short enough for a socket
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "fm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// TestListenSocket tests socket permissions and stale socket handling
+func TestListenSocket(t *testing.T) {
+	dir := shortTempDir(t)
+	path := filepath.Join(dir, "run", "daemon.sock")
+
+	listener, err := listenSocket(path)
+	if err != nil {
+		t.Fatalf("listenSocket() returned error: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(filepath.Dir(path))
+		if perm := info.Mode().Perm(); perm != 0700 {
+			t.Errorf("Expected socket directory mode 0700, got %o", perm)
+		}
+		info, _ = os.Stat(path)
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected socket mode 0600, got %o", perm)
+		}
+	}
+
+	if _, err := listenSocket(path); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("Expected error for a live socket, got %v", err)
+	}
+
+	// Leave a stale socket behind as a crashed daemon would
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	listener, err = listenSocket(path)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+
+	file := filepath.Join(dir, "file.sock")
+	os.WriteFile(file, []byte("data"), 0644)
+	if _, err := listenSocket(file); err == nil {
+		t.Error("Expected error for a regular file at the socket path")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Error("Expected regular file to be left alone")
+	}
+}
+
+// TestRunRemoteOverSocket tests a local command end to end over the daemon socket
+func TestRunRemoteOverSocket(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "daemon.sock")
+	listener, err := listenSocket(path)
+	if err != nil {
+		t.Fatalf("listenSocket() returned error: %v", err)
+	}
+	runner := &fakeRunner{output: "✓ Moved: Discord.lnk\n"}
+	// The configured token is not needed on the socket
+	server := &http.Server{Handler: newDaemonServer(testDaemonConfig(), runner.run, "").localHandler()}
+	go server.Serve(listener)
+	defer server.Close()
+
+	opts, rest, err := parseRemoteArgs([]string{"--local", "apply", "focusmode"})
+	if err != nil || opts.Address != localAddressPrefix {
+		t.Fatalf("Expected --local to select the socket, got %+v, %v", opts, err)
+	}
+	opts.Address += path
+
+	client, err := remoteHTTPClient(opts)
+	if err != nil {
+		t.Fatalf("remoteHTTPClient() returned error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := runRemote(client, opts, rest, &out); err != nil {
+		t.Fatalf("runRemote() returned error: %v", err)
+	}
+	if out.String() != runner.output {
+		t.Errorf("Expected command output, got %q", out.String())
+	}
+	if len(runner.calls) != 1 || runner.calls[0][1] != "focusmode" {
+		t.Errorf("Unexpected calls: %v", runner.calls)
+	}
+}
+
+// TestIsRemoteFlag tests recognition of the flags that send commands to a daemon
+func TestIsRemoteFlag(t *testing.T) {
+	for arg, want := range map[string]bool{
+		"--remote": true, "-remote=desk:7420": true, "--local": true,
+		"-mode": false, "remote": false, "status": false,
+	} {
+		if got := isRemoteFlag(arg); got != want {
+			t.Errorf("isRemoteFlag(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// topLayer returns the most recently applied layer and the layers below it
+func topLayer(modes []appliedMode) (appliedMode, []appliedMode, bool) {
+	if len(modes) == 0 {
+		return appliedMode{}, nil, false
+	}
+	return modes[len(modes)-1], modes[:len(modes)-1], true
+}
+
+// restoreLayer moves exactly the shortcuts recorded in a layer's manifest back to the desktop, or
+// to their restore handlers' folders; policy settles names already taken, as in restoreShortcutWithPolicy
+// It returns how many were restored and the names that couldn't be
+func restoreLayer(config *Config, layer appliedMode, policy string, dryRun bool) (int, []string) {
+	restored := 0
+	var failed []string
+	var names []string
+	for _, shortcutName := range layer.Shortcuts {
+		if dryRun {
+			to := "Desktop"
+			if folder, ok, _ := config.handlerFolder(shortcutName); ok {
+				to = folder
+			}
+			fmt.Printf("[DRY RUN] Would restore: %s -> %s\n", shortcutName, to)
+			restored++
+			continue
+		}
+		result, err := config.restoreShortcut(shortcutName, layer.Destination, policy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring '%s': %v\n", shortcutName, err)
+			failed = append(failed, shortcutName)
+			continue
+		}
+		fmt.Println(result.describe(shortcutName))
+		if result.Skipped {
+			continue
+		}
+		if result.undoable(shortcutName) {
+			names = append(names, shortcutName)
+		}
+		restored++
+	}
+	if !dryRun {
+		moves, err := config.withRestoreHandlers(desktopMoves(layer.Mode, layer.Destination, names, true))
+		if err == nil {
+			recordOperation(operationRestore, layer.Mode, moves, "")
+		}
+	}
+	return restored, failed
+}
+
+// afterPop returns the layers left once a layer is popped: those below it, with the layer
+// kept on top holding only the shortcuts that failed to restore
+func afterPop(layer appliedMode, below []appliedMode, failed []string) []appliedMode {
+	if len(failed) == 0 {
+		return below
+	}
+	layer.Shortcuts = failed
+	return append(below[:len(below):len(below)], layer)
+}
+
+// runPushCommand handles the "push" subcommand, applying a mode as a new layer
+func runPushCommand(args []string) {
+	flags := flag.NewFlagSet("push", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be moved without actually moving")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode push [-config profile.yml] [-dry-run] <mode>")
+		os.Exit(1)
+	}
+	modeName := flags.Arg(0)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := config.getModeConfig(modeName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, active := range activeModeNames() {
+		if active == modeName {
+			fmt.Fprintf(os.Stderr, "Error: mode %s is already applied (pop or restore it first)\n", modeName)
+			os.Exit(1)
+		}
+	}
+
+	if _, err := applyModeWithDependencies(config, modeName, *dryRun, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runPopCommand handles the "pop" subcommand, restoring exactly the shortcuts of the top layer
+func runPopCommand(args []string) {
+	flags := flag.NewFlagSet("pop", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be restored without actually moving")
+	flags.StringVar(&onConflictFlag, "on-conflict", "", "When restoring onto a desktop file of the same name: fail, skip, overwrite, rename-with-suffix or keep-newer")
+	flags.Parse(args)
+	if err := validConflictPolicy(onConflictFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	path, err := appliedModesPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	modes, err := readAppliedModes(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	layer, below, ok := topLayer(modes)
+	if !ok {
+		fmt.Println("No modes applied - nothing to pop.")
+		return
+	}
+
+	config, err := readConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Popping mode: %s (%d shortcut(s) from %s)\n", layer.Mode, len(layer.Shortcuts), layer.Destination)
+	layer.Shortcuts = config.restorable(layer.Shortcuts)
+	restored, failed := restoreLayer(config, layer, config.Modes[layer.Mode].restoreConflictPolicy(), *dryRun)
+	if !*dryRun {
+		refreshHiddenMenu(config)
+	}
+
+	// Undo the color change if the popped mode made one, going back to the next layer's color
+	if config.Modes[layer.Mode].ColorTemperature != 0 && len(failed) == 0 {
+		restoreColorTemperature(*dryRun)
+		for i := len(below) - 1; i >= 0; i-- {
+			if modeConfig := config.Modes[below[i].Mode]; modeConfig.ColorTemperature != 0 {
+				applyModeColorTemperature(below[i].Mode, &modeConfig, *dryRun)
+				break
+			}
+		}
+	}
+
+	fmt.Println("\n--- Summary ---")
+	fmt.Printf("Mode: %s\n", layer.Mode)
+	fmt.Printf("Successfully restored: %d\n", restored)
+	if len(failed) > 0 {
+		fmt.Printf("Failed: %d\n", len(failed))
+	}
+	if *dryRun {
+		fmt.Println("(Dry run - no files were actually restored)")
+		return
+	}
+
+	// A layer with shortcuts still hidden stays on top, so popping again retries them
+	left := afterPop(layer, below, failed)
+	if err := writeAppliedModes(path, left); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d shortcut(s) of %s could not be restored; it stays on top, run pop again to retry\n", len(failed), layer.Mode)
+		os.Exit(1)
+	}
+	if top, _, ok := topLayer(left); ok {
+		fmt.Printf("Now on top: %s\n", top.Mode)
+	} else {
+		fmt.Println("No modes applied")
+	}
+}
@@ -0,0 +1,178 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// activeSession is the on-disk marker of a running session
+type activeSession struct {
+	PID             int           `json:"pid"`
+	Mode            string        `json:"mode"`
+	StartTime       time.Time     `json:"start_time"`
+	DurationSeconds int64         `json:"duration_seconds"`
+	Break           bool          `json:"break,omitempty"`
+	AutoRestore     bool          `json:"auto_restore,omitempty"`
+	MovedShortcuts  manifestNames `json:"moved_shortcuts,omitempty"` // Manifest used to recover after a crash
+	PausedAt        *time.Time    `json:"paused_at,omitempty"`       // When the session was paused (nil if running)
+	PausedSeconds   int64         `json:"paused_seconds,omitempty"`  // Time spent paused before PausedAt
+	Strict          bool          `json:"strict,omitempty"`          // Machine policy forbids ending the session early
+	Goal            string        `json:"goal,omitempty"`            // What the session is for
+	AllowSites      []string      `json:"allow_sites,omitempty"`     // The only sites expected, from the preset's allowlist
+}
+
+// remaining returns the time left in a running session, excluding paused time
+func (s *activeSession) remaining(now time.Time) time.Duration {
+	if s.PausedAt != nil {
+		now = *s.PausedAt
+	}
+	elapsed := now.Sub(s.StartTime) - time.Duration(s.PausedSeconds)*time.Second
+	if remaining := time.Duration(s.DurationSeconds)*time.Second - elapsed; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// activeSessionPath returns the path of the running session marker
+func activeSessionPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session.json"), nil
+}
+
+// writeActiveSession records a running session at path
+func writeActiveSession(path string, state activeSession) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding session state: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing session state: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// readActiveSession returns the running session recorded at path
+// It returns nil if no session is recorded or the recording process is gone
+func readActiveSession(path string) (*activeSession, error) {
+	state, err := readSessionFile(path)
+	if err != nil || state == nil || !processAlive(state.PID) {
+		return nil, err
+	}
+	return state, nil
+}
+
+// readSessionFile returns the session recorded at path whether or not it is still running
+func readSessionFile(path string) (*activeSession, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading session state: %w", err)
+	}
+
+	var state activeSession
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing session state: %w", err)
+	}
+	return &state, nil
+}
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens a handle on Windows and fails for missing processes
+		process.Release()
+		return true
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// isSessionRunning reports whether a FocusMode session is currently running
+func isSessionRunning() bool {
+	path, err := activeSessionPath()
+	if err != nil {
+		return false
+	}
+	state, err := readActiveSession(path)
+	return err == nil && state != nil
+}
+
+// sessionStateWriter is a session hook that maintains the running session marker
+type sessionStateWriter struct {
+	path string
+}
+
+// OnStart records the session as running
+func (w *sessionStateWriter) OnStart(fs *FocusSession) {
+	w.write(fs)
+}
+
+// OnExtend records the session's new duration, so a recovered session ends at the right time
+func (w *sessionStateWriter) OnExtend(fs *FocusSession) {
+	w.write(fs)
+}
+
+// write records the session's current state
+func (w *sessionStateWriter) write(fs *FocusSession) {
+	state := activeSession{
+		PID:             os.Getpid(),
+		Mode:            fs.Mode,
+		StartTime:       fs.StartTime,
+		DurationSeconds: int64(fs.Duration.Seconds()),
+		Break:           fs.Break,
+		AutoRestore:     fs.AutoRestore,
+		MovedShortcuts:  fs.MovedShortcuts,
+		PausedAt:        fs.PausedAt,
+		PausedSeconds:   int64(fs.PausedTotal.Seconds()),
+		Strict:          fs.Config.isStrict() && !fs.Break,
+		Goal:            fs.Goal,
+	}
+	if fs.Allowlist != nil && !fs.Break {
+		state.AllowSites = fs.Allowlist.Sites
+	}
+	if err := writeActiveSession(w.path, state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// OnPause records the pause, so readers such as "focusmode prompt" stop the countdown
+func (w *sessionStateWriter) OnPause(fs *FocusSession) {
+	w.write(fs)
+}
+
+// OnResume records the time spent paused
+func (w *sessionStateWriter) OnResume(fs *FocusSession) {
+	w.write(fs)
+}
+
+// OnEnd removes the running session marker, unless the session was suspended
+func (w *sessionStateWriter) OnEnd(fs *FocusSession) {
+	if fs.Suspended {
+		return
+	}
+	if err := removeFile(w.path); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "\nWarning: error removing session state: %v\n", err)
+	}
+}
+
+// DryRun reports the running session marker that would be written
+func (w *sessionStateWriter) DryRun(fs *FocusSession) []string {
+	return []string{fmt.Sprintf("record the running session in %s", w.path)}
+}
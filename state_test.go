@@ -0,0 +1,56 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestActiveSessionState tests writing, reading and clearing the running session marker
+func TestActiveSessionState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	state, err := readActiveSession(path)
+	if err != nil || state != nil {
+		t.Fatalf("Expected no session, got %+v (%v)", state, err)
+	}
+
+	writer := &sessionStateWriter{path: path}
+	fs := &FocusSession{Duration: 25 * time.Minute, Mode: "focusmode", StartTime: time.Now(), State: StateRunning}
+	writer.OnStart(fs)
+
+	state, err = readActiveSession(path)
+	if err != nil {
+		t.Fatalf("readActiveSession() returned error: %v", err)
+	}
+	if state == nil || state.Mode != "focusmode" || state.PID != os.Getpid() || state.DurationSeconds != 1500 {
+		t.Fatalf("Unexpected session state: %+v", state)
+	}
+
+	fs.Hooks = []SessionHook{writer}
+	fs.pause()
+	if state, _ = readActiveSession(path); state == nil || state.PausedAt == nil {
+		t.Fatalf("Expected the pause to be recorded, got %+v", state)
+	}
+	fs.resume()
+	if state, _ = readActiveSession(path); state == nil || state.PausedAt != nil {
+		t.Fatalf("Expected the resume to be recorded, got %+v", state)
+	}
+
+	writer.OnEnd(fs)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected session state to be removed on end")
+	}
+}
+
+// TestReadActiveSessionStale tests that markers left by dead processes are ignored
+func TestReadActiveSessionStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	writeActiveSession(path, activeSession{PID: -1, Mode: "focusmode", StartTime: time.Now()})
+
+	state, err := readActiveSession(path)
+	if err != nil || state != nil {
+		t.Errorf("Expected stale session to be ignored, got %+v (%v)", state, err)
+	}
+}
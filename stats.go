@@ -0,0 +1,392 @@
+package focusmode
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHeatmapWeeks = 12
+
+// heatmapCells are the shades used for empty days and the four activity levels
+var heatmapCells = []string{"·", "░", "▒", "▓", "█"}
+
+// dailyFocusMinutes sums focused minutes per local calendar day for focus sessions
+func dailyFocusMinutes(records []SessionRecord) map[string]int {
+	seconds := make(map[string]int64)
+	for _, record := range records {
+		if record.Kind != RecordSession {
+			continue
+		}
+		seconds[record.StartTime.Local().Format("2006-01-02")] += record.FocusedSeconds
+	}
+
+	minutes := make(map[string]int)
+	for day, s := range seconds {
+		minutes[day] = int(s / 60)
+	}
+	return minutes
+}
+
+// heatLevel maps focused minutes to a shade from 0 (none) to 4 (busiest day)
+func heatLevel(minutes, max int) int {
+	if minutes <= 0 || max <= 0 {
+		return 0
+	}
+	level := (minutes*4 + max - 1) / max // round up so any focus shows
+	if level > 4 {
+		return 4
+	}
+	return level
+}
+
+// printHeatmap writes a GitHub-style heatmap of the given weeks ending with the week of today
+// Rows are weekdays starting on Monday and each column is one week
+func printHeatmap(w io.Writer, minutes map[string]int, today time.Time, weeks int) {
+	start := startOfWeek(today).AddDate(0, 0, -7*(weeks-1))
+
+	max, total, activeDays := 0, 0, 0
+	for i := 0; i < weeks*7; i++ {
+		day := start.AddDate(0, 0, i)
+		if day.After(today) {
+			break
+		}
+		m := minutes[day.Format("2006-01-02")]
+		if m > max {
+			max = m
+		}
+		if m > 0 {
+			total += m
+			activeDays++
+		}
+	}
+
+	// Month labels above the first week of each month
+	labels := []rune(strings.Repeat(" ", weeks*2+2))
+	lastMonth, free := time.Month(0), 0
+	for week := 0; week < weeks; week++ {
+		monday := start.AddDate(0, 0, week*7)
+		if monday.Month() != lastMonth && week*2 >= free {
+			copy(labels[week*2:], []rune(monday.Format("Jan")))
+			lastMonth, free = monday.Month(), week*2+4
+		}
+	}
+	fmt.Fprintf(w, "    %s\n", strings.TrimRight(string(labels), " "))
+
+	weekdays := []string{"Mon", "", "Wed", "", "Fri", "", "Sun"}
+	for row := 0; row < 7; row++ {
+		line := fmt.Sprintf("%-3s ", weekdays[row])
+		for week := 0; week < weeks; week++ {
+			day := start.AddDate(0, 0, week*7+row)
+			if day.After(today) {
+				break
+			}
+			line += heatmapCells[heatLevel(minutes[day.Format("2006-01-02")], max)] + " "
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	fmt.Fprintf(w, "\n    Less %s More\n\n", strings.Join(heatmapCells, " "))
+	fmt.Fprintf(w, "Focused: %s over %d day(s)", formatDuration(time.Duration(total)*time.Minute), activeDays)
+	if max > 0 {
+		fmt.Fprintf(w, ", best day %s", formatDuration(time.Duration(max)*time.Minute))
+	}
+	fmt.Fprintln(w)
+}
+
+// statsSummary holds the totals printed by "focusmode stats"
+type statsSummary struct {
+	Days          []periodTotal // Focused time per day, oldest first
+	Weeks         []periodTotal // Focused time per week starting on Monday, oldest first
+	Sessions      int
+	Completed     int
+	Average       time.Duration // Average focused time per session
+	AveragePauses float64
+	LongestStreak int // Most days in a row with any focus
+	CurrentStreak int // Days in a row up to today, or yesterday if nothing is recorded today yet
+}
+
+// periodTotal is the focused time and number of sessions of a day or week
+type periodTotal struct {
+	Start    time.Time
+	Focused  time.Duration
+	Sessions int
+}
+
+// summarizeHistory totals the focus sessions of the history for the days and weeks up to today
+// Averages and streaks cover the whole history
+func summarizeHistory(records []SessionRecord, today time.Time, days, weeks int) statsSummary {
+	var summary statsSummary
+	for i := days - 1; i >= 0; i-- {
+		start := today.AddDate(0, 0, -i)
+		summary.Days = append(summary.Days, periodTotal{Start: start})
+	}
+	monday := startOfWeek(today)
+	for i := weeks - 1; i >= 0; i-- {
+		summary.Weeks = append(summary.Weeks, periodTotal{Start: monday.AddDate(0, 0, -7*i)})
+	}
+	add := func(totals []periodTotal, length int, record SessionRecord) {
+		for i := range totals {
+			end := totals[i].Start.AddDate(0, 0, length)
+			if !record.StartTime.Before(totals[i].Start) && record.StartTime.Before(end) {
+				totals[i].Focused += time.Duration(record.FocusedSeconds) * time.Second
+				totals[i].Sessions++
+			}
+		}
+	}
+
+	var focused time.Duration
+	pauses := 0
+	for _, record := range records {
+		if record.Kind != RecordSession {
+			continue
+		}
+		summary.Sessions++
+		if record.Completed {
+			summary.Completed++
+		}
+		focused += time.Duration(record.FocusedSeconds) * time.Second
+		pauses += record.Pauses
+		add(summary.Days, 1, record)
+		add(summary.Weeks, 7, record)
+	}
+	if summary.Sessions > 0 {
+		summary.Average = focused / time.Duration(summary.Sessions)
+		summary.AveragePauses = float64(pauses) / float64(summary.Sessions)
+	}
+	summary.LongestStreak, summary.CurrentStreak = focusStreaks(dailyFocusMinutes(records), today)
+	return summary
+}
+
+// focusStreaks returns the most days in a row with focused time, and the run up to today
+// A run ending yesterday still counts as current, since today may not have had a session yet
+func focusStreaks(minutes map[string]int, today time.Time) (longest, current int) {
+	var days []string
+	for day, m := range minutes {
+		if m > 0 {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	run := 0
+	var previous time.Time
+	for _, day := range days {
+		date, err := time.ParseInLocation("2006-01-02", day, today.Location())
+		if err != nil {
+			continue
+		}
+		if run > 0 && date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = date
+		if run > longest {
+			longest = run
+		}
+	}
+	day := today
+	if minutes[day.Format("2006-01-02")] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for minutes[day.Format("2006-01-02")] > 0 {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return longest, current
+}
+
+// printStatsSummary writes the daily and weekly totals, averages and streaks
+func printStatsSummary(w io.Writer, summary statsSummary) {
+	if summary.Sessions == 0 {
+		fmt.Fprintln(w, "No focus sessions recorded yet.")
+		return
+	}
+	fmt.Fprintf(w, "Last %d days:\n", len(summary.Days))
+	for _, day := range summary.Days {
+		fmt.Fprintf(w, "  %-10s  %8s  %d session(s)\n", day.Start.Format("Mon Jan 2"), formatDuration(day.Focused), day.Sessions)
+	}
+	fmt.Fprintf(w, "\nLast %d weeks:\n", len(summary.Weeks))
+	for _, week := range summary.Weeks {
+		fmt.Fprintf(w, "  %-10s  %8s  %d session(s)\n", "w/c "+week.Start.Format("Jan 2"), formatDuration(week.Focused), week.Sessions)
+	}
+	fmt.Fprintf(w, "\nSessions: %d (%d completed, %d stopped early)\n", summary.Sessions, summary.Completed, summary.Sessions-summary.Completed)
+	fmt.Fprintf(w, "Average session: %s, %.1f pause(s)\n", formatDuration(summary.Average.Round(time.Minute)), summary.AveragePauses)
+	fmt.Fprintf(w, "Longest streak: %d day(s); current streak: %d day(s)\n", summary.LongestStreak, summary.CurrentStreak)
+}
+
+// runStatsCommand handles the "stats" subcommand
+func runStatsCommand(args []string) {
+	if len(args) > 0 && args[0] == "distractions" {
+		runStatsDistractions(args[1:])
+		return
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		runStatsSummary(args)
+		return
+	}
+	if args[0] != "heatmap" {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode stats [-days N] [-weeks N] [-export csv|json [-since YYYY-MM-DD]]")
+		fmt.Fprintln(os.Stderr, "       focusmode stats heatmap [-weeks N]")
+		fmt.Fprintln(os.Stderr, "       focusmode stats distractions [-days N]")
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("stats heatmap", flag.ExitOnError)
+	weeks := flags.Int("weeks", defaultHeatmapWeeks, "Number of weeks to show")
+	flags.Parse(args[1:])
+
+	if *weeks <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -weeks must be positive")
+		os.Exit(1)
+	}
+
+	records, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Focused minutes per day, last %d weeks\n\n", *weeks)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	printHeatmap(os.Stdout, dailyFocusMinutes(records), today, *weeks)
+}
+
+// runStatsDistractions handles "stats distractions", which counts the relaunches of blocked apps
+func runStatsDistractions(args []string) {
+	flags := flag.NewFlagSet("stats distractions", flag.ExitOnError)
+	days := flags.Int("days", 7, "Number of days to count")
+	flags.Parse(args)
+
+	if *days <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -days must be positive")
+		os.Exit(1)
+	}
+	path, err := distractionLogPath()
+	if err == nil {
+		var events []DistractionEvent
+		if events, err = readDistractions(path); err == nil {
+			fmt.Printf("Last %d day(s)\n", *days)
+			printDistractions(os.Stdout, events, time.Now().AddDate(0, 0, -*days))
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error reading distraction log: %v\n", err)
+	os.Exit(1)
+}
+
+// runStatsSummary handles "stats" on its own, which prints totals, averages and streaks from the
+// history, or with -export writes the history's records as CSV or JSON
+func runStatsSummary(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	days := flags.Int("days", 7, "Number of days to total")
+	weeks := flags.Int("weeks", 4, "Number of weeks to total")
+	export := flags.String("export", "", "Write the recorded sessions, breaks and routines as csv or json instead")
+	sinceFlag := flags.String("since", "", "With -export, only the records started on or after this date (YYYY-MM-DD)")
+	flags.Parse(args)
+	if *days <= 0 || *weeks <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -days and -weeks must be positive")
+		os.Exit(1)
+	}
+	if *export != "" && *export != "csv" && *export != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown export format %q (use csv or json)\n", *export)
+		os.Exit(1)
+	}
+	if *sinceFlag != "" && *export == "" {
+		fmt.Fprintln(os.Stderr, "Error: -since is only used with -export")
+		os.Exit(1)
+	}
+	var since time.Time
+	if *sinceFlag != "" {
+		var err error
+		if since, err = time.ParseInLocation("2006-01-02", *sinceFlag, time.Local); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -since must be a date like 2024-01-31, got %q\n", *sinceFlag)
+			os.Exit(1)
+		}
+	}
+
+	records, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(1)
+	}
+	if *export != "" {
+		if err := exportHistory(os.Stdout, recordsSince(records, since), *export); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	printStatsSummary(os.Stdout, summarizeHistory(records, today, *days, *weeks))
+}
+
+// historyCSVColumns are the columns of an exported CSV, named as the fields of history.jsonl
+// Moved shortcuts are joined with semicolons and coding activity is reduced to its total
+var historyCSVColumns = []string{
+	"kind", "mode", "routine", "routine_step", "start_time", "end_time", "planned_seconds",
+	"focused_seconds", "paused_seconds", "pauses", "completed", "moved_shortcuts", "coding_seconds",
+}
+
+// recordsSince returns the records started at or after since; a zero since keeps them all
+func recordsSince(records []SessionRecord, since time.Time) []SessionRecord {
+	var kept []SessionRecord
+	for _, record := range records {
+		if !record.StartTime.Before(since) {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// exportHistory writes records as CSV with a header row, or as a JSON array of the records as
+// history.jsonl stores them; times are local RFC 3339 in both
+func exportHistory(w io.Writer, records []SessionRecord, format string) error {
+	if format == "json" {
+		local := make([]SessionRecord, len(records))
+		for i, record := range records {
+			record.StartTime, record.EndTime = record.StartTime.Local(), record.EndTime.Local()
+			local[i] = record
+		}
+		data, err := json.MarshalIndent(local, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding history: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(historyCSVColumns)
+	for _, record := range records {
+		coding := ""
+		if record.Coding != nil {
+			coding = strconv.FormatInt(record.Coding.TotalSeconds, 10)
+		}
+		step := ""
+		if record.RoutineStep > 0 {
+			step = strconv.Itoa(record.RoutineStep)
+		}
+		writer.Write([]string{
+			record.Kind, record.Mode, record.Routine, step,
+			record.StartTime.Local().Format(time.RFC3339), record.EndTime.Local().Format(time.RFC3339),
+			strconv.FormatInt(record.PlannedSeconds, 10), strconv.FormatInt(record.FocusedSeconds, 10),
+			strconv.FormatInt(record.PausedSeconds, 10), strconv.Itoa(record.Pauses),
+			strconv.FormatBool(record.Completed), strings.Join(record.MovedShortcuts, ";"), coding,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
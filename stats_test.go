@@ -0,0 +1,157 @@
+package focusmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDailyFocusMinutes tests that focused time is summed per day for sessions only
+func TestDailyFocusMinutes(t *testing.T) {
+	day := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	records := []SessionRecord{
+		{Kind: RecordSession, StartTime: day, FocusedSeconds: 90},
+		{Kind: RecordSession, StartTime: day.Add(2 * time.Hour), FocusedSeconds: 30},
+		{Kind: RecordBreak, StartTime: day.Add(time.Hour), FocusedSeconds: 300},
+		{Kind: RecordSession, StartTime: day.AddDate(0, 0, 1), FocusedSeconds: 1500},
+	}
+
+	minutes := dailyFocusMinutes(records)
+	if minutes["2024-05-06"] != 2 {
+		t.Errorf("Expected 2 minutes on 2024-05-06, got %d", minutes["2024-05-06"])
+	}
+	if minutes["2024-05-07"] != 25 {
+		t.Errorf("Expected 25 minutes on 2024-05-07, got %d", minutes["2024-05-07"])
+	}
+}
+
+// TestHeatLevel tests mapping minutes to shades relative to the busiest day
+func TestHeatLevel(t *testing.T) {
+	tests := []struct {
+		minutes, max, want int
+	}{
+		{0, 100, 0},
+		{1, 100, 1},
+		{25, 100, 1},
+		{26, 100, 2},
+		{75, 100, 3},
+		{100, 100, 4},
+		{10, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := heatLevel(tt.minutes, tt.max); got != tt.want {
+			t.Errorf("heatLevel(%d, %d) = %d, want %d", tt.minutes, tt.max, got, tt.want)
+		}
+	}
+}
+
+// TestPrintHeatmap tests the heatmap layout and that future days are left blank
+func TestPrintHeatmap(t *testing.T) {
+	today := time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC) // Wednesday
+	minutes := map[string]int{"2024-05-06": 120, "2024-04-29": 30}
+
+	var out bytes.Buffer
+	printHeatmap(&out, minutes, today, 4)
+	lines := strings.Split(out.String(), "\n")
+
+	if !strings.Contains(lines[0], "Apr") || !strings.Contains(lines[0], "May") {
+		t.Errorf("Expected month labels, got %q", lines[0])
+	}
+	if lines[1] != "Mon · · ░ █" {
+		t.Errorf("Unexpected Monday row: %q", lines[1])
+	}
+	if lines[3] != "Wed · · · ·" {
+		t.Errorf("Unexpected Wednesday row: %q", lines[3])
+	}
+	if lines[4] != "    · · ·" {
+		t.Errorf("Expected Thursday of this week to be blank, got %q", lines[4])
+	}
+	if !strings.Contains(out.String(), "Focused: 2h 30m over 2 day(s), best day 2h\n") {
+		t.Errorf("Unexpected summary: %s", out.String())
+	}
+}
+
+// TestSummarizeHistory tests the daily and weekly totals, averages and streaks of "stats"
+func TestSummarizeHistory(t *testing.T) {
+	today := time.Date(2024, 5, 10, 0, 0, 0, 0, time.Local) // A Friday
+	at := func(daysAgo, hour int) time.Time {
+		return today.AddDate(0, 0, -daysAgo).Add(time.Duration(hour) * time.Hour)
+	}
+	records := []SessionRecord{
+		{Kind: RecordSession, StartTime: at(20, 9), FocusedSeconds: 1500, Completed: true}, // A 3-day streak, long ago
+		{Kind: RecordSession, StartTime: at(19, 9), FocusedSeconds: 1500, Completed: true},
+		{Kind: RecordSession, StartTime: at(18, 9), FocusedSeconds: 1500, Completed: true},
+		{Kind: RecordSession, StartTime: at(2, 9), FocusedSeconds: 3000, Completed: true, Pauses: 2},
+		{Kind: RecordSession, StartTime: at(1, 9), FocusedSeconds: 600, Pauses: 1},
+		{Kind: RecordSession, StartTime: at(1, 14), FocusedSeconds: 1500, Completed: true},
+		{Kind: RecordBreak, StartTime: at(0, 9), FocusedSeconds: 300, Completed: true},
+	}
+
+	summary := summarizeHistory(records, today, 3, 2)
+	if len(summary.Days) != 3 || !summary.Days[0].Start.Equal(today.AddDate(0, 0, -2)) {
+		t.Fatalf("Expected the last 3 days, oldest first, got %+v", summary.Days)
+	}
+	if summary.Days[1].Focused != 35*time.Minute || summary.Days[1].Sessions != 2 || summary.Days[2].Sessions != 0 {
+		t.Errorf("Unexpected daily totals: %+v", summary.Days)
+	}
+	if summary.Weeks[1].Focused != 85*time.Minute || summary.Weeks[0].Sessions != 0 {
+		t.Errorf("Unexpected weekly totals: %+v", summary.Weeks)
+	}
+	if summary.Sessions != 6 || summary.Completed != 5 || summary.Average != 1600*time.Second || summary.AveragePauses != 0.5 {
+		t.Errorf("Unexpected counts and averages: %+v", summary)
+	}
+	// Nothing today yet, so the run up to yesterday is current
+	if summary.LongestStreak != 3 || summary.CurrentStreak != 2 {
+		t.Errorf("Expected streaks of 3 and 2 days, got %d and %d", summary.LongestStreak, summary.CurrentStreak)
+	}
+
+	var out bytes.Buffer
+	printStatsSummary(&out, summary)
+	for _, want := range []string{"Last 3 days:", "Thu May 9", "w/c May 6", "6 (5 completed, 1 stopped early)", "Longest streak: 3 day(s); current streak: 2 day(s)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the summary:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestExportHistory tests the CSV and JSON exports and filtering them by start date
+func TestExportHistory(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local)
+	records := []SessionRecord{
+		{Kind: RecordSession, Mode: "old", StartTime: start.AddDate(0, -1, 0), EndTime: start.AddDate(0, -1, 0)},
+		{Kind: RecordSession, Mode: "focusmode", StartTime: start, EndTime: start.Add(25 * time.Minute), PlannedSeconds: 1500,
+			FocusedSeconds: 1500, Pauses: 1, Completed: true, MovedShortcuts: []string{"Steam.lnk", "Discord, Inc.lnk"}, Coding: &CodingStats{TotalSeconds: 1200}},
+	}
+	kept := recordsSince(records, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local))
+	if len(kept) != 1 || kept[0].Mode != "focusmode" {
+		t.Fatalf("Expected only the session since March 1st, got %+v", kept)
+	}
+
+	var buf bytes.Buffer
+	if err := exportHistory(&buf, kept, "csv"); err != nil {
+		t.Fatalf("exportHistory(csv) returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	row := fmt.Sprintf(`session,focusmode,,,%s,%s,1500,1500,0,1,true,"Steam.lnk;Discord, Inc.lnk",1200`,
+		start.Format(time.RFC3339), start.Add(25*time.Minute).Format(time.RFC3339))
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "kind,mode,") || lines[1] != row {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := exportHistory(&buf, kept, "json"); err != nil {
+		t.Fatalf("exportHistory(json) returned error: %v", err)
+	}
+	var exported []SessionRecord
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil || len(exported) != 1 || exported[0].FocusedSeconds != 1500 || !exported[0].StartTime.Equal(start) {
+		t.Errorf("Unexpected JSON %s (err %v)", buf.String(), err)
+	}
+	buf.Reset()
+	exportHistory(&buf, nil, "json")
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected an empty array for no records, got %s", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package focusmode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// minSuggestionSamples is the number of past sessions needed before a duration is suggested
+const minSuggestionSamples = 3
+
+// sessionOption groups past sessions by mode and planned length
+type sessionOption struct {
+	Mode      string
+	Minutes   int
+	Total     int
+	Completed int
+}
+
+// completionRate returns the fraction of sessions that ran to completion
+func (o sessionOption) completionRate() float64 {
+	if o.Total == 0 {
+		return 0
+	}
+	return float64(o.Completed) / float64(o.Total)
+}
+
+// expectedMinutes returns the focused minutes this option is expected to produce
+func (o sessionOption) expectedMinutes() float64 {
+	return o.completionRate() * float64(o.Minutes)
+}
+
+// dayPeriod returns the time-of-day bucket for t and a phrase describing it
+func dayPeriod(t time.Time) (int, string) {
+	switch hour := t.Hour(); {
+	case hour >= 5 && hour < 12:
+		return 0, "in the morning"
+	case hour >= 12 && hour < 16:
+		return 1, "in the early afternoon"
+	case hour >= 16 && hour < 21:
+		return 2, "after 4pm"
+	default:
+		return 3, "at night"
+	}
+}
+
+// sessionOptionsAt groups historical sessions started in the same time-of-day period as now
+// Options are sorted by expected focused minutes, best first
+func sessionOptionsAt(records []SessionRecord, now time.Time) []sessionOption {
+	period, _ := dayPeriod(now)
+	byKey := make(map[string]*sessionOption)
+
+	for _, record := range records {
+		if record.Kind != RecordSession || record.PlannedSeconds <= 0 {
+			continue
+		}
+		if p, _ := dayPeriod(record.StartTime.In(now.Location())); p != period {
+			continue
+		}
+
+		minutes := int(record.PlannedSeconds / 60)
+		key := fmt.Sprintf("%s/%d", record.Mode, minutes)
+		option, exists := byKey[key]
+		if !exists {
+			option = &sessionOption{Mode: record.Mode, Minutes: minutes}
+			byKey[key] = option
+		}
+		option.Total++
+		if record.Completed {
+			option.Completed++
+		}
+	}
+
+	options := make([]sessionOption, 0, len(byKey))
+	for _, option := range byKey {
+		options = append(options, *option)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].expectedMinutes() != options[j].expectedMinutes() {
+			return options[i].expectedMinutes() > options[j].expectedMinutes()
+		}
+		return options[i].Minutes < options[j].Minutes
+	})
+	return options
+}
+
+// suggestSessionOption returns the best option with enough history, if any
+func suggestSessionOption(options []sessionOption) (sessionOption, bool) {
+	for _, option := range options {
+		if option.Total >= minSuggestionSamples {
+			return option, true
+		}
+	}
+	return sessionOption{}, false
+}
+
+// describeSessionOptions returns one line per option with enough history, e.g.
+// "your 25m focusmode sessions after 4pm complete 90% of the time (9/10)"
+func describeSessionOptions(options []sessionOption, now time.Time) []string {
+	_, phrase := dayPeriod(now)
+	var lines []string
+	for _, option := range options {
+		if option.Total < minSuggestionSamples {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("your %dm %s sessions %s complete %.0f%% of the time (%d/%d)",
+			option.Minutes, option.Mode, phrase, option.completionRate()*100, option.Completed, option.Total))
+	}
+	return lines
+}
+
+// printSessionSuggestion prints the history analysis and returns the suggested option
+func printSessionSuggestion(records []SessionRecord, now time.Time) (sessionOption, bool) {
+	options := sessionOptionsAt(records, now)
+	suggestion, ok := suggestSessionOption(options)
+	if !ok {
+		_, phrase := dayPeriod(now)
+		fmt.Printf("%sNot enough session history %s to make a suggestion yet (need %d sessions of the same length).\n", glyph("💡 "), phrase, minSuggestionSamples)
+		return sessionOption{}, false
+	}
+
+	fmt.Println(glyph("💡 ") + "Based on your history:")
+	for _, line := range describeSessionOptions(options, now) {
+		fmt.Printf("   - %s\n", line)
+	}
+	fmt.Printf("Suggested: %dm in %s\n", suggestion.Minutes, suggestion.Mode)
+	return suggestion, true
+}
+
+// confirm asks a yes/no question on the terminal, returning defaultYes on empty input
+func confirm(question string, defaultYes bool, answers <-chan string) bool {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	fmt.Printf("%s %s ", question, hint)
+
+	answer, ok := <-answers
+	if !ok {
+		return defaultYes
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
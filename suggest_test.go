@@ -0,0 +1,105 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyAt builds session records started at the given time
+func historyAt(start time.Time, mode string, minutes int, completed, total int) []SessionRecord {
+	var records []SessionRecord
+	for i := 0; i < total; i++ {
+		records = append(records, SessionRecord{
+			Kind:           RecordSession,
+			Mode:           mode,
+			StartTime:      start.AddDate(0, 0, -i-1),
+			PlannedSeconds: int64(minutes * 60),
+			Completed:      i < completed,
+		})
+	}
+	return records
+}
+
+// TestSessionOptionsAt tests grouping and ranking of historical sessions
+func TestSessionOptionsAt(t *testing.T) {
+	now := time.Date(2024, 5, 10, 17, 30, 0, 0, time.UTC)
+	afternoon := time.Date(2024, 5, 1, 16, 15, 0, 0, time.UTC)
+	morning := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	var records []SessionRecord
+	records = append(records, historyAt(afternoon, "focusmode", 25, 9, 10)...)
+	records = append(records, historyAt(afternoon, "focusmode", 50, 2, 5)...)
+	records = append(records, historyAt(afternoon, "gamemode", 90, 1, 1)...)
+	// Morning sessions must not influence an afternoon suggestion
+	records = append(records, historyAt(morning, "focusmode", 50, 10, 10)...)
+	// Breaks are ignored
+	records = append(records, SessionRecord{Kind: RecordBreak, StartTime: afternoon, PlannedSeconds: 600, Completed: true})
+
+	options := sessionOptionsAt(records, now)
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 options, got %d: %+v", len(options), options)
+	}
+
+	suggestion, ok := suggestSessionOption(options)
+	if !ok {
+		t.Fatal("Expected a suggestion")
+	}
+	if suggestion.Mode != "focusmode" || suggestion.Minutes != 25 {
+		t.Errorf("Expected 25m focusmode suggestion, got %+v", suggestion)
+	}
+	if rate := suggestion.completionRate(); rate != 0.9 {
+		t.Errorf("Expected 90%% completion rate, got %v", rate)
+	}
+
+	lines := describeSessionOptions(options, now)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 description lines (90m has too few samples), got %v", lines)
+	}
+	if !strings.Contains(lines[0], "25m focusmode sessions after 4pm complete 90%") {
+		t.Errorf("Unexpected description: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "50m focusmode sessions after 4pm complete 40%") {
+		t.Errorf("Unexpected description: %q", lines[1])
+	}
+}
+
+// TestSuggestSessionOptionNotEnoughHistory tests that no suggestion is made from sparse history
+func TestSuggestSessionOptionNotEnoughHistory(t *testing.T) {
+	now := time.Date(2024, 5, 10, 10, 0, 0, 0, time.UTC)
+	records := historyAt(now, "focusmode", 25, 2, 2)
+
+	if _, ok := suggestSessionOption(sessionOptionsAt(records, now)); ok {
+		t.Error("Expected no suggestion with fewer than the minimum samples")
+	}
+	if _, ok := suggestSessionOption(nil); ok {
+		t.Error("Expected no suggestion without history")
+	}
+}
+
+// TestConfirm tests yes/no prompts
+func TestConfirm(t *testing.T) {
+	answer := func(s string) <-chan string {
+		ch := make(chan string, 1)
+		ch <- s
+		return ch
+	}
+	closed := make(chan string)
+	close(closed)
+
+	if !confirm("Start?", true, answer("")) {
+		t.Error("Expected empty answer to use default yes")
+	}
+	if confirm("Start?", false, answer("")) {
+		t.Error("Expected empty answer to use default no")
+	}
+	if !confirm("Start?", false, answer(" Yes ")) {
+		t.Error("Expected 'yes' to confirm")
+	}
+	if confirm("Start?", true, answer("n")) {
+		t.Error("Expected 'n' to decline")
+	}
+	if !confirm("Start?", true, closed) {
+		t.Error("Expected closed input to use default")
+	}
+}
@@ -0,0 +1,206 @@
+package focusmode
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFiles holds the profile.yml and categories.yml of each built-in template
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// profileTemplate is a ready-made configuration for a kind of user
+type profileTemplate struct {
+	Name        string
+	Description string
+}
+
+// profileTemplates are the built-in templates, in the order they are listed
+var profileTemplates = []profileTemplate{
+	{Name: "student", Description: "Study with games, social apps and video hidden; a free-time mode hides coursework"},
+	{Name: "streamer", Description: "Go live without business tools in view, edit without the game and stream tools"},
+	{Name: "developer", Description: "Deep work without chat, games or feeds; a gaming mode hides the IDE and Slack"},
+	{Name: "writer", Description: "Draft with only writing tools out, research with the browser back"},
+}
+
+// findProfileTemplate returns the built-in template with the given name
+func findProfileTemplate(name string) (profileTemplate, error) {
+	var names []string
+	for _, t := range profileTemplates {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return profileTemplate{}, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// files returns the template's profile.yml and categories.yml
+func (t profileTemplate) files() (profile, categories []byte, err error) {
+	if profile, err = templateFiles.ReadFile(path.Join("templates", t.Name, "profile.yml")); err != nil {
+		return nil, nil, err
+	}
+	if categories, err = templateFiles.ReadFile(path.Join("templates", t.Name, "categories.yml")); err != nil {
+		return nil, nil, err
+	}
+	return profile, categories, nil
+}
+
+// templateSummary describes what a template sets up, e.g. for the list and after applying it
+type templateSummary struct {
+	Modes       []string
+	DefaultMode string
+	Presets     []string
+	Categories  []string
+}
+
+// summary parses the template's files into the modes, presets and categories they define
+func (t profileTemplate) summary() (templateSummary, error) {
+	profileData, categoriesData, err := t.files()
+	if err != nil {
+		return templateSummary{}, err
+	}
+	var profile Config
+	if err := yaml.Unmarshal(profileData, &profile); err != nil {
+		return templateSummary{}, fmt.Errorf("template %s: error parsing profile.yml: %w", t.Name, err)
+	}
+	var categories CategoriesConfig
+	if err := yaml.Unmarshal(categoriesData, &categories); err != nil {
+		return templateSummary{}, fmt.Errorf("template %s: error parsing categories.yml: %w", t.Name, err)
+	}
+
+	summary := templateSummary{DefaultMode: profile.DefaultMode}
+	for name := range profile.Modes {
+		summary.Modes = append(summary.Modes, name)
+	}
+	for name := range profile.Presets {
+		summary.Presets = append(summary.Presets, name)
+	}
+	for _, name := range categories.CategoryOrder {
+		if name != string(CategoryOther) {
+			summary.Categories = append(summary.Categories, name)
+		}
+	}
+	sort.Strings(summary.Modes)
+	sort.Strings(summary.Presets)
+	return summary, nil
+}
+
+// writeTemplate writes a template's files, refusing to replace existing ones unless force is set
+// Nothing is written if either file is in the way
+func writeTemplate(t profileTemplate, configPath, categoriesPath string, force bool) error {
+	profile, categories, err := t.files()
+	if err != nil {
+		return err
+	}
+	if !force {
+		for _, p := range []string{configPath, categoriesPath} {
+			if _, err := os.Stat(p); err == nil {
+				return fmt.Errorf("%s already exists; use -force to replace it", p)
+			}
+		}
+	}
+	if err := writeFile(configPath, profile, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", configPath, err)
+	}
+	if err := writeFile(categoriesPath, categories, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", categoriesPath, err)
+	}
+	return nil
+}
+
+// runTemplateCommand handles the "template" subcommand
+func runTemplateCommand(args []string) {
+	if len(args) == 0 {
+		printTemplateUsage()
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "list":
+		runTemplateList()
+	case "apply":
+		runTemplateApply(args[1:])
+	default:
+		printTemplateUsage()
+		os.Exit(1)
+	}
+}
+
+// printTemplateUsage prints the usage of the "template" subcommand
+func printTemplateUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: focusmode template list")
+	fmt.Fprintln(os.Stderr, "       focusmode template apply [-config profile.yml] [-categories categories.yml] [-force] [-dry-run] <name>")
+}
+
+// runTemplateList handles "template list"
+func runTemplateList() {
+	fmt.Println("Templates:")
+	for _, t := range profileTemplates {
+		fmt.Printf("\n  %-10s %s\n", t.Name, t.Description)
+		summary, err := t.summary()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("  %-10s modes: %s (default %s)\n", "", strings.Join(summary.Modes, ", "), summary.DefaultMode)
+		fmt.Printf("  %-10s presets: %s\n", "", strings.Join(summary.Presets, ", "))
+		fmt.Printf("  %-10s categories: %s\n", "", strings.Join(summary.Categories, ", "))
+	}
+	fmt.Println("\nApply one with: ./focusmode template apply <name>")
+}
+
+// runTemplateApply handles "template apply", which writes a template's profile.yml and categories.yml
+func runTemplateApply(args []string) {
+	flags := flag.NewFlagSet("template apply", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to write the configuration file to")
+	categoriesPath := flags.String("categories", "categories.yml", "Path to write the categories file to")
+	force := flags.Bool("force", false, "Replace existing files")
+	dryRun := flags.Bool("dry-run", false, "Print the files instead of writing them")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		printTemplateUsage()
+		os.Exit(1)
+	}
+
+	t, err := findProfileTemplate(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		profile, categories, err := t.files()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("[DRY RUN] Would write %s:\n\n%s\n[DRY RUN] Would write %s:\n\n%s", *configPath, profile, *categoriesPath, categories)
+		return
+	}
+	if err := writeTemplate(t, *configPath, *categoriesPath, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	summary, err := t.summary()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%sWrote %s and %s from the %s template\n\n", glyph("✅ "), *configPath, *categoriesPath, t.Name)
+	fmt.Printf("Modes: %s (default %s)\n", strings.Join(summary.Modes, ", "), summary.DefaultMode)
+	fmt.Printf("Session presets: %s\n", strings.Join(summary.Presets, ", "))
+	fmt.Println("\nModes hide shortcuts by category. Check how yours are sorted, then try a mode:")
+	fmt.Println("  ./focusmode -list-desktop")
+	fmt.Printf("  ./focusmode -mode %s -dry-run\n", summary.DefaultMode)
+	if len(summary.Presets) > 0 {
+		fmt.Printf("  ./focusmode session start -preset %s\n", summary.Presets[0])
+	}
+}
@@ -0,0 +1,94 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProfileTemplates tests that every template writes a profile and categories that load cleanly
+// and whose modes and presets only name categories the template defines
+func TestProfileTemplates(t *testing.T) {
+	for _, tmpl := range profileTemplates {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, "profile.yml")
+			categoriesPath := filepath.Join(dir, "categories.yml")
+			if err := writeTemplate(tmpl, configPath, categoriesPath, false); err != nil {
+				t.Fatalf("writeTemplate() returned error: %v", err)
+			}
+
+			config, err := readConfig(configPath)
+			if err != nil {
+				t.Fatalf("readConfig() returned error: %v", err)
+			}
+			if warnings := lintConfig(config); len(warnings) > 0 {
+				t.Errorf("Unexpected lint warnings: %v", warnings)
+			}
+			categories, err := loadCategoriesConfig(categoriesPath)
+			if err != nil {
+				t.Fatalf("loadCategoriesConfig() returned error: %v", err)
+			}
+			for _, key := range categories.CategoryOrder {
+				if _, ok := categories.Categories[key]; !ok && key != string(CategoryOther) {
+					t.Errorf("category_order names undefined category %s", key)
+				}
+			}
+
+			known := func(where string, names []string) {
+				for _, name := range names {
+					if _, ok := categories.Categories[name]; !ok {
+						t.Errorf("%s names undefined category %s", where, name)
+					}
+				}
+			}
+			for name, mode := range config.Modes {
+				if name == meetingModeName {
+					continue
+				}
+				if len(mode.Categories) == 0 {
+					t.Errorf("mode %s hides no categories", name)
+				}
+				known("mode "+name, mode.Categories)
+			}
+			if len(config.Presets) == 0 {
+				t.Error("Expected session presets")
+			}
+			for name, preset := range config.Presets {
+				if _, err := config.getPreset(name); err != nil {
+					t.Errorf("preset %s: %v", name, err)
+				}
+				known("preset "+name, preset.OnCompleteRestore)
+			}
+		})
+	}
+}
+
+// TestWriteTemplateExisting tests that existing files are only replaced with force
+func TestWriteTemplateExisting(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "profile.yml")
+	categoriesPath := filepath.Join(dir, "categories.yml")
+	os.WriteFile(categoriesPath, []byte("# mine\n"), 0644)
+
+	writer, _ := findProfileTemplate("writer")
+	if err := writeTemplate(writer, configPath, categoriesPath, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an error for the existing categories.yml, got %v", err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written when a file is in the way")
+	}
+
+	if err := writeTemplate(writer, configPath, categoriesPath, true); err != nil {
+		t.Fatalf("writeTemplate() with force returned error: %v", err)
+	}
+	data, _ := os.ReadFile(categoriesPath)
+	if !strings.Contains(string(data), "scrivener") {
+		t.Error("Expected categories.yml to be replaced")
+	}
+
+	if _, err := findProfileTemplate("gamer"); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+}
@@ -0,0 +1,85 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Terminal escape sequences: OSC 0 sets the window title; CSI 22/23 t save and restore it
+// on terminals that keep a title stack (xterm, VTE, Windows Terminal)
+const (
+	titleSave    = "\x1b[22;0t"
+	titleRestore = "\x1b[23;0t"
+)
+
+// terminalTitleHook shows the remaining time in the terminal's window title,
+// so the countdown is visible in the taskbar or tab bar while the terminal is in the background
+type terminalTitleHook struct {
+	w    io.Writer
+	last string // title last written, so it's only rewritten when it changes
+}
+
+// stdoutIsTerminal reports whether standard output is an interactive terminal
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// sessionTitle returns the window title for the session's current state
+func sessionTitle(fs *FocusSession) string {
+	switch {
+	case fs.State == StatePaused:
+		return fmt.Sprintf("⏸ %s left · %s (paused)", formatDuration(fs.remaining()), fs.Mode)
+	case fs.Break:
+		return fmt.Sprintf("☕ %s left · break", formatDuration(fs.remaining()))
+	default:
+		return fmt.Sprintf("⏳ %s left · %s", formatDuration(fs.remaining()), fs.Mode)
+	}
+}
+
+// setTitle writes title unless it's already shown
+func (h *terminalTitleHook) setTitle(title string) {
+	if title == h.last {
+		return
+	}
+	h.last = title
+	// Control characters would end the sequence early
+	title = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, title)
+	fmt.Fprintf(h.w, "\x1b]0;%s\x07", title)
+}
+
+// OnStart saves the current title and shows the countdown
+func (h *terminalTitleHook) OnStart(fs *FocusSession) {
+	fmt.Fprint(h.w, titleSave)
+	h.setTitle(sessionTitle(fs))
+}
+
+// OnPause shows that the session is paused
+func (h *terminalTitleHook) OnPause(fs *FocusSession) { h.setTitle(sessionTitle(fs)) }
+
+// OnResume shows the countdown again
+func (h *terminalTitleHook) OnResume(fs *FocusSession) { h.setTitle(sessionTitle(fs)) }
+
+// OnExtend shows the new remaining time
+func (h *terminalTitleHook) OnExtend(fs *FocusSession) { h.setTitle(sessionTitle(fs)) }
+
+// OnTick updates the remaining time
+func (h *terminalTitleHook) OnTick(fs *FocusSession) { h.setTitle(sessionTitle(fs)) }
+
+// OnEnd puts the title back; terminals without a title stack are left showing "FocusMode"
+func (h *terminalTitleHook) OnEnd(fs *FocusSession) {
+	h.setTitle("FocusMode")
+	fmt.Fprint(h.w, titleRestore)
+}
+
+// DryRun reports the title change
+func (h *terminalTitleHook) DryRun(fs *FocusSession) []string {
+	return []string{"show the remaining time in the terminal title"}
+}
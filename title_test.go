@@ -0,0 +1,57 @@
+package focusmode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTerminalTitleHook tests that the title follows the countdown and is put back at the end
+func TestTerminalTitleHook(t *testing.T) {
+	var buf bytes.Buffer
+	hook := &terminalTitleHook{w: &buf}
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		Mode:      "focusmode",
+		StartTime: time.Now().Add(-10*time.Minute - 30*time.Second),
+		State:     StateRunning,
+		Hooks:     []SessionHook{hook},
+	}
+
+	hook.OnStart(fs)
+	out := buf.String()
+	if !strings.HasPrefix(out, titleSave) || !strings.Contains(out, "\x1b]0;⏳ 14m") || !strings.Contains(out, "· focusmode\x07") {
+		t.Errorf("Expected the title to be saved and show the countdown, got %q", out)
+	}
+
+	// The same title is not written again every second
+	buf.Reset()
+	hook.setTitle("FocusMode")
+	hook.setTitle("FocusMode")
+	if strings.Count(buf.String(), "\x1b]0;") != 1 {
+		t.Errorf("Expected an unchanged title to be written once, got %q", buf.String())
+	}
+
+	buf.Reset()
+	fs.pause()
+	if !strings.Contains(buf.String(), "(paused)") {
+		t.Errorf("Expected a paused title, got %q", buf.String())
+	}
+
+	buf.Reset()
+	fs.finish(StateInterrupted)
+	if !strings.HasSuffix(buf.String(), titleRestore) {
+		t.Errorf("Expected the title to be restored, got %q", buf.String())
+	}
+}
+
+// TestTerminalTitleStripsControlCharacters tests that a mode name can't end the escape sequence early
+func TestTerminalTitleStripsControlCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	hook := &terminalTitleHook{w: &buf}
+	hook.setTitle("focus\x07\x1b]0;evil")
+	if got := buf.String(); got != "\x1b]0;focus]0;evil\x07" {
+		t.Errorf("Unexpected title sequence: %q", got)
+	}
+}
@@ -0,0 +1,269 @@
+package focusmode
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const keychainService = "focusmode"
+
+// storedToken is an API token accepted by the daemon; only its hash is kept on disk
+type storedToken struct {
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"` // hex SHA-256 of the token
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// tokensPath returns the path of the daemon's token list
+func tokensPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tokens.json"), nil
+}
+
+// hashToken returns the hex SHA-256 of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateToken returns a new random API token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return "fm_" + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// readTokens returns the tokens stored at path
+func readTokens(path string) ([]storedToken, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokens: %w", err)
+	}
+	var tokens []storedToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("error parsing tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// writeTokens stores tokens at path, readable only by the current user
+func writeTokens(path string, tokens []storedToken) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding tokens: %w", err)
+	}
+	if err := writeFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing tokens: %w", err)
+	}
+	return nil
+}
+
+// tokenMatches reports whether given matches the configured token or one of the stored tokens
+func tokenMatches(given string, configured string, stored []storedToken) bool {
+	if given == "" {
+		return false
+	}
+	if configured != "" && subtle.ConstantTimeCompare([]byte(given), []byte(configured)) == 1 {
+		return true
+	}
+	hash := hashToken(given)
+	for _, token := range stored {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(token.Hash)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// keychainCommand returns the command that stores, looks up or deletes a secret in the OS keychain
+// For "set" the secret is passed on standard input, never in the arguments other processes can read
+func keychainCommand(goos, action, account, secret string) (string, []string, string, error) {
+	switch goos {
+	case "darwin":
+		switch action {
+		case "set":
+			// -w last makes security prompt for the password, and again to confirm it
+			return "security", []string{"add-generic-password", "-U", "-s", keychainService, "-a", account, "-w"}, secret + "\n" + secret + "\n", nil
+		case "get":
+			return "security", []string{"find-generic-password", "-s", keychainService, "-a", account, "-w"}, "", nil
+		case "delete":
+			return "security", []string{"delete-generic-password", "-s", keychainService, "-a", account}, "", nil
+		}
+	case "linux":
+		switch action {
+		case "set":
+			return "secret-tool", []string{"store", "--label=FocusMode token " + account, "service", keychainService, "account", account}, secret, nil
+		case "get":
+			return "secret-tool", []string{"lookup", "service", keychainService, "account", account}, "", nil
+		case "delete":
+			return "secret-tool", []string{"clear", "service", keychainService, "account", account}, "", nil
+		}
+	case "windows":
+		vault := "[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime]; $v = New-Object Windows.Security.Credentials.PasswordVault; "
+		quoted := "'" + strings.ReplaceAll(account, "'", "''") + "'"
+		switch action {
+		case "set":
+			script := vault + fmt.Sprintf("$v.Add((New-Object Windows.Security.Credentials.PasswordCredential('%s', %s, [Console]::In.ReadLine())))", keychainService, quoted)
+			return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, secret + "\n", nil
+		case "get":
+			script := vault + fmt.Sprintf("$c = $v.Retrieve('%s', %s); $c.RetrievePassword(); $c.Password", keychainService, quoted)
+			return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, "", nil
+		case "delete":
+			script := vault + fmt.Sprintf("$v.Remove($v.Retrieve('%s', %s))", keychainService, quoted)
+			return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, "", nil
+		}
+	default:
+		return "", nil, "", fmt.Errorf("unsupported operating system: %s", goos)
+	}
+	return "", nil, "", fmt.Errorf("unknown keychain action: %s", action)
+}
+
+// runKeychain performs a keychain action and returns its trimmed output
+func runKeychain(action, account, secret string) (string, error) {
+	name, args, stdin, err := keychainCommand(runtime.GOOS, action, account, secret)
+	if err != nil {
+		return "", err
+	}
+	if action != "get" {
+		if err := guardWrite(action + " a keychain entry"); err != nil {
+			return "", err
+		}
+	}
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain %s failed (%s): %w", action, name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runTokenCommand handles the "token" subcommand
+func runTokenCommand(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: focusmode token create <name>")
+		fmt.Fprintln(os.Stderr, "       focusmode token revoke <name>")
+		fmt.Fprintln(os.Stderr, "       focusmode token list")
+		fmt.Fprintln(os.Stderr, "       focusmode token import <name>   (reads a token from stdin into this machine's keychain)")
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		usage()
+	}
+
+	path, err := tokensPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	tokens, err := readTokens(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(tokens) == 0 {
+			fmt.Println("No tokens. Create one with: focusmode token create <name>")
+			return
+		}
+		sort.Slice(tokens, func(i, j int) bool { return tokens[i].Name < tokens[j].Name })
+		for _, token := range tokens {
+			fmt.Printf("  %-20s created %s\n", token.Name, token.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+	case "create":
+		if len(args) != 2 {
+			usage()
+		}
+		name := args[1]
+		for _, token := range tokens {
+			if token.Name == name {
+				fmt.Fprintf(os.Stderr, "Error: token '%s' already exists (revoke it first)\n", name)
+				os.Exit(1)
+			}
+		}
+		secret, err := generateToken()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tokens = append(tokens, storedToken{Name: name, Hash: hashToken(secret), CreatedAt: time.Now()})
+		if err := writeTokens(path, tokens); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := runKeychain("set", name, secret); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: token not saved to keychain: %v\n", err)
+		} else {
+			fmt.Printf("Saved to keychain as %s/%s\n", keychainService, name)
+		}
+		fmt.Printf("Created token '%s'. It will not be shown again:\n\n  %s\n\n", name, secret)
+		fmt.Println("Use it with --token, $FOCUSMODE_TOKEN, or --token-name after 'focusmode token import' on the client.")
+	case "revoke":
+		if len(args) != 2 {
+			usage()
+		}
+		name := args[1]
+		var kept []storedToken
+		for _, token := range tokens {
+			if token.Name != name {
+				kept = append(kept, token)
+			}
+		}
+		if len(kept) == len(tokens) {
+			fmt.Fprintf(os.Stderr, "Error: token '%s' not found\n", name)
+			os.Exit(1)
+		}
+		if err := writeTokens(path, kept); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// The daemon no longer accepts the token either way; only a copy may be left behind
+		if _, err := runKeychain("delete", name, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: token not removed from keychain: %v (delete %s/%s there if it was saved)\n", err, keychainService, name)
+		} else {
+			fmt.Printf("Removed from keychain as %s/%s\n", keychainService, name)
+		}
+		fmt.Printf("Revoked token '%s'\n", name)
+	case "import":
+		if len(args) != 2 {
+			usage()
+		}
+		var secret string
+		fmt.Fscanln(os.Stdin, &secret)
+		if secret == "" {
+			fmt.Fprintln(os.Stderr, "Error: no token given on stdin")
+			os.Exit(1)
+		}
+		if _, err := runKeychain("set", args[1], secret); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved token to keychain as %s/%s\n", keychainService, args[1])
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,152 @@
+package focusmode
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTokenMatches tests matching against the configured token and stored hashes
+func TestTokenMatches(t *testing.T) {
+	stored := []storedToken{{Name: "laptop", Hash: hashToken("fm_laptop")}}
+
+	tests := []struct {
+		given, configured string
+		want              bool
+	}{
+		{"fm_laptop", "", true},
+		{"secret", "secret", true},
+		{"fm_laptop", "secret", true},
+		{"fm_other", "secret", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := tokenMatches(tt.given, tt.configured, stored); got != tt.want {
+			t.Errorf("tokenMatches(%q, %q) = %v, want %v", tt.given, tt.configured, got, tt.want)
+		}
+	}
+}
+
+// TestGenerateToken tests that tokens are prefixed and unique
+func TestGenerateToken(t *testing.T) {
+	a, err := generateToken()
+	if err != nil {
+		t.Fatalf("generateToken() returned error: %v", err)
+	}
+	b, _ := generateToken()
+	if !strings.HasPrefix(a, "fm_") || len(a) < 40 || a == b {
+		t.Errorf("Unexpected tokens %q and %q", a, b)
+	}
+}
+
+// TestTokensRoundTrip tests that tokens are stored as hashes in a private file
+func TestTokensRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	tokens := []storedToken{{Name: "laptop", Hash: hashToken("fm_secret"), CreatedAt: time.Now()}}
+	if err := writeTokens(path, tokens); err != nil {
+		t.Fatalf("writeTokens() returned error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "fm_secret") {
+		t.Error("Token file must not contain the plain token")
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 && os.PathSeparator == '/' {
+		t.Errorf("Expected private file permissions, got %v", info.Mode().Perm())
+	}
+
+	read, err := readTokens(path)
+	if err != nil || len(read) != 1 || read[0].Name != "laptop" {
+		t.Errorf("Unexpected tokens %+v (err %v)", read, err)
+	}
+}
+
+// TestKeychainCommand tests the keychain tool used on each platform
+func TestKeychainCommand(t *testing.T) {
+	name, args, stdin, err := keychainCommand("linux", "set", "laptop", "fm_secret")
+	if err != nil || name != "secret-tool" || stdin != "fm_secret" || strings.Contains(strings.Join(args, " "), "fm_secret") {
+		t.Errorf("Unexpected linux set command: %s %v (stdin %q, err %v)", name, args, stdin, err)
+	}
+
+	name, args, stdin, _ = keychainCommand("darwin", "set", "laptop", "fm_secret")
+	if name != "security" || args[len(args)-1] != "-w" || stdin != "fm_secret\nfm_secret\n" || strings.Contains(strings.Join(args, " "), "fm_secret") {
+		t.Errorf("Unexpected darwin set command: %s %v (stdin %q)", name, args, stdin)
+	}
+
+	name, args, _, _ = keychainCommand("darwin", "get", "laptop", "")
+	if name != "security" || args[0] != "find-generic-password" {
+		t.Errorf("Unexpected darwin get command: %s %v", name, args)
+	}
+
+	name, args, _, _ = keychainCommand("windows", "delete", "it's", "")
+	if name != "powershell" || !strings.Contains(args[len(args)-1], "'it''s'") {
+		t.Errorf("Expected quoted account in windows command, got %v", args)
+	}
+
+	if _, _, _, err := keychainCommand("plan9", "get", "laptop", ""); err == nil {
+		t.Error("Expected error for unsupported OS")
+	}
+}
+
+// TestDaemonHandlerStoredTokens tests that created tokens are accepted and revocation takes effect
+func TestDaemonHandlerStoredTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	writeTokens(path, []storedToken{{Name: "laptop", Hash: hashToken("fm_laptop")}})
+
+	config := &Config{Modes: map[string]ModeConfig{"focusmode": {}}}
+	handler := newDaemonHandler(config, (&fakeRunner{}).run, path)
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/pop", nil)
+		req.Header.Set("Authorization", "Bearer fm_laptop")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Errorf("Expected stored token to be accepted, got %d", code)
+	}
+	writeTokens(path, []storedToken{{Name: "other", Hash: hashToken("fm_other")}})
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked token to be rejected, got %d", code)
+	}
+}
+
+// TestMutualTLS tests the client certificate requirement used when client_ca is set
+func TestMutualTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	server.Close()
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tlsConfig, err := mutualTLSConfig(caFile)
+	if err != nil {
+		t.Fatalf("mutualTLSConfig() returned error: %v", err)
+	}
+	if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("Expected client certificates to be required, got %v", tlsConfig.ClientAuth)
+	}
+
+	// A client without a certificate cannot complete the handshake
+	mtls := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mtls.TLS = tlsConfig
+	mtls.StartTLS()
+	defer mtls.Close()
+	if _, err := mtls.Client().Get(mtls.URL); err == nil {
+		t.Error("Expected request without client certificate to fail")
+	}
+
+	if _, err := mutualTLSConfig(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
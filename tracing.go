@@ -0,0 +1,409 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TracingConfig represents the settings of OpenTelemetry tracing
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled" doc:"Send traces of applies, restores and daemon work to an OpenTelemetry collector over OTLP/HTTP" default:"false"`
+	Endpoint    string            `yaml:"endpoint" doc:"OTLP/HTTP traces URL; when empty, OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT or the local collector is used" example:"https://otel.example.com/v1/traces"`
+	ServiceName string            `yaml:"service_name" doc:"service.name reported with the traces" default:"focusmode"`
+	Headers     map[string]string `yaml:"headers" doc:"HTTP headers sent with every export, e.g. for authentication" example:"{authorization: Bearer abc123}"`
+}
+
+const (
+	defaultTracingEndpoint    = "http://localhost:4318/v1/traces"
+	defaultTracingServiceName = "focusmode"
+	tracingExportInterval     = 5 * time.Second
+	tracingExportTimeout      = 5 * time.Second
+
+	// Environment variables that carry tracing to the commands the daemon runs; they are the
+	// OpenTelemetry ones, so a command run from a traced script joins its trace as well
+	traceParentEnv      = "TRACEPARENT"
+	tracingEndpointEnv  = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	tracingHeadersEnv   = "OTEL_EXPORTER_OTLP_TRACES_HEADERS"
+	tracingServiceEnv   = "OTEL_SERVICE_NAME"
+	tracingBaseEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+)
+
+// endpoint returns the URL traces are sent to
+func (c TracingConfig) endpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	if endpoint := os.Getenv(tracingEndpointEnv); endpoint != "" {
+		return endpoint
+	}
+	if base := os.Getenv(tracingBaseEndpoint); base != "" {
+		return strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	return defaultTracingEndpoint
+}
+
+// serviceName returns the service.name reported with the traces
+func (c TracingConfig) serviceName() string {
+	if c.ServiceName == "" {
+		return defaultTracingServiceName
+	}
+	return c.ServiceName
+}
+
+// activeTracer exports the spans of this process; nil unless tracing is on, which makes every span a no-op
+// It is set once at startup, before anything is traced
+var activeTracer *spanTracer
+
+// spanContext identifies a span within its trace
+type spanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// valid reports whether the context identifies a span
+func (c spanContext) valid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+// traceParent formats the context as a W3C traceparent header, marked as sampled
+func (c spanContext) traceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(c.TraceID[:]), hex.EncodeToString(c.SpanID[:]))
+}
+
+// parseTraceParent reads a W3C traceparent header
+func parseTraceParent(value string) (spanContext, error) {
+	var c spanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if _, err := hex.Decode(c.TraceID[:], []byte(parts[1])); err != nil {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if _, err := hex.Decode(c.SpanID[:], []byte(parts[2])); err != nil {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if !c.valid() {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	return c, nil
+}
+
+// span is one traced operation
+// Methods on a nil span do nothing, so code is traced the same way whether tracing is on or not
+type span struct {
+	tracer  *spanTracer
+	name    string
+	context spanContext
+	parent  [8]byte // Zero for the root of a trace
+	start   time.Time
+	end     time.Time
+	attrs   map[string]any
+	err     string
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// startSpan starts a span named name as a child of the span in ctx, or of the span this
+// process was started under; it returns ctx carrying the new span
+func startSpan(ctx context.Context, name string) (context.Context, *span) {
+	t := activeTracer
+	if t == nil {
+		return ctx, nil
+	}
+	s := &span{tracer: t, name: name, start: time.Now(), attrs: make(map[string]any)}
+	parent := t.remote
+	if current := spanFromContext(ctx); current != nil {
+		parent = current.context
+	}
+	if parent.valid() {
+		s.context.TraceID = parent.TraceID
+		s.parent = parent.SpanID
+	} else {
+		rand.Read(s.context.TraceID[:])
+	}
+	rand.Read(s.context.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// spanFromContext returns the span in ctx, or nil
+func spanFromContext(ctx context.Context) *span {
+	s, _ := ctx.Value(spanKey{}).(*span)
+	return s
+}
+
+// set records an attribute of the span
+func (s *span) set(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// finish ends the span, marking it failed when err is set, and hands it to the exporter
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.tracer.add(s)
+}
+
+// environ returns the environment that puts a child process's spans under s
+func (s *span) environ() []string {
+	if s == nil {
+		return nil
+	}
+	return append(s.tracer.environ(), traceParentEnv+"="+s.context.traceParent())
+}
+
+// spanTracer batches finished spans and sends them to an OTLP/HTTP collector
+type spanTracer struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+	remote   spanContext // The span this process was started under, from TRACEPARENT
+	sync     bool        // Export every span as it finishes, for commands that exit right after
+
+	mu      sync.Mutex
+	pending []*span
+	warned  bool // An export failed; warned about until one succeeds
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// newSpanTracer returns a tracer for config
+func newSpanTracer(config TracingConfig) *spanTracer {
+	return &spanTracer{
+		endpoint: config.endpoint(),
+		service:  config.serviceName(),
+		headers:  config.Headers,
+		client:   &http.Client{Timeout: tracingExportTimeout},
+	}
+}
+
+// startTracing makes the spans of this process go to the collector in config, batched in the
+// background; the returned function sends what is left and stops
+func startTracing(config TracingConfig) func() {
+	t := newSpanTracer(config)
+	t.stopped = make(chan struct{})
+	t.done = make(chan struct{})
+	activeTracer = t
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(tracingExportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stopped:
+				t.flush()
+				return
+			case <-ticker.C:
+				t.flush()
+			}
+		}
+	}()
+	return func() {
+		close(t.stopped)
+		<-t.done
+	}
+}
+
+// startTracingFromEnv traces this process when it was started under a span with a collector
+// to send to, as the daemon starts its commands; spans are exported as they finish since commands may exit at any time
+func startTracingFromEnv() {
+	// A traceparent alone, as CI systems set, doesn't mean a collector is listening
+	value := os.Getenv(traceParentEnv)
+	if value == "" || (os.Getenv(tracingEndpointEnv) == "" && os.Getenv(tracingBaseEndpoint) == "") {
+		return
+	}
+	remote, err := parseTraceParent(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+		return
+	}
+	t := newSpanTracer(TracingConfig{ServiceName: os.Getenv(tracingServiceEnv), Headers: parseTracingHeaders(os.Getenv(tracingHeadersEnv))})
+	t.remote = remote
+	t.sync = true
+	activeTracer = t
+}
+
+// environ returns the environment that sends a child process's spans where this tracer's go
+func (t *spanTracer) environ() []string {
+	env := []string{tracingEndpointEnv + "=" + t.endpoint, tracingServiceEnv + "=" + t.service}
+	if len(t.headers) > 0 {
+		env = append(env, tracingHeadersEnv+"="+formatTracingHeaders(t.headers))
+	}
+	return env
+}
+
+// formatTracingHeaders formats headers as OTEL_EXPORTER_OTLP_HEADERS does: key=value pairs
+// separated by commas, with the values percent-encoded
+func formatTracingHeaders(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + url.PathEscape(headers[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseTracingHeaders reads headers written by formatTracingHeaders, skipping malformed pairs
+func parseTracingHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// add queues a finished span, exporting it at once for short-lived commands
+func (t *spanTracer) add(s *span) {
+	t.mu.Lock()
+	t.pending = append(t.pending, s)
+	t.mu.Unlock()
+	if t.sync {
+		t.flush()
+	}
+}
+
+// flush exports the queued spans; they are dropped if the collector can't be reached,
+// with a warning the first time
+func (t *spanTracer) flush() {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	err := t.export(spans)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil && !t.warned {
+		fmt.Fprintf(os.Stderr, "Warning: error exporting traces: %v\n", err)
+	}
+	t.warned = err != nil
+}
+
+// export sends spans to the collector as OTLP JSON
+func (t *spanTracer) export(spans []*span) error {
+	body, err := json.Marshal(otlpRequest(t.service, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// otlpAttribute is an attribute in the OTLP JSON encoding
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// otlpAttributes encodes attributes in the OTLP JSON encoding, in key order
+func otlpAttributes(attrs map[string]any) []otlpAttribute {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]any
+		switch v := attrs[key].(type) {
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case []string:
+			values := make([]map[string]any, len(v))
+			for i, s := range v {
+				values[i] = map[string]any{"stringValue": s}
+			}
+			value = map[string]any{"arrayValue": map[string]any{"values": values}}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: key, Value: value})
+	}
+	return encoded
+}
+
+// otlpRequest returns the body of an OTLP/HTTP JSON export request for spans
+func otlpRequest(service string, spans []*span) map[string]any {
+	encoded := make([]map[string]any, len(spans))
+	for i, s := range spans {
+		span := map[string]any{
+			"traceId":           hex.EncodeToString(s.context.TraceID[:]),
+			"spanId":            hex.EncodeToString(s.context.SpanID[:]),
+			"name":              s.name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attrs),
+		}
+		if s.parent != [8]byte{} {
+			span["parentSpanId"] = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != "" {
+			span["status"] = map[string]any{"code": 2, "message": s.err} // STATUS_CODE_ERROR
+		}
+		encoded[i] = span
+	}
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{"attributes": otlpAttributes(map[string]any{"service.name": service})},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "focusmode"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,140 @@
+package focusmode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestTraceParent tests reading and writing W3C traceparent headers
+func TestTraceParent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	c, err := parseTraceParent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.traceParent(); got != value {
+		t.Errorf("Expected %s, got %s", value, got)
+	}
+	for _, bad := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		if _, err := parseTraceParent(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+// TestTracingHeaders tests that export headers survive the trip to a child process
+func TestTracingHeaders(t *testing.T) {
+	headers := map[string]string{"authorization": "Bearer a,b=c", "x-team": "desk"}
+	formatted := formatTracingHeaders(headers)
+	if strings.Count(formatted, ",") != 1 {
+		t.Errorf("Expected commas in values to be escaped, got %q", formatted)
+	}
+	if got := parseTracingHeaders(formatted); !reflect.DeepEqual(got, headers) {
+		t.Errorf("Expected %v, got %v", headers, got)
+	}
+}
+
+// TestTracingDisabled tests that spans are no-ops without a tracer
+func TestTracingDisabled(t *testing.T) {
+	ctx, span := startSpan(context.Background(), "apply")
+	if span != nil || spanFromContext(ctx) != nil {
+		t.Fatal("Expected no span without tracing")
+	}
+	span.set("focusmode.mode", "focusmode")
+	span.finish(errors.New("ignored"))
+	if env := span.environ(); env != nil {
+		t.Errorf("Expected no environment, got %v", env)
+	}
+}
+
+// TestTracingExport tests that spans reach the collector as OTLP JSON, with children
+// linked to their parents and failures marked
+func TestTracingExport(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]any
+	var auth string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer collector.Close()
+	defer func() { activeTracer = nil }()
+
+	stop := startTracing(TracingConfig{Endpoint: collector.URL, Headers: map[string]string{"Authorization": "Bearer abc"}})
+	ctx, parent := startSpan(context.Background(), "daemon apply")
+	parent.set("focusmode.mode", "gamemode")
+	_, child := startSpan(ctx, "apply")
+	child.set("focusmode.dry_run", true)
+	child.finish(errors.New("mode not found"))
+	parent.finish(nil)
+
+	env := strings.Join(parent.environ(), "\n")
+	if !strings.Contains(env, traceParentEnv+"="+parent.context.traceParent()) || !strings.Contains(env, tracingEndpointEnv+"="+collector.URL) {
+		t.Errorf("Unexpected child environment %q", env)
+	}
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 || auth != "Bearer abc" {
+		t.Fatalf("Expected one authenticated export, got %d (auth %q)", len(bodies), auth)
+	}
+	resource := bodies[0]["resourceSpans"].([]any)[0].(map[string]any)
+	service := resource["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if service["value"].(map[string]any)["stringValue"] != defaultTracingServiceName {
+		t.Errorf("Unexpected resource %v", service)
+	}
+	spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 2 {
+		t.Fatalf("Expected two spans, got %d", len(spans))
+	}
+	first, second := spans[0].(map[string]any), spans[1].(map[string]any)
+	if first["name"] != "apply" || second["name"] != "daemon apply" {
+		t.Errorf("Unexpected span names %v, %v", first["name"], second["name"])
+	}
+	if first["traceId"] != second["traceId"] || first["parentSpanId"] != second["spanId"] || second["parentSpanId"] != nil {
+		t.Errorf("Expected apply to be a child of daemon apply: %v, %v", first, second)
+	}
+	if status := first["status"].(map[string]any); status["code"] != float64(2) || status["message"] != "mode not found" {
+		t.Errorf("Expected an error status, got %v", status)
+	}
+	attr := first["attributes"].([]any)[0].(map[string]any)
+	if attr["key"] != "focusmode.dry_run" || attr["value"].(map[string]any)["boolValue"] != true {
+		t.Errorf("Unexpected attribute %v", attr)
+	}
+}
+
+// TestTracingFromEnv tests that a command started under a span traces into its parent's trace
+func TestTracingFromEnv(t *testing.T) {
+	defer func() { activeTracer = nil }()
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	t.Setenv(traceParentEnv, parent)
+	t.Setenv(tracingEndpointEnv, "")
+	t.Setenv(tracingBaseEndpoint, "")
+	startTracingFromEnv()
+	if activeTracer != nil {
+		t.Fatal("Expected no tracing without a collector")
+	}
+
+	t.Setenv(tracingEndpointEnv, "http://127.0.0.1:1/v1/traces")
+	startTracingFromEnv()
+	if activeTracer == nil || !activeTracer.sync {
+		t.Fatal("Expected tracing that exports as spans finish")
+	}
+	activeTracer.sync = false // Nothing listens on the endpoint
+	_, span := startSpan(context.Background(), "apply")
+	if got := span.context.traceParent(); !strings.HasPrefix(got, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || span.parent != [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7} {
+		t.Errorf("Expected a child of %s, got %s", parent, got)
+	}
+}
@@ -0,0 +1,231 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TTSConfig represents the spoken announcement settings used during sessions
+type TTSConfig struct {
+	Enabled    bool   `yaml:"enabled" doc:"Announce session progress aloud" default:"false"`
+	Voice      string `yaml:"voice" doc:"Platform voice name (empty uses the system default)"`
+	AnnounceAt []int  `yaml:"announce_at" doc:"Minutes remaining at which to announce" default:"[10, 5, 1]"`
+}
+
+// defaultAnnounceAt is used when no announcement thresholds are configured
+var defaultAnnounceAt = []int{10, 5, 1}
+
+// ttsAnnouncer speaks session events through the platform text-to-speech engine
+type ttsAnnouncer struct {
+	voice      string
+	thresholds []int
+	announced  map[int]bool
+	speak      func(text string, done func())
+	session    *FocusSession // session being announced, used for ducking in Notify
+
+	mu sync.Mutex // serializes speech so announcements don't overlap
+}
+
+// newTTSAnnouncer creates an announcer with thresholds sorted from largest to smallest
+func newTTSAnnouncer(config TTSConfig) *ttsAnnouncer {
+	thresholds := config.AnnounceAt
+	if len(thresholds) == 0 {
+		thresholds = defaultAnnounceAt
+	}
+	thresholds = append([]int(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+
+	a := &ttsAnnouncer{
+		voice:      config.Voice,
+		thresholds: thresholds,
+		announced:  make(map[int]bool),
+	}
+	a.speak = a.speakAsync
+	return a
+}
+
+// speakCommandArgs returns the external command used to speak text on the given platform
+func speakCommandArgs(goos string, voice string, text string, lookPath func(string) (string, error)) (string, []string, error) {
+	switch goos {
+	case "windows":
+		script := "Add-Type -AssemblyName System.Speech; $s = New-Object System.Speech.Synthesis.SpeechSynthesizer; "
+		if voice != "" {
+			script += fmt.Sprintf("$s.SelectVoice('%s'); ", strings.ReplaceAll(voice, "'", "''"))
+		}
+		script += fmt.Sprintf("$s.Speak('%s')", strings.ReplaceAll(text, "'", "''"))
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "darwin":
+		if voice != "" {
+			return "say", []string{"-v", voice, text}, nil
+		}
+		return "say", []string{text}, nil
+	case "linux":
+		for _, engine := range []string{"espeak-ng", "espeak"} {
+			if _, err := lookPath(engine); err == nil {
+				if voice != "" {
+					return engine, []string{"-v", voice, text}, nil
+				}
+				return engine, []string{text}, nil
+			}
+		}
+		if _, err := lookPath("spd-say"); err == nil {
+			return "spd-say", []string{"--wait", text}, nil
+		}
+		return "", nil, fmt.Errorf("no text-to-speech engine found (install espeak-ng or speech-dispatcher)")
+	default:
+		return "", nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// speakAsync speaks text in the background and calls done once speech has finished
+func (a *ttsAnnouncer) speakAsync(text string, done func()) {
+	go func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		defer done()
+
+		name, args, err := speakCommandArgs(runtime.GOOS, a.voice, text, exec.LookPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: announcement unavailable: %v\n", err)
+			return
+		}
+		if err := exec.Command(name, args...).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: announcement failed: %v\n", err)
+		}
+	}()
+}
+
+// announce speaks text, ducking any ambient sound of the session for the duration
+func (a *ttsAnnouncer) announce(fs *FocusSession, text string) {
+	var player *ambientPlayer
+	for _, hook := range fs.Hooks {
+		if p, ok := hook.(*ambientPlayer); ok {
+			player = p
+		}
+	}
+
+	if player == nil || player.isDucked() {
+		a.speak(text, func() {})
+		return
+	}
+
+	player.setDucked(true)
+	a.speak(text, func() { player.setDucked(false) })
+}
+
+// remainingAnnouncement returns the phrase spoken when minutes remain
+func remainingAnnouncement(minutes int) string {
+	if minutes == 1 {
+		return "1 minute remaining"
+	}
+	return fmt.Sprintf("%d minutes remaining", minutes)
+}
+
+// Notify speaks a notification message, making TTS usable as a notifier backend
+func (a *ttsAnnouncer) Notify(title, message string) error {
+	if a.session == nil {
+		a.speak(message, func() {})
+		return nil
+	}
+	a.announce(a.session, message)
+	return nil
+}
+
+// OnStart announces the session length
+func (a *ttsAnnouncer) OnStart(fs *FocusSession) {
+	a.session = fs
+	if fs.Break {
+		a.announce(fs, fmt.Sprintf("Break started. %s.", spokenDuration(fs.Duration)))
+		return
+	}
+	a.announce(fs, fmt.Sprintf("Focus session started. %s.", spokenDuration(fs.Duration)))
+}
+
+// OnPause announces that the session is paused
+func (a *ttsAnnouncer) OnPause(fs *FocusSession) {
+	a.announce(fs, "Session paused")
+}
+
+// OnResume announces that the session has resumed
+func (a *ttsAnnouncer) OnResume(fs *FocusSession) {
+	a.announce(fs, "Session resumed")
+}
+
+// OnEnd announces completion or an early stop
+func (a *ttsAnnouncer) OnEnd(fs *FocusSession) {
+	if fs.Break {
+		a.announce(fs, "Break over")
+	} else if fs.State == StateCompleted {
+		a.announce(fs, "Focus session complete. Break time.")
+	} else {
+		a.announce(fs, "Focus session stopped")
+	}
+}
+
+// OnTick announces each configured threshold once as it is crossed
+// Thresholds not shorter than the session itself are skipped
+func (a *ttsAnnouncer) OnTick(fs *FocusSession) {
+	remaining := fs.remaining()
+	for _, minutes := range a.thresholds {
+		threshold := time.Duration(minutes) * time.Minute
+		if a.announced[minutes] || threshold >= fs.Duration || remaining > threshold {
+			continue
+		}
+		a.announced[minutes] = true
+		// Only speak the smallest crossed threshold if several are crossed at once
+		if next := a.nextThreshold(minutes); next > 0 && remaining <= time.Duration(next)*time.Minute {
+			continue
+		}
+		a.announce(fs, remainingAnnouncement(minutes))
+	}
+}
+
+// nextThreshold returns the next smaller threshold after minutes, or 0
+func (a *ttsAnnouncer) nextThreshold(minutes int) int {
+	for _, m := range a.thresholds {
+		if m < minutes {
+			return m
+		}
+	}
+	return 0
+}
+
+// spokenDuration formats a duration for speech, e.g. "1 hour 5 minutes"
+func spokenDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	var parts []string
+	if hours == 1 {
+		parts = append(parts, "1 hour")
+	} else if hours > 1 {
+		parts = append(parts, fmt.Sprintf("%d hours", hours))
+	}
+	if minutes == 1 {
+		parts = append(parts, "1 minute")
+	} else if minutes > 1 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DryRun reports the announcements that would be spoken
+func (a *ttsAnnouncer) DryRun(fs *FocusSession) []string {
+	var at []string
+	for _, minutes := range a.thresholds {
+		if time.Duration(minutes)*time.Minute < fs.Duration {
+			at = append(at, remainingAnnouncement(minutes))
+		}
+	}
+	line := "announce the session start and end aloud"
+	if len(at) > 0 {
+		line += ", and " + strings.Join(at, ", ")
+	}
+	return []string{line}
+}
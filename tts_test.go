@@ -0,0 +1,112 @@
+package focusmode
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTTSAnnouncerThresholds tests that each threshold is announced once as it is crossed
+func TestTTSAnnouncerThresholds(t *testing.T) {
+	announcer := newTTSAnnouncer(TTSConfig{Enabled: true, AnnounceAt: []int{1, 10, 5}})
+	var spoken []string
+	announcer.speak = func(text string, done func()) {
+		spoken = append(spoken, text)
+		done()
+	}
+
+	fs := &FocusSession{
+		Duration:  25 * time.Minute,
+		StartTime: time.Now(),
+		State:     StateRunning,
+	}
+
+	// 20 minutes remaining: nothing to announce
+	fs.StartTime = time.Now().Add(-5 * time.Minute)
+	announcer.OnTick(fs)
+	if len(spoken) != 0 {
+		t.Fatalf("Expected no announcements, got %v", spoken)
+	}
+
+	// 9m30s remaining: announce 10 minutes once
+	fs.StartTime = time.Now().Add(-15*time.Minute - 30*time.Second)
+	announcer.OnTick(fs)
+	announcer.OnTick(fs)
+	if len(spoken) != 1 || spoken[0] != "10 minutes remaining" {
+		t.Fatalf("Expected one 10 minute announcement, got %v", spoken)
+	}
+
+	// Jump past both 5m and 1m: only the smallest crossed threshold is spoken
+	fs.StartTime = time.Now().Add(-24*time.Minute - 30*time.Second)
+	announcer.OnTick(fs)
+	if len(spoken) != 2 || spoken[1] != "1 minute remaining" {
+		t.Fatalf("Expected 1 minute announcement, got %v", spoken)
+	}
+}
+
+// TestTTSAnnouncerSkipsLongThresholds tests that thresholds longer than the session are skipped
+func TestTTSAnnouncerSkipsLongThresholds(t *testing.T) {
+	announcer := newTTSAnnouncer(TTSConfig{Enabled: true})
+	var spoken []string
+	announcer.speak = func(text string, done func()) {
+		spoken = append(spoken, text)
+		done()
+	}
+
+	fs := &FocusSession{
+		Duration:  5 * time.Minute,
+		StartTime: time.Now(),
+		State:     StateRunning,
+	}
+	announcer.OnTick(fs)
+	if len(spoken) != 0 {
+		t.Errorf("Expected no announcements at session start, got %v", spoken)
+	}
+}
+
+// TestSpeakCommandArgs tests TTS engine selection per platform
+func TestSpeakCommandArgs(t *testing.T) {
+	none := func(string) (string, error) { return "", errors.New("not found") }
+	espeak := func(name string) (string, error) {
+		if name == "espeak" {
+			return "/usr/bin/espeak", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	name, args, err := speakCommandArgs("darwin", "Samantha", "hello", none)
+	if err != nil || name != "say" || len(args) != 3 || args[1] != "Samantha" {
+		t.Errorf("Unexpected macOS command: %s %v (%v)", name, args, err)
+	}
+
+	name, args, err = speakCommandArgs("linux", "", "hello", espeak)
+	if err != nil || name != "espeak" || len(args) != 1 {
+		t.Errorf("Unexpected Linux command: %s %v (%v)", name, args, err)
+	}
+
+	if _, _, err := speakCommandArgs("linux", "", "hello", none); err == nil {
+		t.Error("Expected error when no Linux TTS engine is installed")
+	}
+
+	name, _, err = speakCommandArgs("windows", "Zira", "it's", none)
+	if err != nil || name != "powershell" {
+		t.Errorf("Unexpected Windows command: %s (%v)", name, err)
+	}
+}
+
+// TestSpokenDuration tests duration phrasing for speech
+func TestSpokenDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		25 * time.Minute:            "25 minutes",
+		time.Minute:                 "1 minute",
+		time.Hour:                   "1 hour",
+		2*time.Hour + 5*time.Minute: "2 hours 5 minutes",
+		30 * time.Second:            "0 minutes",
+		1*time.Hour + 1*time.Minute: "1 hour 1 minute",
+	}
+	for d, want := range tests {
+		if got := spokenDuration(d); got != want {
+			t.Errorf("spokenDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// operationHistoryLimit is how many operations are kept for undo
+const operationHistoryLimit = 20
+
+// Operation kinds recorded in the operation log
+const (
+	operationApply    = "apply"     // shortcuts moved off the desktop
+	operationRestore  = "restore"   // shortcuts moved back to the desktop
+	operationArchive  = "archive"   // stale files moved into a dated archive folder
+	operationAutoHide = "auto-hide" // shortcuts added by installers moved off the desktop
+)
+
+// fileMove is one shortcut moved by an operation
+type fileMove struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"` // Mode whose folder the shortcut moved to or from
+	From string `json:"from"` // Folder the shortcut was moved out of
+	To   string `json:"to"`   // Folder the shortcut was moved into
+}
+
+// MarshalJSON writes the name with its raw bytes and normalization forms
+func (m fileMove) MarshalJSON() ([]byte, error) {
+	type plain fileMove
+	return json.Marshal(struct {
+		plain
+		Name storedName `json:"name"`
+	}{plain(m), newStoredName(m.Name)})
+}
+
+// UnmarshalJSON reads a move whose name was written as a storedName or a plain string
+func (m *fileMove) UnmarshalJSON(data []byte) error {
+	type plain fileMove
+	var v struct {
+		plain
+		Name json.RawMessage `json:"name"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	name, err := decodeStoredName(v.Name)
+	if err != nil {
+		return err
+	}
+	*m = fileMove(v.plain)
+	m.Name = name
+	return nil
+}
+
+// reversed returns the move that puts the shortcut back
+func (m fileMove) reversed() fileMove {
+	return fileMove{Name: m.Name, Mode: m.Mode, From: m.To, To: m.From}
+}
+
+// operation is an apply or restore and the manifest of shortcuts it moved
+type operation struct {
+	Kind  string     `json:"kind"`
+	Mode  string     `json:"mode"` // Mode name, or "all" for restore-all
+	Time  time.Time  `json:"time"`
+	Moves []fileMove `json:"moves"`
+
+	// Screenshot is the image of the screen before an apply or after a restore, if one was taken
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// describe returns a one-line summary of the operation
+func (op operation) describe() string {
+	return fmt.Sprintf("%s %s (%d shortcut(s), %s)", op.Kind, op.Mode, len(op.Moves), op.Time.Local().Format("Mon 15:04"))
+}
+
+// reversed returns the moves that undo the operation, last move first
+func (op operation) reversed() []fileMove {
+	moves := make([]fileMove, len(op.Moves))
+	for i, move := range op.Moves {
+		moves[len(op.Moves)-1-i] = move.reversed()
+	}
+	return moves
+}
+
+// operationLog holds the operations that can be undone, oldest first, and the undone
+// operations that can be redone, most recently undone last
+type operationLog struct {
+	Done   []operation `json:"done"`
+	Undone []operation `json:"undone"`
+}
+
+// operationLogPath returns the path of the operation log
+func operationLogPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "operations.json"), nil
+}
+
+// readOperationLog returns the operation log recorded at path
+func readOperationLog(path string) (*operationLog, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &operationLog{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading operation log: %w", err)
+	}
+	var log operationLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("error parsing operation log: %w", err)
+	}
+	return &log, nil
+}
+
+// writeOperationLog records the operation log at path
+func writeOperationLog(path string, log *operationLog) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding operation log: %w", err)
+	}
+	if err := writeFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing operation log: %w", err)
+	}
+	ownByUser(path)
+	return nil
+}
+
+// push adds a new operation, dropping the oldest beyond the limit
+// A new operation makes the undone ones impossible to redo; the operations dropped
+// either way are returned
+func (l *operationLog) push(op operation) []operation {
+	dropped := l.Undone
+	l.Done = append(l.Done, op)
+	if len(l.Done) > operationHistoryLimit {
+		dropped = append(dropped, l.Done[:len(l.Done)-operationHistoryLimit]...)
+		l.Done = l.Done[len(l.Done)-operationHistoryLimit:]
+	}
+	l.Undone = nil
+	return dropped
+}
+
+// recordOperation adds an operation to the log with its screenshot, if any, warning on failure
+// Operations that moved nothing are not recorded, and neither is their screenshot
+func recordOperation(kind, mode string, moves []fileMove, screenshot string) {
+	op := operation{Kind: kind, Mode: mode, Time: time.Now(), Moves: moves, Screenshot: screenshot}
+	if len(moves) == 0 {
+		removeScreenshots([]operation{op})
+		return
+	}
+	path, err := operationLogPath()
+	if err == nil {
+		var log *operationLog
+		if log, err = readOperationLog(path); err == nil {
+			dropped := log.push(op)
+			if err = writeOperationLog(path, log); err == nil {
+				removeScreenshots(dropped)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// desktopMoves returns the moves of shortcuts between the desktop and a mode's folder
+// Shortcuts from the public desktop are recorded as moves from there
+// It returns nil if the desktop can't be found, in which case nothing is recorded
+func desktopMoves(mode, folder string, names []string, toDesktop bool) []fileMove {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return nil
+	}
+	public := publicDesktopPath()
+	var origins publicOrigins
+	if public != "" {
+		origins = readPublicOrigins()
+	}
+	moves := make([]fileMove, len(names))
+	for i, name := range names {
+		moves[i] = fileMove{Name: name, Mode: mode, From: desktopPath, To: folder}
+		if origins.has(folder, name) {
+			moves[i].From = public
+		}
+		if toDesktop {
+			moves[i] = moves[i].reversed()
+		}
+	}
+	return moves
+}
+
+// moveConflicts returns why the moves can't be made safely, checked before anything is moved
+// A shortcut must still be where the operation left it, and nothing may be in its way
+func moveConflicts(config *Config, moves []fileMove, desktopPath string) []string {
+	var conflicts []string
+	if isSessionRunning() {
+		conflicts = append(conflicts, "a focus session is running; stop it first")
+	}
+	for _, move := range moves {
+		if _, ok := findFileName(move.From, move.Name); !ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s is no longer in %s", move.Name, move.From))
+		}
+		if err := config.checkPinned(move.From, move.Name); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+		if _, ok := findFileName(move.To, move.Name); ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s already exists in %s", move.Name, move.To))
+		}
+		if filepath.Clean(move.To) == filepath.Clean(desktopPath) && len(config.restorable([]string{move.Name})) == 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s is kept hidden by machine policy", move.Name))
+		}
+	}
+	return conflicts
+}
+
+// performMoves makes the moves, putting back the ones already made if one fails
+func performMoves(config *Config, moves []fileMove, dryRun bool) error {
+	for i := range moves {
+		move := &moves[i]
+		if dryRun {
+			fmt.Printf("[DRY RUN] Would move: %s -> %s\n", move.Name, move.To)
+			continue
+		}
+		if err := moveOne(config, move); err != nil {
+			rollbackMoves(moves[:i])
+			return fmt.Errorf("%w; nothing was changed", err)
+		}
+		fmt.Printf("%sMoved: %s -> %s\n", glyph("✓ "), move.Name, move.To)
+	}
+	return nil
+}
+
+// moveOne makes a single move, creating the folder it goes to; a file config pins stays on the desktop
+// The name is moved, and changed in move, in whichever normalization form it now has on disk
+func moveOne(config *Config, move *fileMove) error {
+	if err := config.checkPinned(move.From, move.Name); err != nil {
+		return err
+	}
+	// The mode's folder may have been removed since the shortcuts left it
+	if err := mkdirAll(move.To, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", move.To, err)
+	}
+	if diskName, ok := findFileName(move.From, move.Name); ok {
+		move.Name = diskName
+	}
+	if err := moveFile(filepath.Join(move.From, move.Name), filepath.Join(move.To, move.Name)); err != nil {
+		return fmt.Errorf("error moving %s: %w", move.Name, err)
+	}
+	return nil
+}
+
+// rollbackMoves puts back moves already made, the last first
+func rollbackMoves(done []fileMove) {
+	for j := len(done) - 1; j >= 0; j-- {
+		back := done[j].reversed()
+		moveFile(filepath.Join(back.From, back.Name), filepath.Join(back.To, back.Name))
+	}
+}
+
+// recordMovesInStack updates the applied mode list after moves made by undo or redo
+// Shortcuts moved to the desktop leave their mode's layer; shortcuts moved off it join it
+func recordMovesInStack(moves []fileMove, desktopPath string) {
+	var modes []string
+	seen := make(map[string]bool)
+	restored := make(map[string][]string)
+	hidden := make(map[string][]string)
+	folders := make(map[string]string)
+	for _, move := range moves {
+		if !seen[move.Mode] {
+			seen[move.Mode] = true
+			modes = append(modes, move.Mode)
+		}
+		if filepath.Clean(move.To) == filepath.Clean(desktopPath) {
+			restored[move.Mode] = append(restored[move.Mode], move.Name)
+		} else {
+			hidden[move.Mode] = append(hidden[move.Mode], move.Name)
+			folders[move.Mode] = move.To
+		}
+	}
+
+	for _, mode := range modes {
+		if names := hidden[mode]; len(names) > 0 {
+			recordModeApplied(mode, folders[mode], names)
+		}
+		if names := restored[mode]; len(names) > 0 {
+			recordShortcutsRestored(mode, names)
+		}
+	}
+}
+
+// hiddenModes returns the modes whose shortcuts the moves take off the desktop
+func hiddenModes(moves []fileMove, desktopPath string) []string {
+	var modes []string
+	seen := make(map[string]bool)
+	for _, move := range moves {
+		if filepath.Clean(move.From) == filepath.Clean(desktopPath) && !seen[move.Mode] {
+			seen[move.Mode] = true
+			modes = append(modes, move.Mode)
+		}
+	}
+	return modes
+}
+
+// replayOperation makes the moves of an undo or redo after checking them
+func replayOperation(config *Config, verb string, op operation, moves []fileMove, dryRun bool) error {
+	desktopPath, err := getDesktopPath()
+	if err != nil {
+		return err
+	}
+	if conflicts := moveConflicts(config, moves, desktopPath); len(conflicts) > 0 {
+		return fmt.Errorf("can't %s %s:\n  %s", verb, op.describe(), strings.Join(conflicts, "\n  "))
+	}
+	// Hiding a protected mode's shortcuts again needs the PIN, as applying the mode does
+	if !dryRun {
+		if err := config.requireGuardianPIN(hiddenModes(moves, desktopPath), stdinLines(), os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s: %s\n", strings.ToUpper(verb[:1])+verb[1:], op.describe())
+	if err := performMoves(config, moves, dryRun); err != nil {
+		return err
+	}
+	// Archived and auto-hidden files never belonged to a mode's layer
+	if !dryRun && op.Kind != operationArchive && op.Kind != operationAutoHide {
+		recordMovesInStack(moves, desktopPath)
+		refreshHiddenMenu(config)
+	}
+	return nil
+}
+
+// runUndoCommand handles the "undo" and "redo" subcommands
+func runUndoCommand(name string, args []string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	dryRun := flags.Bool("dry-run", false, "Show what would be moved without actually moving")
+	list := flags.Bool("list", false, "List the operations that can be undone and redone")
+	flags.Parse(args)
+
+	if *list {
+		path, err := operationLogPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		log, err := readOperationLog(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printOperationLog(log)
+		return
+	}
+	undoLastOperation(name, *configPath, *dryRun)
+}
+
+// undoLastOperation undoes the most recent operation, or redoes the most recently undone
+// one when name is "redo"; used by the undo and redo subcommands and the -undo flag
+func undoLastOperation(name, configPath string, dryRun bool) {
+	path, err := operationLogPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	log, err := readOperationLog(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if name == "undo" {
+		if len(log.Done) == 0 {
+			fmt.Println("Nothing to undo.")
+			return
+		}
+		op := log.Done[len(log.Done)-1]
+		if err := replayOperation(config, "undo", op, op.reversed(), dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		log.Done = log.Done[:len(log.Done)-1]
+		log.Undone = append(log.Undone, op)
+	} else {
+		if len(log.Undone) == 0 {
+			fmt.Println("Nothing to redo.")
+			return
+		}
+		op := log.Undone[len(log.Undone)-1]
+		if err := replayOperation(config, "redo", op, op.Moves, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		log.Undone = log.Undone[:len(log.Undone)-1]
+		log.Done = append(log.Done, op)
+	}
+
+	if dryRun {
+		fmt.Println("(Dry run - no files were actually moved)")
+		return
+	}
+	if err := writeOperationLog(path, log); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printOperationLog lists the operations, most recent first
+func printOperationLog(log *operationLog) {
+	if len(log.Done) == 0 && len(log.Undone) == 0 {
+		fmt.Println("No operations recorded.")
+		return
+	}
+	for i := len(log.Undone) - 1; i >= 0; i-- {
+		fmt.Printf("  redo: %s\n", log.Undone[i].describe())
+		printOperationScreenshot(log.Undone[i])
+	}
+	for i := len(log.Done) - 1; i >= 0; i-- {
+		fmt.Printf("  undo: %s\n", log.Done[i].describe())
+		printOperationScreenshot(log.Done[i])
+	}
+}
+
+// printOperationScreenshot prints where the operation's screenshot is, if it has one
+func printOperationScreenshot(op operation) {
+	if op.Screenshot == "" {
+		return
+	}
+	when := "before"
+	if op.Kind == operationRestore {
+		when = "after"
+	}
+	fmt.Printf("        screen %s: %s\n", when, op.Screenshot)
+}
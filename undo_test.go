@@ -0,0 +1,119 @@
+package focusmode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOperationLogPush tests that the log keeps the last operations and clears redo on a new one
+func TestOperationLogPush(t *testing.T) {
+	log := &operationLog{Undone: []operation{{Kind: operationApply, Mode: "gamemode"}}}
+	var dropped []operation
+	for i := 0; i < operationHistoryLimit+3; i++ {
+		dropped = append(dropped, log.push(operation{Kind: operationApply, Mode: "focusmode", Time: time.Unix(int64(i), 0)})...)
+	}
+	if len(dropped) != 4 || dropped[0].Mode != "gamemode" {
+		t.Errorf("Expected the undone operation and three oldest to be dropped, got %+v", dropped)
+	}
+	if len(log.Done) != operationHistoryLimit {
+		t.Errorf("Expected %d operations, got %d", operationHistoryLimit, len(log.Done))
+	}
+	if log.Done[0].Time.Unix() != 3 {
+		t.Errorf("Expected the oldest operations to be dropped, first is %v", log.Done[0].Time.Unix())
+	}
+	if len(log.Undone) != 0 {
+		t.Error("Expected a new operation to clear the redo list")
+	}
+}
+
+// TestOperationReversed tests that undo moves shortcuts back in reverse order
+func TestOperationReversed(t *testing.T) {
+	op := operation{Moves: []fileMove{
+		{Name: "a.lnk", Mode: "focusmode", From: "/desk", To: "/games"},
+		{Name: "b.lnk", Mode: "focusmode", From: "/desk", To: "/games"},
+	}}
+	got := op.reversed()
+	if len(got) != 2 || got[0].Name != "b.lnk" || got[0].From != "/games" || got[0].To != "/desk" {
+		t.Errorf("Unexpected reversed moves: %+v", got)
+	}
+}
+
+// TestUndoMoves tests conflict checks and moves between the desktop and a mode folder
+func TestUndoMoves(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	desktop := filepath.Join(dir, "Desktop")
+	games := filepath.Join(dir, "Games")
+	os.MkdirAll(desktop, 0755)
+	os.MkdirAll(games, 0755)
+	os.WriteFile(filepath.Join(games, "Steam.lnk"), nil, 0644)
+	os.WriteFile(filepath.Join(games, "Discord.lnk"), nil, 0644)
+
+	// An apply that moved both shortcuts into Games, undone
+	op := operation{Kind: operationApply, Mode: "focusmode", Moves: []fileMove{
+		{Name: "Steam.lnk", Mode: "focusmode", From: desktop, To: games},
+		{Name: "Discord.lnk", Mode: "focusmode", From: desktop, To: games},
+	}}
+	config := &Config{}
+	if conflicts := moveConflicts(config, op.reversed(), desktop); len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v", conflicts)
+	}
+
+	// A file of the same name on the desktop blocks the undo before anything moves
+	os.WriteFile(filepath.Join(desktop, "Discord.lnk"), nil, 0644)
+	conflicts := moveConflicts(config, op.reversed(), desktop)
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0], "Discord.lnk already exists") {
+		t.Errorf("Expected a conflict for Discord.lnk, got %v", conflicts)
+	}
+	os.Remove(filepath.Join(desktop, "Discord.lnk"))
+
+	// Shortcuts held back by machine policy stay hidden
+	strict := &Config{Policy: &Policy{AlwaysHidden: []string{"Steam.lnk"}}}
+	if conflicts := moveConflicts(strict, op.reversed(), desktop); len(conflicts) != 1 || !strings.Contains(conflicts[0], "machine policy") {
+		t.Errorf("Expected a policy conflict, got %v", conflicts)
+	}
+
+	if err := performMoves(&Config{}, op.reversed(), false); err != nil {
+		t.Fatalf("performMoves() returned error: %v", err)
+	}
+	for _, name := range []string{"Steam.lnk", "Discord.lnk"} {
+		if _, err := os.Stat(filepath.Join(desktop, name)); err != nil {
+			t.Errorf("Expected %s back on the desktop: %v", name, err)
+		}
+	}
+
+	// Redo finds the shortcuts where the undo left them
+	if conflicts := moveConflicts(config, op.Moves, desktop); len(conflicts) != 0 {
+		t.Errorf("Expected redo to be possible, got %v", conflicts)
+	}
+	os.Remove(filepath.Join(desktop, "Steam.lnk"))
+	if conflicts := moveConflicts(config, op.Moves, desktop); len(conflicts) != 1 || !strings.Contains(conflicts[0], "no longer in") {
+		t.Errorf("Expected a conflict for the missing shortcut, got %v", conflicts)
+	}
+}
+
+// TestRecordShortcutsRestored tests that undoing an apply shrinks or removes the mode's layer
+func TestRecordShortcutsRestored(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+
+	recordModeApplied("focusmode", "/games", []string{"Steam.lnk", "Discord.lnk"})
+	recordShortcutsRestored("focusmode", []string{"Steam.lnk"})
+	path, _ := appliedModesPath()
+	modes, _ := readAppliedModes(path)
+	if len(modes) != 1 || len(modes[0].Shortcuts) != 1 || modes[0].Shortcuts[0] != "Discord.lnk" {
+		t.Fatalf("Expected Discord.lnk left in the layer, got %+v", modes)
+	}
+	recordShortcutsRestored("focusmode", []string{"Discord.lnk"})
+	if modes, _ := readAppliedModes(path); len(modes) != 0 {
+		t.Errorf("Expected the empty layer to be removed, got %+v", modes)
+	}
+}
@@ -0,0 +1,225 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// targetUser is the account whose desktop and state are managed, set by the global --user flag
+// When empty the user is detected from the process context
+var targetUser string
+
+// userContext identifies the account whose desktop and state files are used
+type userContext struct {
+	Name      string
+	Home      string
+	ConfigDir string
+	uid, gid  int    // owner given to created files when acting for another user (-1 to keep)
+	sid       string // Windows security identifier when acting for another user, naming its registry hive
+	other     bool   // true when the account differs from the one running the process
+}
+
+// userNamePattern limits user names accepted from the command line and the daemon API
+var userNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._@\\ -]*$`)
+
+// validUserName reports whether name is safe to pass on as a --user argument
+func validUserName(name string) bool {
+	return len(name) <= 256 && userNamePattern.MatchString(name)
+}
+
+// parseUserArg extracts a leading --user flag and returns the user name and remaining arguments
+func parseUserArg(args []string) (string, []string, error) {
+	value, rest, err := parseValueArg(args, "user")
+	if err != nil || len(rest) == len(args) {
+		return "", rest, err
+	}
+	if !validUserName(value) {
+		return "", nil, fmt.Errorf("invalid user name: %q", value)
+	}
+	return value, rest, nil
+}
+
+// isServiceAccount reports whether the process runs as an account without its own desktop,
+// such as root, SYSTEM or a Windows service account
+func isServiceAccount(goos, username, home string) bool {
+	if goos == "windows" {
+		name := strings.ToUpper(username)
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+		switch name {
+		case "SYSTEM", "LOCAL SERVICE", "NETWORK SERVICE", "LOCALSERVICE", "NETWORKSERVICE":
+			return true
+		}
+		lower := strings.ToLower(home)
+		return strings.Contains(lower, `\systemprofile`) || strings.Contains(lower, `\serviceprofiles\`)
+	}
+	return username == "root"
+}
+
+// consoleUserCommand returns the command that reports the user logged in at the console
+func consoleUserCommand(goos string) (string, []string) {
+	switch goos {
+	case "windows":
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", "(Get-CimInstance Win32_ComputerSystem).UserName"}
+	case "darwin":
+		return "stat", []string{"-f", "%Su", "/dev/console"}
+	default:
+		return "loginctl", []string{"list-sessions", "--no-legend"}
+	}
+}
+
+// parseConsoleUser extracts the console user from the output of consoleUserCommand
+func parseConsoleUser(goos, output string) string {
+	switch goos {
+	case "windows":
+		// DOMAIN\user; user.Lookup accepts the qualified name
+		return strings.TrimSpace(output)
+	case "darwin":
+		name := strings.TrimSpace(output)
+		if name == "root" {
+			return "" // login window
+		}
+		return name
+	default:
+		// SESSION UID USER SEAT ... ; the first session on a seat is the graphical one
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && strings.HasPrefix(fields[3], "seat") && fields[2] != "root" {
+				return fields[2]
+			}
+		}
+		return ""
+	}
+}
+
+var (
+	consoleUserOnce sync.Once
+	consoleUserName string
+)
+
+// consoleUser returns the user logged in at the console, or "" if it can't be determined
+func consoleUser() string {
+	consoleUserOnce.Do(func() {
+		name, args := consoleUserCommand(runtime.GOOS)
+		out, err := exec.Command(name, args...).Output()
+		if err == nil {
+			consoleUserName = parseConsoleUser(runtime.GOOS, string(out))
+		}
+	})
+	return consoleUserName
+}
+
+// currentUserContext returns the account to act for
+// An explicit --user wins; a service account acts for the sudo or console user instead of itself
+func currentUserContext() (*userContext, error) {
+	if targetUser != "" {
+		return lookupUserContext(targetUser)
+	}
+
+	current, err := user.Current()
+	if err == nil && isServiceAccount(runtime.GOOS, current.Username, current.HomeDir) {
+		name := os.Getenv("SUDO_USER")
+		if runtime.GOOS == "windows" || name == "" || name == "root" {
+			name = consoleUser()
+		}
+		if name != "" && name != current.Username {
+			return lookupUserContext(name)
+		}
+	}
+
+	return processUserContext(current)
+}
+
+// processUserContext returns the context of the account running the process
+// The home and config directories come from the environment so HOME and friends are honored
+func processUserContext(current *user.User) (*userContext, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting user config directory: %w", err)
+	}
+	ctx := &userContext{Home: home, ConfigDir: configDir, uid: -1, gid: -1}
+	if current != nil {
+		ctx.Name = current.Username
+	}
+	return ctx, nil
+}
+
+// lookupUserContext returns the context of a named account
+func lookupUserContext(name string) (*userContext, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	if current, err := user.Current(); err == nil && current.Uid == u.Uid {
+		return processUserContext(current)
+	}
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		return nil, fmt.Errorf("managing the desktop of %s requires running as root", name)
+	}
+
+	ctx := &userContext{
+		Name:      u.Username,
+		Home:      u.HomeDir,
+		ConfigDir: configDirFor(runtime.GOOS, u.HomeDir),
+		uid:       -1,
+		gid:       -1,
+		other:     true,
+	}
+	if runtime.GOOS == "windows" {
+		ctx.sid = u.Uid
+	} else {
+		ctx.uid, _ = strconv.Atoi(u.Uid)
+		ctx.gid, _ = strconv.Atoi(u.Gid)
+	}
+	return ctx, nil
+}
+
+// configDirFor returns the per-user configuration directory below another user's home
+func configDirFor(goos, home string) string {
+	switch goos {
+	case "windows":
+		return filepath.Join(home, "AppData", "Roaming")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support")
+	default:
+		return filepath.Join(home, ".config")
+	}
+}
+
+// userHomeDir returns the home directory of the account being managed
+func userHomeDir() (string, error) {
+	ctx, err := currentUserContext()
+	if err != nil {
+		return "", err
+	}
+	return ctx.Home, nil
+}
+
+// ownByUser gives a file or folder created for another user to that user,
+// so they can still use it when running FocusMode themselves
+// Paths outside the user's home, such as the guardian report, are left alone
+func ownByUser(path string) {
+	ctx, err := currentUserContext()
+	if err != nil || !ctx.other || ctx.uid < 0 {
+		return
+	}
+	if rel, err := filepath.Rel(ctx.Home, path); err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	if err := os.Chown(path, ctx.uid, ctx.gid); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not give %s to %s: %v\n", path, ctx.Name, err)
+	}
+}
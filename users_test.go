@@ -0,0 +1,93 @@
+package focusmode
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestParseUserArg tests extraction of the global --user flag
+func TestParseUserArg(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantUser string
+		wantRest []string
+	}{
+		{[]string{"--user", "alice", "-mode", "gamemode"}, "alice", []string{"-mode", "gamemode"}},
+		{[]string{"-user=bob", "status"}, "bob", []string{"status"}},
+		{[]string{"-mode", "gamemode"}, "", []string{"-mode", "gamemode"}},
+		{[]string{"status"}, "", []string{"status"}},
+		{nil, "", nil},
+	}
+	for _, tt := range tests {
+		user, rest, err := parseUserArg(tt.args)
+		if err != nil {
+			t.Errorf("parseUserArg(%v) returned error: %v", tt.args, err)
+			continue
+		}
+		if user != tt.wantUser || !reflect.DeepEqual(rest, tt.wantRest) {
+			t.Errorf("parseUserArg(%v) = %q, %v, want %q, %v", tt.args, user, rest, tt.wantUser, tt.wantRest)
+		}
+	}
+
+	for _, bad := range [][]string{{"--user"}, {"--user", "-mode"}, {"--user=a/b"}} {
+		if _, _, err := parseUserArg(bad); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+}
+
+// TestIsServiceAccount tests detection of accounts without their own desktop
+func TestIsServiceAccount(t *testing.T) {
+	tests := []struct {
+		goos, username, home string
+		want                 bool
+	}{
+		{"windows", `NT AUTHORITY\SYSTEM`, `C:\Windows\system32\config\systemprofile`, true},
+		{"windows", `NT AUTHORITY\LOCAL SERVICE`, `C:\Windows\ServiceProfiles\LocalService`, true},
+		{"windows", `svc-focus`, `C:\Windows\ServiceProfiles\svc-focus`, true},
+		{"windows", `DESKTOP\alice`, `C:\Users\alice`, false},
+		{"linux", "root", "/root", true},
+		{"darwin", "alice", "/Users/alice", false},
+	}
+	for _, tt := range tests {
+		if got := isServiceAccount(tt.goos, tt.username, tt.home); got != tt.want {
+			t.Errorf("isServiceAccount(%s, %q) = %v, want %v", tt.goos, tt.username, got, tt.want)
+		}
+	}
+}
+
+// TestParseConsoleUser tests reading the console user from platform command output
+func TestParseConsoleUser(t *testing.T) {
+	loginctl := "     2 1000 alice seat0 tty2\n     5    0 root        pts/1\n     7 1001 bob         pts/2\n"
+	if got := parseConsoleUser("linux", loginctl); got != "alice" {
+		t.Errorf("Expected alice from loginctl, got %q", got)
+	}
+	if got := parseConsoleUser("linux", "     5    0 root        pts/1\n"); got != "" {
+		t.Errorf("Expected no user without a seat, got %q", got)
+	}
+	if got := parseConsoleUser("darwin", "alice\n"); got != "alice" {
+		t.Errorf("Expected alice from /dev/console, got %q", got)
+	}
+	if got := parseConsoleUser("darwin", "root\n"); got != "" {
+		t.Errorf("Expected no user at the login window, got %q", got)
+	}
+	if got := parseConsoleUser("windows", "DESKTOP\\alice\r\n"); got != `DESKTOP\alice` {
+		t.Errorf("Expected DESKTOP\\alice from Win32_ComputerSystem, got %q", got)
+	}
+}
+
+// TestConfigDirFor tests the per-user config directory of another account
+func TestConfigDirFor(t *testing.T) {
+	home := filepath.Join("home", "alice")
+	tests := map[string]string{
+		"windows": filepath.Join(home, "AppData", "Roaming"),
+		"darwin":  filepath.Join(home, "Library", "Application Support"),
+		"linux":   filepath.Join(home, ".config"),
+	}
+	for goos, want := range tests {
+		if got := configDirFor(goos, home); got != want {
+			t.Errorf("configDirFor(%s) = %s, want %s", goos, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package focusmode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WakaTimeConfig represents the WakaTime integration settings
+type WakaTimeConfig struct {
+	Enabled bool   `yaml:"enabled" doc:"Include WakaTime coding activity in reports" default:"false"`
+	APIKey  string `yaml:"api_key" doc:"WakaTime API key"`
+	APIURL  string `yaml:"api_url" doc:"API base URL; set for Wakapi or other compatible servers" default:"https://wakatime.com/api/v1"`
+}
+
+const defaultWakaTimeAPIURL = "https://wakatime.com/api/v1"
+
+// wakatimeHTTPClient is used for all WakaTime API requests
+var wakatimeHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// CodingStats is the coding activity recorded during a session
+type CodingStats struct {
+	TotalSeconds int64            `json:"total_seconds"`
+	Languages    map[string]int64 `json:"languages,omitempty"` // seconds per language
+	Projects     map[string]int64 `json:"projects,omitempty"`  // seconds per project
+}
+
+// wakatimeDuration is one entry of the WakaTime durations API
+type wakatimeDuration struct {
+	Project  string  `json:"project"`
+	Language string  `json:"language"`
+	Time     float64 `json:"time"`     // Unix timestamp of the start
+	Duration float64 `json:"duration"` // Seconds
+}
+
+// fetchWakaTimeDurations returns durations for each day in [start, end], sliced by the given field
+// Requests are retried under policy
+func fetchWakaTimeDurations(policy RetryPolicy, config WakaTimeConfig, start, end time.Time, sliceBy string) ([]wakatimeDuration, error) {
+	apiURL := strings.TrimSuffix(config.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultWakaTimeAPIURL
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(config.APIKey))
+
+	var durations []wakatimeDuration
+	year, month, date := start.Date()
+	for day := time.Date(year, month, date, 0, 0, 0, 0, start.Location()); !day.After(end); day = day.AddDate(0, 0, 1) {
+		query := url.Values{"date": {day.Format("2006-01-02")}}
+		if sliceBy != "" {
+			query.Set("slice_by", sliceBy)
+		}
+		req, err := http.NewRequest(http.MethodGet, apiURL+"/users/current/durations?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", auth)
+
+		resp, err := doWithRetry(policy, wakatimeHTTPClient, req)
+		if err != nil {
+			return nil, fmt.Errorf("error contacting WakaTime: %w", err)
+		}
+		var body struct {
+			Data []wakatimeDuration `json:"data"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			return nil, fmt.Errorf("WakaTime API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing WakaTime response: %w", err)
+		}
+		durations = append(durations, body.Data...)
+	}
+	return durations, nil
+}
+
+// overlapSeconds returns how many seconds of a duration fall inside [start, end]
+func overlapSeconds(d wakatimeDuration, start, end time.Time) float64 {
+	from := time.Unix(0, int64(d.Time*float64(time.Second)))
+	to := from.Add(time.Duration(d.Duration * float64(time.Second)))
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from).Seconds()
+}
+
+// summarizeCoding builds coding stats for a session window from language and project durations
+func summarizeCoding(languages, projects []wakatimeDuration, start, end time.Time) *CodingStats {
+	stats := &CodingStats{Languages: make(map[string]int64), Projects: make(map[string]int64)}
+
+	var total float64
+	for _, d := range projects {
+		seconds := overlapSeconds(d, start, end)
+		if seconds <= 0 {
+			continue
+		}
+		total += seconds
+		name := d.Project
+		if name == "" {
+			name = "Unknown"
+		}
+		stats.Projects[name] += int64(seconds)
+	}
+	for _, d := range languages {
+		seconds := overlapSeconds(d, start, end)
+		if seconds <= 0 {
+			continue
+		}
+		name := d.Language
+		if name == "" {
+			name = "Other"
+		}
+		stats.Languages[name] += int64(seconds)
+	}
+	stats.TotalSeconds = int64(total)
+	return stats
+}
+
+// topEntries returns the names of a breakdown sorted by time spent, largest first
+func topEntries(breakdown map[string]int64, limit int) []string {
+	names := make([]string, 0, len(breakdown))
+	for name := range breakdown {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if breakdown[names[i]] != breakdown[names[j]] {
+			return breakdown[names[i]] > breakdown[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var entries []string
+	for i, name := range names {
+		if i == limit {
+			break
+		}
+		entries = append(entries, fmt.Sprintf("%s %s", name, formatDuration((time.Duration(breakdown[name])*time.Second).Round(time.Minute))))
+	}
+	return entries
+}
+
+// String formats coding stats as e.g. "42m (Go 30m, YAML 12m)"
+func (c *CodingStats) String() string {
+	if c == nil {
+		return "-"
+	}
+	total := formatDuration((time.Duration(c.TotalSeconds) * time.Second).Round(time.Minute))
+	if languages := topEntries(c.Languages, 3); len(languages) > 0 {
+		return fmt.Sprintf("%s (%s)", total, strings.Join(languages, ", "))
+	}
+	return total
+}
+
+// wakatimeCollector is a session hook that attaches WakaTime stats to the session when it ends
+type wakatimeCollector struct {
+	config WakaTimeConfig
+	retry  RetryPolicy
+}
+
+// OnStart does nothing
+func (w *wakatimeCollector) OnStart(fs *FocusSession) {}
+
+// OnPause does nothing
+func (w *wakatimeCollector) OnPause(fs *FocusSession) {}
+
+// OnResume does nothing
+func (w *wakatimeCollector) OnResume(fs *FocusSession) {}
+
+// OnEnd fetches coding activity for the session window
+// It must run before the history recorder so the stats are stored with the session
+func (w *wakatimeCollector) OnEnd(fs *FocusSession) {
+	if fs.Break {
+		return
+	}
+	end := time.Now()
+	languages, err := fetchWakaTimeDurations(w.retry, w.config, fs.StartTime, end, "language")
+	if err == nil {
+		var projects []wakatimeDuration
+		projects, err = fetchWakaTimeDurations(w.retry, w.config, fs.StartTime, end, "")
+		if err == nil {
+			fs.Coding = summarizeCoding(languages, projects, fs.StartTime, end)
+			fmt.Printf("\n%sCoding: %s\n", glyph("⌨️  "), fs.Coding)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\nWarning: WakaTime stats unavailable: %v\n", err)
+}
+
+// DryRun reports the WakaTime query made at the end of the session
+func (w *wakatimeCollector) DryRun(fs *FocusSession) []string {
+	if fs.Break {
+		return nil
+	}
+	return []string{"fetch coding activity from WakaTime when the session ends"}
+}
@@ -0,0 +1,82 @@
+package focusmode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSummarizeCoding tests clipping of durations to the session window
+func TestSummarizeCoding(t *testing.T) {
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	at := func(offset time.Duration) float64 { return float64(start.Add(offset).Unix()) }
+
+	projects := []wakatimeDuration{
+		{Project: "app", Time: at(-10 * time.Minute), Duration: 20 * 60}, // 10m inside
+		{Project: "app", Time: at(20 * time.Minute), Duration: 15 * 60},  // 15m inside
+		{Project: "lib", Time: at(50 * time.Minute), Duration: 30 * 60},  // 10m inside
+		{Project: "old", Time: at(-3 * time.Hour), Duration: 60 * 60},    // outside
+	}
+	languages := []wakatimeDuration{
+		{Language: "Go", Time: at(-10 * time.Minute), Duration: 20 * 60},
+		{Language: "Go", Time: at(20 * time.Minute), Duration: 15 * 60},
+		{Language: "", Time: at(50 * time.Minute), Duration: 30 * 60},
+	}
+
+	stats := summarizeCoding(languages, projects, start, end)
+	if stats.TotalSeconds != 35*60 {
+		t.Errorf("Expected 35m total, got %ds", stats.TotalSeconds)
+	}
+	if stats.Projects["app"] != 25*60 || stats.Projects["lib"] != 10*60 {
+		t.Errorf("Unexpected projects: %v", stats.Projects)
+	}
+	if _, exists := stats.Projects["old"]; exists {
+		t.Error("Expected durations outside the window to be ignored")
+	}
+	if stats.Languages["Go"] != 25*60 || stats.Languages["Other"] != 10*60 {
+		t.Errorf("Unexpected languages: %v", stats.Languages)
+	}
+	if s := stats.String(); s != "35m (Go 25m, Other 10m)" {
+		t.Errorf("Unexpected stats string %q", s)
+	}
+
+	var none *CodingStats
+	if none.String() != "-" {
+		t.Error("Expected nil stats to format as '-'")
+	}
+}
+
+// TestFetchWakaTimeDurations tests authentication, slicing and one request per day
+func TestFetchWakaTimeDurations(t *testing.T) {
+	var dates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+			t.Errorf("Expected basic auth, got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("slice_by") != "language" {
+			t.Errorf("Expected slice_by=language, got %q", r.URL.RawQuery)
+		}
+		dates = append(dates, r.URL.Query().Get("date"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []wakatimeDuration{{Language: "Go", Time: 1, Duration: 60}},
+		})
+	}))
+	defer server.Close()
+
+	// Session crossing midnight needs two days of durations
+	start := time.Date(2024, 5, 6, 23, 30, 0, 0, time.UTC)
+	durations, err := fetchWakaTimeDurations(RetryPolicy{}, WakaTimeConfig{APIKey: "key", APIURL: server.URL + "/"}, start, start.Add(time.Hour), "language")
+	if err != nil {
+		t.Fatalf("fetchWakaTimeDurations() returned error: %v", err)
+	}
+	if len(durations) != 2 {
+		t.Errorf("Expected 2 durations, got %d", len(durations))
+	}
+	if len(dates) != 2 || dates[0] != "2024-05-06" || dates[1] != "2024-05-07" {
+		t.Errorf("Unexpected dates requested: %v", dates)
+	}
+}
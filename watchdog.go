@@ -0,0 +1,257 @@
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchdogTimeout  = 3 * time.Minute
+	watchdogCheckInterval   = 10 * time.Second
+	maxRememberedIncidents  = 20
+	workerRestartMinBackoff = time.Second
+)
+
+// workerFunc is a long-running daemon loop
+// It must call beat regularly and return when ctx is cancelled
+type workerFunc func(ctx context.Context, beat func())
+
+// watchedWorker is a loop supervised by the watchdog
+type watchedWorker struct {
+	name     string
+	run      workerFunc
+	timeout  time.Duration // longest allowed gap between heartbeats
+	lastBeat time.Time
+	started  time.Time
+	restarts int
+	cancel   context.CancelFunc
+	epoch    int // incremented on restart so beats from an abandoned goroutine are ignored
+}
+
+// withLock runs f with mu held, releasing it even if f panics so a worker the watchdog
+// restarts doesn't leave every other command waiting on the lock
+func withLock(mu sync.Locker, f func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	f()
+}
+
+// incident records a watchdog restart
+type incident struct {
+	Time   time.Time `json:"time"`
+	Worker string    `json:"worker"`
+	Reason string    `json:"reason"`
+}
+
+// watchdog restarts daemon loops that stop sending heartbeats, exit or panic
+type watchdog struct {
+	mu        sync.Mutex
+	workers   []*watchedWorker
+	incidents []incident
+	now       func() time.Time
+	log       io.Writer
+	ctx       context.Context
+	cancel    context.CancelFunc
+	running   sync.WaitGroup // worker goroutines that have not returned yet
+}
+
+// newWatchdog creates a watchdog whose workers stop when ctx is cancelled or stop is called
+func newWatchdog(ctx context.Context, log io.Writer) *watchdog {
+	ctx, cancel := context.WithCancel(ctx)
+	return &watchdog{now: time.Now, log: log, ctx: ctx, cancel: cancel}
+}
+
+// stop cancels all workers and waits for them to return until ctx is done
+func (w *watchdog) stop(ctx context.Context) error {
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background loops did not stop in time: %w", ctx.Err())
+	}
+}
+
+// add registers and starts a worker; timeout 0 uses the default
+func (w *watchdog) add(name string, timeout time.Duration, run workerFunc) {
+	if timeout <= 0 {
+		timeout = defaultWatchdogTimeout
+	}
+	worker := &watchedWorker{name: name, run: run, timeout: timeout}
+	w.mu.Lock()
+	w.workers = append(w.workers, worker)
+	w.start(worker)
+	w.mu.Unlock()
+}
+
+// start runs a worker in a new goroutine; the caller holds w.mu
+func (w *watchdog) start(worker *watchedWorker) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	worker.cancel = cancel
+	worker.epoch++
+	worker.started = w.now()
+	worker.lastBeat = worker.started
+	epoch, started := worker.epoch, worker.started
+
+	beat := func() {
+		w.mu.Lock()
+		if worker.epoch == epoch {
+			worker.lastBeat = w.now()
+		}
+		w.mu.Unlock()
+	}
+
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+		reason := "exited"
+		defer func() {
+			if r := recover(); r != nil {
+				reason = fmt.Sprintf("panicked: %v", r)
+			}
+			if ctx.Err() != nil {
+				return // stopped by the watchdog or the daemon
+			}
+			// Wait a little before restarting a loop that exits straight away
+			if w.now().Sub(started) < workerRestartMinBackoff {
+				time.Sleep(workerRestartMinBackoff)
+			}
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if worker.epoch == epoch {
+				w.restart(worker, reason)
+			}
+		}()
+		worker.run(ctx, beat)
+	}()
+}
+
+// restart records an incident and replaces a worker's goroutine; the caller holds w.mu
+// A hung goroutine can't be killed, so it is cancelled and abandoned
+func (w *watchdog) restart(worker *watchedWorker, reason string) {
+	inc := incident{Time: w.now(), Worker: worker.name, Reason: reason}
+	w.incidents = append(w.incidents, inc)
+	if len(w.incidents) > maxRememberedIncidents {
+		w.incidents = w.incidents[len(w.incidents)-maxRememberedIncidents:]
+	}
+	fmt.Fprintf(w.log, "%s watchdog: restarting %s: %s\n", inc.Time.Format(time.RFC3339), worker.name, reason)
+
+	worker.cancel()
+	worker.restarts++
+	if w.ctx.Err() == nil {
+		w.start(worker)
+	}
+}
+
+// check restarts workers whose last heartbeat is older than their timeout
+func (w *watchdog) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	for _, worker := range w.workers {
+		if silent := now.Sub(worker.lastBeat); silent > worker.timeout {
+			w.restart(worker, fmt.Sprintf("no heartbeat for %s", silent.Round(time.Second)))
+		}
+	}
+}
+
+// watch runs check periodically until the watchdog's context is cancelled
+func (w *watchdog) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// workerHealth is the state of one worker in the health report
+type workerHealth struct {
+	Name     string    `json:"name"`
+	LastBeat time.Time `json:"last_beat"`
+	Restarts int       `json:"restarts"`
+	Healthy  bool      `json:"healthy"`
+}
+
+// healthReport is the body of /healthz
+type healthReport struct {
+	Status    string         `json:"status"` // "ok" or "degraded"
+	Workers   []workerHealth `json:"workers"`
+	Incidents []incident     `json:"incidents,omitempty"`
+}
+
+// health returns the current state of all workers and recent incidents
+func (w *watchdog) health() healthReport {
+	report := healthReport{Status: "ok", Workers: []workerHealth{}}
+	if w == nil {
+		return report
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	for _, worker := range w.workers {
+		healthy := now.Sub(worker.lastBeat) <= worker.timeout
+		if !healthy {
+			report.Status = "degraded"
+		}
+		report.Workers = append(report.Workers, workerHealth{
+			Name:     worker.name,
+			LastBeat: worker.lastBeat,
+			Restarts: worker.restarts,
+			Healthy:  healthy,
+		})
+	}
+	report.Incidents = append(report.Incidents, w.incidents...)
+	return report
+}
+
+// healthHandler serves /healthz: 200 when all workers are beating, 503 otherwise
+// It needs no token and reveals nothing beyond worker names and restart counts
+func healthHandler(w *watchdog) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(rw, http.StatusMethodNotAllowed, commandResponse{Error: "use GET"})
+			return
+		}
+		report := w.health()
+		status := http.StatusOK
+		if report.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(rw, status, report)
+	})
+}
+
+// heartbeatHook is a session hook that keeps a watched worker alive while a session runs
+type heartbeatHook func()
+
+// OnStart beats
+func (h heartbeatHook) OnStart(fs *FocusSession) { h() }
+
+// OnPause beats
+func (h heartbeatHook) OnPause(fs *FocusSession) { h() }
+
+// OnResume beats
+func (h heartbeatHook) OnResume(fs *FocusSession) { h() }
+
+// OnEnd beats
+func (h heartbeatHook) OnEnd(fs *FocusSession) { h() }
+
+// OnTick beats
+func (h heartbeatHook) OnTick(fs *FocusSession) { h() }
+
+// DryRun reports nothing; heartbeats have no side effects
+func (h heartbeatHook) DryRun(fs *FocusSession) []string { return nil }
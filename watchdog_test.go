@@ -0,0 +1,159 @@
+package focusmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for the watchdog
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+// TestWatchdogRestartsHungWorker tests that a worker without heartbeats is restarted
+func TestWatchdogRestartsHungWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	var log bytes.Buffer
+	w := newWatchdog(ctx, &log)
+	w.now = clock.now
+
+	starts := make(chan struct{}, 4)
+	w.add("scheduler", time.Minute, func(ctx context.Context, beat func()) {
+		starts <- struct{}{}
+		<-ctx.Done() // hangs without beating until cancelled
+	})
+	<-starts
+
+	clock.advance(30 * time.Second)
+	w.check()
+	if report := w.health(); report.Status != "ok" || report.Workers[0].Restarts != 0 {
+		t.Fatalf("Expected healthy worker within the timeout, got %+v", report)
+	}
+
+	clock.advance(time.Minute)
+	if report := w.health(); report.Status != "degraded" {
+		t.Errorf("Expected degraded status before the check, got %+v", report)
+	}
+	w.check()
+
+	select {
+	case <-starts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected hung worker to be restarted")
+	}
+	report := w.health()
+	if report.Status != "ok" || report.Workers[0].Restarts != 1 || len(report.Incidents) != 1 {
+		t.Errorf("Unexpected report after restart: %+v", report)
+	}
+	if !strings.Contains(log.String(), "restarting scheduler: no heartbeat for 1m30s") {
+		t.Errorf("Expected incident to be logged, got %q", log.String())
+	}
+}
+
+// TestWatchdogRestartsPanickedWorker tests that a worker that panics is restarted
+func TestWatchdogRestartsPanickedWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var log bytes.Buffer
+	w := newWatchdog(ctx, &log)
+
+	starts := make(chan int, 4)
+	runs := 0
+	w.add("watch", time.Minute, func(ctx context.Context, beat func()) {
+		runs++
+		starts <- runs
+		if runs == 1 {
+			panic("boom")
+		}
+		<-ctx.Done()
+	})
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case got := <-starts:
+			if got != want {
+				t.Fatalf("Expected run %d, got %d", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected run %d", want)
+		}
+	}
+	report := w.health()
+	if len(report.Incidents) != 1 || report.Incidents[0].Reason != "panicked: boom" {
+		t.Errorf("Expected panic incident, got %+v", report.Incidents)
+	}
+}
+
+// TestHealthHandler tests the /healthz status codes and that no token is needed
+func TestHealthHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{t: time.Now()}
+	w := newWatchdog(ctx, &bytes.Buffer{})
+	w.now = clock.now
+	w.add("scheduler", time.Minute, func(ctx context.Context, beat func()) { <-ctx.Done() })
+
+	server := newDaemonServer(testDaemonConfig(), (&fakeRunner{}).run, "")
+	server.watchdog = w
+	handler := server.handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report healthReport
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Status != "ok" || len(report.Workers) != 1 || report.Workers[0].Name != "scheduler" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	clock.advance(2 * time.Minute)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a hung worker, got %d", rec.Code)
+	}
+
+	// A daemon without background loops is healthy
+	rec = httptest.NewRecorder()
+	newDaemonServer(testDaemonConfig(), (&fakeRunner{}).run, "").localHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 without a watchdog, got %d", rec.Code)
+	}
+}
+
+// TestWithLockReleasesOnPanic tests that a panicking worker doesn't keep the lock
+func TestWithLockReleasesOnPanic(t *testing.T) {
+	var mu sync.Mutex
+	func() {
+		defer func() { recover() }()
+		withLock(&mu, func() { panic("boom") })
+	}()
+	if !mu.TryLock() {
+		t.Fatal("Expected the lock released after the panic")
+	}
+	mu.Unlock()
+}
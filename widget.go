@@ -0,0 +1,344 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WidgetConfig represents the countdown widget shown during sessions
+type WidgetConfig struct {
+	Enabled bool `yaml:"enabled" doc:"Show the countdown and goal in a small always-on-top window when a session starts; w shows or hides it either way" default:"false"`
+}
+
+// widgetStaleAfter is how long the widget window stays up without the session updating it,
+// so it closes by itself if FocusMode exits without cleaning up
+const widgetStaleAfter = 10 * time.Second
+
+// widgetPath returns the file the running session writes the widget's text to
+func widgetPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "widget.txt"), nil
+}
+
+// widgetClock formats the time left as on a clock, such as 24:59 or 1:02:03
+func widgetClock(d time.Duration) string {
+	seconds := int(max(d, 0).Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// widgetText returns what the widget shows: the share of the session done as a percentage on
+// the first line, read by progress bars, then the countdown and the goal, or the mode without one
+func widgetText(fs *FocusSession) string {
+	done := 100
+	if fs.Duration > 0 {
+		done = min(100, int(100*fs.elapsed()/fs.Duration))
+	}
+	countdown := widgetClock(fs.remaining())
+	switch {
+	case fs.State == StatePaused:
+		countdown += " (paused)"
+	case fs.Break:
+		countdown += " break"
+	}
+	label := fs.Goal
+	if label == "" {
+		label = fs.Mode
+	}
+	// The goal is one line of the file, whatever was typed
+	label = strings.Join(strings.Fields(label), " ")
+	return fmt.Sprintf("%d\n%s\n%s\n", done, countdown, label)
+}
+
+// widgetWindowsScript is a frameless, always-on-top WinForms window showing the widget file,
+// dragged by its background and closed by a double-click or when the file goes away or goes stale
+const widgetWindowsScript = `Add-Type -AssemblyName System.Windows.Forms
+Add-Type -AssemblyName System.Drawing
+$path = '{{PATH}}'
+$form = New-Object Windows.Forms.Form
+$form.FormBorderStyle = 'None'
+$form.TopMost = $true
+$form.ShowInTaskbar = $false
+$form.StartPosition = 'Manual'
+$form.Size = New-Object Drawing.Size(220, 68)
+$area = [Windows.Forms.Screen]::PrimaryScreen.WorkingArea
+$form.Location = New-Object Drawing.Point(($area.Right - 240), ($area.Top + 20))
+$form.BackColor = [Drawing.Color]::FromArgb(32, 32, 32)
+$form.Opacity = 0.9
+$label = New-Object Windows.Forms.Label
+$label.Dock = 'Fill'
+$label.TextAlign = 'MiddleCenter'
+$label.ForeColor = [Drawing.Color]::White
+$label.Font = New-Object Drawing.Font('Segoe UI', 12)
+$bar = New-Object Windows.Forms.ProgressBar
+$bar.Dock = 'Bottom'
+$bar.Height = 4
+$form.Controls.Add($label)
+$form.Controls.Add($bar)
+$script:drag = $null
+$label.Add_MouseDown({ if ($_.Button -eq 'Left') { $script:drag = $_.Location } })
+$label.Add_MouseMove({ if ($script:drag) { $form.Location = New-Object Drawing.Point(($form.Left + $_.X - $script:drag.X), ($form.Top + $_.Y - $script:drag.Y)) } })
+$label.Add_MouseUp({ $script:drag = $null })
+$label.Add_DoubleClick({ $form.Close() })
+$timer = New-Object Windows.Forms.Timer
+$timer.Interval = 500
+$timer.Add_Tick({
+  $item = Get-Item -LiteralPath $path -ErrorAction SilentlyContinue
+  if (-not $item -or ((Get-Date) - $item.LastWriteTime).TotalSeconds -gt {{STALE}}) { $form.Close(); return }
+  try { $lines = [IO.File]::ReadAllLines($path, [Text.Encoding]::UTF8) } catch { return }
+  if ($lines.Length -lt 2) { return }
+  $bar.Value = [Math]::Min(100, [int]$lines[0])
+  $label.Text = ($lines[1..($lines.Length - 1)] -join [Environment]::NewLine)
+})
+$timer.Start()
+[Windows.Forms.Application]::Run($form)`
+
+// widgetMacScript is the same window for macOS in JavaScript for Automation, a borderless
+// floating panel pumped by hand so it can be dragged and double-clicked
+const widgetMacScript = `ObjC.import('Cocoa');
+var path = '{{PATH}}';
+var app = $.NSApplication.sharedApplication;
+app.setActivationPolicy($.NSApplicationActivationPolicyAccessory);
+var area = $.NSScreen.mainScreen.visibleFrame;
+var panel = $.NSPanel.alloc.initWithContentRectStyleMaskBackingDefer(
+  $.NSMakeRect(area.origin.x + area.size.width - 240, area.origin.y + area.size.height - 88, 220, 68),
+  $.NSWindowStyleMaskBorderless | $.NSWindowStyleMaskNonactivatingPanel, $.NSBackingStoreBuffered, false);
+panel.level = $.NSFloatingWindowLevel;
+panel.collectionBehavior = $.NSWindowCollectionBehaviorCanJoinAllSpaces;
+panel.backgroundColor = $.NSColor.colorWithWhiteAlpha(0.12, 0.9);
+var label = $.NSTextField.labelWithString('');
+label.frame = $.NSMakeRect(0, 8, 220, 52);
+label.alignment = $.NSTextAlignmentCenter;
+label.textColor = $.NSColor.whiteColor;
+label.font = $.NSFont.systemFontOfSize(14);
+panel.contentView.addSubview(label);
+panel.orderFrontRegardless;
+var files = $.NSFileManager.defaultManager;
+var open = true;
+while (open) {
+  var attrs = files.attributesOfItemAtPathError(path, null);
+  if (attrs.isNil() || -attrs.fileModificationDate.timeIntervalSinceNow > {{STALE}}) break;
+  var text = $.NSString.stringWithContentsOfFileEncodingError(path, $.NSUTF8StringEncoding, null);
+  if (!text.isNil()) {
+    var lines = text.js.trim().split('\n');
+    label.stringValue = lines.slice(1).join('\n');
+  }
+  var until = $.NSDate.dateWithTimeIntervalSinceNow(0.5);
+  for (;;) {
+    var ev = app.nextEventMatchingMaskUntilDateInModeDequeue($.NSEventMaskAny, until, $.NSDefaultRunLoopMode, true);
+    if (ev.isNil()) break;
+    if (ev.type == $.NSEventTypeLeftMouseDragged) {
+      var f = panel.frame;
+      panel.setFrameOrigin($.NSMakePoint(f.origin.x + ev.deltaX, f.origin.y - ev.deltaY));
+    } else if (ev.type == $.NSEventTypeLeftMouseDown && ev.clickCount == 2) {
+      open = false;
+    }
+    app.sendEvent(ev);
+  }
+}`
+
+// widgetLinuxScript feeds the widget file to a yad progress dialog every second; yad closes
+// when it's sent 100, which happens once the file goes away or goes stale
+const widgetLinuxScript = `while [ -f "$1" ] && [ $(( $(date +%s) - $(stat -c %Y "$1") )) -le {{STALE}} ]; do
+  head -n 1 "$1"
+  printf '#%s\n' "$(tail -n +2 "$1" | tr '\n' ' ')"
+  sleep 1
+done
+echo 100`
+
+// widgetCommandArgs returns the command that shows the widget window for the file at path
+// lookPath is used to detect yad, which Linux desktops don't all have
+func widgetCommandArgs(goos, path string, lookPath func(string) (string, error)) (string, []string, error) {
+	fill := func(script string, quote func(string) string) string {
+		script = strings.ReplaceAll(script, "{{STALE}}", fmt.Sprint(int(widgetStaleAfter.Seconds())))
+		return strings.ReplaceAll(script, "{{PATH}}", quote(path))
+	}
+	switch goos {
+	case "windows":
+		script := fill(widgetWindowsScript, func(s string) string { return strings.ReplaceAll(s, "'", "''") })
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-WindowStyle", "Hidden", "-Command", script}, nil
+	case "darwin":
+		script := fill(widgetMacScript, func(s string) string { return strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) })
+		return "osascript", []string{"-l", "JavaScript", "-e", script}, nil
+	case "linux":
+		if _, err := lookPath("yad"); err != nil {
+			return "", nil, fmt.Errorf("the countdown widget needs yad on Linux")
+		}
+		feed := fill(widgetLinuxScript, func(string) string { return "$1" })
+		yad := "yad --progress --auto-close --undecorated --on-top --sticky --skip-taskbar --no-buttons --geometry=240x70-20+20 --title=FocusMode"
+		return "sh", []string{"-c", "(" + feed + ") | " + yad, "sh", path}, nil
+	default:
+		return "", nil, fmt.Errorf("the countdown widget isn't available on %s", goos)
+	}
+}
+
+// countdownWidget is a session hook that shows the countdown in a small always-on-top window
+// The window is a separate process reading the widget file, so hiding it only removes the file
+type countdownWidget struct {
+	config WidgetConfig
+	path   string
+
+	mu      sync.Mutex
+	session *FocusSession
+	shown   bool
+	warned  bool          // an update failed and was reported, so later failures aren't
+	window  chan struct{} // closed when the window process exits; nil when none was started
+	fresh   chan struct{} // closed to stop keeping the file fresh while it's shown
+}
+
+// newCountdownWidget returns the widget hook, or nil when there is nowhere to keep its file
+func newCountdownWidget(config WidgetConfig) *countdownWidget {
+	path, err := widgetPath()
+	if err != nil {
+		return nil
+	}
+	return &countdownWidget{config: config, path: path}
+}
+
+// show writes the widget file and opens the window, unless the last one hasn't closed yet,
+// in which case it picks the file up again
+func (w *countdownWidget) show() error {
+	name, args, err := widgetCommandArgs(runtime.GOOS, w.path, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	w.shown = true
+	w.update()
+	w.fresh = make(chan struct{})
+	go keepFresh(w.path, w.fresh)
+	if w.window != nil {
+		select {
+		case <-w.window:
+		default:
+			return nil
+		}
+	}
+
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		w.hide()
+		return fmt.Errorf("error starting %s: %w", name, err)
+	}
+	window := make(chan struct{})
+	w.window = window
+	go func() {
+		cmd.Wait()
+		close(window)
+		// Closed by a double-click, so the next w shows it again
+		w.mu.Lock()
+		if w.window == window && w.shown {
+			w.hide()
+		}
+		w.mu.Unlock()
+	}()
+	return nil
+}
+
+// keepFresh touches the widget file every second until stop is closed, so the window doesn't
+// take a paused session, whose countdown doesn't tick, for one that has gone away
+func keepFresh(path string, stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			os.Chtimes(path, now, now)
+		}
+	}
+}
+
+// update rewrites the widget file with the session's current state
+func (w *countdownWidget) update() {
+	if !w.shown || w.session == nil {
+		return
+	}
+	if err := writeFile(w.path, []byte(widgetText(w.session)), 0644); err != nil && !w.warned {
+		w.warned = true
+		fmt.Fprintf(os.Stderr, "\nWarning: error updating the countdown widget: %v\n", err)
+	}
+}
+
+// hide removes the widget file, which closes the window within a second
+func (w *countdownWidget) hide() {
+	w.shown = false
+	if w.fresh != nil {
+		close(w.fresh)
+		w.fresh = nil
+	}
+	if err := removeFile(w.path); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "\nWarning: error closing the countdown widget: %v\n", err)
+	}
+}
+
+// toggleWidget shows or hides the window and returns whether it is now shown
+func (w *countdownWidget) toggleWidget() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shown {
+		w.hide()
+		return false, nil
+	}
+	if err := w.show(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// OnStart shows the window when the widget is enabled
+func (w *countdownWidget) OnStart(fs *FocusSession) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.session = fs
+	if !w.config.Enabled {
+		return
+	}
+	if err := w.show(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: countdown widget unavailable: %v\n", err)
+	}
+}
+
+// OnPause shows that the session is paused
+func (w *countdownWidget) OnPause(fs *FocusSession) { w.OnTick(fs) }
+
+// OnResume shows the countdown again
+func (w *countdownWidget) OnResume(fs *FocusSession) { w.OnTick(fs) }
+
+// OnExtend shows the new remaining time
+func (w *countdownWidget) OnExtend(fs *FocusSession) { w.OnTick(fs) }
+
+// OnTick updates the countdown
+func (w *countdownWidget) OnTick(fs *FocusSession) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.update()
+}
+
+// OnEnd closes the window
+func (w *countdownWidget) OnEnd(fs *FocusSession) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shown {
+		w.hide()
+	}
+}
+
+// DryRun reports the window when the widget is enabled
+func (w *countdownWidget) DryRun(fs *FocusSession) []string {
+	if !w.config.Enabled {
+		return nil
+	}
+	return []string{"show the countdown in a small always-on-top window"}
+}
@@ -0,0 +1,60 @@
+package focusmode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWidgetText tests the countdown and goal written for the widget window
+func TestWidgetText(t *testing.T) {
+	fs := &FocusSession{Duration: 50 * time.Minute, Mode: "focusmode", StartTime: time.Now().Add(-10 * time.Minute), State: StateRunning}
+	if got := widgetText(fs); !strings.HasPrefix(got, "20\n40:0") || !strings.HasSuffix(got, "\nfocusmode\n") {
+		t.Errorf("Expected 20%% done, the countdown and the mode, got %q", got)
+	}
+
+	fs.Goal = "Draft the\nrelease notes"
+	paused := fs.StartTime.Add(10 * time.Minute)
+	fs.PausedAt, fs.State = &paused, StatePaused
+	if got := widgetText(fs); got != "20\n40:00 (paused)\nDraft the release notes\n" {
+		t.Errorf("Expected the paused countdown and the goal on one line, got %q", got)
+	}
+
+	clocks := map[time.Duration]string{
+		0:                               "00:00",
+		-time.Second:                    "00:00",
+		59*time.Minute + 59*time.Second: "59:59",
+		time.Hour + 2*time.Minute + 3*time.Second: "1:02:03",
+	}
+	for d, want := range clocks {
+		if got := widgetClock(d); got != want {
+			t.Errorf("widgetClock(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// TestWidgetCommandArgs tests the window command chosen for each platform
+func TestWidgetCommandArgs(t *testing.T) {
+	found := func(string) (string, error) { return "/usr/bin/yad", nil }
+	missing := func(string) (string, error) { return "", errors.New("not found") }
+
+	name, args, err := widgetCommandArgs("windows", `C:\Users\o'brien\widget.txt`, found)
+	if err != nil || name != "powershell" || !strings.Contains(args[len(args)-1], `$path = 'C:\Users\o''brien\widget.txt'`) {
+		t.Errorf("Expected a PowerShell window reading the quoted path, got %s %v (err %v)", name, args, err)
+	}
+	if strings.Contains(args[len(args)-1], "{{") {
+		t.Error("Expected every placeholder filled in")
+	}
+
+	name, args, err = widgetCommandArgs("linux", "/home/me/.config/focusmode/widget.txt", found)
+	if err != nil || name != "sh" || args[len(args)-1] != "/home/me/.config/focusmode/widget.txt" || !strings.Contains(args[1], "yad --progress") {
+		t.Errorf("Expected yad fed the path as an argument, got %s %v (err %v)", name, args, err)
+	}
+	if _, _, err := widgetCommandArgs("linux", "widget.txt", missing); err == nil || !strings.Contains(err.Error(), "yad") {
+		t.Errorf("Expected an error naming yad, got %v", err)
+	}
+	if _, _, err := widgetCommandArgs("plan9", "widget.txt", found); err == nil {
+		t.Error("Expected an error on an unsupported OS")
+	}
+}
@@ -0,0 +1,100 @@
+package focusmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits on the profiles and categories FocusMode reads, which may be rules shared by someone
+// else; each is far above what a real file needs
+const (
+	maxYAMLBytes     = 1 << 20 // Size of a file
+	maxYAMLNodes     = 100000  // Values in a file once aliases are expanded, which stops alias bombs
+	maxYAMLDepth     = 32      // Levels of nesting
+	maxYAMLListItems = 10000   // Items in one list or keys in one map, such as a category's keywords
+)
+
+// readYAMLFile reads a file of at most maxYAMLBytes, reading no more of a larger one
+func readYAMLFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxYAMLBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkYAMLSize(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// errYAMLTooLarge is returned for a file over maxYAMLBytes
+var errYAMLTooLarge = fmt.Errorf("file is over the %d KB limit", maxYAMLBytes/1024)
+
+// checkYAMLSize reports data over maxYAMLBytes
+func checkYAMLSize(data []byte) error {
+	if len(data) > maxYAMLBytes {
+		return errYAMLTooLarge
+	}
+	return nil
+}
+
+// parseYAMLDocument parses data into a document node, checking it against the limits before it
+// is decoded, when aliases would be expanded
+func parseYAMLDocument(data []byte) (*yaml.Node, error) {
+	if err := checkYAMLSize(data); err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if err := checkYAMLLimits(&document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// checkYAMLLimits reports a document nested too deeply, with a list or map too long, or with
+// too many values once its aliases are expanded
+// Aliases are followed rather than counted once, so the walk stops as soon as a document that
+// expands to millions of values passes maxYAMLNodes
+func checkYAMLLimits(document *yaml.Node) error {
+	nodes := 0
+	var walk func(node *yaml.Node, depth int) error
+	walk = func(node *yaml.Node, depth int) error {
+		if nodes++; nodes > maxYAMLNodes {
+			return fmt.Errorf("more than %d values once aliases are expanded (line %d)", maxYAMLNodes, node.Line)
+		}
+		if depth > maxYAMLDepth {
+			return fmt.Errorf("nested more than %d levels deep (line %d)", maxYAMLDepth, node.Line)
+		}
+		switch node.Kind {
+		case yaml.AliasNode:
+			if node.Alias != nil {
+				return walk(node.Alias, depth)
+			}
+		case yaml.SequenceNode:
+			if len(node.Content) > maxYAMLListItems {
+				return fmt.Errorf("list of %d items is over the limit of %d (line %d)", len(node.Content), maxYAMLListItems, node.Line)
+			}
+		case yaml.MappingNode:
+			if len(node.Content)/2 > maxYAMLListItems {
+				return fmt.Errorf("map of %d keys is over the limit of %d (line %d)", len(node.Content)/2, maxYAMLListItems, node.Line)
+			}
+		}
+		for _, child := range node.Content {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(document, 0)
+}
@@ -0,0 +1,105 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// aliasBomb returns YAML whose aliases expand to 10^levels values from a few hundred bytes
+func aliasBomb(levels int) string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= levels; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+// TestYAMLLimits tests that hostile profiles and categories are refused with an error naming the limit
+func TestYAMLLimits(t *testing.T) {
+	deep := strings.Repeat("[", maxYAMLDepth+5) + strings.Repeat("]", maxYAMLDepth+5)
+	long := "categories:\n  game:\n    keywords: [" + strings.Repeat("k, ", maxYAMLListItems) + "k]\n"
+	for name, test := range map[string]struct{ data, want string }{
+		"alias bomb":    {aliasBomb(9), "aliases are expanded"},
+		"deep nesting":  {"modes: " + deep, "levels deep"},
+		"long list":     {long, "over the limit of"},
+		"oversize file": {"# " + strings.Repeat("x", maxYAMLBytes), "KB limit"},
+	} {
+		if _, err := parseConfig([]byte(test.data)); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: parseConfig() error %v, want one containing %q", name, err, test.want)
+		}
+		if _, err := parseCategoriesConfig([]byte(test.data)); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: parseCategoriesConfig() error %v, want one containing %q", name, err, test.want)
+		}
+	}
+
+	// Aliases used the ordinary way are fine
+	config, err := parseConfig([]byte("modes:\n  work: &work\n    shortcuts: [Steam.lnk]\n  deep: *work\n"))
+	if err != nil || len(config.Modes["deep"].Shortcuts) != 1 {
+		t.Errorf("Expected an aliased mode to parse, got %+v (err %v)", config, err)
+	}
+
+	// An oversize categories file is an error, not a fallback to the defaults
+	path := filepath.Join(t.TempDir(), "categories.yml")
+	os.WriteFile(path, []byte(strings.Repeat("#", maxYAMLBytes+1)), 0644)
+	if _, err := loadCategoriesConfig(path); err == nil || !strings.Contains(err.Error(), "KB limit") {
+		t.Errorf("Expected the oversize categories file refused, got %v", err)
+	}
+
+	// So are the files the config editor and keyword suggestions rewrite
+	os.WriteFile(path, []byte(aliasBomb(9)), 0644)
+	var document yaml.Node
+	if err := readEditorDocument(path, nil, &document); err == nil || !strings.Contains(err.Error(), "aliases are expanded") {
+		t.Errorf("Expected the config editor to refuse an alias bomb, got %v", err)
+	}
+	if err := addCategoryKeywords(path, map[string][]string{"Games": {"apex"}}); err == nil || !strings.Contains(err.Error(), "aliases are expanded") {
+		t.Errorf("Expected keyword suggestions to refuse an alias bomb, got %v", err)
+	}
+}
+
+// FuzzParseConfig tests that no profile makes parsing or linting panic or run away
+func FuzzParseConfig(f *testing.F) {
+	for _, name := range []string{"profile.yml", "profile.example.yml"} {
+		if data, err := os.ReadFile(name); err == nil {
+			f.Add(data)
+		}
+	}
+	f.Add([]byte("default_mode: work\nmodes:\n  work:\n    move_all: true\n    exclude: [notes.txt]\n"))
+	f.Add([]byte(aliasBomb(3)))
+	f.Add([]byte("modes: {a: {requires: [b]}, b: {requires: [a]}}"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := parseConfig(data)
+		if err != nil {
+			return
+		}
+		lintConfig(config)
+	})
+}
+
+// FuzzParseCategoriesConfig tests that no categories file makes parsing or categorizing panic
+func FuzzParseCategoriesConfig(f *testing.F) {
+	if data, err := os.ReadFile("categories.yml"); err == nil {
+		f.Add(data)
+	}
+	f.Add([]byte("categories:\n  game:\n    keywords: [steam, '']\ncategory_order: [game, other]\n"))
+	f.Add([]byte(aliasBomb(3)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		categories, err := parseCategoriesConfig(data)
+		if err != nil {
+			return
+		}
+		categorizeFile("Steam.lnk", categories)
+	})
+}
@@ -0,0 +1,413 @@
+package focusmode
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ZoneConfig is an area of the desktop's icon grid, in columns and rows counted from 1 at the top left
+type ZoneConfig struct {
+	Columns string `yaml:"columns" doc:"Columns of the zone, as one column or a range; a range without an end runs to the right edge (empty = all)" example:"2-"`
+	Rows    string `yaml:"rows" doc:"Rows of the zone, as one row or a range; a range without an end runs to the bottom (empty = all)" example:"1-5"`
+}
+
+// Zones every config has unless it defines them itself: the left column holds the icons kept
+// on purpose and the rest of the desktop is fair game
+const (
+	zonePinned    = "pinned"
+	zoneSweepable = "sweepable"
+)
+
+var builtinZones = map[string]ZoneConfig{
+	zonePinned:    {Columns: "1"},
+	zoneSweepable: {Columns: "2-"},
+}
+
+// iconLayoutCacheTime is how long icon positions read from the desktop are reused, since
+// one apply looks up its mode's files several times
+const iconLayoutCacheTime = 10 * time.Second
+
+// desktopIcon is an icon on the desktop and where it sits, in pixels from the top left
+type desktopIcon struct {
+	Name string
+	X, Y int
+}
+
+// iconLayout is the arrangement of icons on the desktop
+type iconLayout struct {
+	SpacingX, SpacingY int // Size of a cell of the icon grid
+	Icons              []desktopIcon
+}
+
+// cell returns the column and row of the named icon, counted from 1, and whether it was found
+func (l *iconLayout) cell(name string) (int, int, bool) {
+	if l.SpacingX <= 0 || l.SpacingY <= 0 {
+		return 0, 0, false
+	}
+	for _, icon := range l.Icons {
+		if strings.EqualFold(icon.Name, name) {
+			return icon.X/l.SpacingX + 1, icon.Y/l.SpacingY + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parseGridRange parses a zone's columns or rows: "" for all, "3", "2-4" or "2-"
+// to is 0 when the range has no end
+func parseGridRange(value string) (from, to int, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 1, 0, nil
+	}
+	start, end, isRange := strings.Cut(value, "-")
+	from, err = strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("%q is not a number from 1 or a range such as 2-4 or 2-", value)
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	if strings.TrimSpace(end) == "" {
+		return from, 0, nil
+	}
+	to, err = strconv.Atoi(strings.TrimSpace(end))
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("%q is not a number from 1 or a range such as 2-4 or 2-", value)
+	}
+	return from, to, nil
+}
+
+// inGridRange reports whether n lies in a range parsed by parseGridRange
+func inGridRange(n, from, to int) bool {
+	return n >= from && (to == 0 || n <= to)
+}
+
+// contains reports whether the cell at column and row lies in the zone
+// A zone with a bad range contains nothing, so a typo never widens what is moved
+func (z ZoneConfig) contains(column, row int) bool {
+	fromColumn, toColumn, err := parseGridRange(z.Columns)
+	if err != nil {
+		return false
+	}
+	fromRow, toRow, err := parseGridRange(z.Rows)
+	if err != nil {
+		return false
+	}
+	return inGridRange(column, fromColumn, toColumn) && inGridRange(row, fromRow, toRow)
+}
+
+// validate reports a zone whose columns or rows can't be parsed
+func (z ZoneConfig) validate() error {
+	if _, _, err := parseGridRange(z.Columns); err != nil {
+		return fmt.Errorf("columns %w", err)
+	}
+	if _, _, err := parseGridRange(z.Rows); err != nil {
+		return fmt.Errorf("rows %w", err)
+	}
+	return nil
+}
+
+// zone returns the named zone, from the config or the built-in ones
+func (c *Config) zone(name string) (ZoneConfig, bool) {
+	if zone, ok := c.Zones[name]; ok {
+		return zone, true
+	}
+	zone, ok := builtinZones[name]
+	return zone, ok
+}
+
+// zoneNames returns the names of every zone, sorted
+func (c *Config) zoneNames() []string {
+	names := make([]string, 0, len(c.Zones)+len(builtinZones))
+	for name := range builtinZones {
+		if _, redefined := c.Zones[name]; !redefined {
+			names = append(names, name)
+		}
+	}
+	for name := range c.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lintZones reports zones that can't be parsed and modes that name zones that don't exist
+func (c *Config) lintZones() []string {
+	var warnings []string
+	for _, name := range c.zoneNames() {
+		zone, _ := c.zone(name)
+		if err := zone.validate(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("zones.%s: %v; it holds no icons", name, err))
+		}
+	}
+	for _, modeName := range c.getAvailableModes() {
+		for _, name := range c.Modes[modeName].Zones {
+			if _, ok := c.zone(name); !ok {
+				warnings = append(warnings, fmt.Sprintf("mode '%s' refers to undefined zone '%s'; files in it are never moved", modeName, name))
+			}
+		}
+	}
+	return warnings
+}
+
+var (
+	iconLayoutMu     sync.Mutex
+	iconLayoutCached *iconLayout
+	iconLayoutErr    error
+	iconLayoutAt     time.Time
+)
+
+// currentIconLayout returns the icon positions of the OS desktop, reading them at most once
+// every iconLayoutCacheTime
+func currentIconLayout() (*iconLayout, error) {
+	iconLayoutMu.Lock()
+	defer iconLayoutMu.Unlock()
+	if iconLayoutAt.IsZero() || time.Since(iconLayoutAt) > iconLayoutCacheTime {
+		iconLayoutCached, iconLayoutErr = currentPlatform.DesktopIcons()
+		iconLayoutAt = time.Now()
+		if iconLayoutErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: can't tell where desktop icons sit, so modes limited to zones move none of them: %v\n", iconLayoutErr)
+		}
+	}
+	return iconLayoutCached, iconLayoutErr
+}
+
+// inModeZones returns the names whose icons sit in one of the mode's zones
+// A mode without zones keeps every name; with zones, icons that can't be placed are left alone,
+// so a pinned icon is never moved because its position couldn't be read
+func (c *Config) inModeZones(modeConfig *ModeConfig, names []string) []string {
+	if len(modeConfig.Zones) == 0 || len(names) == 0 {
+		return names
+	}
+	// Icon positions are only known for the OS desktop
+	if locationActive() {
+		fmt.Fprintf(os.Stderr, "Warning: zones only apply to the OS desktop, so a mode limited to zones moves no files from --location %s\n", targetLocation)
+		return nil
+	}
+	layout, err := currentIconLayout()
+	if err != nil {
+		return nil
+	}
+	var kept []string
+	for _, name := range names {
+		if c.zoneOf(layout, modeConfig.Zones, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// zoneOf reports whether the named icon sits in any of the zones
+func (c *Config) zoneOf(layout *iconLayout, zones []string, name string) bool {
+	column, row, ok := layout.cell(name)
+	if !ok {
+		return false
+	}
+	for _, zoneName := range zones {
+		if zone, ok := c.zone(zoneName); ok && zone.contains(column, row) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIconLayout parses the output of the desktop icon script: a "spacing" line with the
+// grid cell size, then a line of x, y and name per icon, separated by tabs
+func parseIconLayout(out string) (*iconLayout, error) {
+	layout := &iconLayout{}
+	for _, line := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "spacing" {
+			x, errX := strconv.Atoi(fields[1])
+			y, errY := strconv.Atoi(fields[2])
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("unexpected icon spacing %q", line)
+			}
+			layout.SpacingX, layout.SpacingY = x, y
+			continue
+		}
+		x, errX := strconv.Atoi(fields[0])
+		y, errY := strconv.Atoi(fields[1])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("unexpected icon position %q", line)
+		}
+		layout.Icons = append(layout.Icons, desktopIcon{Name: fields[2], X: x, Y: y})
+	}
+	if layout.SpacingX <= 0 || layout.SpacingY <= 0 {
+		return nil, fmt.Errorf("the desktop reported no icon spacing")
+	}
+	return layout, nil
+}
+
+// desktopIconsSource asks Explorer's desktop view where its icons are, through the same
+// IFolderView the desktop itself uses, so no memory of another process is read
+const desktopIconsSource = `using System;
+using System.Runtime.InteropServices;
+using System.Text;
+
+public static class DesktopIcons {
+	[StructLayout(LayoutKind.Sequential)]
+	public struct Point { public int X; public int Y; }
+
+	[ComImport, Guid("85CB6900-4D95-11CF-960C-0080C7F4EE85"), InterfaceType(ComInterfaceType.InterfaceIsDual)]
+	interface IShellWindows {
+		void _VtblGap1_8();
+		[return: MarshalAs(UnmanagedType.IDispatch)]
+		object FindWindowSW([MarshalAs(UnmanagedType.Struct)] ref object location, [MarshalAs(UnmanagedType.Struct)] ref object root, int windowClass, out int window, int options);
+	}
+
+	[ComImport, Guid("6D5140C1-7436-11CE-8034-00AA006009FA"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+	interface IComServiceProvider {
+		[return: MarshalAs(UnmanagedType.IUnknown)]
+		object QueryService(ref Guid service, ref Guid riid);
+	}
+
+	[ComImport, Guid("000214E2-0000-0000-C000-000000000046"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+	interface IShellBrowser {
+		void _VtblGap1_12();
+		[return: MarshalAs(UnmanagedType.IUnknown)]
+		object QueryActiveShellView();
+	}
+
+	[ComImport, Guid("CDE725B0-CCC9-4519-917E-325D72FAB4CE"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+	interface IFolderView {
+		void _VtblGap1_2();
+		[return: MarshalAs(UnmanagedType.IUnknown)]
+		object GetFolder(ref Guid riid);
+		IntPtr Item(int index);
+		int ItemCount(uint flags);
+		void _VtblGap2_3();
+		Point GetItemPosition(IntPtr pidl);
+		Point GetSpacing();
+	}
+
+	[ComImport, Guid("000214E6-0000-0000-C000-000000000046"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+	interface IShellFolder {
+		void _VtblGap1_8();
+		void GetDisplayNameOf(IntPtr pidl, uint flags, IntPtr name);
+	}
+
+	[DllImport("shlwapi.dll", CharSet = CharSet.Unicode)]
+	static extern int StrRetToBuf(IntPtr strret, IntPtr pidl, StringBuilder buffer, int size);
+
+	public static string List() {
+		IShellWindows windows = (IShellWindows)Activator.CreateInstance(Type.GetTypeFromCLSID(new Guid("9BA05972-F6A8-11CF-A442-00A0C90A8F39")));
+		object location = 0, root = null;
+		int window;
+		IComServiceProvider provider = (IComServiceProvider)windows.FindWindowSW(ref location, ref root, 8, out window, 1);
+		Guid service = new Guid("4C96BE40-915C-11CF-99D3-00AA004AE837"), browserID = typeof(IShellBrowser).GUID, folderID = typeof(IShellFolder).GUID;
+		IShellBrowser browser = (IShellBrowser)provider.QueryService(ref service, ref browserID);
+		IFolderView view = (IFolderView)browser.QueryActiveShellView();
+		IShellFolder folder = (IShellFolder)view.GetFolder(ref folderID);
+
+		Point spacing = view.GetSpacing();
+		StringBuilder result = new StringBuilder();
+		result.AppendFormat("spacing\t{0}\t{1}\n", spacing.X, spacing.Y);
+		IntPtr strret = Marshal.AllocCoTaskMem(1024);
+		try {
+			int count = view.ItemCount(2);
+			for (int i = 0; i < count; i++) {
+				IntPtr pidl = view.Item(i);
+				try {
+					Point at = view.GetItemPosition(pidl);
+					folder.GetDisplayNameOf(pidl, 0x8001, strret);
+					StringBuilder name = new StringBuilder(260);
+					StrRetToBuf(strret, pidl, name, name.Capacity);
+					result.AppendFormat("{0}\t{1}\t{2}\n", at.X, at.Y, name);
+				} finally {
+					Marshal.FreeCoTaskMem(pidl);
+				}
+			}
+		} finally {
+			Marshal.FreeCoTaskMem(strret);
+		}
+		return result.ToString();
+	}
+}`
+
+// desktopIconsCommand returns the command that prints the desktop's icon positions for parseIconLayout
+// Only Windows keeps a free arrangement of icons that programs can read
+func desktopIconsCommand(goos string) ([]string, error) {
+	if goos != "windows" {
+		return nil, fmt.Errorf("reading where desktop icons sit needs Windows")
+	}
+	script := "Add-Type -TypeDefinition '" + desktopIconsSource + "'; [DesktopIcons]::List()"
+	return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}, nil
+}
+
+// printZones lists each zone with the desktop files whose icons sit in it
+func printZones(c *Config, layout *iconLayout, files []string) {
+	placed := make(map[string]bool)
+	for _, name := range c.zoneNames() {
+		zone, _ := c.zone(name)
+		var members []string
+		for _, file := range files {
+			if column, row, ok := layout.cell(file); ok && zone.contains(column, row) {
+				members = append(members, file)
+				placed[file] = true
+			}
+		}
+		fmt.Printf("%s (columns %s, rows %s): %d file(s)\n", name, valueOrAll(zone.Columns), valueOrAll(zone.Rows), len(members))
+		for _, member := range members {
+			fmt.Printf("  %s\n", member)
+		}
+	}
+	var unplaced []string
+	for _, file := range files {
+		if !placed[file] {
+			unplaced = append(unplaced, file)
+		}
+	}
+	if len(unplaced) > 0 {
+		fmt.Printf("In no zone: %d file(s)\n", len(unplaced))
+		for _, file := range unplaced {
+			fmt.Printf("  %s\n", file)
+		}
+	}
+}
+
+// valueOrAll returns a zone range for display
+func valueOrAll(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "all"
+	}
+	return value
+}
+
+// runZonesCommand handles "zones", which shows which desktop files sit in which zone
+func runZonesCommand(args []string) {
+	flags := flag.NewFlagSet("zones", flag.ExitOnError)
+	configPath := flags.String("config", "profile.yml", "Path to configuration file")
+	flags.Parse(args)
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if locationActive() {
+		fmt.Fprintln(os.Stderr, "Error: zones only apply to the OS desktop, not --location")
+		os.Exit(1)
+	}
+	layout, err := currentPlatform.DesktopIcons()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, err := getAllDesktopShortcuts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	files = append(files, publicDesktopShortcuts(&ModeConfig{PublicDesktop: true}, files)...)
+	printZones(config, layout, files)
+}
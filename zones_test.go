@@ -0,0 +1,113 @@
+package focusmode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// useIconLayout makes the fake platform report layout and forgets any layout read before
+func useIconLayout(t *testing.T, layout *iconLayout) {
+	useFakePlatform(t, &fakePlatform{icons: layout})
+	iconLayoutAt = time.Time{}
+	t.Cleanup(func() { iconLayoutAt = time.Time{} })
+}
+
+// TestParseIconLayout tests reading the icon positions printed by the desktop script
+func TestParseIconLayout(t *testing.T) {
+	layout, err := parseIconLayout("spacing\t75\t100\r\n10\t2\tThis PC.lnk\r\n85\t102\tSteam.lnk\r\n\r\n")
+	if err != nil {
+		t.Fatalf("parseIconLayout() error: %v", err)
+	}
+	if layout.SpacingX != 75 || layout.SpacingY != 100 || len(layout.Icons) != 2 {
+		t.Fatalf("parseIconLayout() = %+v", layout)
+	}
+	if column, row, ok := layout.cell("steam.lnk"); !ok || column != 2 || row != 2 {
+		t.Errorf("cell(steam.lnk) = %d, %d, %v; want 2, 2, true", column, row, ok)
+	}
+	if _, _, ok := layout.cell("Discord.lnk"); ok {
+		t.Error("Expected an icon that isn't on the desktop not to be placed")
+	}
+
+	for _, out := range []string{"10\t2\tSteam.lnk\n", "spacing\tx\t100\n", "spacing\t75\t100\nx\t2\tSteam.lnk\n"} {
+		if _, err := parseIconLayout(out); err == nil {
+			t.Errorf("Expected an error for %q", out)
+		}
+	}
+}
+
+// TestParseGridRange tests the column and row ranges of zones
+func TestParseGridRange(t *testing.T) {
+	tests := map[string][2]int{"": {1, 0}, "3": {3, 3}, "2-4": {2, 4}, " 2- ": {2, 0}}
+	for value, want := range tests {
+		from, to, err := parseGridRange(value)
+		if err != nil || from != want[0] || to != want[1] {
+			t.Errorf("parseGridRange(%q) = %d, %d, %v; want %d, %d", value, from, to, err, want[0], want[1])
+		}
+	}
+	for _, value := range []string{"0", "4-2", "a", "-3", "2-x"} {
+		if _, _, err := parseGridRange(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+	if (ZoneConfig{Columns: "x"}).contains(1, 1) {
+		t.Error("Expected a zone with a bad range to contain nothing")
+	}
+}
+
+// TestInModeZones tests that a mode limited to zones only keeps files whose icons sit in them
+func TestInModeZones(t *testing.T) {
+	useIconLayout(t, &iconLayout{SpacingX: 75, SpacingY: 100, Icons: []desktopIcon{
+		{Name: "Notes.lnk", X: 10, Y: 5},
+		{Name: "Steam.lnk", X: 160, Y: 5},
+		{Name: "Discord.lnk", X: 85, Y: 305},
+	}})
+	config := &Config{Zones: map[string]ZoneConfig{"top": {Rows: "1"}}}
+	names := []string{"Notes.lnk", "Steam.lnk", "Discord.lnk", "Unplaced.lnk"}
+
+	if got := config.inModeZones(&ModeConfig{}, names); len(got) != 4 {
+		t.Errorf("Expected a mode without zones to keep every file, got %v", got)
+	}
+	if got := config.inModeZones(&ModeConfig{Zones: []string{zoneSweepable}}, names); strings.Join(got, ",") != "Steam.lnk,Discord.lnk" {
+		t.Errorf("sweepable kept %v, want Steam.lnk and Discord.lnk", got)
+	}
+	if got := config.inModeZones(&ModeConfig{Zones: []string{"top"}}, names); strings.Join(got, ",") != "Notes.lnk,Steam.lnk" {
+		t.Errorf("top kept %v, want Notes.lnk and Steam.lnk", got)
+	}
+	if got := config.inModeZones(&ModeConfig{Zones: []string{"missing"}}, names); len(got) != 0 {
+		t.Errorf("Expected an undefined zone to keep nothing, got %v", got)
+	}
+
+	// Without positions nothing is moved, so pinned icons stay put
+	useIconLayout(t, nil)
+	if got := config.inModeZones(&ModeConfig{Zones: []string{zoneSweepable}}, names); len(got) != 0 {
+		t.Errorf("Expected nothing kept without icon positions, got %v", got)
+	}
+}
+
+// TestLintZones tests warnings for bad zones and modes naming undefined ones
+func TestLintZones(t *testing.T) {
+	config := &Config{
+		Zones: map[string]ZoneConfig{"left": {Columns: "0-2"}},
+		Modes: map[string]ModeConfig{"focusmode": {Zones: []string{"sweepable", "right"}}},
+	}
+	warnings := strings.Join(config.lintZones(), "\n")
+	if !strings.Contains(warnings, "zones.left: columns") || !strings.Contains(warnings, "undefined zone 'right'") || strings.Contains(warnings, "sweepable") {
+		t.Errorf("lintZones() = %s", warnings)
+	}
+}
+
+// TestDesktopIconsCommand tests that only Windows can read icon positions
+func TestDesktopIconsCommand(t *testing.T) {
+	command, err := desktopIconsCommand("windows")
+	if err != nil || command[0] != "powershell" || !strings.Contains(command[4], "[DesktopIcons]::List()") {
+		t.Errorf("desktopIconsCommand(windows) = %v, %v", command, err)
+	}
+	// The C# source is passed in single quotes
+	if strings.Contains(desktopIconsSource, "'") {
+		t.Error("desktopIconsSource must not contain single quotes")
+	}
+	if _, err := desktopIconsCommand("linux"); err == nil {
+		t.Error("Expected an error on Linux")
+	}
+}